@@ -0,0 +1,36 @@
+// Package main runs GoAgent as a line-delimited JSON-RPC server on stdio so
+// editors can embed it as a subprocess.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/asynkron/goagent/internal/agentserver"
+	runtimepkg "github.com/asynkron/goagent/internal/core/runtime"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	historyPath := ""
+	base := runtimepkg.RuntimeOptions{
+		Model:           os.Getenv("OPENAI_MODEL"),
+		ReasoningEffort: os.Getenv("OPENAI_REASONING_EFFORT"),
+		APIBaseURL:      os.Getenv("OPENAI_BASE_URL"),
+		Provider:        os.Getenv("GOAGENT_PROVIDER"),
+		// stdout carries the protocol, so never write the history log or
+		// logs anywhere implicit.
+		HistoryLogPath: &historyPath,
+		EventLogPath:   &historyPath,
+		LogPath:        os.Getenv("GOAGENT_LOG_PATH"),
+	}
+
+	if err := agentserver.New(os.Stdin, os.Stdout, base).Serve(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
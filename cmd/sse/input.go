@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	runtimepkg "github.com/asynkron/goagent/internal/core/runtime"
+)
+
+// maxInputBody bounds the JSON body of POST /stream/{id}/input.
+const maxInputBody = 1 << 20
+
+// inputRequest is the body of POST /stream/{id}/input. Type names the input
+// like runtime.InputEventType; the other fields apply to some types only.
+type inputRequest struct {
+	Type runtimepkg.InputEventType `json:"type"`
+	// Prompt is the message for "prompt". While the agent works it steers
+	// the running turn.
+	Prompt string `json:"prompt"`
+	// Model and ReasoningEffort override the session's for one "prompt".
+	Model           string `json:"model"`
+	ReasoningEffort string `json:"reasoningEffort"`
+	// StepID names the step for "approval_decision" and "command_stdin".
+	StepID   string `json:"stepId"`
+	Approved bool   `json:"approved"`
+	// Reason explains an approval decision, a cancel or a pause.
+	Reason string `json:"reason"`
+	// Data is written to the step's stdin by "command_stdin"; EOF closes it
+	// afterwards.
+	Data string `json:"data"`
+	EOF  bool   `json:"eof"`
+}
+
+// inputHandler serves POST /stream/{id}/input: it feeds the JSON body into
+// the session's runtime as an InputEvent, so clients can answer
+// request_input and approval_request events over HTTP.
+func inputHandler(w http.ResponseWriter, r *http.Request) {
+	agent, ok := sessions.get(r.PathValue("id"), requestClient(r))
+	if !ok {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	var input inputRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxInputBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&input); err != nil {
+		http.Error(w, fmt.Sprintf("invalid input: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := submitInput(agent, input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = fmt.Fprintln(w, `{"accepted":true}`)
+}
+
+// submitInput hands input to agent, checking the fields its type needs.
+func submitInput(agent *runtimepkg.Runtime, input inputRequest) error {
+	switch input.Type {
+	case runtimepkg.InputTypePrompt:
+		if strings.TrimSpace(input.Prompt) == "" {
+			return fmt.Errorf("prompt is required")
+		}
+		agent.SubmitPromptWithOverride(input.Prompt, nil, runtimepkg.ModelOverride{Model: input.Model, ReasoningEffort: input.ReasoningEffort})
+	case runtimepkg.InputTypeApprovalDecision:
+		if strings.TrimSpace(input.StepID) == "" {
+			return fmt.Errorf("stepId is required")
+		}
+		agent.SubmitApproval(input.StepID, input.Approved, input.Reason)
+	case runtimepkg.InputTypeCommandStdin:
+		if strings.TrimSpace(input.StepID) == "" {
+			return fmt.Errorf("stepId is required")
+		}
+		return agent.SubmitCommandStdin(input.StepID, input.Data, input.EOF)
+	case runtimepkg.InputTypeCancel:
+		agent.Cancel(input.Reason)
+	case runtimepkg.InputTypePause:
+		agent.Pause(input.Reason)
+	case runtimepkg.InputTypeResume:
+		agent.Resume()
+	default:
+		return fmt.Errorf("unknown input type %q (want prompt, approval_decision, command_stdin, cancel, pause or resume)", input.Type)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	runtimepkg "github.com/asynkron/goagent/internal/core/runtime"
+	"github.com/asynkron/goagent/pkg/runtimetest"
+)
+
+func postInput(t *testing.T, handler http.Handler, id, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/stream/"+id+"/input", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestInputHandlerAnswersApprovalRequests(t *testing.T) {
+	t.Parallel()
+
+	h := runtimetest.New(t, runtimepkg.RuntimeOptions{ApprovalPolicy: runtimepkg.ApprovalPolicyAlways},
+		runtimetest.PlanCall("call-1", runtimepkg.PlanResponse{
+			Message: "Listing files",
+			Plan:    []runtimepkg.PlanStep{runtimetest.Step("list", "ls")},
+		}),
+		runtimetest.MessageCall("call-2", "All done"),
+	)
+	h.Commands.On("ls", runtimetest.CommandResult{Stdout: "main.go\n"})
+	id := sessions.add(h.Runtime, "")
+	defer sessions.remove(id)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /stream/{id}/input", inputHandler)
+
+	h.WaitFor(runtimepkg.EventTypeRequestInput)
+	if rec := postInput(t, mux, id, `{"type":"prompt","prompt":"what is here?"}`); rec.Code != http.StatusAccepted {
+		t.Fatalf("prompt: status %d: %s", rec.Code, rec.Body.String())
+	}
+	request := h.WaitFor(runtimepkg.EventTypeApprovalRequest)
+	if request.Metadata["step_id"] != "list" {
+		t.Fatalf("unexpected approval request: %+v", request)
+	}
+	if rec := postInput(t, mux, id, `{"type":"approval_decision","stepId":"list","approved":true}`); rec.Code != http.StatusAccepted {
+		t.Fatalf("approval: status %d: %s", rec.Code, rec.Body.String())
+	}
+	h.WaitFor(runtimepkg.EventTypeRequestInput)
+	if got := h.Commands.Commands(); len(got) != 1 || got[0] != "ls" {
+		t.Fatalf("expected the approved step to run, got %v", got)
+	}
+}
+
+func TestInputHandlerRejectsBadInput(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /stream/{id}/input", inputHandler)
+	if rec := postInput(t, mux, "missing", `{"type":"cancel"}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown session: status %d", rec.Code)
+	}
+
+	id := sessions.add(&runtimepkg.Runtime{}, "")
+	defer sessions.remove(id)
+	for _, body := range []string{
+		`not json`,
+		`{"type":"prompt","prompt":"  "}`,
+		`{"type":"approval_decision","approved":true}`,
+		`{"type":"shutdown"}`,
+		`{"type":"cancel","extra":1}`,
+	} {
+		if rec := postInput(t, mux, id, body); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status %d, want 400", body, rec.Code)
+		}
+	}
+}
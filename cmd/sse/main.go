@@ -4,16 +4,26 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	runtimepkg "github.com/asynkron/goagent/internal/core/runtime"
 )
 
+// readOnly makes every session reject plan steps that may modify the
+// server's workspace; see RuntimeOptions.ReadOnly. approvalPolicy decides
+// which steps wait for an approval_decision posted to the input endpoint.
+var (
+	readOnly       bool
+	approvalPolicy runtimepkg.ApprovalPolicy
+)
+
 // sseWrite sends a single SSE event with the given name and data, followed by a flush.
 func sseWrite(w http.ResponseWriter, flusher http.Flusher, event string, data string) error {
 	if event != "" {
@@ -59,6 +69,22 @@ func streamHandler(w http.ResponseWriter, r *http.Request) {
 		prompt = "Say hello with a few words."
 	}
 
+	// attach=<path> (repeatable) sends files below the server's working
+	// directory with the prompt, e.g. screenshots for vision models.
+	var attachments []runtimepkg.Attachment
+	for _, path := range r.URL.Query()["attach"] {
+		if !filepath.IsLocal(path) {
+			http.Error(w, fmt.Sprintf("attach %q: path must be relative to the server directory", path), http.StatusBadRequest)
+			return
+		}
+		attachment, err := runtimepkg.LoadAttachment(path)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		attachments = append(attachments, attachment)
+	}
+
 	// Build a fresh runtime instance per request to avoid multiplexing outputs
 	// across multiple clients for this simple example.
 	opts := runtimepkg.RuntimeOptions{
@@ -68,6 +94,8 @@ func streamHandler(w http.ResponseWriter, r *http.Request) {
 		APIBaseURL:              os.Getenv("OPENAI_BASE_URL"),
 		DisableOutputForwarding: true, // we will forward via SSE
 		UseStreaming:            true,
+		ReadOnly:                readOnly,
+		ApprovalPolicy:          approvalPolicy,
 		// Keep generous defaults
 		EmitTimeout: 0,
 	}
@@ -81,6 +109,11 @@ func streamHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithCancel(r.Context())
 	defer cancel()
 
+	// Register the session so GET /sessions/{id}/plan can report progress.
+	sessionID := sessions.add(agent, requestClient(r))
+	defer sessions.remove(sessionID)
+	w.Header().Set("X-Session-Id", sessionID)
+
 	outputs := agent.Outputs()
 
 	// Kick off the agent
@@ -91,12 +124,13 @@ func streamHandler(w http.ResponseWriter, r *http.Request) {
 	}()
 
 	// Submit the prompt
-	agent.SubmitPrompt(prompt)
+	agent.SubmitPromptWithAttachments(prompt, attachments)
 
 	// Initial comment to open the stream for some clients
 	if _, err := fmt.Fprint(w, ": connected\n\n"); err == nil {
 		flusher.Flush()
 	}
+	_ = sseWrite(w, flusher, "session", sessionID)
 
 	// Forward events until the request is canceled or the runtime closes.
 	for {
@@ -119,6 +153,8 @@ func streamHandler(w http.ResponseWriter, r *http.Request) {
 			switch evt.Type {
 			case runtimepkg.EventTypeAssistantDelta:
 				_ = sseWrite(w, flusher, "assistant_delta", evt.Message)
+			case runtimepkg.EventTypeReasoningDelta:
+				_ = sseWrite(w, flusher, "reasoning_delta", evt.Message)
 			case runtimepkg.EventTypeAssistantMessage:
 				_ = sseWrite(w, flusher, "assistant_message", evt.Message)
 			case runtimepkg.EventTypeStatus:
@@ -127,6 +163,9 @@ func streamHandler(w http.ResponseWriter, r *http.Request) {
 				_ = sseWrite(w, flusher, "error", evt.Message)
 			case runtimepkg.EventTypeRequestInput:
 				_ = sseWrite(w, flusher, "request_input", evt.Message)
+			case runtimepkg.EventTypeApprovalRequest:
+				// Clients answer with the step_id via POST /stream/{id}/input.
+				_ = sseWrite(w, flusher, "approval_request", meta)
 			default:
 				// Unknown types as generic data
 				payload := evt.Message
@@ -140,11 +179,59 @@ func streamHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func main() {
+	addr := flag.String("addr", envOr("SSE_ADDR", ":8080"), "address to listen on (env SSE_ADDR)")
+	apiKeys := flag.String("api-keys", os.Getenv("SSE_API_KEYS"), "comma-separated API keys clients send as \"Authorization: Bearer <key>\" or X-API-Key (env SSE_API_KEYS); empty leaves the server open")
+	corsOrigins := flag.String("cors-origins", os.Getenv("SSE_CORS_ORIGINS"), "comma-separated browser origins allowed to call the server, or * (env SSE_CORS_ORIGINS)")
+	rate := flag.Float64("rate-limit", 0, "requests per minute allowed per client, by API key or address (0 = unlimited)")
+	burst := flag.Int("rate-burst", 5, "requests a client may make at once before --rate-limit applies")
+	trustProxy := flag.Bool("trust-proxy", false, "identify clients by X-Forwarded-For; set only behind a reverse proxy that overwrites it")
+	flag.BoolVar(&readOnly, "read-only", false, "reject plan steps that may modify the server's workspace")
+	approval := flag.String("approval", string(runtimepkg.ApprovalPolicyNever), "ask before executing plan steps: never, on-write, or always; answer with POST /stream/{id}/input")
+	flag.Parse()
+
+	approvalPolicy = runtimepkg.ApprovalPolicy(*approval)
+	switch approvalPolicy {
+	case runtimepkg.ApprovalPolicyNever, runtimepkg.ApprovalPolicyOnWrite, runtimepkg.ApprovalPolicyAlways:
+	default:
+		log.Fatalf("unknown --approval %q (want never, on-write, or always)", *approval)
+	}
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/stream", streamHandler)
+	mux.HandleFunc("GET /sessions/{id}/plan", planHandler)
+	mux.HandleFunc("POST /stream/{id}/input", inputHandler)
+
+	security := securityOptions{
+		apiKeys:       splitList(*apiKeys),
+		corsOrigins:   splitList(*corsOrigins),
+		ratePerMinute: *rate,
+		burst:         *burst,
+		trustProxy:    *trustProxy,
+	}
+	if len(security.apiKeys) == 0 {
+		log.Printf("warning: no API keys configured; anyone who can reach %s can run commands on this host (set SSE_API_KEYS)", *addr)
+	}
 
-	addr := ":8080"
-	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
-	log.Printf("SSE server listening on %s (GET /stream?q=your+prompt)", addr)
+	srv := &http.Server{Addr: *addr, Handler: secure(mux, security), ReadHeaderTimeout: 10 * time.Second}
+	log.Printf("SSE server listening on %s (GET /stream?q=your+prompt[&attach=path], GET /sessions/{id}/plan, POST /stream/{id}/input)", *addr)
 	log.Fatal(srv.ListenAndServe())
 }
+
+// envOr returns the environment variable key, or fallback when it is unset.
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// splitList splits a comma-separated value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
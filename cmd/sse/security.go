@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// securityOptions configures the middleware in front of every endpoint.
+type securityOptions struct {
+	// apiKeys are accepted as "Authorization: Bearer <key>" or
+	// "X-API-Key: <key>". Empty leaves the server open.
+	apiKeys []string
+	// corsOrigins may call the server from a browser; "*" allows any
+	// origin. Empty sends no CORS headers.
+	corsOrigins []string
+	// ratePerMinute and burst bound the requests of each client, identified
+	// by its API key or address. Zero disables the limit.
+	ratePerMinute float64
+	burst         int
+	// trustProxy takes the client address from X-Forwarded-For, for
+	// deployments behind a reverse proxy.
+	trustProxy bool
+}
+
+// secure wraps next with CORS, authentication and rate limiting, in that
+// order, so preflight requests need no credentials and rejected requests
+// still carry CORS headers the browser can read.
+func secure(next http.Handler, options securityOptions) http.Handler {
+	limiter := newRateLimiter(options.ratePerMinute, options.burst, time.Now)
+	keys := hashKeys(options.apiKeys)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !applyCORS(w, r, options.corsOrigins) {
+			return
+		}
+		key, ok := authenticate(r, keys)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="goagent"`)
+			http.Error(w, "missing or invalid API key", http.StatusUnauthorized)
+			return
+		}
+		client := key
+		if client == "" {
+			client = clientAddress(r, options.trustProxy)
+		}
+		if wait, ok := limiter.allow(client); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds()+0.999)))
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientKey{}, key)))
+	})
+}
+
+// applyCORS sets the CORS headers for an allowed origin and answers
+// preflight requests. It reports whether the request should continue.
+func applyCORS(w http.ResponseWriter, r *http.Request, origins []string) bool {
+	origin := r.Header.Get("Origin")
+	allowed := origin != "" && (slices.Contains(origins, "*") || slices.Contains(origins, origin))
+	if allowed {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Expose-Headers", "X-Session-Id, Retry-After")
+		w.Header().Add("Vary", "Origin")
+	}
+	if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+		return true
+	}
+	if !allowed {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return false
+	}
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Authorization, X-API-Key, Content-Type")
+	w.Header().Set("Access-Control-Max-Age", "600")
+	w.WriteHeader(http.StatusNoContent)
+	return false
+}
+
+// clientKey carries the authenticated client (see authenticate) in the
+// request context.
+type clientKey struct{}
+
+// requestClient returns the API key client of a request that passed secure,
+// "" when the server has no keys.
+func requestClient(r *http.Request) string {
+	client, _ := r.Context().Value(clientKey{}).(string)
+	return client
+}
+
+// hashKeys returns the SHA-256 of each key, so comparisons take the same
+// time whatever the key lengths.
+func hashKeys(apiKeys []string) [][sha256.Size]byte {
+	var keys [][sha256.Size]byte
+	for _, key := range apiKeys {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, sha256.Sum256([]byte(key)))
+		}
+	}
+	return keys
+}
+
+// authenticate checks the request's API key against keys. It returns a
+// client identifier derived from the key, empty when no keys are
+// configured.
+func authenticate(r *http.Request, keys [][sha256.Size]byte) (string, bool) {
+	if len(keys) == 0 {
+		return "", true
+	}
+	presented := strings.TrimSpace(r.Header.Get("X-API-Key"))
+	if auth := r.Header.Get("Authorization"); presented == "" && len(auth) > len("Bearer ") && strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+		presented = strings.TrimSpace(auth[len("Bearer "):])
+	}
+	if presented == "" {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(presented))
+	match := 0
+	for i := range keys {
+		match |= subtle.ConstantTimeCompare(sum[:], keys[i][:])
+	}
+	if match != 1 {
+		return "", false
+	}
+	return "key:" + hex.EncodeToString(sum[:8]), true
+}
+
+// clientAddress returns the host the request came from. With trustProxy the
+// first X-Forwarded-For entry wins.
+func clientAddress(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded, _, _ := strings.Cut(r.Header.Get("X-Forwarded-For"), ","); strings.TrimSpace(forwarded) != "" {
+			return strings.TrimSpace(forwarded)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimiter is a token bucket per client.
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64 // tokens per second
+	burst   float64
+	now     func() time.Time
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	seen   time.Time
+}
+
+// newRateLimiter allows perMinute requests per client with bursts of burst
+// (at least one). A non-positive perMinute returns nil, which allows
+// everything.
+func newRateLimiter(perMinute float64, burst int, now func() time.Time) *rateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &rateLimiter{rate: perMinute / 60, burst: float64(max(burst, 1)), now: now, buckets: make(map[string]*bucket)}
+}
+
+// allow takes a token for client. When none is left it returns how long
+// until one is.
+func (l *rateLimiter) allow(client string) (time.Duration, bool) {
+	if l == nil {
+		return 0, true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	b, ok := l.buckets[client]
+	if !ok {
+		l.evict(now)
+		b = &bucket{tokens: l.burst}
+		l.buckets[client] = b
+	} else {
+		b.tokens = min(l.burst, b.tokens+now.Sub(b.seen).Seconds()*l.rate)
+	}
+	b.seen = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.rate * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+// evict drops buckets that have refilled completely, so idle clients do not
+// accumulate.
+func (l *rateLimiter) evict(now time.Time) {
+	full := time.Duration(l.burst / l.rate * float64(time.Second))
+	for client, b := range l.buckets {
+		if now.Sub(b.seen) >= full {
+			delete(l.buckets, client)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	runtimepkg "github.com/asynkron/goagent/internal/core/runtime"
+)
+
+func TestSecureRequiresAPIKey(t *testing.T) {
+	t.Parallel()
+
+	handler := secure(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), securityOptions{apiKeys: []string{"secret", "other"}})
+
+	for _, tc := range []struct {
+		name   string
+		header string
+		value  string
+		want   int
+	}{
+		{"missing", "", "", http.StatusUnauthorized},
+		{"wrong bearer", "Authorization", "Bearer nope", http.StatusUnauthorized},
+		{"bearer", "Authorization", "Bearer secret", http.StatusOK},
+		{"lowercase scheme", "Authorization", "bearer other", http.StatusOK},
+		{"api key header", "X-API-Key", "secret", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/stream", nil)
+		if tc.header != "" {
+			req.Header.Set(tc.header, tc.value)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("%s: status %d, want %d", tc.name, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestSecureAnswersPreflightForAllowedOrigins(t *testing.T) {
+	t.Parallel()
+
+	handler := secure(http.NotFoundHandler(), securityOptions{apiKeys: []string{"secret"}, corsOrigins: []string{"https://app.example"}})
+
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/stream", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := preflight("https://app.example"); rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example" {
+		t.Fatalf("allowed preflight: status %d, headers %v", rec.Code, rec.Header())
+	}
+	if rec := preflight("https://evil.example"); rec.Code != http.StatusForbidden || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("foreign preflight: status %d, headers %v", rec.Code, rec.Header())
+	}
+}
+
+func TestRateLimiterRefillsPerClient(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(0, 0)
+	limiter := newRateLimiter(60, 2, func() time.Time { return now })
+	for i := range 2 {
+		if _, ok := limiter.allow("a"); !ok {
+			t.Fatalf("request %d within the burst was refused", i)
+		}
+	}
+	wait, ok := limiter.allow("a")
+	if ok || wait != time.Second {
+		t.Fatalf("expected a 1s wait after the burst, got %v %v", wait, ok)
+	}
+	if _, ok := limiter.allow("b"); !ok {
+		t.Fatalf("another client was limited")
+	}
+	now = now.Add(time.Second)
+	if _, ok := limiter.allow("a"); !ok {
+		t.Fatalf("expected a token after a second")
+	}
+	if _, ok := newRateLimiter(0, 0, time.Now).allow("a"); !ok {
+		t.Fatalf("a disabled limiter refused a request")
+	}
+}
+
+func TestSessionsOnlyServeTheirClient(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	var owner string
+	mux.HandleFunc("GET /stream", func(w http.ResponseWriter, r *http.Request) {
+		owner = requestClient(r)
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("POST /stream/{id}/input", inputHandler)
+	handler := secure(mux, securityOptions{apiKeys: []string{"secret", "other"}})
+
+	req := httptest.NewRequest(http.MethodGet, "/stream", nil)
+	req.Header.Set("X-API-Key", "secret")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if owner == "" {
+		t.Fatal("expected the authenticated client in the request context")
+	}
+	id := sessions.add(&runtimepkg.Runtime{}, owner)
+	defer sessions.remove(id)
+
+	for _, tc := range []struct {
+		key  string
+		want int
+	}{
+		{"other", http.StatusNotFound},
+		// The owner gets past the lookup to the input validation.
+		{"secret", http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/stream/"+id+"/input", strings.NewReader(`{"type":"prompt","prompt":" "}`))
+		req.Header.Set("X-API-Key", tc.key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("%s: status %d, want %d", tc.key, rec.Code, tc.want)
+		}
+	}
+}
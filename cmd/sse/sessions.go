@@ -0,0 +1,70 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	runtimepkg "github.com/asynkron/goagent/internal/core/runtime"
+)
+
+// sessions tracks the runtimes of open streams so other endpoints can
+// inspect them. A session is removed when its stream ends.
+var sessions = &sessionRegistry{runtimes: make(map[string]session)}
+
+type sessionRegistry struct {
+	mu       sync.RWMutex
+	runtimes map[string]session
+}
+
+// session is an open stream's runtime and the API key client that opened
+// it ("" when the server has no keys).
+type session struct {
+	agent  *runtimepkg.Runtime
+	client string
+}
+
+// add registers agent, opened by client, under a new random ID and returns
+// the ID.
+func (s *sessionRegistry) add(agent *runtimepkg.Runtime, client string) string {
+	var raw [8]byte
+	_, _ = rand.Read(raw[:])
+	id := hex.EncodeToString(raw[:])
+	s.mu.Lock()
+	s.runtimes[id] = session{agent: agent, client: client}
+	s.mu.Unlock()
+	return id
+}
+
+func (s *sessionRegistry) remove(id string) {
+	s.mu.Lock()
+	delete(s.runtimes, id)
+	s.mu.Unlock()
+}
+
+// get returns the session with id if client opened it. Sessions of other
+// clients look unknown, so IDs cannot be probed with another key.
+func (s *sessionRegistry) get(id, client string) (*runtimepkg.Runtime, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	session, ok := s.runtimes[id]
+	if !ok || session.client != client {
+		return nil, false
+	}
+	return session.agent, true
+}
+
+// planHandler serves GET /sessions/{id}/plan: the session's plan with step
+// statuses, timings, and observations as JSON.
+func planHandler(w http.ResponseWriter, r *http.Request) {
+	agent, ok := sessions.get(r.PathValue("id"), requestClient(r))
+	if !ok {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	_ = json.NewEncoder(w).Encode(agent.PlanSnapshot())
+}
@@ -0,0 +1,390 @@
+// Package agentserver exposes the runtime over a line-delimited JSON-RPC 2.0
+// protocol on stdio so editors can embed GoAgent as a subprocess.
+//
+// Requests (one JSON object per line on stdin):
+//
+//	initialize {model?, provider?, approvalPolicy?, augment?, cwd?}
+//	prompt     {text, attachments?: [{path} | {name, media_type, data}]}
+//	cancel     {reason?}
+//	approve    {stepId, approved, reason?}
+//	stdin      {stepId, data, eof?}
+//	shutdown   {}
+//
+// Runtime events are written to stdout as "event" notifications whose params
+// are the serialized RuntimeEvent.
+package agentserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/asynkron/goagent/internal/core/runtime"
+)
+
+// ProtocolVersion identifies the wire protocol revision reported by initialize.
+const ProtocolVersion = 1
+
+// Standard JSON-RPC 2.0 error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type initializeParams struct {
+	Model          string `json:"model"`
+	Provider       string `json:"provider"`
+	ApprovalPolicy string `json:"approvalPolicy"`
+	Augment        string `json:"augment"`
+	Cwd            string `json:"cwd"`
+}
+
+type promptParams struct {
+	Text        string            `json:"text"`
+	Attachments []attachmentParam `json:"attachments"`
+	// Model and ReasoningEffort override the session's for this prompt.
+	Model           string `json:"model"`
+	ReasoningEffort string `json:"reasoningEffort"`
+}
+
+// attachmentParam names a file to read (relative to the server's working
+// directory) or carries the attachment inline: base64 data for images, the
+// text itself otherwise.
+type attachmentParam struct {
+	Path string `json:"path"`
+	runtime.Attachment
+}
+
+// cancelParams also carries the reason for "pause".
+type cancelParams struct {
+	Reason string `json:"reason"`
+}
+
+type stdinParams struct {
+	StepID string `json:"stepId"`
+	Data   string `json:"data"`
+	EOF    bool   `json:"eof"`
+}
+
+type approveParams struct {
+	StepID   string `json:"stepId"`
+	Approved bool   `json:"approved"`
+	Reason   string `json:"reason"`
+}
+
+// Server reads requests from in, drives a single runtime, and writes
+// responses and event notifications to out.
+type Server struct {
+	in  io.Reader
+	out io.Writer
+
+	// base holds host-level options (logging, history path). initialize
+	// params override the model-related fields; an empty APIKey is read
+	// from the provider's environment variable.
+	base runtime.RuntimeOptions
+
+	writeMu sync.Mutex
+	agent   *runtime.Runtime
+	done    chan struct{}
+}
+
+// New configures a server that creates its runtime from base on initialize.
+func New(in io.Reader, out io.Writer, base runtime.RuntimeOptions) *Server {
+	return &Server{
+		in:   in,
+		out:  out,
+		base: base,
+	}
+}
+
+// Serve processes requests until stdin closes, shutdown is requested, or ctx
+// is cancelled. It returns nil on a clean exit.
+func (s *Server) Serve(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	scanner := bufio.NewScanner(s.in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if stop := s.handleLine(ctx, line); stop {
+			break
+		}
+	}
+	s.stopAgent()
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("agentserver: read: %w", err)
+	}
+	return nil
+}
+
+// handleLine dispatches one request and reports whether the server should stop.
+func (s *Server) handleLine(ctx context.Context, line string) bool {
+	var req request
+	if err := json.Unmarshal([]byte(line), &req); err != nil {
+		s.reply(nil, nil, &rpcError{Code: codeParseError, Message: err.Error()})
+		return false
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		s.reply(req.ID, nil, &rpcError{Code: codeInvalidRequest, Message: "expected a JSON-RPC 2.0 request with a method"})
+		return false
+	}
+
+	result, rpcErr := s.dispatch(ctx, req)
+	// Requests without an id are notifications and never get a response.
+	if len(req.ID) > 0 {
+		s.reply(req.ID, result, rpcErr)
+	}
+	return req.Method == "shutdown" && rpcErr == nil
+}
+
+func (s *Server) dispatch(ctx context.Context, req request) (any, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		var params initializeParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.initialize(ctx, params)
+	case "prompt":
+		var params promptParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(params.Text) == "" {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "text is required"}
+		}
+		attachments, rpcErr := loadAttachments(params.Attachments)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		agent, err := s.requireAgent()
+		if err != nil {
+			return nil, err
+		}
+		agent.SubmitPromptWithOverride(params.Text, attachments, runtime.ModelOverride{Model: params.Model, ReasoningEffort: params.ReasoningEffort})
+		return map[string]any{"accepted": true}, nil
+	case "cancel":
+		var params cancelParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		agent, err := s.requireAgent()
+		if err != nil {
+			return nil, err
+		}
+		agent.Cancel(params.Reason)
+		return map[string]any{}, nil
+	case "pause":
+		var params cancelParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		agent, err := s.requireAgent()
+		if err != nil {
+			return nil, err
+		}
+		agent.Pause(params.Reason)
+		return map[string]any{}, nil
+	case "resume":
+		agent, err := s.requireAgent()
+		if err != nil {
+			return nil, err
+		}
+		agent.Resume()
+		return map[string]any{}, nil
+	case "approve":
+		var params approveParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		agent, err := s.requireAgent()
+		if err != nil {
+			return nil, err
+		}
+		agent.SubmitApproval(params.StepID, params.Approved, params.Reason)
+		return map[string]any{}, nil
+	case "stdin":
+		var params stdinParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		agent, err := s.requireAgent()
+		if err != nil {
+			return nil, err
+		}
+		if err := agent.SubmitCommandStdin(params.StepID, params.Data, params.EOF); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		return map[string]any{}, nil
+	case "shutdown":
+		s.stopAgent()
+		return map[string]any{}, nil
+	default:
+		return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+	}
+}
+
+func (s *Server) initialize(ctx context.Context, params initializeParams) (any, *rpcError) {
+	if s.agent != nil {
+		return nil, &rpcError{Code: codeInvalidRequest, Message: "already initialized"}
+	}
+	if cwd := strings.TrimSpace(params.Cwd); cwd != "" {
+		if err := os.Chdir(cwd); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+	}
+
+	options := s.base
+	if params.Model != "" {
+		options.Model = params.Model
+	}
+	if params.Provider != "" {
+		options.Provider = params.Provider
+	}
+	if params.ApprovalPolicy != "" {
+		options.ApprovalPolicy = runtime.ApprovalPolicy(params.ApprovalPolicy)
+	}
+	if params.Augment != "" {
+		options.SystemPromptAugment = params.Augment
+	}
+	if options.APIKey == "" {
+		// Fall back to the environment key for whichever provider initialize selected.
+		env := "OPENAI_API_KEY"
+		if runtime.ResolveProvider(options.Provider, options.Model) == runtime.ProviderAnthropic {
+			env = "ANTHROPIC_API_KEY"
+		}
+		options.APIKey = os.Getenv(env)
+	}
+	options.DisableInputReader = true
+	options.DisableOutputForwarding = true
+	options.UseStreaming = true
+
+	agent, err := runtime.NewRuntime(options)
+	if err != nil {
+		return nil, &rpcError{Code: codeInternalError, Message: err.Error()}
+	}
+	s.agent = agent
+	s.done = make(chan struct{})
+
+	go func() {
+		for evt := range agent.Outputs() {
+			s.write(notification{JSONRPC: "2.0", Method: "event", Params: evt})
+		}
+		close(s.done)
+	}()
+	go func() { _ = agent.Run(ctx) }()
+
+	return map[string]any{
+		"protocolVersion": ProtocolVersion,
+		"serverInfo":      map[string]any{"name": "goagent"},
+		"capabilities": map[string]any{
+			"methods": []string{"initialize", "prompt", "cancel", "pause", "resume", "approve", "stdin", "shutdown"},
+			"events":  true,
+		},
+	}, nil
+}
+
+func (s *Server) requireAgent() (*runtime.Runtime, *rpcError) {
+	if s.agent == nil {
+		return nil, &rpcError{Code: codeInvalidRequest, Message: "initialize must be called first"}
+	}
+	return s.agent, nil
+}
+
+// stopAgent requests a runtime shutdown and waits for the remaining events to
+// be flushed as notifications.
+func (s *Server) stopAgent() {
+	if s.agent == nil {
+		return
+	}
+	s.agent.Shutdown("agent-server shutdown")
+	<-s.done
+	s.agent = nil
+}
+
+func (s *Server) reply(id json.RawMessage, result any, rpcErr *rpcError) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	resp := response{JSONRPC: "2.0", ID: id, Error: rpcErr}
+	if rpcErr == nil {
+		resp.Result = result
+	}
+	s.write(resp)
+}
+
+func (s *Server) write(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_, _ = s.out.Write(append(data, '\n'))
+}
+
+func decodeParams(raw json.RawMessage, dst any) *rpcError {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func loadAttachments(params []attachmentParam) ([]runtime.Attachment, *rpcError) {
+	attachments := make([]runtime.Attachment, 0, len(params))
+	for _, param := range params {
+		attachment := param.Attachment
+		if path := strings.TrimSpace(param.Path); path != "" {
+			loaded, err := runtime.LoadAttachment(path)
+			if err != nil {
+				return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+			}
+			attachment = loaded
+		}
+		if err := attachment.Validate(); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
@@ -0,0 +1,82 @@
+package agentserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asynkron/goagent/internal/core/runtime"
+)
+
+func TestServeHandlesLifecycle(t *testing.T) {
+	t.Parallel()
+
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"prompt","params":{"text":"hi"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"initialize","params":{"model":"gpt-4.1"}}`,
+		`not json`,
+		`{"jsonrpc":"2.0","id":3,"method":"bogus"}`,
+		`{"jsonrpc":"2.0","id":5,"method":"prompt","params":{"text":"see","attachments":[{"name":"shot.png","media_type":"image/png","data":"not base64!"}]}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"shutdown"}`,
+	}, "\n")
+
+	var out bytes.Buffer
+	historyPath := ""
+	server := New(strings.NewReader(input), &out, runtime.RuntimeOptions{
+		APIKey:           "test-key",
+		HistoryLogPath:   &historyPath,
+		EventLogPath:     &historyPath,
+		DisableSnapshots: true,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Serve(ctx); err != nil {
+		t.Fatalf("Serve returned error: %v", err)
+	}
+
+	responses := map[string]map[string]any{}
+	var parseErrors int
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var msg map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatalf("server wrote invalid JSON %q: %v", scanner.Text(), err)
+		}
+		if msg["method"] == "event" {
+			continue
+		}
+		id, _ := json.Marshal(msg["id"])
+		if string(id) == "null" {
+			parseErrors++
+			continue
+		}
+		responses[string(id)] = msg
+	}
+
+	if parseErrors != 1 {
+		t.Fatalf("expected one parse error response, got %d", parseErrors)
+	}
+	if responses["1"]["error"] == nil {
+		t.Fatalf("expected prompt before initialize to fail, got %v", responses["1"])
+	}
+	result, _ := responses["2"]["result"].(map[string]any)
+	if result["protocolVersion"] != float64(ProtocolVersion) {
+		t.Fatalf("unexpected initialize result: %v", responses["2"])
+	}
+	rpcErr, _ := responses["3"]["error"].(map[string]any)
+	if rpcErr["code"] != float64(codeMethodNotFound) {
+		t.Fatalf("expected method not found, got %v", responses["3"])
+	}
+	rpcErr, _ = responses["5"]["error"].(map[string]any)
+	if rpcErr["code"] != float64(codeInvalidParams) {
+		t.Fatalf("expected invalid attachment to be rejected, got %v", responses["5"])
+	}
+	if responses["4"]["error"] != nil {
+		t.Fatalf("expected shutdown to succeed, got %v", responses["4"])
+	}
+}
@@ -7,7 +7,13 @@ package bootprobe
 // helper in the bootprobe package means callers can import it from a single
 // place without having to remember to compile additional files manually.
 func BuildAugmentation(ctx *Context, userAugment string) (Result, string, string) {
-	result := Run(ctx)
+	return BuildAugmentationWithOptions(ctx, userAugment, Options{})
+}
+
+// BuildAugmentationWithOptions is BuildAugmentation running only the probes
+// enabled by opts.
+func BuildAugmentationWithOptions(ctx *Context, userAugment string, opts Options) (Result, string, string) {
+	result := RunWithOptions(ctx, opts)
 	summary := FormatSummary(result)
 	combined := CombineAugmentation(summary, userAugment)
 	return result, summary, combined
@@ -1,14 +1,30 @@
 package bootprobe
 
 import (
+	"context"
 	"errors"
 	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 )
 
+// DefaultCommandTimeout bounds each command a probe runs, such as
+// `node --version`, so a broken toolchain cannot stall startup.
+const DefaultCommandTimeout = 5 * time.Second
+
+// CommandRunner executes the resolved command path with args and returns its
+// combined output.
+type CommandRunner func(ctx context.Context, path string, args ...string) (string, error)
+
+func execCommand(ctx context.Context, path string, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, path, args...).CombinedOutput()
+	return string(out), err
+}
+
 // Context provides helper methods for inspecting the repository root and
 // interrogating the current execution environment. The helpers are intentionally
 // lightweight so that unit tests can supply fixture directories and a custom
@@ -16,6 +32,12 @@ import (
 type Context struct {
 	root     string
 	lookPath func(string) (string, error)
+	run      CommandRunner
+	timeout  time.Duration
+
+	versionsMu sync.Mutex
+	// versions caches CommandVersion results, including misses.
+	versions map[string]string
 }
 
 // NewContext constructs a Context rooted at the provided path. Commands are
@@ -24,6 +46,8 @@ func NewContext(root string) *Context {
 	return &Context{
 		root:     root,
 		lookPath: exec.LookPath,
+		run:      execCommand,
+		timeout:  DefaultCommandTimeout,
 	}
 }
 
@@ -38,6 +62,17 @@ func NewContextWithLookPath(root string, lookPath func(string) (string, error))
 	return ctx
 }
 
+// NewContextWithRunner additionally replaces command execution so tests can
+// return canned output, e.g. for version probes. Nil arguments keep the
+// defaults.
+func NewContextWithRunner(root string, lookPath func(string) (string, error), run CommandRunner) *Context {
+	ctx := NewContextWithLookPath(root, lookPath)
+	if run != nil {
+		ctx.run = run
+	}
+	return ctx
+}
+
 // Root returns the root directory that probes should inspect.
 func (c *Context) Root() string {
 	return c.root
@@ -108,9 +143,35 @@ func (c *Context) RunCommandOutput(name string, args ...string) (string, error)
 	if err != nil {
 		return "", err
 	}
-	cmd := exec.Command(path, args...)
-	out, err := cmd.CombinedOutput()
-	return string(out), err
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	return c.run(ctx, path, args...)
+}
+
+// CommandVersion runs the command's version flag (see versionArgs) and
+// returns the version number it reports, e.g. "20.11.1" for node. It returns
+// an empty string for unknown or unavailable commands and unparsable output.
+// Results are cached per Context.
+func (c *Context) CommandVersion(name string) string {
+	args, ok := versionArgs[name]
+	if !ok {
+		return ""
+	}
+
+	c.versionsMu.Lock()
+	defer c.versionsMu.Unlock()
+	if version, ok := c.versions[name]; ok {
+		return version
+	}
+	version := ""
+	if out, err := c.RunCommandOutput(name, args...); err == nil {
+		version = parseVersion(out)
+	}
+	if c.versions == nil {
+		c.versions = make(map[string]string)
+	}
+	c.versions[name] = version
+	return version
 }
 
 // FindFirstWithSuffix walks the repository looking for a file with any of the
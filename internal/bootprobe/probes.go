@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
@@ -15,88 +16,124 @@ import (
 // implementation and captures the detected capabilities of the current project
 // and execution environment.
 type Result struct {
-	Node       *NodeProbeResult
-	Python     *PythonProbeResult
-	DotNet     *SimpleProbeResult
-	Go         *SimpleProbeResult
-	Rust       *RustProbeResult
-	JVM        *JVMProbeResult
-	Git        *SimpleProbeResult
-	Containers []ContainerProbeResult
-	Linters    []ToolingProbeResult
-	Formatters []ToolingProbeResult
-	OS         OSResult
-	Shell      ShellProbeResult
-}
-
-// CommandStatus records whether a particular command is available on PATH.
+	Node       *NodeProbeResult       `json:"node,omitempty"`
+	Python     *PythonProbeResult     `json:"python,omitempty"`
+	DotNet     *SimpleProbeResult     `json:"dotnet,omitempty"`
+	Go         *SimpleProbeResult     `json:"go,omitempty"`
+	Rust       *RustProbeResult       `json:"rust,omitempty"`
+	JVM        *JVMProbeResult        `json:"jvm,omitempty"`
+	Git        *SimpleProbeResult     `json:"git,omitempty"`
+	Containers []ContainerProbeResult `json:"containers,omitempty"`
+	Linters    []ToolingProbeResult   `json:"linters,omitempty"`
+	Formatters []ToolingProbeResult   `json:"formatters,omitempty"`
+	// Tasks lists the task runners and their task names.
+	Tasks []TaskRunnerResult `json:"tasks,omitempty"`
+	CI    []CIResult         `json:"ci,omitempty"`
+	// Tests lists the detected test frameworks.
+	Tests []TestFramework `json:"tests,omitempty"`
+	// Workspace lists the projects nested below the root, if any.
+	Workspace []SubProject `json:"workspace,omitempty"`
+	// Probes holds the results of probes added with Register.
+	Probes []ProbeResult    `json:"probes,omitempty"`
+	OS     OSResult         `json:"os"`
+	Shell  ShellProbeResult `json:"shell"`
+}
+
+// CommandStatus records whether a particular command is available on PATH
+// and, for toolchain commands, the version it reports.
 type CommandStatus struct {
-	Name      string
-	Available bool
+	Name      string `json:"name"`
+	Available bool   `json:"available"`
+	Version   string `json:"version,omitempty"`
+}
+
+// versionArgs lists the commands whose version is detected and the arguments
+// that print it. java writes its version to stderr, which is captured too.
+var versionArgs = map[string][]string{
+	"node":    {"--version"},
+	"npm":     {"--version"},
+	"pnpm":    {"--version"},
+	"yarn":    {"--version"},
+	"python3": {"--version"},
+	"python":  {"--version"},
+	"dotnet":  {"--version"},
+	"go":      {"version"},
+	"cargo":   {"--version"},
+	"rustc":   {"--version"},
+	"java":    {"-version"},
+	"docker":  {"--version"},
+	"podman":  {"--version"},
+}
+
+// versionPattern matches the first dotted version number in tool output.
+var versionPattern = regexp.MustCompile(`\d+(?:\.\d+)+`)
+
+func parseVersion(output string) string {
+	return versionPattern.FindString(output)
 }
 
 // SimpleProbeResult captures a boolean detection and supporting indicators for
 // a tooling family.
 type SimpleProbeResult struct {
-	Detected   bool
-	Indicators []string
-	Commands   []CommandStatus
+	Detected   bool            `json:"detected"`
+	Indicators []string        `json:"indicators,omitempty"`
+	Commands   []CommandStatus `json:"commands,omitempty"`
 }
 
 // NodeProbeResult captures information about a JavaScript/TypeScript project.
 type NodeProbeResult struct {
-	Detected        bool
-	Indicators      []string
-	Commands        []CommandStatus
-	HasTypeScript   bool
-	HasJavaScript   bool
-	PackageManagers []string
+	Detected        bool            `json:"detected"`
+	Indicators      []string        `json:"indicators,omitempty"`
+	Commands        []CommandStatus `json:"commands,omitempty"`
+	HasTypeScript   bool            `json:"has_typescript"`
+	HasJavaScript   bool            `json:"has_javascript"`
+	PackageManagers []string        `json:"package_managers,omitempty"`
 }
 
 // PythonProbeResult captures Python specific metadata.
 type PythonProbeResult struct {
-	Detected   bool
-	Indicators []string
-	Commands   []CommandStatus
-	UsesPoetry bool
-	UsesPipenv bool
+	Detected   bool            `json:"detected"`
+	Indicators []string        `json:"indicators,omitempty"`
+	Commands   []CommandStatus `json:"commands,omitempty"`
+	UsesPoetry bool            `json:"uses_poetry"`
+	UsesPipenv bool            `json:"uses_pipenv"`
 }
 
 // RustProbeResult captures Rust specific metadata.
 type RustProbeResult struct {
-	Detected   bool
-	Indicators []string
-	Commands   []CommandStatus
+	Detected   bool            `json:"detected"`
+	Indicators []string        `json:"indicators,omitempty"`
+	Commands   []CommandStatus `json:"commands,omitempty"`
 }
 
 // JVMProbeResult captures information about JVM build tooling.
 type JVMProbeResult struct {
-	Detected   bool
-	Indicators []string
-	Commands   []CommandStatus
-	BuildTools []string
+	Detected   bool            `json:"detected"`
+	Indicators []string        `json:"indicators,omitempty"`
+	Commands   []CommandStatus `json:"commands,omitempty"`
+	BuildTools []string        `json:"build_tools,omitempty"`
 }
 
 // ContainerProbeResult describes container configuration or tooling.
 type ContainerProbeResult struct {
-	Detected   bool
-	Indicators []string
-	Commands   []CommandStatus
-	Runtime    string
+	Detected   bool            `json:"detected"`
+	Indicators []string        `json:"indicators,omitempty"`
+	Commands   []CommandStatus `json:"commands,omitempty"`
+	Runtime    string          `json:"runtime,omitempty"`
 }
 
 // ToolingProbeResult captures formatter or linter tools.
 type ToolingProbeResult struct {
-	Name       string
-	Indicators []string
-	Commands   []CommandStatus
+	Name       string          `json:"name"`
+	Indicators []string        `json:"indicators,omitempty"`
+	Commands   []CommandStatus `json:"commands,omitempty"`
 }
 
 // OSResult summarises the host operating system and architecture.
 type OSResult struct {
-	GOOS         string
-	GOARCH       string
-	Distribution string
+	GOOS         string `json:"goos"`
+	GOARCH       string `json:"goarch"`
+	Distribution string `json:"distribution,omitempty"`
 }
 
 // ShellProbeResult summarises the user's shells.
@@ -104,27 +141,67 @@ type OSResult struct {
 // Current: the parent process shell of the CLI invocation (e.g. zsh, bash, fish).
 // Source: how Default was determined (dscl, getent, passwd, env).
 type ShellProbeResult struct {
-	Default string
-	Current string
-	Source  string
+	Default string `json:"default,omitempty"`
+	Current string `json:"current,omitempty"`
+	Source  string `json:"source,omitempty"`
 }
 
 // Run executes all boot probes and returns a consolidated result structure.
 func Run(ctx *Context) Result {
-	return Result{
-		Node:       runNodeProbe(ctx),
-		Python:     runPythonProbe(ctx),
-		DotNet:     runDotNetProbe(ctx),
-		Go:         runGoProbe(ctx),
-		Rust:       runRustProbe(ctx),
-		JVM:        runJVMProbe(ctx),
-		Git:        runGitProbe(ctx),
-		Containers: runContainerProbes(ctx),
-		Linters:    runLintProbes(ctx),
-		Formatters: runFormatterProbes(ctx),
-		OS:         detectOS(),
-		Shell:      detectShell(ctx),
+	return RunWithOptions(ctx, Options{})
+}
+
+// RunWithOptions executes the probes enabled by opts: the built-in ones
+// followed by those added with Register.
+func RunWithOptions(ctx *Context, opts Options) Result {
+	result := Result{
+		OS:    detectOS(),
+		Shell: detectShell(ctx),
+	}
+	if opts.enabled(ProbeNode) {
+		result.Node = runNodeProbe(ctx)
+	}
+	if opts.enabled(ProbePython) {
+		result.Python = runPythonProbe(ctx)
+	}
+	if opts.enabled(ProbeDotNet) {
+		result.DotNet = runDotNetProbe(ctx)
+	}
+	if opts.enabled(ProbeGo) {
+		result.Go = runGoProbe(ctx)
+	}
+	if opts.enabled(ProbeRust) {
+		result.Rust = runRustProbe(ctx)
 	}
+	if opts.enabled(ProbeJVM) {
+		result.JVM = runJVMProbe(ctx)
+	}
+	if opts.enabled(ProbeGit) {
+		result.Git = runGitProbe(ctx)
+	}
+	if opts.enabled(ProbeContainers) {
+		result.Containers = runContainerProbes(ctx)
+	}
+	if opts.enabled(ProbeLinters) {
+		result.Linters = runLintProbes(ctx)
+	}
+	if opts.enabled(ProbeFormatters) {
+		result.Formatters = runFormatterProbes(ctx)
+	}
+	if opts.enabled(ProbeTasks) {
+		result.Tasks = runTaskRunnerProbes(ctx)
+	}
+	if opts.enabled(ProbeCI) {
+		result.CI = runCIProbes(ctx)
+	}
+	if opts.enabled(ProbeTests) {
+		result.Tests = DetectTestFrameworks(ctx)
+	}
+	if opts.enabled(ProbeWorkspace) {
+		result.Workspace = runWorkspaceProbe(ctx, opts.WorkspaceDepth)
+	}
+	result.Probes = runRegisteredProbes(ctx, opts)
+	return result
 }
 
 func runNodeProbe(ctx *Context) *NodeProbeResult {
@@ -591,10 +668,14 @@ func collectExistingFiles(ctx *Context, files []string) []string {
 func commandStatuses(ctx *Context, commands ...string) []CommandStatus {
 	statuses := make([]CommandStatus, 0, len(commands))
 	for _, cmd := range commands {
-		statuses = append(statuses, CommandStatus{
+		status := CommandStatus{
 			Name:      cmd,
 			Available: ctx.CommandExists(cmd),
-		})
+		}
+		if status.Available {
+			status.Version = ctx.CommandVersion(cmd)
+		}
+		statuses = append(statuses, status)
 	}
 	return statuses
 }
@@ -621,6 +702,23 @@ func availableCommandNames(commands []CommandStatus, names ...string) []string {
 	return available
 }
 
+// availableCommandLabels names the available commands with their detected
+// version, e.g. "node 20.11.1", for the summary lines.
+func availableCommandLabels(commands []CommandStatus) []string {
+	var labels []string
+	for _, cmd := range commands {
+		if !cmd.Available {
+			continue
+		}
+		if cmd.Version != "" {
+			labels = append(labels, cmd.Name+" "+cmd.Version)
+			continue
+		}
+		labels = append(labels, cmd.Name)
+	}
+	return labels
+}
+
 func dedupeStrings(values []string) []string {
 	seen := make(map[string]struct{}, len(values))
 	var result []string
@@ -685,7 +783,21 @@ func bytesContainsAny(data []byte, needles []string) bool {
 
 // HasCapabilities reports whether any tooling was detected.
 func (r Result) HasCapabilities() bool {
-	return r.Node != nil || r.Python != nil || r.DotNet != nil || r.Go != nil || r.Rust != nil || r.JVM != nil || r.Git != nil || len(r.Containers) > 0 || len(r.Linters) > 0 || len(r.Formatters) > 0
+	return r.Node != nil || r.Python != nil || r.DotNet != nil || r.Go != nil || r.Rust != nil || r.JVM != nil || r.Git != nil || len(r.Containers) > 0 || len(r.Linters) > 0 || len(r.Formatters) > 0 || len(r.Tasks) > 0 || len(r.CI) > 0 || len(r.Tests) > 0 || len(r.Probes) > 0 || len(r.Workspace) > 0
+}
+
+// ContainerRuntime returns the first container CLI (docker, podman, nerdctl)
+// found on PATH by the container probes, or an empty string when none is
+// available.
+func (r Result) ContainerRuntime() string {
+	for _, container := range r.Containers {
+		for _, command := range container.Commands {
+			if command.Available {
+				return command.Name
+			}
+		}
+	}
+	return ""
 }
 
 // SummaryLines returns the human-readable bullet lines describing the detected
@@ -725,6 +837,21 @@ func (r Result) SummaryLines() []string {
 	if len(r.Formatters) > 0 {
 		lines = append(lines, formatToolSummary("Formatters", r.Formatters))
 	}
+	if len(r.Tasks) > 0 {
+		lines = append(lines, formatTaskRunnerSummary(r.Tasks))
+	}
+	if len(r.CI) > 0 {
+		lines = append(lines, formatCISummary(r.CI))
+	}
+	if len(r.Tests) > 0 {
+		lines = append(lines, formatTestFrameworkSummary(r.Tests))
+	}
+	for _, probe := range r.Probes {
+		lines = append(lines, formatProbeSummary(probe))
+	}
+	if len(r.Workspace) > 0 {
+		lines = append(lines, formatWorkspaceSummary(r.Workspace))
+	}
 
 	return lines
 }
@@ -803,7 +930,7 @@ func formatNodeSummary(result NodeProbeResult) string {
 	if len(result.PackageManagers) > 0 {
 		details = append(details, "pkg mgrs: "+strings.Join(result.PackageManagers, ", "))
 	}
-	available := availableCommandNames(result.Commands)
+	available := availableCommandLabels(result.Commands)
 	if len(available) > 0 {
 		details = append(details, "commands: "+strings.Join(available, ", "))
 	}
@@ -818,7 +945,7 @@ func formatJVMSummary(result JVMProbeResult) string {
 	if len(result.BuildTools) > 0 {
 		details = append(details, "build: "+strings.Join(result.BuildTools, ", "))
 	}
-	available := availableCommandNames(result.Commands)
+	available := availableCommandLabels(result.Commands)
 	if len(available) > 0 {
 		details = append(details, "commands: "+strings.Join(available, ", "))
 	}
@@ -834,7 +961,7 @@ func formatContainerSummary(result ContainerProbeResult) string {
 	if len(result.Indicators) > 0 {
 		details = append(details, strings.Join(result.Indicators, ", "))
 	}
-	available := availableCommandNames(result.Commands)
+	available := availableCommandLabels(result.Commands)
 	if len(available) > 0 {
 		details = append(details, "commands: "+strings.Join(available, ", "))
 	}
@@ -854,7 +981,7 @@ func formatSimpleSummary(title string, indicators []string, commands []CommandSt
 	if len(indicators) > 0 {
 		details = append(details, strings.Join(indicators, ", "))
 	}
-	available := availableCommandNames(commands)
+	available := availableCommandLabels(commands)
 	if len(available) > 0 {
 		details = append(details, "commands: "+strings.Join(available, ", "))
 	}
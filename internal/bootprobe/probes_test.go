@@ -1,6 +1,8 @@
 package bootprobe
 
 import (
+	"context"
+	"encoding/json"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -115,6 +117,21 @@ line-length = 88
 	require.True(t, strings.HasPrefix(summary, "OS:"))
 }
 
+func TestResultMarshalsWithSnakeCaseKeys(t *testing.T) {
+	result := Result{
+		Node: &NodeProbeResult{Detected: true, HasTypeScript: true, PackageManagers: []string{"pnpm"}},
+		OS:   OSResult{GOOS: "linux", GOARCH: "amd64"},
+	}
+
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"node": {"detected": true, "has_typescript": true, "has_javascript": false, "package_managers": ["pnpm"]},
+		"os": {"goos": "linux", "goarch": "amd64"},
+		"shell": {}
+	}`, string(data))
+}
+
 func TestCombineAugmentation(t *testing.T) {
 	summary := "OS: linux/amd64\n- Tooling"
 	combined := CombineAugmentation(summary, "user notes")
@@ -131,3 +148,42 @@ func mustWriteFile(t *testing.T, dir, name, contents string) {
 	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
 	require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
 }
+
+func TestRunDetectsCommandVersions(t *testing.T) {
+	dir := t.TempDir()
+	mustWriteFile(t, dir, "package.json", "{}")
+
+	lookup := func(name string) (string, error) {
+		if name == "node" || name == "java" {
+			return "/usr/bin/" + name, nil
+		}
+		return "", exec.ErrNotFound
+	}
+	calls := map[string]int{}
+	run := func(_ context.Context, path string, args ...string) (string, error) {
+		calls[path]++
+		switch path {
+		case "/usr/bin/node":
+			return "v20.11.1\n", nil
+		case "/usr/bin/java":
+			return "openjdk version \"21.0.2\" 2024-01-16\n", nil
+		}
+		return "", exec.ErrNotFound
+	}
+	ctx := NewContextWithRunner(dir, lookup, run)
+
+	result := Run(ctx)
+	require.Equal(t, []CommandStatus{
+		{Name: "node", Available: true, Version: "20.11.1"},
+		{Name: "npm", Available: false},
+		{Name: "pnpm", Available: false},
+		{Name: "yarn", Available: false},
+		{Name: "npx", Available: false},
+	}, result.Node.Commands)
+	require.Contains(t, FormatSummary(result), "commands: node 20.11.1")
+
+	require.Equal(t, "21.0.2", ctx.CommandVersion("java"))
+	require.Equal(t, "20.11.1", ctx.CommandVersion("node"))
+	require.Equal(t, 1, calls["/usr/bin/node"], "versions should be cached")
+	require.Empty(t, ctx.CommandVersion("eslint"))
+}
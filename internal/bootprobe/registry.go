@@ -0,0 +1,174 @@
+package bootprobe
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ProbeFunc inspects ctx for one tool or stack. It returns nil when nothing
+// was detected.
+type ProbeFunc func(ctx *Context) *ProbeResult
+
+// ProbeResult is what a registered probe reports. Title labels its summary
+// line (defaulting to the probe name) and Details holds probe specific values
+// for the JSON output.
+type ProbeResult struct {
+	Name       string          `json:"name"`
+	Title      string          `json:"title,omitempty"`
+	Indicators []string        `json:"indicators,omitempty"`
+	Commands   []CommandStatus `json:"commands,omitempty"`
+	Details    map[string]any  `json:"details,omitempty"`
+}
+
+// Built-in probe names, usable in Options.Disable and Options.Only.
+const (
+	ProbeNode       = "node"
+	ProbePython     = "python"
+	ProbeDotNet     = "dotnet"
+	ProbeGo         = "go"
+	ProbeRust       = "rust"
+	ProbeJVM        = "jvm"
+	ProbeGit        = "git"
+	ProbeContainers = "containers"
+	ProbeLinters    = "linters"
+	ProbeFormatters = "formatters"
+	ProbeWorkspace  = "workspace"
+	ProbeTasks      = "tasks"
+	ProbeCI         = "ci"
+	ProbeTests      = "tests"
+)
+
+var builtinProbes = []string{
+	ProbeNode, ProbePython, ProbeDotNet, ProbeGo, ProbeRust, ProbeJVM,
+	ProbeGit, ProbeContainers, ProbeLinters, ProbeFormatters, ProbeWorkspace,
+	ProbeTasks, ProbeCI, ProbeTests,
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]ProbeFunc{}
+)
+
+// Register adds a probe that Run executes after the built-in ones, in name
+// order. It is meant to be called from init functions and panics when name
+// is empty, already registered (including built-in names) or probe is nil.
+func Register(name string, probe ProbeFunc) {
+	name = normalizeProbeName(name)
+	if name == "" {
+		panic("bootprobe: Register with empty name")
+	}
+	if probe == nil {
+		panic(fmt.Sprintf("bootprobe: Register probe %q with nil func", name))
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[name]; dup || isBuiltinProbe(name) {
+		panic(fmt.Sprintf("bootprobe: probe %q registered twice", name))
+	}
+	registry[name] = probe
+}
+
+// Unregister removes a probe added with Register. Built-in probes cannot be
+// removed; disable them with Options instead.
+func Unregister(name string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	delete(registry, normalizeProbeName(name))
+}
+
+// ProbeNames lists the built-in probes followed by the registered ones.
+func ProbeNames() []string {
+	names := append([]string(nil), builtinProbes...)
+	return append(names, registeredProbeNames()...)
+}
+
+func registeredProbeNames() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupProbe(name string) ProbeFunc {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return registry[name]
+}
+
+func isBuiltinProbe(name string) bool {
+	for _, builtin := range builtinProbes {
+		if builtin == name {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeProbeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Options selects which probes run. The OS and shell are always detected.
+type Options struct {
+	// Disable skips the named probes.
+	Disable []string
+	// Only, when non-empty, runs just the named probes.
+	Only []string
+	// WorkspaceDepth bounds how deep the workspace probe looks for nested
+	// projects; zero means DefaultWorkspaceDepth.
+	WorkspaceDepth int
+}
+
+func (o Options) enabled(name string) bool {
+	for _, disabled := range o.Disable {
+		if normalizeProbeName(disabled) == name {
+			return false
+		}
+	}
+	if len(o.Only) == 0 {
+		return true
+	}
+	for _, only := range o.Only {
+		if normalizeProbeName(only) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// runRegisteredProbes runs the enabled registered probes in name order.
+func runRegisteredProbes(ctx *Context, opts Options) []ProbeResult {
+	var results []ProbeResult
+	for _, name := range registeredProbeNames() {
+		if !opts.enabled(name) {
+			continue
+		}
+		probe := lookupProbe(name)
+		if probe == nil {
+			continue
+		}
+		result := probe(ctx)
+		if result == nil {
+			continue
+		}
+		result.Name = name
+		result.Indicators = dedupeStrings(result.Indicators)
+		results = append(results, *result)
+	}
+	return results
+}
+
+func formatProbeSummary(result ProbeResult) string {
+	title := result.Title
+	if title == "" {
+		title = result.Name
+	}
+	return formatSimpleSummary(title, result.Indicators, result.Commands)
+}
@@ -0,0 +1,56 @@
+package bootprobe
+
+import (
+	"encoding/json"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisteredProbesMergeIntoResult(t *testing.T) {
+	Register("terraform", func(ctx *Context) *ProbeResult {
+		if !ctx.HasFile("main.tf") {
+			return nil
+		}
+		return &ProbeResult{
+			Title:      "Terraform",
+			Indicators: []string{"main.tf"},
+			Commands:   commandStatuses(ctx, "terraform"),
+			Details:    map[string]any{"backend": "s3"},
+		}
+	})
+	t.Cleanup(func() { Unregister("terraform") })
+	require.Panics(t, func() { Register("terraform", func(*Context) *ProbeResult { return nil }) })
+	require.Panics(t, func() { Register(ProbeGo, func(*Context) *ProbeResult { return nil }) })
+	require.Contains(t, ProbeNames(), "terraform")
+
+	dir := t.TempDir()
+	mustWriteFile(t, dir, "main.tf", `terraform {}`)
+	mustWriteFile(t, dir, "go.mod", "module example.com/demo")
+	ctx := NewContextWithLookPath(dir, func(name string) (string, error) {
+		if name == "terraform" {
+			return filepath.Join("/usr/bin", name), nil
+		}
+		return "", exec.ErrNotFound
+	})
+
+	result := Run(ctx)
+	require.NotNil(t, result.Go)
+	require.Len(t, result.Probes, 1)
+	require.Equal(t, "terraform", result.Probes[0].Name)
+	require.Contains(t, result.SummaryLines(), "Terraform (main.tf; commands: terraform)")
+
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"probes":[{"name":"terraform","title":"Terraform"`)
+
+	filtered := RunWithOptions(ctx, Options{Disable: []string{"Terraform", ProbeGo}})
+	require.Nil(t, filtered.Go)
+	require.Empty(t, filtered.Probes)
+
+	only := RunWithOptions(ctx, Options{Only: []string{"terraform"}})
+	require.Nil(t, only.Go)
+	require.Len(t, only.Probes, 1)
+}
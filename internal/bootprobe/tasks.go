@@ -0,0 +1,264 @@
+package bootprobe
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxSummaryTasks caps the task and job names listed per summary entry; the
+// JSON result keeps all of them.
+const maxSummaryTasks = 15
+
+// TaskRunnerResult lists the tasks a task runner file defines, so the model
+// can run `make test` or `npm run lint` instead of inventing commands.
+type TaskRunnerResult struct {
+	// Name is the command that runs the tasks: make, task, just or npm.
+	Name  string   `json:"name"`
+	File  string   `json:"file"`
+	Tasks []string `json:"tasks,omitempty"`
+}
+
+// CIResult describes one CI system configured for the project.
+type CIResult struct {
+	Name  string   `json:"name"`
+	Files []string `json:"files"`
+	// Jobs lists the job names found in the configuration files.
+	Jobs []string `json:"jobs,omitempty"`
+}
+
+func runTaskRunnerProbes(ctx *Context) []TaskRunnerResult {
+	var results []TaskRunnerResult
+	for _, file := range []string{"Makefile", "makefile", "GNUmakefile"} {
+		if data, err := ctx.ReadFile(file); err == nil {
+			results = append(results, TaskRunnerResult{Name: "make", File: file, Tasks: parseMakeTargets(data)})
+			break
+		}
+	}
+	for _, file := range []string{"Taskfile.yml", "Taskfile.yaml", "taskfile.yml", "taskfile.yaml"} {
+		if data, err := ctx.ReadFile(file); err == nil {
+			results = append(results, TaskRunnerResult{Name: "task", File: file, Tasks: yamlSectionKeys(data, "tasks")})
+			break
+		}
+	}
+	for _, file := range []string{"justfile", "Justfile", ".justfile"} {
+		if data, err := ctx.ReadFile(file); err == nil {
+			results = append(results, TaskRunnerResult{Name: "just", File: file, Tasks: parseJustRecipes(data)})
+			break
+		}
+	}
+	if data, err := ctx.ReadFile("package.json"); err == nil {
+		if scripts := parsePackageScripts(data); len(scripts) > 0 {
+			results = append(results, TaskRunnerResult{Name: "npm", File: "package.json", Tasks: scripts})
+		}
+	}
+	return results
+}
+
+func runCIProbes(ctx *Context) []CIResult {
+	var results []CIResult
+
+	if workflows := listFiles(ctx, ".github/workflows", ".yml", ".yaml"); len(workflows) > 0 {
+		ci := CIResult{Name: "GitHub Actions", Files: workflows}
+		for _, file := range workflows {
+			if data, err := ctx.ReadFile(file); err == nil {
+				ci.Jobs = append(ci.Jobs, yamlSectionKeys(data, "jobs")...)
+			}
+		}
+		ci.Jobs = dedupeStrings(ci.Jobs)
+		results = append(results, ci)
+	}
+	if data, err := ctx.ReadFile(".gitlab-ci.yml"); err == nil {
+		results = append(results, CIResult{Name: "GitLab CI", Files: []string{".gitlab-ci.yml"}, Jobs: gitlabJobs(data)})
+	}
+	if data, err := ctx.ReadFile(".circleci/config.yml"); err == nil {
+		results = append(results, CIResult{Name: "CircleCI", Files: []string{".circleci/config.yml"}, Jobs: yamlSectionKeys(data, "jobs")})
+	}
+	if ctx.HasFile("azure-pipelines.yml") {
+		results = append(results, CIResult{Name: "Azure Pipelines", Files: []string{"azure-pipelines.yml"}})
+	}
+	if ctx.HasFile("Jenkinsfile") {
+		results = append(results, CIResult{Name: "Jenkins", Files: []string{"Jenkinsfile"}})
+	}
+	return results
+}
+
+// makeTargetPattern matches rule lines such as "test:" or "build lint: deps"
+// while skipping variable assignments ("CC := gcc") and special targets that
+// start with a dot.
+var makeTargetPattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9_./-]*(?:\s+[A-Za-z0-9][A-Za-z0-9_./-]*)*)\s*::?(?:[^=]|$)`)
+
+func parseMakeTargets(data []byte) []string {
+	var targets []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "\t") {
+			continue
+		}
+		match := makeTargetPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		for _, target := range strings.Fields(match[1]) {
+			if !strings.ContainsAny(target, "%/") {
+				targets = append(targets, target)
+			}
+		}
+	}
+	return dedupeStrings(targets)
+}
+
+// justRecipePattern matches recipe headers such as "test:", "@build target:"
+// or "deploy env='dev': build", but not settings like "set shell := [...]".
+var justRecipePattern = regexp.MustCompile(`^@?([A-Za-z_][A-Za-z0-9_-]*)(?:\s+[^:=]*)?:(?:[^=]|$)`)
+
+func parseJustRecipes(data []byte) []string {
+	var recipes []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '#' {
+			continue
+		}
+		if match := justRecipePattern.FindStringSubmatch(line); match != nil {
+			recipes = append(recipes, match[1])
+		}
+	}
+	return dedupeStrings(recipes)
+}
+
+func parsePackageScripts(data []byte) []string {
+	var manifest struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil
+	}
+	scripts := make([]string, 0, len(manifest.Scripts))
+	for name := range manifest.Scripts {
+		scripts = append(scripts, name)
+	}
+	sort.Strings(scripts)
+	return scripts
+}
+
+// gitlabReservedKeys are top-level .gitlab-ci.yml keywords that are not jobs.
+var gitlabReservedKeys = map[string]bool{
+	"default": true, "include": true, "stages": true, "variables": true,
+	"workflow": true, "image": true, "services": true, "cache": true,
+	"before_script": true, "after_script": true,
+}
+
+func gitlabJobs(data []byte) []string {
+	var jobs []string
+	for _, key := range yamlMappingKeys(data) {
+		if strings.HasPrefix(key, ".") || gitlabReservedKeys[key] {
+			continue
+		}
+		jobs = append(jobs, key)
+	}
+	return jobs
+}
+
+// yamlSectionKeys returns the keys of the top-level mapping named section, in
+// file order.
+func yamlSectionKeys(data []byte, section string) []string {
+	root := yamlRootMapping(data)
+	if root == nil {
+		return nil
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == section {
+			return mappingKeys(root.Content[i+1])
+		}
+	}
+	return nil
+}
+
+// yamlMappingKeys returns the top-level keys of a YAML document in file order.
+func yamlMappingKeys(data []byte) []string {
+	return mappingKeys(yamlRootMapping(data))
+}
+
+func yamlRootMapping(data []byte) *yaml.Node {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	return doc.Content[0]
+}
+
+func mappingKeys(node *yaml.Node) []string {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	keys := make([]string, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keys = append(keys, node.Content[i].Value)
+	}
+	return keys
+}
+
+// listFiles returns the files directly inside relDir with one of the given
+// extensions, as sorted paths relative to the root.
+func listFiles(ctx *Context, relDir string, exts ...string) []string {
+	entries, err := os.ReadDir(filepath.Join(ctx.Root(), relDir))
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		for _, candidate := range exts {
+			if ext == candidate {
+				files = append(files, relDir+"/"+entry.Name())
+				break
+			}
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+func formatTaskRunnerSummary(runners []TaskRunnerResult) string {
+	parts := make([]string, 0, len(runners))
+	for _, runner := range runners {
+		label := runner.Name + " (" + runner.File + ")"
+		if len(runner.Tasks) > 0 {
+			label += ": " + joinLimited(runner.Tasks, maxSummaryTasks)
+		}
+		parts = append(parts, label)
+	}
+	return "Task runners: " + strings.Join(parts, "; ")
+}
+
+func formatCISummary(systems []CIResult) string {
+	parts := make([]string, 0, len(systems))
+	for _, ci := range systems {
+		label := ci.Name
+		if len(ci.Jobs) > 0 {
+			label += " jobs: " + joinLimited(ci.Jobs, maxSummaryTasks)
+		}
+		parts = append(parts, label)
+	}
+	return "CI: " + strings.Join(parts, "; ")
+}
+
+func joinLimited(values []string, limit int) string {
+	if len(values) <= limit {
+		return strings.Join(values, ", ")
+	}
+	return fmt.Sprintf("%s, +%d more", strings.Join(values[:limit], ", "), len(values)-limit)
+}
@@ -0,0 +1,58 @@
+package bootprobe
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDetectsTaskRunnersAndCI(t *testing.T) {
+	dir := t.TempDir()
+	mustWriteFile(t, dir, "Makefile", `CC := gcc
+.PHONY: build test
+build: deps
+	go build ./...
+test lint:
+	go test ./...
+%.o: %.c
+	$(CC) -c $<
+`)
+	mustWriteFile(t, dir, "justfile", `set shell := ["bash", "-c"]
+alias t := test
+
+# run the tests
+test *args:
+    go test {{args}}
+
+@fmt:
+    gofmt -w .
+`)
+	mustWriteFile(t, dir, "Taskfile.yml", "version: '3'\ntasks:\n  generate:\n    cmds: [go generate]\n  release: {}\n")
+	mustWriteFile(t, dir, "package.json", `{"scripts": {"test": "vitest", "lint": "eslint ."}}`)
+	mustWriteFile(t, dir, ".github/workflows/ci.yml", "on: push\njobs:\n  build: {}\n  test: {}\n")
+	mustWriteFile(t, dir, ".github/workflows/release.yaml", "on: push\njobs:\n  publish: {}\n")
+	mustWriteFile(t, dir, ".gitlab-ci.yml", "stages: [test]\n.template: {}\nunit:\n  script: make test\n")
+
+	ctx := NewContextWithLookPath(dir, func(string) (string, error) { return "", exec.ErrNotFound })
+	result := RunWithOptions(ctx, Options{Only: []string{ProbeTasks, ProbeCI}})
+
+	require.Equal(t, []TaskRunnerResult{
+		{Name: "make", File: "Makefile", Tasks: []string{"build", "test", "lint"}},
+		{Name: "task", File: "Taskfile.yml", Tasks: []string{"generate", "release"}},
+		{Name: "just", File: "justfile", Tasks: []string{"test", "fmt"}},
+		{Name: "npm", File: "package.json", Tasks: []string{"lint", "test"}},
+	}, result.Tasks)
+	require.Equal(t, []CIResult{
+		{Name: "GitHub Actions", Files: []string{".github/workflows/ci.yml", ".github/workflows/release.yaml"}, Jobs: []string{"build", "test", "publish"}},
+		{Name: "GitLab CI", Files: []string{".gitlab-ci.yml"}, Jobs: []string{"unit"}},
+	}, result.CI)
+
+	summary := FormatSummary(result)
+	require.Contains(t, summary, "- Task runners: make (Makefile): build, test, lint; task (Taskfile.yml): generate, release;")
+	require.Contains(t, summary, "- CI: GitHub Actions jobs: build, test, publish; GitLab CI jobs: unit")
+}
+
+func TestJoinLimitedTruncates(t *testing.T) {
+	require.Equal(t, "a, b, +2 more", joinLimited([]string{"a", "b", "c", "d"}, 2))
+}
@@ -0,0 +1,113 @@
+package bootprobe
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Test framework names reported by DetectTestFrameworks.
+const (
+	TestFrameworkGo     = "go"
+	TestFrameworkPytest = "pytest"
+	TestFrameworkJest   = "jest"
+	TestFrameworkVitest = "vitest"
+	TestFrameworkCargo  = "cargo"
+	TestFrameworkDotNet = "dotnet"
+)
+
+// TestFramework is a test runner the project is set up for and the command
+// that runs its whole suite.
+type TestFramework struct {
+	Name       string   `json:"name"`
+	Command    string   `json:"command"`
+	Indicators []string `json:"indicators,omitempty"`
+}
+
+// DetectTestFrameworks reports the test tooling configured at the root, in
+// a stable order (go, cargo, dotnet, vitest, jest, pytest). The run_tests
+// internal command uses it to pick an invocation.
+func DetectTestFrameworks(ctx *Context) []TestFramework {
+	var frameworks []TestFramework
+	if ctx.HasFile("go.mod") {
+		frameworks = append(frameworks, TestFramework{Name: TestFrameworkGo, Command: "go test ./...", Indicators: []string{"go.mod"}})
+	}
+	if ctx.HasFile("Cargo.toml") {
+		frameworks = append(frameworks, TestFramework{Name: TestFrameworkCargo, Command: "cargo test", Indicators: []string{"Cargo.toml"}})
+	}
+	if path, ok := ctx.FindFirstWithSuffix(".sln", ".csproj", ".fsproj"); ok {
+		frameworks = append(frameworks, TestFramework{Name: TestFrameworkDotNet, Command: "dotnet test", Indicators: []string{filepath.Base(path)}})
+	}
+	if data, err := ctx.ReadFile("package.json"); err == nil {
+		frameworks = append(frameworks, detectJSTestFrameworks(ctx, data)...)
+	}
+	if indicators := pytestIndicators(ctx); len(indicators) > 0 {
+		frameworks = append(frameworks, TestFramework{Name: TestFrameworkPytest, Command: "python -m pytest", Indicators: indicators})
+	}
+	return frameworks
+}
+
+func detectJSTestFrameworks(ctx *Context, data []byte) []TestFramework {
+	var manifest struct {
+		Scripts         map[string]string `json:"scripts"`
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil
+	}
+	uses := func(name string) []string {
+		var indicators []string
+		if _, ok := manifest.DevDependencies[name]; ok {
+			indicators = append(indicators, "package.json devDependencies")
+		} else if _, ok := manifest.Dependencies[name]; ok {
+			indicators = append(indicators, "package.json dependencies")
+		}
+		if strings.Contains(manifest.Scripts["test"], name) {
+			indicators = append(indicators, "package.json test script")
+		}
+		for _, config := range []string{name + ".config.js", name + ".config.ts", name + ".config.mjs", name + ".config.cjs"} {
+			if ctx.HasFile(config) {
+				indicators = append(indicators, config)
+			}
+		}
+		return indicators
+	}
+
+	var frameworks []TestFramework
+	if indicators := uses("vitest"); len(indicators) > 0 {
+		frameworks = append(frameworks, TestFramework{Name: TestFrameworkVitest, Command: "npx vitest run", Indicators: indicators})
+	}
+	if indicators := uses("jest"); len(indicators) > 0 {
+		frameworks = append(frameworks, TestFramework{Name: TestFrameworkJest, Command: "npx jest", Indicators: indicators})
+	}
+	return frameworks
+}
+
+func pytestIndicators(ctx *Context) []string {
+	indicators := collectExistingFiles(ctx, []string{"pytest.ini", "conftest.py"})
+	for file, marker := range map[string]string{
+		"pyproject.toml":       "[tool.pytest",
+		"setup.cfg":            "[tool:pytest]",
+		"tox.ini":              "[pytest]",
+		"requirements.txt":     "pytest",
+		"requirements-dev.txt": "pytest",
+	} {
+		if data, err := ctx.ReadFile(file); err == nil && bytes.Contains(data, []byte(marker)) {
+			indicators = append(indicators, file)
+		}
+	}
+	// The map is iterated in random order.
+	sort.Strings(indicators)
+	return indicators
+}
+
+func formatTestFrameworkSummary(frameworks []TestFramework) string {
+	parts := make([]string, 0, len(frameworks))
+	for _, framework := range frameworks {
+		parts = append(parts, framework.Name+" (`"+framework.Command+"`)")
+	}
+	return "Tests: " + strings.Join(parts, ", ") + "; the run_tests internal command runs them with parsed results"
+}
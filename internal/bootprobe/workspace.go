@@ -0,0 +1,130 @@
+package bootprobe
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/asynkron/goagent/internal/gitignore"
+)
+
+// DefaultWorkspaceDepth is how many directory levels below the root the
+// workspace probe searches for nested projects.
+const DefaultWorkspaceDepth = 3
+
+// maxWorkspaceSummaryProjects caps the sub-projects listed in the summary
+// line; the JSON result keeps all of them.
+const maxWorkspaceSummaryProjects = 12
+
+// SubProject is a project nested below the root, such as frontend/ holding a
+// package.json next to a backend/ with a go.mod.
+type SubProject struct {
+	// Path is relative to the root, using forward slashes.
+	Path    string   `json:"path"`
+	Kinds   []string `json:"kinds"`
+	Markers []string `json:"markers"`
+}
+
+// projectMarkers maps manifest file names to the kind of project they mark.
+var projectMarkers = map[string]string{
+	"package.json":     ProbeNode,
+	"go.mod":           ProbeGo,
+	"Cargo.toml":       ProbeRust,
+	"pyproject.toml":   ProbePython,
+	"setup.py":         ProbePython,
+	"requirements.txt": ProbePython,
+	"pom.xml":          ProbeJVM,
+	"build.gradle":     ProbeJVM,
+	"build.gradle.kts": ProbeJVM,
+	"Dockerfile":       "docker",
+}
+
+// projectMarkerSuffixes maps manifest extensions to project kinds.
+var projectMarkerSuffixes = map[string]string{
+	".csproj": ProbeDotNet,
+	".fsproj": ProbeDotNet,
+	".sln":    ProbeDotNet,
+}
+
+// runWorkspaceProbe lists the projects nested up to depth levels below the
+// root. Hidden directories, dependency folders and paths excluded by
+// .gitignore are skipped. It returns nil when the root is a single project.
+func runWorkspaceProbe(ctx *Context, depth int) []SubProject {
+	if depth <= 0 {
+		depth = DefaultWorkspaceDepth
+	}
+	root := ctx.Root()
+	ignore := &gitignore.Matcher{}
+	var projects []SubProject
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		rel, relErr := filepath.Rel(root, path)
+		if relErr != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if path != root {
+			name := d.Name()
+			if strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor" || name == "target" || ignore.Ignored(rel, true) {
+				return filepath.SkipDir
+			}
+			if project, ok := detectSubProject(path, rel); ok {
+				projects = append(projects, project)
+			}
+			if strings.Count(rel, "/")+1 >= depth {
+				return filepath.SkipDir
+			}
+		}
+		ignore.Load(path, rel)
+		return nil
+	})
+	return projects
+}
+
+func detectSubProject(dir, rel string) (SubProject, bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return SubProject{}, false
+	}
+	project := SubProject{Path: rel}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		kind, ok := projectMarkers[name]
+		if !ok {
+			kind, ok = projectMarkerSuffixes[strings.ToLower(filepath.Ext(name))]
+		}
+		if !ok {
+			continue
+		}
+		project.Markers = append(project.Markers, name)
+		project.Kinds = append(project.Kinds, kind)
+	}
+	if len(project.Markers) == 0 {
+		return SubProject{}, false
+	}
+	project.Kinds = dedupeStrings(project.Kinds)
+	sort.Strings(project.Kinds)
+	return project, true
+}
+
+// formatWorkspaceSummary renders the compact workspace map, e.g.
+// "Workspace: frontend (node), backend (go)".
+func formatWorkspaceSummary(projects []SubProject) string {
+	parts := make([]string, 0, len(projects))
+	for i, project := range projects {
+		if i == maxWorkspaceSummaryProjects {
+			parts = append(parts, fmt.Sprintf("+%d more", len(projects)-i))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s (%s)", project.Path, strings.Join(project.Kinds, ", ")))
+	}
+	return "Workspace: " + strings.Join(parts, "; ")
+}
@@ -0,0 +1,44 @@
+package bootprobe
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDetectsNestedProjects(t *testing.T) {
+	dir := t.TempDir()
+	mustWriteFile(t, dir, ".gitignore", "build/\n")
+	mustWriteFile(t, dir, "frontend/package.json", "{}")
+	mustWriteFile(t, dir, "frontend/Dockerfile", "FROM node")
+	mustWriteFile(t, dir, "frontend/node_modules/dep/package.json", "{}")
+	mustWriteFile(t, dir, "backend/go.mod", "module backend")
+	mustWriteFile(t, dir, "services/billing/api/Api.csproj", "<Project />")
+	mustWriteFile(t, dir, "services/billing/api/deep/go.mod", "module deep")
+	mustWriteFile(t, dir, "build/package.json", "{}")
+	mustWriteFile(t, dir, ".cache/go.mod", "module cache")
+
+	ctx := NewContextWithLookPath(dir, func(string) (string, error) { return "", exec.ErrNotFound })
+	result := RunWithOptions(ctx, Options{Only: []string{ProbeWorkspace}})
+
+	require.Equal(t, []SubProject{
+		{Path: "backend", Kinds: []string{"go"}, Markers: []string{"go.mod"}},
+		{Path: "frontend", Kinds: []string{"docker", "node"}, Markers: []string{"Dockerfile", "package.json"}},
+		{Path: "services/billing/api", Kinds: []string{"dotnet"}, Markers: []string{"Api.csproj"}},
+	}, result.Workspace)
+	require.True(t, result.HasCapabilities())
+	require.Contains(t, FormatSummary(result), "- Workspace: backend (go); frontend (docker, node); services/billing/api (dotnet)")
+
+	shallow := RunWithOptions(ctx, Options{Only: []string{ProbeWorkspace}, WorkspaceDepth: 1})
+	require.Len(t, shallow.Workspace, 2)
+}
+
+func TestRunSkipsWorkspaceForSingleProject(t *testing.T) {
+	dir := t.TempDir()
+	mustWriteFile(t, dir, "go.mod", "module single")
+	mustWriteFile(t, dir, "internal/app/app.go", "package app")
+
+	ctx := NewContextWithLookPath(dir, func(string) (string, error) { return "", exec.ErrNotFound })
+	require.Empty(t, RunWithOptions(ctx, Options{Only: []string{ProbeWorkspace}}).Workspace)
+}
@@ -12,12 +12,15 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/joho/godotenv"
 
 	"github.com/asynkron/goagent/internal/bootprobe"
 	"github.com/asynkron/goagent/internal/core/runtime"
+	"github.com/asynkron/goagent/internal/lsp"
+	"github.com/asynkron/goagent/internal/notify"
 	tuiui "github.com/asynkron/goagent/internal/tui"
 )
 
@@ -31,6 +34,16 @@ func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
 		stderr = io.Discard
 	}
 
+	if len(args) > 0 && args[0] == "probe" {
+		return runProbe(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "sessions" {
+		return runSessions(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "replay" {
+		return runReplay(ctx, args[1:], stdout, stderr)
+	}
+
 	if err := godotenv.Load(); err != nil {
 		// A missing .env file is fine, but other errors should be surfaced to help with debugging.
 		var pathErr *os.PathError
@@ -51,47 +64,271 @@ func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
 
 	flagSet := flag.NewFlagSet("goagent", flag.ContinueOnError)
 	flagSet.SetOutput(stderr)
-	model := flagSet.String("model", defaultModel, "OpenAI model identifier to use for responses")
+	model := flagSet.String("model", defaultModel, "model identifier to use for responses (claude-* models use Anthropic)")
+	provider := flagSet.String("provider", os.Getenv("GOAGENT_PROVIDER"), "model provider: openai or anthropic (default: inferred from --model)")
 	reasoningEffort := flagSet.String("reasoning-effort", defaultReasoning, "Reasoning effort hint forwarded to OpenAI (low, medium, high)")
+	adaptiveEffort := flagSet.Bool("adaptive-effort", false, "pick the reasoning effort per request: lower for command follow-ups, higher for new instructions")
+	minEffort := flagSet.String("min-reasoning-effort", "", "lowest effort --adaptive-effort picks (default low)")
+	maxEffort := flagSet.String("max-reasoning-effort", "", "highest effort --adaptive-effort picks (default high)")
+	reasoningSummary := flagSet.String("reasoning-summary", os.Getenv("OPENAI_REASONING_SUMMARY"), "stream OpenAI reasoning summaries as reasoning events: auto, concise or detailed (optional)")
 	promptAugmentation := flagSet.String("augment", "", "additional system prompt instructions appended after the default prompt")
 	baseURL := flagSet.String("openai-base-url", defaultBaseURL, "override the OpenAI API base URL (optional)")
+	azureDeployment := flagSet.String("azure-deployment", os.Getenv("AZURE_OPENAI_DEPLOYMENT"), "Azure OpenAI deployment name; use with --openai-base-url set to the resource endpoint")
+	azureAPIVersion := flagSet.String("azure-api-version", os.Getenv("AZURE_OPENAI_API_VERSION"), "Azure OpenAI api-version query parameter (optional)")
+	session := flagSet.String("session", "", "resume the conversation saved at this path and save it again on exit")
 	// Optional: submit a prompt immediately. In TUI mode this will be enqueued
 	// on startup.
 	prompt := flagSet.String("prompt", "", "submit this prompt immediately")
 	// Research hands-free mode: pass a JSON object {"goal":"...","turns":N}
 	research := flagSet.String("research", "", "hands-free mode: JSON {\"goal\":\"...\", \"turns\":N}")
+	sandboxImage := flagSet.String("sandbox-image", "", "run shell plan steps inside this container image (docker/podman)")
+	sandboxNetwork := flagSet.String("sandbox-network", "none", "container network for sandboxed steps (none, bridge, host)")
+	sandboxCPUs := flagSet.String("sandbox-cpus", "", "CPU limit for sandboxed steps, e.g. 2")
+	sandboxMemory := flagSet.String("sandbox-memory", "", "memory limit for sandboxed steps, e.g. 2g")
+	readOnly := flagSet.Bool("read-only", false, "reject plan steps that may modify the workspace (analysis only)")
+	maxParallel := flagSet.Int("max-parallel-steps", 0, "maximum number of plan steps to run at once (0 = unlimited)")
+	maxRequests := flagSet.Int("max-requests", 0, "stop after this many model requests per session (0 = unlimited)")
+	maxTokens := flagSet.Int("max-tokens", 0, "stop after this many estimated tokens per session (0 = unlimited)")
+	maxUSD := flagSet.Float64("max-usd", 0, "stop after this estimated spend in USD per session; needs --usd-per-million-input/-output")
+	inputRate := flagSet.Float64("usd-per-million-input", 0, "USD per million input tokens, for --max-usd")
+	outputRate := flagSet.Float64("usd-per-million-output", 0, "USD per million output tokens, for --max-usd")
+	envPolicy := flagSet.String("env-policy", string(runtime.EnvInheritAll), "environment inherited by shell steps: inherit, allowlist, or clean")
+	summarize := flagSet.Bool("summarize-compaction", false, "summarize old messages with a model when the context budget is exceeded")
+	compactionModel := flagSet.String("compaction-model", "", "model used for --summarize-compaction (default: --model)")
+	watch := flagSet.Bool("watch", false, "report files changed outside the agent (e.g. in your editor) and tell the model before its next plan")
+	lspServers := flagSet.String("lsp", "", "comma-separated language servers queried for diagnostics after apply_patch: gopls, typescript, pyright, or auto for those on PATH")
+	noWebFetch := flagSet.Bool("no-web-fetch", false, "remove the fetch_url internal command so the model cannot read web pages in-process")
+	fetchDeny := flagSet.String("fetch-deny-hosts", "", "comma-separated hosts (and their subdomains) fetch_url refuses")
+	fetchAllow := flagSet.String("fetch-allow-hosts", "", "comma-separated hosts (and their subdomains) fetch_url is limited to")
+	fetchPrivate := flagSet.Bool("fetch-allow-private", false, "let fetch_url reach loopback, private-network and cloud metadata addresses")
+	searchProvider := flagSet.String("search-provider", os.Getenv("GOAGENT_SEARCH_PROVIDER"), "web_search backend: searxng, brave or bing (API keys come from BRAVE_SEARCH_API_KEY or BING_SEARCH_API_KEY)")
+	searchURL := flagSet.String("search-url", os.Getenv("SEARXNG_URL"), "SearxNG instance URL for --search-provider searxng, or an API endpoint override")
+	embeddingModel := flagSet.String("embedding-model", "", "enable semantic_search with this embedding model, e.g. text-embedding-3-small (index in .goagent/index)")
+	embeddingURL := flagSet.String("embedding-base-url", defaultBaseURL, "OpenAI compatible embeddings endpoint for --embedding-model, e.g. a local Ollama at http://localhost:11434/v1")
+	fallbackModels := flagSet.String("fallback-models", "", "comma-separated models tried in order when --model fails, e.g. gpt-4.1-mini,claude-sonnet-4-5 (keys come from the provider's API key variable)")
+	defaultShell := flagSet.String("shell", "", "shell for plan steps whose shell is missing on this host (default: your login shell)")
+	notifyMode := flagSet.String("notify", "", "desktop notification when the agent needs input or a hands-free run finishes: auto, system, osc, bell or off (/notify toggles it in the TUI)")
+	pty := flagSet.Bool("pty", false, "run shell plan steps under a pseudo-terminal (keeps colors and progress output)")
+	noMemory := flagSet.Bool("no-memory", false, "do not load or store memories in .goagent/memory.json (remember, recall and forget)")
+	repoMapTokens := flagSet.Int("repo-map-tokens", 0, "token budget of the repository map in the system prompt (default 1500; negative disables it)")
+	noInstructions := flagSet.Bool("no-project-instructions", false, "do not load AGENTS.md, CLAUDE.md or .goagent/instructions.md into the system prompt")
+	approval := flagSet.String("approval", string(runtime.ApprovalPolicyNever), "ask before executing plan steps: never, on-write, or always")
+	disableProbes := flagSet.String("disable-probes", "", "comma-separated environment probes to skip (see goagent probe --list)")
+	exitCommands := flagSet.String("exit-commands", "", "comma-separated inputs that end the session (default: exit, quit, /exit, /quit)")
+	profileName := flagSet.String("profile", "", "named profile from the config file selecting provider, model, base URL and API key variable")
+	outputFormat := flagSet.String("output-format", string(runtime.OutputFormatText), "headless output: text (final answer only) or jsonl (every runtime event as a JSON line)")
+	theme := flagSet.String("theme", "dark", "TUI color theme: dark, light, high-contrast, or a Glamour style name such as dracula")
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "failed to determine working directory: %v\n", err)
+		return 1
+	}
+
+	// Config files fill in defaults; environment variables and flags win.
+	settings, err := loadConfigFiles(configPaths(cwd))
+	if err == nil {
+		err = applyConfig(flagSet, settings, os.LookupEnv)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "invalid config: %v\n", err)
+		return 2
+	}
 
 	if err := flagSet.Parse(args); err != nil {
 		return 2
 	}
+	format := runtime.OutputFormat(strings.ToLower(strings.TrimSpace(*outputFormat)))
+	if format != runtime.OutputFormatText && format != runtime.OutputFormatJSONL {
+		_, _ = fmt.Fprintf(stderr, "unknown --output-format %q (want text or jsonl)\n", *outputFormat)
+		return 2
+	}
+	if err := tuiui.SetTheme(*theme, themeColors(settings)); err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if err := tuiui.SetNotify(*notifyMode); err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	profiles, err := collectProfiles(settings)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "invalid config: %v\n", err)
+		return 2
+	}
+	selected := profile{}
+	if name := strings.TrimSpace(*profileName); name != "" {
+		p, ok := profiles[name]
+		if !ok {
+			_, _ = fmt.Fprintf(stderr, "unknown profile %q (available: %s)\n", name, strings.Join(profileNames(profiles), ", "))
+			return 2
+		}
+		if err := applyProfile(flagSet, p); err != nil {
+			_, _ = fmt.Fprintf(stderr, "profile %s: %v\n", name, err)
+			return 2
+		}
+		selected = p
+	}
 
-	apiKey := os.Getenv("OPENAI_API_KEY")
+	resolvedProvider := runtime.ResolveProvider(*provider, *model)
+	apiKeyEnv := apiKeyEnvFor(resolvedProvider, *azureDeployment, selected["api-key-env"])
+	apiKey := os.Getenv(apiKeyEnv)
 	if apiKey == "" {
-		_, _ = fmt.Fprintln(stderr, "OPENAI_API_KEY must be set in the environment.")
+		_, _ = fmt.Fprintf(stderr, "%s must be set in the environment.\n", apiKeyEnv)
 		return 1
 	}
 
-	cwd, err := os.Getwd()
+	languageServers, err := lsp.ResolveServers(splitList(*lspServers))
 	if err != nil {
-		_, _ = fmt.Fprintf(stderr, "failed to determine working directory: %v\n", err)
-		return 1
+		_, _ = fmt.Fprintf(stderr, "invalid --lsp: %v\n", err)
+		return 2
+	}
+
+	var webSearch runtime.WebSearchOptions
+	if name := strings.TrimSpace(*searchProvider); name != "" {
+		webSearch.Provider, err = runtime.NewSearchProvider(runtime.SearchProviderConfig{
+			Name:     name,
+			Endpoint: *searchURL,
+			APIKey:   os.Getenv(runtime.SearchAPIKeyEnv(name)),
+		})
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "invalid --search-provider: %v\n", err)
+			return 2
+		}
+	}
+
+	var semanticSearch runtime.SemanticSearchOptions
+	if model := strings.TrimSpace(*embeddingModel); model != "" {
+		key := os.Getenv("GOAGENT_EMBEDDING_API_KEY")
+		if key == "" {
+			key = os.Getenv("OPENAI_API_KEY")
+		}
+		semanticSearch.Embedder = &runtime.OpenAIEmbedder{BaseURL: strings.TrimSpace(*embeddingURL), APIKey: key, EmbeddingModel: model}
+	}
+
+	var sameProvider []string
+	var otherProviders []runtime.ModelSettings
+	for _, m := range splitList(*fallbackModels) {
+		p := runtime.ResolveProvider("", m)
+		if p == resolvedProvider {
+			sameProvider = append(sameProvider, m)
+			continue
+		}
+		otherProviders = append(otherProviders, runtime.ModelSettings{Provider: p, Model: m, APIKey: os.Getenv(apiKeyEnvFor(p, "", ""))})
 	}
 
 	probeCtx := bootprobe.NewContext(cwd)
-	probeResult, probeSummary, combinedAugment := bootprobe.BuildAugmentation(probeCtx, *promptAugmentation)
-	if probeResult.HasCapabilities() && probeSummary != "" {
+	probeOptions := bootprobe.Options{Disable: splitList(*disableProbes)}
+	probeResult, probeSummary, combinedAugment := bootprobe.BuildAugmentationWithOptions(probeCtx, *promptAugmentation, probeOptions)
+	if probeResult.HasCapabilities() && probeSummary != "" && format != runtime.OutputFormatJSONL {
 		_, _ = fmt.Fprintln(stdout, probeSummary)
 		_, _ = fmt.Fprintln(stdout)
 	}
 
+	stepShell := strings.TrimSpace(*defaultShell)
+	if stepShell == "" {
+		stepShell = probeResult.Shell.Default
+	}
+
 	options := runtime.RuntimeOptions{
 		APIKey:                  apiKey,
 		APIBaseURL:              strings.TrimSpace(*baseURL),
+		Provider:                resolvedProvider,
+		AzureDeployment:         *azureDeployment,
+		AzureAPIVersion:         *azureAPIVersion,
 		Model:                   *model,
+		FallbackModels:          sameProvider,
+		Fallbacks:               otherProviders,
 		ReasoningEffort:         *reasoningEffort,
+		ReasoningSummary:        strings.TrimSpace(*reasoningSummary),
+		AdaptiveEffort:          *adaptiveEffort,
+		MinReasoningEffort:      *minEffort,
+		MaxReasoningEffort:      *maxEffort,
 		SystemPromptAugment:     combinedAugment,
+		Environment:             probeResult,
+		ApprovalPolicy:          runtime.ApprovalPolicy(*approval),
+		ReadOnly:                *readOnly,
+		PTY:                     *pty,
+		DefaultShell:            stepShell,
+		EnvPolicy:               runtime.EnvPolicy(*envPolicy),
+		MaxParallelSteps:        *maxParallel,
+		SummarizeCompaction:     *summarize,
+		CompactionModel:         strings.TrimSpace(*compactionModel),
+		DisableInstructionFiles: *noInstructions,
+		DisableMemory:           *noMemory,
+		DisableRepoMap:          *repoMapTokens < 0,
+		RepoMapTokens:           *repoMapTokens,
+		ExitCommands:            splitList(*exitCommands),
 		DisableOutputForwarding: true,
 		UseStreaming:            true,
+		JournalPath:             filepath.Join(".goagent", "plan_journal.json"),
+		WatchWorkspace:          *watch,
+		LanguageServers:         languageServers,
+		WebFetch: runtime.WebFetchOptions{
+			Disable:              *noWebFetch,
+			DenyHosts:            splitList(*fetchDeny),
+			AllowHosts:           splitList(*fetchAllow),
+			AllowPrivateNetworks: *fetchPrivate,
+		},
+		WebSearch:      webSearch,
+		SemanticSearch: semanticSearch,
+		Budget: runtime.Budget{
+			MaxRequestsPerSession: *maxRequests,
+			MaxTokensPerSession:   *maxTokens,
+			MaxUSD:                *maxUSD,
+			InputUSDPerMillion:    *inputRate,
+			OutputUSDPerMillion:   *outputRate,
+		},
+	}
+
+	if len(profiles) > 0 {
+		base := runtime.ModelSettings{
+			Provider:        options.Provider,
+			APIBaseURL:      options.APIBaseURL,
+			Model:           options.Model,
+			ReasoningEffort: options.ReasoningEffort,
+			AzureDeployment: options.AzureDeployment,
+			AzureAPIVersion: options.AzureAPIVersion,
+		}
+		tuiui.SetProfiles(profileNames(profiles), func(name string) (runtime.ModelSettings, error) {
+			p, ok := profiles[name]
+			if !ok {
+				return runtime.ModelSettings{}, fmt.Errorf("unknown profile %q", name)
+			}
+			return p.modelSettings(base)
+		})
+	}
+
+	if image := strings.TrimSpace(*sandboxImage); image != "" {
+		containerRuntime := probeResult.ContainerRuntime()
+		if containerRuntime == "" && probeCtx.CommandExists("docker") {
+			containerRuntime = "docker"
+		}
+		if containerRuntime == "" {
+			_, _ = fmt.Fprintln(stderr, "--sandbox-image requires docker or podman on PATH")
+			return 1
+		}
+		options.ExecutionBackend = &runtime.ContainerBackend{
+			Runtime:   containerRuntime,
+			Image:     image,
+			Workspace: cwd,
+			Network:   *sandboxNetwork,
+			CPUs:      *sandboxCPUs,
+			Memory:    *sandboxMemory,
+		}
+	}
+
+	// Piped stdin (cat error.log | goagent --prompt "explain this") is
+	// appended to the prompt or research goal; on its own it is the prompt.
+	var piped string
+	var pipedTruncated bool
+	if stdinIsPiped(os.Stdin) {
+		piped, pipedTruncated, err = readPipedInput(os.Stdin, maxStdinBytes)
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "failed to read stdin: %v\n", err)
+			return 1
+		}
 	}
 
 	// Research mode takes precedence over --prompt.
@@ -115,32 +352,59 @@ func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
 			rs.Turns = 0
 		}
 		options.HandsFree = true
-		options.HandsFreeTopic = rs.Goal
+		// The auto-reply repeats the goal every turn, so it leaves the piped
+		// input out.
+		options.HandsFreeTopic = withPipedInput(rs.Goal, piped, pipedTruncated)
 		if rs.Turns > 0 {
 			options.MaxPasses = rs.Turns
 		}
 		options.HandsFreeAutoReply = fmt.Sprintf("Please continue to work on the set goal. No human available. Goal: %s", rs.Goal)
 
 		// Run in headless mode and exit on completion.
-		return runHeadlessResearch(ctx, options, stdout, stderr)
-	} else if p := strings.TrimSpace(*prompt); p != "" {
-		// TUI is the only UI. If a prompt is provided, set hands-free so the
-		// runtime will submit it immediately on startup.
+		return runHeadlessResearch(ctx, options, format, *notifyMode, stdout, stderr)
+	} else if p := strings.TrimSpace(withPipedInput(strings.TrimSpace(*prompt), piped, pipedTruncated)); p != "" {
+		// If a prompt is provided, set hands-free so the runtime will submit
+		// it immediately on startup.
 		options.HandsFree = true
 		options.HandsFreeTopic = p
+		if format == runtime.OutputFormatJSONL {
+			// The event stream replaces the TUI; run until the agent is done.
+			return runHeadlessResearch(ctx, options, format, *notifyMode, stdout, stderr)
+		}
+	} else if format == runtime.OutputFormatJSONL {
+		_, _ = fmt.Fprintln(stderr, "--output-format jsonl requires --prompt, --research, or piped input")
+		return 2
+	}
+	return tuiui.RunSession(ctx, options, strings.TrimSpace(*session))
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
-	return tuiui.Run(ctx, options)
+	return items
 }
 
 // runHeadlessResearch executes the runtime without the TUI, watching events
 // to determine success or failure, and printing the final assistant message
-// to stdout on success or stderr on failure. It returns a POSIX exit code.
-func runHeadlessResearch(ctx context.Context, options runtime.RuntimeOptions, stdout, stderr io.Writer) int {
+// to stdout on success or stderr on failure. With OutputFormatJSONL every
+// event is written to stdout as a JSON line instead. It returns a POSIX exit
+// code. A notifyMode other than off announces the end of the run.
+func runHeadlessResearch(ctx context.Context, options runtime.RuntimeOptions, format runtime.OutputFormat, notifyMode string, stdout, stderr io.Writer) int {
 	// Ensure we don't read stdin or forward outputs internally.
 	options.UseStreaming = true
 	options.DisableOutputForwarding = true
 	options.DisableInputReader = true
 
+	notifier, err := notify.New(notifyMode, stderr)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+	}
+
 	agent, err := runtime.NewRuntime(options)
 	if err != nil {
 		_, _ = fmt.Fprintln(stderr, "failed to create runtime:", err)
@@ -157,6 +421,9 @@ func runHeadlessResearch(ctx context.Context, options runtime.RuntimeOptions, st
 	var failedBudget bool
 
 	for evt := range outputs {
+		if format == runtime.OutputFormatJSONL {
+			_, _ = stdout.Write(runtime.MarshalEventJSON(evt))
+		}
 		switch evt.Type {
 		case runtime.EventTypeAssistantMessage:
 			// Capture latest full assistant message.
@@ -174,6 +441,19 @@ func runHeadlessResearch(ctx context.Context, options runtime.RuntimeOptions, st
 		}
 	}
 
+	if success {
+		_ = notifier.Notify("goagent finished", lastAssistant)
+	} else {
+		_ = notifier.Notify("goagent stopped", "The run ended without a final result.")
+	}
+
+	if format == runtime.OutputFormatJSONL {
+		// The events already carry the outcome; only the exit code remains.
+		if success {
+			return 0
+		}
+		return 1
+	}
 	if success {
 		if lastAssistant != "" {
 			_, _ = fmt.Fprintln(stdout, lastAssistant)
@@ -0,0 +1,364 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envBackedFlags lists flags whose defaults come from environment variables.
+// A set variable wins over config files, so files only fill in the rest.
+var envBackedFlags = map[string]string{
+	"model":             "OPENAI_MODEL",
+	"provider":          "GOAGENT_PROVIDER",
+	"reasoning-effort":  "OPENAI_REASONING_EFFORT",
+	"openai-base-url":   "OPENAI_BASE_URL",
+	"azure-deployment":  "AZURE_OPENAI_DEPLOYMENT",
+	"azure-api-version": "AZURE_OPENAI_API_VERSION",
+}
+
+// configSetting is one key from a config file. Keys are flag names, except
+// inside profiles (see profileKeys) and theme colors, which are stored as
+// "colors.<name>".
+type configSetting struct {
+	key    string
+	value  string
+	source string
+	// profile names the profile the setting belongs to; empty for
+	// top-level settings.
+	profile string
+}
+
+// configPaths returns the config files to load, lowest precedence first:
+// the user file (~/.config/goagent/config.{toml,yaml}, or $GOAGENT_CONFIG)
+// followed by the project file (.goagent/config in cwd).
+func configPaths(cwd string) []string {
+	var paths []string
+	if explicit := strings.TrimSpace(os.Getenv("GOAGENT_CONFIG")); explicit != "" {
+		paths = append(paths, explicit)
+	} else if dir := userConfigDir(); dir != "" {
+		paths = append(paths, firstExisting(filepath.Join(dir, "goagent"), "config.toml", "config.yaml", "config.yml"))
+	}
+	paths = append(paths, firstExisting(filepath.Join(cwd, ".goagent"), "config", "config.toml", "config.yaml", "config.yml"))
+
+	existing := paths[:0]
+	for _, path := range paths {
+		if path != "" {
+			existing = append(existing, path)
+		}
+	}
+	return existing
+}
+
+func userConfigDir() string {
+	if dir := strings.TrimSpace(os.Getenv("XDG_CONFIG_HOME")); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config")
+}
+
+// colorKeyPrefix marks settings from the [colors] table.
+const colorKeyPrefix = "colors."
+
+func firstExisting(dir string, names ...string) string {
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// loadConfigFiles reads the files in order. Later files override earlier ones
+// when both set a key. Missing files are skipped.
+func loadConfigFiles(paths []string) ([]configSetting, error) {
+	var settings []configSetting
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read config %s: %w", path, err)
+		}
+		values, err := parseConfig(path, data)
+		if err != nil {
+			return nil, fmt.Errorf("parse config %s: %w", path, err)
+		}
+		for _, setting := range values {
+			setting.source = path
+			settings = append(settings, setting)
+		}
+	}
+	return settings, nil
+}
+
+// parseConfig decodes YAML files by extension and everything else, including
+// the extensionless project file, as TOML unless it looks like YAML.
+func parseConfig(path string, data []byte) ([]configSetting, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return parseYAMLConfig(data)
+	case ".toml":
+		return parseTOMLConfig(data)
+	}
+	if looksLikeYAML(data) {
+		return parseYAMLConfig(data)
+	}
+	return parseTOMLConfig(data)
+}
+
+// looksLikeYAML reports whether the first setting uses "key: value".
+func looksLikeYAML(data []byte) bool {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || line == "---" {
+			continue
+		}
+		colon := strings.Index(line, ":")
+		equals := strings.Index(line, "=")
+		return colon >= 0 && (equals < 0 || colon < equals)
+	}
+	return false
+}
+
+func parseYAMLConfig(data []byte) ([]configSetting, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	if len(node.Content) == 0 {
+		return nil, nil
+	}
+	root := node.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, errors.New("expected a mapping of settings")
+	}
+	return yamlSettings(root, "")
+}
+
+// yamlSettings reads one mapping of settings. The top-level "profiles" key
+// holds a mapping of profile names to their own settings and "colors" a
+// mapping of theme color overrides.
+func yamlSettings(root *yaml.Node, profile string) ([]configSetting, error) {
+	settings := make([]configSetting, 0, len(root.Content)/2)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if profile == "" && normalizeConfigKey(key.Value) == "profiles" {
+			if value.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("line %d: profiles must map names to settings", value.Line)
+			}
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				name, body := value.Content[j], value.Content[j+1]
+				if body.Kind != yaml.MappingNode {
+					return nil, fmt.Errorf("line %d: profile %s must be a mapping", body.Line, name.Value)
+				}
+				nested, err := yamlSettings(body, strings.TrimSpace(name.Value))
+				if err != nil {
+					return nil, err
+				}
+				settings = append(settings, nested...)
+			}
+			continue
+		}
+		if profile == "" && normalizeConfigKey(key.Value) == "colors" {
+			if value.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("line %d: colors must map names to colors", value.Line)
+			}
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				name, color := value.Content[j], value.Content[j+1]
+				if color.Kind != yaml.ScalarNode {
+					return nil, fmt.Errorf("line %d: color %s must be a value", color.Line, name.Value)
+				}
+				settings = append(settings, configSetting{key: colorKeyPrefix + normalizeConfigKey(name.Value), value: color.Value})
+			}
+			continue
+		}
+		var text string
+		switch value.Kind {
+		case yaml.ScalarNode:
+			text = value.Value
+		case yaml.SequenceNode:
+			items := make([]string, 0, len(value.Content))
+			for _, item := range value.Content {
+				if item.Kind != yaml.ScalarNode {
+					return nil, fmt.Errorf("line %d: %s must be a list of plain values", item.Line, key.Value)
+				}
+				items = append(items, item.Value)
+			}
+			text = strings.Join(items, ",")
+		default:
+			return nil, fmt.Errorf("line %d: %s must be a value or a list", value.Line, key.Value)
+		}
+		settings = append(settings, configSetting{key: normalizeConfigKey(key.Value), value: text, profile: profile})
+	}
+	return settings, nil
+}
+
+// parseTOMLConfig supports the subset of TOML the settings need: key = value
+// pairs with strings, numbers, booleans, and arrays of those, plus
+// [profiles.<name>] and [colors] tables.
+func parseTOMLConfig(data []byte) ([]configSetting, error) {
+	var settings []configSetting
+	profile := ""
+	prefix := ""
+	for i, raw := range strings.Split(string(data), "\n") {
+		line := strings.TrimSpace(stripTOMLComment(raw))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			table := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(line, "["), "]"))
+			if table == "colors" && strings.HasSuffix(line, "]") {
+				profile, prefix = "", colorKeyPrefix
+				continue
+			}
+			name, found := strings.CutPrefix(table, "profiles.")
+			name = strings.Trim(strings.TrimSpace(name), `"`)
+			if !found || name == "" || !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: only [profiles.<name>] and [colors] tables are supported", i+1)
+			}
+			profile, prefix = name, ""
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected key = value", i+1)
+		}
+		key = strings.Trim(strings.TrimSpace(key), `"`)
+		text, err := parseTOMLValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", i+1, key, err)
+		}
+		settings = append(settings, configSetting{key: prefix + normalizeConfigKey(key), value: text, profile: profile})
+	}
+	return settings, nil
+}
+
+func parseTOMLValue(value string) (string, error) {
+	if strings.HasPrefix(value, "[") {
+		if !strings.HasSuffix(value, "]") {
+			return "", errors.New("arrays must fit on one line")
+		}
+		inner := strings.TrimSpace(value[1 : len(value)-1])
+		if inner == "" {
+			return "", nil
+		}
+		var items []string
+		for _, part := range splitTOMLArray(inner) {
+			item, err := parseTOMLValue(strings.TrimSpace(part))
+			if err != nil {
+				return "", err
+			}
+			items = append(items, item)
+		}
+		return strings.Join(items, ","), nil
+	}
+	switch {
+	case strings.HasPrefix(value, `"`):
+		return strconv.Unquote(value)
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return "", errors.New("unterminated string")
+		}
+		return value[1 : len(value)-1], nil
+	case value == "true" || value == "false":
+		return value, nil
+	}
+	if _, err := strconv.ParseFloat(strings.ReplaceAll(value, "_", ""), 64); err == nil {
+		return strings.ReplaceAll(value, "_", ""), nil
+	}
+	return "", fmt.Errorf("invalid value %q (quote strings)", value)
+}
+
+// splitTOMLArray splits on commas outside quotes, ignoring a trailing comma.
+func splitTOMLArray(inner string) []string {
+	var parts []string
+	var quote rune
+	start := 0
+	for i, r := range inner {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			parts = append(parts, inner[start:i])
+			start = i + 1
+		}
+	}
+	if tail := strings.TrimSpace(inner[start:]); tail != "" {
+		parts = append(parts, tail)
+	}
+	return parts
+}
+
+// stripTOMLComment removes a trailing # comment outside of quotes.
+func stripTOMLComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func normalizeConfigKey(key string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), "_", "-")
+}
+
+// applyConfig sets flag values from config settings before the command line
+// is parsed, so flags given on the command line still win. Settings for
+// env-backed flags are skipped when the variable is set.
+func applyConfig(flagSet *flag.FlagSet, settings []configSetting, lookupEnv func(string) (string, bool)) error {
+	for _, setting := range settings {
+		if setting.profile != "" || strings.HasPrefix(setting.key, colorKeyPrefix) {
+			continue
+		}
+		if flagSet.Lookup(setting.key) == nil {
+			return fmt.Errorf("%s: unknown setting %q", setting.source, setting.key)
+		}
+		if env, ok := envBackedFlags[setting.key]; ok {
+			if value, set := lookupEnv(env); set && value != "" {
+				continue
+			}
+		}
+		if err := flagSet.Set(setting.key, setting.value); err != nil {
+			return fmt.Errorf("%s: invalid value for %s: %w", setting.source, setting.key, err)
+		}
+	}
+	return nil
+}
+
+// themeColors collects the [colors] overrides; later files win.
+func themeColors(settings []configSetting) map[string]string {
+	colors := make(map[string]string)
+	for _, setting := range settings {
+		if name, ok := strings.CutPrefix(setting.key, colorKeyPrefix); ok {
+			colors[name] = setting.value
+		}
+	}
+	return colors
+}
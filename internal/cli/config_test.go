@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseConfigFormats(t *testing.T) {
+	t.Parallel()
+
+	toml := []byte(`
+# defaults for this repo
+model = "gpt-4.1" # trailing comment
+max_parallel_steps = 4
+read-only = true
+exit_commands = ["bye", 'done,now']
+`)
+	yamlData := []byte(`
+model: gpt-4.1
+max-parallel-steps: 4
+read_only: true
+exit-commands:
+  - bye
+  - "done,now"
+`)
+	for name, data := range map[string][]byte{"config.toml": toml, "config.yaml": yamlData, "config": yamlData} {
+		settings, err := parseConfig(name, data)
+		if err != nil {
+			t.Fatalf("%s: parse: %v", name, err)
+		}
+		got := map[string]string{}
+		for _, s := range settings {
+			got[s.key] = s.value
+		}
+		want := map[string]string{"model": "gpt-4.1", "max-parallel-steps": "4", "read-only": "true", "exit-commands": "bye,done,now"}
+		for key, value := range want {
+			if got[key] != value {
+				t.Fatalf("%s: %s = %q, want %q (all: %v)", name, key, got[key], value, got)
+			}
+		}
+	}
+
+	if _, err := parseConfig("config.toml", []byte("[openai]\nmodel = \"x\"")); err == nil {
+		t.Fatal("expected tables to be rejected")
+	}
+	if _, err := parseConfig("config", []byte("model = gpt-4.1")); err == nil {
+		t.Fatal("expected unquoted TOML string to be rejected")
+	}
+}
+
+func TestApplyConfigPrecedence(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	user := filepath.Join(dir, "user.toml")
+	project := filepath.Join(dir, "project.yaml")
+	if err := os.WriteFile(user, []byte("model = \"from-user\"\napproval = \"always\"\nreasoning-effort = \"low\"\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.WriteFile(project, []byte("approval: on-write\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	model := flagSet.String("model", "default", "")
+	approval := flagSet.String("approval", "never", "")
+	effort := flagSet.String("reasoning-effort", "from-env", "")
+
+	settings, err := loadConfigFiles([]string{user, project, filepath.Join(dir, "missing.toml")})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	env := func(key string) (string, bool) {
+		if key == "OPENAI_REASONING_EFFORT" {
+			return "from-env", true
+		}
+		return "", false
+	}
+	if err := applyConfig(flagSet, settings, env); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if err := flagSet.Parse([]string{"-model", "from-flag"}); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if *model != "from-flag" || *approval != "on-write" || *effort != "from-env" {
+		t.Fatalf("unexpected values: model=%q approval=%q effort=%q", *model, *approval, *effort)
+	}
+
+	if err := applyConfig(flagSet, []configSetting{{key: "no-such-flag", value: "x", source: "cfg"}}, env); err == nil {
+		t.Fatal("expected unknown setting to fail")
+	}
+}
+
+func TestThemeColorsFromConfig(t *testing.T) {
+	t.Parallel()
+
+	toml := []byte("theme = \"light\"\n[colors]\naccent = \"#5f87ff\"\ndiff_add = \"34\"\n")
+	yamlData := []byte("theme: light\ncolors:\n  accent: \"#5f87ff\"\n  diff_add: 34\n")
+	for name, data := range map[string][]byte{"config.toml": toml, "config.yaml": yamlData} {
+		settings, err := parseConfig(name, data)
+		if err != nil {
+			t.Fatalf("%s: parse: %v", name, err)
+		}
+		colors := themeColors(settings)
+		if colors["accent"] != "#5f87ff" || colors["diff-add"] != "34" || len(colors) != 2 {
+			t.Fatalf("%s: unexpected colors %v", name, colors)
+		}
+
+		flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+		theme := flagSet.String("theme", "dark", "")
+		if err := applyConfig(flagSet, settings, func(string) (string, bool) { return "", false }); err != nil {
+			t.Fatalf("%s: apply: %v", name, err)
+		}
+		if *theme != "light" {
+			t.Fatalf("%s: theme = %q, want light", name, *theme)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/asynkron/goagent/internal/bootprobe"
+)
+
+// runProbe implements "goagent probe": it runs the boot probes against the
+// working directory and prints the summary the model sees, or the full result
+// as JSON with --json.
+func runProbe(args []string, stdout, stderr io.Writer) int {
+	flagSet := flag.NewFlagSet("goagent probe", flag.ContinueOnError)
+	flagSet.SetOutput(stderr)
+	asJSON := flagSet.Bool("json", false, "print the full probe result as JSON")
+	dir := flagSet.String("dir", "", "directory to probe (default: the working directory)")
+	disable := flagSet.String("disable", "", "comma-separated probes to skip")
+	only := flagSet.String("only", "", "comma-separated probes to run instead of all of them")
+	list := flagSet.Bool("list", false, "list the available probes and exit")
+	if err := flagSet.Parse(args); err != nil {
+		return 2
+	}
+	if *list {
+		for _, name := range bootprobe.ProbeNames() {
+			_, _ = fmt.Fprintln(stdout, name)
+		}
+		return 0
+	}
+
+	root := *dir
+	if root == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "failed to resolve working directory: %v\n", err)
+			return 1
+		}
+		root = cwd
+	}
+
+	result := bootprobe.RunWithOptions(bootprobe.NewContext(root), bootprobe.Options{
+		Disable: splitList(*disable),
+		Only:    splitList(*only),
+	})
+	if !*asJSON {
+		_, _ = fmt.Fprintln(stdout, bootprobe.FormatSummary(result))
+		return 0
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		_, _ = fmt.Fprintf(stderr, "failed to encode probe result: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/asynkron/goagent/internal/core/runtime"
+)
+
+// profileKeys are the settings a profile may override. api-key-env names
+// the environment variable holding the profile's API key; the others are
+// flag names.
+var profileKeys = map[string]bool{
+	"provider":          true,
+	"model":             true,
+	"openai-base-url":   true,
+	"reasoning-effort":  true,
+	"azure-deployment":  true,
+	"azure-api-version": true,
+	"api-key-env":       true,
+}
+
+// profile maps profileKeys to values.
+type profile map[string]string
+
+// collectProfiles groups profile settings by name. Later settings win, so
+// a project file can refine a profile from the user file.
+func collectProfiles(settings []configSetting) (map[string]profile, error) {
+	profiles := make(map[string]profile)
+	for _, setting := range settings {
+		if setting.profile == "" {
+			continue
+		}
+		if setting.key == "base-url" {
+			setting.key = "openai-base-url"
+		}
+		if !profileKeys[setting.key] {
+			return nil, fmt.Errorf("%s: profile %s: unknown setting %q", setting.source, setting.profile, setting.key)
+		}
+		if profiles[setting.profile] == nil {
+			profiles[setting.profile] = profile{}
+		}
+		profiles[setting.profile][setting.key] = setting.value
+	}
+	return profiles, nil
+}
+
+// applyProfile sets the profile's values on flags not given on the command
+// line. Unlike config defaults, a selected profile overrides environment
+// variables.
+func applyProfile(flagSet *flag.FlagSet, p profile) error {
+	explicit := map[string]bool{}
+	flagSet.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	for key, value := range p {
+		if key == "api-key-env" || explicit[key] {
+			continue
+		}
+		if err := flagSet.Set(key, value); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// apiKeyEnvFor returns the environment variable holding the API key for the
+// provider, honouring a profile's api-key-env override.
+func apiKeyEnvFor(provider, azureDeployment, override string) string {
+	if override = strings.TrimSpace(override); override != "" {
+		return override
+	}
+	if strings.TrimSpace(azureDeployment) != "" && os.Getenv("AZURE_OPENAI_API_KEY") != "" {
+		return "AZURE_OPENAI_API_KEY"
+	}
+	if provider == runtime.ProviderAnthropic {
+		return "ANTHROPIC_API_KEY"
+	}
+	return "OPENAI_API_KEY"
+}
+
+// modelSettings resolves the profile against the current flag values, which
+// fill in anything the profile leaves out.
+func (p profile) modelSettings(base runtime.ModelSettings) (runtime.ModelSettings, error) {
+	settings := base
+	if value, ok := p["model"]; ok {
+		settings.Model = value
+	}
+	if value, ok := p["openai-base-url"]; ok {
+		settings.APIBaseURL = strings.TrimSpace(value)
+	}
+	if value, ok := p["reasoning-effort"]; ok {
+		settings.ReasoningEffort = value
+	}
+	if value, ok := p["azure-deployment"]; ok {
+		settings.AzureDeployment = value
+	}
+	if value, ok := p["azure-api-version"]; ok {
+		settings.AzureAPIVersion = value
+	}
+	// A profile that names a model but no provider infers it from the model.
+	provider := p["provider"]
+	if provider == "" && p["model"] == "" {
+		provider = base.Provider
+	}
+	settings.Provider = runtime.ResolveProvider(provider, settings.Model)
+
+	keyEnv := apiKeyEnvFor(settings.Provider, settings.AzureDeployment, p["api-key-env"])
+	settings.APIKey = os.Getenv(keyEnv)
+	if settings.APIKey == "" {
+		return settings, fmt.Errorf("%s must be set in the environment", keyEnv)
+	}
+	return settings, nil
+}
+
+// profileNames lists profiles alphabetically.
+func profileNames(profiles map[string]profile) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/asynkron/goagent/internal/core/runtime"
+)
+
+func TestProfilesFromConfig(t *testing.T) {
+	t.Setenv("LOCAL_OLLAMA_KEY", "ollama")
+
+	toml := []byte(`
+model = "gpt-4.1"
+
+[profiles.local-ollama]
+base_url = "http://localhost:11434/v1"
+model = "qwen3"
+api_key_env = "LOCAL_OLLAMA_KEY"
+
+[profiles.claude]
+model = "claude-sonnet-4"
+`)
+	yamlData := []byte(`
+profiles:
+  local-ollama:
+    openai-base-url: http://localhost:11434/v1
+    model: qwen3
+    api-key-env: LOCAL_OLLAMA_KEY
+  claude:
+    model: claude-sonnet-4
+`)
+	for name, data := range map[string][]byte{"config.toml": toml, "config.yaml": yamlData} {
+		settings, err := parseConfig(name, data)
+		if err != nil {
+			t.Fatalf("%s: parse: %v", name, err)
+		}
+		profiles, err := collectProfiles(settings)
+		if err != nil {
+			t.Fatalf("%s: collect: %v", name, err)
+		}
+		if got := profileNames(profiles); len(got) != 2 || got[0] != "claude" || got[1] != "local-ollama" {
+			t.Fatalf("%s: unexpected profiles %v", name, got)
+		}
+
+		base := runtime.ModelSettings{Provider: runtime.ProviderOpenAI, Model: "gpt-4.1", ReasoningEffort: "high"}
+		local, err := profiles["local-ollama"].modelSettings(base)
+		if err != nil {
+			t.Fatalf("%s: resolve: %v", name, err)
+		}
+		if local.APIKey != "ollama" || local.APIBaseURL != "http://localhost:11434/v1" || local.Model != "qwen3" || local.Provider != runtime.ProviderOpenAI || local.ReasoningEffort != "high" {
+			t.Fatalf("%s: unexpected settings %+v", name, local)
+		}
+	}
+
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	claude := profile{"model": "claude-sonnet-4"}
+	if _, err := claude.modelSettings(runtime.ModelSettings{Provider: runtime.ProviderOpenAI}); err == nil {
+		t.Fatal("expected missing ANTHROPIC_API_KEY to be reported")
+	}
+}
+
+func TestApplyProfileKeepsExplicitFlags(t *testing.T) {
+	t.Parallel()
+
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	model := flagSet.String("model", "default", "")
+	baseURL := flagSet.String("openai-base-url", "", "")
+	if err := flagSet.Parse([]string{"-model", "from-flag"}); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if err := applyProfile(flagSet, profile{"model": "qwen3", "openai-base-url": "http://localhost", "api-key-env": "X"}); err != nil {
+		t.Fatalf("applyProfile: %v", err)
+	}
+	if *model != "from-flag" || *baseURL != "http://localhost" {
+		t.Fatalf("unexpected values: model=%q base=%q", *model, *baseURL)
+	}
+
+	if _, err := collectProfiles([]configSetting{{key: "theme", value: "light", profile: "p", source: "cfg"}}); err == nil {
+		t.Fatal("expected unknown profile key to fail")
+	}
+}
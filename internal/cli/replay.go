@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/asynkron/goagent/internal/core/runtime"
+	tuiui "github.com/asynkron/goagent/internal/tui"
+)
+
+// runReplay implements "goagent replay": it loads the events recorded for a
+// session and re-renders them in the TUI, or writes them to stdout as JSON
+// lines with --jsonl. Without a session it replays the newest one. No model
+// is contacted and nothing is executed.
+func runReplay(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flagSet := flag.NewFlagSet("goagent replay", flag.ContinueOnError)
+	flagSet.SetOutput(stderr)
+	jsonl := flagSet.Bool("jsonl", false, "write the recorded events to stdout as JSON lines instead of opening the TUI")
+	dir := flagSet.String("dir", runtime.SessionsDir, "directory holding the sessions")
+	flagSet.Usage = func() {
+		_, _ = fmt.Fprintln(stderr, "usage: goagent replay [--jsonl] [--dir path] [session ID or events.jsonl path]")
+		flagSet.PrintDefaults()
+	}
+	if err := flagSet.Parse(args); err != nil {
+		return 2
+	}
+	if flagSet.NArg() > 1 {
+		flagSet.Usage()
+		return 2
+	}
+
+	path, name, err := resolveEventLog(*dir, flagSet.Arg(0))
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 1
+	}
+	events, err := runtime.ReadEventLog(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		_, _ = fmt.Fprintf(stderr, "session %s has no recorded events (%s)\n", name, path)
+		return 1
+	}
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	if *jsonl {
+		for _, evt := range events {
+			if _, err := stdout.Write(runtime.MarshalEventJSON(evt)); err != nil {
+				_, _ = fmt.Fprintf(stderr, "failed to write events: %v\n", err)
+				return 1
+			}
+		}
+		return 0
+	}
+	return tuiui.RunReplay(ctx, events, name)
+}
+
+// resolveEventLog turns the replay argument into an event log path and a
+// name for messages. The argument may be a session ID under dir, a session
+// directory or an events file; empty picks the newest session.
+func resolveEventLog(dir, arg string) (path, name string, err error) {
+	if arg == "" {
+		sessions, err := runtime.ListSessions(dir)
+		if err != nil {
+			return "", "", err
+		}
+		if len(sessions) == 0 {
+			return "", "", fmt.Errorf("no sessions in %s", dir)
+		}
+		return runtime.SessionEventLogPath(dir, sessions[0].ID), sessions[0].ID, nil
+	}
+	if info, err := os.Stat(arg); err == nil {
+		if info.IsDir() {
+			return filepath.Join(arg, runtime.EventLogFile), filepath.Base(filepath.Clean(arg)), nil
+		}
+		return arg, arg, nil
+	}
+	return runtime.SessionEventLogPath(dir, arg), arg, nil
+}
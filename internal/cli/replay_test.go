@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/asynkron/goagent/internal/core/runtime"
+)
+
+func TestReplayDumpsRecordedEventsAsJSONL(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	path := runtime.SessionEventLogPath(root, "20261017-101500-abcdef")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	recorded := string(runtime.MarshalEventJSON(runtime.RuntimeEvent{Type: runtime.EventTypeStatus, Message: "hello", Agent: "main"})) +
+		string(runtime.MarshalEventJSON(runtime.RuntimeEvent{Type: runtime.EventTypeAssistantMessage, Message: "done", Agent: "main"}))
+	if err := os.WriteFile(path, []byte(recorded), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	for _, arg := range []string{"20261017-101500-abcdef", filepath.Dir(path), path} {
+		var stdout, stderr bytes.Buffer
+		if code := runReplay(context.Background(), []string{"--jsonl", "--dir", root, arg}, &stdout, &stderr); code != 0 {
+			t.Fatalf("replay %s: exit code %d: %s", arg, code, stderr.String())
+		}
+		if stdout.String() != recorded {
+			t.Fatalf("replay %s wrote:\n%s", arg, stdout.String())
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := runReplay(context.Background(), []string{"--jsonl", "--dir", root, "missing"}, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "no recorded events") {
+		t.Fatalf("expected a missing-session error, got %d %q", code, stderr.String())
+	}
+}
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/asynkron/goagent/internal/core/runtime"
+)
+
+// sessionPromptWidth caps the prompt column of "goagent sessions list".
+const sessionPromptWidth = 60
+
+// runSessions implements "goagent sessions": "list" prints the sessions
+// recorded under .goagent/sessions, newest first, or as JSON with --json.
+func runSessions(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "list" {
+		_, _ = fmt.Fprintln(stderr, "usage: goagent sessions list [--json] [--dir path]")
+		return 2
+	}
+	flagSet := flag.NewFlagSet("goagent sessions list", flag.ContinueOnError)
+	flagSet.SetOutput(stderr)
+	asJSON := flagSet.Bool("json", false, "print the sessions as JSON")
+	dir := flagSet.String("dir", runtime.SessionsDir, "directory holding the sessions")
+	if err := flagSet.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	sessions, err := runtime.ListSessions(*dir)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+	if *asJSON {
+		if sessions == nil {
+			sessions = []runtime.SessionInfo{}
+		}
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(sessions); err != nil {
+			_, _ = fmt.Fprintf(stderr, "failed to encode sessions: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	if len(sessions) == 0 {
+		_, _ = fmt.Fprintf(stdout, "No sessions in %s.\n", *dir)
+		return 0
+	}
+
+	writer := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "ID\tUPDATED\tMESSAGES\tPROMPT")
+	for _, session := range sessions {
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%d\t%s\n",
+			session.ID, session.Updated.Local().Format(time.DateTime), session.Messages, sessionPrompt(session.Prompt))
+	}
+	_ = writer.Flush()
+	return 0
+}
+
+// sessionPrompt flattens prompt to one line of at most sessionPromptWidth
+// runes.
+func sessionPrompt(prompt string) string {
+	prompt = strings.Join(strings.Fields(prompt), " ")
+	if runes := []rune(prompt); len(runes) > sessionPromptWidth {
+		return string(runes[:sessionPromptWidth-1]) + "…"
+	}
+	return prompt
+}
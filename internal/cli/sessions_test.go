@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/asynkron/goagent/internal/core/runtime"
+)
+
+func TestSessionsListPrintsRecordedSessions(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	store := &runtime.JSONFileHistoryStore{Path: runtime.SessionHistoryPath(root, "20261017-101500-abcdef")}
+	if err := store.Snapshot(context.Background(), []runtime.ChatMessage{
+		{Role: runtime.RoleUser, Content: "fix the\nflaky test"},
+	}); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := runSessions([]string{"list", "--dir", root}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "20261017-101500-abcdef") || !strings.Contains(out, "fix the flaky test") {
+		t.Fatalf("unexpected listing:\n%s", out)
+	}
+
+	stdout.Reset()
+	if code := runSessions([]string{"list", "--json", "--dir", t.TempDir()}, &stdout, &stderr); code != 0 || strings.TrimSpace(stdout.String()) != "[]" {
+		t.Fatalf("expected an empty JSON list, got %d %q", code, stdout.String())
+	}
+	if code := runSessions(nil, &stdout, &stderr); code != 2 {
+		t.Fatalf("expected usage error, got %d", code)
+	}
+}
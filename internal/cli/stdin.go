@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// maxStdinBytes caps how much piped input is added to the prompt.
+const maxStdinBytes = 256 * 1024
+
+// stdinIsPiped reports whether f is a pipe or file rather than a terminal.
+func stdinIsPiped(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice == 0
+}
+
+// readPipedInput reads up to limit bytes and reports whether more followed.
+func readPipedInput(r io.Reader, limit int) (string, bool, error) {
+	data, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return "", false, err
+	}
+	truncated := len(data) > limit
+	if truncated {
+		data = data[:limit]
+	}
+	return strings.ToValidUTF8(string(data), ""), truncated, nil
+}
+
+// withPipedInput appends piped input to prompt as a fenced block. Input
+// alone becomes the whole prompt.
+func withPipedInput(prompt, input string, truncated bool) string {
+	input = strings.TrimRight(input, "\n")
+	if strings.TrimSpace(input) == "" {
+		return prompt
+	}
+	fence := "```"
+	for strings.Contains(input, fence) {
+		fence += "`"
+	}
+	header := "Input from stdin:"
+	if truncated {
+		header = fmt.Sprintf("Input from stdin (first %d bytes):", maxStdinBytes)
+	}
+	block := fmt.Sprintf("%s\n%s\n%s\n%s", header, fence, input, fence)
+	if strings.TrimSpace(prompt) == "" {
+		return block
+	}
+	return prompt + "\n\n" + block
+}
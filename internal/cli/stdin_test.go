@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPipedInputIsFencedAndCapped(t *testing.T) {
+	t.Parallel()
+
+	input, truncated, err := readPipedInput(strings.NewReader("panic: boom\n"), maxStdinBytes)
+	if err != nil || truncated {
+		t.Fatalf("read = %q, %v, %v", input, truncated, err)
+	}
+	got := withPipedInput("explain this failure", input, truncated)
+	want := "explain this failure\n\nInput from stdin:\n```\npanic: boom\n```"
+	if got != want {
+		t.Fatalf("prompt = %q, want %q", got, want)
+	}
+	if got := withPipedInput("", input, false); !strings.HasPrefix(got, "Input from stdin:") {
+		t.Fatalf("stdin-only prompt = %q", got)
+	}
+	if got := withPipedInput("explain", "  \n", false); got != "explain" {
+		t.Fatalf("blank input changed prompt: %q", got)
+	}
+
+	input, truncated, err = readPipedInput(strings.NewReader("abcdef"), 4)
+	if err != nil || !truncated || input != "abcd" {
+		t.Fatalf("capped read = %q, %v, %v", input, truncated, err)
+	}
+	if got := withPipedInput("x", "a ``` b", false); !strings.Contains(got, "````\na ``` b\n````") {
+		t.Fatalf("fence not lengthened: %q", got)
+	}
+}
@@ -0,0 +1,388 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/asynkron/goagent/internal/core/schema"
+)
+
+// AnthropicClient calls the Anthropic Messages API and forces the plan tool so
+// responses map onto the same ToolCall contract as the OpenAI client.
+type AnthropicClient struct {
+	apiKey      string
+	model       string
+	httpClient  *http.Client
+	tool        schema.ToolDefinition
+	extraTools  []schema.ToolDefinition
+	baseURL     string
+	maxTokens   int
+	logger      Logger
+	metrics     Metrics
+	retryConfig *RetryConfig
+}
+
+const (
+	defaultAnthropicBaseURL   = "https://api.anthropic.com/v1"
+	anthropicAPIVersion       = "2023-06-01"
+	defaultAnthropicMaxTokens = 8192
+)
+
+// NewAnthropicClient configures the client with the provided API key and model identifier.
+func NewAnthropicClient(apiKey, model, baseURL string, logger Logger, metrics Metrics, retryConfig *RetryConfig, httpTimeout time.Duration) (*AnthropicClient, error) {
+	if apiKey == "" {
+		return nil, errors.New("anthropic: API key is required")
+	}
+	if model == "" {
+		return nil, errors.New("anthropic: model is required")
+	}
+	baseURL = strings.TrimSpace(baseURL)
+	if baseURL == "" {
+		baseURL = defaultAnthropicBaseURL
+	}
+	tool, err := schema.Definition()
+	if err != nil {
+		return nil, err
+	}
+	if logger == nil {
+		logger = &NoOpLogger{}
+	}
+	if metrics == nil {
+		metrics = &NoOpMetrics{}
+	}
+	return &AnthropicClient{
+		apiKey: apiKey,
+		model:  model,
+		httpClient: &http.Client{
+			Timeout: httpTimeout,
+		},
+		tool:        tool,
+		baseURL:     baseURL,
+		maxTokens:   defaultAnthropicMaxTokens,
+		logger:      logger,
+		metrics:     metrics,
+		retryConfig: retryConfig,
+	}, nil
+}
+
+// AddTools advertises additional tools next to the plan tool.
+func (c *AnthropicClient) AddTools(tools ...schema.ToolDefinition) {
+	c.extraTools = append(c.extraTools, tools...)
+}
+
+// RequestPlan sends the chat history to Anthropic and returns the plan tool call.
+func (c *AnthropicClient) RequestPlan(ctx context.Context, history []ChatMessage) (ToolCall, error) {
+	return c.RequestPlanStreaming(ctx, history, nil)
+}
+
+// RequestPlanStreaming streams a Messages API response. Text deltas and the
+// partially decoded plan message are forwarded to onDelta, thinking and the
+// plan's reasoning entries to the ReasoningHandler of ctx, while tool_use
+// input deltas are accumulated into the returned ToolCall.
+func (c *AnthropicClient) RequestPlanStreaming(ctx context.Context, history []ChatMessage, onDelta func(string)) (ToolCall, error) {
+	start := time.Now()
+	c.logger.Debug(ctx, "Requesting plan from Anthropic",
+		Field("model", c.model),
+		Field("history_length", len(history)),
+	)
+
+	debugStream := strings.TrimSpace(os.Getenv("GOAGENT_DEBUG_STREAM")) != ""
+
+	payload, err := c.buildRequestBody(history)
+	if err != nil {
+		c.logger.Error(ctx, "Failed to build Anthropic request body", err,
+			Field("model", c.model),
+			Field("history_length", len(history)),
+		)
+		return ToolCall{}, fmt.Errorf("anthropic: build request body: %w", err)
+	}
+
+	resp, err := c.executeRequest(ctx, payload, start)
+	if err != nil {
+		return ToolCall{}, fmt.Errorf("anthropic: request failed after retries: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	parser := newAnthropicStreamParser(bufio.NewReader(resp.Body), onDelta, debugStream)
+	parser.onReasoning = ReasoningHandler(ctx)
+	toolCall, err := parser.parse()
+	duration := time.Since(start)
+	if err != nil {
+		c.metrics.RecordAPICall(duration, false)
+		c.logger.Error(ctx, "Anthropic API stream parsing failed", err,
+			Field("duration_ms", duration.Milliseconds()),
+			Field("model", c.model),
+		)
+		return ToolCall{}, fmt.Errorf("anthropic: stream parsing failed: %w", err)
+	}
+
+	c.metrics.RecordAPICall(duration, true)
+	c.logger.Debug(ctx, "Anthropic API request completed",
+		Field("duration_ms", duration.Milliseconds()),
+		Field("tool_name", toolCall.Name),
+	)
+	return toolCall, nil
+}
+
+// buildAnthropicMessages splits the history into the top-level system prompt
+// and the alternating user/assistant turns the Messages API expects. Tool
+// observations are sent as user turns and consecutive turns with the same
+// role are merged. Attachments follow their message's text as image or text
+// blocks.
+func buildAnthropicMessages(history []ChatMessage) (string, []map[string]any) {
+	var system []string
+	messages := make([]map[string]any, 0, len(history))
+	lastRole := ""
+	for _, m := range history {
+		if strings.TrimSpace(m.Content) == "" {
+			continue
+		}
+		if m.Role == RoleSystem {
+			system = append(system, m.Content)
+			continue
+		}
+		role := "user"
+		if m.Role == RoleAssistant {
+			role = "assistant"
+		}
+		blocks := []map[string]any{{"type": "text", "text": m.Content}}
+		for _, a := range m.Attachments {
+			if a.IsImage() {
+				blocks = append(blocks, map[string]any{
+					"type":   "image",
+					"source": map[string]any{"type": "base64", "media_type": a.MediaType, "data": a.Data},
+				})
+			} else {
+				blocks = append(blocks, map[string]any{"type": "text", "text": attachmentText(a)})
+			}
+		}
+		if role == lastRole {
+			prev := messages[len(messages)-1]
+			prev["content"] = append(prev["content"].([]map[string]any), blocks...)
+			continue
+		}
+		messages = append(messages, map[string]any{
+			"role":    role,
+			"content": blocks,
+		})
+		lastRole = role
+	}
+	return strings.Join(system, "\n\n"), messages
+}
+
+// buildRequestBody constructs the Messages API request and forces the plan tool.
+func (c *AnthropicClient) buildRequestBody(history []ChatMessage) ([]byte, error) {
+	system, messages := buildAnthropicMessages(history)
+	reqBody := map[string]any{
+		"model":       c.model,
+		"max_tokens":  c.maxTokens,
+		"messages":    messages,
+		"stream":      true,
+		"tools":       c.messageTools(),
+		"tool_choice": map[string]any{"type": "tool", "name": c.tool.Name},
+	}
+	if len(c.extraTools) > 0 {
+		// Let the model pick between the plan tool and host tools, one at a time.
+		reqBody["tool_choice"] = map[string]any{"type": "any", "disable_parallel_tool_use": true}
+	}
+	if system != "" {
+		reqBody["system"] = system
+	}
+	return json.Marshal(reqBody)
+}
+
+// messageTools lists the plan tool followed by any host tools.
+func (c *AnthropicClient) messageTools() []map[string]any {
+	tools := make([]map[string]any, 0, 1+len(c.extraTools))
+	for _, tool := range append([]schema.ToolDefinition{c.tool}, c.extraTools...) {
+		tools = append(tools, map[string]any{
+			"name":         tool.Name,
+			"description":  tool.Description,
+			"input_schema": tool.Parameters,
+		})
+	}
+	return tools
+}
+
+// executeRequest posts the payload with retries and returns the streaming response.
+func (c *AnthropicClient) executeRequest(ctx context.Context, payload []byte, start time.Time) (*http.Response, error) {
+	var resp *http.Response
+	var lastErr error
+
+	err := executeWithRetry(ctx, c.retryConfig, func() error {
+		url := strings.TrimRight(c.baseURL, "/") + "/messages"
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+		if err != nil {
+			lastErr = fmt.Errorf("anthropic(messages): build request: %w", err)
+			return lastErr
+		}
+		req.Header.Set("x-api-key", c.apiKey)
+		req.Header.Set("anthropic-version", anthropicAPIVersion)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err = c.httpClient.Do(req)
+		if err != nil {
+			retryable := isRetryableError(err)
+			c.logger.Error(ctx, "Anthropic API request failed", err,
+				Field("url", url),
+				Field("duration_ms", time.Since(start).Milliseconds()),
+				Field("retryable", retryable),
+			)
+			lastErr = &retryableAPIError{
+				err:       fmt.Errorf("anthropic(messages): do request: %w", err),
+				retryable: retryable,
+			}
+			return lastErr
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+			_ = resp.Body.Close()
+			// 529 signals an overloaded API and is worth retrying.
+			retryable := isRetryableStatusCode(resp.StatusCode)
+			c.logger.Error(ctx, "Anthropic API returned error status", fmt.Errorf("status %s: %s", resp.Status, string(msg)),
+				Field("status_code", resp.StatusCode),
+				Field("duration_ms", time.Since(start).Milliseconds()),
+				Field("retryable", retryable),
+			)
+			lastErr = &retryableAPIError{
+				err:        fmt.Errorf("anthropic(messages): status %s: %s", resp.Status, string(msg)),
+				statusCode: resp.StatusCode,
+				retryable:  retryable,
+				retryAfter: retryAfterFromHeader(resp.Header),
+			}
+			resp = nil
+			return lastErr
+		}
+		return nil
+	})
+
+	if err != nil {
+		c.metrics.RecordAPICall(time.Since(start), false)
+		if lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+// anthropicStreamParser reads the Messages API event stream. It reuses the
+// OpenAI parser's partial-JSON helpers so plan messages stream the same way.
+type anthropicStreamParser struct {
+	streamParser
+}
+
+func newAnthropicStreamParser(reader *bufio.Reader, onDelta func(string), debugStream bool) *anthropicStreamParser {
+	return &anthropicStreamParser{streamParser: streamParser{
+		reader:      reader,
+		onDelta:     onDelta,
+		debugStream: debugStream,
+	}}
+}
+
+// parse consumes SSE data lines until message_stop or EOF. Anthropic sends
+// the event name both as the "event:" line and as the payload's "type", so
+// only the data lines are inspected.
+func (p *anthropicStreamParser) parse() (ToolCall, error) {
+	for {
+		line, rerr := p.reader.ReadString('\n')
+		if rerr != nil && !errors.Is(rerr, io.EOF) {
+			return ToolCall{}, fmt.Errorf("anthropic(messages): stream read: %w", rerr)
+		}
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "data:") {
+			done, err := p.processData(strings.TrimSpace(strings.TrimPrefix(trimmed, "data:")))
+			if err != nil {
+				return ToolCall{}, err
+			}
+			if done {
+				break
+			}
+		}
+		if rerr != nil {
+			break
+		}
+	}
+
+	if p.toolName != "" {
+		return ToolCall{ID: p.toolID, Name: p.toolName, Arguments: p.toolArgs}, nil
+	}
+	return ToolCall{}, nil
+}
+
+// processData handles one event payload and reports whether the stream ended.
+func (p *anthropicStreamParser) processData(data string) (bool, error) {
+	var evt struct {
+		Type         string `json:"type"`
+		ContentBlock struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"content_block"`
+		Delta struct {
+			Type        string `json:"type"`
+			Text        string `json:"text"`
+			PartialJSON string `json:"partial_json"`
+			Thinking    string `json:"thinking"`
+		} `json:"delta"`
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(data), &evt); err != nil {
+		if p.debugStream {
+			fmt.Println("------ STREAM: decode-error", err)
+		}
+		return false, nil
+	}
+	if p.debugStream {
+		fmt.Println("------ STREAM:", evt.Type)
+	}
+
+	switch evt.Type {
+	case "content_block_start":
+		switch evt.ContentBlock.Type {
+		case "tool_use":
+			p.resetCall(evt.ContentBlock.ID)
+			p.toolName = evt.ContentBlock.Name
+		case "thinking":
+			p.newReasoningPart = true
+		}
+	case "content_block_delta":
+		switch evt.Delta.Type {
+		case "text_delta":
+			if evt.Delta.Text != "" && p.onDelta != nil {
+				p.onDelta(evt.Delta.Text)
+			}
+		case "thinking_delta":
+			separator := ""
+			if p.newReasoningPart {
+				separator = "\n\n"
+				p.newReasoningPart = false
+			}
+			p.emitReasoning(evt.Delta.Thinking, separator)
+		case "input_json_delta":
+			if evt.Delta.PartialJSON != "" {
+				p.toolArgs += evt.Delta.PartialJSON
+				p.emitMessageDelta(p.toolArgs)
+				p.emitReasoningDeltas(p.toolArgs)
+			}
+		}
+	case "message_stop":
+		return true, nil
+	case "error":
+		return true, fmt.Errorf("anthropic(messages): %s: %s", evt.Error.Type, evt.Error.Message)
+	}
+	return false, nil
+}
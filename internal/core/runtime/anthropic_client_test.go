@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asynkron/goagent/internal/core/schema"
+)
+
+func TestAnthropicClientStreamsToolUse(t *testing.T) {
+	t.Parallel()
+
+	var (
+		captured map[string]any
+		headers  http.Header
+		path     string
+	)
+	stream := strings.Join([]string{
+		`event: message_start`,
+		`data: {"type":"message_start","message":{"id":"msg_1","role":"assistant","content":[]}}`,
+		``,
+		`event: content_block_start`,
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"` + schema.ToolName + `","input":{}}}`,
+		``,
+		`event: ping`,
+		`data: {"type":"ping"}`,
+		``,
+		`event: content_block_delta`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"message\":\"he"}}`,
+		``,
+		`event: content_block_delta`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"llo\",\"plan\":[],\"requireHumanInput\":false}"}}`,
+		``,
+		`event: content_block_stop`,
+		`data: {"type":"content_block_stop","index":0}`,
+		``,
+		`event: message_stop`,
+		`data: {"type":"message_stop"}`,
+		``,
+	}, "\n")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		path = r.URL.Path
+		defer func() { _ = r.Body.Close() }()
+		if err := json.NewDecoder(r.Body).Decode(&captured); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(stream))
+	}))
+	defer server.Close()
+
+	client, err := NewAnthropicClient("test-key", "claude-test", server.URL, nil, nil, nil, 120*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected client error: %v", err)
+	}
+	client.httpClient = server.Client()
+
+	history := []ChatMessage{
+		{Role: RoleSystem, Content: "system prompt"},
+		{Role: RoleUser, Content: "hi"},
+		{Role: RoleTool, Content: "observation"},
+	}
+	var deltas strings.Builder
+	toolCall, err := client.RequestPlanStreaming(context.Background(), history, func(s string) { deltas.WriteString(s) })
+	if err != nil {
+		t.Fatalf("RequestPlanStreaming returned error: %v", err)
+	}
+
+	if toolCall.ID != "toolu_1" || toolCall.Name != schema.ToolName {
+		t.Fatalf("unexpected tool call metadata: %+v", toolCall)
+	}
+	if toolCall.Arguments != `{"message":"hello","plan":[],"requireHumanInput":false}` {
+		t.Fatalf("unexpected arguments: %s", toolCall.Arguments)
+	}
+	if deltas.String() != "hello" {
+		t.Fatalf("expected streamed message %q, got %q", "hello", deltas.String())
+	}
+
+	if path != "/messages" {
+		t.Fatalf("expected /messages path, got %s", path)
+	}
+	if headers.Get("x-api-key") != "test-key" || headers.Get("anthropic-version") == "" {
+		t.Fatalf("missing Anthropic auth headers: %v", headers)
+	}
+	if captured["system"] != "system prompt" {
+		t.Fatalf("expected system prompt to be lifted, got %v", captured["system"])
+	}
+	messages, ok := captured["messages"].([]any)
+	if !ok || len(messages) != 1 {
+		t.Fatalf("expected consecutive user turns to merge into one message, got %v", captured["messages"])
+	}
+	choice, _ := captured["tool_choice"].(map[string]any)
+	if choice["type"] != "tool" || choice["name"] != schema.ToolName {
+		t.Fatalf("expected forced tool choice, got %v", captured["tool_choice"])
+	}
+}
+
+func TestResolveProviderInfersFromModel(t *testing.T) {
+	t.Parallel()
+
+	cases := map[[2]string]string{
+		{"", "claude-sonnet-4"}:  ProviderAnthropic,
+		{"", "gpt-4.1"}:          ProviderOpenAI,
+		{"OpenAI", "claude-foo"}: ProviderOpenAI,
+	}
+	for in, want := range cases {
+		if got := ResolveProvider(in[0], in[1]); got != want {
+			t.Fatalf("ResolveProvider(%q, %q) = %q, want %q", in[0], in[1], got, want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ApprovalPolicy controls when the runtime pauses to ask the host for
+// permission before executing a plan step.
+type ApprovalPolicy string
+
+const (
+	// ApprovalPolicyNever executes every step without asking. This is the
+	// default and matches the historical behaviour.
+	ApprovalPolicyNever ApprovalPolicy = "never"
+	// ApprovalPolicyOnWrite asks before apply_patch and any shell command that
+	// is not recognised as read-only.
+	ApprovalPolicyOnWrite ApprovalPolicy = "on-write"
+	// ApprovalPolicyAlways asks before every step.
+	ApprovalPolicyAlways ApprovalPolicy = "always"
+)
+
+// readOnlyCommands lists programs that only inspect the workspace. Commands
+// outside this list are assumed to be able to write under
+// ApprovalPolicyOnWrite.
+var readOnlyCommands = map[string]bool{
+	"cat": true, "head": true, "tail": true, "less": true, "ls": true,
+	"pwd": true, "echo": true, "grep": true, "rg": true, "find": true,
+	"wc": true, "tree": true, "stat": true, "file": true, "which": true,
+	"sed": true, "awk": true, "sort": true, "uniq": true, "cut": true,
+	"diff": true, "du": true, "df": true, "env": true, "printenv": true,
+	"date": true, "whoami": true, "uname": true,
+}
+
+// sedWriteScript matches sed scripts that write files or run commands: the
+// w/W and e commands and the s command's w and e flags.
+var sedWriteScript = regexp.MustCompile(`(^|[\s'";{}/0-9$])[wWe]\s+\S|/[gpiImM0-9]*[we]([\s'";}]|$)`)
+
+// findWriteActions are find actions that delete files, write files or run
+// commands.
+var findWriteActions = map[string]bool{
+	"-delete": true, "-exec": true, "-execdir": true, "-ok": true, "-okdir": true,
+	"-fprint": true, "-fprint0": true, "-fprintf": true, "-fls": true,
+}
+
+// awkWriteScript matches awk invocations that run commands or edit files in
+// place.
+var awkWriteScript = regexp.MustCompile(`\bsystem\s*\(|(^|\s)-i\s*inplace\b|--include[= ]inplace\b`)
+
+// readOnlyBranchFlags are the git branch flags that only list branches.
+var readOnlyBranchFlags = map[string]bool{
+	"-a": true, "--all": true, "-r": true, "--remotes": true, "-v": true,
+	"-vv": true, "--verbose": true, "-l": true, "--list": true,
+	"--show-current": true, "--no-color": true, "--color": true,
+}
+
+// readOnlyGitSubcommands lists git subcommands that never modify the
+// repository or working tree.
+var readOnlyGitSubcommands = map[string]bool{
+	"status": true, "diff": true, "log": true, "show": true, "blame": true,
+	"branch": true, "rev-parse": true, "ls-files": true, "grep": true,
+}
+
+// writingInternalCommands lists internal commands that modify the workspace
+// or the git index. run_tests executes project code, which may write.
+var writingInternalCommands = map[string]bool{
+	applyPatchCommandName: true,
+	writeFileCommandName:  true,
+	gitStageCommandName:   true,
+	runTestsCommandName:   true,
+}
+
+// requiresApproval reports whether step must be confirmed by the host before
+// it runs. Hands-free sessions have nobody to answer, so the executor denies
+// such steps instead of asking.
+func (r *Runtime) requiresApproval(step PlanStep) bool {
+	switch r.options.ApprovalPolicy {
+	case ApprovalPolicyAlways:
+		return true
+	case ApprovalPolicyOnWrite:
+		return stepMayWrite(step)
+	default:
+		return false
+	}
+}
+
+// stepMayWrite conservatively classifies a step as mutating unless every
+// command in it is known to be read-only.
+func stepMayWrite(step PlanStep) bool {
+	run := strings.TrimSpace(step.Command.Run)
+	if strings.EqualFold(strings.TrimSpace(step.Command.Shell), agentShell) {
+		name, _, _ := strings.Cut(run, "\n")
+		fields := strings.Fields(name)
+		return len(fields) == 0 || writingInternalCommands[strings.ToLower(fields[0])]
+	}
+	// Redirections and substitutions can write through otherwise harmless
+	// programs.
+	if strings.ContainsAny(run, ">`$") {
+		return true
+	}
+	// "&&" must be listed before "&", which also separates commands.
+	replacer := strings.NewReplacer("&&", "\n", "||", "\n", "&", "\n", ";", "\n", "|", "\n", "\r", "\n")
+	for _, segment := range strings.Split(replacer.Replace(run), "\n") {
+		if segmentMayWrite(segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// segmentMayWrite classifies a single command of a shell step. Programs on
+// readOnlyCommands still write through some arguments, which are checked
+// here.
+func segmentMayWrite(segment string) bool {
+	fields := strings.Fields(segment)
+	if len(fields) == 0 {
+		return false
+	}
+	program, args := fields[0], fields[1:]
+	if program == "git" {
+		if len(args) == 0 || !readOnlyGitSubcommands[args[0]] || hasOutputFlag(args[1:], "") {
+			return true
+		}
+		switch args[0] {
+		case "branch":
+			return !branchOnlyLists(args[1:])
+		case "grep":
+			// -O opens the matches with an arbitrary program.
+			return slices.ContainsFunc(args[1:], func(arg string) bool {
+				return strings.HasPrefix(arg, "-O") || strings.HasPrefix(arg, "--open-files-in-pager")
+			})
+		}
+		return false
+	}
+	if !readOnlyCommands[program] {
+		return true
+	}
+	switch program {
+	case "env":
+		// env runs its arguments as a command.
+		return len(args) > 0
+	case "sort":
+		return hasOutputFlag(args, "-o")
+	case "tree":
+		return hasOutputFlag(args, "-o")
+	case "uniq":
+		// A second operand is the output file.
+		operands := 0
+		for _, arg := range args {
+			if !strings.HasPrefix(arg, "-") {
+				operands++
+			}
+		}
+		return operands > 1
+	case "find":
+		return slices.ContainsFunc(args, func(arg string) bool { return findWriteActions[arg] })
+	case "awk":
+		// system() runs commands; gawk -i inplace rewrites its inputs.
+		return awkWriteScript.MatchString(strings.Join(args, " "))
+	case "sed":
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "-i") || strings.HasPrefix(arg, "--in-place") {
+				return true
+			}
+		}
+		return sedWriteScript.MatchString(strings.Join(args, " "))
+	}
+	return false
+}
+
+// hasOutputFlag reports whether args name an output file with --output or
+// with short, when set (e.g. "-o", also as "-ofile").
+func hasOutputFlag(args []string, short string) bool {
+	for _, arg := range args {
+		if arg == "--output" || strings.HasPrefix(arg, "--output=") {
+			return true
+		}
+		if short != "" && strings.HasPrefix(arg, short) {
+			return true
+		}
+	}
+	return false
+}
+
+// branchOnlyLists reports whether git branch args only list branches;
+// anything else may create, rename, delete or reconfigure one.
+func branchOnlyLists(args []string) bool {
+	listing := false
+	var operands int
+	for _, arg := range args {
+		switch {
+		case arg == "-l" || arg == "--list":
+			listing = true
+		case readOnlyBranchFlags[arg], strings.HasPrefix(arg, "--contains"), strings.HasPrefix(arg, "--merged"),
+			strings.HasPrefix(arg, "--no-merged"), strings.HasPrefix(arg, "--sort="), strings.HasPrefix(arg, "--format="):
+		case strings.HasPrefix(arg, "-"):
+			return false
+		default:
+			operands++
+		}
+	}
+	// "git branch <name>" creates a branch; with --list the operands are
+	// patterns.
+	return operands == 0 || listing
+}
+
+// awaitApproval emits an approval request for step and blocks until the host
+// answers through an InputTypeApprovalDecision event. Other inputs go to
+// handle, the plan's input handler, so prompts and steering are deferred,
+// stdin reaches running steps, and a cancel or shutdown stops the whole
+// plan, which rejects the step as ctx ends.
+func (r *Runtime) awaitApproval(ctx context.Context, step PlanStep, handle func(InputEvent, bool)) (bool, string) {
+	title := strings.TrimSpace(step.Title)
+	if title == "" {
+		title = step.ID
+	}
+	r.emit(RuntimeEvent{
+		Type:    EventTypeApprovalRequest,
+		Message: fmt.Sprintf("Approve step %s: %s?", step.ID, title),
+		Level:   StatusLevelWarn,
+		Metadata: map[string]any{
+			"step_id": step.ID,
+			"title":   step.Title,
+			"command": step.Command.Run,
+			"shell":   step.Command.Shell,
+			"cwd":     step.Command.Cwd,
+		},
+	})
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false, "canceled: " + ctx.Err().Error()
+		case <-r.closed:
+			return false, "runtime closed"
+		case evt, ok := <-r.inputs:
+			if ok && evt.Type == InputTypeApprovalDecision {
+				if evt.StepID != "" && evt.StepID != step.ID {
+					r.emit(RuntimeEvent{
+						Type:    EventTypeStatus,
+						Message: fmt.Sprintf("Ignoring approval for step %s while waiting on step %s.", evt.StepID, step.ID),
+						Level:   StatusLevelWarn,
+					})
+					continue
+				}
+				return evt.Approved, strings.TrimSpace(evt.Reason)
+			}
+			handle(evt, ok)
+			if !ok {
+				return false, "input channel closed"
+			}
+		}
+	}
+}
+
+// SubmitApproval answers a pending approval request for the given step.
+func (r *Runtime) SubmitApproval(stepID string, approved bool, reason string) {
+	r.enqueue(InputEvent{Type: InputTypeApprovalDecision, StepID: stepID, Approved: approved, Reason: reason})
+}
@@ -0,0 +1,210 @@
+package runtime
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStepMayWriteClassifiesCommands(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		shell string
+		run   string
+		want  bool
+	}{
+		{shell: "bash", run: "ls -la && cat README.md | grep -i go", want: false},
+		{shell: "bash", run: "git status", want: false},
+		{shell: "bash", run: "git commit -m wip", want: true},
+		{shell: "bash", run: "echo hi > out.txt", want: true},
+		{shell: "bash", run: "sed -i 's/a/b/' file", want: true},
+		{shell: "bash", run: "go test ./...", want: true},
+		{shell: "bash", run: "cat x & rm -rf y", want: true},
+		{shell: "bash", run: "cat x\nrm -rf y", want: true},
+		{shell: "bash", run: "env rm -rf y", want: true},
+		{shell: "bash", run: "env", want: false},
+		{shell: "bash", run: "sort -o f in.txt", want: true},
+		{shell: "bash", run: "sort --output=f in.txt", want: true},
+		{shell: "bash", run: "sort -r in.txt", want: false},
+		{shell: "bash", run: "uniq a b", want: true},
+		{shell: "bash", run: "uniq -c a", want: false},
+		{shell: "bash", run: "tree -o f", want: true},
+		{shell: "bash", run: "sed 'w f' in.txt", want: true},
+		{shell: "bash", run: "sed -n '1,5w out' in.txt", want: true},
+		{shell: "bash", run: "sed 's/a/b/w out' in.txt", want: true},
+		{shell: "bash", run: "sed 's/a/rm x/e' in.txt", want: true},
+		{shell: "bash", run: "sed -n '/error/p' /etc/hosts", want: false},
+		{shell: "bash", run: "git branch new", want: true},
+		{shell: "bash", run: "git branch -a", want: false},
+		{shell: "bash", run: "git branch --list 'feat/*'", want: false},
+		{shell: "bash", run: "git diff --output=f", want: true},
+		{shell: "bash", run: "git diff --stat", want: false},
+		{shell: "bash", run: "find . -name '*.tmp' -delete", want: true},
+		{shell: "bash", run: "find . -name '*.tmp' -exec rm {} +", want: true},
+		{shell: "bash", run: "find . -type f -fprint list.txt", want: true},
+		{shell: "bash", run: "find . -name '*.go'", want: false},
+		{shell: "bash", run: "awk 'BEGIN { system(\"rm -rf y\") }'", want: true},
+		{shell: "bash", run: "awk -i inplace '{ print }' f", want: true},
+		{shell: "bash", run: "awk -F: '{ print NF }' /etc/passwd", want: false},
+		{shell: "bash", run: "git grep -Ovim TODO", want: true},
+		{shell: "bash", run: "git grep --open-files-in-pager=vim TODO", want: true},
+		{shell: "bash", run: "git grep -n TODO", want: false},
+		{shell: agentShell, run: "apply_patch\n*** Begin Patch", want: true},
+		{shell: agentShell, run: "run_research goal=x", want: false},
+		{shell: agentShell, run: "write_file notes.txt\nhello", want: true},
+		{shell: agentShell, run: "read_file notes.txt", want: false},
+	}
+	for _, tc := range cases {
+		step := PlanStep{ID: "s", Command: CommandDraft{Shell: tc.shell, Run: tc.run}}
+		if got := stepMayWrite(step); got != tc.want {
+			t.Fatalf("stepMayWrite(%q) = %v, want %v", tc.run, got, tc.want)
+		}
+	}
+}
+
+func TestExecutePendingCommands_RejectedStepIsNotExecuted(t *testing.T) {
+	t.Parallel()
+
+	rt := &Runtime{
+		options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}, ApprovalPolicy: ApprovalPolicyAlways},
+		inputs:    make(chan InputEvent, 1),
+		plan:      NewPlanManager(),
+		executor:  NewCommandExecutor(nil, nil),
+		outputs:   make(chan RuntimeEvent, 10),
+		closed:    make(chan struct{}),
+		history:   []ChatMessage{},
+		agentName: "main",
+	}
+
+	executed := false
+	if err := rt.executor.RegisterInternalCommand("touch", func(context.Context, InternalCommandRequest) (PlanObservationPayload, error) {
+		executed = true
+		return PlanObservationPayload{}, nil
+	}); err != nil {
+		t.Fatalf("failed to register internal command: %v", err)
+	}
+	rt.plan.Replace([]PlanStep{{
+		ID:      "step-1",
+		Title:   "Touch",
+		Status:  PlanPending,
+		Command: CommandDraft{Shell: agentShell, Run: "touch"},
+	}})
+
+	rt.SubmitApproval("step-1", false, "not now")
+	rt.executePendingCommands(context.Background(), ToolCall{ID: "call-1", Name: "open-agent"})
+	close(rt.outputs)
+
+	if executed {
+		t.Fatalf("expected rejected step not to run")
+	}
+	var sawRequest bool
+	for evt := range rt.outputs {
+		if evt.Type == EventTypeApprovalRequest && evt.Metadata["step_id"] == "step-1" {
+			sawRequest = true
+		}
+	}
+	if !sawRequest {
+		t.Fatalf("expected an approval request event")
+	}
+	history := rt.historySnapshot()
+	if len(history) != 1 || !strings.Contains(history[0].Content, "not approved: not now") {
+		t.Fatalf("expected rejection in tool observation, got %#v", history)
+	}
+}
+
+func TestExecutePendingCommands_HandsFreeDeniesStepsNeedingApproval(t *testing.T) {
+	t.Parallel()
+
+	rt := &Runtime{
+		options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}, ApprovalPolicy: ApprovalPolicyOnWrite, HandsFree: true},
+		inputs:    make(chan InputEvent, 1),
+		plan:      NewPlanManager(),
+		executor:  NewCommandExecutor(nil, nil),
+		outputs:   make(chan RuntimeEvent, 10),
+		closed:    make(chan struct{}),
+		history:   []ChatMessage{},
+		agentName: "main",
+	}
+
+	executed := false
+	if err := rt.executor.RegisterInternalCommand(writeFileCommandName, func(context.Context, InternalCommandRequest) (PlanObservationPayload, error) {
+		executed = true
+		return PlanObservationPayload{}, nil
+	}); err != nil {
+		t.Fatalf("failed to register internal command: %v", err)
+	}
+	rt.plan.Replace([]PlanStep{{
+		ID:      "step-1",
+		Title:   "Write",
+		Status:  PlanPending,
+		Command: CommandDraft{Shell: agentShell, Run: "write_file notes.txt\nhello"},
+	}})
+
+	rt.executePendingCommands(context.Background(), ToolCall{ID: "call-1", Name: "open-agent"})
+	close(rt.outputs)
+
+	if executed {
+		t.Fatalf("expected a hands-free session not to run a step needing approval")
+	}
+	for evt := range rt.outputs {
+		if evt.Type == EventTypeApprovalRequest {
+			t.Fatalf("expected no approval request in a hands-free session")
+		}
+	}
+	history := rt.historySnapshot()
+	if len(history) != 1 || !strings.Contains(history[0].Content, "hands-free") {
+		t.Fatalf("expected hands-free denial in tool observation, got %#v", history)
+	}
+}
+
+func TestAwaitApprovalDefersInputsAndCancelsThePlan(t *testing.T) {
+	t.Parallel()
+
+	rt := &Runtime{
+		options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}, ApprovalPolicy: ApprovalPolicyAlways},
+		inputs:    make(chan InputEvent, 4),
+		plan:      NewPlanManager(),
+		executor:  NewCommandExecutor(nil, nil),
+		outputs:   make(chan RuntimeEvent, 32),
+		closed:    make(chan struct{}),
+		history:   []ChatMessage{},
+		agentName: "main",
+	}
+	executed := false
+	if err := rt.executor.RegisterInternalCommand("touch", func(context.Context, InternalCommandRequest) (PlanObservationPayload, error) {
+		executed = true
+		return PlanObservationPayload{}, nil
+	}); err != nil {
+		t.Fatalf("failed to register internal command: %v", err)
+	}
+	rt.plan.Replace([]PlanStep{
+		{ID: "step-1", Title: "Touch", Status: PlanPending, Command: CommandDraft{Shell: agentShell, Run: "touch"}},
+		{ID: "step-2", Title: "Touch again", Status: PlanPending, Command: CommandDraft{Shell: agentShell, Run: "touch"}},
+	})
+
+	go func() {
+		for evt := range rt.outputs {
+			if evt.Type == EventTypeApprovalRequest {
+				rt.enqueue(InputEvent{Type: InputTypePrompt, Prompt: "also check the docs"})
+				rt.enqueue(InputEvent{Type: InputTypeCancel, Reason: "wrong approach"})
+				return
+			}
+		}
+	}()
+	if !rt.executePendingCommands(context.Background(), ToolCall{ID: "call-1", Name: "open-agent"}) {
+		t.Fatal("expected a cancel while awaiting approval to cancel the plan")
+	}
+	if executed {
+		t.Fatal("expected no step to run")
+	}
+	select {
+	case evt := <-rt.inputs:
+		if evt.Type != InputTypePrompt || evt.Prompt != "also check the docs" {
+			t.Fatalf("expected the prompt to be re-queued, got %+v", evt)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the prompt sent while awaiting approval to be re-queued")
+	}
+}
@@ -0,0 +1,174 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	readArtifactCommandName = "read_artifact"
+
+	defaultArtifactPageBytes = 16 * 1024
+)
+
+// artifactSeq keeps artifact ids unique when several steps finish within
+// the same second.
+var artifactSeq atomic.Int64
+
+var (
+	// artifactIDPattern guards read_artifact against ids that would leave
+	// the artifact directory.
+	artifactIDPattern   = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+	unsafeArtifactChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+)
+
+// ArtifactRef points at the full output of a stream whose observation was
+// truncated. read_artifact pages through it.
+type ArtifactRef struct {
+	ID     string `json:"id"`
+	Stream string `json:"stream"`
+	Size   int    `json:"size"`
+}
+
+// ArtifactPage is the structured result of read_artifact.
+type ArtifactPage struct {
+	ID string `json:"id"`
+	// Offset is the byte offset of the first returned byte.
+	Offset     int    `json:"offset"`
+	Bytes      int    `json:"bytes"`
+	Size       int64  `json:"size"`
+	NextOffset int    `json:"next_offset,omitempty"`
+	EOF        bool   `json:"eof"`
+	Content    string `json:"content"`
+}
+
+// SetArtifactDir stores the full stdout/stderr of truncated shell steps
+// under dir so the model can read them with read_artifact. An empty dir
+// disables the store and truncated output is dropped as before.
+func (e *CommandExecutor) SetArtifactDir(dir string) {
+	e.artifactDir = dir
+}
+
+// saveArtifacts writes the raw streams of a truncated step and returns the
+// references to put in its observation.
+func (e *CommandExecutor) saveArtifacts(step PlanStep, streams map[string][]byte) ([]ArtifactRef, error) {
+	if e.artifactDir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(e.artifactDir, 0o755); err != nil {
+		return nil, err
+	}
+	base := fmt.Sprintf("%s-%d-%s", time.Now().Format("20060102-150405"), artifactSeq.Add(1), artifactName(step.ID))
+	var refs []ArtifactRef
+	for _, stream := range []string{"stdout", "stderr"} {
+		data := streams[stream]
+		if len(data) == 0 {
+			continue
+		}
+		id := base + "-" + stream
+		if err := os.WriteFile(filepath.Join(e.artifactDir, id), data, 0o644); err != nil {
+			return refs, fmt.Errorf("write artifact %s: %w", id, err)
+		}
+		refs = append(refs, ArtifactRef{ID: id, Stream: stream, Size: len(data)})
+	}
+	return refs, nil
+}
+
+// artifactName reduces a step id to characters that are safe in file names.
+func artifactName(stepID string) string {
+	name := unsafeArtifactChars.ReplaceAllString(stepID, "_")
+	if name == "" {
+		name = "step"
+	}
+	return name
+}
+
+// newReadArtifactCommand handles "read_artifact id=<id> [offset=N] [limit=N]".
+// The id may also be given as the first positional argument.
+func newReadArtifactCommand(executor *CommandExecutor) InternalCommandHandler {
+	return func(_ context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		id := strings.TrimSpace(argString(req, "id", ""))
+		if args := positionalStrings(req); id == "" && len(args) > 0 {
+			id = args[0]
+		}
+		if id == "" {
+			return failFileCommand(errors.New("read_artifact requires an id"))
+		}
+		if executor.artifactDir == "" {
+			return failFileCommand(errors.New("read_artifact: the artifact store is disabled"))
+		}
+		if !artifactIDPattern.MatchString(id) || strings.Trim(id, ".") == "" {
+			return failFileCommand(fmt.Errorf("read_artifact: invalid artifact id %q", id))
+		}
+		offset := max(argInt(req, "offset", 0), 0)
+		limit := argInt(req, "limit", defaultArtifactPageBytes)
+		if limit <= 0 || limit > maxObservationBytes {
+			limit = defaultArtifactPageBytes
+		}
+
+		page, err := readArtifactPage(filepath.Join(executor.artifactDir, id), offset, limit)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				err = fmt.Errorf("read_artifact: unknown artifact %q", id)
+			}
+			return failFileCommand(err)
+		}
+		page.ID = id
+		return structuredObservation(page)
+	}
+}
+
+// readArtifactPage reads up to limit bytes at offset, ending the page on a
+// UTF-8 boundary so multi-byte characters are not split between pages.
+func readArtifactPage(path string, offset, limit int) (ArtifactPage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ArtifactPage{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return ArtifactPage{}, err
+	}
+	size := info.Size()
+	page := ArtifactPage{Offset: offset, Size: size}
+	if int64(offset) >= size {
+		page.EOF = true
+		return page, nil
+	}
+
+	buf := make([]byte, limit)
+	n, err := f.ReadAt(buf, int64(offset))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return ArtifactPage{}, err
+	}
+	buf = buf[:n]
+	if int64(offset+n) < size {
+		// Back up to the start of a character that was cut off.
+		for end := len(buf); end > 0 && end > len(buf)-utf8.UTFMax; end-- {
+			if utf8.RuneStart(buf[end-1]) {
+				if !utf8.FullRune(buf[end-1:]) {
+					buf = buf[:end-1]
+				}
+				break
+			}
+		}
+	}
+	page.Bytes = len(buf)
+	page.Content = string(buf)
+	if next := offset + len(buf); int64(next) < size {
+		page.NextOffset = next
+	} else {
+		page.EOF = true
+	}
+	return page, nil
+}
@@ -0,0 +1,92 @@
+package runtime
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestTruncatedOutputIsStoredAsArtifact(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	executor := newFileCommandExecutor(t)
+	executor.SetArtifactDir(dir)
+
+	size := maxObservationBytes + 1000
+	observation, err := executor.Execute(context.Background(), PlanStep{ID: "big output", Command: CommandDraft{
+		Shell: "/bin/sh",
+		Run:   "printf 'first\\n'; head -c " + strconv.Itoa(size) + " /dev/zero | tr '\\0' x",
+		Cwd:   dir,
+	}})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if !observation.Truncated || len(observation.Stdout) != maxObservationBytes {
+		t.Fatalf("expected a truncated tail, got %d bytes (truncated=%v)", len(observation.Stdout), observation.Truncated)
+	}
+	if len(observation.Artifacts) != 1 || observation.Artifacts[0].Stream != "stdout" || observation.Artifacts[0].Size != size+len("first\n") {
+		t.Fatalf("unexpected artifacts %+v", observation.Artifacts)
+	}
+	id := observation.Artifacts[0].ID
+	if strings.ContainsAny(id, " /") {
+		t.Fatalf("artifact id %q is not file-name safe", id)
+	}
+
+	read := func(command string) ArtifactPage {
+		t.Helper()
+		result, err := executor.Execute(context.Background(), PlanStep{ID: "read", Command: CommandDraft{Shell: agentShell, Run: command}})
+		if err != nil {
+			t.Fatalf("%s: %v", command, err)
+		}
+		page, ok := result.Data.(ArtifactPage)
+		if !ok {
+			t.Fatalf("%s: unexpected data %#v", command, result.Data)
+		}
+		return page
+	}
+
+	first := read("read_artifact id=" + id + " limit=10")
+	if first.Content != "first\nxxxx" || first.NextOffset != 10 || first.EOF {
+		t.Fatalf("unexpected first page %+v", first)
+	}
+	last := read("read_artifact " + id + " offset=" + strconv.Itoa(size))
+	if last.Bytes != len("first\n") || !last.EOF || last.NextOffset != 0 {
+		t.Fatalf("unexpected last page %+v", last)
+	}
+
+	for _, bad := range []string{"read_artifact id=..", "read_artifact id=../secret", "read_artifact id=missing"} {
+		if _, err := executor.Execute(context.Background(), PlanStep{ID: "read", Command: CommandDraft{Shell: agentShell, Run: bad}}); err == nil {
+			t.Fatalf("%s: expected an error", bad)
+		}
+	}
+}
+
+func TestReadArtifactPageKeepsCharactersWhole(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	executor := NewCommandExecutor(nil, nil)
+	executor.SetArtifactDir(dir)
+	refs, err := executor.saveArtifacts(PlanStep{ID: "s"}, map[string][]byte{"stderr": []byte("aé€b")})
+	if err != nil || len(refs) != 1 {
+		t.Fatalf("save artifacts: %v %+v", err, refs)
+	}
+
+	page, err := readArtifactPage(filepath.Join(dir, refs[0].ID), 0, 3)
+	if err != nil {
+		t.Fatalf("read page: %v", err)
+	}
+	if page.Content != "aé" || page.NextOffset != 3 {
+		t.Fatalf("unexpected page %+v", page)
+	}
+	page, err = readArtifactPage(filepath.Join(dir, refs[0].ID), 3, 3)
+	if err != nil {
+		t.Fatalf("read page: %v", err)
+	}
+	if page.Content != "€" || page.NextOffset != 6 {
+		t.Fatalf("unexpected page %+v", page)
+	}
+}
@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxImageAttachmentBytes matches the per-image limit of the providers.
+	maxImageAttachmentBytes = 5 << 20
+	// maxTextAttachmentBytes caps text files inlined into a prompt.
+	maxTextAttachmentBytes = 256 << 10
+	// imageAttachmentTokens is a rough per-image estimate for the context
+	// budget; providers bill images by resolution, not by encoded size.
+	imageAttachmentTokens = 1000
+)
+
+// Attachment is a file sent along with a prompt. Images go to the model as
+// image input on vision-capable models; everything else is inlined as text.
+type Attachment struct {
+	Name      string `json:"name"`
+	MediaType string `json:"media_type"`
+	// Data is base64 for images and the file contents for text.
+	Data string `json:"data"`
+}
+
+// attachmentImageTypes lists the image formats both providers accept.
+var attachmentImageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// IsImage reports whether the attachment is sent as image input.
+func (a Attachment) IsImage() bool {
+	return attachmentImageTypes[a.MediaType]
+}
+
+// Validate checks that the attachment has content the providers can encode.
+func (a Attachment) Validate() error {
+	if strings.TrimSpace(a.Name) == "" {
+		return errors.New("attachment: name is required")
+	}
+	if a.IsImage() {
+		decoded, err := base64.StdEncoding.DecodeString(a.Data)
+		if err != nil {
+			return fmt.Errorf("attachment %s: image data must be base64: %w", a.Name, err)
+		}
+		if len(decoded) > maxImageAttachmentBytes {
+			return fmt.Errorf("attachment %s: image exceeds %d bytes", a.Name, maxImageAttachmentBytes)
+		}
+		return nil
+	}
+	if strings.HasPrefix(a.MediaType, "image/") {
+		return fmt.Errorf("attachment %s: unsupported image type %s (use png, jpeg, gif or webp)", a.Name, a.MediaType)
+	}
+	if !utf8.ValidString(a.Data) {
+		return fmt.Errorf("attachment %s: only images and text files can be attached", a.Name)
+	}
+	if len(a.Data) > maxTextAttachmentBytes {
+		return fmt.Errorf("attachment %s: text exceeds %d bytes", a.Name, maxTextAttachmentBytes)
+	}
+	return nil
+}
+
+// LoadAttachment reads path into an Attachment named after the file. The
+// media type is sniffed from the contents, so a screenshot without an
+// extension still becomes an image.
+func LoadAttachment(path string) (Attachment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("attachment: %w", err)
+	}
+	mediaType, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	attachment := Attachment{Name: filepath.Base(path), MediaType: mediaType}
+	if attachment.IsImage() {
+		attachment.Data = base64.StdEncoding.EncodeToString(data)
+	} else {
+		if !strings.HasPrefix(mediaType, "image/") && utf8.Valid(data) {
+			attachment.MediaType = "text/plain"
+		}
+		attachment.Data = string(data)
+	}
+	if err := attachment.Validate(); err != nil {
+		return Attachment{}, err
+	}
+	return attachment, nil
+}
+
+// attachmentText renders a text attachment as a fenced block.
+func attachmentText(a Attachment) string {
+	fence := "```"
+	for strings.Contains(a.Data, fence) {
+		fence += "`"
+	}
+	return fmt.Sprintf("Attached file %s:\n%s\n%s\n%s", a.Name, fence, strings.TrimRight(a.Data, "\n"), fence)
+}
+
+// dataURL encodes an image attachment for the OpenAI input_image type.
+func (a Attachment) dataURL() string {
+	return "data:" + a.MediaType + ";base64," + a.Data
+}
+
+func validateAttachments(attachments []Attachment) error {
+	for _, a := range attachments {
+		if err := a.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pngHeader is enough of a PNG file for content sniffing.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestLoadAttachmentSniffsImagesAndText(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	image, err := LoadAttachment(write("screenshot", pngHeader))
+	if err != nil {
+		t.Fatalf("load image: %v", err)
+	}
+	if !image.IsImage() || image.MediaType != "image/png" || image.Name != "screenshot" {
+		t.Fatalf("unexpected image attachment %+v", image)
+	}
+
+	text, err := LoadAttachment(write("error.log", []byte("panic: boom\n")))
+	if err != nil {
+		t.Fatalf("load text: %v", err)
+	}
+	if text.IsImage() || text.MediaType != "text/plain" || text.Data != "panic: boom\n" {
+		t.Fatalf("unexpected text attachment %+v", text)
+	}
+
+	if _, err := LoadAttachment(write("blob.bin", []byte{0xff, 0xfe, 0x00, 0x01})); err == nil {
+		t.Fatal("expected binary file to be rejected")
+	}
+}
+
+func TestProvidersEncodeAttachments(t *testing.T) {
+	t.Parallel()
+
+	history := []ChatMessage{{
+		Role:    RoleUser,
+		Content: "what is wrong here?",
+		Attachments: []Attachment{
+			{Name: "shot.png", MediaType: "image/png", Data: "iVBORw=="},
+			{Name: "error.log", MediaType: "text/plain", Data: "panic: boom\n"},
+		},
+	}}
+
+	openai := buildMessagesFromHistory(history)
+	content := openai[0]["content"].([]map[string]any)
+	if len(content) != 3 || content[1]["type"] != "input_image" || content[1]["image_url"] != "data:image/png;base64,iVBORw==" {
+		t.Fatalf("unexpected OpenAI content %v", content)
+	}
+	if text, _ := content[2]["text"].(string); !strings.Contains(text, "Attached file error.log:\n```\npanic: boom\n```") {
+		t.Fatalf("unexpected OpenAI text attachment %q", text)
+	}
+
+	_, messages := buildAnthropicMessages(history)
+	blocks := messages[0]["content"].([]map[string]any)
+	if len(blocks) != 3 || blocks[1]["type"] != "image" {
+		t.Fatalf("unexpected Anthropic blocks %v", blocks)
+	}
+	if source := blocks[1]["source"].(map[string]any); source["media_type"] != "image/png" || source["data"] != "iVBORw==" {
+		t.Fatalf("unexpected Anthropic image source %v", source)
+	}
+}
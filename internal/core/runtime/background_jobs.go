@@ -0,0 +1,301 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	jobsCommandName    = "jobs"
+	killJobCommandName = "kill_job"
+
+	// backgroundStartupWait is how long a background step is observed before
+	// the plan continues, so early crashes and startup banners are reported.
+	backgroundStartupWait = 2 * time.Second
+	// backgroundOutputLimit caps the output retained per job.
+	backgroundOutputLimit = 64 * 1024
+	// backgroundTailLines is how much recent output observations include.
+	backgroundTailLines = 20
+	// backgroundStopGrace is how long a job may exit after SIGTERM before it is killed.
+	backgroundStopGrace = 3 * time.Second
+)
+
+// backgroundJob is a long-running process started by a step with
+// CommandDraft.Background set.
+type backgroundJob struct {
+	id      string
+	stepID  string
+	run     string
+	started time.Time
+	cmd     *exec.Cmd
+	cancel  context.CancelFunc
+	output  *tailBuffer
+	done    chan struct{}
+
+	// Set once done is closed.
+	exitCode int
+	err      error
+}
+
+func (j *backgroundJob) finished() bool {
+	select {
+	case <-j.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (j *backgroundJob) status() string {
+	if !j.finished() {
+		return "running"
+	}
+	if j.err != nil && j.exitCode < 0 {
+		return "failed: " + j.err.Error()
+	}
+	return "exited with code " + strconv.Itoa(j.exitCode)
+}
+
+// describe renders the job header and its most recent output.
+func (j *backgroundJob) describe() string {
+	var b strings.Builder
+	pid := 0
+	if j.cmd.Process != nil {
+		pid = j.cmd.Process.Pid
+	}
+	fmt.Fprintf(&b, "%s (step %s, pid %d, %s, started %s ago): %s\n", j.id, j.stepID, pid, j.status(), time.Since(j.started).Round(time.Second), j.run)
+	if tail := j.output.tail(backgroundTailLines); tail != "" {
+		b.WriteString(tail)
+		if !strings.HasSuffix(tail, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// tailBuffer keeps the most recent bytes written to it.
+type tailBuffer struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.data = append(t.data, p...)
+	if over := len(t.data) - backgroundOutputLimit; over > 0 {
+		t.data = append([]byte(nil), t.data[over:]...)
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) tail(lines int) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out, _ := truncateOutput(t.data, 0, lines)
+	return string(out)
+}
+
+// startBackground launches cmd without waiting for it to exit. The process
+// outlives the step; it is stopped through kill_job or StopBackgroundJobs.
+func (e *CommandExecutor) startBackground(ctx context.Context, step PlanStep) (PlanObservationPayload, error) {
+	jobCtx, cancel := context.WithCancel(context.Background())
+	backend := e.backend
+	if backend == nil {
+		backend = HostBackend{}
+	}
+	cmd, err := backend.Command(jobCtx, step)
+	if err != nil {
+		cancel()
+		return PlanObservationPayload{}, fmt.Errorf("command: %w", err)
+	}
+
+	if env := e.commandEnv(step); env != nil {
+		cmd.Env = env
+	}
+
+	job := &backgroundJob{
+		stepID:  step.ID,
+		run:     strings.TrimSpace(step.Command.Run),
+		started: time.Now(),
+		cmd:     cmd,
+		cancel:  cancel,
+		output:  &tailBuffer{},
+		done:    make(chan struct{}),
+	}
+	cmd.Stdout = job.output
+	cmd.Stderr = job.output
+	// The context only force-kills the process group as a last resort; stop
+	// normally goes through stopJob so children get a chance to clean up.
+	setProcessGroup(cmd)
+	killOnCancel(cmd)
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return PlanObservationPayload{}, fmt.Errorf("command[%s]: start background job: %w", step.ID, err)
+	}
+
+	e.jobsMu.Lock()
+	e.nextJob++
+	job.id = "job-" + strconv.Itoa(e.nextJob)
+	if e.jobs == nil {
+		e.jobs = make(map[string]*backgroundJob)
+	}
+	e.jobs[job.id] = job
+	e.jobsMu.Unlock()
+
+	go func() {
+		err := cmd.Wait()
+		job.err = err
+		job.exitCode = -1
+		if cmd.ProcessState != nil {
+			job.exitCode = cmd.ProcessState.ExitCode()
+		}
+		close(job.done)
+		cancel()
+	}()
+
+	e.logger.Info(ctx, "Started background job",
+		Field("step_id", step.ID),
+		Field("job_id", job.id),
+		Field("pid", cmd.Process.Pid),
+	)
+
+	select {
+	case <-job.done:
+	case <-time.After(backgroundStartupWait):
+	case <-ctx.Done():
+	}
+
+	observation := PlanObservationPayload{Stdout: job.describe()}
+	code := 0
+	if job.finished() {
+		code = job.exitCode
+		observation.Details = fmt.Sprintf("background job %s exited during startup", job.id)
+	} else {
+		observation.Details = fmt.Sprintf("background job %s is running; use the %q internal command to check its output and %q to stop it", job.id, jobsCommandName, killJobCommandName+" "+job.id)
+	}
+	observation.ExitCode = &code
+	if code != 0 {
+		// The service never came up; later steps must not rely on it.
+		return observation, fmt.Errorf("command[%s]: background job %s exited during startup with code %d: %w", step.ID, job.id, code, job.err)
+	}
+	return observation, nil
+}
+
+// BackgroundJobIDs lists the jobs that are still running.
+func (e *CommandExecutor) BackgroundJobIDs() []string {
+	e.jobsMu.Lock()
+	defer e.jobsMu.Unlock()
+	var ids []string
+	for id, job := range e.jobs {
+		if !job.finished() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// StopBackgroundJobs terminates every running job and waits for them to exit.
+func (e *CommandExecutor) StopBackgroundJobs() {
+	e.jobsMu.Lock()
+	jobs := make([]*backgroundJob, 0, len(e.jobs))
+	for _, job := range e.jobs {
+		jobs = append(jobs, job)
+	}
+	e.jobsMu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func(job *backgroundJob) {
+			defer wg.Done()
+			stopJob(job)
+		}(job)
+	}
+	wg.Wait()
+}
+
+// stopJob asks the job's process group to terminate and kills it if it is
+// still running after backgroundStopGrace.
+func stopJob(job *backgroundJob) {
+	if job.finished() {
+		return
+	}
+	_ = terminateProcessGroup(job.cmd)
+	select {
+	case <-job.done:
+	case <-time.After(backgroundStopGrace):
+		job.cancel()
+		<-job.done
+	}
+}
+
+// sortedJobs returns all known jobs ordered by id number.
+func (e *CommandExecutor) sortedJobs() []*backgroundJob {
+	e.jobsMu.Lock()
+	defer e.jobsMu.Unlock()
+	jobs := make([]*backgroundJob, 0, len(e.jobs))
+	for _, job := range e.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, k int) bool {
+		a, _ := strconv.Atoi(strings.TrimPrefix(jobs[i].id, "job-"))
+		b, _ := strconv.Atoi(strings.TrimPrefix(jobs[k].id, "job-"))
+		return a < b
+	})
+	return jobs
+}
+
+func newJobsCommand(executor *CommandExecutor) InternalCommandHandler {
+	return func(_ context.Context, _ InternalCommandRequest) (PlanObservationPayload, error) {
+		jobs := executor.sortedJobs()
+		var b strings.Builder
+		if len(jobs) == 0 {
+			b.WriteString("no background jobs\n")
+		}
+		for _, job := range jobs {
+			b.WriteString(job.describe())
+		}
+		zero := 0
+		return PlanObservationPayload{Stdout: b.String(), ExitCode: &zero}, nil
+	}
+}
+
+func newKillJobCommand(executor *CommandExecutor) InternalCommandHandler {
+	return func(_ context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		id := ""
+		if value, ok := req.Args["id"]; ok {
+			id = fmt.Sprint(value)
+		} else if len(req.Positionals) > 0 {
+			id = fmt.Sprint(req.Positionals[0])
+		}
+		id = strings.TrimSpace(id)
+		if id == "" {
+			return failKillJob("kill_job requires a job id, e.g. \"kill_job job-1\"")
+		}
+
+		executor.jobsMu.Lock()
+		job, ok := executor.jobs[id]
+		executor.jobsMu.Unlock()
+		if !ok {
+			return failKillJob(fmt.Sprintf("unknown background job %q", id))
+		}
+
+		stopJob(job)
+		zero := 0
+		return PlanObservationPayload{Stdout: job.describe(), ExitCode: &zero}, nil
+	}
+}
+
+func failKillJob(message string) (PlanObservationPayload, error) {
+	one := 1
+	return PlanObservationPayload{Stderr: message, Details: message, ExitCode: &one}, fmt.Errorf("kill_job: %s", message)
+}
@@ -0,0 +1,64 @@
+package runtime
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestBackgroundStepKeepsRunningUntilKilled(t *testing.T) {
+	t.Parallel()
+
+	executor := NewCommandExecutor(nil, nil)
+	if err := registerBuiltinInternalCommands(nil, executor); err != nil {
+		t.Fatalf("register builtin commands: %v", err)
+	}
+	defer executor.StopBackgroundJobs()
+
+	step := PlanStep{ID: "serve", Command: CommandDraft{Shell: "/bin/sh -c", Run: "echo listening; sleep 30", Background: true}}
+	observation, err := executor.Execute(context.Background(), step)
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if !strings.Contains(observation.Stdout, "job-1") || !strings.Contains(observation.Stdout, "listening") {
+		t.Fatalf("expected job id and startup output, got %q", observation.Stdout)
+	}
+	if ids := executor.BackgroundJobIDs(); len(ids) != 1 || ids[0] != "job-1" {
+		t.Fatalf("expected job-1 to be running, got %v", ids)
+	}
+
+	list, err := executor.Execute(context.Background(), PlanStep{ID: "list", Command: CommandDraft{Shell: agentShell, Run: "jobs"}})
+	if err != nil {
+		t.Fatalf("jobs returned error: %v", err)
+	}
+	if !strings.Contains(list.Stdout, "job-1") || !strings.Contains(list.Stdout, "running") {
+		t.Fatalf("unexpected jobs output %q", list.Stdout)
+	}
+
+	if _, err := executor.Execute(context.Background(), PlanStep{ID: "kill", Command: CommandDraft{Shell: agentShell, Run: "kill_job job-1"}}); err != nil {
+		t.Fatalf("kill_job returned error: %v", err)
+	}
+	if ids := executor.BackgroundJobIDs(); len(ids) != 0 {
+		t.Fatalf("expected no running jobs after kill_job, got %v", ids)
+	}
+
+	if _, err := executor.Execute(context.Background(), PlanStep{ID: "kill", Command: CommandDraft{Shell: agentShell, Run: "kill_job job-9"}}); err == nil {
+		t.Fatal("expected unknown job id to fail")
+	}
+}
+
+func TestBackgroundStepFailsWhenJobExitsDuringStartup(t *testing.T) {
+	t.Parallel()
+
+	executor := NewCommandExecutor(nil, nil)
+	defer executor.StopBackgroundJobs()
+
+	step := PlanStep{ID: "serve", Command: CommandDraft{Shell: "/bin/sh -c", Run: "echo port in use; exit 3", Background: true}}
+	observation, err := executor.Execute(context.Background(), step)
+	if err == nil || !strings.Contains(err.Error(), "code 3") {
+		t.Fatalf("expected the failed startup to return an error, got %v", err)
+	}
+	if observation.ExitCode == nil || *observation.ExitCode != 3 || !strings.Contains(observation.Stdout, "port in use") {
+		t.Fatalf("expected a failed observation with the job output, got %+v", observation)
+	}
+}
@@ -0,0 +1,196 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tiktoken encodings supported by BPETokenizer.
+const (
+	EncodingCL100K = "cl100k_base"
+	EncodingO200K  = "o200k_base"
+)
+
+// whitespaceClass matches Unicode white space; Go's \s only covers ASCII.
+const whitespaceClass = `\s\x{0B}\x{85}\p{Z}`
+
+// tiktokenPatterns are the pre-tokenization expressions of each encoding.
+// The original patterns include a `\s+(?!\S)` alternative that RE2 cannot
+// express; splitPieces emulates it.
+var tiktokenPatterns = map[string]string{
+	EncodingCL100K: `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^WS\p{L}\p{N}]+[\r\n]*|[WS]*[\r\n]+|[WS]+`,
+	EncodingO200K: `[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|\p{N}{1,3}| ?[^WS\p{L}\p{N}]+[\r\n/]*|[WS]*[\r\n]+|[WS]+`,
+}
+
+// TiktokenEncodingForModel returns the tiktoken encoding an OpenAI model uses.
+func TiktokenEncodingForModel(model string) string {
+	model = strings.ToLower(strings.TrimSpace(model))
+	switch {
+	case strings.HasPrefix(model, "gpt-4o"), strings.HasPrefix(model, "gpt-4."):
+		return EncodingO200K
+	case strings.HasPrefix(model, "gpt-4"), strings.HasPrefix(model, "gpt-3.5"), strings.HasPrefix(model, "text-embedding"):
+		return EncodingCL100K
+	default:
+		return EncodingO200K
+	}
+}
+
+// BPETokenizer is a byte pair encoder compatible with tiktoken ranks files.
+// Special tokens are encoded as plain text.
+type BPETokenizer struct {
+	ranks   map[string]int
+	pattern *regexp.Regexp
+}
+
+// LoadTiktokenFile reads a ranks file as published for tiktoken.
+func LoadTiktokenFile(path, encoding string) (*BPETokenizer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	return NewBPETokenizer(file, encoding)
+}
+
+// NewBPETokenizer parses "<base64 token> <rank>" lines for encoding.
+func NewBPETokenizer(r io.Reader, encoding string) (*BPETokenizer, error) {
+	pattern, ok := tiktokenPatterns[encoding]
+	if !ok {
+		return nil, fmt.Errorf("tokenizer: unknown encoding %q", encoding)
+	}
+	re, err := regexp.Compile(strings.ReplaceAll(pattern, "WS", whitespaceClass))
+	if err != nil {
+		return nil, fmt.Errorf("tokenizer: compile %s pattern: %w", encoding, err)
+	}
+
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := bytes.Fields(scanner.Bytes())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("tokenizer: line %d: expected token and rank", line)
+		}
+		token, err := base64.StdEncoding.DecodeString(string(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("tokenizer: line %d: %w", line, err)
+		}
+		rank, err := strconv.Atoi(string(fields[1]))
+		if err != nil {
+			return nil, fmt.Errorf("tokenizer: line %d: %w", line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("tokenizer: read ranks: %w", err)
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("tokenizer: no ranks for %s", encoding)
+	}
+	return &BPETokenizer{ranks: ranks, pattern: re}, nil
+}
+
+// Encode returns the token ids for text.
+func (t *BPETokenizer) Encode(text string) []int {
+	var tokens []int
+	t.splitPieces(text, func(piece string) {
+		tokens = t.encodePiece(piece, tokens)
+	})
+	return tokens
+}
+
+// CountTokens implements TokenCounter.
+func (t *BPETokenizer) CountTokens(text string) int {
+	count := 0
+	t.splitPieces(text, func(piece string) {
+		if _, ok := t.ranks[piece]; ok {
+			count++
+			return
+		}
+		count += len(t.encodePiece(piece, nil))
+	})
+	return count
+}
+
+// splitPieces runs the pre-tokenizer. A run of white space followed by more
+// text leaves its last character to the next piece, which is what the
+// `\s+(?!\S)` alternative does in tiktoken.
+func (t *BPETokenizer) splitPieces(text string, fn func(string)) {
+	for len(text) > 0 {
+		loc := t.pattern.FindStringIndex(text)
+		if loc == nil {
+			fn(text)
+			return
+		}
+		if loc[0] > 0 {
+			fn(text[:loc[0]])
+		}
+		end := loc[1]
+		piece := text[loc[0]:end]
+		if end < len(text) && isWhitespaceRun(piece) && !strings.HasSuffix(piece, "\n") && !strings.HasSuffix(piece, "\r") {
+			if _, size := utf8.DecodeLastRuneInString(piece); size < len(piece) {
+				end -= size
+				piece = piece[:len(piece)-size]
+			}
+		}
+		fn(piece)
+		text = text[end:]
+	}
+}
+
+func isWhitespaceRun(s string) bool {
+	for _, r := range s {
+		if !unicode.IsSpace(r) && !unicode.Is(unicode.Z, r) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// encodePiece merges the lowest ranked adjacent pair until no pair is in
+// the vocabulary.
+func (t *BPETokenizer) encodePiece(piece string, out []int) []int {
+	if rank, ok := t.ranks[piece]; ok {
+		return append(out, rank)
+	}
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	for len(bounds) > 2 {
+		best, bestRank := -1, math.MaxInt
+		for i := 0; i+2 < len(bounds); i++ {
+			if rank, ok := t.ranks[piece[bounds[i]:bounds[i+2]]]; ok && rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		bounds = append(bounds[:best+1], bounds[best+2:]...)
+	}
+	for i := 0; i+1 < len(bounds); i++ {
+		// Every byte has a rank in tiktoken vocabularies; unknown bytes
+		// still count as one token.
+		rank, ok := t.ranks[piece[bounds[i]:bounds[i+1]]]
+		if !ok {
+			rank = -1
+		}
+		out = append(out, rank)
+	}
+	return out
+}
@@ -0,0 +1,175 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Budget caps what a session may spend on model requests. Zero fields are
+// unlimited. Tokens are the runtime's own estimate (the same counter the
+// context budget uses): the request history as input and the returned tool
+// call as output.
+type Budget struct {
+	MaxRequestsPerSession int
+	MaxTokensPerSession   int
+	// MaxUSD converts tokens to dollars with the per-million rates below,
+	// which must be set along with it.
+	MaxUSD              float64
+	InputUSDPerMillion  float64
+	OutputUSDPerMillion float64
+}
+
+func (b Budget) validate() error {
+	if b.MaxRequestsPerSession < 0 || b.MaxTokensPerSession < 0 || b.MaxUSD < 0 || b.InputUSDPerMillion < 0 || b.OutputUSDPerMillion < 0 {
+		return errors.New("budget: limits and rates must not be negative")
+	}
+	if b.MaxUSD > 0 && b.InputUSDPerMillion == 0 && b.OutputUSDPerMillion == 0 {
+		return errors.New("budget: MaxUSD requires InputUSDPerMillion or OutputUSDPerMillion")
+	}
+	return nil
+}
+
+// BudgetUsage is what the session has spent so far.
+type BudgetUsage struct {
+	Requests     int     `json:"requests"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	USD          float64 `json:"usd"`
+}
+
+// Tokens is the sum of input and output tokens.
+func (u BudgetUsage) Tokens() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+func (u BudgetUsage) sub(other BudgetUsage) BudgetUsage {
+	return BudgetUsage{
+		Requests:     u.Requests - other.Requests,
+		InputTokens:  u.InputTokens - other.InputTokens,
+		OutputTokens: u.OutputTokens - other.OutputTokens,
+		USD:          u.USD - other.USD,
+	}
+}
+
+// BudgetExceededError reports which limit stopped the session.
+type BudgetExceededError struct {
+	// Limit is "requests", "tokens", or "usd".
+	Limit string
+	Max   float64
+	Used  float64
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("budget exceeded: %s used %g of %g", e.Limit, e.Used, e.Max)
+}
+
+// budgetTracker accumulates usage. Limits apply to usage since base, which
+// moves forward each time the user confirms continuing past the budget.
+type budgetTracker struct {
+	mu        sync.Mutex
+	usage     BudgetUsage
+	base      BudgetUsage
+	exhausted bool
+	// parent is the tracker of the runtime that spawned this sub-agent. It
+	// is charged for every request, and its limits (parentBudget) apply
+	// here too.
+	parent       *budgetTracker
+	parentBudget Budget
+}
+
+// record adds one model request.
+func (t *budgetTracker) record(budget Budget, inputTokens, outputTokens int) {
+	t.mu.Lock()
+	t.usage.Requests++
+	t.usage.InputTokens += inputTokens
+	t.usage.OutputTokens += outputTokens
+	t.usage.USD += float64(inputTokens)*budget.InputUSDPerMillion/1e6 + float64(outputTokens)*budget.OutputUSDPerMillion/1e6
+	t.mu.Unlock()
+	if t.parent != nil {
+		t.parent.record(t.parentBudget, inputTokens, outputTokens)
+	}
+}
+
+// check reports the first limit the next request would exceed, checking the
+// parent's budget after this one.
+func (t *budgetTracker) check(budget Budget) error {
+	if err := t.checkOwn(budget); err != nil || t.parent == nil {
+		return err
+	}
+	return t.parent.check(t.parentBudget)
+}
+
+func (t *budgetTracker) checkOwn(budget Budget) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	spent := t.usage.sub(t.base)
+	var err *BudgetExceededError
+	switch {
+	case budget.MaxRequestsPerSession > 0 && spent.Requests >= budget.MaxRequestsPerSession:
+		err = &BudgetExceededError{Limit: "requests", Max: float64(budget.MaxRequestsPerSession), Used: float64(spent.Requests)}
+	case budget.MaxTokensPerSession > 0 && spent.Tokens() >= budget.MaxTokensPerSession:
+		err = &BudgetExceededError{Limit: "tokens", Max: float64(budget.MaxTokensPerSession), Used: float64(spent.Tokens())}
+	case budget.MaxUSD > 0 && spent.USD >= budget.MaxUSD:
+		err = &BudgetExceededError{Limit: "usd", Max: budget.MaxUSD, Used: spent.USD}
+	default:
+		return nil
+	}
+	t.exhausted = true
+	return err
+}
+
+// renew grants another budget after the user chose to continue. It reports
+// whether the budget had been exhausted.
+func (t *budgetTracker) renew() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.exhausted {
+		return false
+	}
+	t.exhausted = false
+	t.base = t.usage
+	return true
+}
+
+func (t *budgetTracker) snapshot() BudgetUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.usage
+}
+
+// BudgetUsage returns the model usage of this session.
+func (r *Runtime) BudgetUsage() BudgetUsage {
+	return r.budget.snapshot()
+}
+
+// recordRequestUsage estimates the tokens of a finished model request, plan
+// or summary, and adds them to the session's usage.
+func (r *Runtime) recordRequestUsage(history []ChatMessage, toolCall ToolCall) {
+	counter := r.tokenCounter()
+	input, _ := estimateHistoryTokenUsage(counter, history)
+	r.budget.record(r.options.Budget, input, counter.CountTokens(toolCall.Arguments))
+}
+
+// handleBudgetExceeded stops the pass. Hands-free sessions shut down; an
+// interactive session waits, and the next prompt renews the budget.
+func (r *Runtime) handleBudgetExceeded(budgetErr *BudgetExceededError, pass int) {
+	usage := r.budget.snapshot()
+	r.emit(RuntimeEvent{
+		Type:    EventTypeError,
+		Message: fmt.Sprintf("Session budget exceeded (%s: %g of %g). Stopping before the next model request.", budgetErr.Limit, budgetErr.Used, budgetErr.Max),
+		Level:   StatusLevelError,
+		Metadata: map[string]any{
+			"budget_exceeded": budgetErr.Limit,
+			"limit":           budgetErr.Max,
+			"used":            budgetErr.Used,
+			"usage":           usage,
+			"pass":            pass,
+		},
+	})
+	if r.options.HandsFree {
+		r.close()
+		return
+	}
+	r.emitRequestInput("Budget exhausted. Send a prompt to continue with a renewed budget.")
+}
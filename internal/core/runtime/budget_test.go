@@ -0,0 +1,119 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/asynkron/goagent/internal/core/schema"
+)
+
+func TestRequestPlanStopsAtRequestBudget(t *testing.T) {
+	t.Parallel()
+
+	lookup := ToolSpec{
+		Name:       "lookup",
+		JSONSchema: map[string]any{"type": "object"},
+		Handler: func(context.Context, json.RawMessage) (string, error) {
+			return "found", nil
+		},
+	}
+	provider := &scriptedProvider{calls: []ToolCall{
+		{ID: "call-1", Name: "lookup", Arguments: `{}`},
+		{ID: "call-2", Name: "lookup", Arguments: `{}`},
+		{ID: "call-3", Name: schema.ToolName, Arguments: `{"message":"done","reasoning":[],"plan":[],"requireHumanInput":false}`},
+	}}
+	rt := &Runtime{
+		options: RuntimeOptions{
+			Logger:  &NoOpLogger{},
+			Metrics: &NoOpMetrics{},
+			Tools:   []ToolSpec{lookup},
+			Budget:  Budget{MaxRequestsPerSession: 2},
+		},
+		outputs:   make(chan RuntimeEvent, 32),
+		closed:    make(chan struct{}),
+		plan:      NewPlanManager(),
+		client:    provider,
+		history:   []ChatMessage{{Role: RoleSystem, Content: "system"}},
+		agentName: "main",
+	}
+
+	_, _, err := rt.requestPlan(context.Background())
+	var budgetErr *BudgetExceededError
+	if !errors.As(err, &budgetErr) || budgetErr.Limit != "requests" || budgetErr.Used != 2 {
+		t.Fatalf("expected the request budget to stop the third request, got %v", err)
+	}
+	if provider.requests != 2 {
+		t.Fatalf("expected two provider requests, got %d", provider.requests)
+	}
+	usage := rt.BudgetUsage()
+	if usage.Requests != 2 || usage.InputTokens == 0 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+
+	// Confirming renews the budget from the current usage.
+	if !rt.budget.renew() {
+		t.Fatalf("expected renew to report the exhausted budget")
+	}
+	plan, _, err := rt.requestPlan(context.Background())
+	if err != nil || plan == nil || plan.Message != "done" {
+		t.Fatalf("expected the plan after renewing, got %+v, %v", plan, err)
+	}
+}
+
+func TestBudgetTrackerTokenAndSpendLimits(t *testing.T) {
+	t.Parallel()
+
+	budget := Budget{MaxTokensPerSession: 1500, MaxUSD: 0.01, InputUSDPerMillion: 2, OutputUSDPerMillion: 8}
+	if err := budget.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	var tracker budgetTracker
+	tracker.record(budget, 1000, 400)
+	if err := tracker.check(budget); err != nil {
+		t.Fatalf("expected budget to remain, got %v", err)
+	}
+	tracker.record(budget, 100, 50)
+	var budgetErr *BudgetExceededError
+	if err := tracker.check(budget); !errors.As(err, &budgetErr) || budgetErr.Limit != "tokens" {
+		t.Fatalf("expected the token limit, got %v", err)
+	}
+
+	spend := Budget{MaxUSD: 0.01, InputUSDPerMillion: 2, OutputUSDPerMillion: 8}
+	tracker = budgetTracker{}
+	tracker.record(spend, 3000, 600) // $0.006 + $0.0048
+	if err := tracker.check(spend); !errors.As(err, &budgetErr) || budgetErr.Limit != "usd" {
+		t.Fatalf("expected the spend limit, got %v", err)
+	}
+
+	if err := (Budget{MaxUSD: 1}).validate(); err == nil {
+		t.Fatalf("expected MaxUSD without rates to be rejected")
+	}
+}
+
+func TestBudgetTrackerChargesParent(t *testing.T) {
+	t.Parallel()
+
+	var parent budgetTracker
+	parentBudget := Budget{MaxRequestsPerSession: 3}
+	child := budgetTracker{parent: &parent, parentBudget: parentBudget}
+	childBudget := Budget{MaxRequestsPerSession: 5}
+
+	parent.record(parentBudget, 10, 1)
+	child.record(childBudget, 20, 2)
+	if usage := parent.snapshot(); usage.Requests != 2 || usage.InputTokens != 30 || usage.OutputTokens != 3 {
+		t.Fatalf("expected the child's request charged to the parent, got %+v", usage)
+	}
+	if err := child.check(childBudget); err != nil {
+		t.Fatalf("expected budget to remain, got %v", err)
+	}
+	child.record(childBudget, 20, 2)
+	var budgetErr *BudgetExceededError
+	if err := child.check(childBudget); !errors.As(err, &budgetErr) || budgetErr.Limit != "requests" || budgetErr.Max != 3 {
+		t.Fatalf("expected the parent's request limit to stop the child, got %v", err)
+	}
+	if err := parent.check(parentBudget); err == nil {
+		t.Fatalf("expected the parent to be out of budget too")
+	}
+}
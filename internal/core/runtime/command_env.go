@@ -0,0 +1,122 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// EnvPolicy decides which variables of the agent's own environment shell
+// steps inherit. CommandDraft.Env is applied on top in every mode.
+type EnvPolicy string
+
+const (
+	// EnvInheritAll passes the agent's full environment, including secrets
+	// such as OPENAI_API_KEY. This is the default.
+	EnvInheritAll EnvPolicy = "inherit"
+	// EnvInheritAllowlist passes only variables named in the allowlist
+	// (RuntimeOptions.EnvAllowlist, or DefaultEnvAllowlist when empty).
+	EnvInheritAllowlist EnvPolicy = "allowlist"
+	// EnvClean starts steps with an empty environment.
+	EnvClean EnvPolicy = "clean"
+)
+
+// DefaultEnvAllowlist keeps what typical build tools need to locate
+// binaries, caches, and the terminal without exposing credentials. A
+// trailing "*" matches any suffix.
+var DefaultEnvAllowlist = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "TERM", "COLORTERM",
+	"LANG", "LC_*", "TZ", "TMPDIR", "TEMP", "TMP", "PWD",
+	"GOPATH", "GOROOT", "GOCACHE", "GOMODCACHE", "GOFLAGS",
+	"NODE_PATH", "NVM_DIR", "PYTHONPATH", "VIRTUAL_ENV", "CARGO_HOME", "RUSTUP_HOME", "JAVA_HOME",
+	"SYSTEMROOT", "COMSPEC", "PATHEXT", "WINDIR",
+}
+
+// commandEnvSettings is the executor's environment configuration.
+type commandEnvSettings struct {
+	policy    EnvPolicy
+	allowlist []string
+}
+
+// SetEnvironment configures how shell steps inherit the agent's environment.
+// An empty policy means EnvInheritAll.
+func (e *CommandExecutor) SetEnvironment(policy EnvPolicy, allowlist []string) {
+	e.env = commandEnvSettings{policy: policy, allowlist: append([]string(nil), allowlist...)}
+}
+
+// commandEnv returns the environment for step, or nil to inherit the agent's
+// environment unchanged.
+func (e *CommandExecutor) commandEnv(step PlanStep) []string {
+	policy := e.env.policy
+	if (policy == "" || policy == EnvInheritAll) && len(step.Command.Env) == 0 {
+		return nil
+	}
+	return buildCommandEnv(os.Environ(), policy, e.env.allowlist, step.Command.Env)
+}
+
+// buildCommandEnv filters base according to policy and applies overrides.
+func buildCommandEnv(base []string, policy EnvPolicy, allowlist []string, overrides map[string]string) []string {
+	if len(allowlist) == 0 {
+		allowlist = DefaultEnvAllowlist
+	}
+	// Non-nil even when empty: callers read nil as "inherit everything".
+	env := []string{}
+	switch policy {
+	case EnvClean:
+	case EnvInheritAllowlist:
+		for _, entry := range base {
+			name, _, _ := strings.Cut(entry, "=")
+			if envNameAllowed(name, allowlist) {
+				env = append(env, entry)
+			}
+		}
+	default:
+		env = append(env, base...)
+	}
+
+	if len(overrides) == 0 {
+		return env
+	}
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	filtered := env[:0]
+	for _, entry := range env {
+		name, _, _ := strings.Cut(entry, "=")
+		if _, overridden := overrides[name]; !overridden {
+			filtered = append(filtered, entry)
+		}
+	}
+	env = filtered
+	for _, name := range names {
+		env = append(env, name+"="+overrides[name])
+	}
+	return env
+}
+
+func envNameAllowed(name string, allowlist []string) bool {
+	for _, allowed := range allowlist {
+		if prefix, ok := strings.CutSuffix(allowed, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(name, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func validateEnvPolicy(policy EnvPolicy) error {
+	switch policy {
+	case "", EnvInheritAll, EnvInheritAllowlist, EnvClean:
+		return nil
+	default:
+		return fmt.Errorf("unknown env policy %q", policy)
+	}
+}
@@ -0,0 +1,77 @@
+package runtime
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildCommandEnvAppliesPolicy(t *testing.T) {
+	t.Parallel()
+
+	base := []string{"PATH=/bin", "OPENAI_API_KEY=secret", "LC_ALL=C", "HOME=/home/agent"}
+	overrides := map[string]string{"HOME": "/tmp", "MODE": "test"}
+
+	cases := map[EnvPolicy][]string{
+		EnvInheritAll:       {"PATH=/bin", "OPENAI_API_KEY=secret", "LC_ALL=C", "HOME=/tmp", "MODE=test"},
+		EnvInheritAllowlist: {"PATH=/bin", "LC_ALL=C", "HOME=/tmp", "MODE=test"},
+		EnvClean:            {"HOME=/tmp", "MODE=test"},
+	}
+	for policy, want := range cases {
+		if got := buildCommandEnv(base, policy, nil, overrides); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: got %v, want %v", policy, got, want)
+		}
+	}
+
+	if got := buildCommandEnv(base, EnvInheritAllowlist, []string{"OPENAI_*"}, nil); !reflect.DeepEqual(got, []string{"OPENAI_API_KEY=secret"}) {
+		t.Fatalf("custom allowlist: got %v", got)
+	}
+	if got := buildCommandEnv(base, EnvClean, nil, nil); got == nil || len(got) != 0 {
+		t.Fatalf("clean without overrides: got %#v, want an empty non-nil env", got)
+	}
+	if got := buildCommandEnv(base, EnvInheritAllowlist, []string{"NOTHING_*"}, nil); got == nil || len(got) != 0 {
+		t.Fatalf("allowlist matching nothing: got %#v, want an empty non-nil env", got)
+	}
+}
+
+func TestCommandExecutorHidesSecretsWithAllowlist(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "secret")
+
+	executor := NewCommandExecutor(nil, nil)
+	executor.SetEnvironment(EnvInheritAllowlist, nil)
+	step := PlanStep{ID: "env", Command: CommandDraft{
+		Shell: "/bin/sh -c",
+		Run:   `echo "key=${OPENAI_API_KEY:-unset} mode=$MODE"`,
+		Env:   map[string]string{"MODE": "ci"},
+	}}
+
+	observation, err := executor.Execute(context.Background(), step)
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if strings.TrimSpace(observation.Stdout) != "key=unset mode=ci" {
+		t.Fatalf("unexpected stdout %q", observation.Stdout)
+	}
+}
+
+func TestCommandExecutorCleanEnvHidesSecrets(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "secret")
+
+	for name, allowlist := range map[EnvPolicy][]string{EnvClean: nil, EnvInheritAllowlist: {"NOTHING_*"}} {
+		executor := NewCommandExecutor(nil, nil)
+		executor.SetEnvironment(name, allowlist)
+		step := PlanStep{ID: "env", Command: CommandDraft{
+			Shell: "/bin/sh -c",
+			Run:   `echo "key=${OPENAI_API_KEY:-unset}"`,
+		}}
+
+		observation, err := executor.Execute(context.Background(), step)
+		if err != nil {
+			t.Fatalf("%s: Execute returned error: %v", name, err)
+		}
+		if strings.TrimSpace(observation.Stdout) != "key=unset" {
+			t.Fatalf("%s: unexpected stdout %q", name, observation.Stdout)
+		}
+	}
+}
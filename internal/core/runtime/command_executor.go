@@ -14,6 +14,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 )
@@ -22,6 +23,18 @@ const maxObservationBytes = 50 * 1024
 
 const agentShell = "openagent"
 
+// AgentShell is the CommandDraft.Shell value that routes a plan step to the
+// internal commands instead of the host shell.
+const AgentShell = agentShell
+
+const (
+	// maxStepRetries caps CommandDraft.Retries so a stuck step cannot spin.
+	maxStepRetries = 5
+	// defaultRetryBackoff is used when a step sets retries without a backoff.
+	defaultRetryBackoff = time.Second
+	maxRetryBackoff     = 30 * time.Second
+)
+
 // InternalCommandHandler executes agent scoped commands that are not forwarded to the
 // host shell. Implementations can inspect the parsed arguments and return a
 // PlanObservationPayload describing the outcome.
@@ -47,6 +60,33 @@ type CommandExecutor struct {
 	internal map[string]InternalCommandHandler
 	logger   Logger
 	metrics  Metrics
+	backend  ExecutionBackend
+	sink     CommandOutputSink
+
+	stdinMu sync.Mutex
+	stdins  map[string]io.WriteCloser
+
+	ptyMu   sync.Mutex
+	pty     bool
+	ptyCols int
+	ptyRows int
+	ptys    map[string]*os.File
+
+	env   commandEnvSettings
+	shell shellSettings
+
+	jobsMu  sync.Mutex
+	jobs    map[string]*backgroundJob
+	nextJob int
+
+	// artifactDir receives the full output of truncated steps. Empty
+	// disables the artifact store.
+	artifactDir string
+
+	failureLogsDisabled bool
+	failureRetention    FailureLogRetention
+
+	runner CommandRunner
 }
 
 // NewCommandExecutor builds the default executor that shells out using exec.CommandContext.
@@ -58,9 +98,75 @@ func NewCommandExecutor(logger Logger, metrics Metrics) *CommandExecutor {
 		metrics = &NoOpMetrics{}
 	}
 	return &CommandExecutor{
-		internal: make(map[string]InternalCommandHandler),
-		logger:   logger,
-		metrics:  metrics,
+		internal:         make(map[string]InternalCommandHandler),
+		logger:           logger,
+		metrics:          metrics,
+		backend:          HostBackend{},
+		failureRetention: *DefaultFailureLogRetention(),
+	}
+}
+
+// SetExecutionBackend swaps the backend used for shell commands. A nil
+// backend restores host execution.
+func (e *CommandExecutor) SetExecutionBackend(backend ExecutionBackend) {
+	if backend == nil {
+		backend = HostBackend{}
+	}
+	e.backend = backend
+}
+
+// SetCommandRunner hands shell steps to runner instead of starting
+// processes. A nil runner restores normal execution.
+func (e *CommandExecutor) SetCommandRunner(runner CommandRunner) {
+	e.runner = runner
+}
+
+// SetOutputSink streams shell step output to sink while the process runs.
+// A nil sink disables streaming; observations are unaffected either way.
+func (e *CommandExecutor) SetOutputSink(sink CommandOutputSink) {
+	e.sink = sink
+}
+
+// WriteStdin forwards data to the stdin of the running interactive step
+// stepID and closes the pipe when eof is set.
+func (e *CommandExecutor) WriteStdin(stepID, data string, eof bool) error {
+	e.stdinMu.Lock()
+	defer e.stdinMu.Unlock()
+	stdin, ok := e.stdins[stepID]
+	if !ok {
+		return fmt.Errorf("command: step %q is not running interactively", stepID)
+	}
+	if data != "" {
+		if _, err := io.WriteString(stdin, data); err != nil {
+			return fmt.Errorf("command: write stdin for step %q: %w", stepID, err)
+		}
+	}
+	if eof {
+		delete(e.stdins, stepID)
+		// Wait closes the pipe once the process exits, which can race with
+		// an EOF sent right after the final line was consumed.
+		if err := stdin.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+			return fmt.Errorf("command: close stdin for step %q: %w", stepID, err)
+		}
+	}
+	return nil
+}
+
+// trackStdin registers the stdin pipe of an interactive step and returns a
+// function that unregisters it.
+func (e *CommandExecutor) trackStdin(stepID string, stdin io.WriteCloser) func() {
+	e.stdinMu.Lock()
+	if e.stdins == nil {
+		e.stdins = make(map[string]io.WriteCloser)
+	}
+	e.stdins[stepID] = stdin
+	e.stdinMu.Unlock()
+	return func() {
+		e.stdinMu.Lock()
+		if current, ok := e.stdins[stepID]; ok && current == stdin {
+			delete(e.stdins, stepID)
+		}
+		e.stdinMu.Unlock()
 	}
 }
 
@@ -82,7 +188,63 @@ func (e *CommandExecutor) RegisterInternalCommand(name string, handler InternalC
 }
 
 // Execute runs the provided command and returns stdout/stderr observations.
+// Failed shell commands are retried as configured by CommandDraft.Retries.
+// Steps whose shell is missing on the host run with the default shell (see
+// SetDefaultShell) and say so in the observation details.
 func (e *CommandExecutor) Execute(ctx context.Context, step PlanStep) (PlanObservationPayload, error) {
+	step, shellNote := e.resolveShell(step)
+	observation, err := e.executeWithRetries(ctx, step)
+	if shellNote != "" {
+		observation.Details = strings.TrimSpace(shellNote + " " + observation.Details)
+	}
+	return observation, err
+}
+
+// executeWithRetries runs step, retrying failed shell commands.
+func (e *CommandExecutor) executeWithRetries(ctx context.Context, step PlanStep) (PlanObservationPayload, error) {
+	retries := step.Command.Retries
+	if retries <= 0 || step.Command.Background || strings.EqualFold(strings.TrimSpace(step.Command.Shell), agentShell) {
+		return e.execute(ctx, step)
+	}
+	if retries > maxStepRetries {
+		retries = maxStepRetries
+	}
+
+	backoff := time.Duration(step.Command.RetryBackoffSec) * time.Second
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+	attempt := 1
+	for {
+		observation, err := e.execute(ctx, step)
+		if err == nil || attempt > retries || ctx.Err() != nil {
+			observation.Attempts = attempt
+			if err != nil && attempt > 1 {
+				observation.Details = strings.TrimSpace(fmt.Sprintf("%s (failed after %d attempts)", observation.Details, attempt))
+			}
+			return observation, err
+		}
+		e.logger.Warn(ctx, "Retrying failed command",
+			Field("step_id", step.ID),
+			Field("attempt", attempt),
+			Field("backoff_ms", backoff.Milliseconds()),
+			Field("error", err.Error()),
+		)
+		select {
+		case <-ctx.Done():
+			observation.Attempts = attempt
+			return observation, err
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+		attempt++
+	}
+}
+
+// execute runs a single attempt of step.
+func (e *CommandExecutor) execute(ctx context.Context, step PlanStep) (PlanObservationPayload, error) {
 	start := time.Now()
 	e.logger.Debug(ctx, "Executing command",
 		Field("step_id", step.ID),
@@ -112,6 +274,18 @@ func (e *CommandExecutor) Execute(ctx context.Context, step PlanStep) (PlanObser
 		return observation, err
 	}
 
+	if e.runner != nil {
+		observation, err := e.runner.RunCommand(ctx, step)
+		e.metrics.RecordCommandExecution(step.ID, time.Since(start), err == nil)
+		return observation, err
+	}
+
+	if step.Command.Background {
+		observation, err := e.startBackground(ctx, step)
+		e.metrics.RecordCommandExecution(step.ID, time.Since(start), err == nil)
+		return observation, err
+	}
+
 	// Derive a timeout-scoped context before building the command so the exec.Cmd
 	// inherits the cancellation behavior directly.
 	timeout := time.Duration(step.Command.TimeoutSec) * time.Second
@@ -122,7 +296,11 @@ func (e *CommandExecutor) Execute(ctx context.Context, step PlanStep) (PlanObser
 	runCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	execCmd, err := buildShellCommand(runCtx, step.Command.Shell, step.Command.Run)
+	backend := e.backend
+	if backend == nil {
+		backend = HostBackend{}
+	}
+	cmd, err := backend.Command(runCtx, step)
 	if err != nil {
 		duration := time.Since(start)
 		e.metrics.RecordCommandExecution(step.ID, duration, false)
@@ -131,17 +309,46 @@ func (e *CommandExecutor) Execute(ctx context.Context, step PlanStep) (PlanObser
 		)
 		return PlanObservationPayload{}, fmt.Errorf("command: %w", err)
 	}
-	cmd := execCmd
-	if step.Command.Cwd != "" {
-		cmd.Dir = step.Command.Cwd
+
+	if env := e.commandEnv(step); env != nil {
+		cmd.Env = env
 	}
 
 	var stdoutBuf bytes.Buffer
 	var stderrBuf bytes.Buffer
-	cmd.Stdout = &stdoutBuf
-	cmd.Stderr = &stderrBuf
-
-	runErr := cmd.Run()
+	var stdoutWriter io.Writer = &stdoutBuf
+	var stderrWriter io.Writer = &stderrBuf
+	if e.sink != nil {
+		stdoutStream := newStreamingWriter(step, "stdout", e.sink)
+		stderrStream := newStreamingWriter(step, "stderr", e.sink)
+		defer stdoutStream.close()
+		defer stderrStream.close()
+		stdoutWriter = io.MultiWriter(&stdoutBuf, stdoutStream)
+		stderrWriter = io.MultiWriter(&stderrBuf, stderrStream)
+	}
+
+	var runErr error
+	usedPTY := false
+	if e.ptyEnabled() {
+		usedPTY, runErr = e.runWithPTY(ctx, cmd, step, stdoutWriter)
+	}
+	if !usedPTY {
+		cmd.Stdout = stdoutWriter
+		cmd.Stderr = stderrWriter
+		// Cancelling the step kills everything the command spawned, not
+		// just the shell.
+		setProcessGroup(cmd)
+		killOnCancel(cmd)
+		if step.Command.Interactive {
+			stdin, err := cmd.StdinPipe()
+			if err != nil {
+				e.metrics.RecordCommandExecution(step.ID, time.Since(start), false)
+				return PlanObservationPayload{}, fmt.Errorf("command: stdin pipe: %w", err)
+			}
+			defer e.trackStdin(step.ID, stdin)()
+		}
+		runErr = cmd.Run()
+	}
 	// Preserve the previous timeout message while letting other context cancellations
 	// bubble up naturally for the caller to inspect.
 	if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
@@ -152,6 +359,10 @@ func (e *CommandExecutor) Execute(ctx context.Context, step PlanStep) (PlanObser
 
 	stdout := stdoutBuf.Bytes()
 	stderr := stderrBuf.Bytes()
+	if usedPTY {
+		// The terminal translates newlines to CRLF; observations use plain LF.
+		stdout = bytes.ReplaceAll(stdout, []byte("\r\n"), []byte("\n"))
+	}
 
 	filteredStdout := applyFilter(stdout, step.Command.FilterRegex)
 	filteredStderr := applyFilter(stderr, step.Command.FilterRegex)
@@ -168,6 +379,19 @@ func (e *CommandExecutor) Execute(ctx context.Context, step PlanStep) (PlanObser
 
 	enforceObservationLimit(&observation)
 
+	if observation.Truncated {
+		// Keep the full output so the model can page through it with
+		// read_artifact instead of losing it to truncation.
+		refs, err := e.saveArtifacts(step, map[string][]byte{"stdout": stdout, "stderr": stderr})
+		if err != nil {
+			e.logger.Warn(ctx, "Failed to store output artifact",
+				Field("step_id", step.ID),
+				Field("error", err.Error()),
+			)
+		}
+		observation.Artifacts = refs
+	}
+
 	var exitErr *exec.ExitError
 	if errors.As(runErr, &exitErr) {
 		code := exitErr.ExitCode()
@@ -185,12 +409,8 @@ func (e *CommandExecutor) Execute(ctx context.Context, step PlanStep) (PlanObser
 
 	// If the command failed, persist a detailed failure report for inspection.
 	if runErr != nil {
-		if err := writeFailureLog(step, stdout, stderr, runErr); err != nil {
-			// Log warning but don't fail execution - failure logging is best-effort
-			e.logger.Warn(ctx, "Failed to write failure log",
-				Field("step_id", step.ID),
-				Field("error", err.Error()),
-			)
+		if !e.failureLogsDisabled {
+			e.recordFailure(ctx, step, stdout, stderr, runErr)
 		}
 		e.metrics.RecordCommandExecution(step.ID, duration, false)
 		e.logger.Error(ctx, "Command execution failed", runErr,
@@ -216,29 +436,38 @@ func (e *CommandExecutor) Execute(ctx context.Context, step PlanStep) (PlanObser
 	return observation, nil
 }
 
+// recordFailure writes the failure report for step and prunes old reports.
+// Both are best-effort and only logged when they fail.
+func (e *CommandExecutor) recordFailure(ctx context.Context, step PlanStep, stdout, stderr []byte, runErr error) {
+	if err := writeFailureLog(step, stdout, stderr, runErr); err != nil {
+		e.logger.Warn(ctx, "Failed to write failure log",
+			Field("step_id", step.ID),
+			Field("error", err.Error()),
+		)
+		return
+	}
+	if _, err := pruneFailureLogs(failureLogDir(step.Command.Cwd), e.failureRetention); err != nil {
+		e.logger.Warn(ctx, "Failed to prune failure logs",
+			Field("step_id", step.ID),
+			Field("error", err.Error()),
+		)
+	}
+}
+
 // writeFailureLog persists a diagnostic file under .goagent/ whenever a command
 // fails. The log captures the run string and the full, unfiltered stdout/stderr.
 // Any errors while writing the log are swallowed to avoid impacting the runtime.
 func writeFailureLog(step PlanStep, fullStdout, fullStderr []byte, runErr error) error {
-	// Resolve the base directory for logs. Prefer the step-specific Cwd when provided
-	// so test invocations and sandboxed executions keep logs local to their workspace.
-	baseDir := strings.TrimSpace(step.Command.Cwd)
-	if baseDir == "" {
-		if wd, err := os.Getwd(); err == nil {
-			baseDir = wd
-		} else {
-			baseDir = "."
-		}
-	}
-
-	// Ensure target directory exists relative to the resolved base directory.
-	dir := filepath.Join(baseDir, ".goagent")
+	// Prefer the step-specific Cwd when provided so test invocations and
+	// sandboxed executions keep logs local to their workspace.
+	dir := failureLogDir(step.Command.Cwd)
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return err
 	}
 
-	// Timestamped filename to avoid collisions.
-	filename := fmt.Sprintf("failure-%s.txt", time.Now().Format("20060102-150405"))
+	// Timestamped filename to avoid collisions; the milliseconds keep
+	// failures within the same second apart and the names in order.
+	filename := fmt.Sprintf("failure-%s.txt", time.Now().Format("20060102-150405.000"))
 	path := filepath.Join(dir, filename)
 
 	// Compose a human-readable report. We intentionally include unfiltered,
@@ -323,7 +552,11 @@ func (e *CommandExecutor) executeInternal(ctx context.Context, step PlanStep) (P
 
 func parseInternalInvocation(step PlanStep) (InternalCommandRequest, error) {
 	run := strings.TrimSpace(step.Command.Run)
-	tokens, err := tokenizeInternalCommand(run)
+	// Commands that carry a body after the first line (apply_patch,
+	// write_file) read it from Raw; only the command line is tokenized so
+	// quotes in the body cannot break parsing.
+	commandLine, _, _ := strings.Cut(run, "\n")
+	tokens, err := tokenizeInternalCommand(commandLine)
 	if err != nil {
 		return InternalCommandRequest{}, fmt.Errorf("parse internal command %q: %w", run, err)
 	}
@@ -370,6 +603,12 @@ func tokenizeInternalCommand(input string) ([]string, error) {
 	for _, r := range input {
 		switch {
 		case escape:
+			// Only quotes, backslashes, and whitespace need escaping; other
+			// sequences such as \d keep their backslash so regular
+			// expressions survive tokenization.
+			if r != '\'' && r != '"' && r != '\\' && !unicode.IsSpace(r) {
+				current.WriteRune('\\')
+			}
 			current.WriteRune(r)
 			escape = false
 		case r == '\\':
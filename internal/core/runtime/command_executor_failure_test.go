@@ -94,3 +94,82 @@ func TestCommandFailureLogging(t *testing.T) {
 		t.Fatalf("failure log missing STDERR section or expected stderr; got:\n%s", body)
 	}
 }
+
+func TestPruneFailureLogsAppliesRetention(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	now := time.Now()
+	write := func(name string, size int, age time.Duration) {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("failure-1.txt", 10, time.Minute)
+	write("failure-2.txt", 10, 2*time.Minute)
+	write("failure-3.txt", 10, 3*time.Minute)
+	write("failure-old.txt", 10, 48*time.Hour)
+	write("notes.txt", 10, 48*time.Hour)
+
+	removed, err := pruneFailureLogs(dir, FailureLogRetention{MaxCount: 3, MaxAge: 24 * time.Hour, MaxTotalBytes: 25})
+	if err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if removed != 2 {
+		t.Fatalf("expected 2 reports removed, got %d", removed)
+	}
+	for name, want := range map[string]bool{"failure-1.txt": true, "failure-2.txt": true, "failure-3.txt": false, "failure-old.txt": false, "notes.txt": true} {
+		if _, err := os.Stat(filepath.Join(dir, name)); (err == nil) != want {
+			t.Fatalf("%s: exists=%v, want %v", name, err == nil, want)
+		}
+	}
+}
+
+func TestListFailuresSummarizesReports(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	executor := newFileCommandExecutor(t)
+	executor.SetFailureLogs(true, FailureLogRetention{MaxCount: 1})
+	for _, code := range []string{"3", "4"} {
+		step := PlanStep{ID: "fail-" + code, Command: CommandDraft{Shell: "/bin/sh", Run: "echo boom 1>&2; exit " + code, Cwd: tmp}}
+		if _, err := executor.Execute(context.Background(), step); err == nil {
+			t.Fatalf("expected step %s to fail", step.ID)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	result, err := executor.Execute(context.Background(), PlanStep{ID: "list", Command: CommandDraft{Shell: agentShell, Run: "list_failures", Cwd: tmp}})
+	if err != nil {
+		t.Fatalf("list_failures: %v", err)
+	}
+	list, ok := result.Data.(FailureList)
+	if !ok {
+		t.Fatalf("unexpected data %#v", result.Data)
+	}
+	if list.Total != 1 || len(list.Failures) != 1 {
+		t.Fatalf("expected retention to keep one report, got %+v", list)
+	}
+	failure := list.Failures[0]
+	if failure.StepID != "fail-4" || failure.Run != "echo boom 1>&2; exit 4" || failure.StderrTail != "boom" || !strings.Contains(failure.Error, "exit status 4") {
+		t.Fatalf("unexpected summary %+v", failure)
+	}
+}
+
+func TestFailureLogsCanBeDisabled(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	executor := NewCommandExecutor(nil, nil)
+	executor.SetFailureLogs(false, FailureLogRetention{})
+	if _, err := executor.Execute(context.Background(), PlanStep{ID: "fail", Command: CommandDraft{Shell: "/bin/sh", Run: "exit 1", Cwd: tmp}}); err == nil {
+		t.Fatal("expected the step to fail")
+	}
+	if _, err := os.Stat(filepath.Join(tmp, ".goagent")); !os.IsNotExist(err) {
+		t.Fatalf("expected no failure log directory, got %v", err)
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestBuildShellCommand(t *testing.T) {
@@ -231,3 +233,145 @@ func TestTokenizeInternalCommandErrors(t *testing.T) {
 		t.Fatalf("expected unfinished escape error, got %v", err)
 	}
 }
+
+func TestCommandExecutorStreamsOutputWhileRunning(t *testing.T) {
+	t.Parallel()
+
+	executor := NewCommandExecutor(nil, nil)
+	var (
+		mu     sync.Mutex
+		chunks []string
+		first  time.Time
+	)
+	executor.SetOutputSink(func(step PlanStep, stream, chunk string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if step.ID != "stream" {
+			t.Errorf("unexpected step id %q", step.ID)
+		}
+		if len(chunks) == 0 {
+			first = time.Now()
+		}
+		chunks = append(chunks, stream+":"+chunk)
+	})
+
+	start := time.Now()
+	step := PlanStep{ID: "stream", Command: CommandDraft{Shell: "/bin/sh -c", Run: "echo one; sleep 0.6; echo two; echo err >&2"}}
+	observation, err := executor.Execute(context.Background(), step)
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	finished := time.Now()
+
+	if observation.Stdout != "one\ntwo\n" {
+		t.Fatalf("observation stdout changed by streaming: %q", observation.Stdout)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	joined := strings.Join(chunks, "|")
+	if !strings.Contains(joined, "stdout:one") || !strings.Contains(joined, "two") || !strings.Contains(joined, "stderr:err") {
+		t.Fatalf("expected stdout and stderr chunks, got %q", joined)
+	}
+	if first.Sub(start) >= finished.Sub(start)-200*time.Millisecond {
+		t.Fatalf("expected the first chunk before the process exited (first=%s total=%s)", first.Sub(start), finished.Sub(start))
+	}
+}
+
+func TestCommandExecutorForwardsStdinToInteractiveStep(t *testing.T) {
+	t.Parallel()
+
+	executor := NewCommandExecutor(nil, nil)
+	step := PlanStep{ID: "ask", Command: CommandDraft{Shell: "/bin/sh -c", Run: "read name; echo hello $name", Interactive: true}}
+
+	type result struct {
+		observation PlanObservationPayload
+		err         error
+	}
+	done := make(chan result, 1)
+	go func() {
+		observation, err := executor.Execute(context.Background(), step)
+		done <- result{observation, err}
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err := executor.WriteStdin("ask", "gopher\n", true)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("step never became interactive: %v", err)
+		}
+		select {
+		case res := <-done:
+			t.Fatalf("step finished before receiving input: %+v %v", res.observation, res.err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	res := <-done
+	if res.err != nil {
+		t.Fatalf("Execute returned error: %v", res.err)
+	}
+	if res.observation.Stdout != "hello gopher\n" {
+		t.Fatalf("unexpected stdout %q", res.observation.Stdout)
+	}
+	if err := executor.WriteStdin("ask", "late\n", false); err == nil {
+		t.Fatal("expected writes after the step finished to fail")
+	}
+}
+
+func TestCommandExecutorRunsStepsUnderPTY(t *testing.T) {
+	t.Parallel()
+
+	if master, slave, err := openPTY(); err != nil {
+		t.Skipf("pty unavailable: %v", err)
+	} else {
+		_ = master.Close()
+		_ = slave.Close()
+	}
+
+	executor := NewCommandExecutor(nil, nil)
+	executor.SetPTY(true)
+	executor.SetTerminalSize(100, 30)
+	step := PlanStep{ID: "tty", Command: CommandDraft{Shell: "/bin/sh -c", Run: "if [ -t 1 ]; then echo tty; fi; stty size; echo oops >&2"}}
+
+	observation, err := executor.Execute(context.Background(), step)
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if observation.Stdout != "tty\n30 100\noops\n" {
+		t.Fatalf("unexpected stdout %q", observation.Stdout)
+	}
+	if observation.Stderr != "" {
+		t.Fatalf("expected stderr merged into stdout, got %q", observation.Stderr)
+	}
+}
+
+func TestCommandExecutorRetriesFailedSteps(t *testing.T) {
+	t.Parallel()
+
+	marker := filepath.Join(t.TempDir(), "attempts")
+	executor := NewCommandExecutor(nil, nil)
+	// Fails until the marker file has two lines, so the third attempt succeeds.
+	run := fmt.Sprintf(`echo x >> %q; test "$(wc -l < %q)" -ge 3`, marker, marker)
+	step := PlanStep{ID: "flaky", Command: CommandDraft{Shell: "/bin/sh -c", Run: run, Retries: 3, RetryBackoffSec: 0}}
+
+	observation, err := executor.Execute(context.Background(), step)
+	if err != nil {
+		t.Fatalf("expected retries to recover, got %v", err)
+	}
+	if observation.Attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", observation.Attempts)
+	}
+
+	step.Command.Run = "exit 7"
+	step.Command.Retries = 1
+	observation, err = executor.Execute(context.Background(), step)
+	if err == nil {
+		t.Fatal("expected persistent failure to be reported")
+	}
+	if observation.Attempts != 2 || !strings.Contains(observation.Details, "failed after 2 attempts") {
+		t.Fatalf("unexpected observation after exhausting retries: %+v", observation)
+	}
+}
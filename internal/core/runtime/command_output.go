@@ -0,0 +1,91 @@
+package runtime
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// commandOutputFlushInterval rate-limits EventTypeCommandOutput events per stream.
+	commandOutputFlushInterval = 200 * time.Millisecond
+	// commandOutputMaxChunk forces a flush once this many bytes are pending.
+	commandOutputMaxChunk = 4096
+)
+
+// CommandOutputSink receives incremental output from a running shell step.
+// stream is "stdout" or "stderr".
+type CommandOutputSink func(step PlanStep, stream string, chunk string)
+
+// streamingWriter forwards process output to a sink in rate-limited chunks.
+// It is used alongside the observation buffer, so the sink never affects what
+// the model sees.
+type streamingWriter struct {
+	mu      sync.Mutex
+	step    PlanStep
+	stream  string
+	sink    CommandOutputSink
+	pending []byte
+	last    time.Time
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// newStreamingWriter starts a ticker that flushes output which arrives
+// slower than the flush interval. Call close to flush the tail and stop it.
+func newStreamingWriter(step PlanStep, stream string, sink CommandOutputSink) *streamingWriter {
+	w := &streamingWriter{
+		step:   step,
+		stream: stream,
+		sink:   sink,
+		last:   time.Now(),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go w.tick()
+	return w
+}
+
+func (w *streamingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending = append(w.pending, p...)
+	if len(w.pending) >= commandOutputMaxChunk || time.Since(w.last) >= commandOutputFlushInterval {
+		w.flushLocked()
+	}
+	return len(p), nil
+}
+
+func (w *streamingWriter) tick() {
+	defer close(w.done)
+	ticker := time.NewTicker(commandOutputFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.mu.Lock()
+			w.flushLocked()
+			w.mu.Unlock()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+func (w *streamingWriter) flushLocked() {
+	w.last = time.Now()
+	if len(w.pending) == 0 {
+		return
+	}
+	chunk := string(w.pending)
+	w.pending = w.pending[:0]
+	w.sink(w.step, w.stream, chunk)
+}
+
+// close stops the ticker and emits any remaining output.
+func (w *streamingWriter) close() {
+	close(w.stop)
+	<-w.done
+	w.mu.Lock()
+	w.flushLocked()
+	w.mu.Unlock()
+}
@@ -0,0 +1,175 @@
+package runtime
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PolicyAction is the outcome of evaluating a plan step against a CommandPolicy.
+type PolicyAction string
+
+const (
+	// PolicyAllow runs the step (subject to the approval policy).
+	PolicyAllow PolicyAction = "allow"
+	// PolicyDeny rejects the step without running it.
+	PolicyDeny PolicyAction = "deny"
+	// PolicyRequireApproval asks the host before running the step, regardless
+	// of RuntimeOptions.ApprovalPolicy. Hands-free sessions have nobody to
+	// ask, so such steps are denied there.
+	PolicyRequireApproval PolicyAction = "require-approval"
+)
+
+// CommandRule matches plan steps by their run string and optionally by shell
+// and working directory.
+type CommandRule struct {
+	// Pattern is a glob matched against the whole trimmed run string ("*"
+	// matches any text, "?" one character) unless Regex is set, in which
+	// case it is a regular expression that may match anywhere in the string.
+	Pattern string
+	Regex   bool
+	// Shell restricts the rule to steps using this shell (case-insensitive).
+	// Empty matches every shell.
+	Shell string
+	// Cwd is a glob matched against the step's working directory. Empty
+	// matches every directory.
+	Cwd    string
+	Action PolicyAction
+	// Reason is reported to the model and host when the rule fires.
+	Reason string
+}
+
+// CommandPolicy evaluates rules in order; the first matching rule decides.
+// Steps that match no rule get Default, which falls back to PolicyAllow.
+type CommandPolicy struct {
+	Rules   []CommandRule
+	Default PolicyAction
+}
+
+// PolicyDecision reports which action applies to a step and why.
+type PolicyDecision struct {
+	Action PolicyAction
+	Reason string
+}
+
+// DefaultCommandPolicy blocks commands that are almost never intended by an
+// agent and asks before history-rewriting git operations. Hosts can extend it
+// by prepending their own rules.
+func DefaultCommandPolicy() *CommandPolicy {
+	return &CommandPolicy{
+		Rules: []CommandRule{
+			{
+				Pattern: `\brm\s+(-[a-zA-Z]*\s+)*-[a-zA-Z]*[rR][a-zA-Z]*\s+(-[a-zA-Z]+\s+)*(/|/\*|~|~/|\$HOME/?)(\s|;|&|\||$)`,
+				Regex:   true,
+				Action:  PolicyDeny,
+				Reason:  "recursive delete of the filesystem root or home directory",
+			},
+			{Pattern: `--no-preserve-root`, Regex: true, Action: PolicyDeny, Reason: "rm --no-preserve-root"},
+			{Pattern: `\bgit\s+push\b.*(\s--force(\s|$)|\s-f(\s|$)|\s\+\S)`, Regex: true, Action: PolicyDeny, Reason: "force push rewrites shared history"},
+			{Pattern: `\bmkfs(\.\w+)?\b`, Regex: true, Action: PolicyDeny, Reason: "formatting a filesystem"},
+			{Pattern: `\bdd\b.*\bof=/dev/`, Regex: true, Action: PolicyDeny, Reason: "writing directly to a block device"},
+			{Pattern: `:\(\)\s*\{\s*:\s*\|\s*:\s*&\s*\}`, Regex: true, Action: PolicyDeny, Reason: "fork bomb"},
+			{Pattern: `\bchmod\s+-R\s+0?777\s+/(\s|$)`, Regex: true, Action: PolicyDeny, Reason: "making the filesystem world-writable"},
+			{Pattern: `\bgit\s+(reset\s+--hard|clean\s+-[a-zA-Z]*f)`, Regex: true, Action: PolicyRequireApproval, Reason: "discards uncommitted work"},
+		},
+		Default: PolicyAllow,
+	}
+}
+
+// Validate checks that every rule has a known action and a pattern that compiles.
+func (p *CommandPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if !validPolicyAction(p.Default) && p.Default != "" {
+		return fmt.Errorf("command policy: unknown default action %q", p.Default)
+	}
+	for i, rule := range p.Rules {
+		if !validPolicyAction(rule.Action) {
+			return fmt.Errorf("command policy: rule %d has unknown action %q", i, rule.Action)
+		}
+		if _, err := rule.compile(); err != nil {
+			return fmt.Errorf("command policy: rule %d: %w", i, err)
+		}
+		if rule.Cwd != "" {
+			if _, err := regexp.Compile(globToRegexp(rule.Cwd)); err != nil {
+				return fmt.Errorf("command policy: rule %d cwd: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Evaluate returns the decision for step. A nil policy allows everything.
+func (p *CommandPolicy) Evaluate(step PlanStep) PolicyDecision {
+	if p == nil {
+		return PolicyDecision{Action: PolicyAllow}
+	}
+	for _, rule := range p.Rules {
+		if rule.matches(step) {
+			reason := rule.Reason
+			if reason == "" {
+				reason = fmt.Sprintf("matched command policy %q", rule.Pattern)
+			}
+			return PolicyDecision{Action: rule.Action, Reason: reason}
+		}
+	}
+	if p.Default == "" {
+		return PolicyDecision{Action: PolicyAllow}
+	}
+	return PolicyDecision{Action: p.Default}
+}
+
+func (rule CommandRule) matches(step PlanStep) bool {
+	if rule.Shell != "" && !strings.EqualFold(strings.TrimSpace(rule.Shell), strings.TrimSpace(step.Command.Shell)) {
+		return false
+	}
+	if rule.Cwd != "" {
+		re, err := regexp.Compile(globToRegexp(rule.Cwd))
+		if err != nil || !re.MatchString(strings.TrimSpace(step.Command.Cwd)) {
+			return false
+		}
+	}
+	re, err := rule.compile()
+	if err != nil {
+		// Invalid rules are rejected by Validate; treat them as non-matching
+		// when a policy is used without validation.
+		return false
+	}
+	return re.MatchString(strings.TrimSpace(step.Command.Run))
+}
+
+func (rule CommandRule) compile() (*regexp.Regexp, error) {
+	if rule.Regex {
+		return regexp.Compile(rule.Pattern)
+	}
+	return regexp.Compile(globToRegexp(rule.Pattern))
+}
+
+// globToRegexp converts a shell-style glob into an anchored regular expression
+// where "*" matches any run of characters, including "/" and newlines.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString(`(?s)^`)
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(`.*`)
+		case '?':
+			b.WriteString(`.`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString(`$`)
+	return b.String()
+}
+
+func validPolicyAction(action PolicyAction) bool {
+	switch action {
+	case PolicyAllow, PolicyDeny, PolicyRequireApproval:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,100 @@
+package runtime
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDefaultCommandPolicy(t *testing.T) {
+	t.Parallel()
+
+	policy := DefaultCommandPolicy()
+	if err := policy.Validate(); err != nil {
+		t.Fatalf("default policy failed validation: %v", err)
+	}
+	cases := []struct {
+		run  string
+		want PolicyAction
+	}{
+		{run: "rm -rf /", want: PolicyDeny},
+		{run: "sudo rm -fr ~ ", want: PolicyDeny},
+		{run: "rm -rf ./build", want: PolicyAllow},
+		{run: "git push --force origin main", want: PolicyDeny},
+		{run: "git push -f", want: PolicyDeny},
+		{run: "git push --force-with-lease", want: PolicyAllow},
+		{run: "git push origin main", want: PolicyAllow},
+		{run: "dd if=img.iso of=/dev/sda", want: PolicyDeny},
+		{run: "git reset --hard HEAD~1", want: PolicyRequireApproval},
+		{run: "go test ./...", want: PolicyAllow},
+	}
+	for _, tc := range cases {
+		step := PlanStep{Command: CommandDraft{Shell: "bash", Run: tc.run}}
+		if got := policy.Evaluate(step).Action; got != tc.want {
+			t.Fatalf("Evaluate(%q) = %s, want %s", tc.run, got, tc.want)
+		}
+	}
+}
+
+func TestCommandPolicyGlobShellAndCwd(t *testing.T) {
+	t.Parallel()
+
+	policy := &CommandPolicy{
+		Rules: []CommandRule{
+			{Pattern: "npm publish*", Action: PolicyDeny, Reason: "no publishing"},
+			{Pattern: "*", Shell: "python", Action: PolicyRequireApproval},
+			{Pattern: "make *", Cwd: "/srv/*", Action: PolicyDeny},
+		},
+		Default: PolicyAllow,
+	}
+	if err := policy.Validate(); err != nil {
+		t.Fatalf("Validate returned error: %v", err)
+	}
+
+	if d := policy.Evaluate(PlanStep{Command: CommandDraft{Shell: "bash", Run: "npm publish --tag next"}}); d.Action != PolicyDeny || d.Reason != "no publishing" {
+		t.Fatalf("expected glob deny, got %+v", d)
+	}
+	if d := policy.Evaluate(PlanStep{Command: CommandDraft{Shell: "Python", Run: "print(1)"}}); d.Action != PolicyRequireApproval {
+		t.Fatalf("expected shell-scoped rule, got %+v", d)
+	}
+	if d := policy.Evaluate(PlanStep{Command: CommandDraft{Shell: "bash", Run: "make all", Cwd: "/srv/app"}}); d.Action != PolicyDeny {
+		t.Fatalf("expected cwd-scoped deny, got %+v", d)
+	}
+	if d := policy.Evaluate(PlanStep{Command: CommandDraft{Shell: "bash", Run: "make all", Cwd: "/home/me"}}); d.Action != PolicyAllow {
+		t.Fatalf("expected default allow outside cwd, got %+v", d)
+	}
+
+	bad := &CommandPolicy{Rules: []CommandRule{{Pattern: "(", Regex: true, Action: PolicyDeny}}}
+	if err := bad.Validate(); err == nil {
+		t.Fatal("expected invalid regex to fail validation")
+	}
+}
+
+func TestExecutePendingCommands_PolicyDeniesStep(t *testing.T) {
+	t.Parallel()
+
+	rt := &Runtime{
+		options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}, CommandPolicy: DefaultCommandPolicy()},
+		inputs:    make(chan InputEvent, 1),
+		plan:      NewPlanManager(),
+		executor:  NewCommandExecutor(nil, nil),
+		outputs:   make(chan RuntimeEvent, 10),
+		closed:    make(chan struct{}),
+		history:   []ChatMessage{},
+		agentName: "main",
+	}
+	rt.plan.Replace([]PlanStep{{
+		ID:      "step-1",
+		Title:   "Nuke",
+		Status:  PlanPending,
+		Command: CommandDraft{Shell: "bash", Run: "rm -rf /"},
+	}})
+
+	rt.executePendingCommands(context.Background(), ToolCall{ID: "call-1", Name: "open-agent"})
+	close(rt.outputs)
+
+	history := rt.historySnapshot()
+	if len(history) != 1 || !strings.Contains(history[0].Content, "blocked by the command policy") {
+		t.Fatalf("expected policy rejection in tool observation, got %#v", history)
+	}
+}
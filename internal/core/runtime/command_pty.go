@@ -0,0 +1,142 @@
+package runtime
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+)
+
+const (
+	defaultPTYCols = 120
+	defaultPTYRows = 40
+	// ptyDrainTimeout bounds how long output is read after the process exits;
+	// background children that inherited the terminal would otherwise keep
+	// the step open.
+	ptyDrainTimeout = 2 * time.Second
+)
+
+// SetPTY runs shell steps under a pseudo-terminal so programs that check
+// isatty keep their progress bars, colors, and pagers. Stdout and stderr are
+// merged into Stdout and ANSI sequences are kept. Platforms without PTY
+// support fall back to pipes.
+func (e *CommandExecutor) SetPTY(enabled bool) {
+	e.ptyMu.Lock()
+	defer e.ptyMu.Unlock()
+	e.pty = enabled
+}
+
+// SetTerminalSize sets the window size reported to PTY steps, including
+// those already running. Non-positive values restore the defaults.
+func (e *CommandExecutor) SetTerminalSize(cols, rows int) {
+	e.ptyMu.Lock()
+	defer e.ptyMu.Unlock()
+	e.ptyCols = cols
+	e.ptyRows = rows
+	cols, rows = e.terminalSizeLocked()
+	for _, master := range e.ptys {
+		_ = setPTYSize(master, cols, rows)
+	}
+}
+
+func (e *CommandExecutor) ptyEnabled() bool {
+	e.ptyMu.Lock()
+	defer e.ptyMu.Unlock()
+	return e.pty
+}
+
+func (e *CommandExecutor) terminalSizeLocked() (int, int) {
+	cols, rows := e.ptyCols, e.ptyRows
+	if cols <= 0 {
+		cols = defaultPTYCols
+	}
+	if rows <= 0 {
+		rows = defaultPTYRows
+	}
+	return cols, rows
+}
+
+// trackPTY registers the master side of a running step for resizes and
+// returns a function that unregisters it.
+func (e *CommandExecutor) trackPTY(stepID string, master *os.File) func() {
+	e.ptyMu.Lock()
+	if e.ptys == nil {
+		e.ptys = make(map[string]*os.File)
+	}
+	e.ptys[stepID] = master
+	cols, rows := e.terminalSizeLocked()
+	_ = setPTYSize(master, cols, rows)
+	e.ptyMu.Unlock()
+	return func() {
+		e.ptyMu.Lock()
+		if current, ok := e.ptys[stepID]; ok && current == master {
+			delete(e.ptys, stepID)
+		}
+		e.ptyMu.Unlock()
+	}
+}
+
+// runWithPTY runs cmd attached to a new pseudo-terminal and copies everything
+// it writes to output. It reports false without starting the process when no
+// terminal can be allocated so the caller can fall back to pipes.
+func (e *CommandExecutor) runWithPTY(ctx context.Context, cmd *exec.Cmd, step PlanStep, output io.Writer) (bool, error) {
+	master, slave, err := openPTY()
+	if err != nil {
+		e.logger.Warn(ctx, "PTY unavailable, running step with pipes",
+			Field("step_id", step.ID),
+			Field("error", err.Error()),
+		)
+		return false, nil
+	}
+	defer e.trackPTY(step.ID, master)()
+
+	cmd.Stdin = slave
+	cmd.Stdout = slave
+	cmd.Stderr = slave
+	cmd.SysProcAttr = ptySysProcAttr(cmd.SysProcAttr)
+	// The new session is also a process group, so cancellation reaches
+	// every process on the terminal.
+	killOnCancel(cmd)
+	if step.Command.Interactive {
+		defer e.trackStdin(step.ID, ptyInput{master})()
+	}
+
+	if err := cmd.Start(); err != nil {
+		_ = slave.Close()
+		_ = master.Close()
+		return true, err
+	}
+	// Only the child keeps the slave open, so reads hit EOF once it exits.
+	_ = slave.Close()
+
+	copied := make(chan struct{})
+	go func() {
+		defer close(copied)
+		_, _ = io.Copy(output, master)
+	}()
+
+	waitErr := cmd.Wait()
+	select {
+	case <-copied:
+	case <-time.After(ptyDrainTimeout):
+	}
+	_ = master.Close()
+	<-copied
+	return true, waitErr
+}
+
+// ptyInput writes host input to the terminal. Closing it sends the EOF
+// character instead of closing the master, which would hang up the step.
+type ptyInput struct {
+	master *os.File
+}
+
+func (p ptyInput) Write(b []byte) (int, error) {
+	return p.master.Write(b)
+}
+
+func (p ptyInput) Close() error {
+	_, err := p.master.Write([]byte{4})
+	return err
+}
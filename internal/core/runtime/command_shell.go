@@ -0,0 +1,113 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	goruntime "runtime"
+	"strings"
+	"sync"
+)
+
+// platformShells are tried in order when a step's shell is missing and no
+// usable default shell is configured.
+func platformShells() []string {
+	if goruntime.GOOS == "windows" {
+		return []string{"pwsh", "powershell", "cmd"}
+	}
+	return []string{"bash", "zsh", "sh"}
+}
+
+// shellSettings remembers which shell executables exist on the host.
+type shellSettings struct {
+	mu           sync.Mutex
+	defaultShell string
+	available    map[string]bool
+	// lookPath is exec.LookPath; tests replace it.
+	lookPath func(string) (string, error)
+}
+
+// SetDefaultShell sets the shell used for steps that name none and for steps
+// whose shell does not exist on the host, such as /bin/bash on Alpine or
+// Windows. Empty falls back to the first of bash, zsh and sh (pwsh,
+// powershell and cmd on Windows) found on PATH.
+func (e *CommandExecutor) SetDefaultShell(shell string) {
+	e.shell.mu.Lock()
+	defer e.shell.mu.Unlock()
+	e.shell.defaultShell = strings.TrimSpace(shell)
+}
+
+// resolveShell fills in and validates the shell of a host shell step. A
+// missing shell is replaced with the default one; the returned note tells
+// the model about the substitution so later steps use the right shell.
+// Steps for internal commands, a CommandRunner or a non-host backend are
+// returned unchanged.
+func (e *CommandExecutor) resolveShell(step PlanStep) (PlanStep, string) {
+	shell := strings.TrimSpace(step.Command.Shell)
+	if strings.EqualFold(shell, agentShell) || e.runner != nil {
+		return step, ""
+	}
+	switch e.backend.(type) {
+	case nil, HostBackend, *HostBackend:
+	default:
+		return step, ""
+	}
+	if shell == "" {
+		step.Command.Shell = e.shell.fallback()
+		return step, ""
+	}
+	executable := strings.Fields(shell)[0]
+	if e.shell.exists(executable) {
+		return step, ""
+	}
+	fallback := e.shell.fallback()
+	if fallback == "" {
+		return step, ""
+	}
+	step.Command.Shell = fallback
+	return step, fmt.Sprintf("Shell %q is not available on this host; the step ran with %q instead. Use %q for later steps.", executable, fallback, fallback)
+}
+
+// fallback returns the configured default shell when it exists, otherwise
+// the first platform shell found, or "".
+func (s *shellSettings) fallback() string {
+	s.mu.Lock()
+	configured := s.defaultShell
+	s.mu.Unlock()
+	if configured != "" && s.exists(strings.Fields(configured)[0]) {
+		return configured
+	}
+	for _, candidate := range platformShells() {
+		if s.exists(candidate) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// exists reports whether executable is a file path that exists or a command
+// on PATH. Results are cached for the executor's lifetime.
+func (s *shellSettings) exists(executable string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if found, ok := s.available[executable]; ok {
+		return found
+	}
+	var found bool
+	if strings.ContainsAny(executable, `/\`) {
+		info, err := os.Stat(executable)
+		found = err == nil && !info.IsDir()
+	} else {
+		lookPath := s.lookPath
+		if lookPath == nil {
+			lookPath = exec.LookPath
+		}
+		_, err := lookPath(executable)
+		found = err == nil
+	}
+	if s.available == nil {
+		s.available = make(map[string]bool)
+	}
+	s.available[executable] = found
+	return found
+}
@@ -0,0 +1,69 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExecuteSubstitutesMissingShell(t *testing.T) {
+	t.Parallel()
+
+	executor := NewCommandExecutor(nil, nil)
+	executor.SetDefaultShell("sh")
+
+	observation, err := executor.Execute(context.Background(), PlanStep{
+		ID:      "step-1",
+		Command: CommandDraft{Shell: "/definitely/missing/bash -lc", Run: "echo hi"},
+	})
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if strings.TrimSpace(observation.Stdout) != "hi" {
+		t.Fatalf("expected the step to run with sh, got stdout %q", observation.Stdout)
+	}
+	if !strings.Contains(observation.Details, `"/definitely/missing/bash" is not available`) || !strings.Contains(observation.Details, `"sh"`) {
+		t.Fatalf("expected the substitution in the details, got %q", observation.Details)
+	}
+}
+
+func TestResolveShell(t *testing.T) {
+	t.Parallel()
+
+	executor := NewCommandExecutor(nil, nil)
+	executor.SetDefaultShell("zsh -c")
+	executor.shell.lookPath = func(name string) (string, error) {
+		if name == "zsh" || name == "sh" {
+			return "/usr/bin/" + name, nil
+		}
+		return "", errors.New("not found")
+	}
+
+	tests := map[string]struct {
+		shell     string
+		wantShell string
+		wantNote  bool
+	}{
+		"keeps an existing shell":        {shell: "sh", wantShell: "sh"},
+		"fills in an empty shell":        {shell: "", wantShell: "zsh -c"},
+		"replaces a missing shell":       {shell: "bash -lc", wantShell: "zsh -c", wantNote: true},
+		"leaves internal commands alone": {shell: agentShell, wantShell: agentShell},
+	}
+	for name, tc := range tests {
+		step, note := executor.resolveShell(PlanStep{Command: CommandDraft{Shell: tc.shell}})
+		if step.Command.Shell != tc.wantShell || (note != "") != tc.wantNote {
+			t.Fatalf("%s: got shell %q note %q", name, step.Command.Shell, note)
+		}
+	}
+
+	executor.SetExecutionBackend(&HostBackend{})
+	if step, note := executor.resolveShell(PlanStep{Command: CommandDraft{Shell: "bash -lc"}}); step.Command.Shell != "zsh -c" || note == "" {
+		t.Fatalf("expected a *HostBackend to substitute missing shells, got %q %q", step.Command.Shell, note)
+	}
+
+	executor.SetExecutionBackend(&ContainerBackend{Image: "alpine"})
+	if step, note := executor.resolveShell(PlanStep{Command: CommandDraft{Shell: "bash"}}); step.Command.Shell != "bash" || note != "" {
+		t.Fatalf("expected container steps to keep their shell, got %q %q", step.Command.Shell, note)
+	}
+}
@@ -0,0 +1,73 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/asynkron/goagent/internal/lsp"
+)
+
+// LanguageServer configures a language server queried for diagnostics after
+// apply_patch edits files it handles.
+type LanguageServer = lsp.Server
+
+// Diagnostic is a problem a language server reported for an edited file.
+type Diagnostic = lsp.Diagnostic
+
+// maxObservationDiagnostics caps the diagnostics appended to an apply_patch
+// observation.
+const maxObservationDiagnostics = 30
+
+// withDiagnostics wraps apply_patch so that a successful edit reports the
+// language server diagnostics for the files it created or modified. The
+// model then sees compile and type errors without running a build.
+func withDiagnostics(manager *lsp.Manager, next InternalCommandHandler) InternalCommandHandler {
+	return func(ctx context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		payload, err := next(ctx, req)
+		if err != nil || len(payload.FileChanges) == 0 {
+			return payload, err
+		}
+
+		// Changes are relative to the step's cwd; the manager resolves
+		// relative paths against its own root.
+		cwd := req.Step.Command.Cwd
+		paths := make([]string, 0, len(payload.FileChanges))
+		relative := make(map[string]string, len(payload.FileChanges))
+		for _, change := range payload.FileChanges {
+			path := change.Path
+			if cwd != "" && !filepath.IsAbs(path) {
+				path = filepath.Join(cwd, path)
+			}
+			if change.Status == "D" || !manager.Handles(path) {
+				continue
+			}
+			paths = append(paths, path)
+			relative[path] = change.Path
+		}
+		if len(paths) == 0 {
+			return payload, nil
+		}
+		diagnostics, errs := manager.Diagnostics(ctx, paths)
+		for i := range diagnostics {
+			diagnostics[i].Path = relative[diagnostics[i].Path]
+		}
+
+		var b strings.Builder
+		switch {
+		case len(diagnostics) > 0:
+			fmt.Fprintf(&b, "\n\nDiagnostics after the edit (%d):\n%s", len(diagnostics), lsp.FormatDiagnostics(diagnostics, maxObservationDiagnostics))
+		case len(errs) == 0:
+			b.WriteString("\n\nDiagnostics after the edit: none.")
+		}
+		for _, diagErr := range errs {
+			fmt.Fprintf(&b, "\nDiagnostics unavailable: %v", diagErr)
+		}
+		payload.Stdout += b.String()
+		if len(diagnostics) > 0 {
+			payload.Data = map[string]any{"diagnostics": diagnostics}
+		}
+		return payload, nil
+	}
+}
@@ -0,0 +1,47 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asynkron/goagent/internal/lsp"
+)
+
+func TestWithDiagnosticsReportsOnlyHandledFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	manager := lsp.NewManager(dir, []LanguageServer{{Name: "missing", Command: []string{"goagent-no-such-server"}, Extensions: []string{".go"}}}, time.Second)
+	defer manager.Close()
+	handler := withDiagnostics(manager, newApplyPatchCommand())
+
+	run := func(patch string) PlanObservationPayload {
+		t.Helper()
+		payload, err := handler(context.Background(), InternalCommandRequest{
+			Name: applyPatchCommandName,
+			Raw:  "apply_patch\n" + patch,
+			Step: PlanStep{ID: "patch", Command: CommandDraft{Shell: agentShell, Cwd: dir}},
+		})
+		if err != nil {
+			t.Fatalf("apply_patch: %v", err)
+		}
+		return payload
+	}
+
+	payload := run("*** Begin Patch\n*** Add File: notes.md\n+hello\n*** End Patch")
+	if strings.Contains(payload.Stdout, "Diagnostics") {
+		t.Fatalf("expected no diagnostics section for unhandled files, got %q", payload.Stdout)
+	}
+
+	payload = run("*** Begin Patch\n*** Add File: main.go\n+package main\n*** End Patch")
+	if !strings.Contains(payload.Stdout, "Diagnostics unavailable: lsp: start missing") {
+		t.Fatalf("expected the start failure to be reported, got %q", payload.Stdout)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "main.go")); err != nil {
+		t.Fatalf("expected the patch to be applied: %v", err)
+	}
+}
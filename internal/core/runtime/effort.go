@@ -0,0 +1,101 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// reasoningEffortLevels lists the OpenAI reasoning efforts from cheapest to
+// most thorough.
+var reasoningEffortLevels = []string{"minimal", "low", "medium", "high"}
+
+// Default bounds for RuntimeOptions.AdaptiveEffort.
+const (
+	defaultMinReasoningEffort = "low"
+	defaultMaxReasoningEffort = "high"
+)
+
+// reasoningEffortKey is the context key for a per-request reasoning effort.
+type reasoningEffortKey struct{}
+
+// WithReasoningEffort returns a context whose plan requests use effort
+// instead of the client's configured reasoning effort.
+func WithReasoningEffort(ctx context.Context, effort string) context.Context {
+	return context.WithValue(ctx, reasoningEffortKey{}, effort)
+}
+
+// ReasoningEffortFrom returns the effort set by WithReasoningEffort, or "".
+func ReasoningEffortFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	effort, _ := ctx.Value(reasoningEffortKey{}).(string)
+	return effort
+}
+
+// validateEffortBounds checks the AdaptiveEffort bounds.
+func validateEffortBounds(floor, ceiling string) error {
+	lo, hi := slices.Index(reasoningEffortLevels, floor), slices.Index(reasoningEffortLevels, ceiling)
+	switch {
+	case lo < 0:
+		return fmt.Errorf("unknown minimum reasoning effort %q (want minimal, low, medium or high)", floor)
+	case hi < 0:
+		return fmt.Errorf("unknown maximum reasoning effort %q (want minimal, low, medium or high)", ceiling)
+	case lo > hi:
+		return fmt.Errorf("minimum reasoning effort %q is above the maximum %q", floor, ceiling)
+	}
+	return nil
+}
+
+// adaptiveEffort picks the reasoning effort for the next plan request from
+// the shape of history, within [floor, ceiling]. New instructions from the
+// user get the ceiling; a pass that only follows up on steps that all
+// succeeded gets the floor; failures and anything else get the middle.
+// reason explains the choice for event metadata.
+func adaptiveEffort(history []ChatMessage, floor, ceiling string) (effort, reason string) {
+	lo, hi := slices.Index(reasoningEffortLevels, floor), slices.Index(reasoningEffortLevels, ceiling)
+	if lo < 0 || hi < lo {
+		return "", ""
+	}
+	middle := reasoningEffortLevels[(lo+hi+1)/2]
+	if len(history) == 0 {
+		return middle, "no history"
+	}
+
+	last := history[len(history)-1]
+	switch last.Role {
+	case RoleUser:
+		return ceiling, "new instructions"
+	case RoleTool:
+		var payload PlanObservationPayload
+		if err := json.Unmarshal([]byte(last.Content), &payload); err != nil || len(payload.PlanObservation) == 0 {
+			return middle, "tool result"
+		}
+		if payload.CanceledByHuman {
+			return middle, "cancelled steps"
+		}
+		for _, step := range payload.PlanObservation {
+			if step.Status != PlanCompleted || (step.ExitCode != nil && *step.ExitCode != 0) {
+				return middle, "failed steps"
+			}
+		}
+		return floor, "command follow-up"
+	}
+	return middle, "default"
+}
+
+// effortForRequest returns the context for a plan request over history and
+// the reasoning effort it carries when AdaptiveEffort chose one. An effort
+// already on ctx, such as a per-prompt override, is kept.
+func (r *Runtime) effortForRequest(ctx context.Context, history []ChatMessage) (context.Context, string, string) {
+	if !r.options.AdaptiveEffort || ReasoningEffortFrom(ctx) != "" {
+		return ctx, "", ""
+	}
+	effort, reason := adaptiveEffort(history, r.options.MinReasoningEffort, r.options.MaxReasoningEffort)
+	if effort == "" {
+		return ctx, "", ""
+	}
+	return WithReasoningEffort(ctx, effort), effort, reason
+}
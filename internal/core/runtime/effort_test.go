@@ -0,0 +1,74 @@
+package runtime
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAdaptiveEffort(t *testing.T) {
+	t.Parallel()
+
+	observation := func(payload PlanObservationPayload) ChatMessage {
+		content, err := BuildToolMessage(payload)
+		if err != nil {
+			t.Fatalf("BuildToolMessage returned error: %v", err)
+		}
+		return ChatMessage{Role: RoleTool, Content: content}
+	}
+	failed := 1
+	cases := []struct {
+		name    string
+		history []ChatMessage
+		want    string
+	}{
+		{name: "prompt", history: []ChatMessage{{Role: RoleSystem}, {Role: RoleUser, Content: "refactor"}}, want: "high"},
+		{name: "success", history: []ChatMessage{observation(PlanObservationPayload{PlanObservation: []StepObservation{{ID: "a", Status: PlanCompleted}}})}, want: "low"},
+		{name: "failure", history: []ChatMessage{observation(PlanObservationPayload{PlanObservation: []StepObservation{{ID: "a", Status: PlanFailed, ExitCode: &failed}}})}, want: "medium"},
+		{name: "tool result", history: []ChatMessage{{Role: RoleTool, Content: `{"hits":[]}`}}, want: "medium"},
+	}
+	for _, tc := range cases {
+		if got, reason := adaptiveEffort(tc.history, "low", "high"); got != tc.want || reason == "" {
+			t.Fatalf("%s: adaptiveEffort = %q (%q), want %q", tc.name, got, reason, tc.want)
+		}
+	}
+	if got, _ := adaptiveEffort(cases[2].history, "minimal", "low"); got != "low" {
+		t.Fatalf("expected the middle of minimal..low to round up, got %q", got)
+	}
+}
+
+func TestEffortForRequestKeepsExplicitEffort(t *testing.T) {
+	t.Parallel()
+
+	rt := &Runtime{options: RuntimeOptions{AdaptiveEffort: true, MinReasoningEffort: "low", MaxReasoningEffort: "high"}}
+	history := []ChatMessage{{Role: RoleUser, Content: "go"}}
+
+	ctx, effort, _ := rt.effortForRequest(context.Background(), history)
+	if effort != "high" || ReasoningEffortFrom(ctx) != "high" {
+		t.Fatalf("expected adaptive effort high, got %q", effort)
+	}
+	ctx, effort, _ = rt.effortForRequest(WithReasoningEffort(context.Background(), "minimal"), history)
+	if effort != "" || ReasoningEffortFrom(ctx) != "minimal" {
+		t.Fatalf("expected the explicit effort to win, got %q", ReasoningEffortFrom(ctx))
+	}
+
+	if err := validateEffortBounds("high", "low"); err == nil {
+		t.Fatal("expected inverted bounds to be rejected")
+	}
+}
+
+func TestOpenAIRequestUsesPerRequestEffort(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewOpenAIClient("test-key", "o3", "low", "", nil, nil, nil, 0)
+	if err != nil {
+		t.Fatalf("NewOpenAIClient returned error: %v", err)
+	}
+	body, err := client.buildRequestBody(nil, "", "high", false)
+	if err != nil {
+		t.Fatalf("buildRequestBody returned error: %v", err)
+	}
+	if !strings.Contains(string(body), `"effort":"high"`) {
+		t.Fatalf("expected the request effort in the body, got %s", body)
+	}
+}
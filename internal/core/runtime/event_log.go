@@ -0,0 +1,115 @@
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// EventLogFile is the name of the event log in a session directory.
+const EventLogFile = "events.jsonl"
+
+// maxEventLogLine bounds a single line read by ReadEventLog.
+const maxEventLogLine = 16 << 20
+
+// SessionEventLogPath returns the event log of the session with id under
+// root (SessionsDir when empty).
+func SessionEventLogPath(root, id string) string {
+	return filepath.Join(filepath.Dir(SessionHistoryPath(root, id)), EventLogFile)
+}
+
+// eventRecorder appends every emitted event to RuntimeOptions.EventLogPath as
+// a JSON line. The file is opened on the first event, so runtimes that never
+// emit leave nothing behind. After a write error it stops recording.
+type eventRecorder struct {
+	mu     sync.Mutex
+	path   string
+	file   *os.File
+	failed bool
+}
+
+// record appends evt. It returns the error that stopped recording, once.
+func (e *eventRecorder) record(evt RuntimeEvent) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.path == "" || e.failed {
+		return nil
+	}
+	if e.file == nil {
+		if err := os.MkdirAll(filepath.Dir(e.path), 0o755); err != nil {
+			e.failed = true
+			return fmt.Errorf("open event log: %w", err)
+		}
+		file, err := os.OpenFile(e.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			e.failed = true
+			return fmt.Errorf("open event log: %w", err)
+		}
+		e.file = file
+	}
+	if _, err := e.file.Write(MarshalEventJSON(evt)); err != nil {
+		e.failed = true
+		return fmt.Errorf("write event log: %w", err)
+	}
+	return nil
+}
+
+// close closes the file; later events are not recorded.
+func (e *eventRecorder) close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.failed = true
+	if e.file == nil {
+		return nil
+	}
+	err := e.file.Close()
+	e.file = nil
+	return err
+}
+
+// recordEvent writes evt to the event log, warning once when that fails.
+func (r *Runtime) recordEvent(evt RuntimeEvent) {
+	if err := r.events.record(evt); err != nil {
+		r.options.Logger.Warn(context.Background(), "Event log disabled", Field("error", err.Error()))
+	}
+}
+
+// ReadEventLog returns the events recorded at path, in the order they were
+// emitted. A truncated last line, as left by a crash, is ignored.
+func ReadEventLog(path string) ([]RuntimeEvent, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var events []RuntimeEvent
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventLogLine)
+	line := 0
+	var pending error
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if pending != nil {
+			return nil, pending
+		}
+		var evt RuntimeEvent
+		if err := json.Unmarshal(scanner.Bytes(), &evt); err != nil {
+			// Only the last line may be cut short.
+			pending = fmt.Errorf("decode event log %s line %d: %w", path, line, err)
+			continue
+		}
+		events = append(events, evt)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read event log %s: %w", path, err)
+	}
+	return events, nil
+}
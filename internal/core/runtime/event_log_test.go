@@ -0,0 +1,77 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEmit_RecordsEventLog(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "session", EventLogFile)
+	rt := &Runtime{
+		outputs:   make(chan RuntimeEvent, 4),
+		closed:    make(chan struct{}),
+		agentName: "main",
+	}
+	rt.events.path = path
+
+	rt.emit(RuntimeEvent{Type: EventTypeStatus, Message: "first", Metadata: map[string]any{"paused": true}})
+	rt.emit(RuntimeEvent{Type: EventTypeAssistantMessage, Message: "second", Agent: "helper"})
+	rt.close()
+	rt.emit(RuntimeEvent{Type: EventTypeStatus, Message: "after close"})
+
+	events, err := ReadEventLog(path)
+	if err != nil {
+		t.Fatalf("ReadEventLog: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %+v", events)
+	}
+	if events[0].Message != "first" || events[0].Agent != "main" || events[0].Timestamp.IsZero() || events[0].Metadata["paused"] != true {
+		t.Fatalf("unexpected first event: %+v", events[0])
+	}
+	if events[1].Type != EventTypeAssistantMessage || events[1].Agent != "helper" {
+		t.Fatalf("unexpected second event: %+v", events[1])
+	}
+}
+
+func TestEmit_WithoutEventLogPathWritesNothing(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	rt := &Runtime{
+		outputs:   make(chan RuntimeEvent, 1),
+		closed:    make(chan struct{}),
+		agentName: "main",
+	}
+	rt.emit(RuntimeEvent{Type: EventTypeStatus, Message: "ignored"})
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("expected no files, got %v, %v", entries, err)
+	}
+}
+
+func TestReadEventLog_IgnoresTruncatedLastLine(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), EventLogFile)
+	content := `{"type":"status","message":"one","pass":1,"agent":"main","timestamp":"2026-10-17T10:00:00Z"}` + "\n" + `{"type":"stat`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	events, err := ReadEventLog(path)
+	if err != nil || len(events) != 1 || events[0].Message != "one" {
+		t.Fatalf("expected the complete event, got %+v, %v", events, err)
+	}
+
+	corrupt := `{"type":"stat` + "\n" + `{"type":"status","message":"two"}` + "\n"
+	if err := os.WriteFile(path, []byte(corrupt), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := ReadEventLog(path); err == nil {
+		t.Fatalf("expected an error for a corrupt line before the end")
+	}
+}
@@ -0,0 +1,48 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// OutputFormat selects how forwarded events are written to
+// RuntimeOptions.OutputWriter.
+type OutputFormat string
+
+const (
+	// OutputFormatText writes "[type] message" lines (the default).
+	OutputFormatText OutputFormat = "text"
+	// OutputFormatJSONL writes each RuntimeEvent as one JSON object per line.
+	OutputFormatJSONL OutputFormat = "jsonl"
+)
+
+func validateOutputFormat(format OutputFormat) error {
+	switch format {
+	case "", OutputFormatText, OutputFormatJSONL:
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q (want text or jsonl)", format)
+	}
+}
+
+// MarshalEventJSON encodes evt as a single JSON line. Metadata that cannot be
+// encoded is replaced by a "metadata_error" entry so the line is still
+// emitted.
+func MarshalEventJSON(evt RuntimeEvent) []byte {
+	data, err := json.Marshal(evt)
+	if err != nil {
+		evt.Metadata = map[string]any{"metadata_error": err.Error()}
+		data, _ = json.Marshal(evt)
+	}
+	return append(data, '\n')
+}
+
+// writeEvent formats evt for the forwarding writer.
+func writeEvent(w io.Writer, format OutputFormat, evt RuntimeEvent) {
+	if format == OutputFormatJSONL {
+		_, _ = w.Write(MarshalEventJSON(evt))
+		return
+	}
+	_, _ = fmt.Fprintf(w, "[%s] %s\n", evt.Type, evt.Message)
+}
@@ -0,0 +1,229 @@
+package runtime
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy decides what a subscription does when its buffer is full.
+type OverflowPolicy string
+
+const (
+	// OverflowDropNewest discards the event that does not fit. It is the
+	// default, so a slow subscriber never holds up the runtime.
+	OverflowDropNewest OverflowPolicy = "drop-newest"
+	// OverflowDropOldest discards the oldest buffered event to make room,
+	// keeping the subscriber close to the live state.
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowBlock waits for the subscriber like Outputs does. Use it only
+	// for consumers that must see every event.
+	OverflowBlock OverflowPolicy = "block"
+)
+
+// defaultSubscriptionBuffer is used when EventFilter.Buffer is zero.
+const defaultSubscriptionBuffer = 64
+
+// EventFilter selects the events delivered to a subscription and how they are
+// buffered. Empty selectors match everything.
+type EventFilter struct {
+	// Types limits delivery to these event types.
+	Types []EventType
+	// Agents limits delivery to events emitted by these agents ("main" or a
+	// sub-agent name).
+	Agents []string
+	// MinLevel drops status and error events below this level. Events
+	// without a level always pass.
+	MinLevel StatusLevel
+	// Match is an extra predicate applied after the other selectors.
+	Match func(RuntimeEvent) bool
+	// Buffer is the subscription channel capacity. Defaults to 64.
+	Buffer int
+	// Overflow applies when the buffer is full. Defaults to
+	// OverflowDropNewest.
+	Overflow OverflowPolicy
+}
+
+func (f EventFilter) matches(evt RuntimeEvent) bool {
+	if len(f.Types) > 0 && !containsValue(f.Types, evt.Type) {
+		return false
+	}
+	if len(f.Agents) > 0 && !containsValue(f.Agents, evt.Agent) {
+		return false
+	}
+	if f.MinLevel != "" && evt.Level != "" && statusLevelRank(evt.Level) < statusLevelRank(f.MinLevel) {
+		return false
+	}
+	return f.Match == nil || f.Match(evt)
+}
+
+func containsValue[T comparable](values []T, value T) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+func statusLevelRank(level StatusLevel) int {
+	switch level {
+	case StatusLevelWarn:
+		return 1
+	case StatusLevelError:
+		return 2
+	default:
+		return 0
+	}
+}
+
+type subscription struct {
+	filter EventFilter
+	// mu serializes sends on ch with closing it, so delivery runs without
+	// the hub's lock.
+	mu     sync.Mutex
+	ch     chan RuntimeEvent
+	closed bool
+	// done is closed by stop so a blocked delivery gives up.
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// stop ends delivery and closes the channel once.
+func (s *subscription) stop() {
+	s.doneOnce.Do(func() { close(s.done) })
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+// eventHub fans emitted events out to subscriptions. The zero value is ready
+// to use.
+type eventHub struct {
+	mu     sync.Mutex
+	subs   map[*subscription]struct{}
+	closed bool
+	// used is set by the first subscription; emit then stops waiting on
+	// Outputs unless the host asked for it.
+	used atomic.Bool
+}
+
+func (h *eventHub) subscribe(filter EventFilter) (<-chan RuntimeEvent, func()) {
+	if filter.Buffer <= 0 {
+		filter.Buffer = defaultSubscriptionBuffer
+	}
+	if filter.Overflow == "" {
+		filter.Overflow = OverflowDropNewest
+	}
+	sub := &subscription{
+		filter: filter,
+		ch:     make(chan RuntimeEvent, filter.Buffer),
+		done:   make(chan struct{}),
+	}
+
+	h.used.Store(true)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		sub.stop()
+		return sub.ch, func() {}
+	}
+	if h.subs == nil {
+		h.subs = make(map[*subscription]struct{})
+	}
+	h.subs[sub] = struct{}{}
+
+	return sub.ch, func() {
+		h.mu.Lock()
+		delete(h.subs, sub)
+		h.mu.Unlock()
+		sub.stop()
+	}
+}
+
+// publish delivers evt to every matching subscription and returns how many
+// dropped it because their buffer was full. The hub's lock only covers
+// taking the list of subscriptions, so a blocking subscriber holds up this
+// event but not subscribing, cancelling or other subscribers' delivery.
+func (h *eventHub) publish(evt RuntimeEvent, closed <-chan struct{}) int {
+	h.mu.Lock()
+	subs := make([]*subscription, 0, len(h.subs))
+	for sub := range h.subs {
+		subs = append(subs, sub)
+	}
+	h.mu.Unlock()
+
+	dropped := 0
+	for _, sub := range subs {
+		if !sub.filter.matches(evt) {
+			continue
+		}
+		if !sub.deliver(evt, closed) {
+			dropped++
+		}
+	}
+	return dropped
+}
+
+// deliver sends evt unless the subscription was stopped meanwhile, which
+// does not count as a drop.
+func (s *subscription) deliver(evt RuntimeEvent, closed <-chan struct{}) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return true
+	}
+	select {
+	case s.ch <- evt:
+		return true
+	default:
+	}
+
+	switch s.filter.Overflow {
+	case OverflowBlock:
+		select {
+		case s.ch <- evt:
+			return true
+		case <-s.done:
+		case <-closed:
+		}
+		return false
+	case OverflowDropOldest:
+		// Only publish sends, so after taking one event there is room.
+		select {
+		case <-s.ch:
+		default:
+		}
+		s.ch <- evt
+		return false
+	default:
+		return false
+	}
+}
+
+// close ends every subscription; later subscriptions are closed at once.
+func (h *eventHub) close() {
+	h.mu.Lock()
+	h.closed = true
+	subs := h.subs
+	h.subs = nil
+	h.mu.Unlock()
+	for sub := range subs {
+		sub.stop()
+	}
+}
+
+// Subscribe returns a channel receiving the events that match filter, next to
+// Outputs and any other subscriptions. Each subscription buffers
+// independently, so a slow consumer only loses its own events (see
+// EventFilter.Overflow). Call cancel to stop delivery and close the channel;
+// the channel is also closed when the runtime shuts down.
+//
+// Once a host subscribes, the runtime no longer waits for Outputs to be
+// drained unless the host has called Outputs, so hosts may use
+// subscriptions alone.
+func (r *Runtime) Subscribe(filter EventFilter) (<-chan RuntimeEvent, func()) {
+	return r.subscribers.subscribe(filter)
+}
@@ -0,0 +1,134 @@
+package runtime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscribeFiltersAndFansOutEvents(t *testing.T) {
+	t.Parallel()
+
+	metrics := NewInMemoryMetrics()
+	rt := &Runtime{
+		options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: metrics},
+		outputs:   make(chan RuntimeEvent, 16),
+		closed:    make(chan struct{}),
+		agentName: "main",
+	}
+
+	outputs := rt.Outputs()
+	errorsOnly, cancelErrors := rt.Subscribe(EventFilter{MinLevel: StatusLevelError})
+	defer cancelErrors()
+	latest, _ := rt.Subscribe(EventFilter{Types: []EventType{EventTypeStatus}, Buffer: 1, Overflow: OverflowDropOldest})
+	dropping, _ := rt.Subscribe(EventFilter{Buffer: 1})
+
+	rt.emit(RuntimeEvent{Type: EventTypeStatus, Message: "first", Level: StatusLevelInfo})
+	rt.emit(RuntimeEvent{Type: EventTypeError, Message: "boom", Level: StatusLevelError})
+	rt.emit(RuntimeEvent{Type: EventTypeStatus, Message: "second", Level: StatusLevelWarn})
+
+	if len(outputs) != 3 {
+		t.Fatalf("expected Outputs to keep receiving every event, got %d", len(outputs))
+	}
+	if evt := <-errorsOnly; evt.Message != "boom" || len(errorsOnly) != 0 {
+		t.Fatalf("expected only the error event, got %+v (%d more)", evt, len(errorsOnly))
+	}
+	if evt := <-latest; evt.Message != "second" {
+		t.Fatalf("expected drop-oldest to keep the newest status, got %+v", evt)
+	}
+	if evt := <-dropping; evt.Message != "first" {
+		t.Fatalf("expected drop-newest to keep the first event, got %+v", evt)
+	}
+	if got := metrics.GetSnapshot().DroppedEvents; got != 3 {
+		t.Fatalf("expected three dropped deliveries, got %d", got)
+	}
+
+	cancelErrors()
+	if _, ok := <-errorsOnly; ok {
+		t.Fatal("expected cancel to close the subscription")
+	}
+	rt.close()
+	if _, ok := <-latest; ok {
+		t.Fatal("expected shutdown to close subscriptions")
+	}
+	late, _ := rt.Subscribe(EventFilter{})
+	if _, ok := <-late; ok {
+		t.Fatal("expected subscriptions after shutdown to be closed")
+	}
+}
+
+func TestSubscribeOnlyHostDoesNotBlockEmit(t *testing.T) {
+	t.Parallel()
+
+	rt := &Runtime{
+		options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}},
+		outputs:   make(chan RuntimeEvent, 1),
+		closed:    make(chan struct{}),
+		agentName: "main",
+	}
+	events, cancel := rt.Subscribe(EventFilter{Buffer: 8})
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 4; i++ {
+			rt.emit(RuntimeEvent{Type: EventTypeStatus, Message: "tick"})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected emit not to wait on an Outputs channel nobody reads")
+	}
+	if len(events) != 4 || len(rt.outputs) != 0 {
+		t.Fatalf("expected the events on the subscription only, got %d and %d on Outputs", len(events), len(rt.outputs))
+	}
+}
+
+func TestBlockingSubscriberDoesNotHoldTheHub(t *testing.T) {
+	t.Parallel()
+
+	rt := &Runtime{
+		options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}},
+		outputs:   make(chan RuntimeEvent, 4),
+		closed:    make(chan struct{}),
+		agentName: "main",
+	}
+	_ = rt.Outputs()
+	blocking, cancelBlocking := rt.Subscribe(EventFilter{Buffer: 1, Overflow: OverflowBlock})
+
+	published := make(chan struct{})
+	go func() {
+		defer close(published)
+		rt.emit(RuntimeEvent{Type: EventTypeStatus, Message: "first"})
+		rt.emit(RuntimeEvent{Type: EventTypeStatus, Message: "second"})
+	}()
+	// Wait until the second event is stuck on the full subscription.
+	deadline := time.After(5 * time.Second)
+	for len(rt.outputs) < 1 || len(blocking) < 1 {
+		select {
+		case <-deadline:
+			t.Fatal("expected the first event to be delivered")
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	subscribed := make(chan struct{})
+	go func() {
+		defer close(subscribed)
+		_, cancel := rt.Subscribe(EventFilter{})
+		cancel()
+	}()
+	select {
+	case <-subscribed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected subscribing not to wait for a blocked delivery")
+	}
+
+	cancelBlocking()
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected cancel to release the blocked delivery")
+	}
+}
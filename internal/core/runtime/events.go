@@ -1,6 +1,8 @@
 // Package runtime implements the GoAgent runtime orchestration loop and event types.
 package runtime
 
+import "time"
+
 // EventType RuntimeEventType represents the category of a runtime event emitted by the
 // agent loop. The names map to the event payload types produced by the
 // TypeScript implementation, so consumers can react in the same fashion.
@@ -15,11 +17,43 @@ const (
 	// Hosts can render these incrementally and optionally wait for a final
 	// EventTypeAssistantMessage with the consolidated content when the stream ends.
 	EventTypeAssistantDelta EventType = "assistant_delta"
+	// EventTypeReasoningDelta streams a chunk of the model's reasoning: the
+	// plan's "reasoning" entries and native reasoning summaries when
+	// RuntimeOptions.ReasoningSummary is set. It is never part of
+	// EventTypeAssistantMessage or the exported transcript, so hosts can show
+	// it in a separate, collapsible pane.
+	EventTypeReasoningDelta EventType = "reasoning_delta"
 	// EventTypeError is emitted when the runtime hits an unrecoverable error.
 	EventTypeError EventType = "error"
 	// EventTypeRequestInput notifies the host that the runtime is ready to
 	// receive further input from the user or automation harness.
 	EventTypeRequestInput EventType = "request_input"
+	// EventTypeFileChange is emitted once per file created, modified, or
+	// deleted by an internal command such as apply_patch. Metadata carries the
+	// "path", "status" (A/M/D), "hunks", "bytes", "additions", and "deletions"
+	// keys so hosts can render a changed-files panel or trigger reloads, plus
+	// "diff" with the applied hunks in unified diff form when available.
+	EventTypeFileChange EventType = "file_change"
+	// EventTypeApprovalRequest asks the host to confirm a plan step before it
+	// runs. Metadata carries the "step_id", "title", "command", "shell", and
+	// "cwd" keys; the host answers with an InputTypeApprovalDecision event.
+	EventTypeApprovalRequest EventType = "approval_request"
+	// EventTypeCommandOutput streams chunks of a running shell step's output.
+	// Message holds the chunk; Metadata carries "step_id" and "stream"
+	// ("stdout" or "stderr"). Chunks are rate-limited and may split lines.
+	EventTypeCommandOutput EventType = "command_output"
+	// EventTypeSubagentRequest carries a question a sub-agent posted to its
+	// parent with ask_parent. Agent names the sub-agent; hosts can answer
+	// through Orchestrator.Send.
+	EventTypeSubagentRequest EventType = "subagent_request"
+	// EventTypeWorkspaceChange reports files created, modified, or deleted
+	// outside the agent while RuntimeOptions.WatchWorkspace is on. Metadata
+	// "changes" lists WorkspaceChange values.
+	EventTypeWorkspaceChange EventType = "workspace_change"
+	// EventTypeEnvironment is emitted once at startup when
+	// RuntimeOptions.Environment is set. Metadata "environment" holds the
+	// host's environment detection result, such as a bootprobe result.
+	EventTypeEnvironment EventType = "environment"
 )
 
 // StatusLevel mirrors the severity levels surfaced by the TypeScript runtime.
@@ -48,6 +82,8 @@ type RuntimeEvent struct {
 	Metadata map[string]any `json:"metadata,omitempty"`
 	Pass     int            `json:"pass"`
 	Agent    string         `json:"agent"`
+	// Timestamp is set when the event is emitted.
+	Timestamp time.Time `json:"timestamp"`
 }
 
 // InputEventType distinguishes the different kinds of inputs that can be
@@ -62,14 +98,34 @@ const (
 	InputTypeCancel InputEventType = "cancel"
 	// InputTypeShutdown initiates a graceful shutdown of the runtime.
 	InputTypeShutdown InputEventType = "shutdown"
+	// InputTypeApprovalDecision answers an EventTypeApprovalRequest. StepID
+	// identifies the step and Approved carries the decision.
+	InputTypeApprovalDecision InputEventType = "approval_decision"
+	// InputTypeCommandStdin writes Data to the stdin of the running
+	// interactive step identified by StepID. EOF closes the pipe afterwards.
+	InputTypeCommandStdin InputEventType = "command_stdin"
+	// InputTypePause stops the runtime from starting work: running steps
+	// finish, but no new steps start and the next plan request waits.
+	InputTypePause InputEventType = "pause"
+	// InputTypeResume lifts an InputTypePause.
+	InputTypeResume InputEventType = "resume"
 )
 
 // InputEvent is the public payload that can be enqueued on the runtime input
 // queue. When Type is InputTypePrompt the Prompt field carries the actual user
 // message. Reason can be used to describe the origin of a cancel or shutdown
-// request.
+// request, or why an approval was rejected.
 type InputEvent struct {
-	Type   InputEventType
-	Prompt string
-	Reason string
+	Type     InputEventType
+	Prompt   string
+	Reason   string
+	StepID   string
+	Approved bool
+	Data     string
+	EOF      bool
+	// Attachments accompany an InputTypePrompt.
+	Attachments []Attachment
+	// Override runs an InputTypePrompt's turn on another model or
+	// reasoning effort. A "/model" prefix in Prompt takes precedence.
+	Override ModelOverride
 }
@@ -2,6 +2,7 @@ package runtime
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -113,10 +114,31 @@ func (r *Runtime) recordPlanResponse(plan *PlanResponse, toolCall ToolCall) int
 	return r.plan.ExecutableCount()
 }
 
-func (r *Runtime) executePendingCommands(ctx context.Context, toolCall ToolCall) {
+// executePendingCommands runs ready plan steps until the plan stalls, a step
+// fails, or the host cancels. It reports whether the plan was cancelled via
+// InputTypeCancel, in which case running steps are killed and every step
+// that did not finish is reported as cancelled.
+func (r *Runtime) executePendingCommands(ctx context.Context, toolCall ToolCall) bool {
 	r.commandMu.Lock()
 	defer r.commandMu.Unlock()
 
+	// Steps run under their own context so a cancel input can stop them
+	// without tearing down the runtime.
+	parentCtx := ctx
+	// The workspace watcher ignores what the steps write.
+	r.stepsRunning.Store(true)
+	defer func() {
+		if r.watcher != nil {
+			r.watcher.rebaseline(parentCtx)
+		}
+		r.stepsRunning.Store(false)
+	}()
+	ctx, cancelSteps := context.WithCancel(ctx)
+	defer cancelSteps()
+	cancelled := false
+	cancelReason := ""
+	finished := make(map[string]struct{})
+
 	var (
 		executedSteps   int
 		lastStepID      string
@@ -131,21 +153,43 @@ func (r *Runtime) executePendingCommands(ctx context.Context, toolCall ToolCall)
 		step        PlanStep
 		observation PlanObservationPayload
 		err         error
+		// started and finished bracket the executor call; both are the
+		// rejection time for steps that never ran.
+		started  time.Time
+		finished time.Time
 	}
 
 	results := make(chan stepExecutionResult)
 	executing := 0
+	// running feeds the plan journal; it holds the steps handed to workers.
+	running := make(map[string]bool)
 	haltScheduling := false
 
+	// busyGroups counts running steps per concurrency group; a group admits
+	// one step at a time.
+	busyGroups := make(map[string]int)
+	groupFree := func(step PlanStep) bool {
+		group := strings.TrimSpace(step.ConcurrencyGroup)
+		return group == "" || busyGroups[group] == 0
+	}
+
+	// handleInput processes an input that arrives while the plan runs; it
+	// is defined below, next to the state it updates.
+	var handleInput func(evt InputEvent, ok bool)
+
 	// scheduleReadySteps launches goroutines for every currently-ready step.
+	// A pause stops it from starting more.
 	scheduleReadySteps := func() bool {
 		started := false
 		if haltScheduling {
 			return started
 		}
 
-		for ctx.Err() == nil {
-			stepPtr, ok := r.plan.Ready()
+		for ctx.Err() == nil && !r.paused.Load() {
+			if limit := r.options.MaxParallelSteps; limit > 0 && executing >= limit {
+				break
+			}
+			stepPtr, ok := r.plan.ReadyWhere(groupFree)
 			if !ok {
 				break
 			}
@@ -153,64 +197,242 @@ func (r *Runtime) executePendingCommands(ctx context.Context, toolCall ToolCall)
 			step := *stepPtr
 			started = true
 
+			// reject records step as failed without running it. A rejection
+			// halts scheduling once it is recorded, so callers stop asking
+			// about further steps in this pass.
+			reject := func(rejection error) {
+				executing++
+				now := time.Now()
+				go func(step PlanStep) {
+					results <- stepExecutionResult{step: step, observation: PlanObservationPayload{Details: rejection.Error()}, err: rejection, started: now, finished: now}
+				}(step)
+			}
+
+			if err := r.options.Hooks.beforeStepExecute(ctx, &step); err != nil {
+				reject(fmt.Errorf("step %s was vetoed by a hook: %w", step.ID, err))
+				break
+			}
+
+			if violation := r.readOnlyViolation(step); violation != "" {
+				reject(errors.New(violation))
+				break
+			}
+
+			decision := r.options.CommandPolicy.Evaluate(step)
+			if decision.Action == PolicyRequireApproval && r.options.HandsFree {
+				decision = PolicyDecision{Action: PolicyDeny, Reason: decision.Reason + " (requires approval, but the session is hands-free)"}
+			}
+			if decision.Action == PolicyDeny {
+				reject(fmt.Errorf("step %s was blocked by the command policy: %s", step.ID, decision.Reason))
+				break
+			}
+
+			needsApproval := decision.Action == PolicyRequireApproval || r.requiresApproval(step)
+			if needsApproval && r.options.HandsFree {
+				reject(fmt.Errorf("step %s requires approval under the %q approval policy, but the session is hands-free", step.ID, r.options.ApprovalPolicy))
+				break
+			}
+			if needsApproval {
+				if approved, reason := r.awaitApproval(ctx, step, handleInput); !approved {
+					rejection := fmt.Errorf("step %s was not approved", step.ID)
+					if reason != "" {
+						rejection = fmt.Errorf("step %s was not approved: %s", step.ID, reason)
+					}
+					reject(rejection)
+					break
+				}
+			}
+
+			if r.snapshots != nil && stepMayWrite(step) {
+				if err := r.snapshots.recordStep(r.currentPassCount(), step); err != nil {
+					r.options.Logger.Warn(ctx, "Failed to snapshot workspace before step",
+						Field("step_id", step.ID),
+						Field("error", err.Error()),
+					)
+				}
+			}
+
 			title := strings.TrimSpace(step.Title)
 			if title == "" {
 				title = step.ID
 			}
 
+			started := time.Now()
 			r.emit(RuntimeEvent{
 				Type:    EventTypeStatus,
 				Message: fmt.Sprintf("Executing step %s: %s", step.ID, title),
 				Level:   StatusLevelInfo,
 				Metadata: map[string]any{
-					"step_id": step.ID,
-					"title":   step.Title,
-					"command": step.Command.Run,
-					"shell":   step.Command.Shell,
-					"cwd":     step.Command.Cwd,
+					"step_id":    step.ID,
+					"started_at": started,
+					"title":      step.Title,
+					"command":    step.Command.Run,
+					"shell":      step.Command.Shell,
+					"cwd":        step.Command.Cwd,
+					// interactive tells hosts to offer an input box for stdin.
+					"interactive": step.Command.Interactive,
 				},
 			})
 
 			executing++
+			running[step.ID] = true
+			if group := strings.TrimSpace(step.ConcurrencyGroup); group != "" {
+				busyGroups[group]++
+			}
 
 			go func(step PlanStep) {
 				// Each worker reports its outcome so the main loop can
 				// record results and schedule additional ready steps.
-				observation, err := r.executor.Execute(ctx, step)
-				results <- stepExecutionResult{step: step, observation: observation, err: err}
+				stepCtx, span := r.startSpan(ctx, SpanStep,
+					Field("step_id", step.ID),
+					Field("shell", step.Command.Shell),
+				)
+				observation, err := r.executor.Execute(stepCtx, step)
+				r.options.Hooks.afterStepExecute(stepCtx, step, &observation, err)
+				if err != nil {
+					span.RecordError(err)
+				}
+				if observation.ExitCode != nil {
+					span.SetAttributes(Field("exit_code", *observation.ExitCode))
+				}
+				span.End()
+				results <- stepExecutionResult{step: step, observation: observation, err: err, started: started, finished: time.Now()}
 			}(step)
 		}
 
 		return started
 	}
 
+	// Inputs that arrive while steps run are handled here because the main
+	// loop is blocked; anything unrelated to the running plan is re-queued
+	// for the loop once execution finishes.
+	inputs := r.inputs
+	var deferred []InputEvent
+	defer func() {
+		if len(deferred) > 0 {
+			go func() {
+				for _, evt := range deferred {
+					r.enqueue(evt)
+				}
+			}()
+		}
+	}()
+	cancelPlan := func(reason string) {
+		if cancelled {
+			return
+		}
+		cancelled = true
+		cancelReason = strings.TrimSpace(reason)
+		haltScheduling = true
+		cancelSteps()
+		r.setPaused(false, "Pause lifted by the cancel.")
+		r.emit(RuntimeEvent{
+			Type:     EventTypeStatus,
+			Message:  fmt.Sprintf("Cancelling plan: %s", cancelReason),
+			Level:    StatusLevelWarn,
+			Metadata: map[string]any{"reason": cancelReason},
+		})
+	}
+	handleInput = func(evt InputEvent, ok bool) {
+		if !ok {
+			inputs = nil
+			cancelPlan("input channel closed")
+			return
+		}
+		if r.handlePauseInput(evt) {
+			return
+		}
+		switch evt.Type {
+		case InputTypeCancel:
+			cancelPlan(evt.Reason)
+		case InputTypeCommandStdin:
+			if err := r.SubmitCommandStdin(evt.StepID, evt.Data, evt.EOF); err != nil {
+				r.emit(RuntimeEvent{
+					Type:    EventTypeStatus,
+					Message: err.Error(),
+					Level:   StatusLevelWarn,
+				})
+			}
+		case InputTypeShutdown:
+			cancelPlan(evt.Reason)
+			deferred = append(deferred, evt)
+		default:
+			deferred = append(deferred, evt)
+		}
+	}
+
+	// A pause queued while the plan was requested takes effect before the
+	// first step starts; other queued inputs go back on the queue.
+	var requeue []InputEvent
+	for drained := false; !drained; {
+		select {
+		case evt, ok := <-inputs:
+			switch {
+			case !ok:
+				handleInput(evt, ok)
+			case !r.handlePauseInput(evt):
+				requeue = append(requeue, evt)
+			}
+		default:
+			drained = true
+		}
+	}
+	if len(requeue) > 0 {
+		go func() {
+			for _, evt := range requeue {
+				r.enqueue(evt)
+			}
+		}()
+	}
+
 	for {
-		if ctxErr := ctx.Err(); ctxErr != nil && finalErr == nil {
+		if ctxErr := parentCtx.Err(); ctxErr != nil && finalErr == nil {
 			finalErr = ctxErr
 		}
 
 		started := scheduleReadySteps()
-		if executing == 0 {
-			if !started {
-				if !r.plan.HasPending() {
-					r.emit(RuntimeEvent{
-						Type:    EventTypeStatus,
-						Message: "Plan execution completed.",
-						Level:   StatusLevelInfo,
-					})
-				}
-				break
-			}
+		if started {
+			r.writeJournal(toolCall, running)
 		}
-
-		if executing == 0 {
+		// A paused plan with ready steps waits here for resume or cancel
+		// once its running steps have finished.
+		waiting := executing == 0 && r.paused.Load() && !haltScheduling && r.plan.ExecutableCount() > 0
+		if executing == 0 && !waiting {
+			if !started && !r.plan.HasPending() {
+				r.emit(RuntimeEvent{
+					Type:    EventTypeStatus,
+					Message: "Plan execution completed.",
+					Level:   StatusLevelInfo,
+				})
+			}
 			break
 		}
+		var stop <-chan struct{}
+		var closed <-chan struct{}
+		if waiting {
+			stop, closed = parentCtx.Done(), r.closed
+		}
 
-		result := <-results
+		var result stepExecutionResult
+		select {
+		case result = <-results:
+		case evt, ok := <-inputs:
+			handleInput(evt, ok)
+			continue
+		case <-stop:
+			haltScheduling = true
+			continue
+		case <-closed:
+			haltScheduling = true
+			continue
+		}
 		executing--
 
 		step := result.step
+		delete(running, step.ID)
+		if group := strings.TrimSpace(step.ConcurrencyGroup); group != "" && busyGroups[group] > 0 {
+			busyGroups[group]--
+		}
 		observation := result.observation
 		err := result.err
 
@@ -220,7 +442,13 @@ func (r *Runtime) executePendingCommands(ctx context.Context, toolCall ToolCall)
 		status := PlanCompleted
 		level := StatusLevelInfo
 		message := fmt.Sprintf("Step %s completed successfully.", step.ID)
-		if err != nil {
+		finished[step.ID] = struct{}{}
+		if err != nil && cancelled {
+			status = PlanCancelled
+			level = StatusLevelWarn
+			observation.Details = "cancelled by user"
+			message = fmt.Sprintf("Step %s was cancelled.", step.ID)
+		} else if err != nil {
 			status = PlanFailed
 			level = StatusLevelError
 			if observation.Details == "" {
@@ -233,14 +461,18 @@ func (r *Runtime) executePendingCommands(ctx context.Context, toolCall ToolCall)
 			haltScheduling = true
 		}
 
+		duration := result.finished.Sub(result.started)
 		stepResult := StepObservation{
-			ID:        step.ID,
-			Status:    status,
-			Stdout:    observation.Stdout,
-			Stderr:    observation.Stderr,
-			ExitCode:  observation.ExitCode,
-			Details:   observation.Details,
-			Truncated: observation.Truncated,
+			ID:         step.ID,
+			Status:     status,
+			Stdout:     observation.Stdout,
+			Stderr:     observation.Stderr,
+			ExitCode:   observation.ExitCode,
+			Details:    observation.Details,
+			Truncated:  observation.Truncated,
+			Attempts:   observation.Attempts,
+			DurationMs: duration.Milliseconds(),
+			Artifacts:  observation.Artifacts,
 		}
 
 		// Record metrics for plan step status
@@ -249,6 +481,7 @@ func (r *Runtime) executePendingCommands(ctx context.Context, toolCall ToolCall)
 		planObservation := &PlanObservation{ObservationForLLM: &PlanObservationPayload{
 			PlanObservation: []StepObservation{stepResult},
 		}}
+		r.plan.recordTiming(step.ID, result.started, result.finished)
 		if updateErr := r.plan.UpdateStatus(step.ID, status, planObservation); updateErr != nil {
 			updateErr = fmt.Errorf("execution: failed to update plan status for step %q: %w", step.ID, updateErr)
 			r.options.Logger.Error(ctx, "Failed to update plan status", updateErr,
@@ -271,6 +504,8 @@ func (r *Runtime) executePendingCommands(ctx context.Context, toolCall ToolCall)
 			haltScheduling = true
 		}
 
+		r.writeJournal(toolCall, running)
+
 		lastObservation = observation
 		haveObservation = true
 		orderedResults = append(orderedResults, stepResult)
@@ -282,6 +517,10 @@ func (r *Runtime) executePendingCommands(ctx context.Context, toolCall ToolCall)
 			"stdout":    observation.Stdout,
 			"stderr":    observation.Stderr,
 			"truncated": observation.Truncated,
+			// Timings let hosts show how long each step ran.
+			"started_at":  result.started,
+			"finished_at": result.finished,
+			"duration_ms": duration.Milliseconds(),
 		}
 		if observation.ExitCode != nil {
 			metadata["exit_code"] = *observation.ExitCode
@@ -289,6 +528,9 @@ func (r *Runtime) executePendingCommands(ctx context.Context, toolCall ToolCall)
 		if observation.Details != "" {
 			metadata["details"] = observation.Details
 		}
+		if observation.Data != nil {
+			metadata["data"] = observation.Data
+		}
 
 		r.emit(RuntimeEvent{
 			Type:     EventTypeStatus,
@@ -296,6 +538,42 @@ func (r *Runtime) executePendingCommands(ctx context.Context, toolCall ToolCall)
 			Level:    level,
 			Metadata: metadata,
 		})
+
+		for _, change := range observation.FileChanges {
+			metadata := map[string]any{
+				"step_id":   step.ID,
+				"path":      change.Path,
+				"status":    change.Status,
+				"hunks":     change.Hunks,
+				"bytes":     change.Bytes,
+				"additions": change.Additions,
+				"deletions": change.Deletions,
+			}
+			if change.Diff != "" {
+				metadata["diff"] = change.Diff
+			}
+			r.emit(RuntimeEvent{
+				Type:     EventTypeFileChange,
+				Message:  fmt.Sprintf("%s %s", change.Status, change.Path),
+				Level:    StatusLevelInfo,
+				Metadata: metadata,
+			})
+		}
+	}
+
+	if cancelled {
+		// Steps that never started are reported so the model knows the
+		// plan did not run to completion.
+		for _, step := range r.plan.Snapshot() {
+			if _, ok := finished[step.ID]; ok || step.Status != PlanPending {
+				continue
+			}
+			orderedResults = append(orderedResults, StepObservation{
+				ID:      step.ID,
+				Status:  PlanCancelled,
+				Details: "cancelled by user before the step started",
+			})
+		}
 	}
 
 	payload := PlanObservationPayload{PlanObservation: orderedResults}
@@ -307,6 +585,14 @@ func (r *Runtime) executePendingCommands(ctx context.Context, toolCall ToolCall)
 		payload.Details = lastObservation.Details
 	}
 
+	if cancelled {
+		payload.Summary = "Plan cancelled by user."
+		if cancelReason != "" {
+			payload.Summary = fmt.Sprintf("Plan cancelled by user: %s", cancelReason)
+		}
+		payload.CanceledByHuman = true
+	}
+
 	if payload.Summary == "" {
 		switch {
 		case executedSteps == 0 && finalErr != nil:
@@ -321,6 +607,8 @@ func (r *Runtime) executePendingCommands(ctx context.Context, toolCall ToolCall)
 	}
 
 	r.appendToolObservation(toolCall, payload)
+	r.clearJournal()
+	return cancelled
 }
 
 func (r *Runtime) appendToolObservation(toolCall ToolCall, payload PlanObservationPayload) {
@@ -0,0 +1,227 @@
+package runtime
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExecutionBackend builds the process used to run a shell plan step. The
+// executor wires up stdout/stderr, timeouts, and output handling; backends
+// only decide where and how the command runs. Internal commands never go
+// through a backend.
+type ExecutionBackend interface {
+	Command(ctx context.Context, step PlanStep) (*exec.Cmd, error)
+}
+
+// CommandRunner replaces the executor for shell plan steps: it returns the
+// observation without starting a process, e.g. to replay canned output in
+// tests. Internal commands still run in-process.
+type CommandRunner interface {
+	RunCommand(ctx context.Context, step PlanStep) (PlanObservationPayload, error)
+}
+
+// HostBackend runs commands directly on the host. It is the default backend.
+type HostBackend struct{}
+
+// Command implements ExecutionBackend.
+func (HostBackend) Command(ctx context.Context, step PlanStep) (*exec.Cmd, error) {
+	cmd, err := buildShellCommand(ctx, step.Command.Shell, step.Command.Run)
+	if err != nil {
+		return nil, err
+	}
+	if step.Command.Cwd != "" {
+		cmd.Dir = step.Command.Cwd
+	}
+	return cmd, nil
+}
+
+// ContainerBackend runs each step in a throwaway Docker or Podman container
+// with the workspace bind-mounted, so plans cannot touch the rest of the host.
+type ContainerBackend struct {
+	// Runtime is the container CLI to invoke, e.g. "docker" or "podman".
+	Runtime string
+	// Image is the container image used for every step.
+	Image string
+	// Workspace is the host directory mounted into the container. Step
+	// working directories must live inside it. Defaults to the process
+	// working directory.
+	Workspace string
+	// MountPath is where Workspace appears inside the container. Defaults to
+	// "/workspace".
+	MountPath string
+	// Network is passed to --network. Defaults to "none" for isolation; set
+	// to "bridge" or "host" to allow network access.
+	Network string
+	// CPUs and Memory map to --cpus and --memory when non-empty.
+	CPUs   string
+	Memory string
+	// ExtraArgs are appended to the run invocation before the image name.
+	ExtraArgs []string
+}
+
+// Command implements ExecutionBackend.
+func (b *ContainerBackend) Command(ctx context.Context, step PlanStep) (*exec.Cmd, error) {
+	runtimeBinary := strings.TrimSpace(b.Runtime)
+	if runtimeBinary == "" {
+		return nil, errors.New("container backend: runtime is required")
+	}
+	image := strings.TrimSpace(b.Image)
+	if image == "" {
+		return nil, errors.New("container backend: image is required")
+	}
+
+	workspace := strings.TrimSpace(b.Workspace)
+	if workspace == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("container backend: failed to determine working directory: %w", err)
+		}
+		workspace = wd
+	}
+	workspace, err := filepath.Abs(workspace)
+	if err != nil {
+		return nil, fmt.Errorf("container backend: %w", err)
+	}
+	mountPath := strings.TrimSpace(b.MountPath)
+	if mountPath == "" {
+		mountPath = "/workspace"
+	}
+
+	workdir := mountPath
+	if cwd := strings.TrimSpace(step.Command.Cwd); cwd != "" {
+		if !filepath.IsAbs(cwd) {
+			cwd = filepath.Join(workspace, cwd)
+		}
+		rel, err := filepath.Rel(workspace, cwd)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("container backend: cwd %s is outside the mounted workspace %s", step.Command.Cwd, workspace)
+		}
+		workdir = path.Join(mountPath, filepath.ToSlash(rel))
+	}
+
+	shellParts := strings.Fields(step.Command.Shell)
+	if len(shellParts) == 0 {
+		return nil, fmt.Errorf("invalid shell: %q", step.Command.Shell)
+	}
+	if len(shellParts) == 1 {
+		shellParts = append(shellParts, "-lc")
+	}
+
+	network := strings.TrimSpace(b.Network)
+	if network == "" {
+		network = "none"
+	}
+
+	// The name lets cancellation remove the container: killing the CLI
+	// alone leaves it running. --init reaps and forwards signals.
+	name := newContainerName()
+	args := []string{
+		"run", "--rm", "-i", "--init",
+		"--name", name,
+		"--network", network,
+		"-v", workspace + ":" + mountPath,
+		"-w", workdir,
+	}
+	if cpus := strings.TrimSpace(b.CPUs); cpus != "" {
+		args = append(args, "--cpus", cpus)
+	}
+	if memory := strings.TrimSpace(b.Memory); memory != "" {
+		args = append(args, "--memory", memory)
+	}
+	// The container does not see the host environment, so only the step's
+	// own variables are forwarded.
+	envNames := make([]string, 0, len(step.Command.Env))
+	for name := range step.Command.Env {
+		envNames = append(envNames, name)
+	}
+	sort.Strings(envNames)
+	for _, name := range envNames {
+		args = append(args, "-e", name+"="+step.Command.Env[name])
+	}
+	args = append(args, b.ExtraArgs...)
+	args = append(args, image)
+	args = append(args, shellParts...)
+	args = append(args, step.Command.Run)
+	cmd := exec.CommandContext(ctx, runtimeBinary, args...)
+	cmd.Cancel = func() error {
+		removeContainer(runtimeBinary, name)
+		return cmd.Process.Kill()
+	}
+	return cmd, nil
+}
+
+// containerRemoveTimeout bounds the cleanup after a cancelled container step.
+const containerRemoveTimeout = 30 * time.Second
+
+// newContainerName returns a unique name for a step's container.
+func newContainerName() string {
+	suffix := make([]byte, 6)
+	_, _ = rand.Read(suffix)
+	return "goagent-" + hex.EncodeToString(suffix)
+}
+
+// removeContainer force-removes a container, stopping it first. Errors are
+// ignored: the container may already be gone.
+func removeContainer(runtimeBinary, name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), containerRemoveTimeout)
+	defer cancel()
+	_ = exec.CommandContext(ctx, runtimeBinary, "rm", "-f", name).Run()
+}
+
+// killOnCancel makes cancelling cmd kill everything it spawned, not just the
+// direct child, and then run the cleanup the backend installed as
+// cmd.Cancel, such as removing a container.
+func killOnCancel(cmd *exec.Cmd) {
+	cleanup := cmd.Cancel
+	cmd.Cancel = func() error {
+		err := killProcessGroup(cmd)
+		if cleanup != nil {
+			_ = cleanup()
+		}
+		return err
+	}
+}
+
+// argvCommand builds a command that runs args in step's working directory
+// with the same isolation as a shell step: the configured backend, the
+// environment policy, and a process group that cancellation kills. Host
+// execution skips the shell so arguments need no quoting; other backends
+// receive args as a POSIX sh command line.
+func (e *CommandExecutor) argvCommand(ctx context.Context, step PlanStep, args []string) (*exec.Cmd, error) {
+	if len(args) == 0 {
+		return nil, errors.New("command: no arguments")
+	}
+	var cmd *exec.Cmd
+	switch backend := e.backend.(type) {
+	case nil, HostBackend, *HostBackend:
+		cmd = exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Dir = step.Command.Cwd
+	default:
+		quoted := make([]string, len(args))
+		for i, arg := range args {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		step.Command.Shell = "sh"
+		step.Command.Run = strings.Join(quoted, " ")
+		var err error
+		if cmd, err = backend.Command(ctx, step); err != nil {
+			return nil, err
+		}
+	}
+	if env := e.commandEnv(step); env != nil {
+		cmd.Env = env
+	}
+	setProcessGroup(cmd)
+	killOnCancel(cmd)
+	return cmd, nil
+}
@@ -0,0 +1,114 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContainerBackendBuildsRunInvocation(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	backend := &ContainerBackend{Runtime: "podman", Image: "golang:1.25", Workspace: workspace, Memory: "1g"}
+	step := PlanStep{ID: "s1", Command: CommandDraft{Shell: "bash", Run: "go test ./...", Cwd: filepath.Join(workspace, "pkg")}}
+
+	cmd, err := backend.Command(context.Background(), step)
+	if err != nil {
+		t.Fatalf("Command returned error: %v", err)
+	}
+	got := containerInvocation(t, cmd)
+	want := "podman run --rm -i --init --name NAME --network none -v " + workspace + ":/workspace -w /workspace/pkg --memory 1g golang:1.25 bash -lc go test ./..."
+	if got != want {
+		t.Fatalf("unexpected invocation:\n got: %s\nwant: %s", got, want)
+	}
+}
+
+// containerInvocation joins cmd's arguments with the generated container
+// name replaced by NAME.
+func containerInvocation(t *testing.T, cmd *exec.Cmd) string {
+	t.Helper()
+	args := slices.Clone(cmd.Args)
+	index := slices.Index(args, "--name")
+	if index == -1 || index+1 >= len(args) || !strings.HasPrefix(args[index+1], "goagent-") {
+		t.Fatalf("expected a generated container name in %v", args)
+	}
+	args[index+1] = "NAME"
+	return strings.Join(args, " ")
+}
+
+func TestContainerBackendRemovesContainerOnCancel(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "calls.log")
+	// A stand-in container CLI: "run" blocks like a long step, every call is
+	// logged.
+	fake := filepath.Join(dir, "fake-docker")
+	script := "#!/bin/sh\necho \"$@\" >> " + logPath + "\nif [ \"$1\" = run ]; then exec sleep 30; fi\n"
+	if err := os.WriteFile(fake, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	executor := NewCommandExecutor(nil, nil)
+	executor.SetExecutionBackend(&ContainerBackend{Runtime: fake, Image: "alpine", Workspace: dir})
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := executor.Execute(ctx, PlanStep{ID: "slow", Command: CommandDraft{Shell: "sh", Run: "sleep 30", Cwd: dir}}); err == nil {
+		t.Fatal("expected the cancelled step to fail")
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "rm -f goagent-") {
+		t.Fatalf("expected the container to be removed after cancel, got calls:\n%s", data)
+	}
+	name := strings.TrimPrefix(lines[1], "rm -f ")
+	if !strings.Contains(lines[0], "--name "+name) {
+		t.Fatalf("expected the removed container to be the one started, got calls:\n%s", data)
+	}
+}
+
+func TestContainerBackendRejectsCwdOutsideWorkspace(t *testing.T) {
+	t.Parallel()
+
+	backend := &ContainerBackend{Runtime: "docker", Image: "alpine", Workspace: t.TempDir()}
+	step := PlanStep{ID: "s1", Command: CommandDraft{Shell: "sh", Run: "ls", Cwd: "/etc"}}
+	if _, err := backend.Command(context.Background(), step); err == nil {
+		t.Fatalf("expected error for cwd outside the workspace")
+	}
+}
+
+func TestArgvCommandRunsThroughBackendWithEnvPolicy(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "secret")
+
+	workspace := t.TempDir()
+	executor := NewCommandExecutor(nil, nil)
+	executor.SetExecutionBackend(&ContainerBackend{Runtime: "docker", Image: "golang:1.25", Workspace: workspace})
+	executor.SetEnvironment(EnvClean, nil)
+	step := PlanStep{ID: "tests", Command: CommandDraft{Shell: agentShell, Run: "run_tests", Cwd: workspace, Env: map[string]string{"GOFLAGS": "-count=1"}}}
+
+	cmd, err := executor.argvCommand(context.Background(), step, []string{"go", "test", "-run", "It's"})
+	if err != nil {
+		t.Fatalf("argvCommand returned error: %v", err)
+	}
+	got := containerInvocation(t, cmd)
+	want := "docker run --rm -i --init --name NAME --network none -v " + workspace + ":/workspace -w /workspace -e GOFLAGS=-count=1 golang:1.25 sh -lc 'go' 'test' '-run' 'It'\\''s'"
+	if got != want {
+		t.Fatalf("unexpected invocation:\n got: %s\nwant: %s", got, want)
+	}
+	if strings.Join(cmd.Env, " ") != "GOFLAGS=-count=1" {
+		t.Fatalf("expected only the step's env, got %v", cmd.Env)
+	}
+	if cmd.Cancel == nil {
+		t.Fatal("expected process-group cancellation")
+	}
+}
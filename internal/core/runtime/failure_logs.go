@@ -0,0 +1,217 @@
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	listFailuresCommandName = "list_failures"
+
+	defaultListFailuresLimit = 5
+	// failureTailLines caps the output lines list_failures returns per log.
+	failureTailLines = 15
+)
+
+// FailureLogRetention bounds the failure-*.txt reports kept under .goagent.
+// Zero fields are unlimited. Older reports are removed first.
+type FailureLogRetention struct {
+	MaxCount      int
+	MaxAge        time.Duration
+	MaxTotalBytes int64
+}
+
+// DefaultFailureLogRetention keeps the 50 newest reports from the last week,
+// up to 20MB in total.
+func DefaultFailureLogRetention() *FailureLogRetention {
+	return &FailureLogRetention{
+		MaxCount:      50,
+		MaxAge:        7 * 24 * time.Hour,
+		MaxTotalBytes: 20 << 20,
+	}
+}
+
+// FailureSummary is one entry in the result of list_failures.
+type FailureSummary struct {
+	File   string `json:"file"`
+	Time   string `json:"time"`
+	StepID string `json:"step_id,omitempty"`
+	Run    string `json:"run"`
+	Error  string `json:"error,omitempty"`
+	// StdoutTail and StderrTail hold the last lines of the raw output.
+	StdoutTail string `json:"stdout_tail,omitempty"`
+	StderrTail string `json:"stderr_tail,omitempty"`
+}
+
+// FailureList is the structured result of list_failures.
+type FailureList struct {
+	Dir      string           `json:"dir"`
+	Total    int              `json:"total"`
+	Failures []FailureSummary `json:"failures"`
+}
+
+// SetFailureLogs enables or disables the failure reports written when a
+// shell step fails and sets how many of them are kept.
+func (e *CommandExecutor) SetFailureLogs(enabled bool, retention FailureLogRetention) {
+	e.failureLogsDisabled = !enabled
+	e.failureRetention = retention
+}
+
+// failureLogDir returns the .goagent directory failure reports for step are
+// written to: the step's cwd when set, otherwise the process working
+// directory.
+func failureLogDir(cwd string) string {
+	baseDir := strings.TrimSpace(cwd)
+	if baseDir == "" {
+		if wd, err := os.Getwd(); err == nil {
+			baseDir = wd
+		} else {
+			baseDir = "."
+		}
+	}
+	return filepath.Join(baseDir, ".goagent")
+}
+
+// failureLogs lists the failure reports in dir, newest first.
+func failureLogs(dir string) ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var logs []os.FileInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "failure-") || !strings.HasSuffix(name, ".txt") {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			logs = append(logs, info)
+		}
+	}
+	sort.Slice(logs, func(i, j int) bool {
+		if !logs[i].ModTime().Equal(logs[j].ModTime()) {
+			return logs[i].ModTime().After(logs[j].ModTime())
+		}
+		return logs[i].Name() > logs[j].Name()
+	})
+	return logs, nil
+}
+
+// pruneFailureLogs removes the reports in dir that fall outside retention
+// and returns how many were removed.
+func pruneFailureLogs(dir string, retention FailureLogRetention) (int, error) {
+	logs, err := failureLogs(dir)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	var total int64
+	var errs []error
+	for i, info := range logs {
+		total += info.Size()
+		expired := (retention.MaxCount > 0 && i >= retention.MaxCount) ||
+			(retention.MaxAge > 0 && time.Since(info.ModTime()) > retention.MaxAge) ||
+			(retention.MaxTotalBytes > 0 && total > retention.MaxTotalBytes)
+		if !expired {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, info.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
+	}
+	return removed, errors.Join(errs...)
+}
+
+// newListFailuresCommand handles "list_failures [limit=N]": the newest
+// failure reports for the step's cwd with their command, error, and the
+// tail of their output.
+func newListFailuresCommand() InternalCommandHandler {
+	return func(_ context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		limit := argInt(req, "limit", defaultListFailuresLimit)
+		if limit <= 0 {
+			limit = defaultListFailuresLimit
+		}
+		dir := failureLogDir(req.Step.Command.Cwd)
+		logs, err := failureLogs(dir)
+		if err != nil {
+			return failFileCommand(err)
+		}
+		result := FailureList{Dir: dir, Total: len(logs), Failures: []FailureSummary{}}
+		for _, info := range logs[:min(limit, len(logs))] {
+			summary, err := readFailureSummary(filepath.Join(dir, info.Name()))
+			if err != nil {
+				continue
+			}
+			if summary.Time == "" {
+				summary.Time = info.ModTime().Format(time.RFC3339)
+			}
+			result.Failures = append(result.Failures, summary)
+		}
+		return structuredObservation(result)
+	}
+}
+
+// readFailureSummary parses a report written by writeFailureLog.
+func readFailureSummary(path string) (FailureSummary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return FailureSummary{}, err
+	}
+	defer f.Close()
+
+	summary := FailureSummary{File: filepath.Base(path)}
+	var section string
+	var stdout, stderr []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "===== STDOUT (raw) =====":
+			section = "stdout"
+		case line == "===== STDERR (raw) =====":
+			section = "stderr"
+		case section == "stdout":
+			stdout = appendTail(stdout, line)
+		case section == "stderr":
+			stderr = appendTail(stderr, line)
+		default:
+			key, value, ok := strings.Cut(line, ": ")
+			if !ok {
+				continue
+			}
+			switch key {
+			case "Timestamp":
+				summary.Time = value
+			case "StepID":
+				summary.StepID = value
+			case "Run":
+				summary.Run = value
+			case "Error":
+				summary.Error = value
+			}
+		}
+	}
+	summary.StdoutTail = strings.Join(stdout, "\n")
+	summary.StderrTail = strings.Join(stderr, "\n")
+	return summary, scanner.Err()
+}
+
+func appendTail(lines []string, line string) []string {
+	lines = append(lines, line)
+	if len(lines) > failureTailLines {
+		lines = lines[1:]
+	}
+	return lines
+}
@@ -2,9 +2,7 @@ package runtime
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"os"
 	"strings"
 )
 
@@ -13,10 +11,19 @@ func (r *Runtime) appendHistory(message ChatMessage) {
 	message.Pass = pass
 
 	r.historyMu.Lock()
-	defer r.historyMu.Unlock()
-
 	r.history = append(r.history, message)
 	r.applyHistoryAmnesiaLocked(pass)
+	r.historyMu.Unlock()
+
+	if store := r.historyStore(); store != nil {
+		if err := store.Append(context.Background(), []ChatMessage{message}); err != nil {
+			r.emit(RuntimeEvent{
+				Type:    EventTypeStatus,
+				Message: fmt.Sprintf("Failed to append to history store: %v", err),
+				Level:   StatusLevelWarn,
+			})
+		}
+	}
 }
 
 func (r *Runtime) historySnapshot() []ChatMessage {
@@ -35,7 +42,8 @@ func (r *Runtime) planningHistorySnapshot() []ChatMessage {
 
 	limit := r.contextBudget.triggerTokens()
 	if limit > 0 {
-		total, per := estimateHistoryTokenUsage(r.history)
+		counter := r.tokenCounter()
+		total, per := estimateHistoryTokenUsage(counter, r.history)
 		if total > limit {
 			beforeLen := len(r.history)
 			// Add safeguard: limit iterations to prevent infinite loops
@@ -44,7 +52,7 @@ func (r *Runtime) planningHistorySnapshot() []ChatMessage {
 			iterations := 0
 			for total > limit && iterations < maxCompactionIterations {
 				var changed bool
-				total, per, changed = compactHistory(r.history, per, total, limit)
+				total, per, changed = compactHistory(counter, r.history, per, total, limit)
 				iterations++
 				if !changed {
 					// No progress made - all eligible messages already summarized
@@ -72,29 +80,31 @@ func (r *Runtime) planningHistorySnapshot() []ChatMessage {
 
 func (r *Runtime) writeHistoryLog(history []ChatMessage) {
 	// Persist the exact payload forwarded to the model so hosts can inspect it.
-	data, err := json.MarshalIndent(history, "", "  ")
-	if err != nil {
+	store := r.historyStore()
+	if store == nil {
+		return
+	}
+	if err := store.Snapshot(context.Background(), history); err != nil {
 		r.emit(RuntimeEvent{
 			Type:    EventTypeStatus,
-			Message: fmt.Sprintf("Failed to encode history log: %v", err),
+			Message: fmt.Sprintf("Failed to write history log: %v", err),
 			Level:   StatusLevelWarn,
 		})
-		return
 	}
+}
 
-	var historyPath string
-	if r.options.HistoryLogPath != nil {
-		historyPath = strings.TrimSpace(*r.options.HistoryLogPath)
+// historyStore returns the configured store, falling back to a JSON file at
+// HistoryLogPath. It returns nil when history persistence is disabled.
+func (r *Runtime) historyStore() HistoryStore {
+	if r.options.HistoryStore != nil {
+		return r.options.HistoryStore
 	}
-	if historyPath == "" {
-		return
+	if r.options.HistoryLogPath == nil {
+		return nil
 	}
-
-	if err := os.WriteFile(historyPath, data, 0o644); err != nil {
-		r.emit(RuntimeEvent{
-			Type:    EventTypeStatus,
-			Message: fmt.Sprintf("Failed to write history log: %v", err),
-			Level:   StatusLevelWarn,
-		})
+	path := strings.TrimSpace(*r.options.HistoryLogPath)
+	if path == "" {
+		return nil
 	}
+	return &JSONFileHistoryStore{Path: path, SessionID: r.options.SessionID}
 }
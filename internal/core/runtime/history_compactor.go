@@ -3,9 +3,7 @@ package runtime
 import (
 	"encoding/json"
 	"fmt"
-	"math"
 	"strings"
-	"unicode/utf8"
 )
 
 const (
@@ -14,58 +12,57 @@ const (
 )
 
 // estimateHistoryTokenUsage walks the history and returns the total estimated
-// token usage together with the per-message contribution. The heuristic is
-// intentionally simple (roughly four characters per token) which keeps the
-// estimator fast while still providing a useful signal for trimming.
-func estimateHistoryTokenUsage(history []ChatMessage) (int, []int) {
+// token usage together with the per-message contribution. A nil counter uses
+// the character heuristic (roughly four characters per token).
+func estimateHistoryTokenUsage(counter TokenCounter, history []ChatMessage) (int, []int) {
+	if counter == nil {
+		counter = HeuristicTokenCounter{}
+	}
 	totals := make([]int, len(history))
 	var sum int
 	for i := range history {
-		tokens := estimateMessageTokens(history[i])
+		tokens := estimateMessageTokens(counter, history[i])
 		totals[i] = tokens
 		sum += tokens
 	}
 	return sum, totals
 }
 
-// estimateMessageTokens approximates the token usage of an individual message
-// using a character based heuristic. We include a small base overhead so that
-// very short messages still contribute to the budget.
-func estimateMessageTokens(message ChatMessage) int {
+// estimateMessageTokens counts the tokens of an individual message. We
+// include a small base overhead so that very short messages still contribute
+// to the budget.
+func estimateMessageTokens(counter TokenCounter, message ChatMessage) int {
 	const baseOverhead = 4
 	total := baseOverhead
 
-	total += estimateStringTokens(string(message.Role))
-	total += estimateStringTokens(message.Content)
-	total += estimateStringTokens(message.ToolCallID)
-	total += estimateStringTokens(message.Name)
+	total += counter.CountTokens(string(message.Role))
+	total += counter.CountTokens(message.Content)
+	total += counter.CountTokens(message.ToolCallID)
+	total += counter.CountTokens(message.Name)
+
+	for _, attachment := range message.Attachments {
+		total += baseOverhead
+		if attachment.IsImage() {
+			total += imageAttachmentTokens
+		} else {
+			total += counter.CountTokens(attachment.Data)
+		}
+	}
 
 	for _, call := range message.ToolCalls {
 		total += baseOverhead
-		total += estimateStringTokens(call.ID)
-		total += estimateStringTokens(call.Name)
-		total += estimateStringTokens(call.Arguments)
+		total += counter.CountTokens(call.ID)
+		total += counter.CountTokens(call.Name)
+		total += counter.CountTokens(call.Arguments)
 	}
 
 	return total
 }
 
-func estimateStringTokens(value string) int {
-	if value == "" {
-		return 0
-	}
-	runes := utf8.RuneCountInString(value)
-	tokens := int(math.Ceil(float64(runes) / 4))
-	if tokens < 1 {
-		tokens = 1
-	}
-	return tokens
-}
-
 // compactHistory replaces the oldest non-system messages with summaries until
 // the history drops below the provided limit or no further compaction is
 // possible. The slice is modified in place, preserving ordering.
-func compactHistory(history []ChatMessage, per []int, total, limit int) (int, []int, bool) {
+func compactHistory(counter TokenCounter, history []ChatMessage, per []int, total, limit int) (int, []int, bool) {
 	if limit <= 0 {
 		return total, per, false
 	}
@@ -80,7 +77,7 @@ func compactHistory(history []ChatMessage, per []int, total, limit int) (int, []
 		}
 
 		summary := synthesizeSummary(message)
-		summaryTokens := estimateMessageTokens(summary)
+		summaryTokens := estimateMessageTokens(counter, summary)
 
 		if i < len(per) {
 			total -= per[i]
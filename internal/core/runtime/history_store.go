@@ -0,0 +1,190 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HistoryStore persists the conversation. Append receives every message as
+// it is added to the history, and Snapshot receives the exact history sent
+// with each plan request, after compaction and amnesia. Archive receives the
+// original messages that a summarizing compaction replaced. Load returns the
+// stored conversation so a runtime can resume from it.
+type HistoryStore interface {
+	Append(ctx context.Context, messages []ChatMessage) error
+	Snapshot(ctx context.Context, history []ChatMessage) error
+	Archive(ctx context.Context, messages []ChatMessage) error
+	Load(ctx context.Context) ([]ChatMessage, error)
+}
+
+// historyLogVersion is the schema version of the file JSONFileHistoryStore
+// writes. Files from before versioning hold a bare message array and load as
+// version 0.
+const historyLogVersion = 1
+
+// historyLog is the file JSONFileHistoryStore writes.
+type historyLog struct {
+	Version   int           `json:"version"`
+	SessionID string        `json:"sessionId,omitempty"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+	History   []ChatMessage `json:"history"`
+}
+
+// JSONFileHistoryStore writes each snapshot to a versioned JSON file,
+// replacing the previous one. Archived messages are appended as JSON lines
+// to a sibling file with an ".archive.jsonl" suffix. It is what
+// RuntimeOptions.HistoryLogPath configures.
+type JSONFileHistoryStore struct {
+	Path string
+	// SessionID is recorded in the file when set.
+	SessionID string
+}
+
+// Append is a no-op; the file only mirrors the latest snapshot.
+func (s *JSONFileHistoryStore) Append(context.Context, []ChatMessage) error {
+	return nil
+}
+
+// Snapshot implements HistoryStore. It creates the file's directory.
+func (s *JSONFileHistoryStore) Snapshot(_ context.Context, history []ChatMessage) error {
+	log := historyLog{Version: historyLogVersion, SessionID: s.SessionID, UpdatedAt: time.Now(), History: history}
+	if err := writeJSONAtomic(s.Path, log); err != nil {
+		return fmt.Errorf("write history: %w", err)
+	}
+	return nil
+}
+
+// Archive implements HistoryStore.
+func (s *JSONFileHistoryStore) Archive(_ context.Context, messages []ChatMessage) error {
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return fmt.Errorf("open history archive: %w", err)
+	}
+	file, err := os.OpenFile(s.ArchivePath(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open history archive: %w", err)
+	}
+	encoder := json.NewEncoder(file)
+	for _, message := range messages {
+		if err := encoder.Encode(message); err != nil {
+			_ = file.Close()
+			return fmt.Errorf("write history archive: %w", err)
+		}
+	}
+	return file.Close()
+}
+
+// ArchivePath returns the file that Archive appends to.
+func (s *JSONFileHistoryStore) ArchivePath() string {
+	return strings.TrimSuffix(s.Path, filepath.Ext(s.Path)) + ".archive.jsonl"
+}
+
+// Load returns the last snapshot, or nothing when the file does not exist.
+func (s *JSONFileHistoryStore) Load(context.Context) ([]ChatMessage, error) {
+	log, err := readHistoryLog(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return log.History, nil
+}
+
+// readHistoryLog reads a history file of any version up to
+// historyLogVersion.
+func readHistoryLog(path string) (historyLog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return historyLog{}, err
+	}
+	var log historyLog
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(data, &log.History)
+	} else {
+		err = json.Unmarshal(data, &log)
+	}
+	if err != nil {
+		return historyLog{}, fmt.Errorf("decode history %s: %w", path, err)
+	}
+	if log.Version > historyLogVersion {
+		return historyLog{}, fmt.Errorf("decode history %s: unsupported version %d (this build reads up to %d)", path, log.Version, historyLogVersion)
+	}
+	return log, nil
+}
+
+// MemoryHistoryStore keeps the history in memory. Messages returns every
+// appended message, including ones later dropped by compaction.
+type MemoryHistoryStore struct {
+	mu       sync.Mutex
+	messages []ChatMessage
+	snapshot []ChatMessage
+	archived []ChatMessage
+	// sinceSnapshot is the index in messages of the first message appended
+	// after the last snapshot.
+	sinceSnapshot int
+}
+
+// NewMemoryHistoryStore returns an empty in-memory store.
+func NewMemoryHistoryStore() *MemoryHistoryStore {
+	return &MemoryHistoryStore{}
+}
+
+// Append implements HistoryStore.
+func (s *MemoryHistoryStore) Append(_ context.Context, messages []ChatMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, messages...)
+	return nil
+}
+
+// Snapshot implements HistoryStore.
+func (s *MemoryHistoryStore) Snapshot(_ context.Context, history []ChatMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshot = append([]ChatMessage(nil), history...)
+	s.sinceSnapshot = len(s.messages)
+	return nil
+}
+
+// Archive implements HistoryStore.
+func (s *MemoryHistoryStore) Archive(_ context.Context, messages []ChatMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.archived = append(s.archived, messages...)
+	return nil
+}
+
+// Load returns the last snapshot followed by the messages appended after it.
+// Without a snapshot it returns every appended message.
+func (s *MemoryHistoryStore) Load(context.Context) ([]ChatMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.snapshot == nil {
+		return append([]ChatMessage(nil), s.messages...), nil
+	}
+	history := append([]ChatMessage(nil), s.snapshot...)
+	return append(history, s.messages[s.sinceSnapshot:]...), nil
+}
+
+// Messages returns every message appended so far.
+func (s *MemoryHistoryStore) Messages() []ChatMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ChatMessage(nil), s.messages...)
+}
+
+// Archived returns the messages replaced by summarizing compactions.
+func (s *MemoryHistoryStore) Archived() []ChatMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ChatMessage(nil), s.archived...)
+}
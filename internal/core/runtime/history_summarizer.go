@@ -0,0 +1,194 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/asynkron/goagent/internal/core/schema"
+)
+
+const (
+	// defaultCompactionBatchSize is how many of the oldest messages a
+	// summarizing compaction folds into one summary.
+	defaultCompactionBatchSize = 20
+	// summaryKeepRecent messages at the end of the history are never
+	// summarized so the model keeps the current exchange verbatim.
+	summaryKeepRecent = 4
+	// summaryMessageRunes caps each message in the transcript sent to the
+	// summarizer.
+	summaryMessageRunes = 4000
+)
+
+const summarizerPrompt = `You compress the early part of a conversation between a user and a coding agent that runs shell commands.
+Write a dense summary of the transcript you are given. Keep the user's goals and constraints, decisions made, files and commands that matter, results of commands, and open problems. Drop chatter and repeated output.
+Respond with the plan tool: put the summary in "message", use an empty "plan", an empty "reasoning" list, and set "requireHumanInput" to false.`
+
+// summarizeHistory replaces the oldest messages with a model written summary
+// when the history exceeds the context budget and RuntimeOptions.SummarizeCompaction
+// is enabled. The replaced messages are archived to the history store. On
+// failure the history is left alone and the heuristic compactor in
+// planningHistorySnapshot takes over.
+func (r *Runtime) summarizeHistory(ctx context.Context) {
+	if !r.options.SummarizeCompaction {
+		return
+	}
+	limit := r.contextBudget.triggerTokens()
+	if limit <= 0 {
+		return
+	}
+
+	r.historyMu.RLock()
+	total, _ := estimateHistoryTokenUsage(r.tokenCounter(), r.history)
+	start, end := summaryBatch(r.history, r.options.CompactionBatchSize)
+	batch := append([]ChatMessage(nil), r.history[start:end]...)
+	r.historyMu.RUnlock()
+	if total <= limit || len(batch) < 2 {
+		return
+	}
+
+	content, err := r.requestSummary(ctx, batch)
+	if err != nil {
+		r.options.Logger.Warn(ctx, "Summarizing compaction failed", Field("error", err.Error()))
+		r.emit(RuntimeEvent{
+			Type:    EventTypeStatus,
+			Message: fmt.Sprintf("Failed to summarize history, falling back to heuristic compaction: %v", err),
+			Level:   StatusLevelWarn,
+		})
+		return
+	}
+
+	var originals []ChatMessage
+	for _, message := range batch {
+		// Earlier summaries were archived together with their originals.
+		if !message.Summarized {
+			originals = append(originals, message)
+		}
+	}
+	if store := r.historyStore(); store != nil && len(originals) > 0 {
+		if err := store.Archive(ctx, originals); err != nil {
+			r.emit(RuntimeEvent{
+				Type:    EventTypeStatus,
+				Message: fmt.Sprintf("Failed to archive summarized history: %v", err),
+				Level:   StatusLevelWarn,
+			})
+		}
+	}
+
+	last := batch[len(batch)-1]
+	summary := ChatMessage{
+		Role:       RoleSystem,
+		Content:    fmt.Sprintf("%s Earlier conversation: %s", summaryPrefix, content),
+		Timestamp:  last.Timestamp,
+		Pass:       last.Pass,
+		Summarized: true,
+	}
+
+	r.historyMu.Lock()
+	if len(r.history) < end {
+		r.historyMu.Unlock()
+		return
+	}
+	updated := make([]ChatMessage, 0, len(r.history)-len(batch)+1)
+	updated = append(updated, r.history[:start]...)
+	updated = append(updated, summary)
+	updated = append(updated, r.history[end:]...)
+	r.history = updated
+	remaining := len(r.history)
+	r.historyMu.Unlock()
+
+	r.options.Metrics.RecordContextCompaction(len(batch)-1, remaining)
+	r.emit(RuntimeEvent{
+		Type:     EventTypeStatus,
+		Message:  fmt.Sprintf("Summarized %d earlier messages to stay within the context budget.", len(batch)),
+		Level:    StatusLevelInfo,
+		Metadata: map[string]any{"summarized": len(batch), "remaining": remaining},
+	})
+}
+
+// summaryBatch selects the oldest messages to summarize: everything after the
+// leading system prompt, including earlier summaries, up to size messages.
+// The most recent messages are kept, and the batch never ends between a tool
+// call and its result.
+func summaryBatch(history []ChatMessage, size int) (int, int) {
+	if size <= 0 {
+		size = defaultCompactionBatchSize
+	}
+	start := 0
+	for start < len(history) && history[start].Role == RoleSystem && !history[start].Summarized {
+		start++
+	}
+	end := start + size
+	if limit := len(history) - summaryKeepRecent; end > limit {
+		end = limit
+	}
+	for end > start && end < len(history) && history[end].Role == RoleTool {
+		end--
+	}
+	if end < start {
+		end = start
+	}
+	return start, end
+}
+
+// requestSummary asks the compaction model to summarize batch through the
+// plan tool and returns the plan message.
+func (r *Runtime) requestSummary(ctx context.Context, batch []ChatMessage) (string, error) {
+	client, summary := r.provider()
+	if summary != nil {
+		client = summary
+	}
+	if client == nil {
+		return "", errors.New("no provider configured")
+	}
+
+	request := []ChatMessage{
+		{Role: RoleSystem, Content: summarizerPrompt},
+		{Role: RoleUser, Content: summaryTranscript(batch)},
+	}
+	toolCall, err := client.RequestPlan(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	r.recordRequestUsage(request, toolCall)
+	if toolCall.Name != schema.ToolName {
+		return "", fmt.Errorf("summarizer called %q instead of the plan tool", toolCall.Name)
+	}
+	var response PlanResponse
+	if err := json.Unmarshal([]byte(toolCall.Arguments), &response); err != nil {
+		return "", fmt.Errorf("decode summary: %w", err)
+	}
+	content := strings.TrimSpace(response.Message)
+	if content == "" {
+		return "", errors.New("summarizer returned an empty message")
+	}
+	return content, nil
+}
+
+// summaryTranscript renders messages as a plain text transcript.
+func summaryTranscript(messages []ChatMessage) string {
+	var b strings.Builder
+	for _, message := range messages {
+		b.WriteString(string(message.Role))
+		b.WriteString(": ")
+		b.WriteString(clipRunes(message.Content, summaryMessageRunes))
+		for _, call := range message.ToolCalls {
+			b.WriteString("\n[tool call ")
+			b.WriteString(call.Name)
+			b.WriteString("] ")
+			b.WriteString(clipRunes(call.Arguments, summaryMessageRunes))
+		}
+		b.WriteString("\n\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func clipRunes(value string, limit int) string {
+	runes := []rune(value)
+	if len(runes) <= limit {
+		return value
+	}
+	return string(runes[:limit]) + "…"
+}
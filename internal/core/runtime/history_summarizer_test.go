@@ -0,0 +1,100 @@
+package runtime
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/asynkron/goagent/internal/core/schema"
+)
+
+func TestSummarizeHistoryReplacesOldestMessages(t *testing.T) {
+	t.Parallel()
+
+	store := NewMemoryHistoryStore()
+	provider := &scriptedProvider{calls: []ToolCall{{
+		ID:        "call-1",
+		Name:      schema.ToolName,
+		Arguments: `{"message":"user asked for a build; it passed","reasoning":[],"plan":[],"requireHumanInput":false}`,
+	}}}
+	history := []ChatMessage{{Role: RoleSystem, Content: "system"}}
+	for i := 0; i < 6; i++ {
+		history = append(history,
+			ChatMessage{Role: RoleUser, Content: strings.Repeat("request ", 50)},
+			ChatMessage{Role: RoleAssistant, Content: "plan", ToolCalls: []ToolCall{{ID: "c", Name: schema.ToolName}}},
+			ChatMessage{Role: RoleTool, Content: strings.Repeat("output ", 50), ToolCallID: "c"},
+		)
+	}
+
+	rt := &Runtime{
+		options: RuntimeOptions{
+			Logger:              &NoOpLogger{},
+			Metrics:             &NoOpMetrics{},
+			HistoryStore:        store,
+			SummarizeCompaction: true,
+			CompactionBatchSize: 5,
+		},
+		outputs:       make(chan RuntimeEvent, 8),
+		closed:        make(chan struct{}),
+		client:        provider,
+		history:       history,
+		agentName:     "test",
+		contextBudget: ContextBudget{MaxTokens: 200, CompactWhenPercent: 0.5},
+	}
+
+	rt.summarizeHistory(context.Background())
+
+	got := rt.historySnapshot()
+	// Five messages would split a tool call from its result, so the batch
+	// shrinks to four and is replaced by a single summary.
+	if len(got) != len(history)-3 {
+		t.Fatalf("expected 4 messages folded into one summary, got %d messages", len(got))
+	}
+	if !got[1].Summarized || got[1].Role != RoleSystem || !strings.Contains(got[1].Content, "it passed") {
+		t.Fatalf("expected summary after the system prompt, got %+v", got[1])
+	}
+	if got[2].Role != RoleAssistant || len(got[2].ToolCalls) != 1 {
+		t.Fatalf("expected the next tool call to stay intact, got %+v", got[2])
+	}
+	if archived := store.Archived(); len(archived) != 4 || archived[0].Role != RoleUser {
+		t.Fatalf("expected originals archived, got %+v", archived)
+	}
+	if provider.requests != 1 {
+		t.Fatalf("expected one summary request, got %d", provider.requests)
+	}
+	if usage := rt.BudgetUsage(); usage.Requests != 1 || usage.InputTokens == 0 {
+		t.Fatalf("expected the summary request to count against the budget, got %+v", usage)
+	}
+}
+
+func TestSummarizeHistoryFailureLeavesHistory(t *testing.T) {
+	t.Parallel()
+
+	history := []ChatMessage{{Role: RoleSystem, Content: "system"}}
+	for i := 0; i < 8; i++ {
+		history = append(history, ChatMessage{Role: RoleUser, Content: strings.Repeat("text ", 50)})
+	}
+	rt := &Runtime{
+		options: RuntimeOptions{
+			Logger:              &NoOpLogger{},
+			Metrics:             &NoOpMetrics{},
+			SummarizeCompaction: true,
+		},
+		outputs:       make(chan RuntimeEvent, 8),
+		closed:        make(chan struct{}),
+		client:        &scriptedProvider{},
+		history:       history,
+		agentName:     "test",
+		contextBudget: ContextBudget{MaxTokens: 100, CompactWhenPercent: 0.5},
+	}
+
+	rt.summarizeHistory(context.Background())
+
+	if got := rt.historySnapshot(); len(got) != len(history) {
+		t.Fatalf("expected history untouched after failure, got %d messages", len(got))
+	}
+	evt := <-rt.outputs
+	if evt.Level != StatusLevelWarn || !strings.Contains(evt.Message, "heuristic") {
+		t.Fatalf("expected fallback warning, got %+v", evt)
+	}
+}
@@ -1,6 +1,7 @@
 package runtime
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"os"
@@ -21,8 +22,9 @@ func TestWriteHistoryLog_UsesDefaultPath(t *testing.T) {
 	if options.HistoryLogPath == nil {
 		t.Fatalf("expected default history path to be configured")
 	}
-	if got := *options.HistoryLogPath; got != "history.json" {
-		t.Fatalf("expected default history path 'history.json', got %q", got)
+	want := filepath.Join(".goagent", "sessions", options.SessionID, "history.json")
+	if got := *options.HistoryLogPath; options.SessionID == "" || got != want {
+		t.Fatalf("expected default history path %q, got %q", want, got)
 	}
 
 	historyPath := filepath.Join(tempDir, *options.HistoryLogPath)
@@ -43,12 +45,15 @@ func TestWriteHistoryLog_UsesDefaultPath(t *testing.T) {
 		t.Fatalf("failed to read history log: %v", err)
 	}
 
-	var logged []ChatMessage
+	var logged historyLog
 	if err := json.Unmarshal(content, &logged); err != nil {
 		t.Fatalf("failed to decode history log: %v", err)
 	}
-	if len(logged) != len(messages) || logged[0].Content != messages[0].Content {
-		t.Fatalf("unexpected history log contents: %+v", logged)
+	if logged.Version != historyLogVersion || logged.SessionID != options.SessionID {
+		t.Fatalf("unexpected history log header: version %d, session %q", logged.Version, logged.SessionID)
+	}
+	if len(logged.History) != len(messages) || logged.History[0].Content != messages[0].Content {
+		t.Fatalf("unexpected history log contents: %+v", logged.History)
 	}
 }
 
@@ -96,3 +101,54 @@ func TestWriteHistoryLog_DisabledSkipsWrite(t *testing.T) {
 		t.Fatalf("expected no files when history logging disabled, found %d", len(entries))
 	}
 }
+
+func TestMemoryHistoryStore_RecordsAppendsAndResumes(t *testing.T) {
+	t.Parallel()
+
+	store := NewMemoryHistoryStore()
+	rt := &Runtime{
+		options: RuntimeOptions{
+			Logger:       &NoOpLogger{},
+			Metrics:      &NoOpMetrics{},
+			HistoryStore: store,
+		},
+		outputs:   make(chan RuntimeEvent, 4),
+		closed:    make(chan struct{}),
+		history:   []ChatMessage{{Role: RoleSystem, Content: "system"}},
+		agentName: "test",
+	}
+
+	rt.appendHistory(ChatMessage{Role: RoleUser, Content: "first"})
+	rt.writeHistoryLog(rt.historySnapshot())
+	rt.appendHistory(ChatMessage{Role: RoleAssistant, Content: "second"})
+
+	if got := len(store.Messages()); got != 2 {
+		t.Fatalf("expected 2 appended messages, got %d", got)
+	}
+	loaded, err := store.Load(context.Background())
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(loaded) != 3 || loaded[1].Content != "first" || loaded[2].Content != "second" {
+		t.Fatalf("unexpected loaded history: %+v", loaded)
+	}
+
+	resumed, err := NewRuntime(RuntimeOptions{APIKey: "test-key", HistoryStore: store, DisableSnapshots: true})
+	if err != nil {
+		t.Fatalf("NewRuntime failed: %v", err)
+	}
+	history := resumed.historySnapshot()
+	if len(history) != 3 || history[0].Role != RoleSystem || history[2].Content != "second" {
+		t.Fatalf("expected resumed history behind a fresh system prompt, got %+v", history)
+	}
+}
+
+func TestJSONFileHistoryStore_LoadMissingFile(t *testing.T) {
+	t.Parallel()
+
+	store := &JSONFileHistoryStore{Path: filepath.Join(t.TempDir(), "missing.json")}
+	history, err := store.Load(context.Background())
+	if err != nil || history != nil {
+		t.Fatalf("expected empty history for missing file, got %+v, %v", history, err)
+	}
+}
@@ -0,0 +1,101 @@
+package runtime
+
+import (
+	"context"
+)
+
+// Hooks lets embedders observe and shape the plan/execute loop without
+// forking it. Every callback is optional. Step hooks run on the worker
+// goroutine of each step, so they may be called concurrently when steps run
+// in parallel.
+type Hooks struct {
+	// BeforePlanRequest receives the history about to be sent to the model
+	// and returns the history to send instead, e.g. to inject context or
+	// redact content. Returning nil keeps the original. The runtime's own
+	// history is not changed.
+	BeforePlanRequest func(ctx context.Context, history []ChatMessage) []ChatMessage
+	// AfterPlanResponse runs once a plan passed validation, before it is
+	// recorded and executed. It may edit plan in place; the model's tool
+	// call in the history keeps the original arguments.
+	AfterPlanResponse func(ctx context.Context, plan *PlanResponse)
+	// BeforeStepExecute runs before a step is checked against the read-only
+	// mode, the command policy and approvals. It may edit step; returning an
+	// error vetoes the step, which then fails with that error.
+	BeforeStepExecute func(ctx context.Context, step *PlanStep) error
+	// AfterStepExecute runs when a step finished, before its observation is
+	// recorded. It may edit observation; err is the execution error.
+	AfterStepExecute func(ctx context.Context, step PlanStep, observation *PlanObservationPayload, err error)
+}
+
+// ChainHooks combines hooks so each callback runs the non-nil callbacks of
+// all of them in order. History returned by one BeforePlanRequest is passed
+// to the next, and the first BeforeStepExecute veto stops the chain.
+func ChainHooks(hooks ...Hooks) Hooks {
+	return Hooks{
+		BeforePlanRequest: func(ctx context.Context, history []ChatMessage) []ChatMessage {
+			for _, h := range hooks {
+				if h.BeforePlanRequest == nil {
+					continue
+				}
+				if shaped := h.BeforePlanRequest(ctx, history); shaped != nil {
+					history = shaped
+				}
+			}
+			return history
+		},
+		AfterPlanResponse: func(ctx context.Context, plan *PlanResponse) {
+			for _, h := range hooks {
+				if h.AfterPlanResponse != nil {
+					h.AfterPlanResponse(ctx, plan)
+				}
+			}
+		},
+		BeforeStepExecute: func(ctx context.Context, step *PlanStep) error {
+			for _, h := range hooks {
+				if h.BeforeStepExecute == nil {
+					continue
+				}
+				if err := h.BeforeStepExecute(ctx, step); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		AfterStepExecute: func(ctx context.Context, step PlanStep, observation *PlanObservationPayload, err error) {
+			for _, h := range hooks {
+				if h.AfterStepExecute != nil {
+					h.AfterStepExecute(ctx, step, observation, err)
+				}
+			}
+		},
+	}
+}
+
+func (h Hooks) beforePlanRequest(ctx context.Context, history []ChatMessage) []ChatMessage {
+	if h.BeforePlanRequest == nil {
+		return history
+	}
+	if shaped := h.BeforePlanRequest(ctx, history); shaped != nil {
+		return shaped
+	}
+	return history
+}
+
+func (h Hooks) afterPlanResponse(ctx context.Context, plan *PlanResponse) {
+	if h.AfterPlanResponse != nil {
+		h.AfterPlanResponse(ctx, plan)
+	}
+}
+
+func (h Hooks) beforeStepExecute(ctx context.Context, step *PlanStep) error {
+	if h.BeforeStepExecute == nil {
+		return nil
+	}
+	return h.BeforeStepExecute(ctx, step)
+}
+
+func (h Hooks) afterStepExecute(ctx context.Context, step PlanStep, observation *PlanObservationPayload, err error) {
+	if h.AfterStepExecute != nil {
+		h.AfterStepExecute(ctx, step, observation, err)
+	}
+}
@@ -0,0 +1,99 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingRunner answers every shell step successfully and remembers its
+// run string.
+type recordingRunner struct {
+	mu   sync.Mutex
+	runs []string
+}
+
+func (r *recordingRunner) RunCommand(_ context.Context, step PlanStep) (PlanObservationPayload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs = append(r.runs, step.Command.Run)
+	zero := 0
+	return PlanObservationPayload{Stdout: "ran " + step.Command.Run, ExitCode: &zero}, nil
+}
+
+func TestHooksMutateVetoAndObserveSteps(t *testing.T) {
+	t.Parallel()
+
+	runner := &recordingRunner{}
+	executor := NewCommandExecutor(nil, nil)
+	executor.SetCommandRunner(runner)
+
+	var observed []string
+	hooks := ChainHooks(
+		Hooks{BeforeStepExecute: func(_ context.Context, step *PlanStep) error {
+			step.Command.Run = strings.ReplaceAll(step.Command.Run, "npm", "pnpm")
+			return nil
+		}},
+		Hooks{
+			BeforeStepExecute: func(_ context.Context, step *PlanStep) error {
+				if step.ID == "deploy" {
+					return errors.New("deploys are frozen")
+				}
+				return nil
+			},
+			AfterStepExecute: func(_ context.Context, step PlanStep, observation *PlanObservationPayload, _ error) {
+				observed = append(observed, step.ID)
+				observation.Stdout += " (audited)"
+			},
+		},
+	)
+
+	rt := &Runtime{
+		options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}, CommandPolicy: &CommandPolicy{}, Hooks: hooks},
+		inputs:    make(chan InputEvent, 1),
+		plan:      NewPlanManager(),
+		executor:  executor,
+		outputs:   make(chan RuntimeEvent, 32),
+		closed:    make(chan struct{}),
+		agentName: "main",
+	}
+	rt.plan.Replace([]PlanStep{
+		{ID: "install", Status: PlanPending, Command: CommandDraft{Shell: "bash", Run: "npm install"}},
+		{ID: "deploy", Status: PlanPending, WaitingForID: []string{"install"}, Command: CommandDraft{Shell: "bash", Run: "npm run deploy"}},
+	})
+
+	rt.executePendingCommands(context.Background(), ToolCall{ID: "call-1", Name: "open-agent"})
+	close(rt.outputs)
+
+	if len(runner.runs) != 1 || runner.runs[0] != "pnpm install" {
+		t.Fatalf("expected only the rewritten install step to run, got %v", runner.runs)
+	}
+	if len(observed) != 1 || observed[0] != "install" {
+		t.Fatalf("expected AfterStepExecute for install only, got %v", observed)
+	}
+	history := rt.historySnapshot()
+	if len(history) != 1 {
+		t.Fatalf("expected one tool observation, got %d", len(history))
+	}
+	if !strings.Contains(history[0].Content, "ran pnpm install (audited)") || !strings.Contains(history[0].Content, "vetoed by a hook: deploys are frozen") {
+		t.Fatalf("unexpected observation: %s", history[0].Content)
+	}
+}
+
+func TestChainHooksShapesHistoryInOrder(t *testing.T) {
+	t.Parallel()
+
+	appendNote := func(note string) Hooks {
+		return Hooks{BeforePlanRequest: func(_ context.Context, history []ChatMessage) []ChatMessage {
+			return append(history, ChatMessage{Role: RoleUser, Content: note})
+		}}
+	}
+	hooks := ChainHooks(appendNote("first"), Hooks{}, appendNote("second"))
+
+	history := hooks.beforePlanRequest(context.Background(), []ChatMessage{{Role: RoleSystem, Content: "system"}})
+	if len(history) != 3 || history[1].Content != "first" || history[2].Content != "second" {
+		t.Fatalf("unexpected shaped history: %+v", history)
+	}
+}
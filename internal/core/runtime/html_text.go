@@ -0,0 +1,223 @@
+package runtime
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// skippedHTMLElements hold scripts, styling and page chrome rather than
+// content.
+var skippedHTMLElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Svg: true,
+	atom.Iframe: true, atom.Template: true, atom.Nav: true, atom.Footer: true,
+	atom.Form: true, atom.Button: true, atom.Select: true, atom.Head: true,
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// htmlToMarkdown extracts the readable content of an HTML document as
+// Markdown: headings, paragraphs, lists, links, and preformatted blocks.
+// Relative links are resolved against base. It returns the page title too.
+func htmlToMarkdown(doc *html.Node, base *url.URL) (title, text string) {
+	c := htmlConverter{base: base}
+	c.walk(doc)
+	text = blankLines.ReplaceAllString(c.b.String(), "\n\n")
+	if t := findElement(doc, atom.Title); t != nil {
+		title = strings.Join(strings.Fields(textContent(t)), " ")
+	}
+	return title, strings.TrimSpace(text)
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findElement(child, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+type htmlConverter struct {
+	b    strings.Builder
+	base *url.URL
+	// pre is set inside <pre>, where whitespace is preserved.
+	pre bool
+	// listDepth indents nested list items.
+	listDepth int
+	// space records whitespace between inline text that has not been
+	// written yet, so lines never end in spaces.
+	space bool
+}
+
+func (c *htmlConverter) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		c.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			c.walk(child)
+		}
+		return
+	}
+
+	if skippedHTMLElements[n.DataAtom] {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		c.block()
+		c.write(strings.Repeat("#", level) + " ")
+		c.children(n)
+		c.block()
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Main, atom.Header, atom.Table, atom.Blockquote, atom.Dl:
+		c.block()
+		c.children(n)
+		c.block()
+	case atom.Tr, atom.Dt, atom.Dd:
+		c.line()
+		c.children(n)
+		c.line()
+	case atom.Td, atom.Th:
+		c.children(n)
+		c.write(" |")
+		c.space = true
+	case atom.Br:
+		c.line()
+	case atom.Ul, atom.Ol:
+		c.block()
+		c.listDepth++
+		c.children(n)
+		c.listDepth--
+		c.block()
+	case atom.Li:
+		c.line()
+		c.write(strings.Repeat("  ", max(c.listDepth-1, 0)) + "- ")
+		c.children(n)
+		c.line()
+	case atom.Pre:
+		c.block()
+		c.b.WriteString("```\n")
+		c.pre = true
+		c.children(n)
+		c.pre = false
+		c.line()
+		c.b.WriteString("```")
+		c.block()
+	case atom.Code:
+		if c.pre {
+			c.children(n)
+			return
+		}
+		c.write("`")
+		c.children(n)
+		c.b.WriteString("`")
+	case atom.A:
+		href := attr(n, "href")
+		label := strings.Join(strings.Fields(textContent(n)), " ")
+		if href == "" || label == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(href, "javascript:") {
+			c.children(n)
+			return
+		}
+		if c.base != nil {
+			if ref, err := url.Parse(href); err == nil {
+				href = c.base.ResolveReference(ref).String()
+			}
+		}
+		c.write("[" + label + "](" + href + ")")
+	case atom.Img:
+		if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+			c.write("[image: " + alt + "]")
+		}
+	default:
+		c.children(n)
+	}
+}
+
+func (c *htmlConverter) children(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.walk(child)
+	}
+}
+
+func (c *htmlConverter) text(data string) {
+	if c.pre {
+		c.b.WriteString(data)
+		return
+	}
+	if strings.TrimLeft(data, " \t\r\n") != data {
+		c.space = true
+	}
+	if collapsed := strings.Join(strings.Fields(data), " "); collapsed != "" {
+		c.write(collapsed)
+	}
+	if strings.TrimRight(data, " \t\r\n") != data {
+		c.space = true
+	}
+}
+
+// write appends s, preceded by a pending space unless the line is empty or
+// already ends in one.
+func (c *htmlConverter) write(s string) {
+	if c.space && !c.atLineStart() && !strings.HasSuffix(c.b.String(), " ") {
+		c.b.WriteString(" ")
+	}
+	c.space = false
+	c.b.WriteString(s)
+}
+
+func (c *htmlConverter) atLineStart() bool {
+	s := c.b.String()
+	return s == "" || strings.HasSuffix(s, "\n")
+}
+
+// line ends the current line unless it is already empty.
+func (c *htmlConverter) line() {
+	c.space = false
+	if !c.atLineStart() {
+		c.b.WriteString("\n")
+	}
+}
+
+// block separates block elements with a blank line.
+func (c *htmlConverter) block() {
+	c.line()
+	if s := c.b.String(); s != "" && !strings.HasSuffix(s, "\n\n") {
+		c.b.WriteString("\n")
+	}
+}
+
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteString(" ")
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			visit(child)
+		}
+	}
+	visit(n)
+	return b.String()
+}
+
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
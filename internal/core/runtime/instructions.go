@@ -0,0 +1,116 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// defaultInstructionFileMaxBytes caps a single instruction file.
+	defaultInstructionFileMaxBytes = 16 * 1024
+	// defaultInstructionMaxBytes caps all instruction files together.
+	defaultInstructionMaxBytes = 48 * 1024
+)
+
+// defaultInstructionFileNames are looked up in every directory from the
+// repository root down to the working directory.
+var defaultInstructionFileNames = []string{"AGENTS.md", "CLAUDE.md", filepath.Join(".goagent", "instructions.md")}
+
+// InstructionFile describes a project instruction file added to the system
+// prompt.
+type InstructionFile struct {
+	Path      string `json:"path"`
+	Bytes     int    `json:"bytes"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// DiscoverInstructions reads instruction files from the repository root (the
+// nearest ancestor containing .git) down to cwd, so files closer to cwd come
+// last and can refine broader conventions. Without a repository only cwd is
+// searched. names defaults to AGENTS.md, CLAUDE.md and
+// .goagent/instructions.md; maxBytes caps the combined text. The returned
+// text is ready to append to the system prompt augmentation.
+func DiscoverInstructions(cwd string, names []string, maxBytes int) (string, []InstructionFile, error) {
+	if len(names) == 0 {
+		names = defaultInstructionFileNames
+	}
+	if maxBytes <= 0 {
+		maxBytes = defaultInstructionMaxBytes
+	}
+	cwd, err := filepath.Abs(cwd)
+	if err != nil {
+		return "", nil, err
+	}
+
+	dirs := []string{cwd}
+	for dir := cwd; ; {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			// No repository: only the working directory applies.
+			dirs = []string{cwd}
+			break
+		}
+		dir = parent
+		dirs = append(dirs, dir)
+	}
+
+	var b strings.Builder
+	var files []InstructionFile
+	remaining := maxBytes
+	for i := len(dirs) - 1; i >= 0 && remaining > 0; i-- {
+		for _, name := range names {
+			path := filepath.Join(dirs[i], name)
+			data, err := os.ReadFile(path)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return "", nil, fmt.Errorf("read instructions %s: %w", path, err)
+			}
+			content := strings.TrimSpace(string(data))
+			if content == "" {
+				continue
+			}
+			limit := min(defaultInstructionFileMaxBytes, remaining)
+			truncated := len(content) > limit
+			if truncated {
+				content = truncateUTF8(content, limit)
+			}
+			remaining -= len(content)
+			rel, relErr := filepath.Rel(cwd, path)
+			if relErr != nil {
+				rel = path
+			}
+			files = append(files, InstructionFile{Path: rel, Bytes: len(content), Truncated: truncated})
+
+			fmt.Fprintf(&b, "## Project instructions (%s)\n\n%s\n", rel, content)
+			if truncated {
+				b.WriteString("[truncated]\n")
+			}
+			b.WriteString("\n")
+			if remaining <= 0 {
+				break
+			}
+		}
+	}
+	return strings.TrimSpace(b.String()), files, nil
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a rune.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,64 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiscoverInstructionsWalksFromRepoRootToCwd(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	cwd := filepath.Join(root, "svc", "api")
+	for _, dir := range []string{filepath.Join(root, ".git"), filepath.Join(cwd, ".goagent")} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	write := func(path, content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+	write(filepath.Join(root, "AGENTS.md"), "Use tabs.")
+	write(filepath.Join(root, "svc", "AGENTS.md"), "   ")
+	write(filepath.Join(cwd, ".goagent", "instructions.md"), "Run make test.")
+	// Files above the repository root are ignored.
+	write(filepath.Join(filepath.Dir(root), "AGENTS.md"), "outside")
+
+	text, files, err := DiscoverInstructions(cwd, nil, 0)
+	if err != nil {
+		t.Fatalf("DiscoverInstructions: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected two instruction files, got %+v", files)
+	}
+	if files[0].Path != filepath.Join("..", "..", "AGENTS.md") || files[1].Path != filepath.Join(".goagent", "instructions.md") {
+		t.Fatalf("unexpected file order: %+v", files)
+	}
+	if strings.Index(text, "Use tabs.") > strings.Index(text, "Run make test.") || strings.Contains(text, "outside") {
+		t.Fatalf("unexpected instructions text:\n%s", text)
+	}
+}
+
+func TestDiscoverInstructionsCapsSize(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "AGENTS.md"), []byte(strings.Repeat("é", 100)), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	text, files, err := DiscoverInstructions(dir, nil, 51)
+	if err != nil {
+		t.Fatalf("DiscoverInstructions: %v", err)
+	}
+	if len(files) != 1 || !files[0].Truncated || files[0].Bytes != 50 {
+		t.Fatalf("expected truncated file entry, got %+v", files)
+	}
+	if !strings.Contains(text, "[truncated]") || strings.Contains(text, "�") {
+		t.Fatalf("unexpected truncated text:\n%s", text)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"unicode"
 
@@ -15,6 +16,10 @@ import (
 
 const applyPatchCommandName = "apply_patch"
 
+// maxApplyPatchFileSize keeps apply_patch away from files too large to be
+// hand-written source.
+const maxApplyPatchFileSize = 16 << 20
+
 func newApplyPatchCommand() InternalCommandHandler {
 	return func(ctx context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
 		payload := PlanObservationPayload{}
@@ -45,7 +50,45 @@ func newApplyPatchCommand() InternalCommandHandler {
 			return failApplyPatch(&payload, err.Error()), err
 		}
 
-		results, applyErr := patch.ApplyFilesystem(ctx, operations, opts)
+		// Overlapping hunks and adds over existing paths stop the patch
+		// before anything is written, together with any missing targets so
+		// the model sees every structural problem at once. Everything else,
+		// a lone missing target included, is left to the apply, whose errors
+		// name the failing operation and include the file content.
+		diagnostics := validateApplyPatch(operations, opts)
+		var blocking, warnings []patch.Diagnostic
+		block := false
+		for _, d := range diagnostics {
+			switch {
+			case d.Severity == patch.SeverityWarning:
+				warnings = append(warnings, d)
+			case d.Code == "OVERLAPPING_HUNKS", d.Code == "ADD_OVER_EXISTING":
+				blocking = append(blocking, d)
+				block = true
+			case d.Code == "MISSING_TARGET":
+				blocking = append(blocking, d)
+			}
+		}
+		if block {
+			message := "apply_patch: the patch was not applied:\n" + patch.FormatDiagnostics(blocking)
+			return failApplyPatch(&payload, message), errors.New("apply_patch: patch failed validation")
+		}
+
+		// Count against the operations as applied; deleted files are read
+		// before they are gone.
+		applied := operations
+		if opts.Reverse {
+			if reversed, err := patch.Reverse(operations); err == nil {
+				applied = reversed
+			}
+		}
+		counts := countPatchLines(applied, opts.WorkingDir)
+
+		apply := patch.ApplyFilesystem
+		if opts.Stage {
+			apply = patch.ApplyGit
+		}
+		results, applyErr := apply(ctx, operations, opts.FilesystemOptions)
 		if applyErr != nil {
 			var perr *patch.Error
 			if errors.As(applyErr, &perr) {
@@ -66,22 +109,231 @@ func newApplyPatchCommand() InternalCommandHandler {
 			return results[i].Path < results[j].Path
 		})
 
+		payload.FileChanges = describeFileChanges(applied, results, opts.WorkingDir, counts)
+		summary := ApplyPatchResult{Files: make([]ApplyPatchFile, 0, len(payload.FileChanges))}
 		builder := strings.Builder{}
 		builder.WriteString("Success. Updated the following files:\n")
-		for _, entry := range results {
-			builder.WriteString(entry.Status)
-			builder.WriteString(" ")
-			builder.WriteString(entry.Path)
-			builder.WriteString("\n")
+		for i, change := range payload.FileChanges {
+			skipped := hunksWithStatus(results[i], patch.HunkAlreadyApplied)
+			merged := hunksWithStatus(results[i], patch.HunkMerged)
+			fmt.Fprintf(&builder, "%s %s (+%d -%d)%s\n", change.Status, change.Path, change.Additions, change.Deletions, describeHunkNotes(skipped, merged))
+			summary.Files = append(summary.Files, ApplyPatchFile{
+				Path:      change.Path,
+				Status:    change.Status,
+				Hunks:     change.Hunks,
+				Additions: change.Additions,
+				Deletions: change.Deletions,
+				Skipped:   len(skipped),
+				Merged:    len(merged),
+			})
+			summary.Additions += change.Additions
+			summary.Deletions += change.Deletions
+		}
+		builder.WriteString(formatDiffStat(len(summary.Files), summary.Additions, summary.Deletions))
+		builder.WriteString("\n")
+
+		if len(warnings) > 0 {
+			builder.WriteString("\nWarnings:\n")
+			builder.WriteString(patch.FormatDiagnostics(warnings))
 		}
 
 		payload.Stdout = strings.TrimRight(builder.String(), "\n")
+		payload.Data = summary
 		zero := 0
 		payload.ExitCode = &zero
 		return payload, nil
 	}
 }
 
+// maxFileChangeDiffBytes caps the diff attached to each file change event.
+const maxFileChangeDiffBytes = 64 * 1024
+
+// ApplyPatchResult is the structured result of apply_patch. Hosts receive it
+// as the "data" metadata of the step; the model sees the same counts in the
+// text summary.
+type ApplyPatchResult struct {
+	Files     []ApplyPatchFile `json:"files"`
+	Additions int              `json:"additions"`
+	Deletions int              `json:"deletions"`
+}
+
+// ApplyPatchFile summarizes the change apply_patch made to one file.
+type ApplyPatchFile struct {
+	Path      string `json:"path"`
+	Status    string `json:"status"`
+	Hunks     int    `json:"hunks"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	// Skipped counts hunks that were already applied and left alone.
+	Skipped int `json:"skipped,omitempty"`
+	// Merged counts hunks merged with lines that changed since the patch
+	// was written.
+	Merged int `json:"merged,omitempty"`
+}
+
+// lineCounts holds the lines a patch adds to and removes from one file.
+// hunks holds the counts of each hunk in the order they are applied, so
+// hunks that turn out to be already applied can be taken out again.
+type lineCounts struct {
+	additions int
+	deletions int
+	hunks     []lineCounts
+}
+
+// hunksWithStatus returns the numbers of the result's hunks with status.
+func hunksWithStatus(result patch.Result, status string) []int {
+	var numbers []int
+	for _, hunk := range result.Hunks {
+		if hunk.Status == status {
+			numbers = append(numbers, hunk.Number)
+		}
+	}
+	return numbers
+}
+
+// describeHunkNotes renders the note appended to a file's summary line,
+// e.g. " (hunks 1, 3 already applied; hunk 2 merged)".
+func describeHunkNotes(skipped, merged []int) string {
+	var notes []string
+	for _, group := range []struct {
+		numbers []int
+		note    string
+	}{{skipped, "already applied"}, {merged, "merged"}} {
+		if len(group.numbers) == 0 {
+			continue
+		}
+		labels := make([]string, len(group.numbers))
+		for i, n := range group.numbers {
+			labels[i] = strconv.Itoa(n)
+		}
+		noun := "hunk"
+		if len(group.numbers) > 1 {
+			noun = "hunks"
+		}
+		notes = append(notes, fmt.Sprintf("%s %s %s", noun, strings.Join(labels, ", "), group.note))
+	}
+	if len(notes) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(notes, "; ") + ")"
+}
+
+// countPatchLines counts the added and removed lines per target path. Files
+// deleted by the patch count all their lines as removed, so this must run
+// before the patch is applied.
+func countPatchLines(operations []patch.Operation, workingDir string) map[string]lineCounts {
+	counts := make(map[string]lineCounts)
+	for _, op := range operations {
+		target := op.Path
+		if strings.TrimSpace(op.MovePath) != "" {
+			target = strings.TrimSpace(op.MovePath)
+		}
+		target = filepath.Clean(target)
+		c := counts[target]
+		if op.Type == patch.OperationDelete {
+			if data, err := os.ReadFile(filepath.Join(workingDir, op.Path)); err == nil && len(data) > 0 {
+				c.deletions += strings.Count(strings.TrimSuffix(string(data), "\n"), "\n") + 1
+			}
+		}
+		for _, hunk := range op.Hunks {
+			var hc lineCounts
+			for _, line := range hunk.Lines {
+				switch {
+				case strings.HasPrefix(line, "+"):
+					hc.additions++
+				case strings.HasPrefix(line, "-"):
+					hc.deletions++
+				}
+			}
+			c.additions += hc.additions
+			c.deletions += hc.deletions
+			c.hunks = append(c.hunks, hc)
+		}
+		counts[target] = c
+	}
+	return counts
+}
+
+// formatDiffStat renders the totals like git diff --stat.
+func formatDiffStat(files, additions, deletions int) string {
+	plural := func(n int, one, many string) string {
+		if n == 1 {
+			return fmt.Sprintf("%d %s", n, one)
+		}
+		return fmt.Sprintf("%d %s", n, many)
+	}
+	return fmt.Sprintf("%s, %s(+), %s(-)", plural(files, "file changed", "files changed"), plural(additions, "insertion", "insertions"), plural(deletions, "deletion", "deletions"))
+}
+
+// describeFileChanges pairs each result with the number of hunks that targeted
+// it, the lines added and removed, and the file size after the patch was
+// applied.
+func describeFileChanges(operations []patch.Operation, results []patch.Result, workingDir string, counts map[string]lineCounts) []FileChange {
+	hunks := make(map[string]int)
+	diffs := make(map[string]*strings.Builder)
+	for _, op := range operations {
+		target := op.Path
+		if strings.TrimSpace(op.MovePath) != "" {
+			target = strings.TrimSpace(op.MovePath)
+		}
+		target = filepath.Clean(target)
+		hunks[target] += len(op.Hunks)
+		for _, hunk := range op.Hunks {
+			b := diffs[target]
+			if b == nil {
+				b = &strings.Builder{}
+				diffs[target] = b
+			}
+			if hunk.Header == "" {
+				b.WriteString("@@\n")
+			}
+			for _, line := range hunk.RawPatchLines {
+				b.WriteString(line)
+				b.WriteString("\n")
+			}
+		}
+	}
+
+	changes := make([]FileChange, 0, len(results))
+	for _, result := range results {
+		c := counts[filepath.Clean(result.Path)]
+		for _, number := range hunksWithStatus(result, patch.HunkAlreadyApplied) {
+			if number-1 < len(c.hunks) {
+				c.additions -= c.hunks[number-1].additions
+				c.deletions -= c.hunks[number-1].deletions
+			}
+		}
+		change := FileChange{Path: result.Path, Status: result.Status, Hunks: hunks[filepath.Clean(result.Path)], Additions: c.additions, Deletions: c.deletions}
+		if b := diffs[filepath.Clean(result.Path)]; b != nil {
+			change.Diff = b.String()
+			if len(change.Diff) > maxFileChangeDiffBytes {
+				change.Diff = truncateUTF8(change.Diff, maxFileChangeDiffBytes) + "\n... diff truncated\n"
+			}
+		}
+		if result.Status != "D" {
+			if info, err := os.Stat(filepath.Join(workingDir, result.Path)); err == nil && !info.IsDir() {
+				change.Bytes = info.Size()
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// validateApplyPatch checks the operations as they will be applied, which
+// for --reverse means their inverse.
+func validateApplyPatch(operations []patch.Operation, opts applyPatchOptions) []patch.Diagnostic {
+	if opts.Reverse {
+		reversed, err := patch.Reverse(operations)
+		if err != nil {
+			// The apply reports the same error.
+			return nil
+		}
+		operations = reversed
+	}
+	return patch.Validate(operations, os.DirFS(opts.WorkingDir))
+}
+
 func failApplyPatch(payload *PlanObservationPayload, message string) PlanObservationPayload {
 	if payload == nil {
 		payload = &PlanObservationPayload{}
@@ -105,13 +357,20 @@ func splitCommandAndPatch(raw string) (commandLine, patch string) {
 	return line, rest
 }
 
-func parseApplyPatchOptions(commandLine, cwd string) (patch.FilesystemOptions, error) {
+// applyPatchOptions captures the apply_patch command line flags.
+type applyPatchOptions struct {
+	patch.FilesystemOptions
+	// Stage adds the touched files to the git index after applying.
+	Stage bool
+}
+
+func parseApplyPatchOptions(commandLine, cwd string) (applyPatchOptions, error) {
 	tokens, err := tokenizeInternalCommand(commandLine)
 	if err != nil {
-		return patch.FilesystemOptions{}, fmt.Errorf("failed to parse command line: %w", err)
+		return applyPatchOptions{}, fmt.Errorf("failed to parse command line: %w", err)
 	}
 	if len(tokens) == 0 {
-		return patch.FilesystemOptions{}, errors.New("apply_patch: missing command name")
+		return applyPatchOptions{}, errors.New("apply_patch: missing command name")
 	}
 
 	workingDir := strings.TrimSpace(cwd)
@@ -119,14 +378,14 @@ func parseApplyPatchOptions(commandLine, cwd string) (patch.FilesystemOptions, e
 		if wd, getErr := os.Getwd(); getErr == nil {
 			workingDir = wd
 		} else {
-			return patch.FilesystemOptions{}, fmt.Errorf("failed to determine working directory: %w", getErr)
+			return applyPatchOptions{}, fmt.Errorf("failed to determine working directory: %w", getErr)
 		}
 	}
 	if abs, err := filepath.Abs(workingDir); err == nil {
 		workingDir = abs
 	}
 
-	opts := patch.FilesystemOptions{Options: patch.Options{IgnoreWhitespace: true}, WorkingDir: workingDir}
+	opts := applyPatchOptions{FilesystemOptions: patch.FilesystemOptions{Options: patch.Options{IgnoreWhitespace: true, Atomic: true, AllowAlreadyApplied: true, Merge: true, MaxFileSize: maxApplyPatchFileSize, RestrictToWorkingDir: true}, WorkingDir: workingDir}}
 	for _, token := range tokens[1:] {
 		if eq := strings.IndexRune(token, '='); eq != -1 {
 			key := strings.TrimSpace(token[:eq])
@@ -142,6 +401,34 @@ func parseApplyPatchOptions(commandLine, cwd string) (patch.FilesystemOptions, e
 				if strings.EqualFold(value, "true") {
 					opts.IgnoreWhitespace = false
 				}
+			case "atomic":
+				if strings.EqualFold(value, "false") {
+					opts.Atomic = false
+				} else if strings.EqualFold(value, "true") {
+					opts.Atomic = true
+				}
+			case "stage":
+				if strings.EqualFold(value, "true") {
+					opts.Stage = true
+				} else if strings.EqualFold(value, "false") {
+					opts.Stage = false
+				}
+			case "reverse":
+				if strings.EqualFold(value, "true") {
+					opts.Reverse = true
+				} else if strings.EqualFold(value, "false") {
+					opts.Reverse = false
+				}
+			case "merge":
+				if strings.EqualFold(value, "true") {
+					opts.Merge = true
+				} else if strings.EqualFold(value, "false") {
+					opts.Merge = false
+				}
+			case "require_context", "require-context":
+				if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+					opts.RequireContext = n
+				}
 			}
 			continue
 		}
@@ -151,12 +438,22 @@ func parseApplyPatchOptions(commandLine, cwd string) (patch.FilesystemOptions, e
 			opts.IgnoreWhitespace = true
 		case "--respect-whitespace", "--no-ignore-whitespace", "-W":
 			opts.IgnoreWhitespace = false
+		case "--reverse", "-R":
+			opts.Reverse = true
+		case "--no-atomic":
+			opts.Atomic = false
+		case "--no-merge":
+			opts.Merge = false
+		case "--stage":
+			opts.Stage = true
 		default:
 			switch strings.ToLower(token) {
 			case "--respect-whitespace", "--no-ignore-whitespace":
 				opts.IgnoreWhitespace = false
 			case "--ignore-whitespace":
 				opts.IgnoreWhitespace = true
+			case "--reverse":
+				opts.Reverse = true
 			}
 		}
 	}
@@ -167,8 +464,73 @@ func registerBuiltinInternalCommands(rt *Runtime, executor *CommandExecutor) err
 	if executor == nil {
 		return errors.New("nil executor")
 	}
-	if err := executor.RegisterInternalCommand(applyPatchCommandName, newApplyPatchCommand()); err != nil {
+	applyPatch := newApplyPatchCommand()
+	if rt != nil && rt.diagnostics != nil {
+		applyPatch = withDiagnostics(rt.diagnostics, applyPatch)
+	}
+	if err := executor.RegisterInternalCommand(applyPatchCommandName, applyPatch); err != nil {
+		return err
+	}
+	for name, handler := range map[string]InternalCommandHandler{
+		gitStatusCommandName:    newGitStatusCommand(),
+		gitDiffCommandName:      newGitDiffCommand(),
+		gitStageCommandName:     newGitStageCommand(),
+		readFileCommandName:     newReadFileCommand(),
+		writeFileCommandName:    newWriteFileCommand(),
+		listDirCommandName:      newListDirCommand(),
+		searchCommandName:       newSearchCommand(),
+		listFailuresCommandName: newListFailuresCommand(),
+		runTestsCommandName:     newRunTestsCommand(executor),
+	} {
+		if err := executor.RegisterInternalCommand(name, handler); err != nil {
+			return err
+		}
+	}
+	if err := executor.RegisterInternalCommand(sendToAgentCommandName, newSendToAgentCommand(rt)); err != nil {
+		return err
+	}
+	if err := executor.RegisterInternalCommand(jobsCommandName, newJobsCommand(executor)); err != nil {
+		return err
+	}
+	if err := executor.RegisterInternalCommand(killJobCommandName, newKillJobCommand(executor)); err != nil {
 		return err
 	}
+	if err := executor.RegisterInternalCommand(readArtifactCommandName, newReadArtifactCommand(executor)); err != nil {
+		return err
+	}
+	var (
+		fetchOptions  WebFetchOptions
+		searchOptions WebSearchOptions
+	)
+	if rt != nil {
+		fetchOptions = rt.options.WebFetch
+		searchOptions = rt.options.WebSearch
+	}
+	if !fetchOptions.Disable {
+		if err := executor.RegisterInternalCommand(fetchURLCommandName, newFetchURLCommand(newWebFetcher(fetchOptions))); err != nil {
+			return err
+		}
+	}
+	if searchOptions.Provider != nil {
+		if err := executor.RegisterInternalCommand(webSearchCommandName, newWebSearchCommand(searchOptions)); err != nil {
+			return err
+		}
+	}
+	if rt != nil && rt.semanticIndex != nil {
+		if err := executor.RegisterInternalCommand(semanticSearchCommandName, newSemanticSearchCommand(rt.semanticIndex)); err != nil {
+			return err
+		}
+	}
+	if rt != nil && rt.memory != nil {
+		for name, handler := range map[string]InternalCommandHandler{
+			rememberCommandName: newRememberCommand(rt.memory),
+			recallCommandName:   newRecallCommand(rt.memory),
+			forgetCommandName:   newForgetCommand(rt.memory),
+		} {
+			if err := executor.RegisterInternalCommand(name, handler); err != nil {
+				return err
+			}
+		}
+	}
 	return executor.RegisterInternalCommand(runResearchCommandName, newRunResearchCommand(rt))
 }
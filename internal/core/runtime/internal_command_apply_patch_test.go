@@ -281,6 +281,15 @@ func TestApplyPatchAppliesMixedOperations(t *testing.T) {
 		!strings.Contains(payload.Stdout, "M modify.txt") {
 		t.Fatalf("stdout missing expected summary: %q", payload.Stdout)
 	}
+	if !strings.Contains(payload.Stdout, "M modify.txt (+1 -1)") ||
+		!strings.Contains(payload.Stdout, "D delete.txt (+0 -1)") ||
+		!strings.Contains(payload.Stdout, "4 files changed, 3 insertions(+), 2 deletions(-)") {
+		t.Fatalf("stdout missing diffstat: %q", payload.Stdout)
+	}
+	summary, ok := payload.Data.(ApplyPatchResult)
+	if !ok || len(summary.Files) != 4 || summary.Additions != 3 || summary.Deletions != 2 {
+		t.Fatalf("unexpected structured result: %+v", payload.Data)
+	}
 
 	createdData, err := os.ReadFile(filepath.Join(dir, "nested", "new.txt"))
 	if err != nil {
@@ -457,3 +466,183 @@ func TestApplyPatchEndOfFileMarker(t *testing.T) {
 		t.Fatalf("unexpected tail contents: %q", string(data))
 	}
 }
+
+func TestApplyPatchReverseRestoresFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(target, []byte("gamma\nbeta\n"), 0o644); err != nil {
+		t.Fatalf("failed to seed file: %v", err)
+	}
+
+	run := "apply_patch --reverse\n*** Begin Patch\n*** Update File: notes.txt\n@@\n-alpha\n+gamma\n*** End Patch"
+	step := PlanStep{ID: "step-reverse", Command: CommandDraft{Shell: agentShell, Run: run, Cwd: dir}}
+	req := InternalCommandRequest{Name: applyPatchCommandName, Raw: run, Step: step}
+
+	payload, err := newApplyPatchCommand()(context.Background(), req)
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	if payload.ExitCode == nil || *payload.ExitCode != 0 {
+		t.Fatalf("expected exit code 0, got %+v", payload.ExitCode)
+	}
+
+	content, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("failed to read patched file: %v", err)
+	}
+	if got, want := string(content), "alpha\nbeta\n"; got != want {
+		t.Fatalf("reversed content mismatch: got %q want %q", got, want)
+	}
+}
+
+func TestApplyPatchSkipsAlreadyAppliedHunks(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "notes.txt")
+	// The first hunk landed in an earlier attempt.
+	if err := os.WriteFile(target, []byte("gamma\nbeta\ndelta\n"), 0o644); err != nil {
+		t.Fatalf("failed to seed file: %v", err)
+	}
+
+	run := "apply_patch\n*** Begin Patch\n*** Update File: notes.txt\n@@\n-alpha\n+gamma\n beta\n@@\n-delta\n+epsilon\n*** End Patch"
+	step := PlanStep{ID: "step-resend", Command: CommandDraft{Shell: agentShell, Run: run, Cwd: dir}}
+	req := InternalCommandRequest{Name: applyPatchCommandName, Raw: run, Step: step}
+
+	payload, err := newApplyPatchCommand()(context.Background(), req)
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	if !strings.Contains(payload.Stdout, "M notes.txt (+1 -1) (hunk 1 already applied)") {
+		t.Fatalf("expected the skipped hunk in stdout, got %q", payload.Stdout)
+	}
+	result, ok := payload.Data.(ApplyPatchResult)
+	if !ok || len(result.Files) != 1 || result.Files[0].Skipped != 1 {
+		t.Fatalf("unexpected result data: %#v", payload.Data)
+	}
+
+	content, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("failed to read patched file: %v", err)
+	}
+	if got, want := string(content), "gamma\nbeta\nepsilon\n"; got != want {
+		t.Fatalf("patched content mismatch: got %q want %q", got, want)
+	}
+}
+
+func TestApplyPatchMergesDriftedFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "notes.txt")
+	// A line was inserted into the region since the patch was written.
+	if err := os.WriteFile(target, []byte("alpha\nbeta\ninserted\ngamma\ndelta\n"), 0o644); err != nil {
+		t.Fatalf("failed to seed file: %v", err)
+	}
+
+	run := "apply_patch\n*** Begin Patch\n*** Update File: notes.txt\n@@\n alpha\n beta\n gamma\n-delta\n+DELTA\n*** End Patch"
+	step := PlanStep{ID: "step-merge", Command: CommandDraft{Shell: agentShell, Run: run, Cwd: dir}}
+	req := InternalCommandRequest{Name: applyPatchCommandName, Raw: run, Step: step}
+
+	payload, err := newApplyPatchCommand()(context.Background(), req)
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	if !strings.Contains(payload.Stdout, "M notes.txt (+1 -1) (hunk 1 merged)") {
+		t.Fatalf("expected the merged hunk in stdout, got %q", payload.Stdout)
+	}
+	content, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("failed to read patched file: %v", err)
+	}
+	if got, want := string(content), "alpha\nbeta\ninserted\ngamma\nDELTA\n"; got != want {
+		t.Fatalf("patched content mismatch: got %q want %q", got, want)
+	}
+
+	// A conflicting change is reported with just its region and nothing is
+	// written.
+	run = "apply_patch\n*** Begin Patch\n*** Update File: notes.txt\n@@\n alpha\n beta\n gamma\n-delta\n+Delta\n*** End Patch"
+	req = InternalCommandRequest{Name: applyPatchCommandName, Raw: run, Step: PlanStep{ID: "step-conflict", Command: CommandDraft{Shell: agentShell, Run: run, Cwd: dir}}}
+	payload, err = newApplyPatchCommand()(context.Background(), req)
+	if err == nil {
+		t.Fatalf("expected a merge conflict")
+	}
+	if !strings.Contains(payload.Stderr, "<<<<<<< ours\nDELTA\n=======\nDelta\n>>>>>>> patch") || strings.Contains(payload.Stderr, "Full content of file") {
+		t.Fatalf("unexpected conflict report: %q", payload.Stderr)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "alpha\nbeta\ninserted\ngamma\nDELTA\n" {
+		t.Fatalf("file changed despite the conflict: %q", content)
+	}
+}
+
+func TestApplyPatchReportsValidationProblemsBeforeWriting(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\ntwo\nthree\n"), 0o644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	run := strings.Join([]string{
+		"apply_patch",
+		"*** Begin Patch",
+		"*** Update File: a.txt",
+		"@@",
+		" one",
+		"-two",
+		"+2",
+		"@@",
+		"-two",
+		"+TWO",
+		" three",
+		"*** Update File: gone.txt",
+		"@@",
+		"-x",
+		"+y",
+		"*** End Patch",
+	}, "\n")
+
+	step := PlanStep{ID: "validate", Command: CommandDraft{Shell: agentShell, Run: run, Cwd: dir}}
+	req := InternalCommandRequest{Name: applyPatchCommandName, Raw: run, Step: step}
+
+	payload, err := newApplyPatchCommand()(context.Background(), req)
+	if err == nil {
+		t.Fatalf("expected validation failure")
+	}
+	for _, want := range []string{"OVERLAPPING_HUNKS: a.txt (hunk 2)", "MISSING_TARGET: gone.txt"} {
+		if !strings.Contains(payload.Stderr, want) {
+			t.Fatalf("stderr missing %q: %q", want, payload.Stderr)
+		}
+	}
+	content, _ := os.ReadFile(filepath.Join(dir, "a.txt"))
+	if string(content) != "one\ntwo\nthree\n" {
+		t.Fatalf("file changed despite validation errors: %q", content)
+	}
+}
+
+func TestApplyPatchLeavesLoneMissingTargetToTheApply(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	run := strings.Join([]string{
+		"apply_patch",
+		"*** Begin Patch",
+		"*** Update File: gone.txt",
+		"@@",
+		"-x",
+		"+y",
+		"*** End Patch",
+	}, "\n")
+
+	step := PlanStep{ID: "missing-update", Command: CommandDraft{Shell: agentShell, Run: run, Cwd: dir}}
+	req := InternalCommandRequest{Name: applyPatchCommandName, Raw: run, Step: step}
+
+	payload, err := newApplyPatchCommand()(context.Background(), req)
+	if err == nil {
+		t.Fatalf("expected update of missing file to fail")
+	}
+	if strings.Contains(payload.Stderr, "MISSING_TARGET") || !strings.Contains(payload.Stderr, "gone.txt") {
+		t.Fatalf("expected the apply's own error, got %q", payload.Stderr)
+	}
+}
@@ -0,0 +1,432 @@
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+const fetchURLCommandName = "fetch_url"
+
+const (
+	defaultFetchTimeout  = 20 * time.Second
+	defaultFetchMaxBytes = 48 * 1024
+	// maxFetchContentBytes bounds the max_bytes argument.
+	maxFetchContentBytes = 256 * 1024
+	// maxFetchBodyBytes bounds what is downloaded before conversion.
+	maxFetchBodyBytes = 5 << 20
+	fetchUserAgent    = "goagent"
+)
+
+// WebFetchOptions configures the fetch_url internal command.
+type WebFetchOptions struct {
+	// Disable removes fetch_url; the model then has no in-process web access.
+	Disable bool
+	// Timeout bounds each request including redirects. Defaults to 20s.
+	Timeout time.Duration
+	// MaxBytes caps the content returned to the model unless a step asks
+	// for less or more with max_bytes. Defaults to 48 KiB.
+	MaxBytes int
+	// DenyHosts blocks these hosts and their subdomains.
+	DenyHosts []string
+	// AllowHosts, when non-empty, restricts fetches to these hosts and their
+	// subdomains.
+	AllowHosts []string
+	// AllowPrivateNetworks lets fetches reach loopback, private, link-local
+	// and cloud metadata addresses such as 169.254.169.254. They are refused
+	// by default, including after redirects and when a public name resolves
+	// to them. Set it as well when the HTTP proxy has a private address.
+	AllowPrivateNetworks bool
+	// IgnoreRobots skips the robots.txt check.
+	IgnoreRobots bool
+	// UserAgent is sent with requests and matched against robots.txt
+	// groups. Defaults to "goagent".
+	UserAgent string
+}
+
+// FetchResult is the structured result of the fetch_url internal command.
+type FetchResult struct {
+	URL         string `json:"url"`
+	FinalURL    string `json:"final_url,omitempty"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Title       string `json:"title,omitempty"`
+	Content     string `json:"content"`
+	// Truncated reports that Content was cut at max_bytes.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+type webFetcher struct {
+	opts   WebFetchOptions
+	client *http.Client
+
+	robotsMu sync.Mutex
+	robots   map[string]*robotsRules
+}
+
+func newWebFetcher(opts WebFetchOptions) *webFetcher {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultFetchTimeout
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaultFetchMaxBytes
+	}
+	if strings.TrimSpace(opts.UserAgent) == "" {
+		opts.UserAgent = fetchUserAgent
+	}
+	f := &webFetcher{opts: opts, robots: make(map[string]*robotsRules)}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !opts.AllowPrivateNetworks {
+		// Checking the address being dialed, rather than the URL, also
+		// covers redirects and names that resolve to private addresses.
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: refusePrivateDial}
+		transport.DialContext = dialer.DialContext
+	}
+	f.client = &http.Client{
+		Transport: transport,
+		Timeout:   opts.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return f.checkHost(req.URL)
+		},
+	}
+	return f
+}
+
+// newFetchURLCommand handles "fetch_url <url> [max_bytes=N] [raw=true]".
+// HTML is converted to Markdown unless raw=true.
+func newFetchURLCommand(f *webFetcher) InternalCommandHandler {
+	return func(ctx context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		target := argString(req, "url", "")
+		if target == "" {
+			target = fetchTarget(req.Raw)
+		}
+		if strings.TrimSpace(target) == "" {
+			return failFetchURL("fetch_url requires a URL")
+		}
+		maxBytes := argInt(req, "max_bytes", f.opts.MaxBytes)
+		if maxBytes <= 0 || maxBytes > maxFetchContentBytes {
+			maxBytes = f.opts.MaxBytes
+		}
+		result, err := f.fetch(ctx, strings.TrimSpace(target), maxBytes, argBool(req, "raw"))
+		if err != nil {
+			return failFetchURL(err.Error())
+		}
+		return structuredObservation(result)
+	}
+}
+
+// fetchTarget returns the first positional argument. It re-reads the
+// command line because a query string such as "?q=go" makes the generic
+// parser take the URL for a key=value argument.
+func fetchTarget(raw string) string {
+	commandLine, _, _ := strings.Cut(raw, "\n")
+	tokens, err := tokenizeInternalCommand(commandLine)
+	if err != nil || len(tokens) == 0 {
+		return ""
+	}
+	for _, token := range tokens[1:] {
+		if strings.Contains(token, "://") || !strings.Contains(token, "=") {
+			return token
+		}
+	}
+	return ""
+}
+
+func failFetchURL(message string) (PlanObservationPayload, error) {
+	one := 1
+	return PlanObservationPayload{Stderr: message, Details: message, ExitCode: &one}, fmt.Errorf("fetch_url: %s", message)
+}
+
+func (f *webFetcher) fetch(ctx context.Context, target string, maxBytes int, raw bool) (FetchResult, error) {
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return FetchResult{}, err
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return FetchResult{}, fmt.Errorf("unsupported URL scheme %q (want http or https)", parsed.Scheme)
+	}
+	if err := f.checkHost(parsed); err != nil {
+		return FetchResult{}, err
+	}
+	if !f.opts.IgnoreRobots && !f.robotsAllow(ctx, parsed) {
+		return FetchResult{}, fmt.Errorf("%s is disallowed by %s://%s/robots.txt", target, parsed.Scheme, parsed.Host)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return FetchResult{}, err
+	}
+	req.Header.Set("User-Agent", f.opts.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain,application/json;q=0.9,*/*;q=0.5")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return FetchResult{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	result := FetchResult{URL: target, Status: resp.StatusCode, ContentType: resp.Header.Get("Content-Type")}
+	if final := resp.Request.URL.String(); final != parsed.String() {
+		result.FinalURL = final
+	}
+	mediaType, _, _ := mime.ParseMediaType(result.ContentType)
+	if mediaType == "" {
+		mediaType = "text/html"
+	}
+	if !isTextMediaType(mediaType) {
+		return FetchResult{}, fmt.Errorf("%s returned unsupported content type %q", target, mediaType)
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, maxFetchBodyBytes), result.ContentType)
+	if err != nil {
+		return FetchResult{}, err
+	}
+	if (mediaType == "text/html" || mediaType == "application/xhtml+xml") && !raw {
+		doc, err := html.Parse(body)
+		if err != nil {
+			return FetchResult{}, err
+		}
+		result.Title, result.Content = htmlToMarkdown(doc, resp.Request.URL)
+	} else {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return FetchResult{}, err
+		}
+		result.Content = string(data)
+	}
+	if len(result.Content) > maxBytes {
+		result.Content = truncateUTF8(result.Content, maxBytes)
+		result.Truncated = true
+	}
+	return result, nil
+}
+
+func isTextMediaType(mediaType string) bool {
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/xhtml+xml", "application/javascript", "application/x-yaml", "application/yaml":
+		return true
+	}
+	return false
+}
+
+// checkHost applies AllowHosts and DenyHosts to u, and refuses literal
+// private addresses up front; names are checked when they are dialed.
+func (f *webFetcher) checkHost(u *url.URL) error {
+	host := strings.ToLower(u.Hostname())
+	if ip := net.ParseIP(host); ip != nil && !f.opts.AllowPrivateNetworks && isPrivateAddress(ip) {
+		return privateAddressError(ip)
+	}
+	for _, denied := range f.opts.DenyHosts {
+		if hostMatches(host, denied) {
+			return fmt.Errorf("host %s is on the fetch deny list", host)
+		}
+	}
+	if len(f.opts.AllowHosts) == 0 {
+		return nil
+	}
+	for _, allowed := range f.opts.AllowHosts {
+		if hostMatches(host, allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %s is not on the fetch allow list", host)
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, which some clouds use
+// for their metadata service (e.g. 100.100.100.200).
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPrivateAddress reports whether ip is loopback, private, link-local
+// (which includes 169.254.169.254), shared or unspecified.
+func isPrivateAddress(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		sharedAddressSpace.Contains(ip)
+}
+
+func privateAddressError(ip net.IP) error {
+	return fmt.Errorf("%s is a private or local address; fetch_url only reaches public hosts unless private networks are allowed", ip)
+}
+
+// refusePrivateDial is a net.Dialer Control hook that rejects connections to
+// private addresses after name resolution.
+func refusePrivateDial(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("unexpected dial address %s", address)
+	}
+	if isPrivateAddress(ip) {
+		return privateAddressError(ip)
+	}
+	return nil
+}
+
+func hostMatches(host, pattern string) bool {
+	pattern = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(pattern), "*."))
+	return pattern != "" && (host == pattern || strings.HasSuffix(host, "."+pattern))
+}
+
+// robotsRules are the Allow and Disallow lines of the robots.txt group that
+// applies to the fetcher's user agent.
+type robotsRules struct {
+	allow    []*regexp.Regexp
+	disallow []*regexp.Regexp
+}
+
+// robotsAllow fetches and caches robots.txt per origin. Missing or
+// unreadable files allow everything.
+func (f *webFetcher) robotsAllow(ctx context.Context, u *url.URL) bool {
+	origin := u.Scheme + "://" + u.Host
+	f.robotsMu.Lock()
+	rules, ok := f.robots[origin]
+	f.robotsMu.Unlock()
+	if !ok {
+		rules = f.loadRobots(ctx, origin)
+		f.robotsMu.Lock()
+		f.robots[origin] = rules
+		f.robotsMu.Unlock()
+	}
+	if rules == nil {
+		return true
+	}
+	target := u.EscapedPath()
+	if u.RawQuery != "" {
+		target += "?" + u.RawQuery
+	}
+	return rules.allows(target)
+}
+
+func (f *webFetcher) loadRobots(ctx context.Context, origin string) *robotsRules {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("User-Agent", f.opts.UserAgent)
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	return parseRobots(io.LimitReader(resp.Body, 512*1024), f.opts.UserAgent)
+}
+
+// parseRobots returns the rules of the group naming agent, falling back to
+// the "*" group.
+func parseRobots(r io.Reader, agent string) *robotsRules {
+	agent = strings.ToLower(agent)
+	groups := map[string]*robotsRules{}
+	var current []string
+	inRules := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if inRules {
+				current = nil
+				inRules = false
+			}
+			current = append(current, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			// The group exists even when its only rule is an empty
+			// "Disallow:", which allows everything.
+			for _, name := range current {
+				if groups[name] == nil {
+					groups[name] = &robotsRules{}
+				}
+			}
+			if value == "" {
+				continue
+			}
+			re, err := regexp.Compile("^" + robotsPatternExpr(value))
+			if err != nil {
+				continue
+			}
+			for _, name := range current {
+				group := groups[name]
+				if key == "allow" {
+					group.allow = append(group.allow, re)
+				} else {
+					group.disallow = append(group.disallow, re)
+				}
+			}
+		}
+	}
+	for name, group := range groups {
+		if name != "*" && strings.Contains(agent, name) {
+			return group
+		}
+	}
+	return groups["*"]
+}
+
+// robotsPatternExpr converts a robots.txt path pattern, where "*" matches
+// anything and a trailing "$" anchors the end, into a regular expression.
+func robotsPatternExpr(pattern string) string {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return expr
+}
+
+// allows applies the longest matching rule to a path and query; Allow wins
+// ties.
+func (r *robotsRules) allows(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	longest := func(rules []*regexp.Regexp) int {
+		best := -1
+		for _, re := range rules {
+			if loc := re.FindStringIndex(path); loc != nil && loc[1] > best {
+				best = loc[1]
+			}
+		}
+		return best
+	}
+	disallow := longest(r.disallow)
+	return disallow < 0 || longest(r.allow) >= disallow
+}
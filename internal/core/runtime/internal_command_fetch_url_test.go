@@ -0,0 +1,157 @@
+package runtime
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newFetchTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("User-agent: *\nDisallow: /private\nDisallow: /*?session=\nAllow: /private/open$\n"))
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<html><head><title> Docs  page </title><style>body{}</style></head>
+<body><nav><a href="/">Home</a></nav>
+<h1>Install</h1>
+<p>Run the   <code>setup</code> script, see <a href="/guide">the guide</a>.</p>
+<ul><li>fast</li><li>small</li></ul>
+<pre>go build ./...
+go test ./...</pre>
+<script>alert(1)</script>
+</body></html>`))
+	})
+	mux.HandleFunc("/data.json", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	})
+	mux.HandleFunc("/image.png", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte{0x89, 'P', 'N', 'G'})
+	})
+	mux.HandleFunc("/private/open", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("open"))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func runFetch(t *testing.T, f *webFetcher, run string) (FetchResult, error) {
+	t.Helper()
+	req, err := parseInternalInvocation(PlanStep{ID: "fetch", Command: CommandDraft{Shell: agentShell, Run: run}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := newFetchURLCommand(f)(context.Background(), req)
+	result, _ := payload.Data.(FetchResult)
+	return result, err
+}
+
+func TestFetchURLConvertsHTMLToMarkdown(t *testing.T) {
+	t.Parallel()
+
+	server := newFetchTestServer(t)
+	result, err := runFetch(t, newWebFetcher(WebFetchOptions{AllowPrivateNetworks: true}), "fetch_url "+server.URL+"/page")
+	if err != nil {
+		t.Fatalf("fetch_url: %v", err)
+	}
+	want := "# Install\n\nRun the `setup` script, see [the guide](" + server.URL + "/guide).\n\n- fast\n- small\n\n```\ngo build ./...\ngo test ./...\n```"
+	if result.Title != "Docs page" || result.Content != want || result.Status != http.StatusOK {
+		t.Fatalf("unexpected result %+v\ncontent:\n%s", result, result.Content)
+	}
+
+	result, err = runFetch(t, newWebFetcher(WebFetchOptions{AllowPrivateNetworks: true}), "fetch_url "+server.URL+"/data.json max_bytes=5")
+	if err != nil || result.Content != `{"ok"` || !result.Truncated {
+		t.Fatalf("unexpected JSON result %+v %v", result, err)
+	}
+}
+
+func TestFetchURLEnforcesPolicies(t *testing.T) {
+	t.Parallel()
+
+	server := newFetchTestServer(t)
+	host := mustParseURL(t, server.URL).Hostname()
+	cases := []struct {
+		name    string
+		opts    WebFetchOptions
+		path    string
+		wantErr string
+	}{
+		{name: "robots", path: "/private/secret", wantErr: "robots.txt"},
+		{name: "robots allow", path: "/private/open"},
+		{name: "robots query", path: "/page?session=1", wantErr: "robots.txt"},
+		{name: "robots other query", path: "/page?lang=en"},
+		{name: "ignore robots", opts: WebFetchOptions{IgnoreRobots: true}, path: "/private/open"},
+		{name: "deny list", opts: WebFetchOptions{DenyHosts: []string{host}}, path: "/page", wantErr: "deny list"},
+		{name: "allow list", opts: WebFetchOptions{AllowHosts: []string{"example.com"}}, path: "/page", wantErr: "allow list"},
+		{name: "binary", path: "/image.png", wantErr: "unsupported content type"},
+	}
+	for _, tc := range cases {
+		tc.opts.AllowPrivateNetworks = true
+		_, err := runFetch(t, newWebFetcher(tc.opts), "fetch_url "+server.URL+tc.path)
+		switch {
+		case tc.wantErr == "" && err != nil:
+			t.Fatalf("%s: unexpected error %v", tc.name, err)
+		case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+			t.Fatalf("%s: expected %q error, got %v", tc.name, tc.wantErr, err)
+		}
+	}
+
+	if _, err := runFetch(t, newWebFetcher(WebFetchOptions{}), "fetch_url file:///etc/passwd"); err == nil {
+		t.Fatal("expected non-http schemes to be rejected")
+	}
+}
+
+func TestFetchURLRefusesPrivateAddresses(t *testing.T) {
+	t.Parallel()
+
+	server := newFetchTestServer(t)
+	local := "http://localhost:" + mustParseURL(t, server.URL).Port() + "/page"
+	for _, target := range []string{server.URL + "/page", local, "http://169.254.169.254/latest/meta-data/"} {
+		if _, err := runFetch(t, newWebFetcher(WebFetchOptions{Timeout: 5 * time.Second}), "fetch_url "+target); err == nil || !strings.Contains(err.Error(), "private or local address") {
+			t.Fatalf("%s: expected the private address to be refused, got %v", target, err)
+		}
+	}
+
+	for _, addr := range []string{"10.1.2.3", "172.16.0.1", "192.168.1.1", "127.0.0.1", "::1", "fd00:ec2::254", "fe80::1", "100.100.100.200", "0.0.0.0", "::ffff:127.0.0.1"} {
+		if !isPrivateAddress(net.ParseIP(addr)) {
+			t.Fatalf("expected %s to be private", addr)
+		}
+	}
+	for _, addr := range []string{"93.184.216.34", "2606:2800:220:1::1"} {
+		if isPrivateAddress(net.ParseIP(addr)) {
+			t.Fatalf("expected %s to be public", addr)
+		}
+	}
+}
+
+func TestParseRobotsKeepsGroupWithEmptyDisallow(t *testing.T) {
+	t.Parallel()
+
+	robots := "User-agent: goagent\nDisallow:\n\nUser-agent: *\nDisallow: /\n"
+	if rules := parseRobots(strings.NewReader(robots), "goagent"); rules == nil || !rules.allows("/docs") {
+		t.Fatalf("expected the goagent group to allow everything, got %+v", rules)
+	}
+	if rules := parseRobots(strings.NewReader(robots), "otherbot"); rules == nil || rules.allows("/docs") {
+		t.Fatalf("expected the * group to apply to other agents, got %+v", rules)
+	}
+}
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
@@ -0,0 +1,312 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	readFileCommandName  = "read_file"
+	writeFileCommandName = "write_file"
+	listDirCommandName   = "list_dir"
+
+	defaultReadFileLimit    = 2000
+	defaultReadFileMaxBytes = 32 * 1024
+	defaultListDirDepth     = 1
+	defaultListDirEntries   = 500
+)
+
+// FileContent is the structured result of read_file.
+type FileContent struct {
+	Path string `json:"path"`
+	// Offset is the 1-based line number of the first returned line.
+	Offset     int    `json:"offset"`
+	Lines      int    `json:"lines"`
+	TotalLines int    `json:"total_lines"`
+	Size       int64  `json:"size"`
+	Truncated  bool   `json:"truncated"`
+	Content    string `json:"content"`
+}
+
+// FileWriteResult is the structured result of write_file.
+type FileWriteResult struct {
+	Path    string `json:"path"`
+	Bytes   int    `json:"bytes"`
+	Created bool   `json:"created"`
+	Append  bool   `json:"append,omitempty"`
+}
+
+// DirListing is the structured result of list_dir.
+type DirListing struct {
+	Path      string     `json:"path"`
+	Entries   []DirEntry `json:"entries"`
+	Truncated bool       `json:"truncated"`
+}
+
+// DirEntry describes one listed path relative to the listed directory.
+type DirEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+	Size int64  `json:"size,omitempty"`
+}
+
+// resolveWorkspacePath resolves path against the step's working directory
+// and rejects results outside of it, including through symlinks.
+func resolveWorkspacePath(cwd, path string) (root, resolved string, err error) {
+	root = strings.TrimSpace(cwd)
+	if root == "" {
+		if root, err = os.Getwd(); err != nil {
+			return "", "", fmt.Errorf("determine working directory: %w", err)
+		}
+	}
+	if root, err = filepath.Abs(root); err != nil {
+		return "", "", err
+	}
+	if realRoot, err := filepath.EvalSymlinks(root); err == nil {
+		root = realRoot
+	}
+
+	path = strings.TrimSpace(path)
+	if path == "" {
+		path = "."
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	resolved = filepath.Clean(path)
+
+	// Resolve symlinks on the longest existing prefix so links cannot point
+	// outside the workspace; the remainder does not exist yet.
+	existing, rest := resolved, ""
+	for {
+		if real, err := filepath.EvalSymlinks(existing); err == nil {
+			resolved = filepath.Join(real, rest)
+			break
+		}
+		// A dangling link has no target to check, and writing through it
+		// would create the target wherever it points.
+		if info, err := os.Lstat(existing); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			return "", "", fmt.Errorf("path %q goes through the dangling symlink %s", path, existing)
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+
+	if rel, err := filepath.Rel(root, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", "", fmt.Errorf("path %q is outside the working directory %s", path, root)
+	}
+	return root, resolved, nil
+}
+
+func relativeTo(root, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+func failFileCommand(err error) (PlanObservationPayload, error) {
+	return failApplyPatch(nil, err.Error()), err
+}
+
+// newReadFileCommand handles "read_file <path> [offset=N] [limit=N] [max_bytes=N]".
+func newReadFileCommand() InternalCommandHandler {
+	return func(_ context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		args := positionalStrings(req)
+		if len(args) == 0 {
+			return failFileCommand(errors.New("read_file requires a path"))
+		}
+		root, path, err := resolveWorkspacePath(req.Step.Command.Cwd, args[0])
+		if err != nil {
+			return failFileCommand(err)
+		}
+		offset := max(argInt(req, "offset", 1), 1)
+		limit := argInt(req, "limit", defaultReadFileLimit)
+		maxBytes := argInt(req, "max_bytes", defaultReadFileMaxBytes)
+		if limit <= 0 {
+			limit = defaultReadFileLimit
+		}
+		if maxBytes <= 0 || maxBytes > maxObservationBytes {
+			maxBytes = defaultReadFileMaxBytes
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return failFileCommand(fmt.Errorf("read_file: %w", err))
+		}
+		defer func() { _ = file.Close() }()
+		info, err := file.Stat()
+		if err != nil {
+			return failFileCommand(fmt.Errorf("read_file: %w", err))
+		}
+		if info.IsDir() {
+			return failFileCommand(fmt.Errorf("read_file: %s is a directory; use list_dir", relativeTo(root, path)))
+		}
+
+		result := FileContent{Path: relativeTo(root, path), Offset: offset, Size: info.Size()}
+		var content bytes.Buffer
+		reader := bufio.NewReader(file)
+		for {
+			line, readErr := reader.ReadString('\n')
+			if line != "" {
+				result.TotalLines++
+				if result.TotalLines >= offset && !result.Truncated {
+					switch {
+					case result.Lines >= limit, content.Len()+len(line) > maxBytes:
+						result.Truncated = true
+					default:
+						content.WriteString(line)
+						result.Lines++
+					}
+				}
+			}
+			if readErr == io.EOF {
+				break
+			}
+			if readErr != nil {
+				return failFileCommand(fmt.Errorf("read_file: %w", readErr))
+			}
+		}
+		result.Content = content.String()
+		return structuredObservation(result)
+	}
+}
+
+// newWriteFileCommand handles "write_file <path> [append=true]" followed by
+// a newline and the file content, mirroring apply_patch's layout. The content
+// is written byte for byte, so it comes from the untrimmed run string.
+func newWriteFileCommand() InternalCommandHandler {
+	return func(_ context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		_, content := splitCommandAndPatch(req.Step.Command.Run)
+		args := positionalStrings(req)
+		if len(args) == 0 {
+			return failFileCommand(errors.New("write_file requires a path"))
+		}
+		root, path, err := resolveWorkspacePath(req.Step.Command.Cwd, args[0])
+		if err != nil {
+			return failFileCommand(err)
+		}
+
+		appendMode := argBool(req, "append")
+		_, statErr := os.Stat(path)
+		created := errors.Is(statErr, fs.ErrNotExist)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return failFileCommand(fmt.Errorf("write_file: %w", err))
+		}
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if appendMode {
+			flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		}
+		file, err := os.OpenFile(path, flags, 0o644)
+		if err != nil {
+			return failFileCommand(fmt.Errorf("write_file: %w", err))
+		}
+		if _, err := file.WriteString(content); err != nil {
+			_ = file.Close()
+			return failFileCommand(fmt.Errorf("write_file: %w", err))
+		}
+		if err := file.Close(); err != nil {
+			return failFileCommand(fmt.Errorf("write_file: %w", err))
+		}
+
+		result := FileWriteResult{Path: relativeTo(root, path), Bytes: len(content), Created: created, Append: appendMode}
+		payload, err := structuredObservation(result)
+		if err != nil {
+			return payload, err
+		}
+		status := "M"
+		if created {
+			status = "A"
+		}
+		change := FileChange{Path: result.Path, Status: status}
+		if info, err := os.Stat(path); err == nil {
+			change.Bytes = info.Size()
+		}
+		payload.FileChanges = []FileChange{change}
+		return payload, nil
+	}
+}
+
+// newListDirCommand handles "list_dir [path] [depth=N] [hidden=true] [max_entries=N]".
+func newListDirCommand() InternalCommandHandler {
+	return func(_ context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		target := "."
+		if args := positionalStrings(req); len(args) > 0 {
+			target = args[0]
+		}
+		root, dir, err := resolveWorkspacePath(req.Step.Command.Cwd, target)
+		if err != nil {
+			return failFileCommand(err)
+		}
+		depth := argInt(req, "depth", defaultListDirDepth)
+		if depth <= 0 {
+			depth = defaultListDirDepth
+		}
+		maxEntries := argInt(req, "max_entries", defaultListDirEntries)
+		if maxEntries <= 0 {
+			maxEntries = defaultListDirEntries
+		}
+		showHidden := argBool(req, "hidden")
+
+		listing := DirListing{Path: relativeTo(root, dir), Entries: []DirEntry{}}
+		walkErr := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				if path == dir {
+					return err
+				}
+				return nil
+			}
+			if path == dir {
+				if !entry.IsDir() {
+					return fmt.Errorf("%s is not a directory", listing.Path)
+				}
+				return nil
+			}
+			rel := relativeTo(dir, path)
+			if !showHidden && strings.HasPrefix(entry.Name(), ".") {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if len(listing.Entries) >= maxEntries {
+				listing.Truncated = true
+				return filepath.SkipAll
+			}
+			item := DirEntry{Path: rel, Type: "file"}
+			switch {
+			case entry.Type()&fs.ModeSymlink != 0:
+				item.Type = "symlink"
+			case entry.IsDir():
+				item.Type = "dir"
+			default:
+				if info, err := entry.Info(); err == nil {
+					item.Size = info.Size()
+				}
+			}
+			listing.Entries = append(listing.Entries, item)
+			if entry.IsDir() && strings.Count(rel, "/")+1 >= depth {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+		if walkErr != nil {
+			return failFileCommand(fmt.Errorf("list_dir: %w", walkErr))
+		}
+		sort.Slice(listing.Entries, func(i, j int) bool { return listing.Entries[i].Path < listing.Entries[j].Path })
+		return structuredObservation(listing)
+	}
+}
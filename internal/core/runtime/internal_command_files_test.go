@@ -0,0 +1,88 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newFileCommandExecutor(t *testing.T) *CommandExecutor {
+	t.Helper()
+	executor := NewCommandExecutor(nil, nil)
+	if err := registerBuiltinInternalCommands(nil, executor); err != nil {
+		t.Fatalf("register builtin commands: %v", err)
+	}
+	return executor
+}
+
+func TestFileInternalCommandsReadWriteAndList(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	executor := newFileCommandExecutor(t)
+	run := func(command string) (PlanObservationPayload, error) {
+		return executor.Execute(context.Background(), PlanStep{ID: "fs", Command: CommandDraft{Shell: agentShell, Run: command, Cwd: dir}})
+	}
+
+	written, err := run("write_file src/notes.txt\nline 1\nit's line 2\nline 3\n")
+	if err != nil {
+		t.Fatalf("write_file returned error: %v", err)
+	}
+	if result, ok := written.Data.(FileWriteResult); !ok || result.Path != "src/notes.txt" || !result.Created {
+		t.Fatalf("unexpected write result %+v", written.Data)
+	}
+	if len(written.FileChanges) != 1 || written.FileChanges[0].Status != "A" {
+		t.Fatalf("expected an added file change, got %+v", written.FileChanges)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "src", "notes.txt")); err != nil || string(data) != "line 1\nit's line 2\nline 3\n" {
+		t.Fatalf("expected the exact content with its final newline, got %q (%v)", data, err)
+	}
+
+	read, err := run("read_file src/notes.txt offset=2 limit=1")
+	if err != nil {
+		t.Fatalf("read_file returned error: %v", err)
+	}
+	content, ok := read.Data.(FileContent)
+	if !ok || content.Content != "it's line 2\n" || content.TotalLines != 3 || !content.Truncated {
+		t.Fatalf("unexpected read result %+v", read.Data)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ".hidden"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	listed, err := run("list_dir depth=2")
+	if err != nil {
+		t.Fatalf("list_dir returned error: %v", err)
+	}
+	listing, ok := listed.Data.(DirListing)
+	if !ok || len(listing.Entries) != 2 || listing.Entries[0] != (DirEntry{Path: "src", Type: "dir"}) || listing.Entries[1].Path != "src/notes.txt" {
+		t.Fatalf("unexpected listing %+v", listed.Data)
+	}
+
+	if _, err := run("read_file ../outside.txt"); err == nil || !strings.Contains(err.Error(), "outside the working directory") {
+		t.Fatalf("expected sandbox violation, got %v", err)
+	}
+	if err := os.Symlink(os.TempDir(), filepath.Join(dir, "escape")); err == nil {
+		if _, err := run("write_file escape/pwned.txt\nx"); err == nil {
+			t.Fatal("expected writes through symlinks leaving the workspace to fail")
+		}
+	}
+	outside := filepath.Join(t.TempDir(), "target.txt")
+	if err := os.Symlink(outside, filepath.Join(dir, "dangling")); err == nil {
+		if _, err := run("write_file dangling\nx"); err == nil {
+			t.Fatal("expected writes through a dangling symlink to fail")
+		}
+		if _, err := os.Stat(outside); err == nil {
+			t.Fatal("the write created the symlink's target outside the workspace")
+		}
+	}
+
+	if _, err := run("write_file padded.txt\n  indented\n\n"); err != nil {
+		t.Fatalf("write_file returned error: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "padded.txt")); err != nil || string(data) != "  indented\n\n" {
+		t.Fatalf("expected surrounding whitespace kept, got %q (%v)", data, err)
+	}
+}
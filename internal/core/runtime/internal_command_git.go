@@ -0,0 +1,267 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const (
+	gitStatusCommandName = "git_status"
+	gitDiffCommandName   = "git_diff"
+	gitStageCommandName  = "git_stage"
+)
+
+// GitStatus is the structured result of the git_status internal command.
+type GitStatus struct {
+	Branch   string          `json:"branch"`
+	Upstream string          `json:"upstream,omitempty"`
+	Ahead    int             `json:"ahead"`
+	Behind   int             `json:"behind"`
+	Clean    bool            `json:"clean"`
+	Files    []GitFileStatus `json:"files"`
+}
+
+// GitFileStatus describes one changed path. Index and Worktree hold git's
+// single-letter status codes ("M", "A", "D", "R", "?", ...; "." when unchanged).
+type GitFileStatus struct {
+	Path     string `json:"path"`
+	OrigPath string `json:"orig_path,omitempty"`
+	Index    string `json:"index"`
+	Worktree string `json:"worktree"`
+	Staged   bool   `json:"staged"`
+	Conflict bool   `json:"conflict,omitempty"`
+}
+
+// GitDiff is the structured result of the git_diff internal command.
+type GitDiff struct {
+	Staged    bool          `json:"staged"`
+	Files     []GitDiffFile `json:"files"`
+	Additions int           `json:"additions"`
+	Deletions int           `json:"deletions"`
+	// Patch holds the unified diff when requested with patch=true.
+	Patch string `json:"patch,omitempty"`
+}
+
+// GitDiffFile summarizes the changes to one file.
+type GitDiffFile struct {
+	Path      string `json:"path"`
+	OrigPath  string `json:"orig_path,omitempty"`
+	Hunks     int    `json:"hunks"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Binary    bool   `json:"binary,omitempty"`
+}
+
+// The git internal commands run the git CLI rather than a Go implementation
+// so status, ignore rules, rename detection and the index match what the
+// user's own git reports. They use its machine-readable output formats so
+// results do not depend on the user's git configuration or locale.
+
+func newGitStatusCommand() InternalCommandHandler {
+	return func(ctx context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		status, err := gitStatus(ctx, req.Step.Command.Cwd)
+		if err != nil {
+			return failGitCommand(gitStatusCommandName, err.Error())
+		}
+		return structuredObservation(status)
+	}
+}
+
+// newGitDiffCommand handles "git_diff [staged=true] [patch=true] [paths...]".
+// Staged diffs double as a preview of the next commit.
+func newGitDiffCommand() InternalCommandHandler {
+	return func(ctx context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		staged := argBool(req, "staged")
+		diff, err := gitDiff(ctx, req.Step.Command.Cwd, staged, argBool(req, "patch"), positionalStrings(req))
+		if err != nil {
+			return failGitCommand(gitDiffCommandName, err.Error())
+		}
+		return structuredObservation(diff)
+	}
+}
+
+// newGitStageCommand handles "git_stage <paths...>" and "git_stage all=true"
+// and returns the repository status after staging.
+func newGitStageCommand() InternalCommandHandler {
+	return func(ctx context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		cwd := req.Step.Command.Cwd
+		paths := positionalStrings(req)
+		args := []string{"add"}
+		switch {
+		case argBool(req, "all"):
+			args = append(args, "--all")
+		case len(paths) == 0:
+			return failGitCommand(gitStageCommandName, "requires paths or all=true")
+		default:
+			args = append(append(args, "--"), paths...)
+		}
+		if _, err := runGit(ctx, cwd, args...); err != nil {
+			return failGitCommand(gitStageCommandName, err.Error())
+		}
+		status, err := gitStatus(ctx, cwd)
+		if err != nil {
+			return failGitCommand(gitStageCommandName, err.Error())
+		}
+		return structuredObservation(status)
+	}
+}
+
+func failGitCommand(command, message string) (PlanObservationPayload, error) {
+	one := 1
+	return PlanObservationPayload{Stderr: message, Details: message, ExitCode: &one}, fmt.Errorf("%s: %s", command, message)
+}
+
+// runGit runs git in cwd and returns stdout, folding stderr into the error.
+func runGit(ctx context.Context, cwd string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = cwd
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return nil, fmt.Errorf("git %s: %s", args[0], message)
+	}
+	return stdout.Bytes(), nil
+}
+
+// requireGitRepository fails with git's own "not a git repository" message
+// when cwd is not inside a work tree.
+func requireGitRepository(ctx context.Context, cwd string) error {
+	_, err := runGit(ctx, cwd, "rev-parse", "--is-inside-work-tree")
+	return err
+}
+
+func gitStatus(ctx context.Context, cwd string) (GitStatus, error) {
+	out, err := runGit(ctx, cwd, "status", "--porcelain=v2", "--branch", "-z")
+	if err != nil {
+		return GitStatus{}, err
+	}
+	return parseGitStatus(out), nil
+}
+
+// parseGitStatus decodes "git status --porcelain=v2 --branch -z" output.
+func parseGitStatus(out []byte) GitStatus {
+	status := GitStatus{Files: []GitFileStatus{}}
+	records := strings.Split(string(out), "\x00")
+	for i := 0; i < len(records); i++ {
+		record := records[i]
+		switch {
+		case strings.HasPrefix(record, "# branch.head "):
+			status.Branch = strings.TrimPrefix(record, "# branch.head ")
+		case strings.HasPrefix(record, "# branch.upstream "):
+			status.Upstream = strings.TrimPrefix(record, "# branch.upstream ")
+		case strings.HasPrefix(record, "# branch.ab "):
+			fields := strings.Fields(strings.TrimPrefix(record, "# branch.ab "))
+			if len(fields) == 2 {
+				status.Ahead, _ = strconv.Atoi(strings.TrimPrefix(fields[0], "+"))
+				status.Behind, _ = strconv.Atoi(strings.TrimPrefix(fields[1], "-"))
+			}
+		case strings.HasPrefix(record, "1 "):
+			// 1 XY sub mH mI mW hH hI path
+			if fields := strings.SplitN(record, " ", 9); len(fields) == 9 {
+				status.Files = append(status.Files, newGitFileStatus(fields[1], fields[8], ""))
+			}
+		case strings.HasPrefix(record, "2 "):
+			// 2 XY sub mH mI mW hH hI Xscore path, followed by the original path.
+			if fields := strings.SplitN(record, " ", 10); len(fields) == 10 {
+				orig := ""
+				if i+1 < len(records) {
+					i++
+					orig = records[i]
+				}
+				status.Files = append(status.Files, newGitFileStatus(fields[1], fields[9], orig))
+			}
+		case strings.HasPrefix(record, "u "):
+			// u XY sub m1 m2 m3 mW h1 h2 h3 path
+			if fields := strings.SplitN(record, " ", 11); len(fields) == 11 {
+				file := newGitFileStatus(fields[1], fields[10], "")
+				file.Conflict = true
+				status.Files = append(status.Files, file)
+			}
+		case strings.HasPrefix(record, "? "):
+			status.Files = append(status.Files, GitFileStatus{Path: strings.TrimPrefix(record, "? "), Index: "?", Worktree: "?"})
+		}
+	}
+	status.Clean = len(status.Files) == 0
+	return status
+}
+
+func newGitFileStatus(xy, path, orig string) GitFileStatus {
+	file := GitFileStatus{Path: path, OrigPath: orig}
+	if len(xy) == 2 {
+		file.Index = xy[:1]
+		file.Worktree = xy[1:]
+	}
+	file.Staged = file.Index != "" && file.Index != "."
+	return file
+}
+
+func gitDiff(ctx context.Context, cwd string, staged, includePatch bool, paths []string) (GitDiff, error) {
+	// Outside a repository "git diff" falls back to --no-index and prints
+	// its usage instead of failing.
+	if err := requireGitRepository(ctx, cwd); err != nil {
+		return GitDiff{}, err
+	}
+	args := []string{"diff", "--no-color", "--no-ext-diff", "--find-renames"}
+	if staged {
+		args = append(args, "--cached")
+	}
+	args = append(args, "--")
+	args = append(args, paths...)
+	out, err := runGit(ctx, cwd, args...)
+	if err != nil {
+		return GitDiff{}, err
+	}
+	diff := parseGitDiff(string(out))
+	diff.Staged = staged
+	if includePatch {
+		diff.Patch = string(out)
+	}
+	return diff, nil
+}
+
+// parseGitDiff summarizes a unified diff per file.
+func parseGitDiff(patch string) GitDiff {
+	diff := GitDiff{Files: []GitDiffFile{}}
+	var current *GitDiffFile
+	inHunk := false
+	for _, line := range strings.Split(patch, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			diff.Files = append(diff.Files, GitDiffFile{})
+			current = &diff.Files[len(diff.Files)-1]
+			inHunk = false
+			// "diff --git a/x b/y": the b/ side is refined by the ---/+++ and rename headers.
+			if idx := strings.LastIndex(line, " b/"); idx >= 0 {
+				current.Path = line[idx+3:]
+			}
+		case current == nil:
+		case !inHunk && strings.HasPrefix(line, "rename from "):
+			current.OrigPath = strings.TrimPrefix(line, "rename from ")
+		case !inHunk && strings.HasPrefix(line, "rename to "):
+			current.Path = strings.TrimPrefix(line, "rename to ")
+		case !inHunk && strings.HasPrefix(line, "+++ b/"):
+			current.Path = strings.TrimPrefix(line, "+++ b/")
+		case !inHunk && strings.HasPrefix(line, "Binary files "):
+			current.Binary = true
+		case strings.HasPrefix(line, "@@"):
+			current.Hunks++
+			inHunk = true
+		case inHunk && strings.HasPrefix(line, "+"):
+			current.Additions++
+			diff.Additions++
+		case inHunk && strings.HasPrefix(line, "-"):
+			current.Deletions++
+			diff.Deletions++
+		}
+	}
+	return diff
+}
@@ -0,0 +1,115 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func initGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "agent@example.com"},
+		{"config", "user.name", "agent"},
+	} {
+		if _, err := runGit(context.Background(), dir, args...); err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+	}
+	return dir
+}
+
+func TestGitInternalCommandsReturnStructuredResults(t *testing.T) {
+	t.Parallel()
+
+	dir := initGitRepo(t)
+	writeFile := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	writeFile("tracked.txt", "one\ntwo\nthree\n")
+	if _, err := runGit(context.Background(), dir, "add", "tracked.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGit(context.Background(), dir, "commit", "-q", "-m", "initial"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile("tracked.txt", "one\n2\nthree\nfour\n")
+	writeFile("new.txt", "fresh\n")
+
+	executor := NewCommandExecutor(nil, nil)
+	if err := registerBuiltinInternalCommands(nil, executor); err != nil {
+		t.Fatalf("register builtin commands: %v", err)
+	}
+	run := func(command string) PlanObservationPayload {
+		t.Helper()
+		observation, err := executor.Execute(context.Background(), PlanStep{ID: "git", Command: CommandDraft{Shell: agentShell, Run: command, Cwd: dir}})
+		if err != nil {
+			t.Fatalf("%s returned error: %v", command, err)
+		}
+		return observation
+	}
+
+	status, ok := run("git_status").Data.(GitStatus)
+	if !ok {
+		t.Fatal("expected git_status to return GitStatus data")
+	}
+	if status.Branch != "main" || status.Clean || len(status.Files) != 2 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	diff, ok := run("git_diff").Data.(GitDiff)
+	if !ok {
+		t.Fatal("expected git_diff to return GitDiff data")
+	}
+	if len(diff.Files) != 1 || diff.Files[0].Path != "tracked.txt" || diff.Files[0].Hunks != 1 || diff.Additions != 2 || diff.Deletions != 1 {
+		t.Fatalf("unexpected diff %+v", diff)
+	}
+
+	staged, ok := run("git_stage new.txt").Data.(GitStatus)
+	if !ok {
+		t.Fatal("expected git_stage to return GitStatus data")
+	}
+	for _, file := range staged.Files {
+		if file.Path == "new.txt" && (!file.Staged || file.Index != "A") {
+			t.Fatalf("expected new.txt to be staged, got %+v", file)
+		}
+	}
+
+	preview, _ := run("git_diff staged=true").Data.(GitDiff)
+	if len(preview.Files) != 1 || preview.Files[0].Path != "new.txt" || !preview.Staged {
+		t.Fatalf("unexpected staged diff %+v", preview)
+	}
+}
+
+func TestGitInternalCommandsFailOutsideARepository(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	executor := NewCommandExecutor(nil, nil)
+	if err := registerBuiltinInternalCommands(nil, executor); err != nil {
+		t.Fatalf("register builtin commands: %v", err)
+	}
+	dir := t.TempDir()
+	for _, command := range []string{"git_status", "git_diff", "git_stage all=true"} {
+		observation, err := executor.Execute(context.Background(), PlanStep{ID: "git", Command: CommandDraft{Shell: agentShell, Run: command, Cwd: dir}})
+		if name, _, _ := strings.Cut(command, " "); err == nil || !strings.Contains(err.Error(), name+": git ") || !strings.Contains(err.Error(), "not a git repository") {
+			t.Fatalf("%s: expected a not-a-repository error, got %v", command, err)
+		}
+		if observation.ExitCode == nil || *observation.ExitCode != 1 || observation.Stderr == "" {
+			t.Fatalf("%s: expected a failed observation, got %+v", command, observation)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// structuredObservation encodes data as the step's stdout so the model sees
+// JSON, and keeps the typed value in Data for hosts.
+func structuredObservation(data any) (PlanObservationPayload, error) {
+	encoded, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return PlanObservationPayload{}, fmt.Errorf("encode observation: %w", err)
+	}
+	zero := 0
+	return PlanObservationPayload{Stdout: string(encoded), Data: data, ExitCode: &zero}, nil
+}
+
+// argString returns the named argument as a string, or fallback when absent.
+func argString(req InternalCommandRequest, name, fallback string) string {
+	value, ok := req.Args[name]
+	if !ok || value == nil {
+		return fallback
+	}
+	return fmt.Sprint(value)
+}
+
+// argBool reports whether the named argument is set to a truthy value.
+func argBool(req InternalCommandRequest, name string) bool {
+	switch value := req.Args[name].(type) {
+	case bool:
+		return value
+	case string:
+		parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+		return err == nil && parsed
+	default:
+		return false
+	}
+}
+
+// argInt returns the named integer argument, or fallback when it is absent
+// or not a whole number.
+func argInt(req InternalCommandRequest, name string, fallback int) int {
+	switch value := req.Args[name].(type) {
+	case int:
+		return value
+	case int64:
+		return int(value)
+	case float64:
+		if value == float64(int(value)) {
+			return int(value)
+		}
+	case string:
+		if parsed, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+// positionalStrings returns the positional arguments as strings.
+func positionalStrings(req InternalCommandRequest) []string {
+	values := make([]string, 0, len(req.Positionals))
+	for _, value := range req.Positionals {
+		values = append(values, fmt.Sprint(value))
+	}
+	return values
+}
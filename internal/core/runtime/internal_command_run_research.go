@@ -6,18 +6,23 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
 )
 
 const runResearchCommandName = "run_research"
 
+// researchSeq numbers research sub-agents so their names stay unique.
+var researchSeq atomic.Int64
+
 func newRunResearchCommand(rt *Runtime) InternalCommandHandler {
 	return func(ctx context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
 		payload := PlanObservationPayload{}
 
 		// 1. Parse the research spec from the raw command
 		type researchSpec struct {
-			Goal  string `json:"goal"`
-			Turns int    `json:"turns"`
+			Goal       string `json:"goal"`
+			Turns      int    `json:"turns"`
+			Background bool   `json:"background"`
 		}
 		var rs researchSpec
 		jsonInput := strings.TrimSpace(strings.TrimPrefix(req.Raw, runResearchCommandName))
@@ -32,48 +37,32 @@ func newRunResearchCommand(rt *Runtime) InternalCommandHandler {
 			rs.Turns = 10 // Default to 10 turns if not specified or invalid
 		}
 
-		// 2. Configure new runtime options for the sub-agent
-		subOptions := rt.options
-		subOptions.HandsFree = true
-		subOptions.HandsFreeTopic = rs.Goal
-		subOptions.MaxPasses = rs.Turns
-		subOptions.HandsFreeAutoReply = fmt.Sprintf("Please continue to work on the set goal. No human available. Goal: %s", rs.Goal)
-		subOptions.DisableInputReader = true
-		subOptions.DisableOutputForwarding = true
-
-		// 3. Create and run the sub-agent
-		subAgent, err := NewRuntime(subOptions)
+		// 2. Run the goal as a sub-agent; its events are forwarded to our outputs.
+		agent, err := rt.Orchestrator().Spawn(ctx, SubAgentSpec{
+			Name:   fmt.Sprintf("research-%d", researchSeq.Add(1)),
+			Goal:   rs.Goal,
+			Budget: SubAgentBudget{MaxPasses: rs.Turns},
+			// Background sub-agents report back through the mailbox.
+			ReportToParent: rs.Background,
+		})
 		if err != nil {
 			return failApplyPatch(&payload, "failed to create sub-agent"), err
 		}
-
-		runCtx, cancel := context.WithCancel(ctx)
-		defer cancel()
-		go func() { _ = subAgent.Run(runCtx) }()
-
-		// 4. Capture the output of the sub-agent
-		var lastAssistant string
-		var success bool
-		for evt := range subAgent.Outputs() {
-			switch evt.Type {
-			case EventTypeAssistantMessage:
-				if m := strings.TrimSpace(evt.Message); m != "" {
-					lastAssistant = m
-				}
-			case EventTypeStatus:
-				if strings.Contains(evt.Message, "Hands-free session complete") {
-					success = true
-				}
-			}
+		if rs.Background {
+			payload.Stdout = fmt.Sprintf("Started sub-agent %s. Steer it with send_to_agent; its result arrives as a message when it finishes.", agent.Name())
+			zero := 0
+			payload.ExitCode = &zero
+			return payload, nil
 		}
 
-		// 5. Populate the payload with the result
-		if success {
-			payload.Stdout = lastAssistant
+		// 3. Populate the payload with the result
+		result := agent.Wait()
+		if result.Completed {
+			payload.Stdout = result.Summary
 			zero := 0
 			payload.ExitCode = &zero
 		} else {
-			payload.Stderr = lastAssistant
+			payload.Stderr = result.Summary
 			one := 1
 			payload.ExitCode = &one
 		}
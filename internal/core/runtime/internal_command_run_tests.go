@@ -0,0 +1,397 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/asynkron/goagent/internal/bootprobe"
+)
+
+const runTestsCommandName = "run_tests"
+
+const (
+	// maxTestFailures caps the failing test names in a run_tests result.
+	maxTestFailures = 50
+	// testOutputTailLines is how much raw output run_tests keeps when tests
+	// fail or the result could not be parsed.
+	testOutputTailLines = 60
+)
+
+// TestRunResult is the structured result of the run_tests internal command.
+type TestRunResult struct {
+	Framework string   `json:"framework"`
+	Command   string   `json:"command"`
+	Passed    int      `json:"passed"`
+	Failed    int      `json:"failed"`
+	Skipped   int      `json:"skipped"`
+	Failures  []string `json:"failures"`
+	ExitCode  int      `json:"exit_code"`
+	// Output holds the failure output, or the tail of the raw output when
+	// no results could be parsed.
+	Output string `json:"output,omitempty"`
+}
+
+// testInvocation builds the command line for a framework and parses its
+// output into counts and failing test names.
+type testInvocation struct {
+	args  func(filter string, paths []string) []string
+	parse func(stdout, stderr []byte, result *TestRunResult)
+}
+
+var testInvocations = map[string]testInvocation{
+	bootprobe.TestFrameworkGo: {
+		args: func(filter string, paths []string) []string {
+			args := []string{"go", "test", "-json"}
+			if filter != "" {
+				args = append(args, "-run", filter)
+			}
+			if len(paths) == 0 {
+				paths = []string{"./..."}
+			}
+			return append(args, paths...)
+		},
+		parse: parseGoTestJSON,
+	},
+	bootprobe.TestFrameworkCargo: {
+		args: func(filter string, paths []string) []string {
+			args := []string{"cargo", "test"}
+			if filter != "" {
+				args = append(args, filter)
+			}
+			return args
+		},
+		parse: parseCargoTestOutput,
+	},
+	bootprobe.TestFrameworkDotNet: {
+		args: func(filter string, paths []string) []string {
+			args := append([]string{"dotnet", "test"}, paths...)
+			if filter != "" {
+				args = append(args, "--filter", filter)
+			}
+			return args
+		},
+		parse: parseDotNetTestOutput,
+	},
+	bootprobe.TestFrameworkJest: {
+		args: func(filter string, paths []string) []string {
+			args := []string{"npx", "jest", "--json"}
+			if filter != "" {
+				args = append(args, "-t", filter)
+			}
+			return append(args, paths...)
+		},
+		parse: parseJestJSON,
+	},
+	bootprobe.TestFrameworkVitest: {
+		args: func(filter string, paths []string) []string {
+			args := []string{"npx", "vitest", "run", "--reporter=json"}
+			if filter != "" {
+				args = append(args, "-t", filter)
+			}
+			return append(args, paths...)
+		},
+		parse: parseJestJSON,
+	},
+	bootprobe.TestFrameworkPytest: {
+		args: func(filter string, paths []string) []string {
+			python := "python3"
+			if _, err := exec.LookPath(python); err != nil {
+				python = "python"
+			}
+			args := []string{python, "-m", "pytest", "-rA", "-q"}
+			if filter != "" {
+				args = append(args, "-k", filter)
+			}
+			return append(args, paths...)
+		},
+		parse: parsePytestOutput,
+	},
+}
+
+// newRunTestsCommand handles "run_tests [framework=<name>] [filter=<pattern>]
+// [paths...]". Without a framework it runs the first one bootprobe detects
+// in the step's cwd. The tests run through executor like a shell step, so
+// the sandbox backend, environment policy and cancellation apply.
+func newRunTestsCommand(executor *CommandExecutor) InternalCommandHandler {
+	return func(ctx context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		cwd := req.Step.Command.Cwd
+		if cwd == "" {
+			wd, err := os.Getwd()
+			if err != nil {
+				return failFileCommand(err)
+			}
+			cwd = wd
+		}
+
+		framework := strings.ToLower(strings.TrimSpace(argString(req, "framework", "")))
+		if framework == "" {
+			detected := bootprobe.DetectTestFrameworks(bootprobe.NewContext(cwd))
+			if len(detected) == 0 {
+				return failFileCommand(fmt.Errorf("run_tests could not detect a test framework in %s; pass framework=go|pytest|jest|vitest|cargo|dotnet", cwd))
+			}
+			framework = detected[0].Name
+		}
+		invocation, ok := testInvocations[framework]
+		if !ok {
+			return failFileCommand(fmt.Errorf("run_tests: unsupported framework %q", framework))
+		}
+
+		args := invocation.args(argString(req, "filter", ""), positionalStrings(req))
+		step := req.Step
+		step.Command.Cwd = cwd
+		cmd, err := executor.argvCommand(ctx, step, args)
+		if err != nil {
+			return failFileCommand(fmt.Errorf("run_tests: %w", err))
+		}
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		runErr := cmd.Run()
+
+		result := TestRunResult{Framework: framework, Command: strings.Join(args, " "), Failures: []string{}}
+		var exitErr *exec.ExitError
+		switch {
+		case runErr == nil:
+		case errors.As(runErr, &exitErr):
+			result.ExitCode = exitErr.ExitCode()
+		default:
+			return failFileCommand(fmt.Errorf("run_tests: %s: %w", result.Command, runErr))
+		}
+
+		invocation.parse(stdout.Bytes(), stderr.Bytes(), &result)
+		if len(result.Failures) > maxTestFailures {
+			result.Failures = append(result.Failures[:maxTestFailures], fmt.Sprintf("... %d more", len(result.Failures)-maxTestFailures))
+		}
+		parsed := result.Passed+result.Failed+result.Skipped > 0
+		if result.Output == "" && (result.ExitCode != 0 || !parsed) {
+			result.Output = tailText(stdout.String()+stderr.String(), testOutputTailLines)
+		}
+
+		payload, err := structuredObservation(result)
+		if err != nil {
+			return payload, err
+		}
+		if result.ExitCode != 0 {
+			code := result.ExitCode
+			payload.ExitCode = &code
+			return payload, fmt.Errorf("run_tests: %d failed, %d passed (exit code %d)", result.Failed, result.Passed, code)
+		}
+		return payload, nil
+	}
+}
+
+// parseGoTestJSON reads `go test -json` events. Output of failed tests and
+// packages is kept so the model does not have to rerun them.
+func parseGoTestJSON(stdout, _ []byte, result *TestRunResult) {
+	type event struct {
+		Action  string
+		Package string
+		Test    string
+		Output  string
+	}
+	outputs := make(map[string]*strings.Builder)
+	failedTests := make(map[string]bool)
+	var failedPackages []string
+	var failureOutput strings.Builder
+
+	scanner := bufio.NewScanner(bytes.NewReader(stdout))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var evt event
+		if err := json.Unmarshal(scanner.Bytes(), &evt); err != nil {
+			continue
+		}
+		key := evt.Package + "." + evt.Test
+		switch evt.Action {
+		case "output", "build-output":
+			buf, ok := outputs[key]
+			if !ok {
+				buf = &strings.Builder{}
+				outputs[key] = buf
+			}
+			buf.WriteString(evt.Output)
+		case "pass":
+			if evt.Test != "" {
+				result.Passed++
+			}
+		case "skip":
+			if evt.Test != "" {
+				result.Skipped++
+			}
+		case "fail":
+			if evt.Test == "" {
+				failedPackages = append(failedPackages, evt.Package)
+				continue
+			}
+			result.Failed++
+			result.Failures = append(result.Failures, key)
+			failedTests[evt.Package] = true
+			if buf, ok := outputs[key]; ok {
+				failureOutput.WriteString(buf.String())
+			}
+		}
+	}
+	// Packages that failed without a failing test did not build or crashed.
+	for _, pkg := range failedPackages {
+		if failedTests[pkg] {
+			continue
+		}
+		result.Failures = append(result.Failures, pkg)
+		if buf, ok := outputs[pkg+"."]; ok {
+			failureOutput.WriteString(buf.String())
+		}
+	}
+	result.Output = tailText(failureOutput.String(), testOutputTailLines)
+}
+
+// parseJestJSON reads the JSON report shared by jest --json and vitest
+// --reporter=json.
+func parseJestJSON(stdout, _ []byte, result *TestRunResult) {
+	start := bytes.IndexByte(stdout, '{')
+	end := bytes.LastIndexByte(stdout, '}')
+	if start < 0 || end < start {
+		return
+	}
+	var report struct {
+		NumPassedTests  int `json:"numPassedTests"`
+		NumFailedTests  int `json:"numFailedTests"`
+		NumPendingTests int `json:"numPendingTests"`
+		NumTodoTests    int `json:"numTodoTests"`
+		TestResults     []struct {
+			Name             string `json:"name"`
+			Message          string `json:"message"`
+			AssertionResults []struct {
+				FullName        string   `json:"fullName"`
+				Status          string   `json:"status"`
+				FailureMessages []string `json:"failureMessages"`
+			} `json:"assertionResults"`
+		} `json:"testResults"`
+	}
+	if err := json.Unmarshal(stdout[start:end+1], &report); err != nil {
+		return
+	}
+	result.Passed = report.NumPassedTests
+	result.Failed = report.NumFailedTests
+	result.Skipped = report.NumPendingTests + report.NumTodoTests
+	var failureOutput strings.Builder
+	for _, file := range report.TestResults {
+		for _, assertion := range file.AssertionResults {
+			if assertion.Status != "failed" {
+				continue
+			}
+			result.Failures = append(result.Failures, file.Name+" > "+assertion.FullName)
+			for _, message := range assertion.FailureMessages {
+				failureOutput.WriteString(message + "\n")
+			}
+		}
+		if len(file.AssertionResults) == 0 && file.Message != "" {
+			result.Failures = append(result.Failures, file.Name)
+			failureOutput.WriteString(file.Message + "\n")
+		}
+	}
+	result.Output = tailText(failureOutput.String(), testOutputTailLines)
+}
+
+var (
+	pytestResultPattern  = regexp.MustCompile(`^(FAILED|ERROR) (\S+)`)
+	pytestSummaryPattern = regexp.MustCompile(`(\d+) (passed|failed|skipped|errors?|xfailed|xpassed)`)
+)
+
+// parsePytestOutput reads the -rA short summary and the final counts line,
+// e.g. "2 failed, 10 passed, 1 skipped in 0.52s".
+func parsePytestOutput(stdout, _ []byte, result *TestRunResult) {
+	scanner := bufio.NewScanner(bytes.NewReader(stdout))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := pytestResultPattern.FindStringSubmatch(line); match != nil {
+			result.Failures = append(result.Failures, match[2])
+			continue
+		}
+		trimmed := strings.Trim(line, "= ")
+		if !strings.Contains(trimmed, " in ") || !pytestSummaryPattern.MatchString(trimmed) {
+			continue
+		}
+		result.Passed, result.Failed, result.Skipped = 0, 0, 0
+		for _, match := range pytestSummaryPattern.FindAllStringSubmatch(trimmed, -1) {
+			count, _ := strconv.Atoi(match[1])
+			switch match[2] {
+			case "passed", "xpassed":
+				result.Passed += count
+			case "failed", "error", "errors":
+				result.Failed += count
+			default:
+				result.Skipped += count
+			}
+		}
+	}
+}
+
+var cargoTestPattern = regexp.MustCompile(`^test (\S+) \.\.\. (ok|FAILED|ignored)`)
+
+// parseCargoTestOutput reads the per-test lines every test binary prints.
+func parseCargoTestOutput(stdout, _ []byte, result *TestRunResult) {
+	scanner := bufio.NewScanner(bytes.NewReader(stdout))
+	for scanner.Scan() {
+		match := cargoTestPattern.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		switch match[2] {
+		case "ok":
+			result.Passed++
+		case "FAILED":
+			result.Failed++
+			result.Failures = append(result.Failures, match[1])
+		default:
+			result.Skipped++
+		}
+	}
+}
+
+var (
+	dotnetFailedPattern  = regexp.MustCompile(`^\s*Failed (\S+)`)
+	dotnetSummaryPattern = regexp.MustCompile(`(?:Passed|Failed)!\s*-\s*Failed:\s*(\d+),\s*Passed:\s*(\d+),\s*Skipped:\s*(\d+)`)
+)
+
+// parseDotNetTestOutput reads "Failed <name>" lines and the per-project
+// "Failed!  - Failed: 1, Passed: 9, Skipped: 0" summaries.
+func parseDotNetTestOutput(stdout, _ []byte, result *TestRunResult) {
+	scanner := bufio.NewScanner(bytes.NewReader(stdout))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := dotnetSummaryPattern.FindStringSubmatch(line); match != nil {
+			failed, _ := strconv.Atoi(match[1])
+			passed, _ := strconv.Atoi(match[2])
+			skipped, _ := strconv.Atoi(match[3])
+			result.Failed += failed
+			result.Passed += passed
+			result.Skipped += skipped
+			continue
+		}
+		if match := dotnetFailedPattern.FindStringSubmatch(line); match != nil {
+			result.Failures = append(result.Failures, match[1])
+		}
+	}
+}
+
+// tailText returns the last n lines of text.
+func tailText(text string, n int) string {
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return ""
+	}
+	lines := strings.Split(text, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,128 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRunTestsRunsDetectedGoTests(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"go.mod": "module example.com/sample\n\ngo 1.21\n",
+		"sample_test.go": `package sample
+
+import "testing"
+
+func TestPasses(t *testing.T) {}
+
+func TestFails(t *testing.T) { t.Fatal("boom") }
+
+func TestSkipped(t *testing.T) { t.Skip("later") }
+`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	executor := newFileCommandExecutor(t)
+	payload, err := executor.Execute(context.Background(), PlanStep{ID: "tests", Command: CommandDraft{Shell: agentShell, Run: "run_tests", Cwd: dir}})
+	if err == nil {
+		t.Fatal("expected failing tests to fail the step")
+	}
+	result, ok := payload.Data.(TestRunResult)
+	if !ok {
+		t.Fatalf("unexpected data %#v", payload.Data)
+	}
+	if result.Framework != "go" || result.Passed != 1 || result.Failed != 1 || result.Skipped != 1 || result.ExitCode == 0 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if !reflect.DeepEqual(result.Failures, []string{"example.com/sample.TestFails"}) {
+		t.Fatalf("unexpected failures %v", result.Failures)
+	}
+	if payload.ExitCode == nil || *payload.ExitCode == 0 {
+		t.Fatalf("expected non-zero exit code, got %+v", payload)
+	}
+
+	payload, err = executor.Execute(context.Background(), PlanStep{ID: "filtered", Command: CommandDraft{Shell: agentShell, Run: "run_tests framework=go filter=TestPasses", Cwd: dir}})
+	if err != nil {
+		t.Fatalf("filtered run: %v", err)
+	}
+	if result := payload.Data.(TestRunResult); result.Passed != 1 || result.Failed != 0 {
+		t.Fatalf("unexpected filtered result %+v", result)
+	}
+}
+
+func TestRunTestsRequiresFramework(t *testing.T) {
+	t.Parallel()
+
+	executor := newFileCommandExecutor(t)
+	if _, err := executor.Execute(context.Background(), PlanStep{ID: "tests", Command: CommandDraft{Shell: agentShell, Run: "run_tests", Cwd: t.TempDir()}}); err == nil {
+		t.Fatal("expected an error without a detectable framework")
+	}
+}
+
+func TestParseTestOutputs(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		parse func(stdout, stderr []byte, result *TestRunResult)
+		out   string
+		want  TestRunResult
+	}{
+		{
+			name:  "pytest",
+			parse: parsePytestOutput,
+			out: `..F.s
+PASSED tests/test_a.py::test_one
+FAILED tests/test_a.py::test_two - AssertionError: boom
+ERROR tests/test_b.py::test_three - fixture missing
+1 failed, 3 passed, 1 skipped, 1 error in 0.12s
+`,
+			want: TestRunResult{Passed: 3, Failed: 2, Skipped: 1, Failures: []string{"tests/test_a.py::test_two", "tests/test_b.py::test_three"}},
+		},
+		{
+			name:  "cargo",
+			parse: parseCargoTestOutput,
+			out: `running 3 tests
+test math::adds ... ok
+test math::divides ... FAILED
+test slow ... ignored
+`,
+			want: TestRunResult{Passed: 1, Failed: 1, Skipped: 1, Failures: []string{"math::divides"}},
+		},
+		{
+			name:  "dotnet",
+			parse: parseDotNetTestOutput,
+			out: `  Failed Sample.Tests.Divides [12 ms]
+  Error Message:
+Failed!  - Failed:     1, Passed:     4, Skipped:     2, Total:     7, Duration: 40 ms - Sample.Tests.dll (net8.0)
+`,
+			want: TestRunResult{Passed: 4, Failed: 1, Skipped: 2, Failures: []string{"Sample.Tests.Divides"}},
+		},
+		{
+			name:  "jest",
+			parse: parseJestJSON,
+			out: `{"numPassedTests":2,"numFailedTests":1,"numPendingTests":1,"numTodoTests":0,"testResults":[{"name":"/src/sum.test.js","assertionResults":[
+{"fullName":"sum adds","status":"passed"},{"fullName":"sum overflows","status":"failed","failureMessages":["expected 1"]}]}]}`,
+			want: TestRunResult{Passed: 2, Failed: 1, Skipped: 1, Failures: []string{"/src/sum.test.js > sum overflows"}, Output: "expected 1"},
+		},
+	}
+	for _, tc := range cases {
+		var got TestRunResult
+		tc.parse([]byte(tc.out), nil, &got)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/asynkron/goagent/internal/gitignore"
+)
+
+const (
+	searchCommandName = "search"
+
+	defaultSearchMaxResults = 100
+	maxSearchResults        = 1000
+	maxSearchContextLines   = 10
+	// maxSearchFileBytes skips generated or vendored blobs that would
+	// dominate the scan.
+	maxSearchFileBytes = 1 << 20
+	// maxSearchSnippet caps each reported line so minified files cannot
+	// blow the observation budget.
+	maxSearchSnippet = 300
+)
+
+// SearchResult is the structured result of the search internal command.
+type SearchResult struct {
+	Pattern      string        `json:"pattern"`
+	Matches      []SearchMatch `json:"matches"`
+	FilesScanned int           `json:"files_scanned"`
+	Truncated    bool          `json:"truncated"`
+}
+
+// SearchMatch is a single matching line. Line and Column are 1-based; Column
+// counts characters, not bytes.
+type SearchMatch struct {
+	Path    string   `json:"path"`
+	Line    int      `json:"line"`
+	Column  int      `json:"column"`
+	Snippet string   `json:"snippet"`
+	Before  []string `json:"before,omitempty"`
+	After   []string `json:"after,omitempty"`
+}
+
+// newSearchCommand handles
+// "search <pattern> [path] [glob=...] [literal=true] [ignore_case=true] [context=N] [max_results=N] [hidden=true]".
+// Patterns are regular expressions unless literal is set. Files excluded by
+// .gitignore, hidden files, and binary files are skipped.
+func newSearchCommand() InternalCommandHandler {
+	return func(ctx context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		args := positionalStrings(req)
+		if len(args) == 0 || args[0] == "" {
+			return failFileCommand(errors.New("search requires a pattern"))
+		}
+		pattern := args[0]
+		expr := pattern
+		if argBool(req, "literal") {
+			expr = regexp.QuoteMeta(pattern)
+		}
+		if argBool(req, "ignore_case") {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return failFileCommand(fmt.Errorf("search: invalid pattern: %w", err))
+		}
+
+		var globRe *regexp.Regexp
+		glob := strings.TrimSpace(argString(req, "glob", ""))
+		if glob != "" {
+			globExpr := gitignore.GlobExpr(glob)
+			if !strings.Contains(glob, "/") {
+				globExpr = `(?:.*/)?` + globExpr
+			}
+			if globRe, err = regexp.Compile(`^` + globExpr + `$`); err != nil {
+				return failFileCommand(fmt.Errorf("search: invalid glob: %w", err))
+			}
+		}
+
+		target := "."
+		if len(args) > 1 {
+			target = args[1]
+		}
+		root, dir, err := resolveWorkspacePath(req.Step.Command.Cwd, target)
+		if err != nil {
+			return failFileCommand(err)
+		}
+
+		contextLines := min(max(argInt(req, "context", 0), 0), maxSearchContextLines)
+		maxResults := argInt(req, "max_results", defaultSearchMaxResults)
+		if maxResults <= 0 || maxResults > maxSearchResults {
+			maxResults = defaultSearchMaxResults
+		}
+		showHidden := argBool(req, "hidden")
+
+		result := SearchResult{Pattern: pattern, Matches: []SearchMatch{}}
+		ignore := &gitignore.Matcher{}
+		walkErr := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				if path == dir {
+					return err
+				}
+				return nil
+			}
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			rel := relativeTo(dir, path)
+			if entry.IsDir() {
+				if path != dir {
+					if entry.Name() == ".git" || (!showHidden && strings.HasPrefix(entry.Name(), ".")) || ignore.Ignored(rel, true) {
+						return filepath.SkipDir
+					}
+				}
+				ignore.Load(path, rel)
+				return nil
+			}
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			if path != dir && ((!showHidden && strings.HasPrefix(entry.Name(), ".")) || ignore.Ignored(rel, false)) {
+				return nil
+			}
+			if globRe != nil && !globRe.MatchString(rel) {
+				return nil
+			}
+			if len(result.Matches) >= maxResults {
+				result.Truncated = true
+				return filepath.SkipAll
+			}
+			result.FilesScanned++
+			matches, truncated := searchFile(path, relativeTo(root, path), re, contextLines, maxResults-len(result.Matches))
+			result.Matches = append(result.Matches, matches...)
+			if truncated {
+				result.Truncated = true
+				return filepath.SkipAll
+			}
+			return nil
+		})
+		if walkErr != nil {
+			return failFileCommand(fmt.Errorf("search: %w", walkErr))
+		}
+		return structuredObservation(result)
+	}
+}
+
+// searchFile returns up to limit matches in path and reports whether more
+// matches were left unreported. Binary and oversized files are skipped.
+func searchFile(path, rel string, re *regexp.Regexp, contextLines, limit int) ([]SearchMatch, bool) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > maxSearchFileBytes {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		return nil, false
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSearchFileBytes)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	var matches []SearchMatch
+	for i, line := range lines {
+		loc := re.FindStringIndex(line)
+		if loc == nil {
+			continue
+		}
+		if len(matches) >= limit {
+			return matches, true
+		}
+		match := SearchMatch{
+			Path:    rel,
+			Line:    i + 1,
+			Column:  utf8.RuneCountInString(line[:loc[0]]) + 1,
+			Snippet: clipSnippet(line),
+		}
+		if contextLines > 0 {
+			for _, before := range lines[max(0, i-contextLines):i] {
+				match.Before = append(match.Before, clipSnippet(before))
+			}
+			for _, after := range lines[i+1 : min(len(lines), i+1+contextLines)] {
+				match.After = append(match.After, clipSnippet(after))
+			}
+		}
+		matches = append(matches, match)
+	}
+	return matches, false
+}
+
+func clipSnippet(line string) string {
+	if len(line) <= maxSearchSnippet {
+		return line
+	}
+	cut := maxSearchSnippet
+	for cut > 0 && !utf8.RuneStart(line[cut]) {
+		cut--
+	}
+	return line[:cut] + "…"
+}
@@ -0,0 +1,80 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSearchCommandHonorsGitignoreGlobAndContext(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		".gitignore":        "build/\n*.log\n!keep.log\n",
+		"main.go":           "package main\n\nfunc main() {\n\trun()\n}\n",
+		"util/helpers.go":   "package util\n\nfunc run() {}\n",
+		"util/notes.md":     "call run() first\n",
+		"build/output.go":   "func run() {}\n",
+		"debug.log":         "run() failed\n",
+		"keep.log":          "run() kept\n",
+		"image.bin":         "run()\x00\x01",
+		".hidden/secret.go": "func run() {}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	executor := newFileCommandExecutor(t)
+	search := func(command string) SearchResult {
+		t.Helper()
+		observation, err := executor.Execute(context.Background(), PlanStep{ID: "search", Command: CommandDraft{Shell: agentShell, Run: command, Cwd: dir}})
+		if err != nil {
+			t.Fatalf("%s returned error: %v", command, err)
+		}
+		result, ok := observation.Data.(SearchResult)
+		if !ok {
+			t.Fatalf("expected SearchResult data, got %T", observation.Data)
+		}
+		return result
+	}
+
+	all := search(`search "\brun\(\)"`)
+	got := map[string]bool{}
+	for _, match := range all.Matches {
+		got[match.Path] = true
+	}
+	want := map[string]bool{"main.go": true, "util/helpers.go": true, "util/notes.md": true, "keep.log": true}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected matched files %v", got)
+	}
+	for path := range want {
+		if !got[path] {
+			t.Fatalf("expected a match in %s, got %v", path, got)
+		}
+	}
+
+	goOnly := search(`search run( glob=*.go literal=true context=1`)
+	if len(goOnly.Matches) != 2 {
+		t.Fatalf("expected two Go matches, got %+v", goOnly.Matches)
+	}
+	for _, match := range goOnly.Matches {
+		if match.Path == "main.go" {
+			if match.Line != 4 || match.Column != 2 || len(match.Before) != 1 || len(match.After) != 1 {
+				t.Fatalf("unexpected main.go match %+v", match)
+			}
+		}
+	}
+
+	capped := search(`search run max_results=1`)
+	if len(capped.Matches) != 1 || !capped.Truncated {
+		t.Fatalf("expected results to be capped, got %+v", capped)
+	}
+}
@@ -0,0 +1,79 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/asynkron/goagent/internal/semindex"
+)
+
+const semanticSearchCommandName = "semantic_search"
+
+const (
+	defaultSemanticResults = 8
+	maxSemanticResults     = 30
+	// maxSemanticMatchBytes caps the text returned per match.
+	maxSemanticMatchBytes = 2000
+)
+
+// Embedder turns texts into embedding vectors for semantic_search.
+type Embedder = semindex.Embedder
+
+// OpenAIEmbedder calls an OpenAI compatible /embeddings endpoint, including
+// local servers such as Ollama.
+type OpenAIEmbedder = semindex.OpenAIEmbedder
+
+// SemanticMatch is a chunk of a file returned by semantic_search.
+type SemanticMatch = semindex.Match
+
+// SemanticSearchOptions configures the semantic_search internal command,
+// which is only offered when Embedder is set.
+type SemanticSearchOptions struct {
+	Embedder Embedder
+	// IndexDir stores the index; it defaults to .goagent/index in the
+	// working directory.
+	IndexDir string
+	// MaxFiles caps the files indexed (default 10000).
+	MaxFiles int
+}
+
+// SemanticSearchResult is the structured result of semantic_search.
+type SemanticSearchResult struct {
+	Query   string          `json:"query"`
+	Matches []SemanticMatch `json:"matches"`
+	Index   semindex.Stats  `json:"index"`
+}
+
+// newSemanticSearchCommand handles "semantic_search <query...> [limit=N]
+// [path=dir]". Each query first re-indexes files changed since the last one.
+func newSemanticSearchCommand(index *semindex.Index) InternalCommandHandler {
+	return func(ctx context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		query := strings.TrimSpace(argString(req, "query", strings.Join(positionalStrings(req), " ")))
+		if query == "" {
+			return failFileCommand(errors.New("semantic_search requires a query"))
+		}
+		limit := min(argInt(req, "limit", defaultSemanticResults), maxSemanticResults)
+		if limit <= 0 {
+			limit = defaultSemanticResults
+		}
+		stats, err := index.Update(ctx)
+		if err != nil {
+			return failFileCommand(fmt.Errorf("semantic_search: index: %w", err))
+		}
+		matches, err := index.Search(ctx, query, argString(req, "path", ""), limit)
+		if err != nil {
+			return failFileCommand(fmt.Errorf("semantic_search: %w", err))
+		}
+		for i := range matches {
+			if len(matches[i].Text) > maxSemanticMatchBytes {
+				matches[i].Text = truncateUTF8(matches[i].Text, maxSemanticMatchBytes) + "\n[truncated]"
+			}
+		}
+		if matches == nil {
+			matches = []SemanticMatch{}
+		}
+		return structuredObservation(SemanticSearchResult{Query: query, Matches: matches, Index: stats})
+	}
+}
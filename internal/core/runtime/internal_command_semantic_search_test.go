@@ -0,0 +1,72 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/asynkron/goagent/internal/semindex"
+)
+
+// keywordEmbedder marks which of a few fixed keywords each text contains.
+type keywordEmbedder struct{}
+
+var embedderKeywords = []string{"token", "invoice", "tax", "login"}
+
+func (keywordEmbedder) Model() string { return "keywords" }
+
+func (keywordEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = make([]float32, len(embedderKeywords))
+		for j, keyword := range embedderKeywords {
+			if strings.Contains(strings.ToLower(text), keyword) {
+				vectors[i][j] = 1
+			}
+		}
+	}
+	return vectors, nil
+}
+
+func TestSemanticSearchCommandReindexesChangedFiles(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("auth.go", "package app\n\nfunc Login() string { return newToken() }\n")
+	write("billing.go", "package app\n\nfunc Invoice() {}\n")
+
+	index, err := semindex.New(semindex.Options{Root: root, Embedder: keywordEmbedder{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	search := func(run string) SemanticSearchResult {
+		t.Helper()
+		req, err := parseInternalInvocation(PlanStep{ID: "s", Command: CommandDraft{Shell: agentShell, Run: run}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload, err := newSemanticSearchCommand(index)(context.Background(), req)
+		if err != nil {
+			t.Fatalf("%s: %v", run, err)
+		}
+		return payload.Data.(SemanticSearchResult)
+	}
+
+	result := search("semantic_search where is the login token created limit=1")
+	if len(result.Matches) != 1 || result.Matches[0].Path != "auth.go" || !strings.Contains(result.Matches[0].Text, "newToken") || result.Index.Embedded != 2 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	write("billing.go", "package app\n\n// Invoice adds tax.\nfunc Invoice() {}\n")
+	result = search("semantic_search query='invoice tax' limit=1")
+	if len(result.Matches) != 1 || result.Matches[0].Path != "billing.go" || result.Index.Embedded != 1 {
+		t.Fatalf("expected the edited file to be re-indexed, got %+v", result)
+	}
+}
@@ -0,0 +1,272 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const webSearchCommandName = "web_search"
+
+const (
+	defaultWebSearchResults = 8
+	maxWebSearchResults     = 20
+	webSearchTimeout        = 20 * time.Second
+)
+
+// Search provider names accepted by NewSearchProvider.
+const (
+	SearchProviderSearxNG = "searxng"
+	SearchProviderBrave   = "brave"
+	SearchProviderBing    = "bing"
+)
+
+// WebSearchResult is one hit returned by web_search.
+type WebSearchResult struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// SearchProvider queries a web search API for the web_search internal
+// command.
+type SearchProvider interface {
+	// Name identifies the provider in observations and errors.
+	Name() string
+	// Search returns at most limit results for query.
+	Search(ctx context.Context, query string, limit int) ([]WebSearchResult, error)
+}
+
+// WebSearchOptions configures the web_search internal command. The command
+// is only offered when Provider is set.
+type WebSearchOptions struct {
+	Provider SearchProvider
+	// MaxResults is the default number of results per query. Defaults to 8.
+	MaxResults int
+}
+
+// SearchProviderConfig selects and configures a built-in SearchProvider.
+type SearchProviderConfig struct {
+	// Name is searxng, brave or bing.
+	Name string
+	// Endpoint is the SearxNG instance URL; for Brave and Bing it overrides
+	// the public API URL.
+	Endpoint string
+	// APIKey authenticates Brave and Bing requests.
+	APIKey string
+	// Client defaults to an http.Client with a 20s timeout.
+	Client *http.Client
+}
+
+// SearchAPIKeyEnv names the environment variable holding the API key of the
+// named provider, or "" when it needs none.
+func SearchAPIKeyEnv(name string) string {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case SearchProviderBrave:
+		return "BRAVE_SEARCH_API_KEY"
+	case SearchProviderBing:
+		return "BING_SEARCH_API_KEY"
+	default:
+		return ""
+	}
+}
+
+// NewSearchProvider returns the built-in provider named by cfg.Name.
+func NewSearchProvider(cfg SearchProviderConfig) (SearchProvider, error) {
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: webSearchTimeout}
+	}
+	endpoint := strings.TrimSpace(cfg.Endpoint)
+	switch name := strings.ToLower(strings.TrimSpace(cfg.Name)); name {
+	case SearchProviderSearxNG:
+		if endpoint == "" {
+			return nil, errors.New("searxng search needs the instance URL")
+		}
+		return &searxngSearch{endpoint: strings.TrimRight(endpoint, "/") + "/search", client: client}, nil
+	case SearchProviderBrave:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("brave search needs an API key (%s)", SearchAPIKeyEnv(name))
+		}
+		if endpoint == "" {
+			endpoint = "https://api.search.brave.com/res/v1/web/search"
+		}
+		return &braveSearch{endpoint: endpoint, apiKey: cfg.APIKey, client: client}, nil
+	case SearchProviderBing:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("bing search needs an API key (%s)", SearchAPIKeyEnv(name))
+		}
+		if endpoint == "" {
+			endpoint = "https://api.bing.microsoft.com/v7.0/search"
+		}
+		return &bingSearch{endpoint: endpoint, apiKey: cfg.APIKey, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown search provider %q (want searxng, brave or bing)", cfg.Name)
+	}
+}
+
+// newWebSearchCommand handles "web_search <query...> [limit=N]". The query
+// is the positional words joined by spaces, or query="...".
+func newWebSearchCommand(opts WebSearchOptions) InternalCommandHandler {
+	defaultLimit := opts.MaxResults
+	if defaultLimit <= 0 {
+		defaultLimit = defaultWebSearchResults
+	}
+	return func(ctx context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		query := strings.TrimSpace(argString(req, "query", strings.Join(positionalStrings(req), " ")))
+		if query == "" {
+			return failFileCommand(errors.New("web_search requires a query"))
+		}
+		limit := min(argInt(req, "limit", defaultLimit), maxWebSearchResults)
+		if limit <= 0 {
+			limit = defaultLimit
+		}
+		results, err := opts.Provider.Search(ctx, query, limit)
+		if err != nil {
+			return failFileCommand(fmt.Errorf("web_search (%s): %w", opts.Provider.Name(), err))
+		}
+		if len(results) > limit {
+			results = results[:limit]
+		}
+		for i := range results {
+			results[i].Snippet = cleanSnippet(results[i].Snippet)
+			results[i].Title = cleanSnippet(results[i].Title)
+		}
+		return structuredObservation(struct {
+			Query    string            `json:"query"`
+			Provider string            `json:"provider"`
+			Results  []WebSearchResult `json:"results"`
+		}{Query: query, Provider: opts.Provider.Name(), Results: results})
+	}
+}
+
+var snippetTags = regexp.MustCompile(`<[^>]*>`)
+
+// cleanSnippet drops the highlighting markup some APIs put in snippets.
+func cleanSnippet(s string) string {
+	s = html.UnescapeString(snippetTags.ReplaceAllString(s, ""))
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// getSearchJSON sends a GET request and decodes the JSON response into out.
+func getSearchJSON(ctx context.Context, client *http.Client, endpoint string, params url.Values, header http.Header, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 2<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, truncateUTF8(strings.TrimSpace(string(body)), 200))
+	}
+	return json.Unmarshal(body, out)
+}
+
+type searxngSearch struct {
+	endpoint string
+	client   *http.Client
+}
+
+func (s *searxngSearch) Name() string { return SearchProviderSearxNG }
+
+func (s *searxngSearch) Search(ctx context.Context, query string, limit int) ([]WebSearchResult, error) {
+	var response struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	params := url.Values{"q": {query}, "format": {"json"}}
+	if err := getSearchJSON(ctx, s.client, s.endpoint, params, nil, &response); err != nil {
+		return nil, err
+	}
+	results := make([]WebSearchResult, 0, min(len(response.Results), limit))
+	for _, r := range response.Results {
+		if len(results) == limit {
+			break
+		}
+		results = append(results, WebSearchResult{Title: r.Title, URL: r.URL, Snippet: r.Content})
+	}
+	return results, nil
+}
+
+type braveSearch struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+func (s *braveSearch) Name() string { return SearchProviderBrave }
+
+func (s *braveSearch) Search(ctx context.Context, query string, limit int) ([]WebSearchResult, error) {
+	var response struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	params := url.Values{"q": {query}, "count": {strconv.Itoa(limit)}}
+	header := http.Header{"X-Subscription-Token": {s.apiKey}}
+	if err := getSearchJSON(ctx, s.client, s.endpoint, params, header, &response); err != nil {
+		return nil, err
+	}
+	results := make([]WebSearchResult, 0, len(response.Web.Results))
+	for _, r := range response.Web.Results {
+		results = append(results, WebSearchResult{Title: r.Title, URL: r.URL, Snippet: r.Description})
+	}
+	return results, nil
+}
+
+type bingSearch struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+func (s *bingSearch) Name() string { return SearchProviderBing }
+
+func (s *bingSearch) Search(ctx context.Context, query string, limit int) ([]WebSearchResult, error) {
+	var response struct {
+		WebPages struct {
+			Value []struct {
+				Name    string `json:"name"`
+				URL     string `json:"url"`
+				Snippet string `json:"snippet"`
+			} `json:"value"`
+		} `json:"webPages"`
+	}
+	params := url.Values{"q": {query}, "count": {strconv.Itoa(limit)}, "textFormat": {"Raw"}}
+	header := http.Header{"Ocp-Apim-Subscription-Key": {s.apiKey}}
+	if err := getSearchJSON(ctx, s.client, s.endpoint, params, header, &response); err != nil {
+		return nil, err
+	}
+	results := make([]WebSearchResult, 0, len(response.WebPages.Value))
+	for _, r := range response.WebPages.Value {
+		results = append(results, WebSearchResult{Title: r.Name, URL: r.URL, Snippet: r.Snippet})
+	}
+	return results, nil
+}
@@ -0,0 +1,91 @@
+package runtime
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebSearchProviders(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("q") != "go generics" {
+			http.Error(w, "bad query", http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/searx/search":
+			_, _ = w.Write([]byte(`{"results":[{"title":"Tutorial","url":"https://go.dev/doc/tutorial/generics","content":"Getting started"},{"title":"Spec","url":"https://go.dev/ref/spec","content":"Type parameters"}]}`))
+		case "/brave":
+			if r.Header.Get("X-Subscription-Token") != "brave-key" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"web":{"results":[{"title":"Tutorial","url":"https://go.dev/doc/tutorial/generics","description":"Getting <strong>started</strong> with &quot;generics&quot;"}]}}`))
+		case "/bing":
+			if r.Header.Get("Ocp-Apim-Subscription-Key") != "bing-key" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"webPages":{"value":[{"name":"Tutorial","url":"https://go.dev/doc/tutorial/generics","snippet":"Getting started"}]}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	cases := []struct {
+		cfg         SearchProviderConfig
+		run         string
+		wantCount   int
+		wantSnippet string
+		wantErr     string
+	}{
+		{cfg: SearchProviderConfig{Name: "searxng", Endpoint: server.URL + "/searx/"}, run: "web_search go generics limit=1", wantCount: 1, wantSnippet: "Getting started"},
+		{cfg: SearchProviderConfig{Name: "Brave", Endpoint: server.URL + "/brave", APIKey: "brave-key"}, run: `web_search query="go generics"`, wantCount: 1, wantSnippet: `Getting started with "generics"`},
+		{cfg: SearchProviderConfig{Name: "bing", Endpoint: server.URL + "/bing", APIKey: "bing-key"}, run: "web_search go generics", wantCount: 1, wantSnippet: "Getting started"},
+		{cfg: SearchProviderConfig{Name: "bing", Endpoint: server.URL + "/bing", APIKey: "wrong"}, run: "web_search go generics", wantErr: "status 401"},
+	}
+	for _, tc := range cases {
+		provider, err := NewSearchProvider(tc.cfg)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.cfg.Name, err)
+		}
+		req, err := parseInternalInvocation(PlanStep{ID: "search", Command: CommandDraft{Shell: agentShell, Run: tc.run}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload, err := newWebSearchCommand(WebSearchOptions{Provider: provider})(context.Background(), req)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("%s: expected %q error, got %v", tc.cfg.Name, tc.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tc.cfg.Name, err)
+		}
+		data := payload.Data.(struct {
+			Query    string            `json:"query"`
+			Provider string            `json:"provider"`
+			Results  []WebSearchResult `json:"results"`
+		})
+		if len(data.Results) != tc.wantCount || data.Results[0].Snippet != tc.wantSnippet || data.Results[0].URL != "https://go.dev/doc/tutorial/generics" {
+			t.Fatalf("%s: unexpected results %+v", tc.cfg.Name, data.Results)
+		}
+	}
+}
+
+func TestNewSearchProviderValidatesConfig(t *testing.T) {
+	t.Parallel()
+
+	for _, cfg := range []SearchProviderConfig{{Name: "searxng"}, {Name: "brave"}, {Name: "bing"}, {Name: "yahoo", APIKey: "k"}} {
+		if _, err := NewSearchProvider(cfg); err == nil {
+			t.Fatalf("expected %+v to be rejected", cfg)
+		}
+	}
+}
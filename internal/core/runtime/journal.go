@@ -0,0 +1,214 @@
+package runtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// journalFormatVersion versions the plan journal layout like
+// sessionFormatVersion does for saved sessions.
+const journalFormatVersion = 1
+
+// resumePrompt is submitted after an interrupted plan is restored so the
+// model plans the next pass from the replayed observation.
+const resumePrompt = "The previous session was interrupted while executing your plan. The observation above reports which steps finished. Continue the task."
+
+// planJournal is written to RuntimeOptions.JournalPath while a plan executes
+// and removed once its observation is recorded, so a journal left behind
+// means the process died mid-plan.
+type planJournal struct {
+	Version   int           `json:"version"`
+	SavedAt   time.Time     `json:"savedAt"`
+	Provider  string        `json:"provider"`
+	Model     string        `json:"model"`
+	PassCount int           `json:"passCount"`
+	History   []ChatMessage `json:"history"`
+	Plan      []PlanStep    `json:"plan"`
+	// Running lists the steps that had started but not finished.
+	Running  []string `json:"running,omitempty"`
+	ToolCall ToolCall `json:"toolCall"`
+}
+
+// InterruptedPlan is a plan journal found at startup.
+type InterruptedPlan struct {
+	Path    string
+	SavedAt time.Time
+	Model   string
+	// Prompt is the last user prompt before the plan.
+	Prompt string
+	Steps  []PlanStep
+
+	journal planJournal
+}
+
+// Finished counts the steps that completed or failed before the interruption.
+func (p *InterruptedPlan) Finished() int {
+	count := 0
+	for _, step := range p.Steps {
+		if step.Status != PlanPending {
+			count++
+		}
+	}
+	return count
+}
+
+// LoadInterruptedPlan reads the plan journal at path. It returns nil without
+// an error when there is none, meaning the last session ended cleanly.
+func LoadInterruptedPlan(path string) (*InterruptedPlan, error) {
+	if strings.TrimSpace(path) == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("journal: read: %w", err)
+	}
+	var journal planJournal
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return nil, fmt.Errorf("journal: decode %s: %w", path, err)
+	}
+	if journal.Version != journalFormatVersion {
+		return nil, fmt.Errorf("journal: unsupported version %d (want %d)", journal.Version, journalFormatVersion)
+	}
+	interrupted := &InterruptedPlan{
+		Path:    path,
+		SavedAt: journal.SavedAt,
+		Model:   journal.Model,
+		Steps:   journal.Plan,
+		journal: journal,
+	}
+	for i := len(journal.History) - 1; i >= 0; i-- {
+		if journal.History[i].Role == RoleUser {
+			interrupted.Prompt = journal.History[i].Content
+			break
+		}
+	}
+	return interrupted, nil
+}
+
+// DiscardInterruptedPlan deletes the journal so it is not offered again.
+func DiscardInterruptedPlan(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("journal: %w", err)
+	}
+	return nil
+}
+
+// ResumeInterruptedPlan restores the conversation and plan from an
+// interrupted session, replays the plan's observation to the model, with
+// unfinished steps marked as interrupted, and submits a prompt to continue.
+// Call it on an idle runtime before the first prompt.
+func (r *Runtime) ResumeInterruptedPlan(interrupted *InterruptedPlan) error {
+	if interrupted == nil {
+		return errors.New("journal: nothing to resume")
+	}
+	if r.isWorking() {
+		return errors.New("journal: the runtime is busy")
+	}
+	journal := interrupted.journal
+
+	history := journal.History
+	if len(history) > 0 && history[0].Role == RoleSystem {
+		history = history[1:]
+	}
+	r.historyMu.Lock()
+	r.history = append(r.history[:1], history...)
+	r.historyMu.Unlock()
+
+	r.passMu.Lock()
+	r.passCount = journal.PassCount
+	r.passMu.Unlock()
+
+	running := make(map[string]bool, len(journal.Running))
+	for _, id := range journal.Running {
+		running[id] = true
+	}
+	steps := make([]PlanStep, 0, len(journal.Plan))
+	var results []StepObservation
+	for _, step := range journal.Plan {
+		if obs := stepObservationOf(step); obs != nil {
+			results = append(results, *obs)
+			steps = append(steps, step)
+			continue
+		}
+		status, details := PlanAbandoned, "not started: the agent process exited before this step ran"
+		if running[step.ID] {
+			status, details = PlanFailed, "interrupted: the agent process exited while this step was running; check its effects before retrying"
+		}
+		results = append(results, StepObservation{ID: step.ID, Status: status, Details: details})
+		step.Status = status
+		steps = append(steps, step)
+	}
+	r.plan.Replace(steps)
+
+	r.appendToolObservation(journal.ToolCall, PlanObservationPayload{
+		PlanObservation: results,
+		Summary:         fmt.Sprintf("Plan interrupted at %s when the agent process exited; resumed in a new session.", journal.SavedAt.Format(time.RFC3339)),
+	})
+	if err := DiscardInterruptedPlan(interrupted.Path); err != nil {
+		return err
+	}
+	r.emit(RuntimeEvent{
+		Type:     EventTypeStatus,
+		Message:  fmt.Sprintf("Resuming interrupted plan (%d of %d steps finished).", interrupted.Finished(), len(interrupted.Steps)),
+		Level:    StatusLevelInfo,
+		Metadata: map[string]any{"plan": steps},
+	})
+	r.SubmitPrompt(resumePrompt)
+	return nil
+}
+
+// stepObservationOf returns the observation recorded for a finished step.
+func stepObservationOf(step PlanStep) *StepObservation {
+	if step.Status == PlanPending || step.Observation == nil || step.Observation.ObservationForLLM == nil {
+		return nil
+	}
+	for _, obs := range step.Observation.ObservationForLLM.PlanObservation {
+		if obs.ID == step.ID {
+			return &obs
+		}
+	}
+	return &StepObservation{ID: step.ID, Status: step.Status}
+}
+
+// writeJournal records the executing plan. Failures are reported but never
+// stop the plan: the journal only matters if the process dies.
+func (r *Runtime) writeJournal(toolCall ToolCall, running map[string]bool) {
+	path := strings.TrimSpace(r.options.JournalPath)
+	if path == "" || toolCall.ID == "" {
+		return
+	}
+	journal := planJournal{
+		Version:   journalFormatVersion,
+		SavedAt:   time.Now(),
+		Provider:  r.options.Provider,
+		Model:     r.options.Model,
+		PassCount: r.currentPassCount(),
+		History:   r.historySnapshot(),
+		Plan:      r.plan.Snapshot(),
+		ToolCall:  toolCall,
+	}
+	for id := range running {
+		journal.Running = append(journal.Running, id)
+	}
+	if err := writeJSONAtomic(path, journal); err != nil {
+		r.emit(RuntimeEvent{
+			Type:    EventTypeStatus,
+			Message: fmt.Sprintf("Failed to write plan journal: %v", err),
+			Level:   StatusLevelWarn,
+		})
+	}
+}
+
+// clearJournal removes the journal once the plan's observation is recorded.
+func (r *Runtime) clearJournal() {
+	if path := strings.TrimSpace(r.options.JournalPath); path != "" {
+		_ = DiscardInterruptedPlan(path)
+	}
+}
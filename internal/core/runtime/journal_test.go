@@ -0,0 +1,101 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlanJournalRecoversInterruptedPlan(t *testing.T) {
+	t.Parallel()
+
+	journalPath := filepath.Join(t.TempDir(), "journal.json")
+	newRuntime := func() *Runtime {
+		return &Runtime{
+			options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}, JournalPath: journalPath},
+			plan:      NewPlanManager(),
+			executor:  NewCommandExecutor(nil, nil),
+			inputs:    make(chan InputEvent, 1),
+			outputs:   make(chan RuntimeEvent, 64),
+			closed:    make(chan struct{}),
+			history:   []ChatMessage{{Role: RoleSystem, Content: "system"}, {Role: RoleUser, Content: "fix the build"}},
+			agentName: "main",
+		}
+	}
+	rt := newRuntime()
+
+	// Step b looks at the journal while it runs, as a crash would leave it.
+	var seen *InterruptedPlan
+	if err := rt.executor.RegisterInternalCommand("work", func(_ context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		if req.Step.ID == "b" {
+			var err error
+			if seen, err = LoadInterruptedPlan(journalPath); err != nil {
+				return PlanObservationPayload{}, err
+			}
+		}
+		return PlanObservationPayload{Stdout: "ok " + req.Step.ID}, nil
+	}); err != nil {
+		t.Fatalf("failed to register internal command: %v", err)
+	}
+	toolCall := ToolCall{ID: "call-1", Name: "open-agent"}
+	rt.appendHistory(ChatMessage{Role: RoleAssistant, ToolCalls: []ToolCall{toolCall}})
+	rt.plan.Replace([]PlanStep{
+		{ID: "a", Status: PlanPending, Command: CommandDraft{Shell: agentShell, Run: "work"}},
+		{ID: "b", Status: PlanPending, WaitingForID: []string{"a"}, Command: CommandDraft{Shell: agentShell, Run: "work"}},
+		{ID: "c", Status: PlanPending, WaitingForID: []string{"b"}, Command: CommandDraft{Shell: agentShell, Run: "work"}},
+	})
+	rt.executePendingCommands(context.Background(), toolCall)
+
+	if seen == nil {
+		t.Fatalf("expected a journal while the plan ran")
+	}
+	if seen.Prompt != "fix the build" || seen.Finished() != 1 || seen.journal.ToolCall.ID != "call-1" {
+		t.Fatalf("unexpected journal: prompt %q, %d finished, tool call %q", seen.Prompt, seen.Finished(), seen.journal.ToolCall.ID)
+	}
+	if len(seen.journal.Running) != 1 || seen.journal.Running[0] != "b" {
+		t.Fatalf("expected b to be journaled as running, got %v", seen.journal.Running)
+	}
+	if _, err := os.Stat(journalPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the journal to be removed after the plan, got %v", err)
+	}
+
+	// Put the mid-plan journal back and resume it in a fresh runtime.
+	if err := writeJSONAtomic(journalPath, seen.journal); err != nil {
+		t.Fatalf("write journal: %v", err)
+	}
+	interrupted, err := LoadInterruptedPlan(journalPath)
+	if err != nil || interrupted == nil {
+		t.Fatalf("LoadInterruptedPlan: %v, %v", interrupted, err)
+	}
+	resumed := newRuntime()
+	resumed.history = resumed.history[:1]
+	if err := resumed.ResumeInterruptedPlan(interrupted); err != nil {
+		t.Fatalf("ResumeInterruptedPlan: %v", err)
+	}
+
+	history := resumed.historySnapshot()
+	last := history[len(history)-1]
+	if last.Role != RoleTool || last.ToolCallID != "call-1" {
+		t.Fatalf("expected the replayed observation last, got %+v", last)
+	}
+	var payload PlanObservationPayload
+	if err := json.Unmarshal([]byte(last.Content), &payload); err != nil {
+		t.Fatalf("decode observation: %v", err)
+	}
+	statuses := map[string]PlanStatus{}
+	for _, obs := range payload.PlanObservation {
+		statuses[obs.ID] = obs.Status
+	}
+	if statuses["a"] != PlanCompleted || statuses["b"] != PlanFailed || statuses["c"] != PlanAbandoned {
+		t.Fatalf("unexpected replayed statuses: %v", statuses)
+	}
+	if evt := <-resumed.inputs; evt.Prompt != resumePrompt {
+		t.Fatalf("expected the resume prompt, got %+v", evt)
+	}
+	if plan, _ := LoadInterruptedPlan(journalPath); plan != nil {
+		t.Fatalf("expected the journal to be consumed by resume")
+	}
+}
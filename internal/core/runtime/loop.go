@@ -30,6 +30,14 @@ func (r *Runtime) Run(ctx context.Context) error {
 		r.queueHandsFreePrompt()
 	}
 
+	if r.watcher != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.watchWorkspace(ctx)
+		}()
+	}
+
 	if !r.options.DisableInputReader && !r.options.HandsFree {
 		wg.Add(1)
 		go func() {
@@ -52,8 +60,10 @@ func (r *Runtime) Run(ctx context.Context) error {
 }
 
 func (r *Runtime) loop(ctx context.Context) error {
-	traceID := generateTraceID()
-	ctx = WithTraceID(ctx, traceID)
+	// Sub-agents keep the trace ID of the step that started them.
+	if getTraceID(ctx) == "" {
+		ctx = WithTraceID(ctx, generateTraceID())
+	}
 	r.options.Logger.Info(ctx, "Agent runtime started",
 		Field("agent_name", r.agentName),
 		Field("model", r.options.Model),
@@ -63,6 +73,34 @@ func (r *Runtime) loop(ctx context.Context) error {
 		Message: "Agent runtime started",
 		Level:   StatusLevelInfo,
 	})
+	if r.options.Environment != nil {
+		r.emit(RuntimeEvent{
+			Type:     EventTypeEnvironment,
+			Message:  "Environment detected",
+			Level:    StatusLevelInfo,
+			Metadata: map[string]any{"environment": r.options.Environment},
+		})
+	}
+	if len(r.instructions) > 0 {
+		paths := make([]string, len(r.instructions))
+		for i, file := range r.instructions {
+			paths[i] = file.Path
+		}
+		r.emit(RuntimeEvent{
+			Type:     EventTypeStatus,
+			Message:  fmt.Sprintf("Loaded project instructions from %s", strings.Join(paths, ", ")),
+			Level:    StatusLevelInfo,
+			Metadata: map[string]any{"instruction_files": r.instructions},
+		})
+	}
+	if r.memoriesLoaded > 0 {
+		r.emit(RuntimeEvent{
+			Type:     EventTypeStatus,
+			Message:  fmt.Sprintf("Loaded %d memories from %s", r.memoriesLoaded, r.memory.Path()),
+			Level:    StatusLevelInfo,
+			Metadata: map[string]any{"memories": r.memoriesLoaded},
+		})
+	}
 	if !r.options.HandsFree {
 		r.emitRequestInput("Enter a prompt to begin.")
 	}
@@ -109,8 +147,23 @@ func (r *Runtime) handleInput(ctx context.Context, evt InputEvent) error {
 			Message: fmt.Sprintf("Cancel requested: %s", strings.TrimSpace(evt.Reason)),
 			Level:   StatusLevelWarn,
 		})
+		r.setPaused(false, "Pause lifted by the cancel.")
 		r.emitRequestInput("Ready for the next instruction.")
 		return nil
+	case InputTypeCommandStdin:
+		// Queued stdin only reaches the loop when no plan is executing;
+		// hosts should prefer SubmitCommandStdin while steps run.
+		if err := r.SubmitCommandStdin(evt.StepID, evt.Data, evt.EOF); err != nil {
+			r.emit(RuntimeEvent{
+				Type:    EventTypeStatus,
+				Message: err.Error(),
+				Level:   StatusLevelWarn,
+			})
+		}
+		return nil
+	case InputTypePause, InputTypeResume:
+		r.handlePauseInput(evt)
+		return nil
 	case InputTypeShutdown:
 		r.emit(RuntimeEvent{
 			Type:    EventTypeStatus,
@@ -126,6 +179,27 @@ func (r *Runtime) handleInput(ctx context.Context, evt InputEvent) error {
 
 func (r *Runtime) handlePrompt(ctx context.Context, evt InputEvent) error {
 	prompt := strings.TrimSpace(evt.Prompt)
+	override := evt.Override
+	if parsed, rest, ok, err := parseModelOverride(prompt); err != nil {
+		r.emit(RuntimeEvent{
+			Type:    EventTypeStatus,
+			Message: fmt.Sprintf("Prompt rejected: %v", err),
+			Level:   StatusLevelWarn,
+		})
+		r.emitRequestInput("Fix the /model prefix and submit the prompt again.")
+		return nil
+	} else if ok {
+		prompt = rest
+		if parsed.Model != "" {
+			override.Model = parsed.Model
+		}
+		if parsed.ReasoningEffort != "" {
+			override.ReasoningEffort = parsed.ReasoningEffort
+		}
+	}
+	if prompt == "" && len(evt.Attachments) > 0 {
+		prompt = "See the attached files."
+	}
 	if prompt == "" {
 		r.options.Logger.Warn(ctx, "Ignoring empty prompt")
 		r.emit(RuntimeEvent{
@@ -137,6 +211,21 @@ func (r *Runtime) handlePrompt(ctx context.Context, evt InputEvent) error {
 		return nil
 	}
 
+	if strings.EqualFold(prompt, "/undo") {
+		r.handleUndo()
+		return nil
+	}
+
+	if err := validateAttachments(evt.Attachments); err != nil {
+		r.emit(RuntimeEvent{
+			Type:    EventTypeStatus,
+			Message: fmt.Sprintf("Prompt rejected: %v", err),
+			Level:   StatusLevelWarn,
+		})
+		r.emitRequestInput("Fix the attachment and submit the prompt again.")
+		return nil
+	}
+
 	if !r.beginWork() {
 		r.options.Logger.Warn(ctx, "Agent is already processing another prompt")
 		r.emit(RuntimeEvent{
@@ -146,9 +235,41 @@ func (r *Runtime) handlePrompt(ctx context.Context, evt InputEvent) error {
 		})
 		return nil
 	}
-	defer r.endWork()
+	defer func() {
+		r.endWork()
+		// Checked after the turn ends so a message posted while it wound
+		// down is not left waiting for the next prompt.
+		if ctx.Err() == nil {
+			r.resubmitMailbox()
+		}
+	}()
+
+	model := r.options.Model
+	if !override.IsZero() {
+		restore, err := r.applyModelOverride(override)
+		if err != nil {
+			r.emit(RuntimeEvent{
+				Type:    EventTypeStatus,
+				Message: fmt.Sprintf("Prompt rejected: model override failed: %v", err),
+				Level:   StatusLevelWarn,
+			})
+			r.emitRequestInput("Submit the prompt without the override or fix it.")
+			return nil
+		}
+		defer restore()
+		if m := strings.TrimSpace(override.Model); m != "" {
+			model = m
+		}
+	}
 
 	r.resetPassCount()
+	if r.budget.renew() {
+		r.emit(RuntimeEvent{
+			Type:    EventTypeStatus,
+			Message: "Continuing with a renewed budget.",
+			Level:   StatusLevelInfo,
+		})
+	}
 
 	r.options.Logger.Info(ctx, "Processing user prompt",
 		Field("prompt_length", len(prompt)),
@@ -156,35 +277,86 @@ func (r *Runtime) handlePrompt(ctx context.Context, evt InputEvent) error {
 
 	r.emit(RuntimeEvent{
 		Type:    EventTypeStatus,
-		Message: fmt.Sprintf("Processing prompt with model %s…", r.options.Model),
+		Message: fmt.Sprintf("Processing prompt with model %s…", model),
 		Level:   StatusLevelInfo,
 	})
 
-	userMessage := ChatMessage{Role: RoleUser, Content: prompt, Timestamp: time.Now()}
+	userMessage := ChatMessage{Role: RoleUser, Content: prompt, Timestamp: time.Now(), Attachments: evt.Attachments}
 	r.appendHistory(userMessage)
 
+	if effort := strings.TrimSpace(override.ReasoningEffort); effort != "" {
+		// Adaptive effort leaves an explicit effort alone.
+		ctx = WithReasoningEffort(ctx, effort)
+	}
 	r.planExecutionLoop(ctx)
+	if ctx.Err() == nil {
+		r.resubmitSteering()
+	}
 
 	return nil
 }
 
+// handleUndo reverts the most recent pass that wrote to the workspace.
+func (r *Runtime) handleUndo() {
+	changed, err := r.UndoLastPass()
+	if err != nil {
+		r.emit(RuntimeEvent{
+			Type:    EventTypeStatus,
+			Message: fmt.Sprintf("Undo failed: %v", err),
+			Level:   StatusLevelWarn,
+		})
+	} else {
+		r.emit(RuntimeEvent{
+			Type:     EventTypeStatus,
+			Message:  fmt.Sprintf("Undo restored %d file(s).", len(changed)),
+			Level:    StatusLevelInfo,
+			Metadata: map[string]any{"paths": changed},
+		})
+	}
+	r.emitRequestInput("Ready for the next instruction.")
+}
+
 // planExecutionLoop is now implemented in plan_execution.go
 
 // requestPlan centralizes the logic for requesting a new plan from the assistant.
 // It snapshots the history to guarantee a consistent view, forwards the request
-// to the OpenAI client, and emits a status update so hosts can surface that a
+// to the model provider, and emits a status update so hosts can surface that a
 // response was received.
 func (r *Runtime) requestPlan(ctx context.Context) (*PlanResponse, ToolCall, error) {
 	var retryCount int
+	var toolCalls int
 	for {
+		r.injectMailbox()
+		r.injectSteering()
+		r.injectWorkspaceChanges()
+		r.refreshRepoMap(ctx)
+		// Checked before summarizing, which is a model request too.
+		if err := r.budget.check(r.options.Budget); err != nil {
+			return nil, ToolCall{}, err
+		}
+		r.summarizeHistory(ctx)
 		history := r.planningHistorySnapshot()
 
 		r.writeHistoryLog(history)
-
+		history = r.withRepoMapMessage(history)
+
+		history = r.options.Hooks.beforePlanRequest(ctx, history)
+
+		client, _ := r.provider()
+		requestCtx, span := r.startSpan(ctx, SpanModelRequest,
+			Field("model", r.options.Model),
+			Field("provider", r.options.Provider),
+			Field("messages", len(history)),
+			Field("streaming", r.options.UseStreaming),
+		)
+		requestCtx, effort, effortReason := r.effortForRequest(requestCtx, history)
+		if effort != "" {
+			span.SetAttributes(Field("reasoning_effort", effort))
+		}
 		var toolCall ToolCall
 		var err error
 		if r.options.UseStreaming {
-			// Stream assistant response using the modern Responses API only.
+			// Stream the assistant response through the configured provider.
 			// Emit deltas as they arrive and accumulate them to emit a final
 			// consolidated message when done.
 			var finalBuilder strings.Builder
@@ -198,7 +370,12 @@ func (r *Runtime) requestPlan(ctx context.Context) (*PlanResponse, ToolCall, err
 				r.emit(RuntimeEvent{Type: EventTypeAssistantDelta, Message: s})
 			}
 
-			toolCall, err = r.client.RequestPlanStreamingResponses(ctx, history, streamFn)
+			reasoningCtx := WithReasoningHandler(requestCtx, func(s string) {
+				if s != "" {
+					r.emit(RuntimeEvent{Type: EventTypeReasoningDelta, Message: s})
+				}
+			})
+			toolCall, err = client.RequestPlanStreaming(reasoningCtx, history, streamFn)
 			// After streaming completes (no error), emit a final assistant message
 			// with the consolidated content so hosts that don't handle deltas can
 			// still present the assistant's reply.
@@ -210,13 +387,28 @@ func (r *Runtime) requestPlan(ctx context.Context) (*PlanResponse, ToolCall, err
 			}
 		} else {
 			// Non-streaming path preserves historical behavior expected by tests.
-			toolCall, err = r.client.RequestPlan(ctx, history)
+			toolCall, err = client.RequestPlan(requestCtx, history)
 		}
+		r.recordRequestUsage(history, toolCall)
 		if err != nil {
-			r.options.Logger.Error(ctx, "Failed to request plan from OpenAI", err)
+			span.RecordError(err)
+		}
+		span.SetAttributes(Field("tool", toolCall.Name))
+		span.End()
+		if err != nil {
+			r.options.Logger.Error(ctx, "Failed to request plan from provider", err)
 			return nil, ToolCall{}, fmt.Errorf("requestPlan: API request failed: %w", err)
 		}
 
+		if tool, ok := r.lookupTool(toolCall.Name); ok {
+			toolCalls++
+			if toolCalls > maxConsecutiveToolCalls {
+				return nil, ToolCall{}, fmt.Errorf("requestPlan: more than %d consecutive tool calls without a plan", maxConsecutiveToolCalls)
+			}
+			r.invokeTool(ctx, tool, toolCall)
+			continue
+		}
+
 		plan, retry, validationErr := r.validatePlanToolCall(toolCall)
 		if validationErr != nil {
 			r.options.Logger.Error(ctx, "Plan validation failed", validationErr,
@@ -235,13 +427,16 @@ func (r *Runtime) requestPlan(ctx context.Context) (*PlanResponse, ToolCall, err
 			continue
 		}
 
-		// reset unnecessary before return
-
-		r.emit(RuntimeEvent{
+		received := RuntimeEvent{
 			Type:    EventTypeStatus,
 			Message: "Assistant response received.",
 			Level:   StatusLevelInfo,
-		})
+		}
+		if effort != "" {
+			// Adaptive effort is recorded so hosts can see what each pass cost.
+			received.Metadata = map[string]any{"reasoning_effort": effort, "effort_reason": effortReason}
+		}
+		r.emit(received)
 
 		return plan, toolCall, nil
 	}
@@ -318,7 +513,7 @@ func (r *Runtime) forwardOutputs(ctx context.Context) {
 			if !ok {
 				return
 			}
-			_, _ = fmt.Fprintf(r.options.OutputWriter, "[%s] %s\n", evt.Type, evt.Message)
+			writeEvent(r.options.OutputWriter, r.options.OutputFormat, evt)
 		}
 	}
 }
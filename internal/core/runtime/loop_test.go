@@ -38,7 +38,7 @@ func TestLoopRequestsPromptWhenInteractive(t *testing.T) {
 	close(inputs)
 
 	rt := &Runtime{
-		options:   RuntimeOptions{UseStreaming: false},
+		options:   RuntimeOptions{UseStreaming: false, Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}},
 		inputs:    inputs,
 		outputs:   make(chan RuntimeEvent, 2),
 		closed:    make(chan struct{}),
@@ -75,7 +75,7 @@ func TestLoopHandsFreeSkipsInitialPromptRequest(t *testing.T) {
 	close(inputs)
 
 	rt := &Runtime{
-		options:   RuntimeOptions{HandsFree: true, UseStreaming: false},
+		options:   RuntimeOptions{HandsFree: true, UseStreaming: false, Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}},
 		inputs:    inputs,
 		outputs:   make(chan RuntimeEvent, 2),
 		closed:    make(chan struct{}),
@@ -104,6 +104,39 @@ func TestLoopHandsFreeSkipsInitialPromptRequest(t *testing.T) {
 	}
 }
 
+func TestLoopReportsEnvironmentAtStartup(t *testing.T) {
+	t.Parallel()
+
+	inputs := make(chan InputEvent)
+	close(inputs)
+
+	environment := map[string]any{"os": map[string]any{"goos": "linux"}}
+	rt := &Runtime{
+		options:   RuntimeOptions{HandsFree: true, Environment: environment, Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}},
+		inputs:    inputs,
+		outputs:   make(chan RuntimeEvent, 4),
+		closed:    make(chan struct{}),
+		agentName: "main",
+	}
+
+	if err := rt.loop(context.Background()); err != nil {
+		t.Fatalf("loop returned error: %v", err)
+	}
+
+	var found bool
+	for evt := range rt.outputs {
+		if evt.Type == EventTypeEnvironment {
+			found = true
+			if evt.Metadata["environment"] == nil {
+				t.Fatalf("expected environment metadata, got %+v", evt.Metadata)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("expected an environment event")
+	}
+}
+
 func TestPlanExecutionLoopPausesForHumanInput(t *testing.T) {
 	t.Parallel()
 
@@ -151,6 +184,8 @@ func TestPlanExecutionLoopPausesForHumanInput(t *testing.T) {
 			Model:        "gpt-4o",
 			OutputBuffer: 16,
 			OutputWriter: io.Discard,
+			Logger:       &NoOpLogger{},
+			Metrics:      &NoOpMetrics{},
 			UseStreaming: false,
 		},
 		inputs:    make(chan InputEvent, 1),
@@ -237,6 +272,8 @@ func TestPlanExecutionLoopHandsFreeCompletes(t *testing.T) {
 			Model:        "gpt-4o",
 			OutputBuffer: 16,
 			OutputWriter: io.Discard,
+			Logger:       &NoOpLogger{},
+			Metrics:      &NoOpMetrics{},
 			HandsFree:    true,
 			UseStreaming: false,
 		},
@@ -341,6 +378,8 @@ func TestPlanExecutionLoopHandsFreeStopsAtPassLimit(t *testing.T) {
 			Model:        "gpt-4o",
 			OutputBuffer: 16,
 			OutputWriter: io.Discard,
+			Logger:       &NoOpLogger{},
+			Metrics:      &NoOpMetrics{},
 			HandsFree:    true,
 			MaxPasses:    1,
 			UseStreaming: false,
@@ -418,6 +457,7 @@ func TestPlanningHistorySnapshotCompactsHistory(t *testing.T) {
 
 	now := time.Now()
 	rt := &Runtime{
+		options: RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}},
 		history: []ChatMessage{
 			{Role: RoleSystem, Content: "system", Timestamp: now},
 			{Role: RoleUser, Content: strings.Repeat("user instruction ", 80), Timestamp: now},
@@ -428,7 +468,7 @@ func TestPlanningHistorySnapshotCompactsHistory(t *testing.T) {
 	}
 
 	original := append([]ChatMessage(nil), rt.history...)
-	beforeTotal, _ := estimateHistoryTokenUsage(original)
+	beforeTotal, _ := estimateHistoryTokenUsage(nil, original)
 	if beforeTotal <= rt.contextBudget.triggerTokens() {
 		t.Fatalf("expected oversized history for compaction test")
 	}
@@ -458,7 +498,7 @@ func TestPlanningHistorySnapshotCompactsHistory(t *testing.T) {
 		t.Fatalf("expected tool summary to drop raw stdout, got %s", history[3].Content)
 	}
 
-	afterTotal, _ := estimateHistoryTokenUsage(history)
+	afterTotal, _ := estimateHistoryTokenUsage(nil, history)
 	if afterTotal > rt.contextBudget.triggerTokens() {
 		t.Fatalf("expected compacted history to be within budget, got %d tokens", afterTotal)
 	}
@@ -0,0 +1,138 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sendToAgentCommandName = "send_to_agent"
+	askParentCommandName   = "ask_parent"
+)
+
+// AgentMessage is a note passed between a parent runtime and one of its
+// sub-agents.
+type AgentMessage struct {
+	From    string
+	Content string
+	Time    time.Time
+}
+
+// mailbox buffers messages until the receiving runtime starts its next model
+// request, so they never interrupt a pass that is already running.
+type mailbox struct {
+	mu      sync.Mutex
+	pending []AgentMessage
+}
+
+func (m *mailbox) post(msg AgentMessage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending = append(m.pending, msg)
+}
+
+func (m *mailbox) drain() []AgentMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pending := m.pending
+	m.pending = nil
+	return pending
+}
+
+// deliverMessage queues a message for the model. It is added to history as a
+// user message before the next plan request.
+func (r *Runtime) deliverMessage(from, content string) {
+	r.inbox.post(AgentMessage{From: from, Content: content, Time: time.Now()})
+}
+
+// deliverOrWake queues a message like deliverMessage and, when the runtime
+// is idle, starts a turn with it so it does not wait for the next prompt.
+func (r *Runtime) deliverOrWake(from, content string) {
+	r.deliverMessage(from, content)
+	if !r.isWorking() {
+		r.resubmitMailbox()
+	}
+}
+
+// injectMailbox moves queued messages into history.
+func (r *Runtime) injectMailbox() {
+	for _, msg := range r.inbox.drain() {
+		r.appendHistory(ChatMessage{
+			Role:      RoleUser,
+			Content:   formatAgentMessage(msg),
+			Timestamp: msg.Time,
+		})
+	}
+}
+
+// resubmitMailbox turns messages that no plan request picked up into the
+// next prompt.
+func (r *Runtime) resubmitMailbox() {
+	pending := r.inbox.drain()
+	if len(pending) == 0 {
+		return
+	}
+	prompts := make([]string, 0, len(pending))
+	for _, msg := range pending {
+		prompts = append(prompts, formatAgentMessage(msg))
+	}
+	r.emit(RuntimeEvent{
+		Type:    EventTypeStatus,
+		Message: fmt.Sprintf("Starting a turn for %d message(s) from other agents.", len(pending)),
+		Level:   StatusLevelInfo,
+	})
+	go r.enqueue(InputEvent{Type: InputTypePrompt, Prompt: strings.Join(prompts, "\n\n")})
+}
+
+func formatAgentMessage(msg AgentMessage) string {
+	return fmt.Sprintf("[message from %s] %s", msg.From, msg.Content)
+}
+
+// newSendToAgentCommand handles "send_to_agent name=<agent> message=<text>".
+func newSendToAgentCommand(rt *Runtime) InternalCommandHandler {
+	return func(_ context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		name := strings.TrimSpace(argString(req, "name", ""))
+		message := strings.TrimSpace(argString(req, "message", ""))
+		if name == "" || message == "" {
+			return failAgentMessage(sendToAgentCommandName, "send_to_agent requires name=<agent> and message=<text>")
+		}
+		if err := rt.Orchestrator().Send(name, message); err != nil {
+			return failAgentMessage(sendToAgentCommandName, err.Error())
+		}
+		zero := 0
+		return PlanObservationPayload{Stdout: fmt.Sprintf("Message delivered to %s.", name), ExitCode: &zero}, nil
+	}
+}
+
+// newAskParentCommand handles "ask_parent message=<text>" inside a sub-agent.
+// The question reaches the parent's model, starting a turn when the parent
+// is idle, and its host; answers arrive later as messages in the sub-agent's
+// history.
+func newAskParentCommand(o *Orchestrator, child *Runtime) InternalCommandHandler {
+	return func(_ context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		message := strings.TrimSpace(argString(req, "message", strings.Join(positionalStrings(req), " ")))
+		if message == "" {
+			return failAgentMessage(askParentCommandName, "ask_parent requires message=<text>")
+		}
+		o.parent.deliverOrWake(child.agentName, message)
+		child.emit(RuntimeEvent{
+			Type:     EventTypeSubagentRequest,
+			Message:  message,
+			Level:    StatusLevelInfo,
+			Metadata: map[string]any{"subagent": child.agentName},
+		})
+		zero := 0
+		return PlanObservationPayload{
+			Stdout:   "Question sent to the parent agent. Keep working; the answer arrives as a message in a later turn.",
+			ExitCode: &zero,
+		}, nil
+	}
+}
+
+func failAgentMessage(command, message string) (PlanObservationPayload, error) {
+	one := 1
+	return PlanObservationPayload{Stderr: message, Details: message, ExitCode: &one}, fmt.Errorf("%s: %s", command, message)
+}
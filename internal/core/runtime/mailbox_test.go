@@ -0,0 +1,99 @@
+package runtime
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newMailboxTestRuntime(name string) *Runtime {
+	return &Runtime{
+		options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}},
+		outputs:   make(chan RuntimeEvent, 8),
+		closed:    make(chan struct{}),
+		history:   []ChatMessage{{Role: RoleSystem, Content: "system"}},
+		agentName: name,
+	}
+}
+
+func TestAskParentDeliversQuestionAndEmitsRequest(t *testing.T) {
+	t.Parallel()
+
+	parent := newMailboxTestRuntime("main")
+	// A busy parent reads the question before its next plan request.
+	parent.working = true
+	child := newMailboxTestRuntime("alpha")
+	handler := newAskParentCommand(NewOrchestrator(parent, 0), child)
+
+	payload, err := handler(context.Background(), InternalCommandRequest{
+		Name: askParentCommandName,
+		Args: map[string]any{"message": "which branch should I review?"},
+	})
+	if err != nil || payload.ExitCode == nil || *payload.ExitCode != 0 {
+		t.Fatalf("ask_parent failed: %+v %v", payload, err)
+	}
+
+	evt := <-child.outputs
+	if evt.Type != EventTypeSubagentRequest || evt.Agent != "alpha" || evt.Message != "which branch should I review?" {
+		t.Fatalf("unexpected event: %+v", evt)
+	}
+
+	parent.injectMailbox()
+	history := parent.historySnapshot()
+	last := history[len(history)-1]
+	if last.Role != RoleUser || last.Content != "[message from alpha] which branch should I review?" {
+		t.Fatalf("expected question in parent history, got %+v", last)
+	}
+	parent.injectMailbox()
+	if len(parent.historySnapshot()) != len(history) {
+		t.Fatal("expected mailbox to be drained")
+	}
+}
+
+func TestAskParentWakesAnIdleParent(t *testing.T) {
+	t.Parallel()
+
+	parent := newMailboxTestRuntime("main")
+	parent.inputs = make(chan InputEvent, 1)
+	child := newMailboxTestRuntime("alpha")
+	handler := newAskParentCommand(NewOrchestrator(parent, 0), child)
+
+	if _, err := handler(context.Background(), InternalCommandRequest{
+		Name: askParentCommandName,
+		Args: map[string]any{"message": "which branch should I review?"},
+	}); err != nil {
+		t.Fatalf("ask_parent failed: %v", err)
+	}
+
+	select {
+	case evt := <-parent.inputs:
+		if evt.Type != InputTypePrompt || evt.Prompt != "[message from alpha] which branch should I review?" {
+			t.Fatalf("unexpected wake-up input: %+v", evt)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the idle parent to start a turn")
+	}
+	if pending := parent.inbox.drain(); len(pending) != 0 {
+		t.Fatalf("expected the question to leave the mailbox, got %+v", pending)
+	}
+}
+
+func TestSendToAgentRejectsUnknownAgent(t *testing.T) {
+	t.Parallel()
+
+	parent := newMailboxTestRuntime("main")
+	handler := newSendToAgentCommand(parent)
+
+	payload, err := handler(context.Background(), InternalCommandRequest{
+		Name: sendToAgentCommandName,
+		Args: map[string]any{"name": "ghost", "message": "hello"},
+	})
+	if err == nil || payload.ExitCode == nil || *payload.ExitCode != 1 || !strings.Contains(payload.Stderr, "ghost") {
+		t.Fatalf("expected unknown agent failure, got %+v %v", payload, err)
+	}
+
+	if _, err := handler(context.Background(), InternalCommandRequest{Name: sendToAgentCommandName, Args: map[string]any{"name": "ghost"}}); err == nil {
+		t.Fatal("expected missing message to fail")
+	}
+}
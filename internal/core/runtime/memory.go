@@ -0,0 +1,287 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	rememberCommandName = "remember"
+	recallCommandName   = "recall"
+	forgetCommandName   = "forget"
+
+	defaultRecallLimit = 10
+	// defaultMemoryPromptBytes caps the memories added to the system prompt.
+	defaultMemoryPromptBytes = 4 * 1024
+	// maxMemoryValueBytes caps a single remembered value.
+	maxMemoryValueBytes = 2 * 1024
+)
+
+// Memory is a fact the agent remembered for later sessions.
+type Memory struct {
+	Key     string    `json:"key"`
+	Value   string    `json:"value"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+// MemoryStore persists memories as a JSON array. Writes replace the file
+// atomically so concurrent readers never see a partial file.
+type MemoryStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewMemoryStore returns a store backed by the JSON file at path. The file is
+// created on the first Remember.
+func NewMemoryStore(path string) *MemoryStore {
+	return &MemoryStore{path: path}
+}
+
+// Path returns the file backing the store.
+func (s *MemoryStore) Path() string {
+	return s.path
+}
+
+// All returns every memory, most recently updated first.
+func (s *MemoryStore) All() ([]Memory, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	memories, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	sortMemoriesByRecency(memories)
+	return memories, nil
+}
+
+// Remember stores value under key, replacing an existing memory with the same
+// key (compared case-insensitively).
+func (s *MemoryStore) Remember(key, value string) (Memory, error) {
+	key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+	if key == "" || value == "" {
+		return Memory{}, errors.New("remember requires a key and a value")
+	}
+	if len(value) > maxMemoryValueBytes {
+		return Memory{}, fmt.Errorf("memory values are limited to %d bytes; store a summary instead", maxMemoryValueBytes)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	memories, err := s.load()
+	if err != nil {
+		return Memory{}, err
+	}
+	now := time.Now().UTC()
+	memory := Memory{Key: key, Value: value, Created: now, Updated: now}
+	replaced := false
+	for i, existing := range memories {
+		if strings.EqualFold(existing.Key, key) {
+			memory.Created = existing.Created
+			memories[i] = memory
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		memories = append(memories, memory)
+	}
+	return memory, s.save(memories)
+}
+
+// Forget removes the memory stored under key and reports whether it existed.
+func (s *MemoryStore) Forget(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	memories, err := s.load()
+	if err != nil {
+		return false, err
+	}
+	for i, existing := range memories {
+		if strings.EqualFold(existing.Key, strings.TrimSpace(key)) {
+			return true, s.save(append(memories[:i], memories[i+1:]...))
+		}
+	}
+	return false, nil
+}
+
+// Recall returns up to limit memories ranked by how many query words appear
+// in their key (counted twice) and value, newest first among equals.
+// Memories sharing no word with the query are left out. An empty query
+// returns the newest memories.
+func (s *MemoryStore) Recall(query string, limit int) ([]Memory, error) {
+	memories, err := s.All()
+	if err != nil {
+		return nil, err
+	}
+	if terms := memoryTerms(query); len(terms) > 0 {
+		type scored struct {
+			memory Memory
+			score  int
+		}
+		var ranked []scored
+		for _, memory := range memories {
+			key, value := memoryTerms(memory.Key), memoryTerms(memory.Value)
+			score := 0
+			for term := range terms {
+				if key[term] {
+					score += 2
+				}
+				if value[term] {
+					score++
+				}
+			}
+			if score > 0 {
+				ranked = append(ranked, scored{memory: memory, score: score})
+			}
+		}
+		sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+		memories = make([]Memory, len(ranked))
+		for i, r := range ranked {
+			memories[i] = r.memory
+		}
+	}
+	if limit > 0 && len(memories) > limit {
+		memories = memories[:limit]
+	}
+	return memories, nil
+}
+
+func (s *MemoryStore) load() ([]Memory, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read memory: %w", err)
+	}
+	var memories []Memory
+	if err := json.Unmarshal(data, &memories); err != nil {
+		return nil, fmt.Errorf("parse memory %s: %w", s.path, err)
+	}
+	return memories, nil
+}
+
+func (s *MemoryStore) save(memories []Memory) error {
+	data, err := json.MarshalIndent(memories, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("write memory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write memory: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("write memory: %w", err)
+	}
+	return nil
+}
+
+func sortMemoriesByRecency(memories []Memory) {
+	sort.SliceStable(memories, func(i, j int) bool { return memories[i].Updated.After(memories[j].Updated) })
+}
+
+// memoryTerms splits text into lowercase words of two or more letters or
+// digits.
+func memoryTerms(text string) map[string]bool {
+	terms := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) > 1 {
+			terms[word] = true
+		}
+	}
+	return terms
+}
+
+// memoryPrompt renders the newest memories that fit in maxBytes for the
+// system prompt.
+func memoryPrompt(memories []Memory, maxBytes int) string {
+	if len(memories) == 0 {
+		return ""
+	}
+	if maxBytes <= 0 {
+		maxBytes = defaultMemoryPromptBytes
+	}
+	var b strings.Builder
+	b.WriteString("## Remembered from earlier sessions\n\nUse recall to search for more and forget to drop entries that are wrong.\n\n")
+	shown := 0
+	for _, memory := range memories {
+		line := fmt.Sprintf("- %s: %s\n", memory.Key, strings.Join(strings.Fields(memory.Value), " "))
+		if b.Len()+len(line) > maxBytes {
+			break
+		}
+		b.WriteString(line)
+		shown++
+	}
+	if shown == 0 {
+		return ""
+	}
+	if omitted := len(memories) - shown; omitted > 0 {
+		fmt.Fprintf(&b, "- (%d older memories; use recall)\n", omitted)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// newRememberCommand handles "remember key=<key> value=<text>".
+func newRememberCommand(store *MemoryStore) InternalCommandHandler {
+	return func(_ context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		value := argString(req, "value", strings.Join(positionalStrings(req), " "))
+		memory, err := store.Remember(argString(req, "key", ""), value)
+		if err != nil {
+			return failFileCommand(err)
+		}
+		return structuredObservation(memory)
+	}
+}
+
+// newRecallCommand handles "recall [query=<words>] [limit=N]".
+func newRecallCommand(store *MemoryStore) InternalCommandHandler {
+	return func(_ context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		query := argString(req, "query", strings.Join(positionalStrings(req), " "))
+		memories, err := store.Recall(query, argInt(req, "limit", defaultRecallLimit))
+		if err != nil {
+			return failFileCommand(err)
+		}
+		if memories == nil {
+			memories = []Memory{}
+		}
+		return structuredObservation(struct {
+			Query    string   `json:"query,omitempty"`
+			Memories []Memory `json:"memories"`
+		}{Query: strings.TrimSpace(query), Memories: memories})
+	}
+}
+
+// newForgetCommand handles "forget key=<key>".
+func newForgetCommand(store *MemoryStore) InternalCommandHandler {
+	return func(_ context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		key := argString(req, "key", strings.Join(positionalStrings(req), " "))
+		if strings.TrimSpace(key) == "" {
+			return failFileCommand(errors.New("forget requires a key"))
+		}
+		found, err := store.Forget(key)
+		if err != nil {
+			return failFileCommand(err)
+		}
+		if !found {
+			return failFileCommand(fmt.Errorf("no memory named %q", key))
+		}
+		return structuredObservation(map[string]string{"forgotten": key})
+	}
+}
@@ -0,0 +1,106 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMemoryStoreRememberRecallForget(t *testing.T) {
+	t.Parallel()
+
+	store := NewMemoryStore(filepath.Join(t.TempDir(), ".goagent", "memory.json"))
+	if memories, err := store.All(); err != nil || len(memories) != 0 {
+		t.Fatalf("expected an empty store, got %+v %v", memories, err)
+	}
+	for _, m := range [][2]string{
+		{"test-command", "Run make test-unit, not go test."},
+		{"style", "Tabs for indentation in Makefiles."},
+		{"deploy", "Never deploy on Fridays."},
+	} {
+		if _, err := store.Remember(m[0], m[1]); err != nil {
+			t.Fatalf("Remember(%s): %v", m[0], err)
+		}
+	}
+	updated, err := store.Remember("Test-Command", "Run make test-unit.")
+	if err != nil {
+		t.Fatalf("Remember: %v", err)
+	}
+	if updated.Key != "Test-Command" || updated.Created.After(updated.Updated) {
+		t.Fatalf("unexpected replacement %+v", updated)
+	}
+
+	recalled, err := store.Recall("how do I run the test suite with make", 10)
+	if err != nil {
+		t.Fatalf("Recall: %v", err)
+	}
+	// "test" and "make" match the first key and value; "makefiles" does not
+	// match "make".
+	if len(recalled) != 1 || recalled[0].Value != "Run make test-unit." {
+		t.Fatalf("unexpected recall %+v", recalled)
+	}
+	all, err := store.Recall("", 0)
+	if err != nil || len(all) != 3 || all[0].Key != "Test-Command" {
+		t.Fatalf("expected all memories newest first, got %+v %v", all, err)
+	}
+
+	if found, err := store.Forget("deploy"); err != nil || !found {
+		t.Fatalf("Forget: %v %v", found, err)
+	}
+	if found, _ := store.Forget("deploy"); found {
+		t.Fatal("expected the memory to be gone")
+	}
+	if _, err := store.Remember("empty", " "); err == nil {
+		t.Fatal("expected an empty value to be rejected")
+	}
+}
+
+func TestMemoryPromptCapsSize(t *testing.T) {
+	t.Parallel()
+
+	memories := []Memory{{Key: "a", Value: "first\nline"}, {Key: "b", Value: strings.Repeat("x", 500)}}
+	prompt := memoryPrompt(memories, 200)
+	if !strings.Contains(prompt, "- a: first line") || strings.Contains(prompt, "xxx") || !strings.Contains(prompt, "1 older memories") {
+		t.Fatalf("unexpected prompt:\n%s", prompt)
+	}
+	if memoryPrompt(nil, 0) != "" {
+		t.Fatal("expected no prompt without memories")
+	}
+}
+
+func TestRuntimeLoadsMemoriesIntoSystemPrompt(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "memory.json")
+	if _, err := NewMemoryStore(path).Remember("lint", "Run golangci-lint before committing."); err != nil {
+		t.Fatal(err)
+	}
+	historyPath := ""
+	rt, err := NewRuntime(RuntimeOptions{
+		APIKey:             "test-key",
+		HistoryLogPath:     &historyPath,
+		DisableSnapshots:   true,
+		DisableInputReader: true,
+		MemoryPath:         path,
+	})
+	if err != nil {
+		t.Fatalf("NewRuntime: %v", err)
+	}
+	if prompt := rt.historySnapshot()[0].Content; !strings.Contains(prompt, "- lint: Run golangci-lint before committing.") {
+		t.Fatalf("expected the memory in the system prompt:\n%s", prompt)
+	}
+
+	req, err := parseInternalInvocation(PlanStep{ID: "m", Command: CommandDraft{Shell: agentShell, Run: "remember key=branch value='Work on feature branches.'"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newRememberCommand(rt.memory)(context.Background(), req); err != nil {
+		t.Fatalf("remember: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(data), "Work on feature branches.") {
+		t.Fatalf("expected the memory on disk, got %s %v", data, err)
+	}
+}
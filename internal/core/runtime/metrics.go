@@ -21,6 +21,12 @@ type Metrics interface {
 	RecordPass(passNumber int)
 	// RecordDroppedEvent records that an event was dropped due to channel timeout.
 	RecordDroppedEvent(eventType string)
+	// RecordPromptCache records the input tokens of an API call and how many
+	// of them were served from the provider's prompt cache.
+	RecordPromptCache(inputTokens int, cachedTokens int)
+	// RecordPlanValidation records whether a plan response passed
+	// validation and how many responses in a row have failed it.
+	RecordPlanValidation(valid bool, consecutiveFailures int)
 	// GetSnapshot returns the current metrics snapshot.
 	GetSnapshot() MetricsSnapshot
 	// Reset clears all metrics (useful for testing).
@@ -35,8 +41,10 @@ type MetricsSnapshot struct {
 	PlanSteps          map[string]int64 // status -> count
 	TotalPasses        int64
 	DroppedEvents      int64
+	PromptCache        PromptCacheMetrics
 	LastAPICallTime    time.Time
 	LastCommandTime    time.Time
+	PlanValidation     PlanValidationMetrics
 }
 
 // APICallMetrics tracks OpenAI API call statistics.
@@ -49,6 +57,31 @@ type APICallMetrics struct {
 	MaxTime   time.Duration
 }
 
+// PromptCacheMetrics tracks prompt cache usage reported by the provider.
+type PromptCacheMetrics struct {
+	Requests     int64
+	Hits         int64 // requests with at least one cached token
+	InputTokens  int64
+	CachedTokens int64
+}
+
+// HitRate returns the share of input tokens served from the cache.
+func (m PromptCacheMetrics) HitRate() float64 {
+	if m.InputTokens == 0 {
+		return 0
+	}
+	return float64(m.CachedTokens) / float64(m.InputTokens)
+}
+
+// PlanValidationMetrics tracks plan responses rejected by validation.
+type PlanValidationMetrics struct {
+	Failures int64
+	// Consecutive is the current run of failures; MaxConsecutive the
+	// longest run seen.
+	Consecutive    int64
+	MaxConsecutive int64
+}
+
 // CommandExecutionMetrics tracks command execution statistics.
 type CommandExecutionMetrics struct {
 	Total     int64
@@ -68,6 +101,8 @@ func (n *NoOpMetrics) RecordContextCompaction(_, _ int)
 func (n *NoOpMetrics) RecordPlanStep(_ string, _ PlanStatus)                    {}
 func (n *NoOpMetrics) RecordPass(_ int)                                         {}
 func (n *NoOpMetrics) RecordDroppedEvent(_ string)                              {}
+func (n *NoOpMetrics) RecordPromptCache(_, _ int)                               {}
+func (n *NoOpMetrics) RecordPlanValidation(_ bool, _ int)                       {}
 func (n *NoOpMetrics) GetSnapshot() MetricsSnapshot                             { return MetricsSnapshot{} }
 func (n *NoOpMetrics) Reset()                                                   {}
 
@@ -80,8 +115,10 @@ type InMemoryMetrics struct {
 	planSteps          map[string]int64
 	totalPasses        int64
 	droppedEvents      int64
+	promptCache        PromptCacheMetrics
 	lastAPICallTime    time.Time
 	lastCommandTime    time.Time
+	planValidation     PlanValidationMetrics
 
 	// For tracking min/max durations
 	apiMinTime atomic.Int64 // nanoseconds
@@ -189,6 +226,28 @@ func (m *InMemoryMetrics) RecordDroppedEvent(eventType string) {
 	atomic.AddInt64(&m.droppedEvents, 1)
 }
 
+func (m *InMemoryMetrics) RecordPromptCache(inputTokens, cachedTokens int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.promptCache.Requests++
+	if cachedTokens > 0 {
+		m.promptCache.Hits++
+	}
+	m.promptCache.InputTokens += int64(inputTokens)
+	m.promptCache.CachedTokens += int64(cachedTokens)
+}
+
+func (m *InMemoryMetrics) RecordPlanValidation(valid bool, consecutiveFailures int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.planValidation.Consecutive = int64(consecutiveFailures)
+	if valid {
+		return
+	}
+	m.planValidation.Failures++
+	m.planValidation.MaxConsecutive = max(m.planValidation.MaxConsecutive, m.planValidation.Consecutive)
+}
+
 func (m *InMemoryMetrics) GetSnapshot() MetricsSnapshot {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -200,8 +259,10 @@ func (m *InMemoryMetrics) GetSnapshot() MetricsSnapshot {
 		PlanSteps:          make(map[string]int64),
 		TotalPasses:        atomic.LoadInt64(&m.totalPasses),
 		DroppedEvents:      atomic.LoadInt64(&m.droppedEvents),
+		PromptCache:        m.promptCache,
 		LastAPICallTime:    m.lastAPICallTime,
 		LastCommandTime:    m.lastCommandTime,
+		PlanValidation:     m.planValidation,
 	}
 
 	// Copy plan steps map
@@ -238,8 +299,10 @@ func (m *InMemoryMetrics) Reset() {
 	m.planSteps = make(map[string]int64)
 	atomic.StoreInt64(&m.totalPasses, 0)
 	atomic.StoreInt64(&m.droppedEvents, 0)
+	m.promptCache = PromptCacheMetrics{}
 	m.lastAPICallTime = time.Time{}
 	m.lastCommandTime = time.Time{}
+	m.planValidation = PlanValidationMetrics{}
 	m.apiMinTime.Store(int64(time.Hour))
 	m.apiMaxTime.Store(0)
 	m.cmdMinTime.Store(int64(time.Hour))
@@ -0,0 +1,164 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fallbackCooldown is how long requests keep going to a fallback model
+// before the primary is tried again.
+const fallbackCooldown = 5 * time.Minute
+
+// fallbackNotice describes a failover to the next model.
+type fallbackNotice struct {
+	From string
+	To   string
+	Err  error
+}
+
+// fallbackProvider sends requests to the first model that answers. Once a
+// fallback answered, later requests start there until fallbackCooldown has
+// passed, so an outage does not cost a full retry cycle per request.
+type fallbackProvider struct {
+	names     []string
+	providers []Provider
+	onSwitch  func(fallbackNotice)
+	now       func() time.Time
+
+	mu     sync.Mutex
+	active int
+	since  time.Time
+}
+
+// withFallbacks wraps primary with the providers for options.FallbackModels
+// and options.Fallbacks. It returns primary unchanged when none are set.
+func withFallbacks(options RuntimeOptions, primary Provider, httpTimeout time.Duration, onSwitch func(fallbackNotice)) (Provider, error) {
+	settings := options.fallbackSettings()
+	if len(settings) == 0 {
+		return primary, nil
+	}
+	f := &fallbackProvider{
+		names:     []string{modelLabel(options.Model, options.Provider)},
+		providers: []Provider{primary},
+		onSwitch:  onSwitch,
+		now:       time.Now,
+	}
+	for _, s := range settings {
+		fallback := options
+		fallback.Provider = s.Provider
+		fallback.Model = s.Model
+		fallback.APIKey = s.APIKey
+		fallback.APIBaseURL = s.APIBaseURL
+		fallback.ReasoningEffort = s.ReasoningEffort
+		fallback.AzureDeployment = s.AzureDeployment
+		fallback.AzureAPIVersion = s.AzureAPIVersion
+		client, err := newProvider(fallback, httpTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("fallback model %s: %w", s.Model, err)
+		}
+		f.names = append(f.names, modelLabel(s.Model, s.Provider))
+		f.providers = append(f.providers, client)
+	}
+	return f, nil
+}
+
+// fallbackSettings resolves FallbackModels and Fallbacks into complete
+// settings. Empty fields inherit the primary model's; the API key and base
+// URL only for the same provider.
+func (o RuntimeOptions) fallbackSettings() []ModelSettings {
+	var settings []ModelSettings
+	for _, model := range o.FallbackModels {
+		if model = strings.TrimSpace(model); model != "" && model != o.Model {
+			settings = append(settings, ModelSettings{Model: model})
+		}
+	}
+	settings = append(settings, o.Fallbacks...)
+	for i, s := range settings {
+		if s.Provider == "" {
+			s.Provider = o.Provider
+		}
+		if s.Provider == o.Provider {
+			if s.APIKey == "" {
+				s.APIKey = o.APIKey
+			}
+			if s.APIBaseURL == "" {
+				s.APIBaseURL = o.APIBaseURL
+			}
+		}
+		if s.ReasoningEffort == "" {
+			s.ReasoningEffort = o.ReasoningEffort
+		}
+		settings[i] = s
+	}
+	return settings
+}
+
+// emitFallback reports a failover as a warning status event.
+func (r *Runtime) emitFallback(notice fallbackNotice) {
+	r.emit(RuntimeEvent{
+		Type:     EventTypeStatus,
+		Message:  fmt.Sprintf("Model %s failed (%v); switching to %s", notice.From, notice.Err, notice.To),
+		Level:    StatusLevelWarn,
+		Metadata: map[string]any{"from_model": notice.From, "to_model": notice.To, "error": notice.Err.Error()},
+	})
+}
+
+func modelLabel(model, provider string) string {
+	if provider == "" {
+		return model
+	}
+	return fmt.Sprintf("%s (%s)", model, provider)
+}
+
+func (f *fallbackProvider) RequestPlan(ctx context.Context, history []ChatMessage) (ToolCall, error) {
+	return f.do(ctx, func(p Provider) (ToolCall, error) { return p.RequestPlan(ctx, history) })
+}
+
+func (f *fallbackProvider) RequestPlanStreaming(ctx context.Context, history []ChatMessage, onDelta func(string)) (ToolCall, error) {
+	return f.do(ctx, func(p Provider) (ToolCall, error) { return p.RequestPlanStreaming(ctx, history, onDelta) })
+}
+
+// do tries the providers in order starting at the active one, wrapping
+// around to those before it. Each provider applies its own retries first.
+func (f *fallbackProvider) do(ctx context.Context, call func(Provider) (ToolCall, error)) (ToolCall, error) {
+	start := f.start()
+	var errs []error
+	for n := range len(f.providers) {
+		i := (start + n) % len(f.providers)
+		toolCall, err := call(f.providers[i])
+		if err == nil {
+			f.setActive(i)
+			return toolCall, nil
+		}
+		if ctx.Err() != nil {
+			return ToolCall{}, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", f.names[i], err))
+		if n+1 < len(f.providers) && f.onSwitch != nil {
+			f.onSwitch(fallbackNotice{From: f.names[i], To: f.names[(i+1)%len(f.providers)], Err: err})
+		}
+	}
+	return ToolCall{}, fmt.Errorf("all models failed: %w", errors.Join(errs...))
+}
+
+func (f *fallbackProvider) start() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.active != 0 && f.now().Sub(f.since) >= fallbackCooldown {
+		f.active = 0
+	}
+	return f.active
+}
+
+func (f *fallbackProvider) setActive(i int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if i != f.active {
+		f.active = i
+		f.since = f.now()
+	}
+}
@@ -0,0 +1,108 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingProvider fails its first failures requests, then answers.
+type failingProvider struct {
+	name     string
+	failures int
+	requests int
+}
+
+func (p *failingProvider) RequestPlan(_ context.Context, _ []ChatMessage) (ToolCall, error) {
+	p.requests++
+	if p.requests <= p.failures {
+		return ToolCall{}, errors.New(p.name + " unavailable")
+	}
+	return ToolCall{ID: p.name}, nil
+}
+
+func (p *failingProvider) RequestPlanStreaming(ctx context.Context, history []ChatMessage, _ func(string)) (ToolCall, error) {
+	return p.RequestPlan(ctx, history)
+}
+
+func TestFallbackProviderSwitchesAndReturnsToPrimary(t *testing.T) {
+	t.Parallel()
+
+	primary := &failingProvider{name: "primary", failures: 1}
+	backup := &failingProvider{name: "backup"}
+	now := time.Unix(0, 0)
+	var notices []fallbackNotice
+	f := &fallbackProvider{
+		names:     []string{"primary", "backup"},
+		providers: []Provider{primary, backup},
+		onSwitch:  func(n fallbackNotice) { notices = append(notices, n) },
+		now:       func() time.Time { return now },
+	}
+
+	call, err := f.RequestPlan(context.Background(), nil)
+	if err != nil || call.ID != "backup" {
+		t.Fatalf("expected the backup to answer, got %+v %v", call, err)
+	}
+	if len(notices) != 1 || notices[0].From != "primary" || notices[0].To != "backup" || !strings.Contains(notices[0].Err.Error(), "primary unavailable") {
+		t.Fatalf("unexpected notices %+v", notices)
+	}
+
+	// The backup stays active until the cooldown has passed.
+	if call, _ := f.RequestPlanStreaming(context.Background(), nil, nil); call.ID != "backup" || primary.requests != 1 {
+		t.Fatalf("expected the backup to stay active, got %+v after %d primary requests", call, primary.requests)
+	}
+	now = now.Add(fallbackCooldown)
+	if call, _ := f.RequestPlan(context.Background(), nil); call.ID != "primary" {
+		t.Fatalf("expected the primary after the cooldown, got %+v", call)
+	}
+
+	primary.failures, backup.failures = 100, 100
+	if _, err := f.RequestPlan(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "all models failed") || !strings.Contains(err.Error(), "backup unavailable") {
+		t.Fatalf("expected both errors, got %v", err)
+	}
+}
+
+func TestFallbackSettingsInheritFromPrimary(t *testing.T) {
+	t.Parallel()
+
+	options := RuntimeOptions{
+		Provider:        ProviderOpenAI,
+		Model:           "gpt-5",
+		APIKey:          "openai-key",
+		APIBaseURL:      "https://gateway.example/v1",
+		ReasoningEffort: "high",
+		FallbackModels:  []string{"gpt-5-mini", " ", "gpt-5"},
+		Fallbacks:       []ModelSettings{{Provider: ProviderAnthropic, Model: "claude-sonnet-4-5", APIKey: "anthropic-key"}},
+	}
+	got := options.fallbackSettings()
+	want := []ModelSettings{
+		{Provider: ProviderOpenAI, Model: "gpt-5-mini", APIKey: "openai-key", APIBaseURL: "https://gateway.example/v1", ReasoningEffort: "high"},
+		{Provider: ProviderAnthropic, Model: "claude-sonnet-4-5", APIKey: "anthropic-key", ReasoningEffort: "high"},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("unexpected settings %+v", got)
+	}
+}
+
+func TestNewRuntimeWrapsFallbackModels(t *testing.T) {
+	t.Parallel()
+
+	historyPath := ""
+	rt, err := NewRuntime(RuntimeOptions{
+		APIKey:             "test-key",
+		HistoryLogPath:     &historyPath,
+		DisableSnapshots:   true,
+		DisableInputReader: true,
+		FallbackModels:     []string{"fallback-model"},
+	})
+	if err != nil {
+		t.Fatalf("NewRuntime: %v", err)
+	}
+	client, summary := rt.provider()
+	f, ok := client.(*fallbackProvider)
+	if !ok || len(f.providers) != 2 || summary != client {
+		t.Fatalf("expected a fallback chain for plans and summaries, got %T", client)
+	}
+}
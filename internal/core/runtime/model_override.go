@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// modelOverridePrefix starts a prompt that runs on another model, e.g.
+// "/model o3 high: refactor this".
+const modelOverridePrefix = "/model"
+
+// ModelOverride selects the model or reasoning effort for a single prompt.
+// Empty fields keep the session's settings. The provider, API key and base
+// URL are always the session's.
+type ModelOverride struct {
+	Model           string
+	ReasoningEffort string
+}
+
+// IsZero reports whether the override changes nothing.
+func (o ModelOverride) IsZero() bool {
+	return strings.TrimSpace(o.Model) == "" && strings.TrimSpace(o.ReasoningEffort) == ""
+}
+
+// SubmitPromptWithOverride enqueues a prompt whose turn runs with override
+// instead of the session's model. While the agent works, the prompt steers
+// the running turn and the override is ignored.
+func (r *Runtime) SubmitPromptWithOverride(prompt string, attachments []Attachment, override ModelOverride) {
+	if r.steer(prompt, attachments) {
+		return
+	}
+	r.enqueue(InputEvent{Type: InputTypePrompt, Prompt: prompt, Attachments: attachments, Override: override})
+}
+
+// parseModelOverride splits a "/model <model> [effort]: <prompt>" prefix off
+// prompt. A lone effort such as "/model high: ..." keeps the model. ok is
+// false when prompt has no prefix.
+func parseModelOverride(prompt string) (override ModelOverride, rest string, ok bool, err error) {
+	after, found := strings.CutPrefix(prompt, modelOverridePrefix)
+	if !found || (after != "" && after[0] != ' ') {
+		return ModelOverride{}, prompt, false, nil
+	}
+	spec, rest, found := strings.Cut(after, ":")
+	fields := strings.Fields(spec)
+	if !found || len(fields) == 0 || len(fields) > 2 {
+		return ModelOverride{}, prompt, true, errors.New("usage: /model <model> [minimal|low|medium|high]: <prompt>")
+	}
+	switch {
+	case len(fields) == 2:
+		override.Model = fields[0]
+		override.ReasoningEffort = strings.ToLower(fields[1])
+		if !slices.Contains(reasoningEffortLevels, override.ReasoningEffort) {
+			return ModelOverride{}, prompt, true, fmt.Errorf("unknown reasoning effort %q (want minimal, low, medium or high)", fields[1])
+		}
+	case slices.Contains(reasoningEffortLevels, strings.ToLower(fields[0])):
+		override.ReasoningEffort = strings.ToLower(fields[0])
+	default:
+		override.Model = fields[0]
+	}
+	return override, strings.TrimSpace(rest), true, nil
+}
+
+// applyModelOverride points plan requests at a client built for override
+// and returns the function that restores the session's client. A model
+// switch during the turn is kept by the restore.
+func (r *Runtime) applyModelOverride(override ModelOverride) (func(), error) {
+	r.clientMu.RLock()
+	options := r.clientOptions
+	previous := r.client
+	r.clientMu.RUnlock()
+	if options.ProviderClient != nil {
+		return nil, errors.New("model overrides need a built-in provider")
+	}
+	if model := strings.TrimSpace(override.Model); model != "" {
+		options.Model = model
+	}
+	if effort := strings.TrimSpace(override.ReasoningEffort); effort != "" {
+		options.ReasoningEffort = effort
+	}
+
+	httpTimeout := options.HTTPTimeout
+	if httpTimeout == 0 {
+		httpTimeout = defaultHTTPTimeout
+	}
+	client, err := newProvider(options, httpTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	r.clientMu.Lock()
+	r.client = client
+	r.clientMu.Unlock()
+
+	message := fmt.Sprintf("Using %s for this request", options.Model)
+	if options.ReasoningEffort != "" {
+		message += fmt.Sprintf(" (reasoning effort %s)", options.ReasoningEffort)
+	}
+	r.emit(RuntimeEvent{
+		Type:    EventTypeStatus,
+		Message: message + ".",
+		Level:   StatusLevelInfo,
+		Metadata: map[string]any{
+			"model":            options.Model,
+			"reasoning_effort": options.ReasoningEffort,
+			"override":         true,
+		},
+	})
+
+	return func() {
+		r.clientMu.Lock()
+		defer r.clientMu.Unlock()
+		if r.client == client {
+			r.client = previous
+		}
+	}, nil
+}
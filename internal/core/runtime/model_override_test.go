@@ -0,0 +1,76 @@
+package runtime
+
+import "testing"
+
+func TestParseModelOverride(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		prompt   string
+		override ModelOverride
+		rest     string
+		ok       bool
+		err      bool
+	}{
+		{prompt: "refactor this", rest: "refactor this"},
+		{prompt: "/modelling is fun", rest: "/modelling is fun"},
+		{prompt: "/model o3 high: refactor this", override: ModelOverride{Model: "o3", ReasoningEffort: "high"}, rest: "refactor this", ok: true},
+		{prompt: "/model gpt-4.1-mini: list files", override: ModelOverride{Model: "gpt-4.1-mini"}, rest: "list files", ok: true},
+		{prompt: "/model High: think: hard", override: ModelOverride{ReasoningEffort: "high"}, rest: "think: hard", ok: true},
+		{prompt: "/model o3 loud: x", ok: true, err: true},
+		{prompt: "/model o3 refactor this", ok: true, err: true},
+	}
+	for _, tc := range cases {
+		override, rest, ok, err := parseModelOverride(tc.prompt)
+		if (err != nil) != tc.err || ok != tc.ok {
+			t.Fatalf("parseModelOverride(%q): ok=%v err=%v", tc.prompt, ok, err)
+		}
+		if tc.err {
+			continue
+		}
+		if override != tc.override || rest != tc.rest {
+			t.Fatalf("parseModelOverride(%q) = %+v, %q; want %+v, %q", tc.prompt, override, rest, tc.override, tc.rest)
+		}
+	}
+}
+
+func TestApplyModelOverrideRestoresClient(t *testing.T) {
+	t.Parallel()
+
+	options := RuntimeOptions{Provider: ProviderOpenAI, APIKey: "test-key", Model: "gpt-4.1", ReasoningEffort: "low", Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}}
+	session, err := NewOpenAIClient(options.APIKey, options.Model, options.ReasoningEffort, "", options.Logger, options.Metrics, nil, 0)
+	if err != nil {
+		t.Fatalf("NewOpenAIClient returned error: %v", err)
+	}
+	rt := &Runtime{
+		options:       options,
+		client:        session,
+		clientOptions: options,
+		outputs:       make(chan RuntimeEvent, 4),
+		closed:        make(chan struct{}),
+		agentName:     "main",
+	}
+
+	restore, err := rt.applyModelOverride(ModelOverride{Model: "o3", ReasoningEffort: "high"})
+	if err != nil {
+		t.Fatalf("applyModelOverride returned error: %v", err)
+	}
+	client, _ := rt.provider()
+	override, ok := client.(*OpenAIClient)
+	if !ok || override.model != "o3" || override.reasoningEffort != "high" || override.apiKey != "test-key" {
+		t.Fatalf("expected an o3 client with high effort, got %+v", client)
+	}
+	if evt := <-rt.outputs; evt.Metadata["override"] != true || evt.Metadata["model"] != "o3" {
+		t.Fatalf("expected an override status event, got %+v", evt)
+	}
+
+	restore()
+	if client, _ := rt.provider(); client != session {
+		t.Fatal("expected the session client to be restored")
+	}
+
+	rt.clientOptions.ProviderClient = session
+	if _, err := rt.applyModelOverride(ModelOverride{Model: "o3"}); err == nil {
+		t.Fatal("expected an error for a host-supplied provider")
+	}
+}
@@ -0,0 +1,75 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ModelSettings selects the backend used for plan requests. Empty fields use
+// the same defaults as the matching RuntimeOptions fields.
+type ModelSettings struct {
+	Provider        string
+	APIKey          string
+	APIBaseURL      string
+	Model           string
+	ReasoningEffort string
+	AzureDeployment string
+	AzureAPIVersion string
+}
+
+// SwitchModel replaces the provider used for the next plan requests, for
+// example when the user picks another profile. A request already in flight
+// finishes on the previous provider; history is kept.
+func (r *Runtime) SwitchModel(settings ModelSettings) error {
+	if strings.TrimSpace(settings.APIKey) == "" {
+		return errors.New("runtime: switch model: API key is required")
+	}
+	options := r.options
+	options.Provider = settings.Provider
+	options.APIKey = settings.APIKey
+	options.APIBaseURL = settings.APIBaseURL
+	options.Model = strings.TrimSpace(settings.Model)
+	options.ReasoningEffort = settings.ReasoningEffort
+	options.AzureDeployment = settings.AzureDeployment
+	options.AzureAPIVersion = settings.AzureAPIVersion
+	options.ProviderClient = nil
+	options.setDefaults()
+
+	httpTimeout := options.HTTPTimeout
+	if httpTimeout == 0 {
+		httpTimeout = defaultHTTPTimeout
+	}
+	client, err := newProvider(options, httpTimeout)
+	if err != nil {
+		return fmt.Errorf("runtime: switch model: %w", err)
+	}
+	client, err = withFallbacks(options, client, httpTimeout, r.emitFallback)
+	if err != nil {
+		return fmt.Errorf("runtime: switch model: %w", err)
+	}
+
+	r.clientMu.Lock()
+	if r.summaryClient == r.client {
+		// Summaries follow the main model unless a compaction model is set.
+		r.summaryClient = client
+	}
+	r.client = client
+	r.clientOptions = options
+	r.clientMu.Unlock()
+
+	r.emit(RuntimeEvent{
+		Type:     EventTypeStatus,
+		Message:  fmt.Sprintf("Switched to %s (%s)", options.Model, options.Provider),
+		Level:    StatusLevelInfo,
+		Metadata: map[string]any{"model": options.Model, "provider": options.Provider},
+	})
+	return nil
+}
+
+// provider returns the clients for plan and summary requests.
+func (r *Runtime) provider() (client, summary Provider) {
+	r.clientMu.RLock()
+	defer r.clientMu.RUnlock()
+	return r.client, r.summaryClient
+}
@@ -0,0 +1,36 @@
+package runtime
+
+import "testing"
+
+func TestSwitchModelReplacesProvider(t *testing.T) {
+	t.Parallel()
+
+	previous := &scriptedProvider{}
+	rt := &Runtime{
+		options:       RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}},
+		outputs:       make(chan RuntimeEvent, 4),
+		closed:        make(chan struct{}),
+		client:        previous,
+		summaryClient: previous,
+		agentName:     "main",
+	}
+
+	if err := rt.SwitchModel(ModelSettings{Model: "claude-sonnet"}); err == nil {
+		t.Fatal("expected missing API key to be rejected")
+	}
+	if err := rt.SwitchModel(ModelSettings{APIKey: "key", Model: "claude-sonnet"}); err != nil {
+		t.Fatalf("SwitchModel: %v", err)
+	}
+
+	client, summary := rt.provider()
+	anthropic, ok := client.(*AnthropicClient)
+	if !ok || anthropic.model != "claude-sonnet" {
+		t.Fatalf("expected Anthropic client for claude model, got %T", client)
+	}
+	if summary != client {
+		t.Fatal("expected summaries to follow the switched model")
+	}
+	if evt := <-rt.outputs; evt.Metadata["provider"] != ProviderAnthropic {
+		t.Fatalf("unexpected switch event: %+v", evt)
+	}
+}
@@ -6,9 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/asynkron/goagent/internal/core/schema"
@@ -19,15 +21,40 @@ type OpenAIClient struct {
 	apiKey          string
 	model           string
 	reasoningEffort string
-	httpClient      *http.Client
-	tool            schema.ToolDefinition
-	baseURL         string
-	logger          Logger
-	metrics         Metrics
-	retryConfig     *RetryConfig
+	// reasoningSummary requests reasoning summaries ("auto", "concise" or
+	// "detailed") along with the effort.
+	reasoningSummary string
+	httpClient       *http.Client
+	tool             schema.ToolDefinition
+	extraTools       []schema.ToolDefinition
+	baseURL          string
+	logger           Logger
+	metrics          Metrics
+	retryConfig      *RetryConfig
+
+	// azureDeployment and azureAPIVersion switch the client to Azure OpenAI
+	// request shapes when azureDeployment is non-empty.
+	azureDeployment string
+	azureAPIVersion string
+
+	// promptCacheKey overrides the prompt_cache_key derived from the stable
+	// history prefix.
+	promptCacheKey string
+
+	// backgroundMode runs responses in the background so dropped streams
+	// can be resumed.
+	backgroundMode bool
+
+	// structuredOutput requests the plan as a json_schema structured output
+	// instead of a tool call. It is switched on automatically when the
+	// endpoint rejects function tools.
+	structuredOutput atomic.Bool
 }
 
-const defaultOpenAIBaseURL = "https://api.openai.com/v1"
+const (
+	defaultOpenAIBaseURL   = "https://api.openai.com/v1"
+	defaultAzureAPIVersion = "2025-04-01-preview"
+)
 
 // NewOpenAIClient configures the client with the provided API key and model identifier.
 func NewOpenAIClient(apiKey, model, reasoningEffort, baseURL string, logger Logger, metrics Metrics, retryConfig *RetryConfig, httpTimeout time.Duration) (*OpenAIClient, error) {
@@ -66,6 +93,83 @@ func NewOpenAIClient(apiKey, model, reasoningEffort, baseURL string, logger Logg
 	}, nil
 }
 
+// AddTools advertises additional function tools next to the plan tool.
+func (c *OpenAIClient) AddTools(tools ...schema.ToolDefinition) {
+	c.extraTools = append(c.extraTools, tools...)
+}
+
+// cacheKeyFor returns the prompt_cache_key for a request. Azure deployments
+// cache without it, so none is sent there.
+func (c *OpenAIClient) cacheKeyFor(history []ChatMessage) string {
+	if c.azureDeployment != "" {
+		return ""
+	}
+	if c.promptCacheKey != "" {
+		return c.promptCacheKey
+	}
+	return promptCacheKey(c.model, history)
+}
+
+// SetAzureDeployment routes requests to an Azure OpenAI deployment. The base
+// URL should be the resource endpoint (https://<name>.openai.azure.com);
+// requests go to /openai/deployments/<deployment>/responses with the
+// api-version query parameter and authenticate with the api-key header.
+// An empty apiVersion selects a default preview version.
+func (c *OpenAIClient) SetAzureDeployment(deployment, apiVersion string) {
+	c.azureDeployment = strings.TrimSpace(deployment)
+	c.azureAPIVersion = strings.TrimSpace(apiVersion)
+	if c.azureAPIVersion == "" {
+		c.azureAPIVersion = defaultAzureAPIVersion
+	}
+}
+
+// SetPromptCacheKey sets the prompt_cache_key sent with every request. An
+// empty key derives one from the messages marked Stable.
+func (c *OpenAIClient) SetPromptCacheKey(key string) {
+	c.promptCacheKey = sanitizePromptCacheKey(key)
+}
+
+// SetReasoningSummary requests a reasoning summary of the given detail
+// ("auto", "concise" or "detailed"); empty disables it.
+func (c *OpenAIClient) SetReasoningSummary(summary string) {
+	c.reasoningSummary = strings.ToLower(strings.TrimSpace(summary))
+}
+
+// SetBackgroundMode starts responses with background=true (which requires
+// store=true) so a stream that drops mid-way is resumed from the last event
+// instead of being requested again.
+func (c *OpenAIClient) SetBackgroundMode(enabled bool) {
+	c.backgroundMode = enabled
+}
+
+// SetStructuredOutput requests the plan as a json_schema structured output
+// rather than a forced tool call, for OpenAI-compatible gateways without
+// function tools. Host tools are not offered in this mode.
+func (c *OpenAIClient) SetStructuredOutput(enabled bool) {
+	c.structuredOutput.Store(enabled)
+}
+
+// responsesURL builds the Responses endpoint for either OpenAI or Azure.
+func (c *OpenAIClient) responsesURL() string {
+	apiRoot := strings.TrimRight(c.baseURL, "/")
+	if c.azureDeployment == "" {
+		return apiRoot + "/responses"
+	}
+	if !strings.HasSuffix(apiRoot, "/openai") {
+		apiRoot += "/openai"
+	}
+	return apiRoot + "/deployments/" + url.PathEscape(c.azureDeployment) + "/responses?api-version=" + url.QueryEscape(c.azureAPIVersion)
+}
+
+// setAuthHeader applies the bearer token for OpenAI or the api-key header for Azure.
+func (c *OpenAIClient) setAuthHeader(req *http.Request) {
+	if c.azureDeployment != "" {
+		req.Header.Set("api-key", c.apiKey)
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+}
+
 // RequestPlan sends the accumulated chat history to OpenAI and returns the
 // resulting tool call payload so the runtime can perform validation before
 // decoding it.
@@ -74,11 +178,17 @@ func (c *OpenAIClient) RequestPlan(ctx context.Context, history []ChatMessage) (
 	return c.RequestPlanStreamingResponses(ctx, history, nil)
 }
 
+// RequestPlanStreaming implements Provider using the Responses API stream.
+func (c *OpenAIClient) RequestPlanStreaming(ctx context.Context, history []ChatMessage, onDelta func(string)) (ToolCall, error) {
+	return c.RequestPlanStreamingResponses(ctx, history, onDelta)
+}
+
 // Chat Completions helpers, types, and streaming have been removed.
 
 // RequestPlanStreamingResponses streams using the modern OpenAI Responses API.
-// It maps response.output_text.delta chunks to the onDelta callback and collects
-// function_call deltas into a ToolCall to return on completion.
+// It maps response.output_text.delta chunks to the onDelta callback, reasoning
+// to the ReasoningHandler of ctx, and collects function_call deltas into a
+// ToolCall to return on completion.
 func (c *OpenAIClient) RequestPlanStreamingResponses(ctx context.Context, history []ChatMessage, onDelta func(string)) (ToolCall, error) {
 	start := time.Now()
 	c.logger.Debug(ctx, "Requesting plan from OpenAI",
@@ -93,8 +203,14 @@ func (c *OpenAIClient) RequestPlanStreamingResponses(ctx context.Context, histor
 	}
 
 	// Build request
+	history = orderForPromptCache(history)
 	inputMsgs := buildMessagesFromHistory(history)
-	payload, err := c.buildRequestBody(inputMsgs)
+	structured := c.structuredOutput.Load()
+	effort := c.reasoningEffort
+	if e := ReasoningEffortFrom(ctx); e != "" {
+		effort = e
+	}
+	payload, err := c.buildRequestBody(inputMsgs, c.cacheKeyFor(history), effort, structured)
 	if err != nil {
 		c.logger.Error(ctx, "Failed to build OpenAI request body", err,
 			Field("model", c.model),
@@ -103,37 +219,99 @@ func (c *OpenAIClient) RequestPlanStreamingResponses(ctx context.Context, histor
 		return ToolCall{}, fmt.Errorf("openai: build request body: %w", err)
 	}
 
-	// Execute request with retry logic
-	resp, err := c.executeRequest(ctx, payload, start, c.retryConfig)
-	if err != nil {
-		return ToolCall{}, fmt.Errorf("openai: request failed after retries: %w", err)
+	// Send the request and parse the stream, retrying transient failures.
+	// A stream that drops mid-way is resumed in background mode or
+	// requested again, with already emitted deltas suppressed.
+	var deduper, reasoningDeduper *deltaDeduper
+	if onDelta != nil {
+		deduper = newDeltaDeduper(onDelta)
+		onDelta = deduper.forward
+	}
+	onReasoning := ReasoningHandler(ctx)
+	if onReasoning != nil {
+		reasoningDeduper = newDeltaDeduper(onReasoning)
+		onReasoning = reasoningDeduper.forward
 	}
-	defer func() { _ = resp.Body.Close() }()
+	var toolCall ToolCall
+	var parser *streamParser
+	err = executeWithRetry(ctx, c.retryConfig, func() error {
+		var resp *http.Response
+		var err error
+		if c.canResume(parser) {
+			resp, err = c.resumeStream(ctx, parser, start)
+			if err != nil {
+				return err
+			}
+			parser.reader = bufio.NewReader(resp.Body)
+		} else {
+			resp, err = c.executeRequest(ctx, payload, start)
+			if err != nil && !structured && toolsRejected(err) {
+				// Fall back to structured output for the rest of the session.
+				c.logger.Warn(ctx, "Endpoint rejected function tools; switching to json_schema structured output",
+					Field("error", err.Error()),
+				)
+				c.structuredOutput.Store(true)
+				structured = true
+				if payload, err = c.buildRequestBody(inputMsgs, c.cacheKeyFor(history), effort, true); err != nil {
+					return fmt.Errorf("openai: build request body: %w", err)
+				}
+				resp, err = c.executeRequest(ctx, payload, start)
+			}
+			if err != nil {
+				return err
+			}
+			if deduper != nil {
+				deduper.restart()
+			}
+			if reasoningDeduper != nil {
+				reasoningDeduper.restart()
+			}
+			parser = newStreamParser(bufio.NewReader(resp.Body), onDelta, debugStream)
+			parser.onReasoning = onReasoning
+			parser.structured = structured
+		}
+		defer func() { _ = resp.Body.Close() }()
 
-	// Parse stream
-	reader := bufio.NewReader(resp.Body)
-	parser := newStreamParser(reader, onDelta, debugStream)
-	toolCall, err := parser.parse()
+		toolCall, err = parser.parse()
+		if err == nil && parser.events > 0 && !parser.completed {
+			err = errors.New("openai(responses): stream ended before the response completed")
+		}
+		if err != nil {
+			c.logger.Error(ctx, "OpenAI API stream parsing failed", err,
+				Field("duration_ms", time.Since(start).Milliseconds()),
+				Field("model", c.model),
+				Field("response_id", parser.responseID),
+			)
+			return &retryableAPIError{
+				err:       fmt.Errorf("openai: stream parsing failed: %w", err),
+				retryable: true,
+			}
+		}
+		return nil
+	})
 
 	// Record metrics
 	duration := time.Since(start)
 	if err != nil {
 		c.metrics.RecordAPICall(duration, false)
-		c.logger.Error(ctx, "OpenAI API stream parsing failed", err,
-			Field("duration_ms", duration.Milliseconds()),
-			Field("model", c.model),
+		return ToolCall{}, fmt.Errorf("openai: request failed: %w", err)
+	}
+	c.metrics.RecordAPICall(duration, true)
+	if parser.hasUsage {
+		c.metrics.RecordPromptCache(parser.usage.InputTokens, parser.usage.CachedTokens)
+		c.logger.Debug(ctx, "OpenAI token usage",
+			Field("input_tokens", parser.usage.InputTokens),
+			Field("cached_tokens", parser.usage.CachedTokens),
+			Field("output_tokens", parser.usage.OutputTokens),
 		)
-		return ToolCall{}, fmt.Errorf("openai: stream parsing failed: %w", err)
 	}
 
 	if toolCall.Name != "" {
-		c.metrics.RecordAPICall(duration, true)
 		c.logger.Debug(ctx, "OpenAI API request completed successfully",
 			Field("duration_ms", duration.Milliseconds()),
 			Field("tool_name", toolCall.Name),
 		)
 	} else {
-		c.metrics.RecordAPICall(duration, true)
 		c.logger.Debug(ctx, "OpenAI API request completed (no tool call)",
 			Field("duration_ms", duration.Milliseconds()),
 		)
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -100,3 +101,331 @@ func TestRequestPlanUsesFunctionToolShape(t *testing.T) {
 		t.Fatalf("expected tool_choice=required, got %v", captured["tool_choice"])
 	}
 }
+
+func TestRequestPlanUsesAzureDeploymentShape(t *testing.T) {
+	t.Parallel()
+
+	var (
+		captured map[string]any
+		gotURL   *url.URL
+		header   http.Header
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL = r.URL
+		header = r.Header.Clone()
+		defer func() { _ = r.Body.Close() }()
+		if err := json.NewDecoder(r.Body).Decode(&captured); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+	}))
+	defer server.Close()
+
+	client, err := NewOpenAIClient("azure-key", "gpt-4o", "", server.URL, nil, nil, nil, 120*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected client error: %v", err)
+	}
+	client.httpClient = server.Client()
+	client.SetAzureDeployment("my-deploy", "2024-10-21")
+
+	if _, err := client.RequestPlan(context.Background(), []ChatMessage{{Role: RoleUser, Content: "hi"}}); err != nil {
+		t.Fatalf("RequestPlan returned error: %v", err)
+	}
+
+	if gotURL.Path != "/openai/deployments/my-deploy/responses" {
+		t.Fatalf("unexpected Azure path %q", gotURL.Path)
+	}
+	if v := gotURL.Query().Get("api-version"); v != "2024-10-21" {
+		t.Fatalf("expected api-version query, got %q", v)
+	}
+	if header.Get("api-key") != "azure-key" || header.Get("Authorization") != "" {
+		t.Fatalf("expected api-key auth only, got %v", header)
+	}
+	if captured["model"] != "my-deploy" {
+		t.Fatalf("expected deployment as model, got %v", captured["model"])
+	}
+}
+
+func TestRequestPlanSendsPromptCacheKeyAndRecordsCacheHits(t *testing.T) {
+	t.Parallel()
+
+	var captured map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() { _ = r.Body.Close() }()
+		if err := json.NewDecoder(r.Body).Decode(&captured); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(`data: {"type":"response.completed","response":{"output":[{"type":"function_call","call_id":"call-1","name":"` + schema.ToolName + `","arguments":"{}"}],"usage":{"input_tokens":2000,"input_tokens_details":{"cached_tokens":1536},"output_tokens":40}}}` + "\n\n"))
+	}))
+	defer server.Close()
+
+	metrics := NewInMemoryMetrics()
+	client, err := NewOpenAIClient("test-key", "test-model", "", server.URL, nil, metrics, nil, 120*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected client error: %v", err)
+	}
+	client.httpClient = server.Client()
+
+	history := []ChatMessage{
+		{Role: RoleUser, Content: "hi"},
+		{Role: RoleSystem, Content: "system", Stable: true},
+	}
+	toolCall, err := client.RequestPlan(context.Background(), history)
+	if err != nil {
+		t.Fatalf("RequestPlan returned error: %v", err)
+	}
+	if toolCall.ID != "call-1" {
+		t.Fatalf("expected tool call from completion event, got %+v", toolCall)
+	}
+
+	key, _ := captured["prompt_cache_key"].(string)
+	if key == "" || key != promptCacheKey("test-model", history) {
+		t.Fatalf("expected derived prompt_cache_key, got %q", key)
+	}
+	input, _ := captured["input"].([]any)
+	first, _ := input[0].(map[string]any)
+	if len(input) != 2 || first["role"] != "system" {
+		t.Fatalf("expected stable system prompt first, got %v", captured["input"])
+	}
+
+	cache := metrics.GetSnapshot().PromptCache
+	if cache.Requests != 1 || cache.Hits != 1 || cache.InputTokens != 2000 || cache.CachedTokens != 1536 {
+		t.Fatalf("unexpected prompt cache metrics: %+v", cache)
+	}
+}
+
+func TestRequestPlanRetriesRateLimitsAndTruncatedStreams(t *testing.T) {
+	t.Parallel()
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			w.Header().Set("retry-after-ms", "10")
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+		case 2:
+			// The stream breaks off before a tool call arrives.
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte(`data: {"type":"response.output_text.delta","delta":"hi"}` + "\n\n"))
+		default:
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte(`data: {"type":"response.completed","response":{"output":[{"type":"function_call","call_id":"call-1","name":"` + schema.ToolName + `","arguments":"{}"}]}}` + "\n\n"))
+		}
+	}))
+	defer server.Close()
+
+	var notices []RetryNotice
+	retry := &RetryConfig{
+		MaxRetries:     4,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+		Multiplier:     2,
+		OnRetry:        func(n RetryNotice) { notices = append(notices, n) },
+	}
+	client, err := NewOpenAIClient("test-key", "test-model", "", server.URL, nil, nil, retry, 120*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected client error: %v", err)
+	}
+	client.httpClient = server.Client()
+
+	toolCall, err := client.RequestPlan(context.Background(), []ChatMessage{{Role: RoleUser, Content: "hi"}})
+	if err != nil {
+		t.Fatalf("RequestPlan returned error: %v", err)
+	}
+	if toolCall.ID != "call-1" || requests != 3 {
+		t.Fatalf("expected success on the third request, got %+v after %d requests", toolCall, requests)
+	}
+	if len(notices) != 2 || notices[0].Attempt != 2 || notices[0].MaxAttempts != 5 || notices[0].Delay != 10*time.Millisecond {
+		t.Fatalf("unexpected retry notices: %+v", notices)
+	}
+}
+
+func TestRetryAfterFromHeader(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]time.Duration{"2": 2 * time.Second, "0": 0, "soon": 0, "": 0}
+	for value, want := range cases {
+		header := http.Header{}
+		header.Set("Retry-After", value)
+		if got := retryAfterFromHeader(header); got != want {
+			t.Fatalf("Retry-After %q: got %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestRequestPlanResumesDroppedBackgroundStream(t *testing.T) {
+	t.Parallel()
+
+	var resumeQuery url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		if r.Method == http.MethodGet {
+			resumeQuery = r.URL.Query()
+			_, _ = w.Write([]byte(`data: {"type":"response.function_call_arguments.delta","sequence_number":2,"delta":"lo\"}"}` + "\n\n" +
+				`data: {"type":"response.completed","sequence_number":3,"response":{"id":"resp_1"}}` + "\n\n"))
+			return
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["background"] != true || body["store"] != true {
+			t.Errorf("expected background request, got %v", body)
+		}
+		_, _ = w.Write([]byte(`data: {"type":"response.created","sequence_number":0,"response":{"id":"resp_1"}}` + "\n\n" +
+			`data: {"type":"response.function_call.delta","sequence_number":1,"name":"` + schema.ToolName + `","call_id":"call-1","arguments":"{\"message\":\"Hel"}` + "\n\n"))
+	}))
+	defer server.Close()
+
+	retry := &RetryConfig{MaxRetries: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 1}
+	client, err := NewOpenAIClient("test-key", "test-model", "", server.URL, nil, nil, retry, 120*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected client error: %v", err)
+	}
+	client.httpClient = server.Client()
+	client.SetBackgroundMode(true)
+
+	var deltas []string
+	toolCall, err := client.RequestPlanStreaming(context.Background(), []ChatMessage{{Role: RoleUser, Content: "hi"}}, func(s string) {
+		deltas = append(deltas, s)
+	})
+	if err != nil {
+		t.Fatalf("RequestPlanStreaming returned error: %v", err)
+	}
+	if toolCall.Arguments != `{"message":"Hello"}` {
+		t.Fatalf("expected arguments stitched across the reconnect, got %q", toolCall.Arguments)
+	}
+	if resumeQuery.Get("starting_after") != "1" || resumeQuery.Get("stream") != "true" {
+		t.Fatalf("unexpected resume query: %v", resumeQuery)
+	}
+	if got := strings.Join(deltas, ""); got != "Hello" {
+		t.Fatalf("expected deltas without duplicates, got %q", deltas)
+	}
+}
+
+func TestRequestPlanRetryDoesNotRepeatEmittedDeltas(t *testing.T) {
+	t.Parallel()
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "text/event-stream")
+		if requests == 1 {
+			_, _ = w.Write([]byte(`data: {"type":"response.output_text.delta","delta":"Hel"}` + "\n\n"))
+			return
+		}
+		_, _ = w.Write([]byte(`data: {"type":"response.output_text.delta","delta":"He"}` + "\n\n" +
+			`data: {"type":"response.output_text.delta","delta":"llo"}` + "\n\n" +
+			`data: [DONE]` + "\n\n"))
+	}))
+	defer server.Close()
+
+	retry := &RetryConfig{MaxRetries: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 1}
+	client, err := NewOpenAIClient("test-key", "test-model", "", server.URL, nil, nil, retry, 120*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected client error: %v", err)
+	}
+	client.httpClient = server.Client()
+
+	var deltas []string
+	if _, err := client.RequestPlanStreaming(context.Background(), []ChatMessage{{Role: RoleUser, Content: "hi"}}, func(s string) {
+		deltas = append(deltas, s)
+	}); err != nil {
+		t.Fatalf("RequestPlanStreaming returned error: %v", err)
+	}
+	if len(deltas) != 2 || deltas[0] != "Hel" || deltas[1] != "lo" {
+		t.Fatalf("expected replayed text to be suppressed, got %q", deltas)
+	}
+}
+
+func TestRequestPlanFallsBackToStructuredOutput(t *testing.T) {
+	t.Parallel()
+
+	var bodies []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		if _, ok := body["tools"]; ok {
+			http.Error(w, `{"error":{"message":"tools are not supported by this model"}}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(`data: {"type":"response.output_text.delta","delta":"{\"message\":\"hi\","}` + "\n\n" +
+			`data: {"type":"response.output_text.delta","delta":"\"plan\":[],\"requireHumanInput\":false}"}` + "\n\n" +
+			`data: [DONE]` + "\n\n"))
+	}))
+	defer server.Close()
+
+	client, err := NewOpenAIClient("test-key", "test-model", "", server.URL, nil, nil, nil, 120*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected client error: %v", err)
+	}
+	client.httpClient = server.Client()
+
+	for i := 0; i < 2; i++ {
+		toolCall, err := client.RequestPlan(context.Background(), []ChatMessage{{Role: RoleUser, Content: "hi"}})
+		if err != nil {
+			t.Fatalf("RequestPlan returned error: %v", err)
+		}
+		if toolCall.Name != schema.ToolName || toolCall.Arguments != `{"message":"hi","plan":[],"requireHumanInput":false}` {
+			t.Fatalf("unexpected structured tool call: %+v", toolCall)
+		}
+	}
+
+	// The rejected tool request is followed by structured requests only.
+	if len(bodies) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(bodies))
+	}
+	format, _ := bodies[1]["text"].(map[string]any)["format"].(map[string]any)
+	if format["type"] != "json_schema" || format["name"] != schema.ToolName {
+		t.Fatalf("expected json_schema text format, got %v", bodies[1]["text"])
+	}
+}
+
+func TestRequestPlanStreamsReasoningSeparately(t *testing.T) {
+	t.Parallel()
+
+	var captured map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() { _ = r.Body.Close() }()
+		if err := json.NewDecoder(r.Body).Decode(&captured); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		args, _ := json.Marshal(`{"message":"Done","reasoning":["check tests","run them"],"plan":[],"requireHumanInput":false}`)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(`data: {"type":"response.reasoning_summary_part.added"}` + "\n\n" +
+			`data: {"type":"response.reasoning_summary_text.delta","delta":"Thinking"}` + "\n\n" +
+			`data: {"type":"response.reasoning_summary_part.added"}` + "\n\n" +
+			`data: {"type":"response.reasoning_summary_text.delta","delta":"More"}` + "\n\n" +
+			`data: {"type":"response.function_call.delta","call_id":"call-1","name":"` + schema.ToolName + `","arguments":` + string(args) + `}` + "\n\n" +
+			`data: [DONE]` + "\n\n"))
+	}))
+	defer server.Close()
+
+	client, err := NewOpenAIClient("test-key", "test-model", "low", server.URL, nil, nil, nil, 120*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected client error: %v", err)
+	}
+	client.httpClient = server.Client()
+	client.SetReasoningSummary("auto")
+
+	var deltas, reasoning strings.Builder
+	ctx := WithReasoningHandler(context.Background(), func(s string) { reasoning.WriteString(s) })
+	if _, err := client.RequestPlanStreaming(ctx, []ChatMessage{{Role: RoleUser, Content: "hi"}}, func(s string) {
+		deltas.WriteString(s)
+	}); err != nil {
+		t.Fatalf("RequestPlanStreaming returned error: %v", err)
+	}
+
+	if got := deltas.String(); got != "Done" {
+		t.Fatalf("expected only the message in deltas, got %q", got)
+	}
+	if got, want := reasoning.String(), "Thinking\n\nMore\ncheck tests\nrun them"; got != want {
+		t.Fatalf("expected reasoning %q, got %q", want, got)
+	}
+	settings, _ := captured["reasoning"].(map[string]any)
+	if settings["effort"] != "low" || settings["summary"] != "auto" {
+		t.Fatalf("expected reasoning effort and summary in request, got %v", captured["reasoning"])
+	}
+}
@@ -0,0 +1,103 @@
+package runtime
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// OpenAI caches prompt prefixes automatically; requests only hit the cache
+// when they start with the same tokens and are routed together. The client
+// therefore sends stable messages first and tags requests with a
+// prompt_cache_key derived from that prefix.
+
+// orderForPromptCache moves messages marked Stable to the front, keeping the
+// relative order within both groups. Histories that already start with their
+// stable messages are returned unchanged.
+func orderForPromptCache(history []ChatMessage) []ChatMessage {
+	sorted := true
+	seenVolatile := false
+	for _, m := range history {
+		if !m.Stable {
+			seenVolatile = true
+		} else if seenVolatile {
+			sorted = false
+			break
+		}
+	}
+	if sorted {
+		return history
+	}
+	ordered := make([]ChatMessage, 0, len(history))
+	for _, m := range history {
+		if m.Stable {
+			ordered = append(ordered, m)
+		}
+	}
+	for _, m := range history {
+		if !m.Stable {
+			ordered = append(ordered, m)
+		}
+	}
+	return ordered
+}
+
+// promptCacheKey hashes the model and the stable prefix so sessions sharing
+// a system prompt share cache entries. It returns "" when nothing is stable.
+func promptCacheKey(model string, history []ChatMessage) string {
+	hash := sha256.New()
+	hash.Write([]byte(model))
+	stable := false
+	for _, m := range history {
+		if !m.Stable {
+			continue
+		}
+		stable = true
+		hash.Write([]byte{0})
+		hash.Write([]byte(m.Role))
+		hash.Write([]byte{0})
+		hash.Write([]byte(m.Content))
+	}
+	if !stable {
+		return ""
+	}
+	return "goagent-" + hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// responseUsage is the token accounting reported with response.completed.
+type responseUsage struct {
+	InputTokens  int
+	CachedTokens int
+	OutputTokens int
+}
+
+// parseResponseUsage reads response.usage from a completion event.
+func parseResponseUsage(evt map[string]any) (responseUsage, bool) {
+	respObj, _ := evt["response"].(map[string]any)
+	usage, _ := respObj["usage"].(map[string]any)
+	if usage == nil {
+		return responseUsage{}, false
+	}
+	number := func(m map[string]any, key string) int {
+		v, _ := m[key].(float64)
+		return int(v)
+	}
+	parsed := responseUsage{
+		InputTokens:  number(usage, "input_tokens"),
+		OutputTokens: number(usage, "output_tokens"),
+	}
+	if details, _ := usage["input_tokens_details"].(map[string]any); details != nil {
+		parsed.CachedTokens = number(details, "cached_tokens")
+	}
+	return parsed, true
+}
+
+// sanitizePromptCacheKey trims the configured key; OpenAI rejects keys
+// longer than 64 characters.
+func sanitizePromptCacheKey(key string) string {
+	key = strings.TrimSpace(key)
+	if len(key) > 64 {
+		key = key[:64]
+	}
+	return key
+}
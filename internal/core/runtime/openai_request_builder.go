@@ -4,16 +4,20 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/asynkron/goagent/internal/core/schema"
 )
 
 // buildMessagesFromHistory converts chat messages to the format expected by
 // the OpenAI Responses API. It maps tool role to developer and determines
-// appropriate content types.
+// appropriate content types. Image attachments become input_image parts and
+// text attachments extra text parts.
 func buildMessagesFromHistory(history []ChatMessage) []map[string]any {
 	inputMsgs := make([]map[string]any, 0, len(history))
 	for _, m := range history {
@@ -28,15 +32,23 @@ func buildMessagesFromHistory(history []ChatMessage) []map[string]any {
 			contentType = "output_text"
 		}
 
-		msg := map[string]any{
-			"role": finalRole,
-			"content": []map[string]any{
-				{
-					"type": contentType,
-					"text": m.Content,
-				},
+		content := []map[string]any{
+			{
+				"type": contentType,
+				"text": m.Content,
 			},
 		}
+		for _, a := range m.Attachments {
+			if a.IsImage() {
+				content = append(content, map[string]any{"type": "input_image", "image_url": a.dataURL()})
+			} else {
+				content = append(content, map[string]any{"type": contentType, "text": attachmentText(a)})
+			}
+		}
+		msg := map[string]any{
+			"role":    finalRole,
+			"content": content,
+		}
 
 		inputMsgs = append(inputMsgs, msg)
 	}
@@ -44,107 +56,146 @@ func buildMessagesFromHistory(history []ChatMessage) []map[string]any {
 }
 
 // buildRequestBody constructs the request body for the OpenAI Responses API.
-func (c *OpenAIClient) buildRequestBody(inputMsgs []map[string]any) ([]byte, error) {
+// A non-empty cacheKey is sent as prompt_cache_key and a non-empty effort as
+// the reasoning effort. With structured set, the plan schema is requested as
+// a json_schema text format instead of a tool.
+func (c *OpenAIClient) buildRequestBody(inputMsgs []map[string]any, cacheKey, effort string, structured bool) ([]byte, error) {
+	model := c.model
+	if c.azureDeployment != "" {
+		// Azure resolves the model from the deployment in the URL.
+		model = c.azureDeployment
+	}
 	reqBody := map[string]any{
-		"model":  c.model,
+		"model":  model,
 		"input":  inputMsgs,
 		"stream": true,
-		// Define the function tool in the flat Responses shape and require a tool call.
-		"tools": []map[string]any{
-			{
-				"type":        "function",
+		// Define the function tools in the flat Responses shape and require a tool call.
+		"tools": c.functionTools(),
+		// Require a tool call; with only the plan tool defined, this forces the
+		// model to call it with arguments.
+		"tool_choice": "required",
+	}
+	if len(c.extraTools) > 0 {
+		// The runtime handles one tool call per response.
+		reqBody["parallel_tool_calls"] = false
+	}
+	if structured {
+		delete(reqBody, "tools")
+		delete(reqBody, "tool_choice")
+		delete(reqBody, "parallel_tool_calls")
+		reqBody["text"] = map[string]any{
+			"format": map[string]any{
+				"type":        "json_schema",
 				"name":        c.tool.Name,
 				"description": c.tool.Description,
-				"parameters":  c.tool.Parameters,
+				"schema":      c.tool.Parameters,
 			},
-		},
-		// Require a tool call; with only one tool defined, this forces the model
-		// to call our tool with arguments.
-		"tool_choice": "required",
+		}
 	}
-	if c.reasoningEffort != "" {
-		reqBody["reasoning"] = map[string]any{"effort": c.reasoningEffort}
+	if c.backgroundMode {
+		reqBody["background"] = true
+		reqBody["store"] = true
+	}
+	if cacheKey != "" {
+		reqBody["prompt_cache_key"] = cacheKey
+	}
+	if effort != "" || c.reasoningSummary != "" {
+		reasoning := map[string]any{}
+		if effort != "" {
+			reasoning["effort"] = effort
+		}
+		if c.reasoningSummary != "" {
+			reasoning["summary"] = c.reasoningSummary
+		}
+		reqBody["reasoning"] = reasoning
 	}
 
 	return json.Marshal(reqBody)
 }
 
-// executeRequest performs the HTTP request and returns the response.
-// It handles request building, authentication, and error checking.
-// This method uses the retry configuration if available.
-func (c *OpenAIClient) executeRequest(ctx context.Context, payload []byte, start time.Time, retryConfig *RetryConfig) (*http.Response, error) {
-	var resp *http.Response
-	var lastErr error
-
-	err := executeWithRetry(ctx, retryConfig, func() error {
-		// Create new request for each retry attempt
-		apiRoot := strings.TrimRight(c.baseURL, "/")
-		url := apiRoot + "/responses"
-
-		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
-		if err != nil {
-			c.logger.Error(ctx, "Failed to build OpenAI request", err,
-				Field("url", url),
-			)
-			lastErr = fmt.Errorf("openai(responses): build request: %w", err)
-			return lastErr
-		}
-		req.Header.Set("Authorization", "Bearer "+c.apiKey)
-		req.Header.Set("Content-Type", "application/json")
-
-		resp, err = c.httpClient.Do(req)
-		if err != nil {
-			duration := time.Since(start)
-			retryable := isRetryableError(err)
-			c.logger.Error(ctx, "OpenAI API request failed", err,
-				Field("url", url),
-				Field("duration_ms", duration.Milliseconds()),
-				Field("retryable", retryable),
-			)
-
-			lastErr = &retryableAPIError{
-				err:       fmt.Errorf("openai(responses): do request: %w", err),
-				retryable: retryable,
-			}
-			return lastErr
-		}
+// functionTools lists the plan tool followed by any host tools.
+func (c *OpenAIClient) functionTools() []map[string]any {
+	tools := make([]map[string]any, 0, 1+len(c.extraTools))
+	for _, tool := range append([]schema.ToolDefinition{c.tool}, c.extraTools...) {
+		tools = append(tools, map[string]any{
+			"type":        "function",
+			"name":        tool.Name,
+			"description": tool.Description,
+			"parameters":  tool.Parameters,
+		})
+	}
+	return tools
+}
 
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
-			_ = resp.Body.Close()
-			duration := time.Since(start)
-			retryable := isRetryableStatusCode(resp.StatusCode)
-
-			c.logger.Error(ctx, "OpenAI API returned error status", fmt.Errorf("status %s: %s", resp.Status, string(msg)),
-				Field("status_code", resp.StatusCode),
-				Field("duration_ms", duration.Milliseconds()),
-				Field("retryable", retryable),
-			)
-
-			lastErr = &retryableAPIError{
-				err:        fmt.Errorf("openai(responses): status %s: %s", resp.Status, string(msg)),
-				statusCode: resp.StatusCode,
-				retryable:  retryable,
-			}
-			resp = nil // Clear response on error
-			return lastErr
-		}
+// executeRequest performs one HTTP request and returns the streaming
+// response. Failures are returned as retryableAPIError so the caller's
+// retry loop can decide whether to try again.
+func (c *OpenAIClient) executeRequest(ctx context.Context, payload []byte, start time.Time) (*http.Response, error) {
+	url := c.responsesURL()
 
-		return nil // Success
-	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		c.logger.Error(ctx, "Failed to build OpenAI request", err,
+			Field("url", url),
+		)
+		return nil, fmt.Errorf("openai(responses): build request: %w", err)
+	}
+	c.setAuthHeader(req)
+	req.Header.Set("Content-Type", "application/json")
 
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		duration := time.Since(start)
-		c.metrics.RecordAPICall(duration, false)
-		if lastErr != nil {
-			return nil, lastErr
+		retryable := isRetryableError(err)
+		c.logger.Error(ctx, "OpenAI API request failed", err,
+			Field("url", url),
+			Field("duration_ms", duration.Milliseconds()),
+			Field("retryable", retryable),
+		)
+		return nil, &retryableAPIError{
+			err:       fmt.Errorf("openai(responses): do request: %w", err),
+			retryable: retryable,
 		}
-		return nil, err
 	}
 
-	// Success - record metrics
-	duration := time.Since(start)
-	c.metrics.RecordAPICall(duration, true)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		_ = resp.Body.Close()
+		duration := time.Since(start)
+		retryable := isRetryableStatusCode(resp.StatusCode)
+
+		c.logger.Error(ctx, "OpenAI API returned error status", fmt.Errorf("status %s: %s", resp.Status, string(msg)),
+			Field("status_code", resp.StatusCode),
+			Field("duration_ms", duration.Milliseconds()),
+			Field("retryable", retryable),
+		)
+
+		return nil, &retryableAPIError{
+			err:        fmt.Errorf("openai(responses): status %s: %s", resp.Status, string(msg)),
+			statusCode: resp.StatusCode,
+			retryable:  retryable,
+			retryAfter: retryAfterFromHeader(resp.Header),
+		}
+	}
 
 	return resp, nil
 }
+
+// toolsRejected reports whether err is an endpoint refusing function tools,
+// as OpenAI-compatible gateways without tool support do.
+func toolsRejected(err error) bool {
+	var apiErr *retryableAPIError
+	if !errors.As(err, &apiErr) || (apiErr.statusCode != http.StatusBadRequest && apiErr.statusCode != http.StatusUnprocessableEntity && apiErr.statusCode != http.StatusNotImplemented) {
+		return false
+	}
+	message := strings.ToLower(apiErr.err.Error())
+	if !strings.Contains(message, "tool") && !strings.Contains(message, "function") {
+		return false
+	}
+	for _, hint := range []string{"not supported", "unsupported", "not support", "unknown parameter", "unrecognized", "not allowed", "invalid"} {
+		if strings.Contains(message, hint) {
+			return true
+		}
+	}
+	return false
+}
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"strings"
+
+	"github.com/asynkron/goagent/internal/core/schema"
 )
 
 // streamParser handles parsing of SSE (Server-Sent Events) streams from OpenAI.
@@ -19,6 +21,27 @@ type streamParser struct {
 	toolArgs                  string
 	lastEmittedMessage        string
 	lastEmittedReasoningCount int
+	// onReasoning receives the plan's reasoning entries and native
+	// reasoning summaries. reasoningStarted and newReasoningPart decide the
+	// separator before the next chunk.
+	onReasoning      func(string)
+	reasoningStarted bool
+	newReasoningPart bool
+	// usage is set from response.completed when the API reports it.
+	usage    responseUsage
+	hasUsage bool
+	// events counts decoded stream events. completed is set once the
+	// stream signalled its end ([DONE] or response.completed); a stream
+	// with events but without an end was cut off.
+	events    int
+	completed bool
+	// responseID and sequence identify the response and the last event
+	// received so a dropped background stream can be resumed.
+	responseID string
+	sequence   int
+	// structured treats the output text as the plan tool arguments, for
+	// requests that ask for a json_schema structured output.
+	structured bool
 }
 
 // newStreamParser creates a new stream parser instance.
@@ -27,6 +50,7 @@ func newStreamParser(reader *bufio.Reader, onDelta func(string), debugStream boo
 		reader:      reader,
 		onDelta:     onDelta,
 		debugStream: debugStream,
+		sequence:    -1,
 	}
 }
 
@@ -53,6 +77,7 @@ func (p *streamParser) parse() (ToolCall, error) {
 		}
 		chunkData := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
 		if chunkData == "[DONE]" {
+			p.completed = true
 			if p.debugStream {
 				fmt.Println("------ STREAM: [DONE]")
 			}
@@ -67,12 +92,20 @@ func (p *streamParser) parse() (ToolCall, error) {
 			continue
 		}
 
+		p.events++
 		p.processEvent(evt)
 	}
 
 	if p.toolName != "" {
 		return ToolCall{ID: p.toolID, Name: p.toolName, Arguments: p.toolArgs}, nil
 	}
+	if p.structured && strings.TrimSpace(p.toolArgs) != "" {
+		id := p.responseID
+		if id == "" {
+			id = "structured-output"
+		}
+		return ToolCall{ID: id, Name: schema.ToolName, Arguments: p.toolArgs}, nil
+	}
 	// No tool call is valid for plain text responses
 	return ToolCall{}, nil
 }
@@ -102,6 +135,16 @@ func (p *streamParser) parseEvent(chunkData string) (map[string]any, error) {
 // processEvent handles a single stream event and updates parser state.
 func (p *streamParser) processEvent(evt map[string]any) {
 	t, _ := evt["type"].(string)
+	if seq, ok := evt["sequence_number"].(float64); ok {
+		p.sequence = int(seq)
+	}
+	if p.responseID == "" {
+		if respObj, _ := evt["response"].(map[string]any); respObj != nil {
+			if id, _ := respObj["id"].(string); id != "" {
+				p.responseID = id
+			}
+		}
+	}
 	switch t {
 	case "response.output_text.delta":
 		p.handleOutputTextDelta(evt)
@@ -113,13 +156,32 @@ func (p *streamParser) processEvent(evt map[string]any) {
 		p.handleArgumentsDelta(evt)
 	case "message.delta", "response.message.delta":
 		p.handleMessageDelta(evt)
+	case "response.reasoning_summary_part.added":
+		p.newReasoningPart = true
+	case "response.reasoning_summary_text.delta", "response.reasoning_text.delta":
+		if s, _ := evt["delta"].(string); s != "" {
+			separator := ""
+			if p.newReasoningPart {
+				separator = "\n\n"
+				p.newReasoningPart = false
+			}
+			p.emitReasoning(s, separator)
+		}
 	case "response.completed", "response.output_text.done", "response.function_call.completed":
+		if t == "response.completed" {
+			p.completed = true
+		}
 		p.handleCompletion(evt)
 	}
 }
 
 // handleOutputTextDelta processes output text delta events.
 func (p *streamParser) handleOutputTextDelta(evt map[string]any) {
+	if p.structured {
+		// The text is the plan JSON; stream its message like tool arguments.
+		p.handleArgumentsDelta(evt)
+		return
+	}
 	if s, _ := evt["delta"].(string); s != "" {
 		if p.onDelta != nil {
 			p.onDelta(s)
@@ -189,6 +251,16 @@ func (p *streamParser) handleMessageDelta(evt map[string]any) {
 
 // handleCompletion processes completion events and extracts final tool call data.
 func (p *streamParser) handleCompletion(evt map[string]any) {
+	if p.structured && p.toolArgs == "" {
+		if s, _ := evt["text"].(string); s != "" {
+			p.toolArgs = s
+		} else if s, ok := findStringInMap(evt["response"], "text"); ok {
+			p.toolArgs = s
+		}
+	}
+	if usage, ok := parseResponseUsage(evt); ok {
+		p.usage, p.hasUsage = usage, true
+	}
 	if p.toolArgs == "" || p.toolName == "" || p.toolID == "" {
 		if respObj, _ := evt["response"].(map[string]any); respObj != nil {
 			if p.toolName == "" {
@@ -267,19 +339,33 @@ func (p *streamParser) emitMessageDelta(buf string) {
 	}
 }
 
-// emitReasoningDeltas extracts and emits reasoning array entries.
+// emitReasoningDeltas extracts the plan's reasoning array entries and
+// forwards each new one on its own line.
 func (p *streamParser) emitReasoningDeltas(buf string) {
-	if p.onDelta == nil {
+	if p.onReasoning == nil {
 		return
 	}
 	if vals, _, ok := extractPartialJSONStringArrayField(buf, "reasoning"); ok {
 		if p.lastEmittedReasoningCount < len(vals) {
 			for i := p.lastEmittedReasoningCount; i < len(vals); i++ {
 				if v := strings.TrimSpace(vals[i]); v != "" {
-					p.onDelta("\n" + v)
+					p.emitReasoning(v, "\n")
 				}
 			}
 			p.lastEmittedReasoningCount = len(vals)
 		}
 	}
 }
+
+// emitReasoning forwards reasoning text, prefixed with separator when
+// reasoning was already emitted.
+func (p *streamParser) emitReasoning(text, separator string) {
+	if p.onReasoning == nil || text == "" {
+		return
+	}
+	if p.reasoningStarted {
+		text = separator + text
+	}
+	p.reasoningStarted = true
+	p.onReasoning(text)
+}
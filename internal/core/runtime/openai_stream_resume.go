@@ -0,0 +1,104 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dropped Responses API streams are recovered in one of two ways. In
+// background mode the response keeps running server side, so the client
+// reconnects to GET /responses/{id}?stream=true&starting_after=<seq> and
+// continues parsing where it stopped. Otherwise the request is sent again
+// and deltaDeduper hides the part of the output that was already forwarded.
+
+// deltaDeduper forwards deltas to onDelta while suppressing text that an
+// earlier attempt already emitted.
+type deltaDeduper struct {
+	onDelta func(string)
+	emitted strings.Builder
+	// pos is how much of emitted the current attempt has reproduced.
+	pos int
+}
+
+func newDeltaDeduper(onDelta func(string)) *deltaDeduper {
+	return &deltaDeduper{onDelta: onDelta}
+}
+
+// restart marks the beginning of a new attempt that replays the output.
+func (d *deltaDeduper) restart() {
+	d.pos = 0
+}
+
+func (d *deltaDeduper) forward(s string) {
+	if remaining := d.emitted.Len() - d.pos; remaining > 0 {
+		skip := min(len(s), remaining)
+		d.pos += skip
+		s = s[skip:]
+	}
+	if s == "" {
+		return
+	}
+	d.emitted.WriteString(s)
+	d.pos += len(s)
+	d.onDelta(s)
+}
+
+// canResume reports whether a dropped stream can be continued instead of
+// requesting the response again.
+func (c *OpenAIClient) canResume(parser *streamParser) bool {
+	return c.backgroundMode && parser != nil && parser.responseID != "" && !parser.completed
+}
+
+// resumeStream reconnects to a background response after the last event the
+// parser saw.
+func (c *OpenAIClient) resumeStream(ctx context.Context, parser *streamParser, start time.Time) (*http.Response, error) {
+	endpoint := strings.TrimRight(c.baseURL, "/") + "/responses/" + url.PathEscape(parser.responseID)
+	query := url.Values{"stream": {"true"}}
+	if parser.sequence >= 0 {
+		query.Set("starting_after", strconv.Itoa(parser.sequence))
+	}
+	if c.azureDeployment != "" {
+		endpoint = strings.TrimRight(c.baseURL, "/")
+		if !strings.HasSuffix(endpoint, "/openai") {
+			endpoint += "/openai"
+		}
+		endpoint += "/responses/" + url.PathEscape(parser.responseID)
+		query.Set("api-version", c.azureAPIVersion)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("openai(responses): build resume request: %w", err)
+	}
+	c.setAuthHeader(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, &retryableAPIError{
+			err:       fmt.Errorf("openai(responses): resume: %w", err),
+			retryable: isRetryableError(err),
+		}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		_ = resp.Body.Close()
+		return nil, &retryableAPIError{
+			err:        fmt.Errorf("openai(responses): resume status %s: %s", resp.Status, string(msg)),
+			statusCode: resp.StatusCode,
+			retryable:  isRetryableStatusCode(resp.StatusCode),
+			retryAfter: retryAfterFromHeader(resp.Header),
+		}
+	}
+	c.logger.Info(ctx, "Resumed OpenAI response stream",
+		Field("response_id", parser.responseID),
+		Field("starting_after", parser.sequence),
+		Field("duration_ms", time.Since(start).Milliseconds()),
+	)
+	return resp, nil
+}
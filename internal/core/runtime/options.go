@@ -3,6 +3,7 @@ package runtime
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -16,10 +17,33 @@ import (
 //
 //revive:disable-next-line exported // Keep RuntimeOptions name for clarity across packages
 type RuntimeOptions struct {
-	APIKey              string
-	APIBaseURL          string
-	Model               string
-	ReasoningEffort     string
+	APIKey     string
+	APIBaseURL string
+	// Provider selects the model backend: "openai" or "anthropic". When
+	// empty, models prefixed with "claude" use Anthropic and everything
+	// else uses OpenAI. APIKey and APIBaseURL apply to the chosen provider.
+	Provider string
+	// AzureDeployment targets an Azure OpenAI deployment. When set,
+	// APIBaseURL must be the Azure resource endpoint and APIKey is sent in
+	// the api-key header. AzureAPIVersion overrides the api-version query
+	// parameter.
+	AzureDeployment string
+	AzureAPIVersion string
+	Model           string
+	ReasoningEffort string
+	// ReasoningSummary asks OpenAI reasoning models for a summary of their
+	// reasoning ("auto", "concise" or "detailed"), streamed as
+	// EventTypeReasoningDelta events. Some organizations must be verified
+	// before OpenAI returns summaries.
+	ReasoningSummary string
+	// AdaptiveEffort picks the reasoning effort of each plan request
+	// between MinReasoningEffort and MaxReasoningEffort ("low" and "high"
+	// by default): the maximum for new instructions, the minimum for passes
+	// that only follow up on successful steps. It replaces ReasoningEffort
+	// and only affects OpenAI reasoning models.
+	AdaptiveEffort      bool
+	MinReasoningEffort  string
+	MaxReasoningEffort  string
 	SystemPromptAugment string
 	AmnesiaAfterPasses  int
 	HandsFree           bool
@@ -30,11 +54,116 @@ type RuntimeOptions struct {
 	// and input requests are effectively ignored as before.
 	HandsFreeAutoReply string
 	MaxPasses          int
+	// MaxPlanValidationFailures ends the session with an error event after
+	// this many plan responses in a row fail validation. Zero uses
+	// DefaultMaxPlanValidationFailures; a negative value retries forever.
+	MaxPlanValidationFailures int
+	// Budget caps model requests, tokens, and spend per session. It is
+	// checked before every plan request and counts summaries too. Sub-agents
+	// also spend from the budget of the runtime that spawned them.
+	Budget Budget
+	// PromptCacheKey is sent as the OpenAI prompt_cache_key. When empty, a
+	// key is derived from the model and the stable prefix of the history
+	// (the system prompt).
+	PromptCacheKey string
+	// BackgroundResponses runs OpenAI responses in background mode so a
+	// stream dropped by a proxy is resumed instead of regenerated. Responses
+	// are then stored by OpenAI.
+	BackgroundResponses bool
+	// StructuredOutput requests plans as a json_schema structured output
+	// instead of a tool call, for OpenAI-compatible gateways without
+	// function tools. It is enabled automatically when the endpoint rejects
+	// tools.
+	StructuredOutput bool
+	// ProviderClient replaces the client built from Provider, APIKey and
+	// Model, e.g. for custom backends. APIKey is not required with it.
+	ProviderClient Provider
+	// FallbackModels are tried in order when a plan request to the primary
+	// model fails after its retries, e.g. during an outage or when the
+	// request overflows its context window. They use the primary's
+	// provider, API key and base URL. Fallbacks adds models on other
+	// providers; their empty fields inherit the primary's settings (the API
+	// key and base URL only when the provider matches). A status
+	// event reports each switch, and requests return to the primary after
+	// five minutes.
+	FallbackModels []string
+	Fallbacks      []ModelSettings
+	// Environment is the host's environment detection result (the CLI
+	// passes its bootprobe result). It is reported once at startup as an
+	// EventTypeEnvironment event so hosts and scripts can read it without
+	// parsing the system prompt. It must be JSON encodable.
+	Environment any
+	// DisableInstructionFiles skips loading project instruction files
+	// (AGENTS.md and friends) into the system prompt. InstructionFileNames
+	// overrides the file names looked up and InstructionMaxBytes caps their
+	// combined size (default 48 KiB).
+	DisableInstructionFiles bool
+	InstructionFileNames    []string
+	InstructionMaxBytes     int
+	// DisableMemory turns off the remember, recall and forget internal
+	// commands and the memories added to the system prompt. MemoryPath
+	// defaults to .goagent/memory.json in the working directory;
+	// MemoryPromptBytes caps the memories in the prompt (default 4 KiB).
+	DisableMemory     bool
+	MemoryPath        string
+	MemoryPromptBytes int
+	// DisableRepoMap leaves the repository map (directories, notable files
+	// and exported Go/TypeScript symbols of the working directory) out of
+	// plan requests. RepoMapTokens caps it (default 1500 tokens). Sub-agents
+	// spawned without their own options never get a map.
+	DisableRepoMap bool
+	RepoMapTokens  int
+	// MaxConcurrentSubAgents limits how many sub-agents the runtime's
+	// Orchestrator runs at once. Zero means unlimited.
+	MaxConcurrentSubAgents int
 	// HistoryLogPath controls where the runtime persists the serialized
-	// conversation history. A nil pointer defaults to "history.json" to
-	// preserve the previous behaviour while allowing callers to override
-	// or disable the log entirely.
+	// conversation history. A nil pointer defaults to
+	// .goagent/sessions/<SessionID>/history.json so sessions in the same
+	// directory do not overwrite each other; an empty string disables the
+	// log.
 	HistoryLogPath *string
+	// EventLogPath is where every emitted RuntimeEvent is appended as a JSON
+	// line, for ReadEventLog and "goagent replay". A nil pointer defaults to
+	// events.jsonl next to the default history log; an empty string disables
+	// the log.
+	EventLogPath *string
+	// SessionID names this session's directory under SessionsDir and is
+	// recorded in the history log. Empty generates one with NewSessionID.
+	SessionID string
+	// HistoryStore persists the conversation. When nil, a
+	// JSONFileHistoryStore writing to HistoryLogPath is used. When set, it
+	// takes precedence over HistoryLogPath and NewRuntime resumes from the
+	// history it loads.
+	HistoryStore HistoryStore
+	// JournalPath is where the executing plan is journaled so a session
+	// that dies mid-plan can be resumed with LoadInterruptedPlan and
+	// Runtime.ResumeInterruptedPlan. Empty disables the journal.
+	JournalPath string
+
+	// WatchWorkspace polls the working directory for files changed outside
+	// the agent, reports them as EventTypeWorkspaceChange, and tells the
+	// model about them before its next plan. WatchInterval defaults to 2s.
+	WatchWorkspace bool
+	WatchInterval  time.Duration
+
+	// LanguageServers are started on demand after apply_patch edits a file
+	// they handle; their diagnostics for the edited files are appended to
+	// the observation. DiagnosticsTimeout bounds the wait per file and
+	// defaults to 5s.
+	LanguageServers    []LanguageServer
+	DiagnosticsTimeout time.Duration
+
+	// WebFetch configures the fetch_url internal command: timeouts, size
+	// caps, host allow/deny lists and robots.txt handling.
+	WebFetch WebFetchOptions
+
+	// WebSearch configures the web_search internal command, which is only
+	// offered when WebSearch.Provider is set (see NewSearchProvider).
+	WebSearch WebSearchOptions
+
+	// SemanticSearch configures the semantic_search internal command, which
+	// is only offered when SemanticSearch.Embedder is set.
+	SemanticSearch SemanticSearchOptions
 
 	// MaxContextTokens defines the soft cap for the conversation history. When
 	// the estimated usage exceeds CompactWhenPercent of this value, older
@@ -43,6 +172,23 @@ type RuntimeOptions struct {
 	// CompactWhenPercent controls when the compactor kicks in. Values are in
 	// the 0-1 range (e.g. 0.85 triggers when the history is ~85% full).
 	CompactWhenPercent float64
+	// TokenCounter measures the history against the context budget. When
+	// nil, DefaultTokenCounter picks one for Provider and Model, loading
+	// tiktoken ranks files from TokenizerDir.
+	TokenCounter TokenCounter
+	// TokenizerDir holds "<encoding>.tiktoken" ranks files. Defaults to
+	// $GOAGENT_TOKENIZER_DIR.
+	TokenizerDir string
+	// SummarizeCompaction asks a model to summarize the oldest messages when
+	// the budget is exceeded instead of only truncating them. The originals
+	// are archived to the history store.
+	SummarizeCompaction bool
+	// CompactionModel is the (typically cheaper) model used for summaries.
+	// It is served by the same provider and defaults to Model.
+	CompactionModel string
+	// CompactionBatchSize is how many of the oldest messages are folded
+	// into one summary. Defaults to 20.
+	CompactionBatchSize int
 
 	// InputBuffer controls the capacity of the input channel. The default is
 	// tuned for interactive usage where only a handful of messages are
@@ -55,6 +201,9 @@ type RuntimeOptions struct {
 	InputReader io.Reader
 	// OutputWriter can be redirected for tests or alternative hosts.
 	OutputWriter io.Writer
+	// OutputFormat selects how forwarded events are written: OutputFormatText
+	// (default) or OutputFormatJSONL.
+	OutputFormat OutputFormat
 
 	// DisableInputReader prevents Run from consuming stdin. Useful when the
 	// host application pushes values into the Inputs queue directly.
@@ -70,11 +219,13 @@ type RuntimeOptions struct {
 	UseStreaming bool
 
 	// EmitTimeout guards against blocking forever when no consumer drains the
-	// output channel. Zero means wait indefinitely.
+	// output channel. Zero means wait indefinitely; hosts that only use
+	// Runtime.Subscribe never wait on it.
 	EmitTimeout time.Duration
 
 	// APIRetryConfig controls retry behavior for transient API failures.
-	// If nil, no retries are attempted.
+	// If nil, DefaultRetryConfig is used; set MaxRetries to 0 to disable
+	// retries. The runtime reports each retry as a status event.
 	APIRetryConfig *RetryConfig
 
 	// HTTPTimeout sets the timeout for HTTP requests to the OpenAI API.
@@ -93,6 +244,9 @@ type RuntimeOptions struct {
 	Logger Logger
 	// Metrics collects runtime metrics. If nil, a NoOpMetrics is used.
 	Metrics Metrics
+	// Tracer receives spans for passes, model requests, and steps. If nil,
+	// a NoOpTracer is used. Sub-agents inherit it.
+	Tracer Tracer
 	// LogLevel sets the minimum log level when using the default logger.
 	// Valid values: "DEBUG", "INFO", "WARN", "ERROR". Defaults to "INFO".
 	LogLevel string
@@ -103,6 +257,72 @@ type RuntimeOptions struct {
 	// LogWriter allows specifying a custom writer for logs. If set, this takes
 	// precedence over LogPath. If both are nil and Logger is nil, logging is disabled.
 	LogWriter io.Writer
+	// ExecutionBackend runs shell plan steps. Nil executes them on the host;
+	// a ContainerBackend sandboxes each step in a container.
+	ExecutionBackend ExecutionBackend
+	// CommandRunner, when set, produces the observations of shell steps
+	// instead of running them. ExecutionBackend, PTY and the environment
+	// settings are then ignored.
+	CommandRunner CommandRunner
+	// DisableSnapshots turns off the undo snapshots recorded under
+	// .goagent/snapshots before steps that write to the workspace.
+	DisableSnapshots bool
+	// DisableFailureLogs stops writing .goagent/failure-*.txt reports for
+	// failed shell steps.
+	DisableFailureLogs bool
+	// FailureLogRetention bounds the failure reports kept per directory; old
+	// reports are pruned on startup and after each new one. If nil,
+	// DefaultFailureLogRetention is used.
+	FailureLogRetention *FailureLogRetention
+	// ApprovalPolicy decides which plan steps need host confirmation before
+	// they run. Defaults to ApprovalPolicyNever. Approval requests are
+	// surfaced as EventTypeApprovalRequest and answered with
+	// InputTypeApprovalDecision. Hands-free sessions, which nobody can
+	// answer, fail such steps instead of asking.
+	ApprovalPolicy ApprovalPolicy
+	// CommandPolicy is consulted before each plan step runs and can allow,
+	// deny, or require approval for it. Nil uses DefaultCommandPolicy; pass
+	// an empty &CommandPolicy{} to allow everything.
+	CommandPolicy *CommandPolicy
+	// ReadOnly rejects plan steps that may write to the workspace:
+	// apply_patch, redirections, and any program not known to be read-only.
+	// Rejected steps fail with an observation explaining the restriction.
+	ReadOnly bool
+	// ReadOnlyDenylist adds regular expressions matched against a step's run
+	// string that are rejected in read-only mode on top of the defaults.
+	ReadOnlyDenylist []string
+	// EnvPolicy controls which of the agent's environment variables shell
+	// steps inherit. Defaults to EnvInheritAll; EnvInheritAllowlist and
+	// EnvClean keep secrets such as API keys away from model-suggested
+	// commands.
+	EnvPolicy EnvPolicy
+	// EnvAllowlist names the variables kept by EnvInheritAllowlist. A
+	// trailing "*" matches a prefix. Empty uses DefaultEnvAllowlist.
+	EnvAllowlist []string
+	// DefaultShell runs host shell steps that name no shell or one that does
+	// not exist on the host (e.g. /bin/bash on Alpine or Windows); the
+	// observation tells the model about the substitution. Empty uses the
+	// first of bash, zsh and sh (pwsh, powershell and cmd on Windows) on
+	// PATH. The CLI sets it to the login shell detected by bootprobe.
+	DefaultShell string
+	// MaxParallelSteps caps how many ready plan steps run at once. Zero
+	// means no limit. Steps that share a PlanStep.ConcurrencyGroup always
+	// run one at a time regardless of this setting.
+	MaxParallelSteps int
+	// PTY runs shell steps under a pseudo-terminal so tools that check for a
+	// TTY keep colors and progress output. Stdout and stderr are merged and
+	// ANSI sequences are preserved. Only supported on Linux; elsewhere steps
+	// fall back to pipes.
+	PTY bool
+	// Tools registers host function tools offered to the model next to the
+	// plan tool. Arguments are validated against each tool's JSONSchema
+	// before its Handler runs, and the result is fed back before the runtime
+	// asks for the next plan.
+	Tools []ToolSpec
+	// Hooks are called around plan requests and step execution so hosts can
+	// inject policy, telemetry and prompt shaping. Use ChainHooks to combine
+	// several.
+	Hooks Hooks
 	// EnableMetrics enables metrics collection. When true and Metrics is nil,
 	// an InMemoryMetrics instance is created automatically.
 	EnableMetrics bool
@@ -115,6 +335,26 @@ func (o *RuntimeOptions) setDefaults() {
 	if o.Model == "" {
 		o.Model = "gpt-4.1"
 	}
+	o.Provider = ResolveProvider(o.Provider, o.Model)
+	o.AzureDeployment = strings.TrimSpace(o.AzureDeployment)
+	o.AzureAPIVersion = strings.TrimSpace(o.AzureAPIVersion)
+	if strings.TrimSpace(o.TokenizerDir) == "" {
+		o.TokenizerDir = defaultTokenizerDir()
+	}
+	if o.TokenCounter == nil {
+		o.TokenCounter = DefaultTokenCounter(o.Provider, o.Model, o.TokenizerDir)
+	}
+
+	if o.AdaptiveEffort {
+		o.MinReasoningEffort = strings.ToLower(strings.TrimSpace(o.MinReasoningEffort))
+		o.MaxReasoningEffort = strings.ToLower(strings.TrimSpace(o.MaxReasoningEffort))
+		if o.MinReasoningEffort == "" {
+			o.MinReasoningEffort = defaultMinReasoningEffort
+		}
+		if o.MaxReasoningEffort == "" {
+			o.MaxReasoningEffort = defaultMaxReasoningEffort
+		}
+	}
 
 	if o.AmnesiaAfterPasses < 0 {
 		o.AmnesiaAfterPasses = 0
@@ -122,6 +362,9 @@ func (o *RuntimeOptions) setDefaults() {
 	if o.MaxPasses < 0 {
 		o.MaxPasses = 0
 	}
+	if o.MaxParallelSteps < 0 {
+		o.MaxParallelSteps = 0
+	}
 	if o.MaxContextTokens <= 0 || o.CompactWhenPercent <= 0 {
 		if budget, ok := defaultModelContextBudgets[strings.ToLower(o.Model)]; ok {
 			if o.MaxContextTokens <= 0 {
@@ -153,10 +396,31 @@ func (o *RuntimeOptions) setDefaults() {
 	if len(o.ExitCommands) == 0 {
 		o.ExitCommands = []string{"exit", "quit", "/exit", "/quit"}
 	}
+	o.ApprovalPolicy = ApprovalPolicy(strings.ToLower(strings.TrimSpace(string(o.ApprovalPolicy))))
+	if o.ApprovalPolicy == "" {
+		o.ApprovalPolicy = ApprovalPolicyNever
+	}
+	if o.CommandPolicy == nil {
+		o.CommandPolicy = DefaultCommandPolicy()
+	}
+	if o.APIRetryConfig == nil {
+		o.APIRetryConfig = DefaultRetryConfig()
+	}
+	if o.FailureLogRetention == nil {
+		o.FailureLogRetention = DefaultFailureLogRetention()
+	}
+	o.SessionID = strings.TrimSpace(o.SessionID)
+	if o.SessionID == "" {
+		o.SessionID = NewSessionID(time.Now())
+	}
 	if o.HistoryLogPath == nil {
-		defaultHistoryPath := "history.json"
+		defaultHistoryPath := SessionHistoryPath("", o.SessionID)
 		o.HistoryLogPath = &defaultHistoryPath
 	}
+	if o.EventLogPath == nil {
+		defaultEventLogPath := SessionEventLogPath("", o.SessionID)
+		o.EventLogPath = &defaultEventLogPath
+	}
 	if o.HandsFree {
 		o.HandsFreeTopic = strings.TrimSpace(o.HandsFreeTopic)
 		if o.HandsFreeTopic == "" {
@@ -214,12 +478,59 @@ func (o *RuntimeOptions) setDefaults() {
 	} else if o.Metrics == nil {
 		o.Metrics = &NoOpMetrics{}
 	}
+	if o.Tracer == nil {
+		o.Tracer = NoOpTracer{}
+	}
 }
 
 // validate performs lightweight validation of user supplied options.
 func (o *RuntimeOptions) validate() error {
-	if o.APIKey == "" {
+	switch o.Provider {
+	case ProviderOpenAI, ProviderAnthropic:
+	default:
+		return fmt.Errorf("unknown provider %q", o.Provider)
+	}
+	if err := o.CommandPolicy.Validate(); err != nil {
+		return err
+	}
+	if err := validateReadOnlyDenylist(o.ReadOnlyDenylist); err != nil {
+		return err
+	}
+	if err := validateEnvPolicy(o.EnvPolicy); err != nil {
+		return err
+	}
+	if err := validateOutputFormat(o.OutputFormat); err != nil {
+		return err
+	}
+	if err := validateToolSpecs(o.Tools); err != nil {
+		return err
+	}
+	if err := o.Budget.validate(); err != nil {
+		return err
+	}
+	if o.AdaptiveEffort {
+		if err := validateEffortBounds(o.MinReasoningEffort, o.MaxReasoningEffort); err != nil {
+			return err
+		}
+	}
+	if o.AzureDeployment != "" {
+		if o.Provider != ProviderOpenAI {
+			return fmt.Errorf("azure deployments require the %s provider", ProviderOpenAI)
+		}
+		if o.APIBaseURL == "" {
+			return errors.New("azure deployments require APIBaseURL to point at the resource endpoint")
+		}
+	}
+	if o.APIKey == "" && o.ProviderClient == nil {
+		if o.Provider == ProviderAnthropic {
+			return errors.New("ANTHROPIC_API_KEY is required")
+		}
 		return errors.New("OPENAI_API_KEY is required")
 	}
+	switch o.ApprovalPolicy {
+	case ApprovalPolicyNever, ApprovalPolicyOnWrite, ApprovalPolicyAlways:
+	default:
+		return fmt.Errorf("unknown approval policy %q", o.ApprovalPolicy)
+	}
 	return nil
 }
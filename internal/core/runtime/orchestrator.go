@@ -0,0 +1,367 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultSubAgentPasses bounds a sub-agent when its budget sets no limit.
+const defaultSubAgentPasses = 10
+
+// Orchestrator runs named child runtimes (sub-agents) on behalf of a parent
+// runtime. Child events are forwarded to the parent's Outputs with Agent set
+// to the child's name, so hosts can tell them apart.
+type Orchestrator struct {
+	parent *Runtime
+	// slots limits how many sub-agents run at once; nil means unlimited.
+	slots chan struct{}
+
+	mu     sync.Mutex
+	agents map[string]*SubAgent
+}
+
+// SubAgentSpec describes a sub-agent to spawn.
+type SubAgentSpec struct {
+	// Name identifies the sub-agent in events and must be unique among the
+	// running sub-agents.
+	Name string
+	// Goal is the prompt the sub-agent works on hands-free.
+	Goal string
+	// Options configures the child runtime. Nil inherits the parent's
+	// options. Sub-agents always run hands-free without stdin, stdout or a
+	// history log.
+	Options *RuntimeOptions
+	Budget  SubAgentBudget
+	// ReportToParent posts the result to the parent's mailbox when the
+	// sub-agent stops, for sub-agents nobody waits on.
+	ReportToParent bool
+}
+
+// SubAgentBudget bounds the work a sub-agent may do.
+type SubAgentBudget struct {
+	// MaxPasses limits plan passes. Defaults to 10.
+	MaxPasses int
+	// Timeout stops the sub-agent after this long. Zero means no limit.
+	Timeout time.Duration
+}
+
+// SubAgentResult reports how a sub-agent finished.
+type SubAgentResult struct {
+	Name string
+	Goal string
+	// Completed is true when the sub-agent reported that no further work
+	// was needed before running out of budget.
+	Completed bool
+	// Summary is the sub-agent's last assistant message.
+	Summary  string
+	Passes   int
+	Duration time.Duration
+	// Err explains why an incomplete sub-agent stopped.
+	Err error
+}
+
+// SubAgent is a running child runtime.
+type SubAgent struct {
+	name    string
+	runtime *Runtime
+	cancel  context.CancelFunc
+	done    chan struct{}
+	report  bool
+	result  SubAgentResult
+}
+
+// NewOrchestrator creates an orchestrator for parent that runs at most
+// maxConcurrent sub-agents at a time (0 means unlimited).
+func NewOrchestrator(parent *Runtime, maxConcurrent int) *Orchestrator {
+	o := &Orchestrator{parent: parent, agents: make(map[string]*SubAgent)}
+	if maxConcurrent > 0 {
+		o.slots = make(chan struct{}, maxConcurrent)
+	}
+	return o
+}
+
+// Orchestrator returns the runtime's orchestrator, limited by
+// RuntimeOptions.MaxConcurrentSubAgents.
+func (r *Runtime) Orchestrator() *Orchestrator {
+	r.orchestratorOnce.Do(func() {
+		r.orchestrator = NewOrchestrator(r, r.options.MaxConcurrentSubAgents)
+	})
+	return r.orchestrator
+}
+
+// Spawn starts a sub-agent and returns immediately. It waits for a free slot
+// in the background, so the sub-agent may start later than Spawn returns.
+func (o *Orchestrator) Spawn(ctx context.Context, spec SubAgentSpec) (*SubAgent, error) {
+	spec.Name = strings.TrimSpace(spec.Name)
+	spec.Goal = strings.TrimSpace(spec.Goal)
+	if spec.Name == "" {
+		return nil, errors.New("orchestrator: sub-agent name is required")
+	}
+	if spec.Goal == "" {
+		return nil, fmt.Errorf("orchestrator: sub-agent %q requires a goal", spec.Name)
+	}
+
+	options := o.parent.options
+	// Sub-agents work on a narrow goal; the parent's map would cost a
+	// directory walk per spawn. Hosts opt in through spec.Options.
+	options.DisableRepoMap = true
+	if spec.Options != nil {
+		options = *spec.Options
+	}
+	maxPasses := spec.Budget.MaxPasses
+	if maxPasses <= 0 {
+		maxPasses = defaultSubAgentPasses
+	}
+	disabled := ""
+	options.HandsFree = true
+	options.HandsFreeTopic = spec.Goal
+	options.MaxPasses = maxPasses
+	options.HandsFreeAutoReply = fmt.Sprintf("Please continue to work on the set goal. No human available. Goal: %s", spec.Goal)
+	options.DisableInputReader = true
+	options.DisableOutputForwarding = true
+	options.HistoryLogPath = &disabled
+	options.EventLogPath = &disabled
+	options.HistoryStore = nil
+	options.JournalPath = ""
+	options.WatchWorkspace = false
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, exists := o.agents[spec.Name]; exists {
+		return nil, fmt.Errorf("orchestrator: sub-agent %q is already running", spec.Name)
+	}
+	child, err := NewRuntime(options)
+	if err != nil {
+		return nil, fmt.Errorf("orchestrator: create sub-agent %q: %w", spec.Name, err)
+	}
+	child.agentName = spec.Name
+	// Sub-agents spend from the parent's budget.
+	child.budget.parent = &o.parent.budget
+	child.budget.parentBudget = o.parent.options.Budget
+	if err := child.executor.RegisterInternalCommand(askParentCommandName, newAskParentCommand(o, child)); err != nil {
+		return nil, fmt.Errorf("orchestrator: create sub-agent %q: %w", spec.Name, err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	if spec.Budget.Timeout > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, spec.Budget.Timeout)
+	}
+	agent := &SubAgent{
+		name:    spec.Name,
+		runtime: child,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		report:  spec.ReportToParent,
+		result:  SubAgentResult{Name: spec.Name, Goal: spec.Goal},
+	}
+	o.agents[spec.Name] = agent
+	go o.run(runCtx, agent)
+	return agent, nil
+}
+
+// Run spawns the sub-agents concurrently and returns their results in the
+// order of specs.
+func (o *Orchestrator) Run(ctx context.Context, specs ...SubAgentSpec) []SubAgentResult {
+	results := make([]SubAgentResult, len(specs))
+	agents := make([]*SubAgent, len(specs))
+	for i, spec := range specs {
+		agent, err := o.Spawn(ctx, spec)
+		if err != nil {
+			results[i] = SubAgentResult{Name: spec.Name, Goal: spec.Goal, Err: err}
+			continue
+		}
+		agents[i] = agent
+	}
+	for i, agent := range agents {
+		if agent != nil {
+			results[i] = agent.Wait()
+		}
+	}
+	return results
+}
+
+// Send queues a message for the running sub-agent with the given name. The
+// sub-agent sees it before its next model request.
+func (o *Orchestrator) Send(name, message string) error {
+	agent, ok := o.Agent(name)
+	if !ok {
+		return fmt.Errorf("orchestrator: no running sub-agent named %q", name)
+	}
+	agent.runtime.deliverMessage(o.parent.agentName, message)
+	return nil
+}
+
+// Agent returns the running sub-agent with the given name.
+func (o *Orchestrator) Agent(name string) (*SubAgent, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	agent, ok := o.agents[name]
+	return agent, ok
+}
+
+// Agents lists the names of the running sub-agents.
+func (o *Orchestrator) Agents() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	names := make([]string, 0, len(o.agents))
+	for name := range o.agents {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (o *Orchestrator) run(ctx context.Context, agent *SubAgent) {
+	defer close(agent.done)
+	defer agent.cancel()
+	defer func() {
+		o.mu.Lock()
+		delete(o.agents, agent.name)
+		o.mu.Unlock()
+	}()
+
+	start := time.Now()
+	if o.slots != nil {
+		select {
+		case o.slots <- struct{}{}:
+			defer func() { <-o.slots }()
+		case <-ctx.Done():
+			agent.result.Err = ctx.Err()
+			return
+		}
+	}
+
+	o.parent.emit(RuntimeEvent{
+		Type:     EventTypeStatus,
+		Message:  fmt.Sprintf("Sub-agent %s started: %s", agent.name, agent.result.Goal),
+		Level:    StatusLevelInfo,
+		Metadata: map[string]any{"subagent": agent.name},
+	})
+
+	child := agent.runtime
+	go func() { _ = child.Run(ctx) }()
+
+	var lastError string
+	for evt := range child.Outputs() {
+		switch evt.Type {
+		case EventTypeAssistantMessage:
+			if m := strings.TrimSpace(evt.Message); m != "" {
+				agent.result.Summary = m
+			}
+		case EventTypeStatus:
+			if complete, _ := evt.Metadata["hands_free_complete"].(bool); complete {
+				agent.result.Completed = true
+			}
+		case EventTypeError:
+			lastError = evt.Message
+		case EventTypeRequestInput:
+			// Nobody answers a sub-agent; do not prompt the host for it.
+			continue
+		}
+		o.parent.emit(evt)
+	}
+
+	agent.result.Passes = child.currentPassCount()
+	agent.result.Duration = time.Since(start)
+	if !agent.result.Completed {
+		switch {
+		case lastError != "":
+			agent.result.Err = errors.New(lastError)
+		case ctx.Err() != nil:
+			agent.result.Err = ctx.Err()
+		default:
+			agent.result.Err = errors.New("sub-agent stopped before completing its goal")
+		}
+	}
+
+	level := StatusLevelInfo
+	message := fmt.Sprintf("Sub-agent %s finished after %d pass(es).", agent.name, agent.result.Passes)
+	if agent.result.Err != nil {
+		level = StatusLevelWarn
+		message = fmt.Sprintf("Sub-agent %s stopped: %v", agent.name, agent.result.Err)
+	}
+	o.parent.emit(RuntimeEvent{
+		Type:     EventTypeStatus,
+		Message:  message,
+		Level:    level,
+		Metadata: map[string]any{"subagent": agent.name, "completed": agent.result.Completed},
+	})
+	if agent.report {
+		report := message
+		if summary := strings.TrimSpace(agent.result.Summary); summary != "" {
+			report += "\n" + summary
+		}
+		o.parent.deliverMessage(agent.name, report)
+	}
+}
+
+// Name returns the sub-agent's name.
+func (a *SubAgent) Name() string {
+	return a.name
+}
+
+// Cancel stops the sub-agent.
+func (a *SubAgent) Cancel() {
+	a.cancel()
+}
+
+// Done is closed once the sub-agent has stopped.
+func (a *SubAgent) Done() <-chan struct{} {
+	return a.done
+}
+
+// Wait blocks until the sub-agent stops and returns its result.
+func (a *SubAgent) Wait() SubAgentResult {
+	<-a.done
+	return a.result
+}
+
+// Observation reports the result the way a plan step would.
+func (r SubAgentResult) Observation() PlanObservationPayload {
+	payload := PlanObservationPayload{Data: r}
+	if r.Completed {
+		payload.Stdout = r.Summary
+		zero := 0
+		payload.ExitCode = &zero
+		return payload
+	}
+	payload.Stderr = r.Summary
+	if r.Err != nil {
+		payload.Details = r.Err.Error()
+	}
+	one := 1
+	payload.ExitCode = &one
+	return payload
+}
+
+// SubAgentObservation combines several results into one observation with a
+// section per sub-agent.
+func SubAgentObservation(results []SubAgentResult) PlanObservationPayload {
+	var b strings.Builder
+	completed := 0
+	for _, result := range results {
+		status := "completed"
+		if result.Completed {
+			completed++
+		} else {
+			status = "incomplete"
+			if result.Err != nil {
+				status = fmt.Sprintf("incomplete: %v", result.Err)
+			}
+		}
+		fmt.Fprintf(&b, "## %s (%s)\n%s\n\n", result.Name, status, strings.TrimSpace(result.Summary))
+	}
+	exitCode := 0
+	if completed < len(results) {
+		exitCode = 1
+	}
+	return PlanObservationPayload{
+		Stdout:   strings.TrimSpace(b.String()),
+		Summary:  fmt.Sprintf("%d of %d sub-agents completed.", completed, len(results)),
+		ExitCode: &exitCode,
+		Data:     results,
+	}
+}
@@ -0,0 +1,75 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/asynkron/goagent/internal/core/schema"
+)
+
+func TestOrchestratorRunsSubAgentsAndForwardsEvents(t *testing.T) {
+	t.Parallel()
+
+	parent := &Runtime{
+		options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}},
+		outputs:   make(chan RuntimeEvent, 256),
+		closed:    make(chan struct{}),
+		agentName: "main",
+	}
+	childOptions := func(message string) *RuntimeOptions {
+		historyPath := ""
+		return &RuntimeOptions{
+			HistoryLogPath:   &historyPath,
+			DisableSnapshots: true,
+			ProviderClient: &scriptedProvider{calls: []ToolCall{
+				{ID: "call-1", Name: schema.ToolName, Arguments: `{"message":"` + message + `","reasoning":[],"plan":[],"requireHumanInput":false}`},
+			}},
+		}
+	}
+
+	orchestrator := NewOrchestrator(parent, 1)
+	results := orchestrator.Run(context.Background(),
+		SubAgentSpec{Name: "alpha", Goal: "find the bug", Options: childOptions("bug found")},
+		SubAgentSpec{Name: "beta", Goal: "write the docs", Options: childOptions("docs written")},
+		SubAgentSpec{Name: "", Goal: "nameless"},
+	)
+
+	if len(results) != 3 {
+		t.Fatalf("expected three results, got %d", len(results))
+	}
+	if !results[0].Completed || results[0].Summary != "bug found" {
+		t.Fatalf("unexpected alpha result: %+v", results[0])
+	}
+	if !results[1].Completed || results[1].Summary != "docs written" {
+		t.Fatalf("unexpected beta result: %+v", results[1])
+	}
+	if results[2].Err == nil {
+		t.Fatalf("expected spawn error for unnamed sub-agent")
+	}
+	if len(orchestrator.Agents()) != 0 {
+		t.Fatalf("expected finished sub-agents to be removed, got %v", orchestrator.Agents())
+	}
+
+	if usage := parent.BudgetUsage(); usage.Requests != 2 {
+		t.Fatalf("expected the sub-agents' requests charged to the parent, got %+v", usage)
+	}
+
+	observation := SubAgentObservation(results)
+	if observation.ExitCode == nil || *observation.ExitCode != 1 || observation.Summary != "2 of 3 sub-agents completed." {
+		t.Fatalf("unexpected aggregate observation: %+v", observation)
+	}
+
+	seen := map[string]bool{}
+	for len(parent.outputs) > 0 {
+		evt := <-parent.outputs
+		seen[evt.Agent] = true
+		if evt.Type == EventTypeRequestInput {
+			t.Fatalf("sub-agent input requests must not reach the parent: %+v", evt)
+		}
+	}
+	for _, name := range []string{"main", "alpha", "beta"} {
+		if !seen[name] {
+			t.Fatalf("expected events from %s, saw %v", name, seen)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package runtime
+
+import (
+	"context"
+	"strings"
+)
+
+// Pause asks the runtime to stop starting work: running steps finish, but no
+// further ready steps start and the next plan request waits for Resume.
+func (r *Runtime) Pause(reason string) {
+	r.enqueue(InputEvent{Type: InputTypePause, Reason: reason})
+}
+
+// Resume lifts a pause.
+func (r *Runtime) Resume() {
+	r.enqueue(InputEvent{Type: InputTypeResume})
+}
+
+// Paused reports whether the runtime is paused.
+func (r *Runtime) Paused() bool {
+	return r.paused.Load()
+}
+
+// handlePauseInput applies InputTypePause and InputTypeResume and reports
+// whether evt was one of them.
+func (r *Runtime) handlePauseInput(evt InputEvent) bool {
+	switch evt.Type {
+	case InputTypePause:
+		message := "Paused: running steps finish, but no new steps or plan requests start until you resume."
+		if reason := strings.TrimSpace(evt.Reason); reason != "" {
+			message = "Paused (" + reason + "): running steps finish, but no new steps or plan requests start until you resume."
+		}
+		r.setPaused(true, message)
+	case InputTypeResume:
+		r.setPaused(false, "Resumed.")
+	default:
+		return false
+	}
+	return true
+}
+
+// setPaused changes the pause state and reports the change with a status
+// event whose "paused" metadata hosts can use to show the state.
+func (r *Runtime) setPaused(paused bool, message string) {
+	if r.paused.Swap(paused) == paused {
+		return
+	}
+	r.emit(RuntimeEvent{
+		Type:     EventTypeStatus,
+		Message:  message,
+		Level:    StatusLevelInfo,
+		Metadata: map[string]any{"paused": paused},
+	})
+}
+
+// awaitResume holds the pass loop before a plan request while the runtime is
+// paused. It reports whether the loop may continue; a cancel or shutdown
+// received while waiting stops it. Other inputs, such as prompts, stdin and
+// approval decisions, are held and re-queued once it returns.
+func (r *Runtime) awaitResume(ctx context.Context) bool {
+	if !r.paused.Load() {
+		return true
+	}
+	var held []InputEvent
+	defer func() {
+		if len(held) > 0 {
+			go func() {
+				for _, evt := range held {
+					r.enqueue(evt)
+				}
+			}()
+		}
+	}()
+	r.emit(RuntimeEvent{
+		Type:     EventTypeStatus,
+		Message:  "Paused before the next plan request; resume to continue.",
+		Level:    StatusLevelInfo,
+		Metadata: map[string]any{"paused": true},
+	})
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-r.closed:
+			return false
+		case evt, ok := <-r.inputs:
+			if !ok {
+				return false
+			}
+			if r.handlePauseInput(evt) {
+				if !r.paused.Load() {
+					return true
+				}
+				continue
+			}
+			switch evt.Type {
+			case InputTypeCancel:
+				r.setPaused(false, "Pause lifted by the cancel.")
+				r.emit(RuntimeEvent{
+					Type:    EventTypeStatus,
+					Message: "Plan cancelled by user.",
+					Level:   StatusLevelWarn,
+				})
+				r.emitRequestInput("Plan cancelled. Provide the next instruction.")
+				return false
+			case InputTypeShutdown:
+				go r.enqueue(evt)
+				return false
+			default:
+				held = append(held, evt)
+				r.emit(RuntimeEvent{
+					Type:    EventTypeStatus,
+					Message: "Paused; the input is queued until you resume.",
+					Level:   StatusLevelInfo,
+				})
+			}
+		}
+	}
+}
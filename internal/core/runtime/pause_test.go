@@ -0,0 +1,128 @@
+package runtime
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newPauseTestRuntime() *Runtime {
+	return &Runtime{
+		options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}},
+		plan:      NewPlanManager(),
+		executor:  NewCommandExecutor(nil, nil),
+		inputs:    make(chan InputEvent, 8),
+		outputs:   make(chan RuntimeEvent, 64),
+		closed:    make(chan struct{}),
+		history:   []ChatMessage{},
+		agentName: "main",
+	}
+}
+
+func TestExecutePendingCommands_PauseHoldsReadySteps(t *testing.T) {
+	t.Parallel()
+
+	rt := newPauseTestRuntime()
+	var mu sync.Mutex
+	started := map[string]time.Time{}
+	if err := rt.executor.RegisterInternalCommand("work", func(_ context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		mu.Lock()
+		started[req.Step.ID] = time.Now()
+		mu.Unlock()
+		if req.Step.ID == "first" {
+			// The running step finishes even though the pause arrives
+			// while it runs.
+			rt.Pause("looks wrong")
+			time.Sleep(50 * time.Millisecond)
+		}
+		return PlanObservationPayload{Stdout: "done"}, nil
+	}); err != nil {
+		t.Fatalf("failed to register internal command: %v", err)
+	}
+	rt.plan.Replace([]PlanStep{
+		{ID: "first", Status: PlanPending, Command: CommandDraft{Shell: agentShell, Run: "work"}},
+		{ID: "second", Status: PlanPending, WaitingForID: []string{"first"}, Command: CommandDraft{Shell: agentShell, Run: "work"}},
+	})
+
+	var resumedAt time.Time
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		mu.Lock()
+		resumedAt = time.Now()
+		mu.Unlock()
+		rt.Resume()
+	}()
+
+	if cancelled := rt.executePendingCommands(context.Background(), ToolCall{ID: "call-pause", Name: "open-agent"}); cancelled {
+		t.Fatal("expected the plan to finish after resuming")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	second, ok := started["second"]
+	if !ok || resumedAt.IsZero() || second.Before(resumedAt) {
+		t.Fatalf("expected the second step to start after resuming, started=%v resumed=%v", second, resumedAt)
+	}
+	if rt.Paused() {
+		t.Fatal("expected the runtime to be resumed")
+	}
+
+	var states []bool
+	for len(rt.outputs) > 0 {
+		if paused, ok := (<-rt.outputs).Metadata["paused"].(bool); ok {
+			states = append(states, paused)
+		}
+	}
+	if len(states) != 2 || !states[0] || states[1] {
+		t.Fatalf("expected pause and resume status events, got %v", states)
+	}
+}
+
+func TestAwaitResume(t *testing.T) {
+	t.Parallel()
+
+	rt := newPauseTestRuntime()
+	if !rt.awaitResume(context.Background()) {
+		t.Fatal("expected an unpaused runtime to continue")
+	}
+
+	rt.handlePauseInput(InputEvent{Type: InputTypePause})
+	rt.Resume()
+	if !rt.awaitResume(context.Background()) || rt.Paused() {
+		t.Fatal("expected resume to release the wait")
+	}
+
+	rt.handlePauseInput(InputEvent{Type: InputTypePause})
+	rt.Cancel("never mind")
+	if rt.awaitResume(context.Background()) {
+		t.Fatal("expected cancel to stop the loop")
+	}
+	if rt.Paused() {
+		t.Fatal("expected cancel to lift the pause")
+	}
+}
+
+func TestAwaitResumeReplaysHeldInputs(t *testing.T) {
+	t.Parallel()
+
+	rt := newPauseTestRuntime()
+	rt.handlePauseInput(InputEvent{Type: InputTypePause})
+	rt.SubmitPrompt("next task")
+	rt.SubmitApproval("step-1", true, "")
+	rt.Resume()
+	if !rt.awaitResume(context.Background()) {
+		t.Fatal("expected resume to release the wait")
+	}
+
+	for _, want := range []InputEventType{InputTypePrompt, InputTypeApprovalDecision} {
+		select {
+		case evt := <-rt.inputs:
+			if evt.Type != want {
+				t.Fatalf("expected %s to be replayed, got %+v", want, evt)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected %s held while paused to be replayed", want)
+		}
+	}
+}
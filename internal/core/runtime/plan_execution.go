@@ -2,6 +2,7 @@ package runtime
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 )
@@ -13,45 +14,77 @@ func (r *Runtime) planExecutionLoop(ctx context.Context) {
 		if ctx.Err() != nil {
 			return
 		}
-
-		pass := r.incrementPassCount()
-		r.options.Metrics.RecordPass(pass)
-		r.options.Logger.Info(ctx, "Starting plan execution pass",
-			Field("pass", pass),
-		)
-
-		if shouldStop := r.checkPassLimit(ctx, pass); shouldStop {
+		if stop := r.runPass(ctx); stop || ctx.Err() != nil {
 			return
 		}
+	}
+}
 
-		r.emit(RuntimeEvent{
-			Type:    EventTypeStatus,
-			Message: fmt.Sprintf("Starting plan execution pass #%d.", pass),
-			Level:   StatusLevelInfo,
-		})
+// runPass requests one plan and executes its ready steps inside a pass span.
+// It reports whether the loop should stop.
+func (r *Runtime) runPass(ctx context.Context) bool {
+	if !r.awaitResume(ctx) {
+		return true
+	}
+	pass := r.incrementPassCount()
+	ctx, span := r.startSpan(ctx, SpanPass, Field("pass", pass))
+	defer span.End()
 
-		plan, toolCall, err := r.requestPlan(ctx)
-		if err != nil {
-			r.handlePlanRequestError(ctx, err, pass)
-			return
-		}
+	r.options.Metrics.RecordPass(pass)
+	r.options.Logger.Info(ctx, "Starting plan execution pass",
+		Field("pass", pass),
+	)
 
-		if plan == nil {
-			r.handleNilPlanResponse(ctx, pass)
-			return
-		}
+	if shouldStop := r.checkPassLimit(ctx, pass); shouldStop {
+		return true
+	}
 
-		execCount := r.recordPlanResponse(plan, toolCall)
+	r.emit(RuntimeEvent{
+		Type:    EventTypeStatus,
+		Message: fmt.Sprintf("Starting plan execution pass #%d.", pass),
+		Level:   StatusLevelInfo,
+	})
 
-		if shouldStop := r.handlePlanState(ctx, plan, toolCall, execCount, pass); shouldStop {
-			return
+	plan, toolCall, err := r.requestPlan(ctx)
+	if err != nil {
+		span.RecordError(err)
+		var budgetErr *BudgetExceededError
+		if errors.As(err, &budgetErr) {
+			r.handleBudgetExceeded(budgetErr, pass)
+			return true
 		}
-
-		r.executePendingCommands(ctx, toolCall)
-		if ctx.Err() != nil {
-			return
+		var validationErr *PlanValidationError
+		if errors.As(err, &validationErr) {
+			r.handlePlanValidationAbort(validationErr, pass)
+			return true
 		}
+		r.handlePlanRequestError(ctx, err, pass)
+		return true
 	}
+
+	if plan == nil {
+		r.handleNilPlanResponse(ctx, pass)
+		return true
+	}
+
+	r.options.Hooks.afterPlanResponse(ctx, plan)
+	execCount := r.recordPlanResponse(plan, toolCall)
+	span.SetAttributes(Field("steps", len(plan.Plan)), Field("executable_steps", execCount))
+
+	if shouldStop := r.handlePlanState(ctx, plan, toolCall, execCount, pass); shouldStop {
+		return true
+	}
+
+	if cancelled := r.executePendingCommands(ctx, toolCall); cancelled {
+		r.emit(RuntimeEvent{
+			Type:    EventTypeStatus,
+			Message: "Plan cancelled by user.",
+			Level:   StatusLevelWarn,
+		})
+		r.emitRequestInput("Plan cancelled. Provide the next instruction.")
+		return true
+	}
+	return false
 }
 
 // checkPassLimit validates if the maximum pass limit has been reached.
@@ -151,9 +184,10 @@ func (r *Runtime) handleEmptyPlan(ctx context.Context, plan *PlanResponse, pass
 			summary = fmt.Sprintf("%s Summary: %s", summary, trimmed)
 		}
 		r.emit(RuntimeEvent{
-			Type:    EventTypeStatus,
-			Message: summary,
-			Level:   StatusLevelInfo,
+			Type:     EventTypeStatus,
+			Message:  summary,
+			Level:    StatusLevelInfo,
+			Metadata: map[string]any{"hands_free_complete": true},
 		})
 		r.close()
 		return true
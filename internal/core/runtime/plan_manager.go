@@ -3,6 +3,7 @@ package runtime
 import (
 	"errors"
 	"sync"
+	"time"
 )
 
 // PlanManager maintains the merged plan shared across passes.
@@ -10,12 +11,21 @@ type PlanManager struct {
 	mu    sync.RWMutex
 	order []string
 	steps map[string]*PlanStep
+	// timings records when each step started and finished executing. It
+	// survives Replace for steps the model keeps in the plan.
+	timings map[string]stepTiming
+}
+
+type stepTiming struct {
+	started  time.Time
+	finished time.Time
 }
 
 // NewPlanManager constructs an empty plan manager.
 func NewPlanManager() *PlanManager {
 	return &PlanManager{
-		steps: make(map[string]*PlanStep),
+		steps:   make(map[string]*PlanStep),
+		timings: make(map[string]stepTiming),
 	}
 }
 
@@ -32,13 +42,44 @@ func (pm *PlanManager) Replace(steps []PlanStep) {
 		pm.steps[step.ID] = &copied
 		pm.order = append(pm.order, step.ID)
 	}
+	for id := range pm.timings {
+		if _, ok := pm.steps[id]; !ok {
+			delete(pm.timings, id)
+		}
+	}
 }
 
 // Snapshot returns a deep copy of the plan for external observers.
 func (pm *PlanManager) Snapshot() []PlanStep {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
+	return pm.snapshotLocked()
+}
+
+// Progress returns a copy of the plan together with step timings.
+func (pm *PlanManager) Progress() PlanSnapshot {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	steps := pm.snapshotLocked()
+	snapshot := PlanSnapshot{Steps: make([]PlanStepProgress, 0, len(steps))}
+	for _, step := range steps {
+		progress := PlanStepProgress{PlanStep: step, Executing: step.Executing}
+		if timing, ok := pm.timings[step.ID]; ok {
+			started := timing.started
+			progress.StartedAt = &started
+			if !timing.finished.IsZero() {
+				finished := timing.finished
+				progress.FinishedAt = &finished
+			}
+		}
+		snapshot.Steps = append(snapshot.Steps, progress)
+		snapshot.count(step.Status)
+	}
+	return snapshot
+}
 
+func (pm *PlanManager) snapshotLocked() []PlanStep {
 	result := make([]PlanStep, 0, len(pm.order))
 	for _, id := range pm.order {
 		if step, ok := pm.steps[id]; ok {
@@ -68,13 +109,21 @@ func (pm *PlanManager) Snapshot() []PlanStep {
 
 // Ready returns the next executable plan step if all dependencies have completed.
 func (pm *PlanManager) Ready() (*PlanStep, bool) {
+	return pm.ReadyWhere(nil)
+}
+
+// ReadyWhere is like Ready but skips steps for which allow returns false, so
+// callers can hold back steps whose concurrency group is busy. A nil allow
+// accepts every ready step.
+func (pm *PlanManager) ReadyWhere(allow func(PlanStep) bool) (*PlanStep, bool) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
 	for _, id := range pm.order {
 		step := pm.steps[id]
-		if pm.stepReadyLocked(step) {
+		if pm.stepReadyLocked(step) && (allow == nil || allow(*step)) {
 			step.Executing = true
+			pm.timings[id] = stepTiming{started: time.Now()}
 			copied := *step
 			return &copied, true
 		}
@@ -127,12 +176,27 @@ func (pm *PlanManager) UpdateStatus(id string, status PlanStatus, observation *P
 	}
 	step.Status = status
 	step.Executing = false
+	if timing, ok := pm.timings[id]; ok && status != PlanPending && timing.finished.IsZero() {
+		timing.finished = time.Now()
+		pm.timings[id] = timing
+	}
 	if observation != nil {
 		step.Observation = observation
 	}
 	return nil
 }
 
+// recordTiming stores measured start and finish times for a step, replacing
+// the scheduling-time estimate taken by ReadyWhere.
+func (pm *PlanManager) recordTiming(id string, started, finished time.Time) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if _, ok := pm.steps[id]; ok {
+		pm.timings[id] = stepTiming{started: started, finished: finished}
+	}
+}
+
 // HasPending reports whether any step is still pending.
 func (pm *PlanManager) HasPending() bool {
 	pm.mu.RLock()
@@ -0,0 +1,59 @@
+package runtime
+
+import "time"
+
+// PlanSnapshot is the plan as reported to hosts: every step in plan order
+// with its status, timings, and latest observation, plus totals by status.
+type PlanSnapshot struct {
+	Steps     []PlanStepProgress `json:"steps"`
+	Total     int                `json:"total"`
+	Pending   int                `json:"pending"`
+	Completed int                `json:"completed"`
+	Failed    int                `json:"failed"`
+	Abandoned int                `json:"abandoned"`
+	Cancelled int                `json:"cancelled"`
+}
+
+// PlanStepProgress is a plan step with its execution state. StartedAt is
+// set once the step has been scheduled and FinishedAt once it has a final
+// status; both are nil for steps that have not run in this session.
+type PlanStepProgress struct {
+	PlanStep
+	Executing  bool       `json:"executing"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Duration reports how long the step ran, or has been running so far.
+func (p PlanStepProgress) Duration() time.Duration {
+	switch {
+	case p.StartedAt == nil:
+		return 0
+	case p.FinishedAt == nil:
+		return time.Since(*p.StartedAt)
+	default:
+		return p.FinishedAt.Sub(*p.StartedAt)
+	}
+}
+
+func (s *PlanSnapshot) count(status PlanStatus) {
+	s.Total++
+	switch status {
+	case PlanPending:
+		s.Pending++
+	case PlanCompleted:
+		s.Completed++
+	case PlanFailed:
+		s.Failed++
+	case PlanAbandoned:
+		s.Abandoned++
+	case PlanCancelled:
+		s.Cancelled++
+	}
+}
+
+// PlanSnapshot returns the current plan with step statuses, timings, and
+// observations. It is safe to call from any goroutine while the runtime runs.
+func (r *Runtime) PlanSnapshot() PlanSnapshot {
+	return r.plan.Progress()
+}
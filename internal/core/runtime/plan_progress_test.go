@@ -0,0 +1,66 @@
+package runtime
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPlanManagerProgressTracksTimings(t *testing.T) {
+	t.Parallel()
+
+	pm := NewPlanManager()
+	pm.Replace([]PlanStep{
+		{ID: "a", Title: "build", Status: PlanPending},
+		{ID: "b", Title: "test", Status: PlanPending, WaitingForID: []string{"a"}},
+	})
+
+	if _, ok := pm.Ready(); !ok {
+		t.Fatalf("expected step a to be ready")
+	}
+	snapshot := pm.Progress()
+	if snapshot.Total != 2 || snapshot.Pending != 2 {
+		t.Fatalf("unexpected totals: %+v", snapshot)
+	}
+	running := snapshot.Steps[0]
+	if !running.Executing || running.StartedAt == nil || running.FinishedAt != nil {
+		t.Fatalf("expected a to be running with a start time, got %+v", running)
+	}
+	if snapshot.Steps[1].StartedAt != nil {
+		t.Fatalf("expected b not to have started")
+	}
+
+	if err := pm.UpdateStatus("a", PlanCompleted, nil); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+	snapshot = pm.Progress()
+	done := snapshot.Steps[0]
+	if done.Executing || done.FinishedAt == nil || done.FinishedAt.Before(*done.StartedAt) {
+		t.Fatalf("expected a to be finished, got %+v", done)
+	}
+	if snapshot.Completed != 1 || snapshot.Pending != 1 {
+		t.Fatalf("unexpected totals: %+v", snapshot)
+	}
+
+	// Timings survive a re-plan that keeps the step and are dropped with it.
+	pm.Replace([]PlanStep{{ID: "a", Status: PlanCompleted}})
+	if pm.Progress().Steps[0].StartedAt == nil {
+		t.Fatalf("expected timings to survive Replace")
+	}
+	pm.Replace([]PlanStep{{ID: "c", Status: PlanPending}})
+	pm.Replace([]PlanStep{{ID: "a", Status: PlanPending}})
+	if pm.Progress().Steps[0].StartedAt != nil {
+		t.Fatalf("expected timings to be dropped with the step")
+	}
+
+	encoded, err := json.Marshal(snapshot.Steps[0])
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if decoded["id"] != "a" || decoded["executing"] != false || decoded["finished_at"] == nil {
+		t.Fatalf("unexpected JSON: %s", encoded)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !unix
+
+package runtime
+
+import "os/exec"
+
+// setProcessGroup is a no-op where process groups are unavailable; only the
+// direct child is stopped.
+func setProcessGroup(*exec.Cmd) {}
+
+func terminateProcessGroup(cmd *exec.Cmd) error {
+	return killProcessGroup(cmd)
+}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
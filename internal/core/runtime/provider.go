@@ -0,0 +1,68 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Provider requests plans from a model backend. Implementations translate the
+// runtime's chat history into their wire format and map the forced plan tool
+// invocation back into a ToolCall.
+type Provider interface {
+	// RequestPlan returns the plan tool call without streaming deltas.
+	RequestPlan(ctx context.Context, history []ChatMessage) (ToolCall, error)
+	// RequestPlanStreaming forwards assistant text deltas to onDelta (when
+	// non-nil) and returns the plan tool call once the stream completes.
+	// Reasoning goes to the ReasoningHandler of ctx instead.
+	RequestPlanStreaming(ctx context.Context, history []ChatMessage, onDelta func(string)) (ToolCall, error)
+}
+
+// Supported provider identifiers for RuntimeOptions.Provider.
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+)
+
+// ResolveProvider picks the provider name from the explicit option or, when
+// empty, from the model identifier ("claude-*" selects Anthropic).
+func ResolveProvider(name, model string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name != "" {
+		return name
+	}
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(model)), "claude") {
+		return ProviderAnthropic
+	}
+	return ProviderOpenAI
+}
+
+// newProvider constructs the client selected by the runtime options.
+func newProvider(options RuntimeOptions, httpTimeout time.Duration) (Provider, error) {
+	switch options.Provider {
+	case ProviderAnthropic:
+		client, err := NewAnthropicClient(options.APIKey, options.Model, options.APIBaseURL, options.Logger, options.Metrics, options.APIRetryConfig, httpTimeout)
+		if err != nil {
+			return nil, err
+		}
+		client.AddTools(toolDefinitions(options.Tools)...)
+		return client, nil
+	case ProviderOpenAI:
+		client, err := NewOpenAIClient(options.APIKey, options.Model, options.ReasoningEffort, options.APIBaseURL, options.Logger, options.Metrics, options.APIRetryConfig, httpTimeout)
+		if err != nil {
+			return nil, err
+		}
+		client.AddTools(toolDefinitions(options.Tools)...)
+		client.SetPromptCacheKey(options.PromptCacheKey)
+		client.SetReasoningSummary(options.ReasoningSummary)
+		client.SetBackgroundMode(options.BackgroundResponses)
+		client.SetStructuredOutput(options.StructuredOutput)
+		if options.AzureDeployment != "" {
+			client.SetAzureDeployment(options.AzureDeployment, options.AzureAPIVersion)
+		}
+		return client, nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q", options.Provider)
+	}
+}
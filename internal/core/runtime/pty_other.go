@@ -0,0 +1,18 @@
+//go:build !linux
+
+package runtime
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// openPTY is only implemented on Linux; other platforms fall back to pipes.
+func openPTY() (master, slave *os.File, err error) {
+	return nil, nil, errors.New("pseudo-terminals are not supported on this platform")
+}
+
+func setPTYSize(*os.File, int, int) error { return nil }
+
+func ptySysProcAttr(attr *syscall.SysProcAttr) *syscall.SysProcAttr { return attr }
@@ -0,0 +1,51 @@
+package runtime
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// defaultReadOnlyDenylist catches programs that stepMayWrite treats as
+// read-only but that can still modify files through their arguments.
+var defaultReadOnlyDenylist = []string{
+	`\bfind\b.*\s-(delete|exec|execdir|ok|okdir|fprint\w*)\b`,
+	`\bawk\b.*\bsystem\s*\(`,
+	`\bgit\s+branch\s+-[dDmMcC]\b`,
+}
+
+// readOnlyPromptNote is appended to the system prompt in read-only mode so
+// the model plans inspection-only steps instead of tripping the guard.
+const readOnlyPromptNote = "The runtime is in read-only mode: steps that modify files, run apply_patch, or use output redirection will be rejected. Limit plans to commands that inspect the workspace."
+
+// readOnlyViolation explains why step is not allowed in read-only mode, or
+// returns an empty string when the step may run.
+func (r *Runtime) readOnlyViolation(step PlanStep) string {
+	if !r.options.ReadOnly {
+		return ""
+	}
+	if stepMayWrite(step) {
+		return fmt.Sprintf("step %s was rejected: read-only mode only allows commands that inspect the workspace (%q may write)", step.ID, strings.TrimSpace(step.Command.Run))
+	}
+	patterns := append(append([]string(nil), defaultReadOnlyDenylist...), r.options.ReadOnlyDenylist...)
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Sprintf("step %s was rejected: invalid read-only denylist pattern %q", step.ID, pattern)
+		}
+		if re.MatchString(step.Command.Run) {
+			return fmt.Sprintf("step %s was rejected: read-only mode denies commands matching %q", step.ID, pattern)
+		}
+	}
+	return ""
+}
+
+// validateReadOnlyDenylist ensures host supplied patterns compile.
+func validateReadOnlyDenylist(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid read-only denylist pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package runtime
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestReadOnlyViolation(t *testing.T) {
+	t.Parallel()
+
+	rt := &Runtime{options: RuntimeOptions{ReadOnly: true, ReadOnlyDenylist: []string{`\bcat\s+secrets`}}}
+	cases := []struct {
+		run     string
+		blocked bool
+	}{
+		{run: "ls -la && grep -rn TODO .", blocked: false},
+		{run: "rm -rf build", blocked: true},
+		{run: "echo hi > out.txt", blocked: true},
+		{run: "find . -name '*.tmp' -delete", blocked: true},
+		{run: "cat secrets.env", blocked: true},
+	}
+	for _, tc := range cases {
+		step := PlanStep{ID: "s", Command: CommandDraft{Shell: "bash", Run: tc.run}}
+		if got := rt.readOnlyViolation(step) != ""; got != tc.blocked {
+			t.Fatalf("readOnlyViolation(%q) blocked=%v, want %v", tc.run, got, tc.blocked)
+		}
+	}
+
+	rt.options.ReadOnly = false
+	if reason := rt.readOnlyViolation(PlanStep{Command: CommandDraft{Run: "rm -rf build"}}); reason != "" {
+		t.Fatalf("expected no restriction outside read-only mode, got %q", reason)
+	}
+}
+
+func TestExecutePendingCommands_ReadOnlyRejectsPatch(t *testing.T) {
+	t.Parallel()
+
+	rt := &Runtime{
+		options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}, ReadOnly: true},
+		inputs:    make(chan InputEvent, 1),
+		plan:      NewPlanManager(),
+		executor:  NewCommandExecutor(nil, nil),
+		outputs:   make(chan RuntimeEvent, 10),
+		closed:    make(chan struct{}),
+		history:   []ChatMessage{},
+		agentName: "main",
+	}
+
+	executed := false
+	if err := rt.executor.RegisterInternalCommand(applyPatchCommandName, func(context.Context, InternalCommandRequest) (PlanObservationPayload, error) {
+		executed = true
+		return PlanObservationPayload{}, nil
+	}); err != nil {
+		t.Fatalf("failed to register internal command: %v", err)
+	}
+	rt.plan.Replace([]PlanStep{{
+		ID:      "step-1",
+		Title:   "Patch",
+		Status:  PlanPending,
+		Command: CommandDraft{Shell: agentShell, Run: "apply_patch\n*** Begin Patch\n*** End Patch"},
+	}})
+
+	rt.executePendingCommands(context.Background(), ToolCall{ID: "call-1", Name: "open-agent"})
+	close(rt.outputs)
+
+	if executed {
+		t.Fatalf("expected read-only mode to block apply_patch")
+	}
+	history := rt.historySnapshot()
+	if len(history) != 1 || !strings.Contains(history[0].Content, "read-only mode") {
+		t.Fatalf("expected read-only explanation in tool observation, got %#v", history)
+	}
+}
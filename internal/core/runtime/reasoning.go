@@ -0,0 +1,24 @@
+package runtime
+
+import "context"
+
+// reasoningHandlerKey is the context key for the reasoning handler.
+type reasoningHandlerKey struct{}
+
+// WithReasoningHandler returns a context whose plan requests forward model
+// reasoning to onReasoning: the plan's "reasoning" entries as they stream
+// and, where the provider offers them, native reasoning summaries. Reasoning
+// is kept out of the onDelta text so hosts can show it apart from the reply.
+func WithReasoningHandler(ctx context.Context, onReasoning func(string)) context.Context {
+	return context.WithValue(ctx, reasoningHandlerKey{}, onReasoning)
+}
+
+// ReasoningHandler returns the handler set by WithReasoningHandler, or nil.
+// Providers call it to stream reasoning.
+func ReasoningHandler(ctx context.Context) func(string) {
+	if ctx == nil {
+		return nil
+	}
+	onReasoning, _ := ctx.Value(reasoningHandlerKey{}).(func(string))
+	return onReasoning
+}
@@ -0,0 +1,46 @@
+package runtime
+
+import (
+	"context"
+	"time"
+)
+
+const repoMapIntro = "## Repository map\nAn outline of the working directory: directories with their file counts, notable files, and the exported symbols of Go and TypeScript/JavaScript files (methods as Type.Method). It is refreshed when files change. Use it to decide where to look instead of listing directories; read the files before relying on details."
+
+// refreshRepoMap regenerates the repository map before a plan request. The
+// first call builds it, so creating a runtime does not walk the tree; later
+// calls only parse files that changed.
+func (r *Runtime) refreshRepoMap(ctx context.Context) {
+	if r.repoMap == nil {
+		return
+	}
+	text, err := r.repoMap.Generate(ctx)
+	if err != nil {
+		r.options.Logger.Warn(ctx, "Failed to refresh repository map", Field("error", err.Error()))
+		return
+	}
+	r.historyMu.Lock()
+	r.repoMapText = text
+	r.historyMu.Unlock()
+}
+
+// withRepoMapMessage inserts the repository map into a plan request as its
+// own system message after the stable prefix. Keeping it out of the stable
+// system prompt lets the map change without breaking the prompt cache, and
+// keeping it out of the history keeps it out of saved sessions.
+func (r *Runtime) withRepoMapMessage(history []ChatMessage) []ChatMessage {
+	r.historyMu.Lock()
+	text := r.repoMapText
+	r.historyMu.Unlock()
+	if text == "" {
+		return history
+	}
+	prefix := 0
+	for prefix < len(history) && history[prefix].Stable {
+		prefix++
+	}
+	withMap := make([]ChatMessage, 0, len(history)+1)
+	withMap = append(withMap, history[:prefix]...)
+	withMap = append(withMap, ChatMessage{Role: RoleSystem, Content: repoMapIntro + "\n\n" + text, Timestamp: time.Now()})
+	return append(withMap, history[prefix:]...)
+}
@@ -0,0 +1,47 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/asynkron/goagent/internal/repomap"
+)
+
+func TestRepoMapIsSentAfterTheStableSystemPrompt(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	write := func(content string) {
+		if err := os.WriteFile(filepath.Join(dir, "api.go"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("package api\n\nfunc Serve() {}\n")
+
+	rt := newSessionTestRuntime(t)
+	rt.repoMap = repomap.New(dir, repomap.Options{})
+	rt.appendHistory(ChatMessage{Role: RoleUser, Content: "hello"})
+	system := rt.historySnapshot()[0]
+
+	rt.refreshRepoMap(context.Background())
+	request := rt.withRepoMapMessage(rt.historySnapshot())
+	if len(request) != 3 || request[0].Content != system.Content || request[1].Stable || request[1].Role != RoleSystem || request[2].Content != "hello" {
+		t.Fatalf("expected the map between the system prompt and the conversation, got %#v", request)
+	}
+	if !strings.HasSuffix(request[1].Content, repoMapIntro+"\n\n./ (1 file)\n  api.go: Serve") {
+		t.Fatalf("unexpected map message:\n%s", request[1].Content)
+	}
+
+	write("package api\n\nfunc Serve() {}\n\nfunc Shutdown() {}\n")
+	rt.refreshRepoMap(context.Background())
+	request = rt.withRepoMapMessage(rt.historySnapshot())
+	if !strings.HasSuffix(request[1].Content, "api.go: Serve, Shutdown") || request[0].Content != system.Content {
+		t.Fatalf("expected a refreshed map and an unchanged system prompt, got %#v", request)
+	}
+	if history := rt.historySnapshot(); len(history) != 2 || strings.Contains(history[0].Content, "## Repository map") {
+		t.Fatalf("expected the map to stay out of the history, got %#v", history)
+	}
+}
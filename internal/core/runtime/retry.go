@@ -4,7 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"math/rand/v2"
 	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 )
 
@@ -18,24 +24,46 @@ type RetryConfig struct {
 	MaxBackoff time.Duration
 	// Multiplier is the factor by which backoff increases with each retry (exponential backoff).
 	Multiplier float64
+	// Jitter randomizes each delay by up to this fraction in either
+	// direction (0.2 = ±20%) so clients do not retry in lockstep.
+	Jitter float64
+	// OnRetry, when set, is called before waiting for the next attempt.
+	OnRetry func(RetryNotice)
+}
+
+// RetryNotice describes an upcoming retry.
+type RetryNotice struct {
+	// Attempt is the number of the attempt about to be made (2 for the
+	// first retry) out of MaxAttempts.
+	Attempt     int
+	MaxAttempts int
+	Delay       time.Duration
+	Err         error
 }
 
 // DefaultRetryConfig returns a sensible default retry configuration for API calls.
 func DefaultRetryConfig() *RetryConfig {
 	return &RetryConfig{
-		MaxRetries:     3,
-		InitialBackoff: 500 * time.Millisecond,
-		MaxBackoff:     8 * time.Second,
+		MaxRetries:     4,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
 		Multiplier:     2.0,
+		Jitter:         0.2,
 	}
 }
 
 // isRetryableError determines if an error should be retried.
 func isRetryableError(err error) bool {
-	if err == nil {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 		return false
 	}
 
+	// Connections dropped by the server or a proxy.
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
 	var netErr net.Error
 	if errors.As(err, &netErr) {
 		// Network errors are retryable
@@ -64,6 +92,8 @@ type retryableAPIError struct {
 	err        error
 	statusCode int
 	retryable  bool
+	// retryAfter is the delay requested by the server, if any.
+	retryAfter time.Duration
 }
 
 func (e *retryableAPIError) Error() string {
@@ -77,7 +107,45 @@ func (e *retryableAPIError) Unwrap() error {
 	return e.err
 }
 
+// retryAfterFromHeader reads the delay requested through retry-after-ms or
+// Retry-After (seconds or an HTTP date). It returns 0 when none is set.
+func retryAfterFromHeader(header http.Header) time.Duration {
+	if ms, err := strconv.ParseFloat(strings.TrimSpace(header.Get("retry-after-ms")), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(at); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+// jitter spreads delay by up to fraction in either direction.
+func jitter(delay time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 || delay <= 0 {
+		return delay
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	spread := (rand.Float64()*2 - 1) * fraction
+	return time.Duration(float64(delay) * (1 + spread))
+}
+
 // executeWithRetry executes a function with retry logic for transient failures.
+// Delays grow exponentially with jitter; a Retry-After from the server
+// replaces the computed delay.
 func executeWithRetry(ctx context.Context, config *RetryConfig, fn func() error) error {
 	if config == nil || config.MaxRetries <= 0 {
 		// No retry config or retries disabled - execute once
@@ -107,11 +175,24 @@ func executeWithRetry(ctx context.Context, config *RetryConfig, fn func() error)
 			break
 		}
 
+		delay := jitter(backoff, config.Jitter)
+		if retryErr.retryAfter > 0 {
+			delay = retryErr.retryAfter
+		}
+		if config.OnRetry != nil {
+			config.OnRetry(RetryNotice{
+				Attempt:     attempt + 2,
+				MaxAttempts: config.MaxRetries + 1,
+				Delay:       delay,
+				Err:         err,
+			})
+		}
+
 		// Wait before retry (with context cancellation check)
 		select {
 		case <-ctx.Done():
 			return fmt.Errorf("context cancelled during retry: %w", ctx.Err())
-		case <-time.After(backoff):
+		case <-time.After(delay):
 			// Continue with retry
 		}
 
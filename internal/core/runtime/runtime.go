@@ -5,9 +5,15 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/asynkron/goagent/internal/lsp"
+	"github.com/asynkron/goagent/internal/repomap"
+	"github.com/asynkron/goagent/internal/semindex"
 )
 
 // Runtime is the Go counterpart to the TypeScript AgentRuntime. It exposes two
@@ -24,9 +30,13 @@ type Runtime struct {
 	closed    chan struct{}
 
 	plan      *PlanManager
-	client    *OpenAIClient
+	clientMu  sync.RWMutex
+	client    Provider
 	executor  *CommandExecutor
 	commandMu sync.Mutex
+	// clientOptions are the options client was built from; SwitchModel
+	// replaces them and per-prompt model overrides start from them.
+	clientOptions RuntimeOptions
 
 	workMu  sync.Mutex
 	working bool
@@ -37,15 +47,74 @@ type Runtime struct {
 	passMu    sync.Mutex
 	passCount int
 
+	budget budgetTracker
+
+	// watcher is set when WatchWorkspace is on; stepsRunning pauses it
+	// while the agent's own steps modify the tree.
+	watcher      *workspaceWatcher
+	stepsRunning atomic.Bool
+
+	// paused is set by InputTypePause; see pause.go.
+	paused atomic.Bool
+
 	agentName string
 
 	contextBudget ContextBudget
+	// summaryClient serves summarizing compactions. It is client unless
+	// RuntimeOptions.CompactionModel selects a different model.
+	summaryClient Provider
+	// tokens measures history against contextBudget.
+	tokens TokenCounter
+
+	// subscribers receives every emitted event in addition to outputs.
+	// outputsClaimed records that the host called Outputs.
+	subscribers    eventHub
+	outputsClaimed atomic.Bool
+	// events records emitted events to RuntimeOptions.EventLogPath.
+	events eventRecorder
+
+	orchestratorOnce sync.Once
+	orchestrator     *Orchestrator
+	// inbox holds messages from the parent runtime or sub-agents.
+	inbox mailbox
+	// steering holds prompts submitted while the agent works.
+	steering steeringQueue
+	// instructions lists the project instruction files in the system prompt.
+	instructions []InstructionFile
+
+	// memory stores facts remembered across sessions. Nil when memory is
+	// disabled. memoriesLoaded counts those added to the system prompt.
+	memory         *MemoryStore
+	memoriesLoaded int
+
+	// repoMap outlines the working directory for plan requests; nil when
+	// the map is disabled. repoMapText is its latest rendering.
+	repoMap     *repomap.Generator
+	repoMapText string
+
+	// snapshots records file originals so passes can be undone. Nil when
+	// snapshots are disabled.
+	snapshots *snapshotManager
+
+	// diagnostics queries the configured language servers after
+	// apply_patch. Nil when none are configured.
+	diagnostics *lsp.Manager
+
+	// semanticIndex backs semantic_search. Nil without an embedder.
+	semanticIndex *semindex.Index
+
+	// validationFailures counts consecutive plan responses that failed
+	// validation. Only the loop goroutine touches it.
+	validationFailures int
 
 	// logFileCloser holds a reference to the log file if one was opened,
 	// so it can be closed when the runtime shuts down.
 	logFileCloser io.Closer
 }
 
+// defaultHTTPTimeout bounds model requests when HTTPTimeout is unset.
+const defaultHTTPTimeout = 120 * time.Second
+
 // NewRuntime configures a new runtime with the provided options.
 func NewRuntime(options RuntimeOptions) (*Runtime, error) {
 	options.setDefaults()
@@ -55,31 +124,142 @@ func NewRuntime(options RuntimeOptions) (*Runtime, error) {
 
 	httpTimeout := options.HTTPTimeout
 	if httpTimeout == 0 {
-		httpTimeout = 120 * time.Second
+		httpTimeout = defaultHTTPTimeout
 	}
 
-	client, err := NewOpenAIClient(options.APIKey, options.Model, options.ReasoningEffort, options.APIBaseURL, options.Logger, options.Metrics, options.APIRetryConfig, httpTimeout)
+	// Retries are reported to the host; the runtime is created below, before
+	// any request can be made.
+	var rt *Runtime
+	retry := *options.APIRetryConfig
+	hostOnRetry := retry.OnRetry
+	retry.OnRetry = func(notice RetryNotice) {
+		rt.emit(RuntimeEvent{
+			Type:    EventTypeStatus,
+			Message: fmt.Sprintf("Model request failed (%v); retrying in %s (attempt %d/%d)", notice.Err, notice.Delay.Round(100*time.Millisecond), notice.Attempt, notice.MaxAttempts),
+			Level:   StatusLevelWarn,
+			Metadata: map[string]any{
+				"attempt":      notice.Attempt,
+				"max_attempts": notice.MaxAttempts,
+				"delay_ms":     notice.Delay.Milliseconds(),
+			},
+		})
+		if hostOnRetry != nil {
+			hostOnRetry(notice)
+		}
+	}
+	options.APIRetryConfig = &retry
+
+	client := options.ProviderClient
+	var err error
+	if client == nil {
+		client, err = newProvider(options, httpTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("runtime: failed to create %s client: %w", options.Provider, err)
+		}
+	}
+	client, err = withFallbacks(options, client, httpTimeout, func(notice fallbackNotice) {
+		rt.emitFallback(notice)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("runtime: failed to create OpenAI client: %w", err)
+		return nil, fmt.Errorf("runtime: %w", err)
+	}
+	summaryClient := client
+	if model := strings.TrimSpace(options.CompactionModel); options.ProviderClient == nil && options.SummarizeCompaction && model != "" && model != options.Model {
+		summaryOptions := options
+		summaryOptions.Model = model
+		summaryOptions.Tools = nil
+		summaryClient, err = newProvider(summaryOptions, httpTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("runtime: failed to create compaction client: %w", err)
+		}
 	}
 
+	augment := options.SystemPromptAugment
+	var instructionFiles []InstructionFile
+	if !options.DisableInstructionFiles {
+		if wd, err := os.Getwd(); err == nil {
+			var instructions string
+			instructions, instructionFiles, err = DiscoverInstructions(wd, options.InstructionFileNames, options.InstructionMaxBytes)
+			if err != nil {
+				return nil, fmt.Errorf("runtime: failed to load project instructions: %w", err)
+			}
+			if instructions != "" {
+				augment = strings.TrimSpace(augment + "\n\n" + instructions)
+			}
+		}
+	}
+	var (
+		memory         *MemoryStore
+		memoriesLoaded int
+	)
+	if !options.DisableMemory {
+		path := options.MemoryPath
+		if path == "" {
+			if wd, err := os.Getwd(); err == nil {
+				path = filepath.Join(wd, ".goagent", "memory.json")
+			}
+		}
+		if path != "" {
+			memory = NewMemoryStore(path)
+			memories, err := memory.All()
+			if err != nil {
+				// A damaged file should not stop the session; remember
+				// reports the error when the model tries to write.
+				options.Logger.Warn(context.Background(), "Failed to load memories", Field("error", err.Error()))
+			}
+			if note := memoryPrompt(memories, options.MemoryPromptBytes); note != "" {
+				augment = strings.TrimSpace(augment + "\n\n" + note)
+				memoriesLoaded = len(memories)
+			}
+		}
+	}
+	if options.ReadOnly {
+		augment = strings.TrimSpace(augment + "\n\n" + readOnlyPromptNote)
+	}
+	systemPrompt := buildSystemPrompt(augment)
+	var repoMap *repomap.Generator
+	if !options.DisableRepoMap {
+		if wd, err := os.Getwd(); err == nil {
+			repoMap = repomap.New(wd, repomap.Options{MaxTokens: options.RepoMapTokens})
+		}
+	}
 	initialHistory := []ChatMessage{{
 		Role:      RoleSystem,
-		Content:   buildSystemPrompt(options.SystemPromptAugment),
+		Content:   systemPrompt,
 		Timestamp: time.Now(),
 		Pass:      0,
+		Stable:    true,
 	}}
+	// Only host supplied stores are resumed from; the default JSON log is
+	// overwritten by every run.
+	if options.HistoryStore != nil {
+		stored, err := options.HistoryStore.Load(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("runtime: failed to load history: %w", err)
+		}
+		if len(stored) > 0 && stored[0].Role == RoleSystem {
+			stored = stored[1:]
+		}
+		initialHistory = append(initialHistory, stored...)
+	}
 
-	rt := &Runtime{
-		options:       options,
-		inputs:        make(chan InputEvent, options.InputBuffer),
-		outputs:       make(chan RuntimeEvent, options.OutputBuffer),
-		closed:        make(chan struct{}),
-		plan:          NewPlanManager(),
-		client:        client,
-		history:       initialHistory,
-		agentName:     "main",
-		contextBudget: ContextBudget{MaxTokens: options.MaxContextTokens, CompactWhenPercent: options.CompactWhenPercent},
+	rt = &Runtime{
+		options:        options,
+		inputs:         make(chan InputEvent, options.InputBuffer),
+		outputs:        make(chan RuntimeEvent, options.OutputBuffer),
+		closed:         make(chan struct{}),
+		plan:           NewPlanManager(),
+		client:         client,
+		clientOptions:  options,
+		summaryClient:  summaryClient,
+		tokens:         options.TokenCounter,
+		history:        initialHistory,
+		agentName:      "main",
+		instructions:   instructionFiles,
+		memory:         memory,
+		memoriesLoaded: memoriesLoaded,
+		repoMap:        repoMap,
+		contextBudget:  ContextBudget{MaxTokens: options.MaxContextTokens, CompactWhenPercent: options.CompactWhenPercent},
 	}
 
 	// If logger was created from a file, extract and store the file handle for cleanup
@@ -88,7 +268,61 @@ func NewRuntime(options RuntimeOptions) (*Runtime, error) {
 			rt.logFileCloser = file
 		}
 	}
+	if options.EventLogPath != nil {
+		rt.events.path = strings.TrimSpace(*options.EventLogPath)
+	}
+	if !options.DisableSnapshots {
+		if wd, err := os.Getwd(); err == nil {
+			rt.snapshots = newSnapshotManager(filepath.Join(wd, ".goagent", "snapshots"), wd)
+		}
+	}
+	if options.WatchWorkspace {
+		if wd, err := os.Getwd(); err == nil {
+			rt.watcher = newWorkspaceWatcher(wd)
+		}
+	}
+	if options.SemanticSearch.Embedder != nil {
+		if wd, err := os.Getwd(); err == nil {
+			rt.semanticIndex, err = semindex.New(semindex.Options{
+				Root:     wd,
+				Dir:      options.SemanticSearch.IndexDir,
+				Embedder: options.SemanticSearch.Embedder,
+				MaxFiles: options.SemanticSearch.MaxFiles,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("runtime: %w", err)
+			}
+		}
+	}
+	if len(options.LanguageServers) > 0 {
+		if wd, err := os.Getwd(); err == nil {
+			rt.diagnostics = lsp.NewManager(wd, options.LanguageServers, options.DiagnosticsTimeout)
+		}
+	}
+
 	executor := NewCommandExecutor(options.Logger, options.Metrics)
+	executor.SetExecutionBackend(options.ExecutionBackend)
+	executor.SetCommandRunner(options.CommandRunner)
+	executor.SetPTY(options.PTY)
+	executor.SetDefaultShell(options.DefaultShell)
+	executor.SetEnvironment(options.EnvPolicy, options.EnvAllowlist)
+	executor.SetFailureLogs(!options.DisableFailureLogs, *options.FailureLogRetention)
+	if wd, err := os.Getwd(); err == nil {
+		executor.SetArtifactDir(filepath.Join(wd, ".goagent", "artifacts"))
+		if !options.DisableFailureLogs {
+			if _, err := pruneFailureLogs(filepath.Join(wd, ".goagent"), *options.FailureLogRetention); err != nil {
+				options.Logger.Warn(context.Background(), "Failed to prune failure logs", Field("error", err.Error()))
+			}
+		}
+	}
+	executor.SetOutputSink(func(step PlanStep, stream, chunk string) {
+		rt.emit(RuntimeEvent{
+			Type:     EventTypeCommandOutput,
+			Message:  chunk,
+			Level:    StatusLevelInfo,
+			Metadata: map[string]any{"step_id": step.ID, "stream": stream},
+		})
+	})
 	if err := registerBuiltinInternalCommands(rt, executor); err != nil {
 		return nil, fmt.Errorf("runtime: failed to register builtin internal commands: %w", err)
 	}
@@ -109,21 +343,38 @@ func (r *Runtime) Inputs() chan<- InputEvent {
 }
 
 // Outputs expose the outbound queue which delivers RuntimeEvents in order.
+// The runtime waits for it to be drained (see RuntimeOptions.EmitTimeout)
+// unless the host only uses Subscribe.
 func (r *Runtime) Outputs() <-chan RuntimeEvent {
+	r.outputsClaimed.Store(true)
 	return r.outputs
 }
 
 // SubmitPrompt is a convenience wrapper that enqueues a prompt input.
 func (r *Runtime) SubmitPrompt(prompt string) {
-	if r.isWorking() {
-		r.emit(RuntimeEvent{
-			Type:    EventTypeStatus,
-			Message: "Agent is currently executing a plan. Please wait before submitting another prompt.",
-			Level:   StatusLevelWarn,
-		})
+	r.SubmitPromptWithAttachments(prompt, nil)
+}
+
+// SubmitPromptWithAttachments enqueues a prompt together with files for the
+// model, such as screenshots or logs (see LoadAttachment). While the agent
+// works, the prompt steers it instead: it is added to history before the
+// next plan request.
+func (r *Runtime) SubmitPromptWithAttachments(prompt string, attachments []Attachment) {
+	if r.steer(prompt, attachments) {
 		return
 	}
-	r.enqueue(InputEvent{Type: InputTypePrompt, Prompt: prompt})
+	r.enqueue(InputEvent{Type: InputTypePrompt, Prompt: prompt, Attachments: attachments})
+}
+
+// SubmitCommandStdin sends data to the stdin of the running interactive step.
+// It bypasses the input queue because the loop is busy while steps execute.
+func (r *Runtime) SubmitCommandStdin(stepID, data string, eof bool) error {
+	return r.executor.WriteStdin(stepID, data, eof)
+}
+
+// SetTerminalSize reports the host's terminal dimensions to PTY steps.
+func (r *Runtime) SetTerminalSize(cols, rows int) {
+	r.executor.SetTerminalSize(cols, rows)
 }
 
 // Cancel enqueues a cancel request, mirroring the TypeScript runtime API.
@@ -169,6 +420,9 @@ func (r *Runtime) emit(evt RuntimeEvent) {
 	if evt.Agent == "" {
 		evt.Agent = r.agentName
 	}
+	if evt.Timestamp.IsZero() {
+		evt.Timestamp = time.Now()
+	}
 
 	select {
 	case <-r.closed:
@@ -176,6 +430,15 @@ func (r *Runtime) emit(evt RuntimeEvent) {
 	default:
 	}
 
+	r.recordEvent(evt)
+	for range r.subscribers.publish(evt, r.closed) {
+		r.options.Metrics.RecordDroppedEvent(string(evt.Type))
+	}
+	if r.subscribers.used.Load() && !r.outputsClaimed.Load() {
+		// Nobody reads Outputs; the subscriptions have the event.
+		return
+	}
+
 	if r.options.EmitTimeout <= 0 {
 		// No timeout: block until sent or runtime is closed
 		select {
@@ -209,7 +472,17 @@ func (r *Runtime) emit(evt RuntimeEvent) {
 func (r *Runtime) close() {
 	r.closeOnce.Do(func() {
 		close(r.closed)
+		if r.executor != nil {
+			r.executor.StopBackgroundJobs()
+		}
+		if r.diagnostics != nil {
+			r.diagnostics.Close()
+		}
+		r.subscribers.close()
 		close(r.outputs)
+		if err := r.events.close(); err != nil {
+			fmt.Fprintf(r.options.OutputWriter, "warning: failed to close event log: %v\n", err)
+		}
 		// Close log file if one was opened
 		if r.logFileCloser != nil {
 			if err := r.logFileCloser.Close(); err != nil {
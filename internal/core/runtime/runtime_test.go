@@ -1,9 +1,11 @@
 package runtime
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -45,6 +47,7 @@ func TestExecutePendingCommands_AppendsSingleToolMessage(t *testing.T) {
 	t.Parallel()
 
 	rt := &Runtime{
+		options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}},
 		plan:      NewPlanManager(),
 		executor:  NewCommandExecutor(nil, nil),
 		outputs:   make(chan RuntimeEvent, 10),
@@ -105,6 +108,7 @@ func TestExecutePendingCommands_FailureStillRecordsSingleToolMessage(t *testing.
 	t.Parallel()
 
 	rt := &Runtime{
+		options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}},
 		plan:      NewPlanManager(),
 		executor:  NewCommandExecutor(nil, nil),
 		outputs:   make(chan RuntimeEvent, 10),
@@ -181,6 +185,7 @@ func TestExecutePendingCommands_RunsReadyStepsInParallel(t *testing.T) {
 	t.Parallel()
 
 	rt := &Runtime{
+		options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}},
 		plan:      NewPlanManager(),
 		executor:  NewCommandExecutor(nil, nil),
 		outputs:   make(chan RuntimeEvent, 10),
@@ -245,6 +250,132 @@ func TestExecutePendingCommands_RunsReadyStepsInParallel(t *testing.T) {
 	if got := len(observation.PlanObservation); got != 2 {
 		t.Fatalf("expected two observations, got %d", got)
 	}
+	for _, obs := range observation.PlanObservation {
+		if want := durations[obs.ID].Milliseconds(); obs.DurationMs < want {
+			t.Fatalf("expected %s to report at least %dms, got %d", obs.ID, want, obs.DurationMs)
+		}
+	}
+	for _, step := range rt.PlanSnapshot().Steps {
+		if step.StartedAt == nil || step.FinishedAt == nil || step.Duration() < durations[step.ID] {
+			t.Fatalf("expected measured timings for %s, got %+v", step.ID, step)
+		}
+	}
+}
+
+func TestExecutePendingCommands_RespectsParallelLimitAndGroups(t *testing.T) {
+	t.Parallel()
+
+	run := func(t *testing.T, maxParallel int, steps []PlanStep) (int, int) {
+		t.Helper()
+		rt := &Runtime{
+			options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}, MaxParallelSteps: maxParallel},
+			plan:      NewPlanManager(),
+			executor:  NewCommandExecutor(nil, nil),
+			outputs:   make(chan RuntimeEvent, 64),
+			closed:    make(chan struct{}),
+			history:   []ChatMessage{},
+			agentName: "main",
+		}
+
+		var mu sync.Mutex
+		running, peak, groupRunning, groupPeak := 0, 0, 0, 0
+		if err := rt.executor.RegisterInternalCommand("work", func(_ context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+			grouped := req.Step.ConcurrencyGroup != ""
+			mu.Lock()
+			running++
+			peak = max(peak, running)
+			if grouped {
+				groupRunning++
+				groupPeak = max(groupPeak, groupRunning)
+			}
+			mu.Unlock()
+
+			time.Sleep(50 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			if grouped {
+				groupRunning--
+			}
+			mu.Unlock()
+			return PlanObservationPayload{Stdout: "done"}, nil
+		}); err != nil {
+			t.Fatalf("failed to register internal command: %v", err)
+		}
+
+		rt.plan.Replace(steps)
+		rt.executePendingCommands(context.Background(), ToolCall{ID: "call-limit", Name: "open-agent"})
+		return peak, groupPeak
+	}
+
+	step := func(id, group string) PlanStep {
+		return PlanStep{ID: id, Status: PlanPending, ConcurrencyGroup: group, Command: CommandDraft{Shell: agentShell, Run: "work"}}
+	}
+
+	if peak, _ := run(t, 2, []PlanStep{step("a", ""), step("b", ""), step("c", ""), step("d", "")}); peak != 2 {
+		t.Fatalf("expected at most 2 concurrent steps, peak was %d", peak)
+	}
+
+	peak, groupPeak := run(t, 0, []PlanStep{step("build", "heavy"), step("test", "heavy"), step("lint", "")})
+	if groupPeak != 1 {
+		t.Fatalf("expected grouped steps to run one at a time, peak was %d", groupPeak)
+	}
+	if peak != 2 {
+		t.Fatalf("expected ungrouped step to run alongside the group, peak was %d", peak)
+	}
+}
+
+func TestExecutePendingCommands_CancelStopsRunningSteps(t *testing.T) {
+	t.Parallel()
+
+	rt := &Runtime{
+		options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}},
+		plan:      NewPlanManager(),
+		executor:  NewCommandExecutor(nil, nil),
+		inputs:    make(chan InputEvent, 4),
+		outputs:   make(chan RuntimeEvent, 32),
+		closed:    make(chan struct{}),
+		history:   []ChatMessage{},
+		agentName: "main",
+	}
+	rt.plan.Replace([]PlanStep{
+		{ID: "slow", Status: PlanPending, Command: CommandDraft{Shell: "/bin/sh -c", Run: "sleep 30 & wait", TimeoutSec: 60}},
+		{ID: "after", Status: PlanPending, WaitingForID: []string{"slow"}, Command: CommandDraft{Shell: "/bin/sh -c", Run: "true"}},
+	})
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		rt.Cancel("changed my mind")
+	}()
+
+	start := time.Now()
+	cancelled := rt.executePendingCommands(context.Background(), ToolCall{ID: "call-cancel", Name: "open-agent"})
+	if !cancelled {
+		t.Fatal("expected executePendingCommands to report cancellation")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected running step to be killed promptly, took %v", elapsed)
+	}
+
+	history := rt.historySnapshot()
+	if len(history) != 1 {
+		t.Fatalf("expected one tool message, got %d", len(history))
+	}
+	var observation PlanObservationPayload
+	if err := json.Unmarshal([]byte(history[0].Content), &observation); err != nil {
+		t.Fatalf("failed to decode tool message: %v", err)
+	}
+	if !strings.Contains(observation.Summary, "Plan cancelled by user") || !observation.CanceledByHuman {
+		t.Fatalf("unexpected summary %q", observation.Summary)
+	}
+	if len(observation.PlanObservation) != 2 {
+		t.Fatalf("expected both steps reported, got %+v", observation.PlanObservation)
+	}
+	for _, step := range observation.PlanObservation {
+		if step.Status != PlanCancelled {
+			t.Fatalf("expected step %s to be cancelled, got %s", step.ID, step.Status)
+		}
+	}
 }
 
 func TestComputeValidationBackoff(t *testing.T) {
@@ -342,6 +473,9 @@ func TestRuntimeEmitAnnotatesEvent(t *testing.T) {
 		if evt.Agent != "main" {
 			t.Fatalf("expected agent to be main, got %s", evt.Agent)
 		}
+		if evt.Timestamp.IsZero() {
+			t.Fatal("expected emit to set a timestamp")
+		}
 	case <-time.After(time.Second):
 		t.Fatalf("timed out waiting for event")
 	}
@@ -484,3 +618,82 @@ func TestRuntimeHistoryAmnesia(t *testing.T) {
 		t.Fatalf("expected most recent assistant content to remain untouched")
 	}
 }
+
+func TestExecutePendingCommands_EmitsFileChangeEvents(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	rt := &Runtime{
+		options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}},
+		plan:      NewPlanManager(),
+		executor:  NewCommandExecutor(nil, nil),
+		outputs:   make(chan RuntimeEvent, 10),
+		closed:    make(chan struct{}),
+		history:   []ChatMessage{},
+		agentName: "main",
+	}
+	if err := rt.executor.RegisterInternalCommand(applyPatchCommandName, newApplyPatchCommand()); err != nil {
+		t.Fatalf("failed to register apply_patch: %v", err)
+	}
+
+	run := "apply_patch\n*** Begin Patch\n*** Add File: hello.txt\n+hello\n*** End Patch"
+	rt.plan.Replace([]PlanStep{{
+		ID:      "step-1",
+		Title:   "Write file",
+		Status:  PlanPending,
+		Command: CommandDraft{Shell: agentShell, Run: run, Cwd: dir},
+	}})
+
+	rt.executePendingCommands(context.Background(), ToolCall{ID: "call-1", Name: "open-agent"})
+	close(rt.outputs)
+
+	var changes []RuntimeEvent
+	for evt := range rt.outputs {
+		if evt.Type == EventTypeFileChange {
+			changes = append(changes, evt)
+		}
+	}
+	if len(changes) != 1 {
+		t.Fatalf("expected one file change event, got %d", len(changes))
+	}
+	meta := changes[0].Metadata
+	if meta["path"] != "hello.txt" || meta["status"] != "A" || meta["hunks"] != 1 || meta["bytes"] != int64(len("hello")) {
+		t.Fatalf("unexpected file change metadata: %#v", meta)
+	}
+	if meta["diff"] != "@@\n+hello\n" {
+		t.Fatalf("unexpected file change diff: %q", meta["diff"])
+	}
+}
+
+func TestWriteEventJSONL(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	writeEvent(&buf, OutputFormatJSONL, RuntimeEvent{Type: EventTypeStatus, Message: "hi", Metadata: map[string]any{"step_id": "s1"}, Pass: 2, Agent: "main", Timestamp: at})
+	writeEvent(&buf, OutputFormatJSONL, RuntimeEvent{Type: EventTypeStatus, Message: "bad", Metadata: map[string]any{"fn": func() {}}})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", buf.String())
+	}
+	var first map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if first["type"] != "status" || first["message"] != "hi" || first["pass"] != float64(2) || first["agent"] != "main" || first["timestamp"] != "2025-01-02T03:04:05Z" {
+		t.Fatalf("unexpected event %v", first)
+	}
+	if meta, _ := first["metadata"].(map[string]any); meta["step_id"] != "s1" {
+		t.Fatalf("unexpected metadata %v", first["metadata"])
+	}
+	if !strings.Contains(lines[1], "metadata_error") {
+		t.Fatalf("expected unencodable metadata to be replaced: %s", lines[1])
+	}
+
+	buf.Reset()
+	writeEvent(&buf, OutputFormatText, RuntimeEvent{Type: EventTypeStatus, Message: "hi"})
+	if buf.String() != "[status] hi\n" {
+		t.Fatalf("text format = %q", buf.String())
+	}
+}
@@ -0,0 +1,127 @@
+package runtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// sessionFormatVersion is bumped whenever the on-disk session layout changes
+// incompatibly. Loading a session with a different version fails rather than
+// guessing at the shape.
+const sessionFormatVersion = 1
+
+// sessionFile is the serialized runtime state written by SaveSession.
+type sessionFile struct {
+	Version       int           `json:"version"`
+	SavedAt       time.Time     `json:"savedAt"`
+	Provider      string        `json:"provider"`
+	Model         string        `json:"model"`
+	PassCount     int           `json:"passCount"`
+	ContextBudget ContextBudget `json:"contextBudget"`
+	History       []ChatMessage `json:"history"`
+	Plan          []PlanStep    `json:"plan"`
+}
+
+// SaveSession writes the conversation history, plan, pass count, and context
+// budget to path as versioned JSON so the conversation can be resumed with
+// NewRuntimeFromSession. The file is replaced atomically.
+func (r *Runtime) SaveSession(path string) error {
+	if path == "" {
+		return errors.New("session: path is required")
+	}
+
+	session := sessionFile{
+		Version:       sessionFormatVersion,
+		SavedAt:       time.Now(),
+		Provider:      r.options.Provider,
+		Model:         r.options.Model,
+		PassCount:     r.currentPassCount(),
+		ContextBudget: r.contextBudget,
+		History:       r.historySnapshot(),
+		Plan:          r.plan.Snapshot(),
+	}
+	if err := writeJSONAtomic(path, session); err != nil {
+		return fmt.Errorf("session: %w", err)
+	}
+	return nil
+}
+
+// writeJSONAtomic writes v as indented JSON, replacing path atomically so a
+// crash never leaves a truncated file.
+func writeJSONAtomic(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode: %w", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create directory: %w", err)
+		}
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("write: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("write: %w", err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// NewRuntimeFromSession builds a runtime from options and restores the state
+// saved by SaveSession. The system prompt is rebuilt from the current options
+// so prompt or augmentation changes apply to resumed conversations; all other
+// history, the plan, the pass count, and the context budget come from the file.
+func NewRuntimeFromSession(path string, options RuntimeOptions) (*Runtime, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("session: read: %w", err)
+	}
+	var session sessionFile
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("session: decode %s: %w", path, err)
+	}
+	if session.Version != sessionFormatVersion {
+		return nil, fmt.Errorf("session: unsupported version %d (want %d)", session.Version, sessionFormatVersion)
+	}
+
+	rt, err := NewRuntime(options)
+	if err != nil {
+		return nil, err
+	}
+
+	history := session.History
+	if len(history) > 0 && history[0].Role == RoleSystem {
+		history = history[1:]
+	}
+	rt.historyMu.Lock()
+	rt.history = append(rt.history[:1], history...)
+	rt.historyMu.Unlock()
+
+	rt.plan.Replace(session.Plan)
+
+	rt.passMu.Lock()
+	rt.passCount = session.PassCount
+	rt.passMu.Unlock()
+
+	if session.ContextBudget.MaxTokens > 0 {
+		rt.contextBudget = session.ContextBudget
+	}
+	return rt, nil
+}
@@ -0,0 +1,82 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newSessionTestRuntime(t *testing.T) *Runtime {
+	t.Helper()
+	historyPath := ""
+	rt, err := NewRuntime(RuntimeOptions{
+		APIKey:             "test-key",
+		HistoryLogPath:     &historyPath,
+		DisableSnapshots:   true,
+		DisableInputReader: true,
+	})
+	if err != nil {
+		t.Fatalf("NewRuntime returned error: %v", err)
+	}
+	return rt
+}
+
+func TestSaveSessionRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	rt := newSessionTestRuntime(t)
+	rt.passCount = 3
+	rt.contextBudget = ContextBudget{MaxTokens: 5000, CompactWhenPercent: 0.5}
+	rt.history = append(rt.history,
+		ChatMessage{Role: RoleUser, Content: "list files", Pass: 1},
+		ChatMessage{Role: RoleAssistant, Content: "done", Pass: 1},
+	)
+	rt.plan.Replace([]PlanStep{
+		{ID: "a", Title: "ls", Status: PlanCompleted, Command: CommandDraft{Shell: "bash", Run: "ls"}},
+		{ID: "b", Title: "cat", Status: PlanPending, WaitingForID: []string{"a"}},
+	})
+
+	path := filepath.Join(t.TempDir(), "sessions", "s.json")
+	if err := rt.SaveSession(path); err != nil {
+		t.Fatalf("SaveSession returned error: %v", err)
+	}
+
+	restored, err := NewRuntimeFromSession(path, RuntimeOptions{
+		APIKey:             "test-key",
+		HistoryLogPath:     rt.options.HistoryLogPath,
+		DisableSnapshots:   true,
+		DisableInputReader: true,
+	})
+	if err != nil {
+		t.Fatalf("NewRuntimeFromSession returned error: %v", err)
+	}
+
+	if restored.currentPassCount() != 3 {
+		t.Fatalf("expected pass count 3, got %d", restored.currentPassCount())
+	}
+	if restored.contextBudget.MaxTokens != 5000 {
+		t.Fatalf("expected restored context budget, got %+v", restored.contextBudget)
+	}
+	history := restored.historySnapshot()
+	if len(history) != 3 || history[0].Role != RoleSystem || history[1].Content != "list files" || history[2].Content != "done" {
+		t.Fatalf("unexpected restored history: %+v", history)
+	}
+	plan := restored.plan.Snapshot()
+	if len(plan) != 2 || plan[0].Status != PlanCompleted || plan[1].WaitingForID[0] != "a" {
+		t.Fatalf("unexpected restored plan: %+v", plan)
+	}
+}
+
+func TestNewRuntimeFromSessionRejectsUnknownVersion(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "s.json")
+	if err := os.WriteFile(path, []byte(`{"version":99}`), 0o644); err != nil {
+		t.Fatalf("write session: %v", err)
+	}
+	historyPath := ""
+	_, err := NewRuntimeFromSession(path, RuntimeOptions{APIKey: "test-key", HistoryLogPath: &historyPath, DisableSnapshots: true})
+	if err == nil {
+		t.Fatal("expected version mismatch error")
+	}
+}
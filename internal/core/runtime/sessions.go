@@ -0,0 +1,119 @@
+package runtime
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SessionsDir holds one directory per session, named by its session ID,
+// with the session's history log.
+var SessionsDir = filepath.Join(".goagent", "sessions")
+
+// sessionIDTimeFormat starts session IDs so they sort by start time.
+const sessionIDTimeFormat = "20060102-150405"
+
+// NewSessionID returns a session ID made of the start time and a random
+// suffix, e.g. "20261017-142501-3f9a1c".
+func NewSessionID(now time.Time) string {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		// The clock alone still tells sessions apart in practice.
+		return now.Format(sessionIDTimeFormat)
+	}
+	return now.Format(sessionIDTimeFormat) + "-" + hex.EncodeToString(suffix)
+}
+
+// SessionHistoryPath returns the history log of the session with id under
+// root (SessionsDir when empty).
+func SessionHistoryPath(root, id string) string {
+	if root == "" {
+		root = SessionsDir
+	}
+	return filepath.Join(root, id, "history.json")
+}
+
+// SessionInfo describes a session found by ListSessions.
+type SessionInfo struct {
+	ID string `json:"id"`
+	// Dir is the session's directory.
+	Dir string `json:"dir"`
+	// Started is parsed from the ID; zero for IDs chosen by the host.
+	Started time.Time `json:"started,omitzero"`
+	// Updated is when the history log was last written.
+	Updated time.Time `json:"updated"`
+	// Version is the history log's schema version.
+	Version int `json:"version"`
+	// Messages counts the messages in the history log.
+	Messages int `json:"messages"`
+	// Prompt is the first user message, for telling sessions apart.
+	Prompt string `json:"prompt,omitempty"`
+}
+
+// ListSessions returns the sessions under root (SessionsDir when empty),
+// newest first. Directories without a readable history log are skipped;
+// a missing root yields no sessions.
+func ListSessions(root string) ([]SessionInfo, error) {
+	if root == "" {
+		root = SessionsDir
+	}
+	entries, err := os.ReadDir(root)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+
+	var sessions []SessionInfo
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := SessionHistoryPath(root, entry.Name())
+		log, err := readHistoryLog(path)
+		if err != nil {
+			continue
+		}
+		info := SessionInfo{
+			ID:       entry.Name(),
+			Dir:      filepath.Join(root, entry.Name()),
+			Updated:  log.UpdatedAt,
+			Version:  log.Version,
+			Messages: len(log.History),
+		}
+		if len(entry.Name()) >= len(sessionIDTimeFormat) {
+			if started, err := time.ParseInLocation(sessionIDTimeFormat, entry.Name()[:len(sessionIDTimeFormat)], time.Local); err == nil {
+				info.Started = started
+			}
+		}
+		if info.Updated.IsZero() {
+			if stat, err := os.Stat(path); err == nil {
+				info.Updated = stat.ModTime()
+			}
+		}
+		for _, message := range log.History {
+			if message.Role == RoleUser {
+				info.Prompt = strings.TrimSpace(message.Content)
+				break
+			}
+		}
+		sessions = append(sessions, info)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Updated.After(sessions[j].Updated)
+	})
+	return sessions, nil
+}
+
+// SessionID returns the ID of this runtime's session.
+func (r *Runtime) SessionID() string {
+	return r.options.SessionID
+}
@@ -0,0 +1,93 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestNewSessionID_StartsWithTimestamp(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 17, 14, 25, 1, 0, time.Local)
+	id := NewSessionID(now)
+	if !regexp.MustCompile(`^20261017-142501-[0-9a-f]{6}$`).MatchString(id) {
+		t.Fatalf("unexpected session ID %q", id)
+	}
+	if other := NewSessionID(now); other == id {
+		t.Fatalf("expected distinct IDs for the same second, got %q twice", id)
+	}
+}
+
+func TestListSessions_NewestFirst(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	older := &JSONFileHistoryStore{Path: SessionHistoryPath(root, "20261016-090000-aaaaaa"), SessionID: "20261016-090000-aaaaaa"}
+	if err := older.Snapshot(context.Background(), []ChatMessage{
+		{Role: RoleSystem, Content: "system"},
+		{Role: RoleUser, Content: "  fix the build  "},
+	}); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	newer := &JSONFileHistoryStore{Path: SessionHistoryPath(root, "custom"), SessionID: "custom"}
+	if err := newer.Snapshot(context.Background(), []ChatMessage{{Role: RoleUser, Content: "add tests"}}); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "empty"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	sessions, err := ListSessions(root)
+	if err != nil {
+		t.Fatalf("ListSessions: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %+v", sessions)
+	}
+	if sessions[0].ID != "custom" || sessions[0].Prompt != "add tests" || !sessions[0].Started.IsZero() {
+		t.Fatalf("unexpected newest session: %+v", sessions[0])
+	}
+	first := sessions[1]
+	if first.Messages != 2 || first.Prompt != "fix the build" || first.Version != historyLogVersion {
+		t.Fatalf("unexpected older session: %+v", first)
+	}
+	if want := time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local); !first.Started.Equal(want) {
+		t.Fatalf("expected start %v, got %v", want, first.Started)
+	}
+}
+
+func TestListSessions_MissingRoot(t *testing.T) {
+	t.Parallel()
+
+	sessions, err := ListSessions(filepath.Join(t.TempDir(), "missing"))
+	if err != nil || len(sessions) != 0 {
+		t.Fatalf("expected no sessions, got %+v, %v", sessions, err)
+	}
+}
+
+func TestJSONFileHistoryStore_LoadsLegacyAndRejectsNewerVersions(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "legacy.json")
+	if err := os.WriteFile(legacy, []byte(`[{"role":"user","content":"hi"}]`), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	history, err := (&JSONFileHistoryStore{Path: legacy}).Load(context.Background())
+	if err != nil || len(history) != 1 || history[0].Content != "hi" {
+		t.Fatalf("expected legacy history to load, got %+v, %v", history, err)
+	}
+
+	future := filepath.Join(dir, "future.json")
+	if err := os.WriteFile(future, []byte(`{"version":99,"history":[]}`), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := (&JSONFileHistoryStore{Path: future}).Load(context.Background()); err == nil {
+		t.Fatalf("expected an error for an unsupported version")
+	}
+}
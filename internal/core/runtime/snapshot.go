@@ -0,0 +1,317 @@
+package runtime
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asynkron/goagent/pkg/patch"
+)
+
+const (
+	// maxSnapshotPasses bounds how many passes can be undone.
+	maxSnapshotPasses = 20
+	// maxSnapshotFiles caps the number of files captured when a shell
+	// command forces a directory-wide snapshot.
+	maxSnapshotFiles = 5000
+	// maxSnapshotFileBytes skips very large files during directory-wide
+	// snapshots; they cannot be restored by undo.
+	maxSnapshotFileBytes = 1 << 20
+)
+
+// snapshotSkipDirs are never captured by directory-wide snapshots.
+var snapshotSkipDirs = map[string]bool{".git": true, ".goagent": true, "node_modules": true}
+
+// snapshotManager records the original state of files before a pass writes
+// to them so the pass can be undone. File contents are stored once per
+// content hash under <dir>/objects.
+type snapshotManager struct {
+	mu  sync.Mutex
+	dir string
+	// workspace bounds directory-wide snapshots: shell steps that run
+	// outside it are not captured.
+	workspace string
+	passes    []*passSnapshot
+}
+
+// passSnapshot holds everything recorded for a single pass.
+type passSnapshot struct {
+	pass  int
+	files map[string]fileSnapshot
+	// roots lists directories captured wholesale. Files created below a
+	// root during the pass are removed on undo only when the root was
+	// listed completely (true); a root cut short by maxSnapshotFiles (false)
+	// cannot tell new files from unrecorded ones.
+	roots map[string]bool
+	// uncovered lists directories whose changes were not recorded, for the
+	// undo error.
+	uncovered []string
+}
+
+type fileSnapshot struct {
+	existed bool
+	hash    string
+	mode    fs.FileMode
+}
+
+func newSnapshotManager(dir, workspace string) *snapshotManager {
+	return &snapshotManager{dir: dir, workspace: workspace}
+}
+
+func (m *snapshotManager) passLocked(pass int) *passSnapshot {
+	if n := len(m.passes); n > 0 && m.passes[n-1].pass == pass {
+		return m.passes[n-1]
+	}
+	snapshot := &passSnapshot{pass: pass, files: make(map[string]fileSnapshot), roots: make(map[string]bool)}
+	m.passes = append(m.passes, snapshot)
+	if len(m.passes) > maxSnapshotPasses {
+		m.passes = m.passes[len(m.passes)-maxSnapshotPasses:]
+	}
+	return snapshot
+}
+
+// recordStep captures the files step may modify. apply_patch and write_file
+// steps record exactly the paths they write; shell commands and run_tests
+// capture their working directory.
+func (m *snapshotManager) recordStep(pass int, step PlanStep) error {
+	cwd := strings.TrimSpace(step.Command.Cwd)
+	if cwd == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		cwd = wd
+	}
+	cwd, err := filepath.Abs(cwd)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := m.passLocked(pass)
+
+	if strings.EqualFold(strings.TrimSpace(step.Command.Shell), agentShell) {
+		req, err := parseInternalInvocation(step)
+		if err != nil {
+			// The command will fail the same way; nothing to capture.
+			return nil
+		}
+		switch req.Name {
+		case applyPatchCommandName:
+			_, patchInput := splitCommandAndPatch(step.Command.Run)
+			operations, err := patch.Parse(patchInput)
+			if err != nil {
+				return nil
+			}
+			for _, op := range operations {
+				for _, target := range []string{op.Path, op.MovePath} {
+					if strings.TrimSpace(target) == "" {
+						continue
+					}
+					if err := m.recordFileLocked(snapshot, filepath.Join(cwd, filepath.Clean(strings.TrimSpace(target)))); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		case writeFileCommandName:
+			args := positionalStrings(req)
+			if len(args) == 0 {
+				return nil
+			}
+			target := filepath.Clean(args[0])
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(cwd, target)
+			}
+			return m.recordFileLocked(snapshot, target)
+		case runTestsCommandName:
+			// Tests run project code, which may write anywhere below cwd;
+			// capture it like a shell step.
+		default:
+			return nil
+		}
+	}
+
+	if _, seen := snapshot.roots[cwd]; seen || slices.Contains(snapshot.uncovered, cwd) {
+		return nil
+	}
+	if rel, err := filepath.Rel(m.workspace, cwd); m.workspace == "" || err != nil || !filepath.IsLocal(rel) {
+		// Never walk an arbitrary directory such as / before a step.
+		snapshot.uncovered = append(snapshot.uncovered, cwd)
+		return nil
+	}
+	snapshot.roots[cwd] = true
+	count := 0
+	return filepath.WalkDir(cwd, func(path string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			if path != cwd && snapshotSkipDirs[entry.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		count++
+		if count > maxSnapshotFiles {
+			snapshot.roots[cwd] = false
+			return filepath.SkipAll
+		}
+		if info, err := entry.Info(); err != nil || info.Size() > maxSnapshotFileBytes {
+			// Record the path as pre-existing so undo does not delete it.
+			if _, seen := snapshot.files[path]; !seen {
+				snapshot.files[path] = fileSnapshot{existed: true}
+			}
+			return nil
+		}
+		return m.recordFileLocked(snapshot, path)
+	})
+}
+
+func (m *snapshotManager) recordFileLocked(snapshot *passSnapshot, path string) error {
+	if _, seen := snapshot.files[path]; seen {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		snapshot.files[path] = fileSnapshot{}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	object := filepath.Join(m.dir, "objects", hash)
+	if _, err := os.Stat(object); errors.Is(err, fs.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(object), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(object, content, 0o644); err != nil {
+			return err
+		}
+	}
+	snapshot.files[path] = fileSnapshot{existed: true, hash: hash, mode: info.Mode()}
+	return nil
+}
+
+// undoLast restores the most recent pass snapshot and returns the paths that
+// were restored or removed.
+func (m *snapshotManager) undoLast() (int, []string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.passes) == 0 {
+		return 0, nil, errors.New("nothing to undo")
+	}
+	snapshot := m.passes[len(m.passes)-1]
+	m.passes = m.passes[:len(m.passes)-1]
+
+	var changed []string
+	var errs []error
+	for path, original := range snapshot.files {
+		switch {
+		case !original.existed:
+			if err := os.Remove(path); err == nil {
+				changed = append(changed, path)
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		case original.hash != "":
+			content, err := os.ReadFile(filepath.Join(m.dir, "objects", original.hash))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("missing snapshot for %s: %w", path, err))
+				continue
+			}
+			if current, err := os.ReadFile(path); err == nil && string(current) == string(content) {
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if err := os.WriteFile(path, content, original.mode.Perm()); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			_ = os.Chmod(path, original.mode.Perm())
+			changed = append(changed, path)
+		}
+	}
+
+	// Files created below completely captured directories did not exist
+	// before the pass. Below a partial capture an unknown file may be one
+	// the walk never reached, so it is left alone.
+	for root, complete := range snapshot.roots {
+		if !complete {
+			errs = append(errs, fmt.Errorf("files created under %s were not removed: it holds more than %d files", root, maxSnapshotFiles))
+			continue
+		}
+		_ = filepath.WalkDir(root, func(path string, entry fs.DirEntry, walkErr error) error {
+			if walkErr != nil {
+				return nil
+			}
+			if entry.IsDir() {
+				if path != root && snapshotSkipDirs[entry.Name()] {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if _, known := snapshot.files[path]; known || !entry.Type().IsRegular() {
+				return nil
+			}
+			if err := os.Remove(path); err == nil {
+				changed = append(changed, path)
+			}
+			return nil
+		})
+	}
+
+	for _, dir := range snapshot.uncovered {
+		errs = append(errs, fmt.Errorf("changes under %s were not recorded: it is outside the workspace", dir))
+	}
+
+	sort.Strings(changed)
+	return snapshot.pass, changed, errors.Join(errs...)
+}
+
+// UndoLastPass restores the files modified by the most recent pass that wrote
+// to the workspace and returns the affected paths. Snapshots are only
+// recorded for steps classified as mutating (see stepMayWrite); directory-wide
+// snapshots skip files larger than 1 MiB, stop after 5000 files (files
+// created in such a directory are then kept) and are not taken outside the
+// working directory.
+func (r *Runtime) UndoLastPass() ([]string, error) {
+	if r.snapshots == nil {
+		return nil, errors.New("workspace snapshots are disabled")
+	}
+	pass, changed, err := r.snapshots.undoLast()
+	if pass > 0 {
+		note := fmt.Sprintf("The user undid the file changes made during pass %d. Re-read files before editing them again.", pass)
+		if len(changed) > 0 {
+			note += " Restored: " + strings.Join(changed, ", ")
+		}
+		r.appendHistory(ChatMessage{Role: RoleUser, Content: note, Timestamp: time.Now()})
+	}
+	return changed, err
+}
@@ -0,0 +1,183 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUndoLastPassRestoresPatchedFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(target, []byte("alpha\n"), 0o644); err != nil {
+		t.Fatalf("failed to seed file: %v", err)
+	}
+
+	rt := &Runtime{
+		options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}},
+		snapshots: newSnapshotManager(filepath.Join(t.TempDir(), "snapshots"), dir),
+		agentName: "main",
+	}
+
+	run := "apply_patch\n*** Begin Patch\n*** Update File: notes.txt\n@@\n-alpha\n+beta\n*** Add File: added.txt\n+new\n*** End Patch"
+	step := PlanStep{ID: "step-1", Command: CommandDraft{Shell: agentShell, Run: run, Cwd: dir}}
+	if err := rt.snapshots.recordStep(1, step); err != nil {
+		t.Fatalf("recordStep returned error: %v", err)
+	}
+	if _, err := newApplyPatchCommand()(context.Background(), InternalCommandRequest{Name: applyPatchCommandName, Raw: run, Step: step}); err != nil {
+		t.Fatalf("apply_patch failed: %v", err)
+	}
+
+	changed, err := rt.UndoLastPass()
+	if err != nil {
+		t.Fatalf("UndoLastPass returned error: %v", err)
+	}
+	if len(changed) != 2 {
+		t.Fatalf("expected two restored paths, got %v", changed)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "alpha\n" {
+		t.Fatalf("expected original content, got %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "added.txt")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected added file to be removed, got %v", err)
+	}
+	if history := rt.historySnapshot(); len(history) != 1 || history[0].Role != RoleUser {
+		t.Fatalf("expected an undo note in history, got %#v", history)
+	}
+	if _, err := rt.UndoLastPass(); err == nil {
+		t.Fatalf("expected error when nothing is left to undo")
+	}
+}
+
+func TestUndoLastPassRestoresWrittenFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(target, []byte("alpha\n"), 0o644); err != nil {
+		t.Fatalf("failed to seed file: %v", err)
+	}
+
+	rt := &Runtime{
+		options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}},
+		snapshots: newSnapshotManager(filepath.Join(t.TempDir(), "snapshots"), dir),
+		agentName: "main",
+	}
+	executor := newFileCommandExecutor(t)
+	for _, run := range []string{"write_file notes.txt\nbeta\n", "write_file sub/new.txt\nnew\n"} {
+		step := PlanStep{ID: "write", Command: CommandDraft{Shell: agentShell, Run: run, Cwd: dir}}
+		if err := rt.snapshots.recordStep(1, step); err != nil {
+			t.Fatalf("recordStep returned error: %v", err)
+		}
+		if _, err := executor.Execute(context.Background(), step); err != nil {
+			t.Fatalf("write_file failed: %v", err)
+		}
+	}
+
+	changed, err := rt.UndoLastPass()
+	if err != nil {
+		t.Fatalf("UndoLastPass returned error: %v", err)
+	}
+	if len(changed) != 2 {
+		t.Fatalf("expected two restored paths, got %v", changed)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "alpha\n" {
+		t.Fatalf("expected original content, got %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub", "new.txt")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected the written file to be removed, got %v", err)
+	}
+}
+
+func TestUndoLastPassRevertsShellChanges(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "keep.txt")
+	if err := os.WriteFile(existing, []byte("original"), 0o644); err != nil {
+		t.Fatalf("failed to seed file: %v", err)
+	}
+
+	manager := newSnapshotManager(filepath.Join(t.TempDir(), "snapshots"), dir)
+	step := PlanStep{ID: "step-1", Command: CommandDraft{Shell: "bash", Run: "make build", Cwd: dir}}
+	if err := manager.recordStep(3, step); err != nil {
+		t.Fatalf("recordStep returned error: %v", err)
+	}
+
+	if err := os.WriteFile(existing, []byte("changed"), 0o644); err != nil {
+		t.Fatalf("failed to modify file: %v", err)
+	}
+	created := filepath.Join(dir, "out", "artifact.bin")
+	if err := os.MkdirAll(filepath.Dir(created), 0o755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(created, []byte("bin"), 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	pass, changed, err := manager.undoLast()
+	if err != nil {
+		t.Fatalf("undoLast returned error: %v", err)
+	}
+	if pass != 3 || len(changed) != 2 {
+		t.Fatalf("unexpected undo result: pass=%d changed=%v", pass, changed)
+	}
+	if content, _ := os.ReadFile(existing); string(content) != "original" {
+		t.Fatalf("expected original content, got %q", content)
+	}
+	if _, err := os.Stat(created); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected created file to be removed, got %v", err)
+	}
+}
+
+func TestUndoLastPassKeepsUnrecordedFilesBeyondTheCap(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for i := range maxSnapshotFiles + 10 {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%05d.txt", i)), nil, 0o644); err != nil {
+			t.Fatalf("failed to seed file: %v", err)
+		}
+	}
+
+	manager := newSnapshotManager(filepath.Join(t.TempDir(), "snapshots"), dir)
+	step := PlanStep{ID: "step-1", Command: CommandDraft{Shell: "bash", Run: "make build", Cwd: dir}}
+	if err := manager.recordStep(1, step); err != nil {
+		t.Fatalf("recordStep returned error: %v", err)
+	}
+	if _, _, err := manager.undoLast(); err == nil {
+		t.Fatalf("expected undo to report the partial snapshot")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != maxSnapshotFiles+10 {
+		t.Fatalf("expected every pre-existing file to survive, have %d (%v)", len(entries), err)
+	}
+}
+
+func TestRecordStepSkipsDirectoriesOutsideTheWorkspace(t *testing.T) {
+	t.Parallel()
+
+	workspace := t.TempDir()
+	outside := t.TempDir()
+	manager := newSnapshotManager(filepath.Join(t.TempDir(), "snapshots"), workspace)
+	step := PlanStep{ID: "step-1", Command: CommandDraft{Shell: "bash", Run: "make build", Cwd: outside}}
+	if err := manager.recordStep(1, step); err != nil {
+		t.Fatalf("recordStep returned error: %v", err)
+	}
+	created := filepath.Join(outside, "new.txt")
+	if err := os.WriteFile(created, nil, 0o644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if _, _, err := manager.undoLast(); err == nil {
+		t.Fatalf("expected undo to report the unrecorded directory")
+	}
+	if _, err := os.Stat(created); err != nil {
+		t.Fatalf("expected the file outside the workspace to be left alone: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// steeringQueue holds prompts submitted while the agent works. They are
+// added to history before the next plan request instead of being rejected.
+type steeringQueue struct {
+	mu      sync.Mutex
+	pending []ChatMessage
+}
+
+func (q *steeringQueue) post(msg ChatMessage) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending = append(q.pending, msg)
+	return len(q.pending)
+}
+
+func (q *steeringQueue) drain() []ChatMessage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	pending := q.pending
+	q.pending = nil
+	return pending
+}
+
+// steer queues a prompt for the running pass loop. It reports false when the
+// agent is idle, in which case the prompt should start a turn instead. Empty
+// prompts are dropped.
+func (r *Runtime) steer(prompt string, attachments []Attachment) bool {
+	r.workMu.Lock()
+	if !r.working {
+		r.workMu.Unlock()
+		return false
+	}
+	prompt = strings.TrimSpace(prompt)
+	// A running turn keeps its model; only the text of a "/model" prompt
+	// steers it.
+	if _, rest, ok, err := parseModelOverride(prompt); ok && err == nil {
+		prompt = rest
+	}
+	if prompt == "" && len(attachments) > 0 {
+		prompt = "See the attached files."
+	}
+	if prompt == "" {
+		r.workMu.Unlock()
+		return true
+	}
+	queued := r.steering.post(ChatMessage{Role: RoleUser, Content: prompt, Timestamp: time.Now(), Attachments: attachments})
+	r.workMu.Unlock()
+
+	r.emit(RuntimeEvent{
+		Type:     EventTypeStatus,
+		Message:  "Steering message queued; the agent sees it before its next plan.",
+		Level:    StatusLevelInfo,
+		Metadata: map[string]any{"steering": true, "queued": queued},
+	})
+	return true
+}
+
+// injectSteering moves queued steering messages into history.
+func (r *Runtime) injectSteering() {
+	for _, msg := range r.steering.drain() {
+		r.appendHistory(msg)
+	}
+}
+
+// resubmitSteering turns steering messages that arrived after the last plan
+// request of a turn into the next prompt, so they are not lost when the
+// turn ends without another request.
+func (r *Runtime) resubmitSteering() {
+	pending := r.steering.drain()
+	if len(pending) == 0 {
+		return
+	}
+	prompts := make([]string, 0, len(pending))
+	var attachments []Attachment
+	for _, msg := range pending {
+		prompts = append(prompts, msg.Content)
+		attachments = append(attachments, msg.Attachments...)
+	}
+	r.emit(RuntimeEvent{
+		Type:    EventTypeStatus,
+		Message: fmt.Sprintf("Continuing with %d steering message(s) sent during the last turn.", len(pending)),
+		Level:   StatusLevelInfo,
+	})
+	go r.enqueue(InputEvent{Type: InputTypePrompt, Prompt: strings.Join(prompts, "\n\n"), Attachments: attachments})
+}
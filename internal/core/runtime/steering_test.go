@@ -0,0 +1,47 @@
+package runtime
+
+import "testing"
+
+func TestSubmitPromptSteersWhileWorking(t *testing.T) {
+	t.Parallel()
+
+	rt := &Runtime{
+		options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}},
+		inputs:    make(chan InputEvent, 4),
+		outputs:   make(chan RuntimeEvent, 8),
+		closed:    make(chan struct{}),
+		agentName: "main",
+	}
+	if !rt.beginWork() {
+		t.Fatal("expected to begin work")
+	}
+
+	rt.SubmitPrompt("use the v2 API instead")
+	rt.SubmitPrompt("   ")
+	if len(rt.inputs) != 0 {
+		t.Fatalf("expected no prompt to be queued while working, got %d", len(rt.inputs))
+	}
+	evt := <-rt.outputs
+	if steering, _ := evt.Metadata["steering"].(bool); !steering {
+		t.Fatalf("expected a steering status event, got %+v", evt)
+	}
+
+	rt.injectSteering()
+	history := rt.historySnapshot()
+	if len(history) != 1 || history[0].Role != RoleUser || history[0].Content != "use the v2 API instead" {
+		t.Fatalf("expected the steering message in history, got %+v", history)
+	}
+
+	rt.SubmitPrompt("also update the docs")
+	rt.SubmitPrompt("and the changelog")
+	rt.resubmitSteering()
+	rt.endWork()
+	if got := <-rt.inputs; got.Type != InputTypePrompt || got.Prompt != "also update the docs\n\nand the changelog" {
+		t.Fatalf("expected late steering to become the next prompt, got %+v", got)
+	}
+
+	rt.SubmitPrompt("next task")
+	if got := <-rt.inputs; got.Prompt != "next task" {
+		t.Fatalf("expected an idle prompt to be queued, got %+v", got)
+	}
+}
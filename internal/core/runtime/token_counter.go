@@ -0,0 +1,121 @@
+package runtime
+
+import (
+	"hash/maphash"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// TokenCounter reports how many tokens a string occupies in the model's
+// context. RuntimeOptions.TokenCounter plugs in a provider specific
+// tokenizer; context budgets and compaction triggers are measured with it.
+type TokenCounter interface {
+	CountTokens(text string) int
+}
+
+// HeuristicTokenCounter approximates four characters per token. It is the
+// fallback when no tokenizer is available for the model.
+type HeuristicTokenCounter struct{}
+
+// CountTokens implements TokenCounter.
+func (HeuristicTokenCounter) CountTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	runes := utf8.RuneCountInString(text)
+	tokens := int(math.Ceil(float64(runes) / 4))
+	if tokens < 1 {
+		tokens = 1
+	}
+	return tokens
+}
+
+// defaultTokenCountCacheSize bounds the number of memoized counts.
+const defaultTokenCountCacheSize = 4096
+
+// cachedTokenCounter memoizes counts by content hash. History messages are
+// re-counted before every plan request, so almost every lookup is a hit.
+type cachedTokenCounter struct {
+	counter  TokenCounter
+	seed     maphash.Seed
+	capacity int
+
+	mu     sync.Mutex
+	counts map[tokenCacheKey]int
+}
+
+type tokenCacheKey struct {
+	hash   uint64
+	length int
+}
+
+// NewCachedTokenCounter wraps counter with a cache of up to capacity
+// entries. The cache is cleared when it fills up.
+func NewCachedTokenCounter(counter TokenCounter, capacity int) TokenCounter {
+	if capacity <= 0 {
+		capacity = defaultTokenCountCacheSize
+	}
+	return &cachedTokenCounter{
+		counter:  counter,
+		seed:     maphash.MakeSeed(),
+		capacity: capacity,
+		counts:   make(map[tokenCacheKey]int),
+	}
+}
+
+// CountTokens implements TokenCounter.
+func (c *cachedTokenCounter) CountTokens(text string) int {
+	// Short strings are cheaper to count than to hash and look up.
+	if len(text) < 64 {
+		return c.counter.CountTokens(text)
+	}
+	key := tokenCacheKey{hash: maphash.String(c.seed, text), length: len(text)}
+	c.mu.Lock()
+	count, ok := c.counts[key]
+	c.mu.Unlock()
+	if ok {
+		return count
+	}
+
+	count = c.counter.CountTokens(text)
+	c.mu.Lock()
+	if len(c.counts) >= c.capacity {
+		clear(c.counts)
+	}
+	c.counts[key] = count
+	c.mu.Unlock()
+	return count
+}
+
+// DefaultTokenCounter picks a counter for the provider and model. OpenAI
+// models use the matching tiktoken encoding when its ranks file
+// ("<encoding>.tiktoken") is found in dir; everything else falls back to the
+// heuristic. The result is cached.
+func DefaultTokenCounter(provider, model, dir string) TokenCounter {
+	var counter TokenCounter = HeuristicTokenCounter{}
+	if provider == ProviderOpenAI && strings.TrimSpace(dir) != "" {
+		encoding := TiktokenEncodingForModel(model)
+		if tokenizer, err := LoadTiktokenFile(filepath.Join(dir, encoding+".tiktoken"), encoding); err == nil {
+			counter = tokenizer
+		}
+	}
+	return NewCachedTokenCounter(counter, defaultTokenCountCacheSize)
+}
+
+// tokenCounter returns the runtime's counter, or the heuristic when none is
+// configured.
+func (r *Runtime) tokenCounter() TokenCounter {
+	if r.tokens == nil {
+		return HeuristicTokenCounter{}
+	}
+	return r.tokens
+}
+
+// defaultTokenizerDir returns the directory holding tiktoken ranks files.
+func defaultTokenizerDir() string {
+	return strings.TrimSpace(os.Getenv("GOAGENT_TOKENIZER_DIR"))
+}
@@ -0,0 +1,82 @@
+package runtime
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testTokenizer(t *testing.T, merges ...string) *BPETokenizer {
+	t.Helper()
+	var ranks strings.Builder
+	for b := 0; b < 256; b++ {
+		fmt.Fprintf(&ranks, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(b)}), b)
+	}
+	for i, merge := range merges {
+		fmt.Fprintf(&ranks, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(merge)), 256+i)
+	}
+	tokenizer, err := NewBPETokenizer(strings.NewReader(ranks.String()), EncodingCL100K)
+	if err != nil {
+		t.Fatalf("NewBPETokenizer: %v", err)
+	}
+	return tokenizer
+}
+
+func TestBPETokenizerMergesByRank(t *testing.T) {
+	t.Parallel()
+
+	tokenizer := testTokenizer(t, "he", "ll", "hell", " w", " wor")
+	if got, want := tokenizer.Encode("hello"), []int{258, 'o'}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Encode(hello) = %v, want %v", got, want)
+	}
+	// " world" is one pre-token; " w" merges first, then " wor" needs "or"
+	// which is not in the vocabulary.
+	if got, want := tokenizer.Encode(" world"), []int{259, 'o', 'r', 'l', 'd'}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Encode(\" world\") = %v, want %v", got, want)
+	}
+	if got := tokenizer.CountTokens("hello world"); got != 7 {
+		t.Fatalf("CountTokens = %d, want 7", got)
+	}
+}
+
+func TestBPETokenizerSplitsLikeTiktoken(t *testing.T) {
+	t.Parallel()
+
+	tokenizer := testTokenizer(t)
+	var pieces []string
+	tokenizer.splitPieces("let's go  now\n\n  x 1234", func(piece string) {
+		pieces = append(pieces, piece)
+	})
+	want := []string{"let", "'s", " go", " ", " now", "\n\n", " ", " x", " ", "123", "4"}
+	if !reflect.DeepEqual(pieces, want) {
+		t.Fatalf("pieces = %q, want %q", pieces, want)
+	}
+}
+
+type countingTokenCounter struct{ calls int }
+
+func (c *countingTokenCounter) CountTokens(text string) int {
+	c.calls++
+	return len(text)
+}
+
+func TestCachedTokenCounterMemoizes(t *testing.T) {
+	t.Parallel()
+
+	inner := &countingTokenCounter{}
+	counter := NewCachedTokenCounter(inner, 2)
+	long := strings.Repeat("x", 100)
+	for i := 0; i < 3; i++ {
+		if got := counter.CountTokens(long); got != 100 {
+			t.Fatalf("CountTokens = %d", got)
+		}
+	}
+	if inner.calls != 1 {
+		t.Fatalf("expected one uncached count, got %d", inner.calls)
+	}
+	if got := DefaultTokenCounter(ProviderAnthropic, "claude-sonnet-4", t.TempDir()).CountTokens("abcdefgh"); got != 2 {
+		t.Fatalf("expected heuristic fallback, got %d", got)
+	}
+}
@@ -0,0 +1,171 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/asynkron/goagent/internal/core/schema"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// ToolHandler runs a host-provided tool with arguments that already passed
+// schema validation. The returned string is forwarded to the model verbatim.
+type ToolHandler func(ctx context.Context, arguments json.RawMessage) (string, error)
+
+// ToolSpec registers a function tool that the model can call alongside the
+// plan tool. Unlike internal commands, tools are advertised to the provider
+// with their own JSON schema and invoked directly from the model's tool call.
+type ToolSpec struct {
+	Name        string
+	Description string
+	// JSONSchema describes the tool arguments. Nil accepts any object.
+	JSONSchema map[string]any
+	Handler    ToolHandler
+}
+
+// maxConsecutiveToolCalls bounds how many host tool calls a single plan
+// request may chain before the runtime gives up on getting a plan.
+const maxConsecutiveToolCalls = 25
+
+// toolParameters returns the schema advertised for the tool.
+func (s ToolSpec) toolParameters() map[string]any {
+	if s.JSONSchema == nil {
+		return map[string]any{"type": "object"}
+	}
+	return s.JSONSchema
+}
+
+// definition converts the spec into the provider-facing tool metadata.
+func (s ToolSpec) definition() schema.ToolDefinition {
+	return schema.ToolDefinition{
+		Name:        s.Name,
+		Description: s.Description,
+		Parameters:  s.toolParameters(),
+	}
+}
+
+// validateToolSpecs rejects unnamed, duplicate, or handler-less tools and
+// names that would shadow the plan tool.
+func validateToolSpecs(tools []ToolSpec) error {
+	seen := make(map[string]struct{}, len(tools))
+	for _, tool := range tools {
+		name := strings.TrimSpace(tool.Name)
+		if name == "" {
+			return errors.New("tool name is required")
+		}
+		if name == schema.ToolName {
+			return fmt.Errorf("tool name %q is reserved for the plan tool", name)
+		}
+		if tool.Handler == nil {
+			return fmt.Errorf("tool %q has no handler", name)
+		}
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("tool %q registered twice", name)
+		}
+		seen[name] = struct{}{}
+	}
+	return nil
+}
+
+// toolDefinitions lists the definitions for the registered host tools.
+func toolDefinitions(tools []ToolSpec) []schema.ToolDefinition {
+	defs := make([]schema.ToolDefinition, 0, len(tools))
+	for _, tool := range tools {
+		defs = append(defs, tool.definition())
+	}
+	return defs
+}
+
+// lookupTool finds a registered host tool by its exact name.
+func (r *Runtime) lookupTool(name string) (ToolSpec, bool) {
+	for _, tool := range r.options.Tools {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return ToolSpec{}, false
+}
+
+// toolResult is the payload recorded in history after a host tool runs. The
+// tool name and arguments are repeated because providers only see message
+// text, not the structured tool call.
+type toolResult struct {
+	Tool      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Result    string          `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// invokeTool validates the call arguments, runs the handler, and appends the
+// call and its outcome to history so the next plan request can use it.
+func (r *Runtime) invokeTool(ctx context.Context, tool ToolSpec, toolCall ToolCall) {
+	result := toolResult{Tool: tool.Name}
+	args := strings.TrimSpace(toolCall.Arguments)
+	if args == "" {
+		args = "{}"
+	}
+	if json.Valid([]byte(args)) {
+		result.Arguments = json.RawMessage(args)
+	}
+
+	r.emit(RuntimeEvent{
+		Type:     EventTypeStatus,
+		Message:  fmt.Sprintf("Running tool %s.", tool.Name),
+		Level:    StatusLevelInfo,
+		Metadata: map[string]any{"tool_name": tool.Name, "tool_call_id": toolCall.ID},
+	})
+
+	if err := validateToolArguments(tool, args); err != nil {
+		result.Error = err.Error()
+	} else if output, err := tool.Handler(ctx, json.RawMessage(args)); err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Result = output
+	}
+
+	if result.Error != "" {
+		r.emit(RuntimeEvent{
+			Type:     EventTypeStatus,
+			Message:  fmt.Sprintf("Tool %s failed: %s", tool.Name, result.Error),
+			Level:    StatusLevelWarn,
+			Metadata: map[string]any{"tool_name": tool.Name, "tool_call_id": toolCall.ID},
+		})
+	}
+
+	r.appendHistory(ChatMessage{
+		Role:      RoleAssistant,
+		Timestamp: time.Now(),
+		ToolCalls: []ToolCall{toolCall},
+	})
+	content, err := json.Marshal(result)
+	if err != nil {
+		content = []byte(fmt.Sprintf(`{"tool":%q,"error":"failed to encode tool result"}`, tool.Name))
+	}
+	r.appendHistory(ChatMessage{
+		Role:       RoleTool,
+		Content:    string(content),
+		ToolCallID: toolCall.ID,
+		Name:       tool.Name,
+		Timestamp:  time.Now(),
+	})
+}
+
+// validateToolArguments checks raw arguments against the tool's JSON schema.
+func validateToolArguments(tool ToolSpec, args string) error {
+	result, err := gojsonschema.Validate(gojsonschema.NewGoLoader(tool.toolParameters()), gojsonschema.NewStringLoader(args))
+	if err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	if result.Valid() {
+		return nil
+	}
+	issues := make([]string, 0, len(result.Errors()))
+	for _, desc := range result.Errors() {
+		issues = append(issues, desc.String())
+	}
+	return fmt.Errorf("arguments failed schema validation: %s", strings.Join(issues, "; "))
+}
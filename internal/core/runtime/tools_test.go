@@ -0,0 +1,109 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/asynkron/goagent/internal/core/schema"
+)
+
+// scriptedProvider returns the queued tool calls in order.
+type scriptedProvider struct {
+	calls    []ToolCall
+	requests int
+}
+
+func (p *scriptedProvider) RequestPlan(_ context.Context, _ []ChatMessage) (ToolCall, error) {
+	if p.requests >= len(p.calls) {
+		return ToolCall{}, errors.New("no scripted tool call left")
+	}
+	call := p.calls[p.requests]
+	p.requests++
+	return call, nil
+}
+
+func (p *scriptedProvider) RequestPlanStreaming(ctx context.Context, history []ChatMessage, _ func(string)) (ToolCall, error) {
+	return p.RequestPlan(ctx, history)
+}
+
+func TestRequestPlanRunsHostToolsBeforePlan(t *testing.T) {
+	t.Parallel()
+
+	var received []string
+	lookup := ToolSpec{
+		Name:        "lookup_ticket",
+		Description: "Fetch a ticket by id",
+		JSONSchema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"id": map[string]any{"type": "string"}},
+			"required":   []any{"id"},
+		},
+		Handler: func(_ context.Context, args json.RawMessage) (string, error) {
+			received = append(received, string(args))
+			return "ticket is open", nil
+		},
+	}
+
+	provider := &scriptedProvider{calls: []ToolCall{
+		{ID: "call-1", Name: "lookup_ticket", Arguments: `{"id":42}`},
+		{ID: "call-2", Name: "lookup_ticket", Arguments: `{"id":"42"}`},
+		{ID: "call-3", Name: schema.ToolName, Arguments: `{"message":"done","reasoning":[],"plan":[],"requireHumanInput":false}`},
+	}}
+
+	rt := &Runtime{
+		options: RuntimeOptions{
+			Logger:  &NoOpLogger{},
+			Metrics: &NoOpMetrics{},
+			Tools:   []ToolSpec{lookup},
+		},
+		outputs:   make(chan RuntimeEvent, 32),
+		closed:    make(chan struct{}),
+		plan:      NewPlanManager(),
+		client:    provider,
+		history:   []ChatMessage{{Role: RoleSystem, Content: "system"}},
+		agentName: "main",
+	}
+
+	plan, toolCall, err := rt.requestPlan(context.Background())
+	if err != nil {
+		t.Fatalf("requestPlan returned error: %v", err)
+	}
+	if plan == nil || plan.Message != "done" || toolCall.ID != "call-3" {
+		t.Fatalf("unexpected plan result: %+v %+v", plan, toolCall)
+	}
+
+	if len(received) != 1 || received[0] != `{"id":"42"}` {
+		t.Fatalf("expected handler to run only for valid arguments, got %v", received)
+	}
+
+	history := rt.historySnapshot()
+	if len(history) != 5 {
+		t.Fatalf("expected two tool exchanges in history, got %d entries", len(history))
+	}
+	if history[2].Role != RoleTool || !strings.Contains(history[2].Content, "schema validation") {
+		t.Fatalf("expected validation error fed back, got %+v", history[2])
+	}
+	if history[4].Role != RoleTool || !strings.Contains(history[4].Content, "ticket is open") {
+		t.Fatalf("expected tool result fed back, got %+v", history[4])
+	}
+}
+
+func TestValidateToolSpecsRejectsReservedAndDuplicateNames(t *testing.T) {
+	t.Parallel()
+
+	handler := func(context.Context, json.RawMessage) (string, error) { return "", nil }
+	cases := [][]ToolSpec{
+		{{Name: schema.ToolName, Handler: handler}},
+		{{Name: "a", Handler: handler}, {Name: "a", Handler: handler}},
+		{{Name: "a"}},
+		{{Handler: handler}},
+	}
+	for i, tools := range cases {
+		if err := validateToolSpecs(tools); err == nil {
+			t.Fatalf("case %d: expected validation error", i)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package runtime
+
+import "context"
+
+// Tracer starts spans around the runtime's units of work: each plan pass,
+// each model request, and each executed step. Spans nest through the
+// context, so a sub-agent started from a step traces under that step.
+//
+// The interface follows the shape of an OpenTelemetry tracer. Hosts that
+// export traces over OTLP wrap their OTel tracer in a few lines; the runtime
+// itself does not depend on the OTel SDK.
+type Tracer interface {
+	Start(ctx context.Context, name string, attributes ...LogField) (context.Context, Span)
+}
+
+// Span is a unit of work started by a Tracer.
+type Span interface {
+	SetAttributes(attributes ...LogField)
+	RecordError(err error)
+	End()
+}
+
+// Span names used by the runtime.
+const (
+	SpanPass         = "goagent.pass"
+	SpanModelRequest = "goagent.model_request"
+	SpanStep         = "goagent.step"
+)
+
+// NoOpTracer is a tracer that records nothing.
+type NoOpTracer struct{}
+
+func (NoOpTracer) Start(ctx context.Context, _ string, _ ...LogField) (context.Context, Span) {
+	return ctx, noOpSpan{}
+}
+
+type noOpSpan struct{}
+
+func (noOpSpan) SetAttributes(_ ...LogField) {}
+func (noOpSpan) RecordError(_ error)         {}
+func (noOpSpan) End()                        {}
+
+// startSpan starts a span tagged with the agent name and the trace ID the
+// structured logs carry, so spans and log lines can be joined.
+func (r *Runtime) startSpan(ctx context.Context, name string, attributes ...LogField) (context.Context, Span) {
+	tracer := r.options.Tracer
+	if tracer == nil {
+		tracer = NoOpTracer{}
+	}
+	attributes = append(attributes, Field("agent_name", r.agentName))
+	if traceID := getTraceID(ctx); traceID != "" {
+		attributes = append(attributes, Field("trace_id", traceID))
+	}
+	return tracer.Start(ctx, name, attributes...)
+}
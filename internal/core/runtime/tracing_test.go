@@ -0,0 +1,92 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+type recordedSpan struct {
+	name       string
+	parent     *recordedSpan
+	attributes map[string]any
+	err        error
+	ended      bool
+}
+
+func (s *recordedSpan) SetAttributes(attributes ...LogField) {
+	for _, attr := range attributes {
+		s.attributes[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordedSpan) RecordError(err error) { s.err = err }
+func (s *recordedSpan) End()                  { s.ended = true }
+
+type spanKey struct{}
+
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, attributes ...LogField) (context.Context, Span) {
+	parent, _ := ctx.Value(spanKey{}).(*recordedSpan)
+	span := &recordedSpan{name: name, parent: parent, attributes: map[string]any{}}
+	span.SetAttributes(attributes...)
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func TestExecutePendingCommandsTracesSteps(t *testing.T) {
+	t.Parallel()
+
+	tracer := &recordingTracer{}
+	rt := &Runtime{
+		options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}, Tracer: tracer},
+		plan:      NewPlanManager(),
+		executor:  NewCommandExecutor(nil, nil),
+		outputs:   make(chan RuntimeEvent, 10),
+		closed:    make(chan struct{}),
+		history:   []ChatMessage{},
+		agentName: "main",
+	}
+
+	var stepSpan *recordedSpan
+	if err := rt.executor.RegisterInternalCommand("fail", func(ctx context.Context, req InternalCommandRequest) (PlanObservationPayload, error) {
+		// Work done by the step, including sub-agents, sees the step span.
+		stepSpan, _ = ctx.Value(spanKey{}).(*recordedSpan)
+		return PlanObservationPayload{}, errors.New("boom")
+	}); err != nil {
+		t.Fatalf("failed to register internal command: %v", err)
+	}
+	rt.plan.Replace([]PlanStep{{
+		ID:      "step-1",
+		Status:  PlanPending,
+		Command: CommandDraft{Shell: agentShell, Run: "fail"},
+	}})
+
+	ctx, pass := rt.startSpan(WithTraceID(context.Background(), "trace-1"), SpanPass)
+	rt.executePendingCommands(ctx, ToolCall{ID: "call-1", Name: "open-agent"})
+	pass.End()
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("expected a pass and a step span, got %d", len(tracer.spans))
+	}
+	step := tracer.spans[1]
+	if step != stepSpan {
+		t.Fatalf("expected the step to run under its span")
+	}
+	if step.name != SpanStep || step.parent != tracer.spans[0] {
+		t.Fatalf("expected a step span under the pass, got %q with parent %v", step.name, step.parent)
+	}
+	if step.attributes["step_id"] != "step-1" || step.attributes["trace_id"] != "trace-1" || step.attributes["agent_name"] != "main" {
+		t.Fatalf("unexpected step attributes: %v", step.attributes)
+	}
+	if step.err == nil || !step.ended {
+		t.Fatalf("expected the failed step span to record the error and end")
+	}
+}
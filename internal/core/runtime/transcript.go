@@ -0,0 +1,329 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/asynkron/goagent/internal/core/schema"
+	"github.com/yuin/goldmark"
+)
+
+// TranscriptFormat selects the output of Runtime.ExportTranscript.
+type TranscriptFormat string
+
+const (
+	// TranscriptMarkdown renders the session as Markdown. Command output is
+	// folded into <details> blocks, which GitHub and most viewers collapse.
+	TranscriptMarkdown TranscriptFormat = "markdown"
+	// TranscriptHTML renders a standalone HTML page.
+	TranscriptHTML TranscriptFormat = "html"
+)
+
+// transcriptEntry is one turn of the rendered conversation.
+type transcriptEntry struct {
+	role        MessageRole
+	time        time.Time
+	text        string
+	attachments []string
+	summary     bool
+	steps       []transcriptStep
+	// tool, toolArgs and toolResult describe host tool calls, and results
+	// that are not step observations (such as validation errors).
+	tool       string
+	toolArgs   string
+	toolResult string
+}
+
+type transcriptStep struct {
+	id       string
+	title    string
+	command  string
+	status   PlanStatus
+	output   string
+	exitCode *int
+	details  string
+}
+
+// ExportTranscript writes the conversation so far, including user prompts,
+// assistant messages, plan steps with their final status and collapsed
+// command output, to w. The system prompt is left out.
+func (r *Runtime) ExportTranscript(w io.Writer, format TranscriptFormat) error {
+	entries := buildTranscript(r.historySnapshot())
+	switch format {
+	case TranscriptMarkdown, "":
+		return writeMarkdownTranscript(w, entries)
+	case TranscriptHTML:
+		return writeHTMLTranscript(w, entries)
+	default:
+		return fmt.Errorf("transcript: unknown format %q", format)
+	}
+}
+
+func buildTranscript(history []ChatMessage) []transcriptEntry {
+	var entries []transcriptEntry
+	// calls maps tool call ids to the entry awaiting their result.
+	calls := make(map[string]int)
+
+	for _, msg := range history {
+		switch msg.Role {
+		case RoleUser:
+			entry := transcriptEntry{role: RoleUser, time: msg.Timestamp, text: msg.Content, summary: msg.Summarized}
+			for _, attachment := range msg.Attachments {
+				entry.attachments = append(entry.attachments, attachment.Name)
+			}
+			entries = append(entries, entry)
+		case RoleAssistant:
+			if strings.TrimSpace(msg.Content) != "" {
+				entries = append(entries, transcriptEntry{role: RoleAssistant, time: msg.Timestamp, text: msg.Content, summary: msg.Summarized})
+			}
+			for _, call := range msg.ToolCalls {
+				entries = append(entries, transcriptCallEntry(msg.Timestamp, call))
+				calls[call.ID] = len(entries) - 1
+			}
+		case RoleTool:
+			index, ok := calls[msg.ToolCallID]
+			if !ok {
+				continue
+			}
+			delete(calls, msg.ToolCallID)
+			applyTranscriptResult(&entries[index], msg.Content)
+		}
+	}
+	return entries
+}
+
+func transcriptCallEntry(at time.Time, call ToolCall) transcriptEntry {
+	entry := transcriptEntry{role: RoleAssistant, time: at}
+	var plan PlanResponse
+	if call.Name != schema.ToolName || json.Unmarshal([]byte(call.Arguments), &plan) != nil {
+		entry.tool = call.Name
+		entry.toolArgs = call.Arguments
+		return entry
+	}
+
+	entry.text = plan.Message
+	for _, step := range plan.Plan {
+		// Steps the model already marked completed were shown in an
+		// earlier turn.
+		if step.Status == PlanCompleted {
+			continue
+		}
+		entry.steps = append(entry.steps, transcriptStep{
+			id:      step.ID,
+			title:   step.Title,
+			command: step.Command.Run,
+			status:  step.Status,
+		})
+	}
+	return entry
+}
+
+func applyTranscriptResult(entry *transcriptEntry, content string) {
+	var payload PlanObservationPayload
+	if entry.tool != "" || json.Unmarshal([]byte(content), &payload) != nil || len(payload.PlanObservation) == 0 {
+		entry.toolResult = content
+		return
+	}
+	for _, obs := range payload.PlanObservation {
+		for i := range entry.steps {
+			step := &entry.steps[i]
+			if step.id != obs.ID {
+				continue
+			}
+			step.status = obs.Status
+			step.output = strings.TrimRight(obs.Stdout, "\n")
+			if stderr := strings.TrimRight(obs.Stderr, "\n"); stderr != "" {
+				if step.output != "" {
+					step.output += "\n"
+				}
+				step.output += stderr
+			}
+			step.exitCode = obs.ExitCode
+			step.details = obs.Details
+		}
+	}
+}
+
+func (e transcriptEntry) heading() string {
+	switch {
+	case e.summary:
+		return "Summary of earlier conversation"
+	case e.tool != "":
+		return "Tool call: " + e.tool
+	case e.role == RoleUser:
+		return "User"
+	default:
+		return "Assistant"
+	}
+}
+
+func (s transcriptStep) label() string {
+	title := strings.TrimSpace(s.title)
+	if title == "" {
+		title = s.id
+	}
+	status := s.status
+	if status == "" {
+		status = PlanPending
+	}
+	return fmt.Sprintf("[%s] %s", status, title)
+}
+
+func (s transcriptStep) outputSummary() string {
+	if s.exitCode != nil {
+		return fmt.Sprintf("Output (exit %d)", *s.exitCode)
+	}
+	return "Output"
+}
+
+func writeMarkdownTranscript(w io.Writer, entries []transcriptEntry) error {
+	var b strings.Builder
+	b.WriteString("# GoAgent transcript\n")
+	for _, entry := range entries {
+		fmt.Fprintf(&b, "\n## %s\n", entry.heading())
+		if !entry.time.IsZero() {
+			fmt.Fprintf(&b, "\n_%s_\n", entry.time.Format(time.RFC3339))
+		}
+		if text := strings.TrimSpace(entry.text); text != "" {
+			fmt.Fprintf(&b, "\n%s\n", text)
+		}
+		if len(entry.attachments) > 0 {
+			fmt.Fprintf(&b, "\nAttachments: %s\n", strings.Join(entry.attachments, ", "))
+		}
+		if entry.toolArgs != "" {
+			fmt.Fprintf(&b, "\n%s\n", markdownFence(entry.toolArgs, "json"))
+		}
+		if len(entry.steps) > 0 {
+			b.WriteString("\n")
+		}
+		for _, step := range entry.steps {
+			fmt.Fprintf(&b, "- **%s**", step.label())
+			if step.command != "" {
+				fmt.Fprintf(&b, " `%s`", strings.ReplaceAll(step.command, "`", "'"))
+			}
+			b.WriteString("\n")
+			if step.details != "" && step.output == "" {
+				fmt.Fprintf(&b, "  %s\n", strings.ReplaceAll(strings.TrimSpace(step.details), "\n", " "))
+			}
+			if step.output != "" {
+				writeMarkdownDetails(&b, step.outputSummary(), step.output, "  ")
+			}
+		}
+		if entry.toolResult != "" {
+			writeMarkdownDetails(&b, "Result", entry.toolResult, "")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeMarkdownDetails(b *strings.Builder, summary, body, indent string) {
+	fmt.Fprintf(b, "\n%s<details><summary>%s</summary>\n\n", indent, html.EscapeString(summary))
+	for _, line := range strings.Split(markdownFence(body, ""), "\n") {
+		fmt.Fprintf(b, "%s%s\n", indent, line)
+	}
+	fmt.Fprintf(b, "\n%s</details>\n\n", indent)
+}
+
+// markdownFence wraps text in a code fence longer than any backtick run it
+// contains.
+func markdownFence(text, lang string) string {
+	longest, run := 0, 0
+	for _, ch := range text {
+		if ch == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	return fence + lang + "\n" + strings.TrimRight(text, "\n") + "\n" + fence
+}
+
+const transcriptHTMLHead = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>GoAgent transcript</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 56rem; margin: 2rem auto; padding: 0 1rem; line-height: 1.5; color: #1f2328; }
+section { border-left: 4px solid #d0d7de; padding: 0 1rem; margin: 1.5rem 0; }
+section.user { border-color: #0969da; }
+section.assistant { border-color: #8250df; }
+h2 { font-size: 1rem; margin: 0; }
+time { color: #59636e; font-size: 0.85rem; }
+pre { background: #f6f8fa; padding: 0.75rem; overflow-x: auto; }
+ul.steps { list-style: none; padding-left: 0; }
+.status { font-weight: 600; }
+.status-completed { color: #1a7f37; }
+.status-failed { color: #cf222e; }
+.status-pending, .status-abandoned, .status-cancelled { color: #59636e; }
+</style>
+</head>
+<body>
+<h1>GoAgent transcript</h1>
+`
+
+func writeHTMLTranscript(w io.Writer, entries []transcriptEntry) error {
+	var b strings.Builder
+	b.WriteString(transcriptHTMLHead)
+	for _, entry := range entries {
+		fmt.Fprintf(&b, "<section class=\"%s\">\n<h2>%s</h2>\n", entry.role, html.EscapeString(entry.heading()))
+		if !entry.time.IsZero() {
+			fmt.Fprintf(&b, "<time datetime=\"%[1]s\">%[1]s</time>\n", entry.time.Format(time.RFC3339))
+		}
+		if text := strings.TrimSpace(entry.text); text != "" {
+			if entry.role == RoleAssistant {
+				// Raw HTML in the markdown is dropped by goldmark's default
+				// renderer, so model output cannot inject markup.
+				var rendered bytes.Buffer
+				if err := goldmark.Convert([]byte(text), &rendered); err != nil {
+					return fmt.Errorf("transcript: render markdown: %w", err)
+				}
+				b.Write(rendered.Bytes())
+			} else {
+				fmt.Fprintf(&b, "<pre>%s</pre>\n", html.EscapeString(text))
+			}
+		}
+		if len(entry.attachments) > 0 {
+			fmt.Fprintf(&b, "<p>Attachments: %s</p>\n", html.EscapeString(strings.Join(entry.attachments, ", ")))
+		}
+		if entry.toolArgs != "" {
+			fmt.Fprintf(&b, "<pre>%s</pre>\n", html.EscapeString(entry.toolArgs))
+		}
+		if len(entry.steps) > 0 {
+			b.WriteString("<ul class=\"steps\">\n")
+			for _, step := range entry.steps {
+				status := step.status
+				if status == "" {
+					status = PlanPending
+				}
+				fmt.Fprintf(&b, "<li><span class=\"status status-%s\">%s</span>", status, html.EscapeString(step.label()))
+				if step.command != "" {
+					fmt.Fprintf(&b, " <code>%s</code>", html.EscapeString(step.command))
+				}
+				if step.details != "" && step.output == "" {
+					fmt.Fprintf(&b, "<br>%s", html.EscapeString(strings.TrimSpace(step.details)))
+				}
+				if step.output != "" {
+					fmt.Fprintf(&b, "\n<details><summary>%s</summary><pre>%s</pre></details>", html.EscapeString(step.outputSummary()), html.EscapeString(step.output))
+				}
+				b.WriteString("</li>\n")
+			}
+			b.WriteString("</ul>\n")
+		}
+		if entry.toolResult != "" {
+			fmt.Fprintf(&b, "<details><summary>Result</summary><pre>%s</pre></details>\n", html.EscapeString(entry.toolResult))
+		}
+		b.WriteString("</section>\n")
+	}
+	b.WriteString("</body>\n</html>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,72 @@
+package runtime
+
+import (
+	"strings"
+	"testing"
+)
+
+func newTranscriptTestRuntime() *Runtime {
+	exit := 1
+	observation, _ := BuildToolMessage(PlanObservationPayload{PlanObservation: []StepObservation{
+		{ID: "s1", Status: PlanCompleted, Stdout: "ok  ./...\n", ExitCode: new(int)},
+		{ID: "s2", Status: PlanFailed, Stderr: "<boom>", ExitCode: &exit},
+	}})
+	return &Runtime{history: []ChatMessage{
+		{Role: RoleSystem, Content: "system prompt"},
+		{Role: RoleUser, Content: "run the tests", Attachments: []Attachment{{Name: "log.txt"}}},
+		{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "call-1", Name: "open-agent", Arguments: `{"message":"Running **tests**","reasoning":[],"requireHumanInput":false,"plan":[
+			{"id":"s1","title":"Unit tests","status":"pending","waitingForId":[],"command":{"shell":"bash","run":"go test ./..."}},
+			{"id":"s2","title":"Lint","status":"pending","waitingForId":[],"command":{"shell":"bash","run":"golangci-lint run"}}]}`}}},
+		{Role: RoleTool, ToolCallID: "call-1", Content: observation},
+	}}
+}
+
+func TestExportTranscriptMarkdown(t *testing.T) {
+	t.Parallel()
+
+	var b strings.Builder
+	if err := newTranscriptTestRuntime().ExportTranscript(&b, TranscriptMarkdown); err != nil {
+		t.Fatalf("ExportTranscript returned error: %v", err)
+	}
+	out := b.String()
+
+	if strings.Contains(out, "system prompt") {
+		t.Fatalf("expected the system prompt to be left out:\n%s", out)
+	}
+	for _, want := range []string{
+		"## User\n\nrun the tests\n\nAttachments: log.txt",
+		"## Assistant\n\nRunning **tests**",
+		"- **[completed] Unit tests** `go test ./...`",
+		"<details><summary>Output (exit 0)</summary>",
+		"- **[failed] Lint** `golangci-lint run`",
+		"<boom>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in transcript:\n%s", want, out)
+		}
+	}
+}
+
+func TestExportTranscriptHTMLEscapesOutput(t *testing.T) {
+	t.Parallel()
+
+	var b strings.Builder
+	if err := newTranscriptTestRuntime().ExportTranscript(&b, TranscriptHTML); err != nil {
+		t.Fatalf("ExportTranscript returned error: %v", err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		"<strong>tests</strong>",
+		`<span class="status status-failed">[failed] Lint</span>`,
+		"&lt;boom&gt;",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in transcript:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "<boom>") {
+		t.Fatal("expected command output to be escaped")
+	}
+}
@@ -25,6 +25,12 @@ type ChatMessage struct {
 	// Summarized marks messages that were synthesized by the compactor so we
 	// avoid repeatedly summarizing the same entry.
 	Summarized bool `json:"summarized,omitempty"`
+	// Stable marks messages that stay identical for the whole session, such
+	// as the system prompt. Providers keep them at the front of the request
+	// so the prefix can be served from the prompt cache.
+	Stable bool `json:"stable,omitempty"`
+	// Attachments carries files sent with a user prompt.
+	Attachments []Attachment `json:"attachments,omitempty"`
 }
 
 // ToolCall stores metadata for an assistant tool invocation.
@@ -44,6 +50,21 @@ type CommandDraft struct {
 	FilterRegex string `json:"filter_regex"`
 	TailLines   int    `json:"tail_lines"`
 	MaxBytes    int    `json:"max_bytes"`
+	// Interactive connects the command's stdin to host input delivered via
+	// InputTypeCommandStdin. Non-interactive commands read from an empty stdin.
+	Interactive bool `json:"interactive,omitempty"`
+	// Background starts the command as a job that keeps running after the
+	// step completes, e.g. a dev server. The step reports its early output;
+	// the jobs and kill_job internal commands inspect and stop it.
+	Background bool `json:"background,omitempty"`
+	// Retries re-runs a failed shell command up to this many extra times
+	// before the step is reported as failed. RetryBackoffSec is the delay
+	// before the first retry and doubles for each subsequent one.
+	Retries         int `json:"retries,omitempty"`
+	RetryBackoffSec int `json:"retry_backoff_sec,omitempty"`
+	// Env sets environment variables for the command on top of what
+	// RuntimeOptions.EnvPolicy lets it inherit.
+	Env map[string]string `json:"env,omitempty"`
 }
 
 // PlanStatus represents execution status for a plan step.
@@ -55,6 +76,9 @@ const (
 	PlanCompleted PlanStatus = "completed"
 	PlanFailed    PlanStatus = "failed"
 	PlanAbandoned PlanStatus = "abandoned"
+	// PlanCancelled marks steps stopped or skipped because the host
+	// cancelled the plan. It is only reported back, never planned.
+	PlanCancelled PlanStatus = "cancelled"
 )
 
 // StepObservation summarizes the outcome for a specific plan step.
@@ -66,6 +90,12 @@ type StepObservation struct {
 	ExitCode  *int       `json:"exit_code,omitempty"`
 	Details   string     `json:"details,omitempty"`
 	Truncated bool       `json:"truncated,omitempty"`
+	// Attempts is set for steps that allow retries.
+	Attempts int `json:"attempts,omitempty"`
+	// DurationMs is how long the step ran, including retries.
+	DurationMs int64 `json:"duration_ms,omitempty"`
+	// Artifacts reference the full output of truncated streams.
+	Artifacts []ArtifactRef `json:"artifacts,omitempty"`
 }
 
 // PlanObservationPayload mirrors the JSON payload forwarded back to the model.
@@ -75,6 +105,7 @@ type PlanObservationPayload struct {
 	Stderr                  string            `json:"-"`
 	Truncated               bool              `json:"-"`
 	ExitCode                *int              `json:"-"`
+	Attempts                int               `json:"-"`
 	JSONParseError          bool              `json:"json_parse_error,omitempty"`
 	SchemaValidationError   bool              `json:"schema_validation_error,omitempty"`
 	ResponseValidationError bool              `json:"response_validation_error,omitempty"`
@@ -82,6 +113,32 @@ type PlanObservationPayload struct {
 	OperationCanceled       bool              `json:"operation_canceled,omitempty"`
 	Summary                 string            `json:"summary,omitempty"`
 	Details                 string            `json:"details,omitempty"`
+	// FileChanges lists files touched by the command. It is surfaced to hosts
+	// through EventTypeFileChange and is not forwarded to the model.
+	FileChanges []FileChange `json:"-"`
+	// Data carries the typed result of internal commands that return
+	// structured output. Hosts receive it as the "data" metadata of the step
+	// completion event; the model sees the JSON in Stdout.
+	Data any `json:"-"`
+	// Artifacts reference the full output of truncated streams; they are
+	// forwarded to the model on the step's observation.
+	Artifacts []ArtifactRef `json:"-"`
+	// ValidationErrors locate the problems in a rejected plan response.
+	ValidationErrors []ValidationIssue `json:"validation_errors,omitempty"`
+}
+
+// FileChange describes a single file created, modified, or deleted by a step.
+type FileChange struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	Hunks  int    `json:"hunks"`
+	Bytes  int64  `json:"bytes"`
+	// Additions and Deletions count the lines added and removed.
+	Additions int `json:"additions"`
+	Deletions int `json:"deletions"`
+	// Diff holds the applied hunks in unified diff form when known, capped
+	// at maxFileChangeDiffBytes.
+	Diff string `json:"diff,omitempty"`
 }
 
 // PlanObservation bundles the payload with optional metadata.
@@ -91,13 +148,15 @@ type PlanObservation struct {
 
 // PlanStep describes an individual plan entry from OpenAI.
 type PlanStep struct {
-	ID           string           `json:"id"`
-	Title        string           `json:"title"`
-	Status       PlanStatus       `json:"status"`
-	WaitingForID []string         `json:"waitingForId"`
-	Command      CommandDraft     `json:"command"`
-	Observation  *PlanObservation `json:"observation,omitempty"`
-	Executing    bool             `json:"-"`
+	ID           string     `json:"id"`
+	Title        string     `json:"title"`
+	Status       PlanStatus `json:"status"`
+	WaitingForID []string   `json:"waitingForId"`
+	// ConcurrencyGroup serializes steps that share the same non-empty group.
+	ConcurrencyGroup string           `json:"concurrencyGroup,omitempty"`
+	Command          CommandDraft     `json:"command"`
+	Observation      *PlanObservation `json:"observation,omitempty"`
+	Executing        bool             `json:"-"`
 }
 
 // PlanResponse captures the structured assistant output.
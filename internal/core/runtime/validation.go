@@ -23,23 +23,61 @@ const (
 	validationBackoffBase   = 250 * time.Millisecond
 	validationBackoffMax    = 4 * time.Second
 	validationBackoffMaxExp = 5
+	// validationFragmentLimit caps the offending JSON quoted per issue.
+	validationFragmentLimit = 200
+	// maxValidationIssues caps the issues reported back to the model.
+	maxValidationIssues = 10
+
+	// DefaultMaxPlanValidationFailures is how many invalid plan responses
+	// in a row end the session when RuntimeOptions leaves it unset.
+	DefaultMaxPlanValidationFailures = 5
 )
 
+// ValidationIssue locates one reason a plan response was rejected.
+type ValidationIssue struct {
+	// Path is the dotted location in the response, e.g. "steps.0.command".
+	Path    string `json:"path"`
+	Message string `json:"message"`
+	// Fragment is the offending JSON, shortened when large.
+	Fragment string `json:"fragment,omitempty"`
+}
+
+func (i ValidationIssue) String() string {
+	return fmt.Sprintf("%s: %s", i.Path, i.Message)
+}
+
+// PlanValidationError ends a plan request after the model sent too many
+// invalid plan responses in a row.
+type PlanValidationError struct {
+	Failures int
+	// Last describes the final rejected response.
+	Last string
+}
+
+func (e *PlanValidationError) Error() string {
+	return fmt.Sprintf("%d plan responses in a row failed validation; last: %s", e.Failures, e.Last)
+}
+
 type schemaValidationError struct {
-	issues []string
+	issues []ValidationIssue
 }
 
 func (e schemaValidationError) Error() string {
 	if len(e.issues) == 0 {
 		return "plan response failed schema validation"
 	}
-	return strings.Join(e.issues, "; ")
+	parts := make([]string, 0, len(e.issues))
+	for _, issue := range e.issues {
+		parts = append(parts, issue.String())
+	}
+	return strings.Join(parts, "; ")
 }
 
 // validatePlanToolCall ensures the assistant response is valid JSON and
 // satisfies the plan schema before we hydrate a PlanResponse structure.
 // Returning retry=true signals that the helper produced feedback for the
-// assistant and the runtime should request a new plan immediately.
+// assistant and the runtime should request a new plan immediately. Once too
+// many responses in a row were invalid, it returns a *PlanValidationError.
 func (r *Runtime) validatePlanToolCall(toolCall ToolCall) (*PlanResponse, bool, error) {
 	trimmedArgs := strings.TrimSpace(toolCall.Arguments)
 	if trimmedArgs == "" {
@@ -49,7 +87,9 @@ func (r *Runtime) validatePlanToolCall(toolCall ToolCall) (*PlanResponse, bool,
 			Summary:                 "Assistant called the tool without providing arguments.",
 			Details:                 "tool arguments were empty",
 		}
-		r.handlePlanValidationFailure(toolCall, payload, r.buildValidationAutoPrompt(payload))
+		if err := r.handlePlanValidationFailure(toolCall, payload, r.buildValidationAutoPrompt(payload)); err != nil {
+			return nil, false, err
+		}
 		return nil, true, nil
 	}
 
@@ -60,8 +100,11 @@ func (r *Runtime) validatePlanToolCall(toolCall ToolCall) (*PlanResponse, bool,
 			ResponseValidationError: true,
 			Summary:                 "Tool call arguments were not valid JSON.",
 			Details:                 err.Error(),
+			ValidationErrors:        jsonErrorIssues(toolCall.Arguments, err),
+		}
+		if err := r.handlePlanValidationFailure(toolCall, payload, r.buildValidationAutoPrompt(payload)); err != nil {
+			return nil, false, err
 		}
-		r.handlePlanValidationFailure(toolCall, payload, r.buildValidationAutoPrompt(payload))
 		return nil, true, nil
 	}
 
@@ -73,15 +116,18 @@ func (r *Runtime) validatePlanToolCall(toolCall ToolCall) (*PlanResponse, bool,
 				ResponseValidationError: true,
 				Summary:                 "Tool call arguments failed schema validation.",
 				Details:                 schemaErr.Error(),
+				ValidationErrors:        schemaErr.issues,
+			}
+			if err := r.handlePlanValidationFailure(toolCall, payload, r.buildValidationAutoPrompt(payload)); err != nil {
+				return nil, false, err
 			}
-			r.handlePlanValidationFailure(toolCall, payload, r.buildValidationAutoPrompt(payload))
 			return nil, true, nil
 		}
 		// Non-schema validation error (e.g., schema loading error)
 		return nil, false, fmt.Errorf("validatePlanToolCall: schema validation error: %w", err)
 	}
 
-	return &plan, false, nil
+	return &plan, false, r.recordPlanValidation(true, "")
 }
 
 func validatePlanAgainstSchema(raw string) error {
@@ -98,13 +144,63 @@ func validatePlanAgainstSchema(raw string) error {
 		return nil
 	}
 
-	issues := make([]string, 0, len(result.Errors()))
+	issues := make([]ValidationIssue, 0, len(result.Errors()))
 	for _, desc := range result.Errors() {
-		issues = append(issues, desc.String())
+		if len(issues) == maxValidationIssues {
+			break
+		}
+		issues = append(issues, ValidationIssue{
+			Path:     desc.Field(),
+			Message:  desc.Description(),
+			Fragment: validationFragment(desc.Value()),
+		})
 	}
 	return schemaValidationError{issues: issues}
 }
 
+// validationFragment renders the value a schema error points at.
+func validationFragment(value any) string {
+	if value == nil {
+		return ""
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return truncateForPrompt(string(encoded), validationFragmentLimit)
+}
+
+// jsonErrorIssues locates a JSON decoding error in raw: syntax errors by
+// their byte offset, type errors by the field they occurred in.
+func jsonErrorIssues(raw string, err error) []ValidationIssue {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		// Offset counts the offending byte; mark the spot before it.
+		offset := int(min(max(syntaxErr.Offset-1, 0), int64(len(raw))))
+		start := max(0, offset-validationFragmentLimit/2)
+		end := min(len(raw), offset+validationFragmentLimit/2)
+		return []ValidationIssue{{
+			Path:     fmt.Sprintf("offset %d", offset),
+			Message:  syntaxErr.Error(),
+			Fragment: strings.ToValidUTF8(raw[start:offset], "") + "⟨here⟩" + strings.ToValidUTF8(raw[offset:end], ""),
+		}}
+	case errors.As(err, &typeErr):
+		path := typeErr.Field
+		if path == "" {
+			path = "(root)"
+		}
+		offset := int(min(max(typeErr.Offset, 0), int64(len(raw))))
+		return []ValidationIssue{{
+			Path:     path,
+			Message:  fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value),
+			Fragment: truncateForPrompt(strings.ToValidUTF8(raw[max(0, offset-validationFragmentLimit/2):offset], ""), validationFragmentLimit),
+		}}
+	}
+	return nil
+}
+
 func loadPlanSchema() (gojsonschema.JSONLoader, error) {
 	planSchemaLoaderOnce.Do(func() {
 		schemaMap, err := schema.PlanResponseSchema()
@@ -120,12 +216,15 @@ func loadPlanSchema() (gojsonschema.JSONLoader, error) {
 	return planSchemaLoader, nil
 }
 
-func (r *Runtime) handlePlanValidationFailure(toolCall ToolCall, payload PlanObservationPayload, autoPrompt string) {
+func (r *Runtime) handlePlanValidationFailure(toolCall ToolCall, payload PlanObservationPayload, autoPrompt string) error {
 	payload.Details = strings.TrimSpace(payload.Details)
 
 	metadata := map[string]any{
 		"details": payload.Details,
 	}
+	if len(payload.ValidationErrors) > 0 {
+		metadata["validation_errors"] = payload.ValidationErrors
+	}
 	if toolCall.ID != "" {
 		metadata["tool_call_id"] = toolCall.ID
 	}
@@ -176,6 +275,50 @@ func (r *Runtime) handlePlanValidationFailure(toolCall ToolCall, payload PlanObs
 			Timestamp: time.Now(),
 		})
 	}
+	return r.recordPlanValidation(false, message)
+}
+
+// recordPlanValidation tracks consecutive invalid plan responses and
+// returns a *PlanValidationError once they reach the configured limit.
+func (r *Runtime) recordPlanValidation(valid bool, last string) error {
+	if valid {
+		r.validationFailures = 0
+		r.options.Metrics.RecordPlanValidation(true, 0)
+		return nil
+	}
+	r.validationFailures++
+	r.options.Metrics.RecordPlanValidation(false, r.validationFailures)
+	limit := r.options.MaxPlanValidationFailures
+	if limit == 0 {
+		limit = DefaultMaxPlanValidationFailures
+	}
+	if limit < 0 || r.validationFailures < limit {
+		return nil
+	}
+	err := &PlanValidationError{Failures: r.validationFailures, Last: last}
+	// The next prompt starts a fresh run of attempts.
+	r.validationFailures = 0
+	return err
+}
+
+// handlePlanValidationAbort reports that the model kept sending invalid
+// plans and waits for the user instead of retrying forever.
+func (r *Runtime) handlePlanValidationAbort(validationErr *PlanValidationError, pass int) {
+	r.emit(RuntimeEvent{
+		Type:    EventTypeError,
+		Message: fmt.Sprintf("Stopped after %d invalid plan responses in a row. Last problem: %s", validationErr.Failures, validationErr.Last),
+		Level:   StatusLevelError,
+		Metadata: map[string]any{
+			"validation_failures": validationErr.Failures,
+			"details":             validationErr.Last,
+			"pass":                pass,
+		},
+	})
+	if r.options.HandsFree {
+		r.close()
+		return
+	}
+	r.emitRequestInput("The model kept sending invalid plans. Send a prompt to try again.")
 }
 
 func (r *Runtime) buildValidationAutoPrompt(payload PlanObservationPayload) string {
@@ -187,11 +330,26 @@ func (r *Runtime) buildValidationAutoPrompt(payload PlanObservationPayload) stri
 
 	builder := strings.Builder{}
 	builder.WriteString(summary)
-	if details != "" {
+	if len(payload.ValidationErrors) > 0 {
+		builder.WriteString(" Fix these fields:")
+		for _, issue := range payload.ValidationErrors {
+			builder.WriteString("\n- ")
+			builder.WriteString(truncateForPrompt(issue.String(), validationDetailLimit))
+			if issue.Fragment != "" {
+				builder.WriteString(" (got ")
+				builder.WriteString(issue.Fragment)
+				builder.WriteString(")")
+			}
+		}
+		builder.WriteString("\n")
+	} else if details != "" {
 		builder.WriteString(" Details: ")
 		builder.WriteString(details)
+		builder.WriteString(" ")
+	} else {
+		builder.WriteString(" ")
 	}
-	builder.WriteString(" Please call ")
+	builder.WriteString("Please call ")
 	builder.WriteString(schema.ToolName)
 	builder.WriteString(" again with JSON that strictly matches the provided schema.")
 	return builder.String()
@@ -0,0 +1,96 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/asynkron/goagent/internal/core/schema"
+)
+
+func TestValidatePlanAgainstSchemaReportsPaths(t *testing.T) {
+	t.Parallel()
+
+	err := validatePlanAgainstSchema(`{"message":"hi","reasoning":[],"plan":[{"id":"a","title":"t","status":"pending","command":{"shell":"bash","run":42}}],"requireHumanInput":false}`)
+	var schemaErr schemaValidationError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("expected a schema validation error, got %v", err)
+	}
+	found := false
+	for _, issue := range schemaErr.issues {
+		if strings.HasPrefix(issue.Path, "plan.0.command") && issue.Fragment != "" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected an issue under plan.0.command with a fragment, got %+v", schemaErr.issues)
+	}
+}
+
+func TestJSONErrorIssuesPointAtTheSyntaxError(t *testing.T) {
+	t.Parallel()
+
+	raw := `{"message":"hi",,"plan":[]}`
+	var plan PlanResponse
+	issues := jsonErrorIssues(raw, json.Unmarshal([]byte(raw), &plan))
+	if len(issues) != 1 || issues[0].Path != "offset 16" || !strings.Contains(issues[0].Fragment, `"hi",⟨here⟩,`) {
+		t.Fatalf("unexpected issues %+v", issues)
+	}
+}
+
+func TestRequestPlanStopsAfterRepeatedValidationFailures(t *testing.T) {
+	t.Parallel()
+
+	metrics := NewInMemoryMetrics()
+	provider := &scriptedProvider{calls: []ToolCall{
+		{ID: "call-1", Name: schema.ToolName, Arguments: `{"message":`},
+		{ID: "call-2", Name: schema.ToolName, Arguments: `{"message":"done"}`},
+		{ID: "call-3", Name: schema.ToolName, Arguments: `{"message":"done","reasoning":[],"plan":[],"requireHumanInput":false}`},
+	}}
+	rt := &Runtime{
+		options: RuntimeOptions{
+			Logger:                    &NoOpLogger{},
+			Metrics:                   metrics,
+			MaxPlanValidationFailures: 2,
+		},
+		outputs:   make(chan RuntimeEvent, 32),
+		closed:    make(chan struct{}),
+		plan:      NewPlanManager(),
+		client:    provider,
+		history:   []ChatMessage{{Role: RoleSystem, Content: "system"}},
+		agentName: "main",
+	}
+
+	_, _, err := rt.requestPlan(context.Background())
+	var validationErr *PlanValidationError
+	if !errors.As(err, &validationErr) || validationErr.Failures != 2 {
+		t.Fatalf("expected a PlanValidationError after 2 failures, got %v", err)
+	}
+	if provider.requests != 2 {
+		t.Fatalf("expected no request after the limit, got %d", provider.requests)
+	}
+	if got := metrics.GetSnapshot().PlanValidation; got.Failures != 2 || got.MaxConsecutive != 2 {
+		t.Fatalf("unexpected validation metrics %+v", got)
+	}
+
+	history := rt.historySnapshot()
+	var feedback string
+	for _, msg := range history {
+		if msg.Role == RoleTool && msg.ToolCallID == "call-2" {
+			feedback = msg.Content
+		}
+	}
+	if !strings.Contains(feedback, `"validation_errors"`) || !strings.Contains(feedback, `"path"`) {
+		t.Fatalf("expected located validation errors in the feedback, got %s", feedback)
+	}
+
+	plan, _, err := rt.requestPlan(context.Background())
+	if err != nil || plan == nil {
+		t.Fatalf("expected the next request to start a fresh run, got %v", err)
+	}
+	if got := metrics.GetSnapshot().PlanValidation; got.Consecutive != 0 {
+		t.Fatalf("expected consecutive failures to reset, got %+v", got)
+	}
+}
@@ -0,0 +1,25 @@
+package runtime
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/asynkron/goagent/internal/gitignore"
+)
+
+// WorkspaceFiles lists regular files below root as forward-slash paths
+// relative to it, skipping .git, hidden entries, and anything excluded by
+// .gitignore files, the same way the search command walks the tree. At most
+// limit files are returned; truncated reports whether more exist.
+func WorkspaceFiles(ctx context.Context, root string, limit int) (files []string, truncated bool, err error) {
+	err = gitignore.WalkFiles(ctx, root, nil, func(rel string, _ fs.DirEntry) error {
+		if limit > 0 && len(files) >= limit {
+			truncated = true
+			return filepath.SkipAll
+		}
+		files = append(files, rel)
+		return nil
+	})
+	return files, truncated, err
+}
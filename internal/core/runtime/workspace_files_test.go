@@ -0,0 +1,47 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestWorkspaceFilesRespectsGitignore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		".gitignore":      "build/\n*.log\n",
+		"main.go":         "package main\n",
+		"pkg/util.go":     "package pkg\n",
+		"pkg/.gitignore":  "gen_*.go\n",
+		"pkg/gen_a.go":    "package pkg\n",
+		"build/out.go":    "package build\n",
+		"debug.log":       "log\n",
+		".hidden/file.go": "package hidden\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, truncated, err := WorkspaceFiles(context.Background(), dir, 0)
+	if err != nil {
+		t.Fatalf("WorkspaceFiles: %v", err)
+	}
+	slices.Sort(files)
+	if want := []string{"main.go", "pkg/util.go"}; !slices.Equal(files, want) || truncated {
+		t.Fatalf("files = %v (truncated %v), want %v", files, truncated, want)
+	}
+
+	files, truncated, err = WorkspaceFiles(context.Background(), dir, 1)
+	if err != nil || len(files) != 1 || !truncated {
+		t.Fatalf("limited walk = %v, %v, %v", files, truncated, err)
+	}
+}
@@ -0,0 +1,196 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultWatchInterval is how often the workspace is rescanned.
+	defaultWatchInterval = 2 * time.Second
+	// maxWatchedFiles caps the files compared on each scan.
+	maxWatchedFiles = 20000
+	// maxReportedChanges caps the paths listed in one observation.
+	maxReportedChanges = 20
+)
+
+// WorkspaceChange is a file created, modified, or deleted outside the agent.
+type WorkspaceChange struct {
+	Path string `json:"path"`
+	// Status is "A", "M", or "D", as for FileChange.
+	Status string `json:"status"`
+}
+
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// workspaceWatcher polls the workspace for changes made outside the agent,
+// such as edits in the user's IDE. It walks the tree like WorkspaceFiles and
+// compares sizes and modification times, so it needs no platform notifier.
+// Scans are skipped while steps run and the baseline is retaken afterwards,
+// so the agent's own writes are never reported.
+type workspaceWatcher struct {
+	root string
+
+	mu         sync.Mutex
+	baseline   map[string]fileStamp
+	generation int
+	pending    map[string]string
+}
+
+func newWorkspaceWatcher(root string) *workspaceWatcher {
+	return &workspaceWatcher{root: root, pending: make(map[string]string)}
+}
+
+func (w *workspaceWatcher) scan(ctx context.Context) (map[string]fileStamp, error) {
+	files, _, err := WorkspaceFiles(ctx, w.root, maxWatchedFiles)
+	if err != nil {
+		return nil, err
+	}
+	stamps := make(map[string]fileStamp, len(files))
+	for _, rel := range files {
+		info, err := os.Stat(filepath.Join(w.root, filepath.FromSlash(rel)))
+		if err != nil {
+			continue
+		}
+		stamps[rel] = fileStamp{size: info.Size(), modTime: info.ModTime()}
+	}
+	return stamps, nil
+}
+
+// rebaseline records the current tree without reporting anything.
+func (w *workspaceWatcher) rebaseline(ctx context.Context) {
+	stamps, err := w.scan(ctx)
+	if err != nil {
+		return
+	}
+	w.mu.Lock()
+	w.baseline = stamps
+	w.generation++
+	w.mu.Unlock()
+}
+
+// poll compares the tree against the baseline and queues the differences.
+// A rebaseline during the scan discards the result.
+func (w *workspaceWatcher) poll(ctx context.Context) []WorkspaceChange {
+	w.mu.Lock()
+	generation := w.generation
+	w.mu.Unlock()
+
+	stamps, err := w.scan(ctx)
+	if err != nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if generation != w.generation || w.baseline == nil {
+		return nil
+	}
+	var changes []WorkspaceChange
+	for path, stamp := range stamps {
+		old, ok := w.baseline[path]
+		switch {
+		case !ok:
+			changes = append(changes, WorkspaceChange{Path: path, Status: "A"})
+		case old != stamp:
+			changes = append(changes, WorkspaceChange{Path: path, Status: "M"})
+		}
+	}
+	for path := range w.baseline {
+		if _, ok := stamps[path]; !ok {
+			changes = append(changes, WorkspaceChange{Path: path, Status: "D"})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	w.baseline = stamps
+	for _, change := range changes {
+		w.pending[change.Path] = change.Status
+	}
+	return changes
+}
+
+// drain returns and clears the changes not yet shown to the model.
+func (w *workspaceWatcher) drain() []WorkspaceChange {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	changes := make([]WorkspaceChange, 0, len(w.pending))
+	for path, status := range w.pending {
+		changes = append(changes, WorkspaceChange{Path: path, Status: status})
+	}
+	clear(w.pending)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// watchWorkspace polls until ctx ends, emitting EventTypeWorkspaceChange for
+// changes found while no plan steps run.
+func (r *Runtime) watchWorkspace(ctx context.Context) {
+	interval := r.options.WatchInterval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	r.watcher.rebaseline(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.closed:
+			return
+		case <-ticker.C:
+		}
+		if r.stepsRunning.Load() {
+			continue
+		}
+		changes := r.watcher.poll(ctx)
+		if len(changes) == 0 {
+			continue
+		}
+		r.emit(RuntimeEvent{
+			Type:     EventTypeWorkspaceChange,
+			Message:  fmt.Sprintf("%d file(s) changed outside the agent: %s", len(changes), describeWorkspaceChanges(changes)),
+			Level:    StatusLevelInfo,
+			Metadata: map[string]any{"changes": changes},
+		})
+	}
+}
+
+// injectWorkspaceChanges tells the model which files changed outside the
+// agent since the last request, so it re-reads them instead of relying on
+// what it saw earlier.
+func (r *Runtime) injectWorkspaceChanges() {
+	if r.watcher == nil {
+		return
+	}
+	changes := r.watcher.drain()
+	if len(changes) == 0 {
+		return
+	}
+	r.appendHistory(ChatMessage{
+		Role:      RoleUser,
+		Content:   fmt.Sprintf("[workspace] Files changed outside the agent since you last looked: %s. Re-read them before relying on or editing their earlier contents.", describeWorkspaceChanges(changes)),
+		Timestamp: time.Now(),
+	})
+}
+
+func describeWorkspaceChanges(changes []WorkspaceChange) string {
+	parts := make([]string, 0, min(len(changes), maxReportedChanges)+1)
+	for i, change := range changes {
+		if i == maxReportedChanges {
+			parts = append(parts, fmt.Sprintf("and %d more", len(changes)-i))
+			break
+		}
+		parts = append(parts, change.Status+" "+change.Path)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWorkspaceWatcherReportsOutsideChanges(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	write("keep.go", "package a\n")
+	write("edit.go", "package a\n")
+	write("gone.go", "package a\n")
+	write(".gitignore", "*.log\n")
+
+	rt := &Runtime{
+		options:   RuntimeOptions{Logger: &NoOpLogger{}, Metrics: &NoOpMetrics{}},
+		outputs:   make(chan RuntimeEvent, 8),
+		closed:    make(chan struct{}),
+		history:   []ChatMessage{{Role: RoleSystem, Content: "system"}},
+		agentName: "main",
+		watcher:   newWorkspaceWatcher(root),
+	}
+	ctx := context.Background()
+	rt.watcher.rebaseline(ctx)
+
+	write("edit.go", "package a\n\nfunc B() {}\n")
+	write("new.go", "package a\n")
+	write("debug.log", "ignored\n")
+	if err := os.Remove(filepath.Join(root, "gone.go")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	changes := rt.watcher.poll(ctx)
+	got := describeWorkspaceChanges(changes)
+	if got != "M edit.go, D gone.go, A new.go" {
+		t.Fatalf("unexpected changes: %s", got)
+	}
+	if again := rt.watcher.poll(ctx); len(again) != 0 {
+		t.Fatalf("expected changes to be reported once, got %v", again)
+	}
+
+	rt.injectWorkspaceChanges()
+	history := rt.historySnapshot()
+	if len(history) != 2 || history[1].Role != RoleUser || !strings.Contains(history[1].Content, "M edit.go, D gone.go, A new.go") {
+		t.Fatalf("expected a workspace note for the model, got %+v", history)
+	}
+	rt.injectWorkspaceChanges()
+	if len(rt.historySnapshot()) != 2 {
+		t.Fatalf("expected pending changes to be drained")
+	}
+
+	// Writes followed by a rebaseline, as after the agent's own steps, are
+	// not reported.
+	write("keep.go", "package a // agent edit\n")
+	rt.watcher.rebaseline(ctx)
+	if changes := rt.watcher.poll(ctx); len(changes) != 0 {
+		t.Fatalf("expected the agent's writes to be ignored, got %v", changes)
+	}
+}
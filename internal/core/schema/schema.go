@@ -58,6 +58,10 @@ const planResponseSchemaJSON = `{
             "default": [],
             "description": "IDs this task has to wait for before it can be executed (dependencies)."
           },
+          "concurrencyGroup": {
+            "type": "string",
+            "description": "Optional group name. Steps sharing a group never run at the same time; use it for heavy steps such as builds and test suites."
+          },
           "command": {
             "type": "object",
             "additionalProperties": false,
@@ -113,6 +117,34 @@ const planResponseSchemaJSON = `{
                 "minimum": 1,
                 "default": 16384,
                 "description": "Maximum number of bytes to include from stdout/stderr (defaults to ~200 lines at 16 KiB)."
+              },
+              "interactive": {
+                "type": "boolean",
+                "default": false,
+                "description": "Set true when the command reads from stdin (e.g. npm init, password prompts) so the human can type answers while it runs."
+              },
+              "env": {
+                "type": "object",
+                "additionalProperties": { "type": "string" },
+                "description": "Extra environment variables for this command. The host decides which of its own variables are inherited, so do not assume credentials are present."
+              },
+              "retries": {
+                "type": "integer",
+                "minimum": 0,
+                "maximum": 5,
+                "default": 0,
+                "description": "Extra attempts for commands that can fail transiently (network fetches, flaky services). Leave at 0 for deterministic commands."
+              },
+              "retry_backoff_sec": {
+                "type": "integer",
+                "minimum": 0,
+                "default": 1,
+                "description": "Seconds to wait before the first retry; the delay doubles for each further retry."
+              },
+              "background": {
+                "type": "boolean",
+                "default": false,
+                "description": "Set true for long-running processes such as dev servers. The step returns after a short startup window while the process keeps running as a background job."
               }
             }
           }
@@ -0,0 +1,136 @@
+// Package gitignore matches paths against .gitignore files collected while
+// walking a directory tree.
+package gitignore
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is one pattern from a .gitignore file.
+type ignoreRule struct {
+	// base is the directory of the .gitignore, relative to the walk root
+	// using forward slashes ("" for the root).
+	base    string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Matcher implements the commonly used subset of gitignore
+// semantics: comments, negation, directory-only patterns, anchoring, and
+// "*", "?", "**" wildcards. Rules from nested .gitignore files apply below
+// their directory and are checked after their parents'.
+type Matcher struct {
+	rules []ignoreRule
+}
+
+// Load reads dir/.gitignore if it exists. rel is dir relative to the walk
+// root. Call it for each directory as the walk enters it.
+func (m *Matcher) Load(dir, rel string) {
+	file, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	base := filepath.ToSlash(rel)
+	if base == "." {
+		base = ""
+	}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, `\`)
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		// Patterns containing a slash are relative to the .gitignore's
+		// directory; others match at any depth below it.
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		expr := GlobExpr(line)
+		if !anchored {
+			expr = `(?:.*/)?` + expr
+		}
+		re, err := regexp.Compile(`^` + expr + `$`)
+		if err != nil {
+			continue
+		}
+		rule.re = re
+		m.rules = append(m.rules, rule)
+	}
+}
+
+// Ignored reports whether rel (relative to the walk root, forward slashes)
+// is excluded. The last matching rule wins.
+func (m *Matcher) Ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		target := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			target = strings.TrimPrefix(rel, rule.base+"/")
+		}
+		if rule.re.MatchString(target) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// GlobExpr converts a path glob into a regular expression fragment where
+// "*" and "?" stop at "/", "**/" matches any number of directories, and a
+// trailing "**" matches everything below.
+func GlobExpr(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '*' && strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString(`(?:.*/)?`)
+			i += 2
+		case c == '*' && strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(`.*`)
+			i++
+		case c == '*':
+			b.WriteString(`[^/]*`)
+		case c == '?':
+			b.WriteString(`[^/]`)
+		case c == '[':
+			if end := strings.IndexByte(glob[i+1:], ']'); end >= 0 {
+				class := glob[i+1 : i+1+end]
+				if strings.HasPrefix(class, "!") {
+					class = "^" + class[1:]
+				}
+				b.WriteString("[" + class + "]")
+				i += end + 1
+				continue
+			}
+			b.WriteString(`\[`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
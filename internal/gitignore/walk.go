@@ -0,0 +1,46 @@
+package gitignore
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// WalkFiles calls fn for every regular file below root with its path
+// relative to root using forward slashes. Hidden entries (including .git),
+// directories for which skipDir returns true, and anything excluded by
+// .gitignore files are skipped; skipDir may be nil. fn returns
+// filepath.SkipAll to stop the walk early.
+func WalkFiles(ctx context.Context, root string, skipDir func(name string) bool, fn func(rel string, entry fs.DirEntry) error) error {
+	ignore := &Matcher{}
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		rel, relErr := filepath.Rel(root, path)
+		if relErr != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if entry.IsDir() {
+			if path != root {
+				if strings.HasPrefix(entry.Name(), ".") || (skipDir != nil && skipDir(entry.Name())) || ignore.Ignored(rel, true) {
+					return filepath.SkipDir
+				}
+			}
+			ignore.Load(path, rel)
+			return nil
+		}
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") || ignore.Ignored(rel, false) {
+			return nil
+		}
+		return fn(rel, entry)
+	})
+}
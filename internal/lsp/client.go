@@ -0,0 +1,386 @@
+// Package lsp is a minimal Language Server Protocol client used to collect
+// diagnostics for files the agent edits.
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// settleDelay is how long Diagnostics keeps listening after the first
+// report for a file, since servers often publish a quick syntactic pass
+// followed by the type checked one.
+const settleDelay = 300 * time.Millisecond
+
+// Client talks to one language server process over stdio.
+type Client struct {
+	server Server
+	root   string
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	nextID   int
+	pending  map[int]chan response
+	versions map[string]int
+	// published holds the latest diagnostics per document URI; updates is
+	// signalled whenever one arrives.
+	published map[string][]Diagnostic
+	updates   chan string
+	done      chan struct{}
+	err       error
+}
+
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *responseError   `json:"error,omitempty"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type response struct {
+	result json.RawMessage
+	err    error
+}
+
+// Start launches the server and performs the initialize handshake with root
+// as the workspace folder.
+func Start(ctx context.Context, server Server, root string) (*Client, error) {
+	if len(server.Command) == 0 {
+		return nil, fmt.Errorf("lsp: server %q has no command", server.Name)
+	}
+	cmd := exec.Command(server.Command[0], server.Command[1:]...)
+	cmd.Dir = root
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("lsp: start %s: %w", server.Name, err)
+	}
+
+	c := &Client{
+		server:    server,
+		root:      root,
+		cmd:       cmd,
+		stdin:     stdin,
+		pending:   make(map[int]chan response),
+		versions:  make(map[string]int),
+		published: make(map[string][]Diagnostic),
+		updates:   make(chan string, 64),
+		done:      make(chan struct{}),
+	}
+	go c.readLoop(bufio.NewReader(stdout))
+
+	rootURI := fileURI(root)
+	params := map[string]any{
+		"processId": os.Getpid(),
+		"rootUri":   rootURI,
+		"workspaceFolders": []map[string]string{
+			{"uri": rootURI, "name": filepath.Base(root)},
+		},
+		"capabilities": map[string]any{
+			"textDocument": map[string]any{
+				"publishDiagnostics": map[string]any{"versionSupport": true},
+				"synchronization":    map[string]any{"didSave": true},
+			},
+			"workspace": map[string]any{"configuration": true, "workspaceFolders": true},
+		},
+	}
+	if _, err := c.call(ctx, "initialize", params); err != nil {
+		_ = c.Close()
+		return nil, fmt.Errorf("lsp: initialize %s: %w", server.Name, err)
+	}
+	if err := c.notify("initialized", map[string]any{}); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Diagnostics sends the current content of path to the server and returns
+// the diagnostics it publishes for it, waiting at most wait. A file that
+// produces no report within wait is assumed clean.
+func (c *Client) Diagnostics(ctx context.Context, path string, wait time.Duration) ([]Diagnostic, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	uri := fileURI(path)
+
+	c.mu.Lock()
+	version := c.versions[uri] + 1
+	c.versions[uri] = version
+	delete(c.published, uri)
+	c.mu.Unlock()
+	c.drainUpdates()
+
+	if version == 1 {
+		err = c.notify("textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{"uri": uri, "languageId": c.server.languageID(path), "version": version, "text": string(text)},
+		})
+	} else {
+		err = c.notify("textDocument/didChange", map[string]any{
+			"textDocument":   map[string]any{"uri": uri, "version": version},
+			"contentChanges": []map[string]any{{"text": string(text)}},
+		})
+	}
+	if err == nil {
+		err = c.notify("textDocument/didSave", map[string]any{"textDocument": map[string]any{"uri": uri}})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	var settle <-chan time.Time
+	for {
+		select {
+		case updated := <-c.updates:
+			if updated == uri && settle == nil {
+				settle = time.After(settleDelay)
+			}
+		case <-settle:
+			return c.diagnosticsFor(uri, path), nil
+		case <-deadline.C:
+			return c.diagnosticsFor(uri, path), nil
+		case <-c.done:
+			return nil, c.exitErr()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Close shuts the server down, killing it when it does not exit promptly.
+func (c *Client) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := c.call(ctx, "shutdown", nil); err == nil {
+		_ = c.notify("exit", nil)
+	}
+	_ = c.stdin.Close()
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		_ = c.cmd.Process.Kill()
+		<-c.done
+	}
+	_ = c.cmd.Wait()
+	return nil
+}
+
+func (c *Client) diagnosticsFor(uri, path string) []Diagnostic {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	diagnostics := append([]Diagnostic(nil), c.published[uri]...)
+	for i := range diagnostics {
+		diagnostics[i].Path = path
+	}
+	return diagnostics
+}
+
+func (c *Client) drainUpdates() {
+	for {
+		select {
+		case <-c.updates:
+		default:
+			return
+		}
+	}
+}
+
+func (c *Client) exitErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	return fmt.Errorf("lsp: %s exited", c.server.Name)
+}
+
+func (c *Client) call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	reply := make(chan response, 1)
+	c.pending[id] = reply
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	raw := json.RawMessage(strconv.Itoa(id))
+	if err := c.write(message{ID: &raw, Method: method, Params: encodeParams(params)}); err != nil {
+		return nil, err
+	}
+	select {
+	case resp := <-reply:
+		return resp.result, resp.err
+	case <-c.done:
+		return nil, c.exitErr()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Client) notify(method string, params any) error {
+	return c.write(message{Method: method, Params: encodeParams(params)})
+}
+
+func (c *Client) write(msg message) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := fmt.Fprintf(c.stdin, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.stdin.Write(body)
+	return err
+}
+
+func (c *Client) readLoop(r *bufio.Reader) {
+	defer close(c.done)
+	headers := textproto.NewReader(r)
+	for {
+		header, err := headers.ReadMIMEHeader()
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		length, err := strconv.Atoi(header.Get("Content-Length"))
+		if err != nil {
+			c.fail(fmt.Errorf("lsp: bad Content-Length: %w", err))
+			return
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r, body); err != nil {
+			c.fail(err)
+			return
+		}
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			continue
+		}
+		c.dispatch(msg)
+	}
+}
+
+func (c *Client) dispatch(msg message) {
+	switch {
+	case msg.ID != nil && msg.Method != "":
+		c.answerServerRequest(msg)
+	case msg.ID != nil:
+		id, err := strconv.Atoi(string(*msg.ID))
+		if err != nil {
+			return
+		}
+		c.mu.Lock()
+		reply, ok := c.pending[id]
+		c.mu.Unlock()
+		if !ok {
+			return
+		}
+		resp := response{result: msg.Result}
+		if msg.Error != nil {
+			resp.err = fmt.Errorf("lsp: %s (code %d)", msg.Error.Message, msg.Error.Code)
+		}
+		reply <- resp
+	case msg.Method == "textDocument/publishDiagnostics":
+		var params publishDiagnosticsParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return
+		}
+		diagnostics := make([]Diagnostic, 0, len(params.Diagnostics))
+		for _, d := range params.Diagnostics {
+			diagnostics = append(diagnostics, d.toDiagnostic())
+		}
+		c.mu.Lock()
+		stale := params.Version != nil && *params.Version < c.versions[params.URI]
+		if !stale {
+			c.published[params.URI] = diagnostics
+		}
+		c.mu.Unlock()
+		if stale {
+			return
+		}
+		select {
+		case c.updates <- params.URI:
+		default:
+		}
+	}
+}
+
+// answerServerRequest replies to requests the server sends the client, such
+// as workspace/configuration, so servers that wait for them keep going.
+func (c *Client) answerServerRequest(msg message) {
+	var result any
+	if msg.Method == "workspace/configuration" {
+		var params struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		_ = json.Unmarshal(msg.Params, &params)
+		result = make([]any, len(params.Items))
+	}
+	encoded, _ := json.Marshal(result)
+	_ = c.write(message{ID: msg.ID, Result: encoded})
+}
+
+func (c *Client) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil && !errors.Is(err, io.EOF) {
+		c.err = fmt.Errorf("lsp: %s: %w", c.server.Name, err)
+	}
+}
+
+func encodeParams(params any) json.RawMessage {
+	if params == nil {
+		return nil
+	}
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return nil
+	}
+	return encoded
+}
+
+func fileURI(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+}
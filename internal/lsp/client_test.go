@@ -0,0 +1,159 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The test binary doubles as a fake language server when this variable is
+// set. It reports one error for every line containing "BROKEN".
+const fakeServerEnv = "GOAGENT_FAKE_LSP"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeServerEnv) == "1" {
+		runFakeServer(os.Stdin, os.Stdout)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func runFakeServer(in io.Reader, out io.Writer) {
+	reader := bufio.NewReader(in)
+	headers := textproto.NewReader(reader)
+	send := func(msg map[string]any) {
+		msg["jsonrpc"] = "2.0"
+		body, _ := json.Marshal(msg)
+		fmt.Fprintf(out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	for {
+		header, err := headers.ReadMIMEHeader()
+		if err != nil {
+			return
+		}
+		length, _ := strconv.Atoi(header.Get("Content-Length"))
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			return
+		}
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params struct {
+				TextDocument struct {
+					URI     string `json:"uri"`
+					Version int    `json:"version"`
+					Text    string `json:"text"`
+				} `json:"textDocument"`
+				ContentChanges []struct {
+					Text string `json:"text"`
+				} `json:"contentChanges"`
+			} `json:"params"`
+		}
+		_ = json.Unmarshal(body, &msg)
+		switch msg.Method {
+		case "initialize":
+			// Ask for configuration first, as real servers do.
+			send(map[string]any{"id": 99, "method": "workspace/configuration", "params": map[string]any{"items": []any{map[string]any{}}}})
+			send(map[string]any{"id": msg.ID, "result": map[string]any{"capabilities": map[string]any{}}})
+		case "shutdown":
+			send(map[string]any{"id": msg.ID, "result": nil})
+		case "exit":
+			return
+		case "textDocument/didOpen", "textDocument/didChange":
+			text := msg.Params.TextDocument.Text
+			if len(msg.Params.ContentChanges) > 0 {
+				text = msg.Params.ContentChanges[0].Text
+			}
+			diagnostics := []any{}
+			for i, line := range strings.Split(text, "\n") {
+				if col := strings.Index(line, "BROKEN"); col >= 0 {
+					diagnostics = append(diagnostics, map[string]any{
+						"range":    map[string]any{"start": map[string]any{"line": i, "character": col}},
+						"severity": 1,
+						"source":   "fake",
+						"message":  "undefined: BROKEN",
+					})
+				}
+			}
+			send(map[string]any{"method": "textDocument/publishDiagnostics", "params": map[string]any{
+				"uri": msg.Params.TextDocument.URI, "version": msg.Params.TextDocument.Version, "diagnostics": diagnostics,
+			}})
+		}
+	}
+}
+
+func fakeServer(t *testing.T) Server {
+	t.Helper()
+	t.Setenv(fakeServerEnv, "1")
+	return Server{Name: "fake", Command: []string{os.Args[0]}, Extensions: []string{".go"}}
+}
+
+func TestManagerReportsDiagnosticsAfterEdits(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(path, []byte("package main\n\nvar x = BROKEN\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.md"), []byte("BROKEN"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := NewManager(dir, []Server{fakeServer(t)}, 2*time.Second)
+	defer manager.Close()
+
+	diagnostics, errs := manager.Diagnostics(context.Background(), []string{"main.go", "notes.md"})
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	want := Diagnostic{Path: "main.go", Line: 3, Column: 9, Severity: "error", Source: "fake", Message: "undefined: BROKEN"}
+	if len(diagnostics) != 1 || diagnostics[0] != want {
+		t.Fatalf("unexpected diagnostics %+v", diagnostics)
+	}
+	if got := FormatDiagnostics(diagnostics, 10); got != "main.go:3:9: error: undefined: BROKEN (fake)" {
+		t.Fatalf("unexpected format %q", got)
+	}
+
+	// The second query reuses the server and sends the edited content.
+	if err := os.WriteFile(path, []byte("package main\n\nvar x = 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	diagnostics, errs = manager.Diagnostics(context.Background(), []string{path})
+	if len(errs) != 0 || len(diagnostics) != 0 {
+		t.Fatalf("expected a clean file, got %+v %v", diagnostics, errs)
+	}
+}
+
+func TestManagerReportsServersThatFailToStart(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	manager := NewManager(dir, []Server{{Name: "missing", Command: []string{"goagent-no-such-server"}, Extensions: []string{".go"}}}, time.Second)
+	defer manager.Close()
+
+	for range 2 {
+		if _, errs := manager.Diagnostics(context.Background(), []string{"main.go"}); len(errs) != 1 {
+			t.Fatalf("expected one start error, got %v", errs)
+		}
+	}
+}
+
+func TestResolveServers(t *testing.T) {
+	servers, err := ResolveServers([]string{"gopls", " Pyright "})
+	if err != nil || len(servers) != 2 || servers[0].Name != "gopls" || servers[1].Name != "pyright" {
+		t.Fatalf("unexpected servers %+v %v", servers, err)
+	}
+	if _, err := ResolveServers([]string{"clangd"}); err == nil {
+		t.Fatal("expected unknown server error")
+	}
+}
@@ -0,0 +1,287 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultWait bounds how long Manager.Diagnostics waits for a server to
+// report on each file.
+const DefaultWait = 5 * time.Second
+
+// Server configures a language server started on demand.
+type Server struct {
+	// Name identifies the server in errors and logs, e.g. "gopls".
+	Name string
+	// Command is the program and arguments that speak LSP over stdio.
+	Command []string
+	// Extensions lists the file extensions the server handles, with the dot.
+	Extensions []string
+	// LanguageID is the LSP language identifier sent for opened files;
+	// empty derives it from the extension.
+	LanguageID string
+}
+
+func (s Server) handles(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, candidate := range s.Extensions {
+		if strings.ToLower(candidate) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Server) languageID(path string) string {
+	if s.LanguageID != "" {
+		return s.LanguageID
+	}
+	switch ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), "."); ext {
+	case "ts":
+		return "typescript"
+	case "tsx":
+		return "typescriptreact"
+	case "js", "mjs", "cjs":
+		return "javascript"
+	case "jsx":
+		return "javascriptreact"
+	case "py":
+		return "python"
+	default:
+		return ext
+	}
+}
+
+// DefaultServers lists the servers known by name: gopls, typescript
+// (typescript-language-server) and pyright.
+func DefaultServers() []Server {
+	return []Server{
+		{Name: "gopls", Command: []string{"gopls"}, Extensions: []string{".go"}, LanguageID: "go"},
+		{Name: "typescript", Command: []string{"typescript-language-server", "--stdio"}, Extensions: []string{".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs"}},
+		{Name: "pyright", Command: []string{"pyright-langserver", "--stdio"}, Extensions: []string{".py"}, LanguageID: "python"},
+	}
+}
+
+// ResolveServers maps names to DefaultServers entries. "auto" selects every
+// default server whose command is on PATH.
+func ResolveServers(names []string) ([]Server, error) {
+	defaults := DefaultServers()
+	var servers []Server
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if name == "auto" {
+			for _, server := range defaults {
+				if _, err := exec.LookPath(server.Command[0]); err == nil {
+					servers = append(servers, server)
+				}
+			}
+			continue
+		}
+		found := false
+		for _, server := range defaults {
+			if server.Name == name {
+				servers = append(servers, server)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown language server %q (known: gopls, typescript, pyright, auto)", name)
+		}
+	}
+	return servers, nil
+}
+
+// Diagnostic is a problem a language server reported for a file.
+type Diagnostic struct {
+	Path string `json:"path"`
+	// Line and Column are 1-based.
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	Source   string `json:"source,omitempty"`
+	Message  string `json:"message"`
+}
+
+type publishDiagnosticsParams struct {
+	URI         string          `json:"uri"`
+	Version     *int            `json:"version,omitempty"`
+	Diagnostics []lspDiagnostic `json:"diagnostics"`
+}
+
+type lspDiagnostic struct {
+	Range struct {
+		Start struct {
+			Line      int `json:"line"`
+			Character int `json:"character"`
+		} `json:"start"`
+	} `json:"range"`
+	Severity int    `json:"severity"`
+	Source   string `json:"source"`
+	Message  string `json:"message"`
+}
+
+func (d lspDiagnostic) toDiagnostic() Diagnostic {
+	severity := "error"
+	switch d.Severity {
+	case 2:
+		severity = "warning"
+	case 3:
+		severity = "info"
+	case 4:
+		severity = "hint"
+	}
+	return Diagnostic{
+		Line:     d.Range.Start.Line + 1,
+		Column:   d.Range.Start.Character + 1,
+		Severity: severity,
+		Source:   d.Source,
+		Message:  d.Message,
+	}
+}
+
+// Manager starts configured servers lazily, one per server, and keeps them
+// running for later queries.
+type Manager struct {
+	root    string
+	servers []Server
+	wait    time.Duration
+
+	mu      sync.Mutex
+	clients map[string]*Client
+	// failed remembers servers that could not start so they are not
+	// retried on every edit.
+	failed map[string]error
+}
+
+// NewManager returns a Manager for the workspace at root. wait bounds each
+// file's query; zero means DefaultWait.
+func NewManager(root string, servers []Server, wait time.Duration) *Manager {
+	if wait <= 0 {
+		wait = DefaultWait
+	}
+	return &Manager{
+		root:    root,
+		servers: servers,
+		wait:    wait,
+		clients: make(map[string]*Client),
+		failed:  make(map[string]error),
+	}
+}
+
+// Handles reports whether a configured server handles path.
+func (m *Manager) Handles(path string) bool {
+	for _, server := range m.servers {
+		if server.handles(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Diagnostics queries the servers handling paths and returns their
+// diagnostics sorted by path and position. Files no server handles are
+// skipped; errors from servers that fail are returned alongside the
+// diagnostics that were collected.
+func (m *Manager) Diagnostics(ctx context.Context, paths []string) ([]Diagnostic, []error) {
+	var (
+		diagnostics []Diagnostic
+		errs        []error
+	)
+	for _, path := range paths {
+		abs := path
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(m.root, path)
+		}
+		for _, server := range m.servers {
+			if !server.handles(abs) {
+				continue
+			}
+			found, err := m.query(ctx, server, abs)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			for i := range found {
+				found[i].Path = path
+			}
+			diagnostics = append(diagnostics, found...)
+		}
+	}
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return diagnostics, errs
+}
+
+// query serializes requests per manager; clients track one file at a time.
+func (m *Manager) query(ctx context.Context, server Server, path string) ([]Diagnostic, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err, ok := m.failed[server.Name]; ok {
+		return nil, err
+	}
+	client, ok := m.clients[server.Name]
+	if !ok {
+		startCtx, cancel := context.WithTimeout(ctx, m.wait)
+		started, err := Start(startCtx, server, m.root)
+		cancel()
+		if err != nil {
+			m.failed[server.Name] = err
+			return nil, err
+		}
+		client = started
+		m.clients[server.Name] = client
+	}
+	diagnostics, err := client.Diagnostics(ctx, path, m.wait)
+	if err != nil && ctx.Err() == nil {
+		// The server died; start a fresh one next time.
+		delete(m.clients, server.Name)
+		_ = client.Close()
+	}
+	return diagnostics, err
+}
+
+// Close stops every running server.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, client := range m.clients {
+		_ = client.Close()
+		delete(m.clients, name)
+	}
+}
+
+// FormatDiagnostics renders diagnostics as "path:line:col: severity: message"
+// lines, showing at most limit of them.
+func FormatDiagnostics(diagnostics []Diagnostic, limit int) string {
+	var b strings.Builder
+	for i, d := range diagnostics {
+		if limit > 0 && i == limit {
+			fmt.Fprintf(&b, "... %d more\n", len(diagnostics)-limit)
+			break
+		}
+		fmt.Fprintf(&b, "%s:%d:%d: %s: %s", d.Path, d.Line, d.Column, d.Severity, d.Message)
+		if d.Source != "" {
+			fmt.Fprintf(&b, " (%s)", d.Source)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
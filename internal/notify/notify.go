@@ -0,0 +1,163 @@
+// Package notify alerts the user outside the terminal window, through the
+// platform's notifier, a terminal notification escape sequence (OSC 777) or
+// the terminal bell.
+package notify
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+// Modes accepted by ParseMode.
+const (
+	ModeOff = "off"
+	// ModeAuto uses the platform notifier when one is installed and falls
+	// back to OSC 777 followed by a bell.
+	ModeAuto   = "auto"
+	ModeSystem = "system"
+	ModeOSC    = "osc"
+	ModeBell   = "bell"
+)
+
+// maxBodyRunes keeps notification bodies to a glanceable length.
+const maxBodyRunes = 200
+
+// ParseMode normalizes a mode name; "" and "false" mean ModeOff and "true"
+// or "on" mean ModeAuto.
+func ParseMode(value string) (string, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(value)); mode {
+	case "", "false", ModeOff:
+		return ModeOff, nil
+	case "true", "on":
+		return ModeAuto, nil
+	case ModeAuto, ModeSystem, ModeOSC, ModeBell:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown notification mode %q (want auto, system, osc, bell or off)", value)
+}
+
+// Notifier sends notifications in one mode. The zero value is off.
+type Notifier struct {
+	mode string
+	out  io.Writer
+	// command builds the platform notifier invocation; nil when none is
+	// installed.
+	command func(title, body string) *exec.Cmd
+}
+
+// New returns a Notifier for mode that writes escape sequences to out.
+// ModeSystem fails when no platform notifier is installed.
+func New(mode string, out io.Writer) (*Notifier, error) {
+	mode, err := ParseMode(mode)
+	if err != nil {
+		return nil, err
+	}
+	n := &Notifier{mode: mode, out: out}
+	if mode == ModeAuto || mode == ModeSystem {
+		n.command = platformCommand(runtime.GOOS, exec.LookPath)
+		if n.command == nil && mode == ModeSystem {
+			return nil, fmt.Errorf("no desktop notifier found (install notify-send, or use --notify osc or bell)")
+		}
+	}
+	return n, nil
+}
+
+// Mode returns the notifier's mode.
+func (n *Notifier) Mode() string {
+	if n == nil || n.mode == "" {
+		return ModeOff
+	}
+	return n.mode
+}
+
+// Enabled reports whether Notify sends anything.
+func (n *Notifier) Enabled() bool {
+	return n.Mode() != ModeOff
+}
+
+// Notify sends a notification. The platform notifier runs in the
+// background; Notify does not wait for it.
+func (n *Notifier) Notify(title, body string) error {
+	if !n.Enabled() {
+		return nil
+	}
+	title, body = clean(title), clean(body)
+	if r := []rune(body); len(r) > maxBodyRunes {
+		body = string(r[:maxBodyRunes-1]) + "…"
+	}
+	if n.command != nil {
+		cmd := n.command(title, body)
+		if err := cmd.Start(); err == nil {
+			go func() { _ = cmd.Wait() }()
+			return nil
+		} else if n.mode == ModeSystem {
+			return err
+		}
+	}
+	var sequence string
+	switch n.mode {
+	case ModeBell:
+		sequence = "\a"
+	case ModeOSC:
+		sequence = osc777(title, body)
+	default:
+		sequence = osc777(title, body) + "\a"
+	}
+	_, err := io.WriteString(n.out, sequence)
+	return err
+}
+
+// osc777 is the notification escape sequence understood by rxvt, foot,
+// WezTerm, Ghostty and others. Terminals without support ignore it.
+func osc777(title, body string) string {
+	// Semicolons separate the fields.
+	title = strings.ReplaceAll(title, ";", ",")
+	return "\x1b]777;notify;" + title + ";" + body + "\x1b\\"
+}
+
+// clean folds whitespace and drops control characters, which could end the
+// escape sequence early.
+func clean(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r):
+			return ' '
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// platformCommand returns the notifier invocation for goos, or nil when
+// none is available.
+func platformCommand(goos string, lookPath func(string) (string, error)) func(title, body string) *exec.Cmd {
+	switch goos {
+	case "darwin":
+		if _, err := lookPath("osascript"); err != nil {
+			return nil
+		}
+		return func(title, body string) *exec.Cmd {
+			script := fmt.Sprintf("display notification %s with title %s", appleScriptString(body), appleScriptString(title))
+			return exec.Command("osascript", "-e", script)
+		}
+	case "windows":
+		return nil
+	default:
+		if _, err := lookPath("notify-send"); err != nil {
+			return nil
+		}
+		return func(title, body string) *exec.Cmd {
+			return exec.Command("notify-send", "--app-name=goagent", "--", title, body)
+		}
+	}
+}
+
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	t.Parallel()
+
+	for input, want := range map[string]string{"": ModeOff, "off": ModeOff, "on": ModeAuto, " Bell ": ModeBell, "osc": ModeOSC} {
+		got, err := ParseMode(input)
+		if err != nil || got != want {
+			t.Fatalf("ParseMode(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := ParseMode("loud"); err == nil {
+		t.Fatalf("expected an error for an unknown mode")
+	}
+}
+
+func TestNotifyWritesEscapeSequences(t *testing.T) {
+	t.Parallel()
+
+	var out strings.Builder
+	n, err := New(ModeOSC, &out)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	if err := n.Notify("goagent; done", "needs\x1b your\ninput"); err != nil {
+		t.Fatalf("Notify returned error: %v", err)
+	}
+	if want := "\x1b]777;notify;goagent, done;needs your input\x1b\\"; out.String() != want {
+		t.Fatalf("unexpected sequence %q", out.String())
+	}
+
+	out.Reset()
+	n, _ = New(ModeBell, &out)
+	_ = n.Notify("goagent", "done")
+	if out.String() != "\a" {
+		t.Fatalf("expected a bell, got %q", out.String())
+	}
+
+	out.Reset()
+	var off *Notifier
+	if err := off.Notify("goagent", "done"); err != nil || off.Enabled() {
+		t.Fatalf("a nil notifier must be off")
+	}
+}
+
+func TestPlatformCommand(t *testing.T) {
+	t.Parallel()
+
+	found := func(string) (string, error) { return "/usr/bin/tool", nil }
+	missing := func(string) (string, error) { return "", errors.New("not found") }
+
+	if platformCommand("linux", missing) != nil {
+		t.Fatalf("expected no notifier without notify-send")
+	}
+	cmd := platformCommand("linux", found)("goagent", "done")
+	if got := strings.Join(cmd.Args, " "); got != "notify-send --app-name=goagent -- goagent done" {
+		t.Fatalf("unexpected command %q", got)
+	}
+	cmd = platformCommand("darwin", found)("goagent", `say "hi"`)
+	if got := cmd.Args[len(cmd.Args)-1]; got != `display notification "say \"hi\"" with title "goagent"` {
+		t.Fatalf("unexpected script %q", got)
+	}
+}
@@ -0,0 +1,313 @@
+// Package repomap renders a compact outline of a repository: its
+// directories, notable files, and the exported symbols of Go and
+// TypeScript/JavaScript sources, trimmed to fit a token budget.
+package repomap
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/asynkron/goagent/internal/gitignore"
+)
+
+const (
+	// DefaultMaxTokens bounds the rendered map when Options.MaxTokens is 0.
+	DefaultMaxTokens = 1500
+	// bytesPerToken approximates the tokenizer for budgeting.
+	bytesPerToken = 4
+
+	defaultMaxFiles = 5000
+	// maxSourceBytes skips symbol extraction for larger (often generated)
+	// files.
+	maxSourceBytes = 512 * 1024
+)
+
+// keyFiles are listed by name even when the budget only allows directory
+// summaries.
+var keyFiles = map[string]bool{
+	"README.md": true, "README": true, "AGENTS.md": true, "CLAUDE.md": true,
+	"go.mod": true, "package.json": true, "tsconfig.json": true, "Cargo.toml": true,
+	"pyproject.toml": true, "requirements.txt": true, "pom.xml": true, "build.gradle": true,
+	"Makefile": true, "Taskfile.yml": true, "justfile": true, "Dockerfile": true,
+	"docker-compose.yml": true, "compose.yaml": true,
+}
+
+var skippedDirs = map[string]bool{"node_modules": true, "vendor": true, "dist": true, "build": true, "target": true}
+
+// exportPattern matches top-level exports in TypeScript and JavaScript.
+var exportPattern = regexp.MustCompile(`^export\s+(?:default\s+)?(?:declare\s+)?(?:abstract\s+)?(?:async\s+)?(?:function\*?|class|interface|type|enum|const|let|var|namespace)\s+([A-Za-z_$][\w$]*)`)
+
+// Options configures a Generator.
+type Options struct {
+	// MaxTokens bounds the rendered map (default 1500), estimated at four
+	// bytes per token.
+	MaxTokens int
+	// MaxFiles stops the walk after that many files (default 5000).
+	MaxFiles int
+}
+
+// Generator renders the map of a directory tree. It remembers the symbols
+// of each file by size and modification time, so regenerating after edits
+// only parses the files that changed.
+type Generator struct {
+	root string
+	opts Options
+
+	mu    sync.Mutex
+	cache map[string]cachedFile
+}
+
+type cachedFile struct {
+	size    int64
+	modTime int64
+	symbols []string
+}
+
+// New returns a Generator for the tree at root.
+func New(root string, opts Options) *Generator {
+	if opts.MaxTokens <= 0 {
+		opts.MaxTokens = DefaultMaxTokens
+	}
+	if opts.MaxFiles <= 0 {
+		opts.MaxFiles = defaultMaxFiles
+	}
+	return &Generator{root: root, opts: opts, cache: make(map[string]cachedFile)}
+}
+
+type dirEntry struct {
+	path  string
+	files []fileEntry
+}
+
+type fileEntry struct {
+	name    string
+	symbols []string
+}
+
+// Generate walks the tree and renders the map. It returns "" for an empty
+// tree.
+func (g *Generator) Generate(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	dirs := map[string]*dirEntry{}
+	seen := map[string]bool{}
+	total, truncated := 0, false
+	err := gitignore.WalkFiles(ctx, g.root, func(name string) bool { return skippedDirs[name] }, func(rel string, entry fs.DirEntry) error {
+		if total >= g.opts.MaxFiles {
+			truncated = true
+			return filepath.SkipAll
+		}
+		total++
+		seen[rel] = true
+		dir, name := path.Split(rel)
+		dir = strings.TrimSuffix(dir, "/")
+		if dir == "" {
+			dir = "."
+		}
+		d := dirs[dir]
+		if d == nil {
+			d = &dirEntry{path: dir}
+			dirs[dir] = d
+		}
+		d.files = append(d.files, fileEntry{name: name, symbols: g.symbols(rel, entry)})
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	for rel := range g.cache {
+		if !seen[rel] {
+			delete(g.cache, rel)
+		}
+	}
+	if total == 0 {
+		return "", nil
+	}
+
+	ordered := make([]*dirEntry, 0, len(dirs))
+	for _, d := range dirs {
+		ordered = append(ordered, d)
+	}
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].path < ordered[j].path })
+
+	budget := g.opts.MaxTokens * bytesPerToken
+	var rendered string
+	for _, level := range []detail{
+		{symbols: 12, names: 12},
+		{symbols: 5, names: 6},
+		{symbols: 0, names: 6},
+		{symbols: 0, names: 0},
+	} {
+		rendered = render(ordered, level)
+		if len(rendered) <= budget {
+			break
+		}
+	}
+	if len(rendered) > budget {
+		rendered = rendered[:strings.LastIndexByte(rendered[:budget], '\n')+1] + "...\n"
+	}
+	if truncated {
+		rendered += fmt.Sprintf("(stopped after %d files)\n", g.opts.MaxFiles)
+	}
+	return strings.TrimRight(rendered, "\n"), nil
+}
+
+// detail limits the symbols listed per file and the file names listed per
+// directory; zero names lists key files only and zero symbols drops symbol
+// lines.
+type detail struct {
+	symbols int
+	names   int
+}
+
+func render(dirs []*dirEntry, level detail) string {
+	var b strings.Builder
+	for _, d := range dirs {
+		var names []string
+		var withSymbols []fileEntry
+		for _, f := range d.files {
+			switch {
+			case level.symbols > 0 && len(f.symbols) > 0:
+				withSymbols = append(withSymbols, f)
+			case keyFiles[f.name] || len(names) < level.names:
+				names = append(names, f.name)
+			}
+		}
+		label := d.path + "/"
+		if d.path == "." {
+			label = "./"
+		}
+		fmt.Fprintf(&b, "%s (%s)", label, countFiles(len(d.files)))
+		if len(names) > 0 {
+			listed := len(names) + len(withSymbols)
+			b.WriteString(": " + strings.Join(names, ", "))
+			if more := len(d.files) - listed; more > 0 {
+				fmt.Fprintf(&b, ", +%d more", more)
+			}
+		}
+		b.WriteString("\n")
+		for _, f := range withSymbols {
+			symbols := f.symbols
+			suffix := ""
+			if len(symbols) > level.symbols {
+				suffix = fmt.Sprintf(", +%d more", len(symbols)-level.symbols)
+				symbols = symbols[:level.symbols]
+			}
+			fmt.Fprintf(&b, "  %s: %s%s\n", f.name, strings.Join(symbols, ", "), suffix)
+		}
+	}
+	return b.String()
+}
+
+func countFiles(n int) string {
+	if n == 1 {
+		return "1 file"
+	}
+	return fmt.Sprintf("%d files", n)
+}
+
+// symbols returns the exported symbols of rel, parsing it only when it
+// changed since the last call.
+func (g *Generator) symbols(rel string, entry fs.DirEntry) []string {
+	ext := path.Ext(rel)
+	isGo := ext == ".go" && !strings.HasSuffix(rel, "_test.go")
+	isScript := (ext == ".ts" || ext == ".tsx" || ext == ".js" || ext == ".jsx" || ext == ".mjs") && !strings.Contains(rel, ".test.") && !strings.Contains(rel, ".spec.")
+	if !isGo && !isScript {
+		return nil
+	}
+	info, err := entry.Info()
+	if err != nil || info.Size() > maxSourceBytes {
+		return nil
+	}
+	if cached, ok := g.cache[rel]; ok && cached.size == info.Size() && cached.modTime == info.ModTime().UnixNano() {
+		return cached.symbols
+	}
+	data, err := os.ReadFile(filepath.Join(g.root, filepath.FromSlash(rel)))
+	if err != nil {
+		return nil
+	}
+	var symbols []string
+	if isGo {
+		symbols = goSymbols(data)
+	} else {
+		symbols = scriptSymbols(string(data))
+	}
+	g.cache[rel] = cachedFile{size: info.Size(), modTime: info.ModTime().UnixNano(), symbols: symbols}
+	return symbols
+}
+
+// goSymbols lists exported types and functions; methods are shown as
+// Type.Method.
+func goSymbols(src []byte) []string {
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+	var symbols []string
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if !decl.Name.IsExported() {
+				continue
+			}
+			name := decl.Name.Name
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				receiver := receiverName(decl.Recv.List[0].Type)
+				if receiver == "" || !ast.IsExported(receiver) {
+					continue
+				}
+				name = receiver + "." + name
+			}
+			symbols = append(symbols, name)
+		case *ast.GenDecl:
+			if decl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok && ts.Name.IsExported() {
+					symbols = append(symbols, ts.Name.Name)
+				}
+			}
+		}
+	}
+	return symbols
+}
+
+func receiverName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+func scriptSymbols(src string) []string {
+	var symbols []string
+	for _, line := range strings.Split(src, "\n") {
+		if m := exportPattern.FindStringSubmatch(line); m != nil {
+			symbols = append(symbols, m[1])
+		}
+	}
+	return symbols
+}
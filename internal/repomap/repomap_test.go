@@ -0,0 +1,97 @@
+package repomap
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenerateListsDirectoriesAndSymbols(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "go.mod", "module example.com/app\n")
+	writeFile(t, root, "README.md", "# App\n")
+	writeFile(t, root, ".gitignore", "gen/\n")
+	writeFile(t, root, "gen/out.go", "package gen\n\nfunc Generated() {}\n")
+	writeFile(t, root, "internal/store/store.go", `package store
+
+type Store struct{}
+
+type cache struct{}
+
+func New() *Store { return nil }
+
+func (s *Store) Get(key string) string { return "" }
+
+func (c *cache) Put() {}
+
+func helper() {}
+`)
+	writeFile(t, root, "internal/store/store_test.go", "package store\n\nfunc TestGet() {}\n")
+	writeFile(t, root, "web/src/api.ts", "export interface User {}\nexport async function fetchUser() {}\nexport default class Client {}\nconst local = 1\n")
+	writeFile(t, root, "node_modules/dep/index.js", "export function dep() {}\n")
+
+	got, err := New(root, Options{}).Generate(context.Background())
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	want := strings.Join([]string{
+		"./ (2 files): README.md, go.mod",
+		"internal/store/ (2 files): store_test.go",
+		"  store.go: Store, New, Store.Get",
+		"web/src/ (1 file)",
+		"  api.ts: User, fetchUser, Client",
+	}, "\n")
+	if got != want {
+		t.Fatalf("unexpected map:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestGenerateFitsTheBudget(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "README.md", "# App\n")
+	for i := range 40 {
+		var src strings.Builder
+		src.WriteString("package pkg\n\n")
+		for j := range 20 {
+			src.WriteString("func Exported" + string(rune('A'+j)) + "() {}\n")
+		}
+		writeFile(t, root, filepath.Join("pkg"+string(rune('a'+i%26))+string(rune('a'+i/26)), "file.go"), src.String())
+	}
+
+	got, err := New(root, Options{MaxTokens: 200}).Generate(context.Background())
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if len(got) > 200*bytesPerToken {
+		t.Fatalf("map exceeds the budget: %d bytes", len(got))
+	}
+	if !strings.HasPrefix(got, "./ (1 file): README.md\npkgaa/ (1 file)") || strings.Contains(got, "ExportedA") {
+		t.Fatalf("expected directory summaries without symbols, got:\n%s", got)
+	}
+}
+
+func TestGenerateReparsesChangedFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "a.go", "package a\n\nfunc Old() {}\n")
+	g := New(root, Options{})
+	if got, _ := g.Generate(context.Background()); !strings.Contains(got, "a.go: Old") {
+		t.Fatalf("unexpected map %q", got)
+	}
+	writeFile(t, root, "a.go", "package a\n\nfunc Renamed() {}\n")
+	if got, _ := g.Generate(context.Background()); !strings.Contains(got, "a.go: Renamed") {
+		t.Fatalf("expected the edited symbols, got %q", got)
+	}
+}
@@ -0,0 +1,115 @@
+package semindex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultEmbeddingModel is used when OpenAIEmbedder.Model is empty.
+const DefaultEmbeddingModel = "text-embedding-3-small"
+
+// defaultEmbeddingBatch bounds the inputs sent in one embeddings request.
+const defaultEmbeddingBatch = 64
+
+// Embedder turns texts into embedding vectors, one per text and in order.
+type Embedder interface {
+	// Model identifies the embedding model. Indexes built with another
+	// model are discarded.
+	Model() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// OpenAIEmbedder calls an OpenAI compatible /embeddings endpoint. Local
+// servers such as Ollama, llama.cpp and LM Studio expose the same API, so
+// BaseURL can point at them; APIKey is then usually empty.
+type OpenAIEmbedder struct {
+	// BaseURL defaults to https://api.openai.com/v1.
+	BaseURL string
+	APIKey  string
+	// EmbeddingModel defaults to DefaultEmbeddingModel.
+	EmbeddingModel string
+	// Client defaults to an http.Client with a 60s timeout.
+	Client *http.Client
+}
+
+// Model implements Embedder.
+func (e *OpenAIEmbedder) Model() string {
+	if e.EmbeddingModel == "" {
+		return DefaultEmbeddingModel
+	}
+	return e.EmbeddingModel
+}
+
+// Embed implements Embedder, splitting texts into batches.
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += defaultEmbeddingBatch {
+		batch, err := e.embedBatch(ctx, texts[start:min(start+defaultEmbeddingBatch, len(texts))])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (e *OpenAIEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	baseURL := strings.TrimRight(e.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	body, err := json.Marshal(map[string]any{"model": e.Model(), "input": texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.APIKey)
+	}
+	client := e.Client
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embeddings: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("embeddings: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embeddings: status %d: %s", resp.StatusCode, bytes.TrimSpace(data[:min(len(data), 300)]))
+	}
+	var parsed struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("embeddings: decode response: %w", err)
+	}
+	if len(parsed.Data) != len(texts) {
+		return nil, fmt.Errorf("embeddings: got %d vectors for %d inputs", len(parsed.Data), len(texts))
+	}
+	vectors := make([][]float32, len(texts))
+	for _, item := range parsed.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("embeddings: vector index %d out of range", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return vectors, nil
+}
@@ -0,0 +1,392 @@
+// Package semindex maintains an embedding index of workspace files for
+// semantic code search. Files are split into overlapping line windows whose
+// embeddings are stored under the index directory; Update re-embeds only
+// files that changed since the last run.
+package semindex
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/asynkron/goagent/internal/gitignore"
+)
+
+const (
+	// indexVersion changes when the stored format or chunking changes.
+	indexVersion = 1
+	indexFile    = "index.gob"
+
+	defaultChunkLines   = 60
+	defaultChunkOverlap = 10
+	defaultMaxFileBytes = 256 * 1024
+	defaultMaxFiles     = 10000
+	// maxChunkBytes keeps minified lines from exceeding the embedding
+	// model's input limit.
+	maxChunkBytes = 6000
+)
+
+// skippedDirs hold dependencies and build output even when they are not
+// listed in .gitignore.
+var skippedDirs = map[string]bool{"node_modules": true, "vendor": true}
+
+// Options configures an Index.
+type Options struct {
+	// Root is the workspace to index.
+	Root string
+	// Dir stores the index; it defaults to Root/.goagent/index.
+	Dir      string
+	Embedder Embedder
+	// ChunkLines and ChunkOverlap size the line windows; defaults 60 and 10.
+	ChunkLines   int
+	ChunkOverlap int
+	// MaxFileBytes skips larger files (default 256 KiB) and MaxFiles stops
+	// indexing after that many files (default 10000).
+	MaxFileBytes int64
+	MaxFiles     int
+}
+
+// Match is a chunk returned by Search.
+type Match struct {
+	Path string `json:"path"`
+	// StartLine and EndLine are 1-based and inclusive.
+	StartLine int     `json:"start_line"`
+	EndLine   int     `json:"end_line"`
+	Score     float64 `json:"score"`
+	Text      string  `json:"text"`
+}
+
+// Stats summarizes an Update.
+type Stats struct {
+	Files    int `json:"files"`
+	Chunks   int `json:"chunks"`
+	Embedded int `json:"embedded_files"`
+	Removed  int `json:"removed_files"`
+	// Truncated reports that MaxFiles stopped the walk.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+type storedIndex struct {
+	Version int
+	Model   string
+	Files   map[string]*fileEntry
+}
+
+type fileEntry struct {
+	Size    int64
+	ModTime int64
+	Hash    string
+	Chunks  []chunk
+}
+
+type chunk struct {
+	Start, End int
+	// Vector is normalized to unit length so scores are dot products.
+	Vector []float32
+}
+
+// Index is safe for concurrent use; Update and Search serialize.
+type Index struct {
+	opts Options
+
+	mu     sync.Mutex
+	loaded bool
+	data   storedIndex
+}
+
+// New returns an Index for opts. The stored index is read on first use.
+func New(opts Options) (*Index, error) {
+	if opts.Embedder == nil {
+		return nil, errors.New("semindex: an embedder is required")
+	}
+	if opts.Root == "" {
+		return nil, errors.New("semindex: a root directory is required")
+	}
+	if opts.Dir == "" {
+		opts.Dir = filepath.Join(opts.Root, ".goagent", "index")
+	}
+	if opts.ChunkLines <= 0 {
+		opts.ChunkLines = defaultChunkLines
+	}
+	if opts.ChunkOverlap < 0 || opts.ChunkOverlap >= opts.ChunkLines {
+		opts.ChunkOverlap = min(defaultChunkOverlap, opts.ChunkLines/2)
+	}
+	if opts.MaxFileBytes <= 0 {
+		opts.MaxFileBytes = defaultMaxFileBytes
+	}
+	if opts.MaxFiles <= 0 {
+		opts.MaxFiles = defaultMaxFiles
+	}
+	return &Index{opts: opts}, nil
+}
+
+// Update walks the workspace, embeds new and changed files, drops deleted
+// ones, and saves the index when anything changed.
+func (ix *Index) Update(ctx context.Context) (Stats, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.load()
+
+	paths, truncated, err := ix.walk(ctx)
+	if err != nil {
+		return Stats{}, err
+	}
+	stats := Stats{Truncated: truncated}
+	seen := make(map[string]bool, len(paths))
+	changed := false
+	for _, rel := range paths {
+		seen[rel] = true
+		embedded, touched, err := ix.updateFile(ctx, rel)
+		changed = changed || touched
+		if err != nil {
+			// Keep the files embedded so far for the next attempt.
+			if changed {
+				_ = ix.save()
+			}
+			return stats, err
+		}
+		if embedded {
+			stats.Embedded++
+		}
+	}
+	for rel := range ix.data.Files {
+		if !seen[rel] {
+			delete(ix.data.Files, rel)
+			stats.Removed++
+			changed = true
+		}
+	}
+	for _, entry := range ix.data.Files {
+		stats.Files++
+		stats.Chunks += len(entry.Chunks)
+	}
+	if changed {
+		if err := ix.save(); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// Search embeds query and returns the limit chunks closest to it. When
+// prefix is set only paths below it are considered. Call Update first to
+// pick up changed files.
+func (ix *Index) Search(ctx context.Context, query, prefix string, limit int) ([]Match, error) {
+	vectors, err := ix.opts.Embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, errors.New("semindex: no embedding for the query")
+	}
+	queryVector := normalize(vectors[0])
+	prefix = strings.Trim(filepath.ToSlash(prefix), "/")
+
+	ix.mu.Lock()
+	ix.load()
+	var matches []Match
+	for rel, entry := range ix.data.Files {
+		if prefix != "" && rel != prefix && !strings.HasPrefix(rel, prefix+"/") {
+			continue
+		}
+		for _, c := range entry.Chunks {
+			matches = append(matches, Match{Path: rel, StartLine: c.Start, EndLine: c.End, Score: dot(queryVector, c.Vector)})
+		}
+	}
+	ix.mu.Unlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		if matches[i].Path != matches[j].Path {
+			return matches[i].Path < matches[j].Path
+		}
+		return matches[i].StartLine < matches[j].StartLine
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	for i := range matches {
+		matches[i].Score = math.Round(matches[i].Score*1000) / 1000
+		data, err := os.ReadFile(filepath.Join(ix.opts.Root, filepath.FromSlash(matches[i].Path)))
+		if err == nil {
+			lines := splitLines(string(data))
+			matches[i].Text = strings.Join(lines[min(matches[i].StartLine-1, len(lines)):min(matches[i].EndLine, len(lines))], "\n")
+		}
+	}
+	return matches, nil
+}
+
+// updateFile re-embeds rel when its size, modification time and content
+// hash no longer match the stored entry. It reports whether it embedded the
+// file and whether the stored entry changed.
+func (ix *Index) updateFile(ctx context.Context, rel string) (embedded, touched bool, err error) {
+	path := filepath.Join(ix.opts.Root, filepath.FromSlash(rel))
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, false, nil
+	}
+	entry := ix.data.Files[rel]
+	if entry != nil && entry.Size == info.Size() && entry.ModTime == info.ModTime().UnixNano() {
+		return false, false, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, false, nil
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if entry != nil && entry.Hash == hash {
+		entry.Size, entry.ModTime = info.Size(), info.ModTime().UnixNano()
+		return false, true, nil
+	}
+
+	// Binary files are recorded without chunks so they are not read again
+	// until they change.
+	var chunks []chunk
+	if bytes.IndexByte(data[:min(len(data), 8000)], 0) < 0 {
+		var texts []string
+		chunks, texts = ix.chunk(rel, string(data))
+		if len(chunks) > 0 {
+			vectors, err := ix.opts.Embedder.Embed(ctx, texts)
+			if err != nil {
+				return false, false, err
+			}
+			if len(vectors) != len(chunks) {
+				return false, false, fmt.Errorf("semindex: got %d embeddings for %d chunks of %s", len(vectors), len(chunks), rel)
+			}
+			for i := range chunks {
+				chunks[i].Vector = normalize(vectors[i])
+			}
+			embedded = true
+		}
+	}
+	ix.data.Files[rel] = &fileEntry{Size: info.Size(), ModTime: info.ModTime().UnixNano(), Hash: hash, Chunks: chunks}
+	return embedded, true, nil
+}
+
+// chunk splits content into overlapping line windows. Each embedded text
+// starts with the path so file names contribute to the match.
+func (ix *Index) chunk(rel, content string) ([]chunk, []string) {
+	lines := splitLines(content)
+	var (
+		chunks []chunk
+		texts  []string
+	)
+	step := ix.opts.ChunkLines - ix.opts.ChunkOverlap
+	for start := 0; start < len(lines); start += step {
+		end := min(start+ix.opts.ChunkLines, len(lines))
+		text := strings.Join(lines[start:end], "\n")
+		if strings.TrimSpace(text) != "" {
+			if len(text) > maxChunkBytes {
+				cut := maxChunkBytes
+				for cut > 0 && !utf8.RuneStart(text[cut]) {
+					cut--
+				}
+				text = text[:cut]
+			}
+			chunks = append(chunks, chunk{Start: start + 1, End: end})
+			texts = append(texts, rel+"\n"+text)
+		}
+		if end == len(lines) {
+			break
+		}
+	}
+	return chunks, texts
+}
+
+func (ix *Index) walk(ctx context.Context) (paths []string, truncated bool, err error) {
+	err = gitignore.WalkFiles(ctx, ix.opts.Root, func(name string) bool { return skippedDirs[name] }, func(rel string, entry fs.DirEntry) error {
+		if info, err := entry.Info(); err != nil || info.Size() == 0 || info.Size() > ix.opts.MaxFileBytes {
+			return nil
+		}
+		if len(paths) >= ix.opts.MaxFiles {
+			truncated = true
+			return filepath.SkipAll
+		}
+		paths = append(paths, rel)
+		return nil
+	})
+	return paths, truncated, err
+}
+
+// load reads the stored index once. A missing, unreadable or outdated
+// index, or one built with another model, starts empty.
+func (ix *Index) load() {
+	if ix.loaded {
+		return
+	}
+	ix.loaded = true
+	ix.data = storedIndex{Version: indexVersion, Model: ix.opts.Embedder.Model(), Files: map[string]*fileEntry{}}
+	file, err := os.Open(filepath.Join(ix.opts.Dir, indexFile))
+	if err != nil {
+		return
+	}
+	defer func() { _ = file.Close() }()
+	var stored storedIndex
+	if err := gob.NewDecoder(file).Decode(&stored); err != nil {
+		return
+	}
+	if stored.Version == indexVersion && stored.Model == ix.data.Model && stored.Files != nil {
+		ix.data = stored
+	}
+}
+
+func (ix *Index) save() error {
+	if err := os.MkdirAll(ix.opts.Dir, 0o755); err != nil {
+		return fmt.Errorf("semindex: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(ix.data); err != nil {
+		return fmt.Errorf("semindex: encode index: %w", err)
+	}
+	path := filepath.Join(ix.opts.Dir, indexFile)
+	if err := os.WriteFile(path+".tmp", buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("semindex: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("semindex: %w", err)
+	}
+	return nil
+}
+
+func splitLines(content string) []string {
+	return strings.Split(strings.TrimRight(strings.ReplaceAll(content, "\r\n", "\n"), "\n"), "\n")
+}
+
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := float32(math.Sqrt(sum))
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = x / norm
+	}
+	return out
+}
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range min(len(a), len(b)) {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
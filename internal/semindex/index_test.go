@@ -0,0 +1,159 @@
+package semindex
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// wordEmbedder hashes each word into one of 64 dimensions, so texts sharing
+// words score high.
+type wordEmbedder struct {
+	model string
+	calls int
+}
+
+func (e *wordEmbedder) Model() string { return e.model }
+
+func (e *wordEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	e.calls++
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector := make([]float32, 64)
+		for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return r < 'a' || r > 'z' }) {
+			h := fnv.New32a()
+			_, _ = h.Write([]byte(word))
+			vector[h.Sum32()%64]++
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+func writeFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndexUpdatesIncrementallyAndSearches(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "auth/login.go", "package auth\n\n// Login checks the password hash and issues a session token.\nfunc Login() {}\n")
+	writeFile(t, root, "billing/invoice.go", "package billing\n\n// Invoice totals line items and applies tax.\nfunc Invoice() {}\n")
+	writeFile(t, root, "build/out.go", "package build // password token session\n")
+	writeFile(t, root, ".gitignore", "build/\n")
+	writeFile(t, root, "logo.png", "\x89PNG\x00\x00")
+
+	embedder := &wordEmbedder{model: "words"}
+	ix, err := New(Options{Root: root, Embedder: embedder})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := ix.Update(context.Background())
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if stats.Files != 3 || stats.Embedded != 2 || stats.Chunks != 2 {
+		t.Fatalf("unexpected first update %+v", stats)
+	}
+
+	matches, err := ix.Search(context.Background(), "where is the session token issued after password check", "", 1)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Path != "auth/login.go" || matches[0].StartLine != 1 || matches[0].EndLine != 4 || !strings.Contains(matches[0].Text, "func Login") {
+		t.Fatalf("unexpected matches %+v", matches)
+	}
+	if matches, _ := ix.Search(context.Background(), "session token", "billing", 5); len(matches) != 1 || matches[0].Path != "billing/invoice.go" {
+		t.Fatalf("expected the prefix to restrict matches, got %+v", matches)
+	}
+
+	// Unchanged files are not embedded again, even by a fresh Index that
+	// loads the stored one.
+	calls := embedder.calls
+	reopened, _ := New(Options{Root: root, Embedder: embedder})
+	if stats, err := reopened.Update(context.Background()); err != nil || stats.Embedded != 0 || stats.Files != 3 {
+		t.Fatalf("expected nothing to embed, got %+v %v", stats, err)
+	}
+	if embedder.calls != calls {
+		t.Fatalf("expected no embedding calls, got %d", embedder.calls-calls)
+	}
+
+	writeFile(t, root, "billing/invoice.go", "package billing\n\n// Refund returns money.\nfunc Refund() {}\n")
+	if err := os.Remove(filepath.Join(root, "auth", "login.go")); err != nil {
+		t.Fatal(err)
+	}
+	if stats, err := reopened.Update(context.Background()); err != nil || stats.Embedded != 1 || stats.Removed != 1 {
+		t.Fatalf("expected one re-embedded and one removed file, got %+v %v", stats, err)
+	}
+
+	// Another model invalidates the stored vectors.
+	other, _ := New(Options{Root: root, Embedder: &wordEmbedder{model: "other"}})
+	if stats, err := other.Update(context.Background()); err != nil || stats.Embedded != 1 {
+		t.Fatalf("expected a rebuild for a new model, got %+v %v", stats, err)
+	}
+}
+
+func TestIndexChunksLongFiles(t *testing.T) {
+	root := t.TempDir()
+	lines := make([]string, 25)
+	for i := range lines {
+		lines[i] = "line"
+	}
+	writeFile(t, root, "long.txt", strings.Join(lines, "\n"))
+	ix, _ := New(Options{Root: root, Embedder: &wordEmbedder{model: "words"}, ChunkLines: 10, ChunkOverlap: 2})
+	if _, err := ix.Update(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var got [][2]int
+	for _, c := range ix.data.Files["long.txt"].Chunks {
+		got = append(got, [2]int{c.Start, c.End})
+	}
+	want := [][2]int{{1, 10}, {9, 18}, {17, 25}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("unexpected chunks %v", got)
+	}
+}
+
+func TestOpenAIEmbedder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer key" || json.NewDecoder(r.Body).Decode(&req) != nil || req.Model != "embed-small" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		// Answer out of order; the index field decides the position.
+		data := make([]map[string]any, len(req.Input))
+		for i := range req.Input {
+			data[len(req.Input)-1-i] = map[string]any{"index": i, "embedding": []float32{float32(len(req.Input[i]))}}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	defer server.Close()
+
+	embedder := &OpenAIEmbedder{BaseURL: server.URL + "/v1/", APIKey: "key", EmbeddingModel: "embed-small"}
+	vectors, err := embedder.Embed(context.Background(), []string{"a", "bbb"})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if len(vectors) != 2 || vectors[0][0] != 1 || vectors[1][0] != 3 {
+		t.Fatalf("unexpected vectors %v", vectors)
+	}
+	if _, err := (&OpenAIEmbedder{BaseURL: server.URL + "/v1", EmbeddingModel: "embed-small"}).Embed(context.Background(), []string{"a"}); err == nil || !strings.Contains(err.Error(), "status 400") {
+		t.Fatalf("expected a status error, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	runtimepkg "github.com/asynkron/goagent/internal/core/runtime"
+)
+
+// pendingAttachment is a file to send with the next prompt. Files attached
+// from the @ picker are only sent while the prompt still mentions them.
+type pendingAttachment struct {
+	path    string
+	mention bool
+}
+
+func attachLabel() string {
+	return lipgloss.NewStyle().Foreground(theme.Accent).Render("[attach] ")
+}
+
+// attachFile queues path (relative to the workspace or absolute) for the
+// next prompt after checking that it can be sent.
+func (m *model) attachFile(path string, mention bool) {
+	attachment, err := runtimepkg.LoadAttachment(m.resolveAttachmentPath(path))
+	if err != nil {
+		m.appendLine(lipgloss.NewStyle().Foreground(theme.Error).Render("[attach] ") + err.Error() + "\n")
+		return
+	}
+	for _, pending := range m.attachments {
+		if pending.path == path {
+			return
+		}
+	}
+	m.attachments = append(m.attachments, pendingAttachment{path: path, mention: mention})
+	m.appendLine(attachLabel() + fmt.Sprintf("%s (%s) will be sent with the next prompt.\n", path, attachment.MediaType))
+}
+
+// handleAttachCommand implements "/attach [path]": with a path it queues the
+// file, without one it lists what is queued.
+func (m *model) handleAttachCommand(path string) {
+	if path != "" {
+		m.attachFile(path, false)
+		return
+	}
+	if len(m.attachments) == 0 {
+		m.appendLine(attachLabel() + "No attachments queued. Use /attach <path> or Ctrl+A in the @ picker.\n")
+		return
+	}
+	paths := make([]string, 0, len(m.attachments))
+	for _, pending := range m.attachments {
+		paths = append(paths, pending.path)
+	}
+	m.appendLine(attachLabel() + "Queued for the next prompt: " + strings.Join(paths, ", ") + "\n")
+}
+
+// takeAttachments loads the queued files for prompt and clears the queue.
+// Files are read again so the model sees their current contents.
+func (m *model) takeAttachments(prompt string) []runtimepkg.Attachment {
+	var attachments []runtimepkg.Attachment
+	for _, pending := range m.attachments {
+		if pending.mention && !strings.Contains(prompt, "@"+pending.path) {
+			continue
+		}
+		attachment, err := runtimepkg.LoadAttachment(m.resolveAttachmentPath(pending.path))
+		if err != nil {
+			m.appendLine(lipgloss.NewStyle().Foreground(theme.Error).Render("[attach] ") + err.Error() + "\n")
+			continue
+		}
+		attachments = append(attachments, attachment)
+	}
+	m.attachments = nil
+	if len(attachments) > 0 {
+		names := make([]string, 0, len(attachments))
+		for _, a := range attachments {
+			names = append(names, a.Name)
+		}
+		m.appendLine(attachLabel() + "Sent " + strings.Join(names, ", ") + "\n")
+	}
+	return attachments
+}
+
+func (m *model) resolveAttachmentPath(path string) string {
+	if filepath.IsAbs(path) || m.workspaceRoot == "" {
+		return path
+	}
+	return filepath.Join(m.workspaceRoot, filepath.FromSlash(path))
+}
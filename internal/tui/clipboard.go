@@ -0,0 +1,72 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// copyToClipboard sends text to the system clipboard with an OSC 52 escape
+// sequence, which most terminals (and tmux with set-clipboard on) forward
+// even over SSH.
+func copyToClipboard(text string) tea.Cmd {
+	return func() tea.Msg {
+		termenv.Copy(text)
+		return nil
+	}
+}
+
+// copyTarget returns the text /copy and Ctrl+Y copy: the block focused with
+// Tab, otherwise the last assistant message (the one still streaming if
+// there is no finished one yet).
+func (m *model) copyTarget() (string, string) {
+	if m.focusedOutput >= 0 && m.focusedOutput < len(m.items) {
+		it := m.items[m.focusedOutput]
+		switch {
+		case it.kind == itemOutput && it.output != nil:
+			text := it.output.stdout
+			if it.output.stderr != "" {
+				text = strings.TrimRight(text, "\n") + "\n" + it.output.stderr
+			}
+			return strings.TrimSpace(text), "output of " + it.output.stepID
+		case it.kind == itemDiff && it.diff != nil:
+			return it.diff.diff, "diff of " + it.diff.path
+		case it.kind == itemReasoning && it.reasoning != nil:
+			return it.reasoning.text, "reasoning"
+		}
+	}
+	for i := len(m.items) - 1; i >= 0; i-- {
+		if m.items[i].kind == itemAssistantMD {
+			return strings.TrimSpace(m.items[i].text), "last assistant message"
+		}
+	}
+	return strings.TrimSpace(m.currentMD.String()), "assistant message"
+}
+
+// copySelection copies the copy target and reports what was copied.
+func (m *model) copySelection() tea.Cmd {
+	label := lipgloss.NewStyle().Foreground(theme.Accent).Render("[copy] ")
+	text, what := m.copyTarget()
+	if text == "" {
+		m.appendLine(label + "Nothing to copy yet.\n")
+		return nil
+	}
+	m.appendLine(label + fmt.Sprintf("Copied the %s (%d characters) to the clipboard.\n", what, len([]rune(text))))
+	return copyToClipboard(text)
+}
+
+// toggleMouse switches mouse capture. Captured, the wheel scrolls the
+// transcript; released, the terminal's own text selection works.
+func (m *model) toggleMouse() tea.Cmd {
+	m.mouseCapture = !m.mouseCapture
+	label := lipgloss.NewStyle().Foreground(theme.Accent).Render("[mouse] ")
+	if m.mouseCapture {
+		m.appendLine(label + "Mouse capture on: the wheel scrolls the transcript. Press F2 or type /mouse to select text.\n")
+		return tea.EnableMouseCellMotion
+	}
+	m.appendLine(label + "Mouse capture off: select text with the mouse. Press F2 or type /mouse to scroll with the wheel.\n")
+	return tea.DisableMouse
+}
@@ -0,0 +1,98 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// diffAutoExpandLines is the largest diff shown expanded by default.
+const diffAutoExpandLines = 40
+
+// fileDiff is a file change with its applied hunks, shown as a collapsible
+// transcript item.
+type fileDiff struct {
+	path     string
+	status   string
+	diff     string
+	expanded bool
+}
+
+// Diff line styles; Theme.apply sets them.
+var (
+	diffAddStyle     lipgloss.Style
+	diffDelStyle     lipgloss.Style
+	diffHunkStyle    lipgloss.Style
+	diffContextStyle lipgloss.Style
+)
+
+// appendFileDiff adds a diff block for a file change event. It reports false
+// when the event carries no diff so the caller can fall back to plain text.
+func (m *model) appendFileDiff(metadata map[string]any) bool {
+	diff, _ := metadata["diff"].(string)
+	if strings.TrimSpace(diff) == "" {
+		return false
+	}
+	path, _ := metadata["path"].(string)
+	status, _ := metadata["status"].(string)
+	d := &fileDiff{path: path, status: status, diff: strings.TrimRight(diff, "\n")}
+	d.expanded = strings.Count(d.diff, "\n")+1 <= diffAutoExpandLines
+	m.items = append(m.items, transcriptItem{kind: itemDiff, diff: d})
+	return true
+}
+
+// renderFileDiff renders the block header with +/- counts and, when
+// expanded, the colored hunks.
+func renderFileDiff(d *fileDiff, focused bool) string {
+	lines := strings.Split(d.diff, "\n")
+	added, removed := 0, 0
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	marker := "▸"
+	if d.expanded {
+		marker = "▾"
+	}
+	header := fmt.Sprintf("%s [diff] %s %s", marker, d.status, d.path)
+	style := outputHeaderStyle
+	hint := ""
+	if focused {
+		style = outputFocusedStyle
+		hint = " — Enter to expand"
+		if d.expanded {
+			hint = " — Enter to collapse"
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(style.Render(header))
+	fmt.Fprintf(&b, " %s %s", diffAddStyle.Render(fmt.Sprintf("+%d", added)), diffDelStyle.Render(fmt.Sprintf("-%d", removed)))
+	if hint != "" {
+		b.WriteString(style.Render(hint))
+	}
+	b.WriteString("\n")
+	if !d.expanded {
+		return b.String()
+	}
+	for _, line := range lines {
+		style := diffContextStyle
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			style = diffHunkStyle
+		case strings.HasPrefix(line, "+"):
+			style = diffAddStyle
+		case strings.HasPrefix(line, "-"):
+			style = diffDelStyle
+		}
+		b.WriteString("  ")
+		b.WriteString(style.Render(terminalText(line)))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,44 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	runtimepkg "github.com/asynkron/goagent/internal/core/runtime"
+)
+
+// handleExportCommand implements "/export [path]": it writes the session
+// transcript as Markdown, or as HTML when path ends in .html or .htm. Without
+// a path the file is named after the current time.
+func (m *model) handleExportCommand(path string) {
+	label := lipgloss.NewStyle().Foreground(theme.Accent).Render("[export] ")
+	if path == "" {
+		path = "transcript-" + time.Now().Format("20060102-150405") + ".md"
+	}
+	format := runtimepkg.TranscriptMarkdown
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		format = runtimepkg.TranscriptHTML
+	}
+
+	target := m.resolveAttachmentPath(path)
+	file, err := os.Create(target)
+	if err != nil {
+		m.appendLine(lipgloss.NewStyle().Foreground(theme.Error).Render("[export] ") + err.Error() + "\n")
+		return
+	}
+	err = m.agent.ExportTranscript(file, format)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		m.appendLine(lipgloss.NewStyle().Foreground(theme.Error).Render("[export] ") + err.Error() + "\n")
+		return
+	}
+	m.appendLine(label + fmt.Sprintf("Wrote the transcript to %s.\n", path))
+}
@@ -0,0 +1,216 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	runtimepkg "github.com/asynkron/goagent/internal/core/runtime"
+)
+
+const (
+	// maxIndexedFiles caps the workspace walk behind the @ picker.
+	maxIndexedFiles = 20000
+	// maxPickerMatches caps how many ranked matches the picker keeps and
+	// pickerRows how many it shows at once.
+	maxPickerMatches = 50
+	pickerRows       = 8
+)
+
+// filePicker is the @-mention file picker. The "@" is typed into the input
+// box; the query after it is kept here until a path is picked.
+type filePicker struct {
+	active   bool
+	query    string
+	matches  []string
+	selected int
+}
+
+// filesIndexedMsg delivers the workspace file list to the picker.
+type filesIndexedMsg struct {
+	files     []string
+	truncated bool
+	err       error
+}
+
+// indexWorkspace walks root in the background. The picker re-indexes each
+// time it opens so new files show up.
+func indexWorkspace(root string) tea.Cmd {
+	return func() tea.Msg {
+		files, truncated, err := runtimepkg.WorkspaceFiles(context.Background(), root, maxIndexedFiles)
+		return filesIndexedMsg{files: files, truncated: truncated, err: err}
+	}
+}
+
+// handlePickerKey opens the picker when "@" starts a word and handles keys
+// while it is open. It runs before the input box sees the key: consumed
+// reports whether the input box should skip it, and cmd starts indexing.
+func (m *model) handlePickerKey(msg tea.KeyMsg) (consumed bool, cmd tea.Cmd) {
+	if !m.picker.active {
+		if msg.Type != tea.KeyRunes || msg.Paste || string(msg.Runes) != "@" || m.workspaceRoot == "" {
+			return false, nil
+		}
+		if m.search.active || m.historySearch.active || m.pendingApproval != "" || m.stdinStep != "" {
+			return false, nil
+		}
+		value := m.ta.Value()
+		if last, _ := utf8.DecodeLastRuneInString(value); value != "" && !unicode.IsSpace(last) {
+			return false, nil
+		}
+		m.picker = filePicker{active: true}
+		m.filterPicker()
+		return false, indexWorkspace(m.workspaceRoot)
+	}
+
+	switch msg.Type {
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.closePicker(m.picker.query)
+	case tea.KeyEnter, tea.KeyTab:
+		m.pickFile(false)
+	case tea.KeyCtrlA:
+		m.pickFile(true)
+	case tea.KeyUp, tea.KeyCtrlP:
+		if m.picker.selected > 0 {
+			m.picker.selected--
+		}
+	case tea.KeyDown, tea.KeyCtrlN:
+		if m.picker.selected < len(m.picker.matches)-1 {
+			m.picker.selected++
+		}
+	case tea.KeyBackspace:
+		if m.picker.query == "" {
+			// Let the input box delete the "@".
+			m.closePicker("")
+			return false, nil
+		}
+		_, size := utf8.DecodeLastRuneInString(m.picker.query)
+		m.picker.query = m.picker.query[:len(m.picker.query)-size]
+		m.filterPicker()
+	case tea.KeySpace:
+		m.closePicker(m.picker.query)
+		return false, nil
+	case tea.KeyRunes:
+		m.picker.query += string(msg.Runes)
+		m.filterPicker()
+	}
+	return true, nil
+}
+
+// pickFile inserts the selected path after the "@" and optionally attaches
+// the file's contents to the next prompt.
+func (m *model) pickFile(attach bool) {
+	if len(m.picker.matches) == 0 {
+		m.closePicker(m.picker.query)
+		return
+	}
+	path := m.picker.matches[m.picker.selected]
+	m.closePicker(path + " ")
+	if attach {
+		m.attachFile(path, true)
+	}
+}
+
+func (m *model) closePicker(insert string) {
+	m.picker = filePicker{}
+	if insert != "" {
+		m.ta.InsertString(insert)
+	}
+}
+
+// filterPicker ranks the indexed files against the query.
+func (m *model) filterPicker() {
+	type scored struct {
+		path  string
+		score int
+	}
+	var ranked []scored
+	for _, path := range m.workspaceFiles {
+		if score, ok := fuzzyScore(path, m.picker.query); ok {
+			ranked = append(ranked, scored{path, score})
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].path < ranked[j].path
+	})
+	if len(ranked) > maxPickerMatches {
+		ranked = ranked[:maxPickerMatches]
+	}
+	m.picker.matches = m.picker.matches[:0]
+	for _, r := range ranked {
+		m.picker.matches = append(m.picker.matches, r.path)
+	}
+	m.picker.selected = min(m.picker.selected, max(0, len(m.picker.matches)-1))
+}
+
+// fuzzyScore matches query as a case-insensitive subsequence of path.
+// Consecutive characters, word starts, and hits in the file name score
+// higher; longer paths score slightly lower.
+func fuzzyScore(path, query string) (int, bool) {
+	lower := strings.ToLower(path)
+	q := strings.ToLower(query)
+	base := strings.LastIndex(lower, "/") + 1
+	score := -len(lower) / 8
+	if q != "" && strings.Contains(lower[base:], q) {
+		score += 20
+	}
+	pos, prev := 0, -2
+	for _, r := range q {
+		idx := strings.IndexRune(lower[pos:], r)
+		if idx < 0 {
+			return 0, false
+		}
+		at := pos + idx
+		score++
+		if at == prev+1 {
+			score += 5
+		}
+		if at >= base {
+			score += 2
+		}
+		if at == 0 || strings.ContainsRune("/_-. ", rune(lower[at-1])) {
+			score += 3
+		}
+		prev = at
+		pos = at + utf8.RuneLen(r)
+	}
+	return score, true
+}
+
+// overlayPicker draws the picker over the bottom rows of the transcript.
+func (m *model) overlayPicker(view string) string {
+	lines := strings.Split(view, "\n")
+	width := max(1, m.vp.Width)
+	row := lipgloss.NewStyle().Width(width).MaxWidth(width)
+
+	header := fmt.Sprintf("@%s — Enter insert, Ctrl+A insert and attach, Esc cancel", m.picker.query)
+	picker := []string{row.Foreground(theme.Accent).Bold(true).Render(header)}
+	switch {
+	case len(m.workspaceFiles) == 0:
+		picker = append(picker, row.Foreground(theme.Muted).Render("  indexing workspace…"))
+	case len(m.picker.matches) == 0:
+		picker = append(picker, row.Foreground(theme.Muted).Render("  no matching files"))
+	}
+	start := max(0, min(m.picker.selected-pickerRows/2, len(m.picker.matches)-pickerRows))
+	end := min(len(m.picker.matches), start+pickerRows)
+	for i := start; i < end; i++ {
+		if i == m.picker.selected {
+			picker = append(picker, row.Foreground(theme.Warning).Bold(true).Render("› "+m.picker.matches[i]))
+		} else {
+			picker = append(picker, row.Foreground(theme.Text).Render("  "+m.picker.matches[i]))
+		}
+	}
+	if len(picker) >= len(lines) {
+		picker = picker[:len(lines)]
+	}
+	copy(lines[len(lines)-len(picker):], picker)
+	return strings.Join(lines, "\n")
+}
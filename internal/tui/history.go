@@ -0,0 +1,245 @@
+package tui
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// promptHistoryFile is where submitted prompts are kept, relative to the
+	// working directory.
+	promptHistoryFile = ".goagent/prompt_history"
+	// maxPromptHistory caps how many prompts are loaded and recalled.
+	maxPromptHistory = 500
+
+	historySearchPlaceholder = "Search prompt history… (Ctrl+R older match, Enter accepts, Esc cancels)"
+)
+
+// promptHistory holds submitted prompts, oldest first. Each prompt is stored
+// as one JSON string per line so multi-line prompts survive the round trip.
+type promptHistory struct {
+	path    string
+	entries []string
+	// index is the entry shown while browsing with Up/Down, or
+	// len(entries) when the input box holds the user's own draft.
+	index int
+	draft string
+}
+
+// loadPromptHistory reads the history file. A missing or unreadable file
+// starts an empty history; recall is a convenience and never blocks the TUI.
+func loadPromptHistory(path string) *promptHistory {
+	h := &promptHistory{path: path}
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var entry string
+			if json.Unmarshal(scanner.Bytes(), &entry) == nil && strings.TrimSpace(entry) != "" {
+				h.entries = append(h.entries, entry)
+			}
+		}
+		_ = f.Close()
+	}
+	if len(h.entries) > maxPromptHistory {
+		h.entries = h.entries[len(h.entries)-maxPromptHistory:]
+	}
+	h.index = len(h.entries)
+	return h
+}
+
+// add records a submitted prompt, skipping repeats of the previous one, and
+// appends it to the history file.
+func (h *promptHistory) add(prompt string) error {
+	defer h.reset()
+	if prompt == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == prompt) {
+		return nil
+	}
+	h.entries = append(h.entries, prompt)
+	if len(h.entries) > maxPromptHistory {
+		h.entries = h.entries[len(h.entries)-maxPromptHistory:]
+	}
+	if h.path == "" {
+		return nil
+	}
+	line, err := json.Marshal(prompt)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// older steps back one entry, saving current as the draft when browsing
+// starts. It reports false when there is nothing older.
+func (h *promptHistory) older(current string) (string, bool) {
+	if h.index == 0 {
+		return "", false
+	}
+	if h.index == len(h.entries) {
+		h.draft = current
+	}
+	h.index--
+	return h.entries[h.index], true
+}
+
+// newer steps forward one entry, returning the saved draft past the newest.
+func (h *promptHistory) newer() (string, bool) {
+	if h.index >= len(h.entries) {
+		return "", false
+	}
+	h.index++
+	if h.index == len(h.entries) {
+		return h.draft, true
+	}
+	return h.entries[h.index], true
+}
+
+func (h *promptHistory) reset() {
+	h.index = len(h.entries)
+	h.draft = ""
+}
+
+// find returns the newest entry before index containing query
+// (case-insensitive), or -1.
+func (h *promptHistory) find(query string, before int) int {
+	query = strings.ToLower(query)
+	for i := min(before, len(h.entries)) - 1; i >= 0; i-- {
+		if strings.Contains(strings.ToLower(h.entries[i]), query) {
+			return i
+		}
+	}
+	return -1
+}
+
+// historySearch holds the Ctrl+R reverse search state. Like transcript
+// search, the query is typed into the input box and the draft is restored
+// when the search is cancelled.
+type historySearch struct {
+	active bool
+	query  string
+	draft  string
+	match  int
+}
+
+// handleHistoryKey recalls prompts with Up on the first line of the input
+// box and Down on its last line, so multi-line drafts keep normal cursor
+// movement. It runs before the input box sees the key and reports whether
+// the key was consumed.
+func (m *model) handleHistoryKey(msg tea.KeyMsg) bool {
+	if m.history == nil || m.search.active || m.historySearch.active || m.picker.active || m.pendingApproval != "" || m.stdinStep != "" {
+		return false
+	}
+	var entry string
+	var ok bool
+	switch msg.Type {
+	case tea.KeyUp:
+		if m.ta.Line() > 0 {
+			return false
+		}
+		entry, ok = m.history.older(m.ta.Value())
+	case tea.KeyDown:
+		if m.ta.Line() < m.ta.LineCount()-1 {
+			return false
+		}
+		entry, ok = m.history.newer()
+	default:
+		return false
+	}
+	if ok {
+		m.ta.SetValue(entry)
+	}
+	return true
+}
+
+// handleHistorySearchKey processes keys while reverse search is open, or
+// Ctrl+R to open it. It reports whether the key was consumed.
+func (m *model) handleHistorySearchKey(msg tea.KeyMsg) bool {
+	if m.history == nil {
+		return false
+	}
+	if !m.historySearch.active {
+		if msg.Type != tea.KeyCtrlR || m.search.active {
+			return false
+		}
+		m.historySearch = historySearch{active: true, draft: m.ta.Value(), match: -1}
+		m.ta.Reset()
+		m.ta.Placeholder = historySearchPlaceholder
+		return true
+	}
+
+	switch msg.Type {
+	case tea.KeyEsc, tea.KeyCtrlC, tea.KeyCtrlG:
+		m.closeHistorySearch(m.historySearch.draft)
+		return true
+	case tea.KeyEnter:
+		// Accept the match for editing rather than submitting it right away.
+		if m.historySearch.match >= 0 {
+			m.closeHistorySearch(m.history.entries[m.historySearch.match])
+		} else {
+			m.closeHistorySearch(m.historySearch.draft)
+		}
+		return true
+	case tea.KeyCtrlR:
+		before := len(m.history.entries)
+		if m.historySearch.match >= 0 {
+			before = m.historySearch.match
+		}
+		if next := m.history.find(m.historySearch.query, before); next >= 0 {
+			m.historySearch.match = next
+		}
+		return true
+	}
+
+	if query := m.ta.Value(); query != m.historySearch.query {
+		m.historySearch.query = query
+		m.historySearch.match = -1
+		if query != "" {
+			m.historySearch.match = m.history.find(query, len(m.history.entries))
+		}
+	}
+	return true
+}
+
+func (m *model) closeHistorySearch(value string) {
+	m.ta.Reset()
+	m.ta.SetValue(value)
+	m.ta.Placeholder = defaultPlaceholder
+	m.historySearch = historySearch{}
+	m.history.reset()
+}
+
+// historySearchStatus renders the status row shown during reverse search.
+func (m *model) historySearchStatus(width int) string {
+	status := "reverse-i-search: "
+	switch {
+	case m.historySearch.query == "":
+	case m.historySearch.match < 0:
+		status = fmt.Sprintf("failing reverse-i-search %q", m.historySearch.query)
+	default:
+		entry := m.history.entries[m.historySearch.match]
+		first, _, multi := strings.Cut(entry, "\n")
+		if multi {
+			first += " …"
+		}
+		status = fmt.Sprintf("reverse-i-search %q: %s", m.historySearch.query, first)
+	}
+	return lipgloss.NewStyle().Width(width).MaxWidth(width).Foreground(theme.Warning).Render(status)
+}
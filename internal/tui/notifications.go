@@ -0,0 +1,56 @@
+package tui
+
+import (
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/asynkron/goagent/internal/notify"
+)
+
+// notifyMode is the mode /notify turns notifications on with and
+// notifyOnStart whether they start enabled; SetNotify sets both.
+var (
+	notifyMode    = notify.ModeAuto
+	notifyOnStart bool
+)
+
+// SetNotify configures desktop notifications for input requests and
+// finished hands-free runs. "off" leaves them off until /notify turns them
+// on in auto mode.
+func SetNotify(mode string) error {
+	parsed, err := notify.ParseMode(mode)
+	if err != nil {
+		return err
+	}
+	notifyOnStart = parsed != notify.ModeOff
+	if notifyOnStart {
+		notifyMode = parsed
+	}
+	return nil
+}
+
+// toggleNotify implements "/notify".
+func (m *model) toggleNotify() {
+	label := lipgloss.NewStyle().Foreground(theme.Accent).Render("[notify] ")
+	if m.notifier.Enabled() {
+		m.notifier = nil
+		m.appendLine(label + "Desktop notifications off.\n")
+		return
+	}
+	notifier, err := notify.New(notifyMode, os.Stderr)
+	if err != nil {
+		m.appendLine(lipgloss.NewStyle().Foreground(theme.Error).Render("[notify] ") + err.Error() + "\n")
+		return
+	}
+	m.notifier = notifier
+	m.appendLine(label + "Desktop notifications on (" + notifier.Mode() + ").\n")
+}
+
+// notify sends a desktop notification when they are enabled. Escape
+// sequences go to stderr so they do not interleave with the rendered frame.
+func (m *model) notify(title, body string) {
+	if err := m.notifier.Notify(title, body); err != nil {
+		m.appendLine(lipgloss.NewStyle().Foreground(theme.Error).Render("[notify] ") + err.Error() + "\n")
+	}
+}
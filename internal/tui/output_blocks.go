@@ -0,0 +1,188 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// stepOutput is the captured output of a finished plan step, shown as a
+// collapsible transcript item.
+type stepOutput struct {
+	stepID   string
+	title    string
+	stdout   string
+	stderr   string
+	exitCode *int
+	expanded bool
+}
+
+// Block styles; Theme.apply sets them.
+var (
+	outputHeaderStyle  lipgloss.Style
+	outputFocusedStyle lipgloss.Style
+	outputStdoutStyle  lipgloss.Style
+	outputStderrStyle  lipgloss.Style
+)
+
+// appendStepOutput adds a collapsed output block for a finished step. Steps
+// without output are skipped.
+func (m *model) appendStepOutput(stepID, title string, metadata map[string]any) {
+	stdout, _ := metadata["stdout"].(string)
+	stderr, _ := metadata["stderr"].(string)
+	if strings.TrimSpace(stdout) == "" && strings.TrimSpace(stderr) == "" {
+		return
+	}
+	out := &stepOutput{stepID: stepID, title: title, stdout: stdout, stderr: stderr}
+	if code, ok := metadata["exit_code"].(int); ok {
+		out.exitCode = &code
+	}
+	m.items = append(m.items, transcriptItem{kind: itemOutput, output: out})
+}
+
+// renderStepOutput renders the block header and, when expanded, its output.
+func renderStepOutput(out *stepOutput, focused bool) string {
+	lines := strings.Count(strings.TrimRight(out.stdout, "\n"), "\n") + strings.Count(strings.TrimRight(out.stderr, "\n"), "\n")
+	if out.stdout != "" {
+		lines++
+	}
+	if out.stderr != "" {
+		lines++
+	}
+	marker := "▸"
+	if out.expanded {
+		marker = "▾"
+	}
+	label := out.stepID
+	if out.title != "" {
+		label += " " + out.title
+	}
+	summary := fmt.Sprintf("%d line(s)", lines)
+	if out.exitCode != nil {
+		summary = fmt.Sprintf("exit %d, %s", *out.exitCode, summary)
+	}
+	header := fmt.Sprintf("%s [output] %s (%s)", marker, label, summary)
+	style := outputHeaderStyle
+	if focused {
+		style = outputFocusedStyle
+		if out.expanded {
+			header += " — Enter to collapse"
+		} else {
+			header += " — Enter to expand"
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(style.Render(header))
+	b.WriteString("\n")
+	if !out.expanded {
+		return b.String()
+	}
+	for _, part := range []struct {
+		text  string
+		style lipgloss.Style
+	}{{out.stdout, outputStdoutStyle}, {out.stderr, outputStderrStyle}} {
+		text := strings.TrimRight(terminalText(part.text), "\n")
+		if text == "" {
+			continue
+		}
+		for _, line := range strings.Split(text, "\n") {
+			b.WriteString("  ")
+			b.WriteString(part.style.Render(line))
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// expandedFlag returns the expanded state of collapsible items (step output,
+// diffs and reasoning), or nil for other items.
+func (it transcriptItem) expandedFlag() *bool {
+	switch {
+	case it.kind == itemOutput && it.output != nil:
+		return &it.output.expanded
+	case it.kind == itemDiff && it.diff != nil:
+		return &it.diff.expanded
+	case it.kind == itemReasoning && it.reasoning != nil:
+		return &it.reasoning.expanded
+	}
+	return nil
+}
+
+// focusOutput moves the focus to the next (1) or previous (-1) collapsible block,
+// wrapping around, and scrolls it into view.
+func (m *model) focusOutput(delta int) bool {
+	var blocks []int
+	for i, it := range m.items {
+		if it.expandedFlag() != nil {
+			blocks = append(blocks, i)
+		}
+	}
+	if len(blocks) == 0 {
+		return false
+	}
+	next := len(blocks) - 1
+	if delta > 0 {
+		next = 0
+	}
+	for i, idx := range blocks {
+		if idx == m.focusedOutput {
+			next = (i + delta + len(blocks)) % len(blocks)
+			break
+		}
+	}
+	m.focusedOutput = blocks[next]
+	m.refresh()
+	m.scrollToItem(m.focusedOutput)
+	return true
+}
+
+// toggleFocusedOutput expands or collapses the focused output block.
+func (m *model) toggleFocusedOutput() bool {
+	if m.focusedOutput < 0 || m.focusedOutput >= len(m.items) {
+		return false
+	}
+	expanded := m.items[m.focusedOutput].expandedFlag()
+	if expanded == nil {
+		return false
+	}
+	*expanded = !*expanded
+	m.refresh()
+	m.scrollToItem(m.focusedOutput)
+	return true
+}
+
+// scrollToItem scrolls the viewport so the item starts near its top.
+func (m *model) scrollToItem(index int) {
+	if index < 0 || index >= len(m.itemLines) {
+		return
+	}
+	m.vp.SetYOffset(max(0, m.contentPadding+m.itemLines[index]-m.vp.Height/4))
+}
+
+// handleOutputKey handles Tab/Shift+Tab to focus output blocks, Enter on an
+// empty prompt to expand or collapse the focused block, and Esc to drop the
+// focus. It reports whether the key was consumed.
+func (m *model) handleOutputKey(msg tea.KeyMsg) bool {
+	switch msg.Type {
+	case tea.KeyTab:
+		return m.focusOutput(1)
+	case tea.KeyShiftTab:
+		return m.focusOutput(-1)
+	case tea.KeyEnter:
+		if msg.Alt || strings.TrimSpace(m.ta.Value()) != "" || m.pendingApproval != "" || m.stdinStep != "" {
+			return false
+		}
+		return m.toggleFocusedOutput()
+	case tea.KeyEsc:
+		if m.focusedOutput < 0 {
+			return false
+		}
+		m.focusedOutput = -1
+		m.refresh()
+		return true
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// handlePauseKey toggles the runtime's pause with Ctrl+P while the agent is
+// working. It runs before the input box, which otherwise treats Ctrl+P as
+// "previous line", and reports whether the key was consumed.
+func (m *model) handlePauseKey(msg tea.KeyMsg) bool {
+	if msg.Type != tea.KeyCtrlP || m.agent == nil || (!m.busy && !m.paused) {
+		return false
+	}
+	if m.paused {
+		m.agent.Resume()
+	} else {
+		m.agent.Pause("user pressed Ctrl+P")
+	}
+	return true
+}
+
+// pausedStatus replaces the activity bar while the runtime is paused.
+func (m *model) pausedStatus(width int) string {
+	status := "Paused: running steps finish, nothing new starts. Ctrl+P resumes, Esc cancels."
+	return lipgloss.NewStyle().Width(width).MaxWidth(width).Foreground(theme.Warning).Render(status)
+}
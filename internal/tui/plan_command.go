@@ -0,0 +1,142 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	runtimepkg "github.com/asynkron/goagent/internal/core/runtime"
+)
+
+// showPlan implements "/plan": it prints the runtime's plan snapshot with
+// each step's status, how long it ran, and its observation summary.
+func (m *model) showPlan() {
+	label := lipgloss.NewStyle().Foreground(theme.Accent).Render("[plan] ")
+	snapshot := m.agent.PlanSnapshot()
+	if snapshot.Total == 0 {
+		m.appendLine(label + "No plan yet.\n")
+		return
+	}
+	var b strings.Builder
+	b.WriteString(label)
+	fmt.Fprintf(&b, "%d steps: %d completed, %d failed, %d pending", snapshot.Total, snapshot.Completed, snapshot.Failed, snapshot.Pending)
+	if other := snapshot.Abandoned + snapshot.Cancelled; other > 0 {
+		fmt.Fprintf(&b, ", %d abandoned or cancelled", other)
+	}
+	b.WriteString("\n")
+	for _, step := range snapshot.Steps {
+		status := string(step.Status)
+		color := theme.Pending
+		switch {
+		case step.Executing:
+			status, color = "executing", theme.Warning
+		case step.Status == runtimepkg.PlanCompleted:
+			color = theme.Success
+		case step.Status == runtimepkg.PlanFailed:
+			color = theme.Failure
+		case step.Status != runtimepkg.PlanPending:
+			color = theme.Muted
+		}
+		title := strings.TrimSpace(step.Title)
+		if title == "" {
+			title = step.ID
+		}
+		line := fmt.Sprintf("  %-10s %s (%s)", status, title, step.ID)
+		if step.StartedAt != nil {
+			line += " " + step.Duration().Round(100*time.Millisecond).String()
+		}
+		if len(step.WaitingForID) > 0 && step.Status == runtimepkg.PlanPending && !step.Executing {
+			line += " waits for " + strings.Join(step.WaitingForID, ", ")
+		}
+		b.WriteString(lipgloss.NewStyle().Foreground(color).Render(line))
+		b.WriteString("\n")
+		if summary := stepSummary(step.PlanStep); summary != "" {
+			b.WriteString(lipgloss.NewStyle().Foreground(theme.Muted).Render("             " + summary))
+			b.WriteString("\n")
+		}
+	}
+	m.appendLine(b.String())
+}
+
+// stepSummary returns the first line of the step's observation summary or
+// details, if it has one.
+func stepSummary(step runtimepkg.PlanStep) string {
+	if step.Observation == nil || step.Observation.ObservationForLLM == nil {
+		return ""
+	}
+	payload := step.Observation.ObservationForLLM
+	text := payload.Summary
+	for _, obs := range payload.PlanObservation {
+		if text == "" && obs.ID == step.ID {
+			text = obs.Details
+		}
+	}
+	first, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return first
+}
+
+// recordStepStart notes when a step started, from the executing event's
+// started_at metadata or, failing that, the time the event arrived.
+func (m *model) recordStepStart(stepID string, metadata map[string]any) {
+	if m.stepStarted == nil {
+		m.stepStarted = make(map[string]time.Time)
+	}
+	started, ok := metadata["started_at"].(time.Time)
+	if !ok {
+		started = time.Now()
+	}
+	m.stepStarted[stepID] = started
+	delete(m.stepElapsed, stepID)
+}
+
+// recordStepDuration stores the duration_ms reported with a step's result.
+func (m *model) recordStepDuration(stepID string, metadata map[string]any) {
+	if m.stepElapsed == nil {
+		m.stepElapsed = make(map[string]time.Duration)
+	}
+	switch ms := metadata["duration_ms"].(type) {
+	case int64:
+		m.stepElapsed[stepID] = time.Duration(ms) * time.Millisecond
+	case float64:
+		m.stepElapsed[stepID] = time.Duration(ms) * time.Millisecond
+	default:
+		if started, ok := m.stepStarted[stepID]; ok {
+			m.stepElapsed[stepID] = time.Since(started)
+		}
+	}
+}
+
+// stepClock formats a step's elapsed time for the plan panel: the final
+// duration once it has finished, the running time while it executes.
+func (m *model) stepClock(stepID string) (string, bool) {
+	if elapsed, ok := m.stepElapsed[stepID]; ok {
+		return formatElapsed(elapsed), true
+	}
+	if started, ok := m.stepStarted[stepID]; ok && m.executing[stepID] {
+		return formatElapsed(time.Since(started)), true
+	}
+	return "", false
+}
+
+func formatElapsed(d time.Duration) string {
+	if d < 10*time.Second {
+		return d.Round(100 * time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
+
+// tickPlanClock redraws the plan panel about once a second while a step is
+// running so its clock keeps moving.
+func (m *model) tickPlanClock() {
+	if len(m.executing) == 0 || m.planSnapshotIndex < 0 || m.planSnapshotIndex >= len(m.items) {
+		return
+	}
+	if time.Since(m.planClock) < time.Second {
+		return
+	}
+	m.planClock = time.Now()
+	m.items[m.planSnapshotIndex].text = m.renderPlan()
+	m.refresh()
+}
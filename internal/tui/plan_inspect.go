@@ -0,0 +1,261 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	runtimepkg "github.com/asynkron/goagent/internal/core/runtime"
+)
+
+const planInspectPlaceholder = "Inspecting the plan… (Up/Down select a step, Enter shows its details, Esc or Ctrl+O returns)"
+
+// detailOutputLines caps the output lines shown in the step detail view.
+const detailOutputLines = 12
+
+// planInspect holds the Ctrl+O plan panel focus. The selection follows the
+// step ID so it survives plan updates; detail is set while the selected
+// step's detail view is open.
+type planInspect struct {
+	active bool
+	stepID string
+	detail bool
+}
+
+// handlePlanKey processes keys while the plan panel has the focus, or
+// Ctrl+O to give it the focus. It runs before the input box sees the key
+// and reports whether the key was consumed.
+func (m *model) handlePlanKey(msg tea.KeyMsg) bool {
+	if !m.inspect.active {
+		if msg.Type != tea.KeyCtrlO || len(m.planSteps) == 0 || m.search.active || m.historySearch.active || m.picker.active {
+			return false
+		}
+		m.inspect = planInspect{active: true, stepID: m.defaultInspectStep()}
+		m.ta.Placeholder = planInspectPlaceholder
+		m.redrawPlan()
+		m.scrollToItem(m.planSnapshotIndex)
+		return true
+	}
+
+	switch msg.Type {
+	case tea.KeyUp:
+		m.selectStep(-1)
+	case tea.KeyDown:
+		m.selectStep(1)
+	case tea.KeyEnter:
+		m.inspect.detail = !m.inspect.detail
+	case tea.KeyEsc:
+		if m.inspect.detail {
+			m.inspect.detail = false
+		} else {
+			m.closePlanInspect()
+		}
+	case tea.KeyCtrlO:
+		m.closePlanInspect()
+	default:
+		return false
+	}
+	return true
+}
+
+// defaultInspectStep picks the first executing step, else the first step
+// that has not finished, else the last one.
+func (m *model) defaultInspectStep() string {
+	for _, step := range m.planSteps {
+		if m.executing[step.ID] {
+			return step.ID
+		}
+	}
+	for _, step := range m.planSteps {
+		if step.Status != runtimepkg.PlanCompleted && step.Status != runtimepkg.PlanFailed {
+			return step.ID
+		}
+	}
+	return m.planSteps[len(m.planSteps)-1].ID
+}
+
+// selectStep moves the selection up (-1) or down (1), stopping at the ends.
+func (m *model) selectStep(delta int) {
+	if len(m.planSteps) == 0 {
+		return
+	}
+	index, ok := m.planIndex[m.inspect.stepID]
+	if !ok {
+		index = 0
+	} else {
+		index = max(0, min(len(m.planSteps)-1, index+delta))
+	}
+	m.inspect.stepID = m.planSteps[index].ID
+	m.redrawPlan()
+}
+
+// closePlanInspect returns the focus to the prompt.
+func (m *model) closePlanInspect() {
+	m.inspect = planInspect{}
+	m.ta.Placeholder = defaultPlaceholder
+	m.redrawPlan()
+}
+
+// redrawPlan re-renders the inline plan snapshot of the current pass.
+func (m *model) redrawPlan() {
+	if m.planSnapshotIndex >= 0 && m.planSnapshotIndex < len(m.items) {
+		m.items[m.planSnapshotIndex].text = m.renderPlan()
+	}
+	m.refresh()
+}
+
+// releasePlanSnapshot renders the current pass's plan snapshot without the
+// selection before a new plan replaces it, so the transcript keeps no stale
+// marker.
+func (m *model) releasePlanSnapshot() {
+	if !m.inspect.active || m.planSnapshotIndex < 0 || m.planSnapshotIndex >= len(m.items) {
+		return
+	}
+	selected := m.inspect.stepID
+	m.inspect.stepID = ""
+	m.items[m.planSnapshotIndex].text = m.renderPlan()
+	m.inspect.stepID = selected
+}
+
+// inspectedStep returns the selected step, preferring the runtime's copy,
+// which carries the command and observation, over the panel's own.
+func (m *model) inspectedStep() (runtimepkg.PlanStepProgress, bool) {
+	if m.agent != nil {
+		for _, step := range m.agent.PlanSnapshot().Steps {
+			if step.ID == m.inspect.stepID {
+				return step, true
+			}
+		}
+	}
+	if index, ok := m.planIndex[m.inspect.stepID]; ok && index < len(m.planSteps) {
+		return runtimepkg.PlanStepProgress{PlanStep: m.planSteps[index]}, true
+	}
+	return runtimepkg.PlanStepProgress{}, false
+}
+
+// overlayStepDetail draws the detail view of the selected step over the
+// top of the transcript, leaving the plan panel below it visible: its command, working directory, live status,
+// duration and the tail of its output.
+func (m *model) overlayStepDetail(view string) string {
+	step, ok := m.inspectedStep()
+	if !ok {
+		return view
+	}
+	lines := strings.Split(view, "\n")
+	width := max(1, m.vp.Width)
+	row := lipgloss.NewStyle().Width(width).MaxWidth(width)
+	labelStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	field := func(label, value string, style lipgloss.Style) string {
+		if label != "" {
+			label += ":"
+		}
+		return row.Render(labelStyle.Render(fmt.Sprintf("%-10s", label)) + style.Render(value))
+	}
+	text := lipgloss.NewStyle().Foreground(theme.Text)
+
+	title := strings.TrimSpace(step.Title)
+	if title == "" {
+		title = step.ID
+	}
+	header := fmt.Sprintf("Step %s: %s — Up/Down other steps, Enter or Esc closes", step.ID, title)
+	detail := []string{row.Foreground(theme.Accent).Bold(true).Render(header)}
+
+	// The panel hears about finished steps before the runtime's snapshot
+	// is read again, so its status wins.
+	status := step.Status
+	if index, ok := m.planIndex[step.ID]; ok && index < len(m.planSteps) && m.planSteps[index].Status != "" {
+		status = m.planSteps[index].Status
+	}
+	label, color := string(status), theme.Pending
+	switch {
+	case m.executing[step.ID] || (step.Executing && status == runtimepkg.PlanPending):
+		label, color = "executing", theme.Warning
+	case status == runtimepkg.PlanCompleted:
+		color = theme.Success
+	case status == runtimepkg.PlanFailed:
+		color = theme.Failure
+	case status == "":
+		label = string(runtimepkg.PlanPending)
+	}
+	detail = append(detail, field("Status", label, lipgloss.NewStyle().Foreground(color)))
+	if elapsed, ok := m.stepClock(step.ID); ok {
+		detail = append(detail, field("Duration", elapsed, text))
+	} else if step.StartedAt != nil {
+		detail = append(detail, field("Duration", formatElapsed(step.Duration()), text))
+	}
+	if run := strings.TrimSpace(step.Command.Run); run != "" {
+		runLines := strings.Split(run, "\n")
+		command := runLines[0]
+		if step.Command.Shell != "" {
+			command = step.Command.Shell + ": " + command
+		}
+		detail = append(detail, field("Command", command, text))
+		for i, line := range runLines[1:] {
+			if i == 2 {
+				detail = append(detail, field("", fmt.Sprintf("… %d more line(s)", len(runLines)-3), text))
+				break
+			}
+			detail = append(detail, field("", line, text))
+		}
+	}
+	if cwd := strings.TrimSpace(step.Command.Cwd); cwd != "" {
+		detail = append(detail, field("Cwd", cwd, text))
+	}
+	if len(step.WaitingForID) > 0 {
+		detail = append(detail, field("Waits", strings.Join(step.WaitingForID, ", "), text))
+	}
+
+	output, exitCode := m.stepDetailOutput(step)
+	if exitCode != nil {
+		detail = append(detail, field("Exit", fmt.Sprintf("%d", *exitCode), text))
+	}
+	output = strings.TrimRight(terminalText(output), "\n")
+	if output == "" {
+		detail = append(detail, row.Render(labelStyle.Render("Output:   (none yet)")))
+	} else {
+		detail = append(detail, row.Render(labelStyle.Render("Output:")))
+		outputLines := strings.Split(output, "\n")
+		if len(outputLines) > detailOutputLines {
+			outputLines = outputLines[len(outputLines)-detailOutputLines:]
+		}
+		for _, line := range outputLines {
+			detail = append(detail, row.Render("  "+line))
+		}
+	}
+
+	detail = append(detail, labelStyle.Render(strings.Repeat("─", width)))
+	copy(lines, detail)
+	return strings.Join(lines, "\n")
+}
+
+// stepDetailOutput returns the step's output: the live tail while it runs,
+// else the captured output block, else the output in its observation.
+func (m *model) stepDetailOutput(step runtimepkg.PlanStepProgress) (string, *int) {
+	if live, ok := m.liveOutput[step.ID]; ok {
+		return live, nil
+	}
+	for i := len(m.items) - 1; i >= 0; i-- {
+		if out := m.items[i].output; m.items[i].kind == itemOutput && out != nil && out.stepID == step.ID {
+			return joinOutput(out.stdout, out.stderr), out.exitCode
+		}
+	}
+	if step.Observation != nil && step.Observation.ObservationForLLM != nil {
+		for _, obs := range step.Observation.ObservationForLLM.PlanObservation {
+			if obs.ID == step.ID {
+				return joinOutput(obs.Stdout, obs.Stderr), obs.ExitCode
+			}
+		}
+	}
+	return "", nil
+}
+
+func joinOutput(stdout, stderr string) string {
+	stdout = strings.TrimRight(stdout, "\n")
+	stderr = strings.TrimRight(stderr, "\n")
+	if stdout != "" && stderr != "" {
+		return stdout + "\n" + stderr
+	}
+	return stdout + stderr
+}
@@ -0,0 +1,84 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// reasoningLiveLines is how many of the latest reasoning lines are shown
+// while the model is still working.
+const reasoningLiveLines = 6
+
+// reasoningBlock is the model's reasoning for one response, shown as a
+// collapsible transcript item.
+type reasoningBlock struct {
+	text     string
+	expanded bool
+}
+
+// reasoningStyle renders reasoning text; Theme.apply sets it.
+var reasoningStyle lipgloss.Style
+
+// flushReasoning moves the streamed reasoning into a collapsed block.
+func (m *model) flushReasoning() {
+	text := strings.TrimSpace(m.currentReasoning.String())
+	m.currentReasoning.Reset()
+	if text == "" {
+		return
+	}
+	m.items = append(m.items, transcriptItem{kind: itemReasoning, reasoning: &reasoningBlock{text: text}})
+}
+
+// renderLiveReasoning renders the tail of the reasoning that is still
+// streaming.
+func (m *model) renderLiveReasoning() string {
+	text := strings.TrimSpace(m.currentReasoning.String())
+	if text == "" {
+		return ""
+	}
+	lines := strings.Split(terminalText(text), "\n")
+	if len(lines) > reasoningLiveLines {
+		lines = lines[len(lines)-reasoningLiveLines:]
+	}
+	var b strings.Builder
+	b.WriteString(outputHeaderStyle.Render("[thinking]") + "\n")
+	for _, line := range lines {
+		b.WriteString("  " + reasoningStyle.Render(line) + "\n")
+	}
+	return b.String()
+}
+
+// renderReasoning renders the block header and, when expanded, the
+// reasoning.
+func renderReasoning(r *reasoningBlock, focused bool) string {
+	lines := strings.Split(terminalText(r.text), "\n")
+	marker := "▸"
+	if r.expanded {
+		marker = "▾"
+	}
+	header := fmt.Sprintf("%s [thinking] (%d line(s))", marker, len(lines))
+	style := outputHeaderStyle
+	if focused {
+		style = outputFocusedStyle
+		if r.expanded {
+			header += " — Enter to collapse"
+		} else {
+			header += " — Enter to expand"
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(style.Render(header))
+	b.WriteString("\n")
+	if !r.expanded {
+		return b.String()
+	}
+	for _, line := range lines {
+		b.WriteString("  ")
+		b.WriteString(reasoningStyle.Render(line))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	runtimepkg "github.com/asynkron/goagent/internal/core/runtime"
+)
+
+// offerRecovery announces a plan left behind by a session that died
+// mid-plan. The user answers with /resume or /discard.
+func (m *model) offerRecovery(plan *runtimepkg.InterruptedPlan) {
+	m.interrupted = plan
+	label := lipgloss.NewStyle().Foreground(theme.Warning).Render("[recovery] ")
+	prompt := strings.TrimSpace(plan.Prompt)
+	if first, _, multi := strings.Cut(prompt, "\n"); multi {
+		prompt = first + " …"
+	}
+	m.appendLine(label + fmt.Sprintf("The last session stopped mid-plan at %s (%d of %d steps finished) while working on: %s\nType /resume to continue it or /discard to start fresh.\n",
+		plan.SavedAt.Local().Format("Jan 2 15:04"), plan.Finished(), len(plan.Steps), prompt))
+}
+
+// handleRecoveryCommand implements /resume and /discard.
+func (m *model) handleRecoveryCommand(command string) {
+	label := lipgloss.NewStyle().Foreground(theme.Warning).Render("[recovery] ")
+	if m.interrupted == nil {
+		m.appendLine(label + "There is no interrupted plan.\n")
+		return
+	}
+	plan := m.interrupted
+	m.interrupted = nil
+	if command == "/discard" {
+		if err := runtimepkg.DiscardInterruptedPlan(plan.Path); err != nil {
+			m.appendLine(label + err.Error() + "\n")
+			return
+		}
+		m.appendLine(label + "Discarded the interrupted plan.\n")
+		return
+	}
+	if err := m.agent.ResumeInterruptedPlan(plan); err != nil {
+		m.interrupted = plan
+		m.appendLine(label + err.Error() + "\n")
+		return
+	}
+	m.appendUserBlock(plan.Prompt)
+	m.requesting = true
+	m.busy = true
+	m.flashFrame = 0
+	m.recalcLayout()
+}
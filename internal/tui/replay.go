@@ -0,0 +1,71 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	runtimepkg "github.com/asynkron/goagent/internal/core/runtime"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// replayPlaceholder is shown in the input box while replaying a session.
+const replayPlaceholder = "Replaying a recorded session (read-only). Tab selects step output, Ctrl+F searches, Esc quits."
+
+// RunReplay renders recorded events in the TUI as if a runtime emitted them,
+// without an agent: prompts are not accepted and nothing is executed. name
+// labels the session in the closing status line. Returns a POSIX-style exit
+// code.
+func RunReplay(ctx context.Context, events []runtimepkg.RuntimeEvent, name string) int {
+	lipgloss.SetColorProfile(termenv.TrueColor)
+	lipgloss.SetHasDarkBackground(theme.Dark)
+
+	// The channel is never closed: the model treats a closed channel as the
+	// runtime going away and quits.
+	outputs := make(chan runtimepkg.RuntimeEvent, len(events)+1)
+	for _, evt := range events {
+		outputs <- evt
+	}
+	outputs <- runtimepkg.RuntimeEvent{
+		Type:    runtimepkg.EventTypeStatus,
+		Message: fmt.Sprintf("Replay of %s finished (%d events).", name, len(events)),
+		Level:   runtimepkg.StatusLevelInfo,
+		Agent:   "replay",
+	}
+
+	m := newModel(nil, outputs, nil)
+	m.replay = true
+	m.ta.Placeholder = replayPlaceholder
+	if cwd, err := os.Getwd(); err == nil {
+		m.workspaceRoot = cwd
+	}
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(ctx))
+	if _, err := p.Run(); err != nil && ctx.Err() == nil {
+		fmt.Fprintln(os.Stderr, "tui error:", err)
+		return 1
+	}
+	return 0
+}
+
+// handleReplayEnter answers Enter while replaying: the view commands work,
+// exit commands quit, and anything else is refused since there is no agent.
+func (m *model) handleReplayEnter() tea.Cmd {
+	prompt := strings.TrimSpace(m.ta.Value())
+	m.ta.Reset()
+	switch prompt {
+	case "":
+		return nil
+	case "/copy":
+		return m.copySelection()
+	case "/mouse":
+		return m.toggleMouse()
+	}
+	if m.isExitCommand(prompt) {
+		return tea.Quit
+	}
+	m.appendLine(lipgloss.NewStyle().Foreground(theme.Warning).Render("[replay] ") + "This is a recording; prompts are not sent anywhere.\n")
+	return nil
+}
@@ -0,0 +1,146 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const searchPlaceholder = "Search transcript… (Enter/Ctrl+N next, Ctrl+P previous, Esc closes)"
+
+// Match highlight styles; Theme.apply sets them.
+var (
+	searchMatchStyle   lipgloss.Style
+	searchCurrentStyle lipgloss.Style
+)
+
+// transcriptSearch holds the Ctrl+F search state. The query is typed into
+// the input box; the prompt being written is kept in draft meanwhile.
+type transcriptSearch struct {
+	active bool
+	query  string
+	draft  string
+	// matches holds the viewport content lines containing the query and
+	// current indexes into it.
+	matches []int
+	current int
+}
+
+// handleSearchKey processes keys while search mode is open, or Ctrl+F to
+// open it. It reports whether the key was consumed.
+func (m *model) handleSearchKey(msg tea.KeyMsg) bool {
+	if !m.search.active {
+		if msg.Type != tea.KeyCtrlF {
+			return false
+		}
+		m.search = transcriptSearch{active: true, draft: m.ta.Value()}
+		m.ta.Reset()
+		m.ta.Placeholder = searchPlaceholder
+		m.refresh()
+		return true
+	}
+
+	switch msg.Type {
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.ta.Reset()
+		m.ta.SetValue(m.search.draft)
+		m.ta.Placeholder = defaultPlaceholder
+		m.search = transcriptSearch{}
+		m.refresh()
+		return true
+	case tea.KeyEnter, tea.KeyCtrlN, tea.KeyCtrlF:
+		m.stepSearch(1)
+		return true
+	case tea.KeyCtrlP:
+		m.stepSearch(-1)
+		return true
+	case tea.KeyPgUp, tea.KeyPgDown, tea.KeyUp, tea.KeyDown, tea.KeyHome, tea.KeyEnd:
+		// Let the viewport scroll while searching.
+		return false
+	}
+
+	// The input box already applied the key; pick up the edited query and
+	// jump to the match closest to the bottom of the transcript.
+	if query := strings.TrimSpace(m.ta.Value()); query != m.search.query {
+		m.search.query = query
+		m.search.current = -1
+		m.refresh()
+		m.stepSearch(-1)
+	}
+	return true
+}
+
+// stepSearch moves to the next (1) or previous (-1) match, wrapping around.
+func (m *model) stepSearch(delta int) {
+	if len(m.search.matches) == 0 {
+		return
+	}
+	if m.search.current < 0 {
+		m.search.current = 0
+		if delta < 0 {
+			m.search.current = len(m.search.matches) - 1
+		}
+	} else {
+		m.search.current = (m.search.current + delta + len(m.search.matches)) % len(m.search.matches)
+	}
+	m.refresh()
+	line := m.search.matches[m.search.current]
+	m.vp.SetYOffset(max(0, line-m.vp.Height/2))
+}
+
+// highlightSearch marks every occurrence of query in content and returns the
+// indexes of matching lines. Matching lines lose their original styling so
+// highlights can be placed on the plain text. Matching is case-insensitive.
+func highlightSearch(content, query string, current int) (string, []int) {
+	if query == "" {
+		return content, nil
+	}
+	lines := strings.Split(content, "\n")
+	var matches []int
+	for i, line := range lines {
+		plain := stripANSI(line)
+		haystack, needle := strings.ToLower(plain), strings.ToLower(query)
+		if len(haystack) != len(plain) || len(needle) != len(query) {
+			// Lowercasing changed byte offsets; fall back to exact matching.
+			haystack, needle = plain, query
+		}
+		if !strings.Contains(haystack, needle) {
+			continue
+		}
+		style := searchMatchStyle
+		if len(matches) == current {
+			style = searchCurrentStyle
+		}
+		matches = append(matches, i)
+
+		var b strings.Builder
+		for {
+			idx := strings.Index(haystack, needle)
+			if idx < 0 {
+				b.WriteString(plain)
+				break
+			}
+			end := idx + len(needle)
+			b.WriteString(plain[:idx])
+			b.WriteString(style.Render(plain[idx:end]))
+			plain, haystack = plain[end:], haystack[end:]
+		}
+		lines[i] = b.String()
+	}
+	return strings.Join(lines, "\n"), matches
+}
+
+// searchStatus renders the status row shown while searching.
+func (m *model) searchStatus(width int) string {
+	status := "Search"
+	switch {
+	case m.search.query == "":
+	case len(m.search.matches) == 0:
+		status = fmt.Sprintf("Search %q: no matches", m.search.query)
+	default:
+		status = fmt.Sprintf("Search %q: %d/%d", m.search.query, m.search.current+1, len(m.search.matches))
+	}
+	return lipgloss.NewStyle().Width(width).MaxWidth(width).Foreground(theme.Warning).Render(status)
+}
@@ -0,0 +1,306 @@
+package tui
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	glamstyles "github.com/charmbracelet/glamour/styles"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme holds every color the TUI draws with. Colors are ANSI 256 numbers
+// ("63") or hex values ("#5f5fff").
+type Theme struct {
+	// Dark tells lipgloss the terminal background is dark.
+	Dark bool
+	// Glamour names the Glamour style used for assistant markdown.
+	Glamour string
+
+	Text    lipgloss.Color // plan and user panel text
+	Muted   lipgloss.Color // status labels, live output, dim plan steps
+	Border  lipgloss.Color // input box border and closed notices
+	Accent  lipgloss.Color // spinner, live output headers, profile labels
+	Panel   lipgloss.Color // user and plan panel borders
+	Error   lipgloss.Color
+	Warning lipgloss.Color // approvals, focused blocks, search status
+	Agent   lipgloss.Color // sub-agent requests
+	Input   lipgloss.Color // input requests
+
+	Success lipgloss.Color // completed plan steps
+	Failure lipgloss.Color // failed plan steps
+	Pending lipgloss.Color // pending plan steps
+
+	Stdout lipgloss.Color
+	Stderr lipgloss.Color
+
+	DiffAdd     lipgloss.Color
+	DiffRemove  lipgloss.Color
+	DiffHunk    lipgloss.Color
+	DiffContext lipgloss.Color
+
+	MatchBackground        lipgloss.Color
+	MatchForeground        lipgloss.Color
+	CurrentMatchBackground lipgloss.Color
+	CurrentMatchForeground lipgloss.Color
+
+	// Bars tune the animated status bar for each busy state.
+	BarBegin  BarPalette
+	BarStream BarPalette
+	BarWork   BarPalette
+}
+
+// BarPalette shapes the color-cycling status bar: how saturated the hues
+// are, around which lightness and by how much it pulses, and the glyph used.
+type BarPalette struct {
+	Saturation float64
+	Lightness  float64
+	Amplitude  float64
+	Char       string
+}
+
+// darkTheme is the default theme.
+func darkTheme() Theme {
+	return Theme{
+		Dark:    true,
+		Glamour: "dark",
+
+		Text:    "252",
+		Muted:   "244",
+		Border:  "240",
+		Accent:  "63",
+		Panel:   "129",
+		Error:   "9",
+		Warning: "214",
+		Agent:   "141",
+		Input:   "33",
+
+		Success: "70",
+		Failure: "196",
+		Pending: "250",
+
+		Stdout: "250",
+		Stderr: "203",
+
+		DiffAdd:     "71",
+		DiffRemove:  "167",
+		DiffHunk:    "74",
+		DiffContext: "246",
+
+		MatchBackground:        "58",
+		MatchForeground:        "230",
+		CurrentMatchBackground: "214",
+		CurrentMatchForeground: "16",
+
+		BarBegin:  BarPalette{Saturation: 0.65, Lightness: 0.50, Amplitude: 0.10, Char: "▄"},
+		BarStream: BarPalette{Saturation: 0.90, Lightness: 0.50, Amplitude: 0.18, Char: "█"},
+		BarWork:   BarPalette{Saturation: 0.75, Lightness: 0.50, Amplitude: 0.08, Char: "▓"},
+	}
+}
+
+// lightTheme uses darker foregrounds for light terminal backgrounds.
+func lightTheme() Theme {
+	t := darkTheme()
+	t.Dark = false
+	t.Glamour = "light"
+	t.Text = "235"
+	t.Muted = "241"
+	t.Border = "250"
+	t.Accent = "25"
+	t.Panel = "91"
+	t.Error = "160"
+	t.Warning = "130"
+	t.Agent = "90"
+	t.Input = "25"
+	t.Success = "28"
+	t.Failure = "160"
+	t.Pending = "240"
+	t.Stdout = "238"
+	t.Stderr = "160"
+	t.DiffAdd = "28"
+	t.DiffRemove = "124"
+	t.DiffHunk = "25"
+	t.DiffContext = "243"
+	t.MatchBackground = "229"
+	t.MatchForeground = "16"
+	t.CurrentMatchBackground = "214"
+	t.CurrentMatchForeground = "16"
+	t.BarBegin.Lightness = 0.40
+	t.BarStream.Lightness = 0.40
+	t.BarWork.Lightness = 0.40
+	return t
+}
+
+// highContrastTheme sticks to the basic bright ANSI colors and full
+// saturation.
+func highContrastTheme() Theme {
+	t := darkTheme()
+	t.Text = "15"
+	t.Muted = "250"
+	t.Border = "15"
+	t.Accent = "14"
+	t.Panel = "13"
+	t.Error = "9"
+	t.Warning = "11"
+	t.Agent = "13"
+	t.Input = "14"
+	t.Success = "10"
+	t.Failure = "9"
+	t.Pending = "15"
+	t.Stdout = "15"
+	t.Stderr = "9"
+	t.DiffAdd = "10"
+	t.DiffRemove = "9"
+	t.DiffHunk = "14"
+	t.DiffContext = "250"
+	t.MatchBackground = "11"
+	t.MatchForeground = "0"
+	t.CurrentMatchBackground = "13"
+	t.CurrentMatchForeground = "0"
+	t.BarBegin = BarPalette{Saturation: 1, Lightness: 0.50, Amplitude: 0, Char: "▄"}
+	t.BarStream = BarPalette{Saturation: 1, Lightness: 0.50, Amplitude: 0, Char: "█"}
+	t.BarWork = BarPalette{Saturation: 1, Lightness: 0.50, Amplitude: 0, Char: "▓"}
+	return t
+}
+
+// builtinThemes maps theme names to their constructors.
+var builtinThemes = map[string]func() Theme{
+	"dark":          darkTheme,
+	"light":         lightTheme,
+	"high-contrast": highContrastTheme,
+}
+
+// theme is the active theme. SetTheme replaces it before the TUI starts.
+var theme = darkTheme()
+
+func init() {
+	theme.apply()
+}
+
+// SetTheme selects the color theme by name and applies color overrides on
+// top of it. Names are "dark" (default), "light", "high-contrast", or any
+// other built-in Glamour style such as "dracula", which keeps the dark colors
+// and only swaps the markdown style. Override keys are the kebab-case field
+// names of Theme ("accent", "diff-add", ...) plus "glamour".
+func SetTheme(name string, overrides map[string]string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = "dark"
+	}
+	var t Theme
+	if build, ok := builtinThemes[name]; ok {
+		t = build()
+	} else if _, ok := glamstyles.DefaultStyles[name]; ok {
+		t = darkTheme()
+		t.Glamour = name
+		t.Dark = name != "light"
+	} else {
+		return fmt.Errorf("unknown theme %q (available: %s)", name, strings.Join(ThemeNames(), ", "))
+	}
+	if err := t.override(overrides); err != nil {
+		return err
+	}
+	theme = t
+	theme.apply()
+	return nil
+}
+
+// ThemeNames lists the built-in theme names.
+func ThemeNames() []string {
+	names := make([]string, 0, len(builtinThemes))
+	for name := range builtinThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var colorValuePattern = regexp.MustCompile(`^(#[0-9a-fA-F]{6}|#[0-9a-fA-F]{3}|[0-9]{1,3})$`)
+
+// override applies user colors. Unknown keys and malformed colors are errors
+// so typos in the config file do not go unnoticed.
+func (t *Theme) override(overrides map[string]string) error {
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fields := t.colorFields()
+	for _, key := range keys {
+		value := strings.TrimSpace(overrides[key])
+		name := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), "_", "-")
+		if name == "glamour" {
+			if _, ok := glamstyles.DefaultStyles[value]; !ok {
+				return fmt.Errorf("theme: unknown glamour style %q", value)
+			}
+			t.Glamour = value
+			continue
+		}
+		field, ok := fields[name]
+		if !ok {
+			return fmt.Errorf("theme: unknown color %q", key)
+		}
+		if !colorValuePattern.MatchString(value) {
+			return fmt.Errorf("theme: %s: %q is not an ANSI color number or #hex value", key, value)
+		}
+		*field = lipgloss.Color(value)
+	}
+	return nil
+}
+
+func (t *Theme) colorFields() map[string]*lipgloss.Color {
+	return map[string]*lipgloss.Color{
+		"text":                     &t.Text,
+		"muted":                    &t.Muted,
+		"border":                   &t.Border,
+		"accent":                   &t.Accent,
+		"panel":                    &t.Panel,
+		"error":                    &t.Error,
+		"warning":                  &t.Warning,
+		"agent":                    &t.Agent,
+		"input":                    &t.Input,
+		"success":                  &t.Success,
+		"failure":                  &t.Failure,
+		"pending":                  &t.Pending,
+		"stdout":                   &t.Stdout,
+		"stderr":                   &t.Stderr,
+		"diff-add":                 &t.DiffAdd,
+		"diff-remove":              &t.DiffRemove,
+		"diff-hunk":                &t.DiffHunk,
+		"diff-context":             &t.DiffContext,
+		"match-background":         &t.MatchBackground,
+		"match-foreground":         &t.MatchForeground,
+		"current-match-background": &t.CurrentMatchBackground,
+		"current-match-foreground": &t.CurrentMatchForeground,
+	}
+}
+
+// apply rebuilds the shared block styles from the theme.
+func (t Theme) apply() {
+	outputHeaderStyle = lipgloss.NewStyle().Foreground(t.Muted)
+	outputFocusedStyle = lipgloss.NewStyle().Foreground(t.Warning).Bold(true)
+	outputStdoutStyle = lipgloss.NewStyle().Foreground(t.Stdout)
+	outputStderrStyle = lipgloss.NewStyle().Foreground(t.Stderr)
+	reasoningStyle = lipgloss.NewStyle().Foreground(t.Muted).Italic(true)
+
+	diffAddStyle = lipgloss.NewStyle().Foreground(t.DiffAdd)
+	diffDelStyle = lipgloss.NewStyle().Foreground(t.DiffRemove)
+	diffHunkStyle = lipgloss.NewStyle().Foreground(t.DiffHunk)
+	diffContextStyle = lipgloss.NewStyle().Foreground(t.DiffContext)
+
+	searchMatchStyle = lipgloss.NewStyle().Background(t.MatchBackground).Foreground(t.MatchForeground)
+	searchCurrentStyle = lipgloss.NewStyle().Background(t.CurrentMatchBackground).Foreground(t.CurrentMatchForeground).Bold(true)
+}
+
+// barPalette returns the status bar palette for a busy state.
+func (t Theme) barPalette(state string) BarPalette {
+	switch state {
+	case "begin":
+		return t.BarBegin
+	case "work":
+		return t.BarWork
+	default:
+		return t.BarStream
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -20,6 +21,7 @@ import (
 	"github.com/muesli/termenv"
 
 	runtimepkg "github.com/asynkron/goagent/internal/core/runtime"
+	"github.com/asynkron/goagent/internal/notify"
 )
 
 type eventMsg struct{ evt runtimepkg.RuntimeEvent }
@@ -32,11 +34,20 @@ const (
 	itemUser
 	itemAssistantMD
 	itemPlan
+	itemOutput
+	itemDiff
+	itemReasoning
 )
 
 type transcriptItem struct {
 	kind transcriptKind
 	text string // raw content; assistant content is markdown
+	// output holds the step output of itemOutput entries, diff the file
+	// change of itemDiff entries and reasoning the thinking of itemReasoning
+	// entries.
+	output    *stepOutput
+	diff      *fileDiff
+	reasoning *reasoningBlock
 }
 
 // markdownRenderer is a minimal interface for rendering Markdown into ANSI.
@@ -63,14 +74,18 @@ type model struct {
 	glam            markdownRenderer
 	currentMD       strings.Builder // accumulating assistant deltas
 	currentRendered string          // last rendered ANSI of currentMD
-	lastRender      time.Time
-	pendingRender   bool
+	// currentReasoning accumulates reasoning deltas until the response
+	// is complete.
+	currentReasoning strings.Builder
+	lastRender       time.Time
+	pendingRender    bool
 
 	// Activity
 	spin       spinner.Model
 	requesting bool // after submit, before first delta
 	streaming  bool // while streaming deltas
 	busy       bool // overall busy: requesting/streaming/working between turns
+	paused     bool // the runtime reported a pause (Ctrl+P)
 	flashFrame int
 
 	// Styling
@@ -85,14 +100,94 @@ type model struct {
 	planSteps []runtimepkg.PlanStep
 	planIndex map[string]int
 	executing map[string]bool
+	// stepStarted and stepElapsed time the steps of the current plan;
+	// planClock is when the plan panel last redrew a running step's clock.
+	stepStarted map[string]time.Time
+	stepElapsed map[string]time.Duration
+	planClock   time.Time
+
+	// interrupted is a plan journal from a crashed session awaiting
+	// /resume or /discard.
+	interrupted *runtimepkg.InterruptedPlan
 
 	// Inline plan snapshot anchoring
 	planSnapshotIndex int
+
+	// focusedOutput is the index of the output block selected with Tab, or
+	// -1. itemLines records where each item starts in the rendered
+	// transcript and contentPadding the blank lines added above it.
+	focusedOutput  int
+	itemLines      []int
+	contentPadding int
+
+	// search is the Ctrl+F transcript search state.
+	search transcriptSearch
+
+	// inspect is the Ctrl+O plan panel focus and step detail view.
+	inspect planInspect
+
+	// exitCommands are inputs that quit the TUI instead of being submitted.
+	exitCommands []string
+
+	// history recalls submitted prompts with Up/Down and Ctrl+R;
+	// historySearch is the Ctrl+R state.
+	history       *promptHistory
+	historySearch historySearch
+
+	// picker is the @ file picker over workspaceFiles, indexed below
+	// workspaceRoot; attachments are files queued with /attach or the
+	// picker's Ctrl+A for the next prompt.
+	picker         filePicker
+	workspaceRoot  string
+	workspaceFiles []string
+	attachments    []pendingAttachment
+
+	// mouseCapture is set while mouse events are captured for wheel
+	// scrolling, which disables the terminal's own text selection.
+	mouseCapture bool
+
+	// notifier sends desktop notifications when the agent needs the user;
+	// nil while they are off.
+	notifier *notify.Notifier
+
+	// pendingApproval holds the step awaiting a yes/no answer, if any.
+	pendingApproval string
+	// stdinStep is the running interactive step that receives typed input.
+	stdinStep string
+
+	// replay is set when the model renders a recorded session; agent is
+	// nil then and input is not submitted (see replay.go).
+	replay bool
+
+	// liveOutput keeps the recent output of executing steps, keyed by step
+	// ID, and liveOrder the order in which they started.
+	liveOutput map[string]string
+	liveOrder  []string
 }
 
+// profiles lists the names accepted by the /profile command and
+// resolveProfile turns one into model settings.
+var (
+	profiles       []string
+	resolveProfile func(name string) (runtimepkg.ModelSettings, error)
+)
+
+// SetProfiles enables the /profile command, which switches the running
+// agent to the named profile's provider and model.
+func SetProfiles(names []string, resolve func(name string) (runtimepkg.ModelSettings, error)) {
+	profiles = names
+	resolveProfile = resolve
+}
+
+// defaultPlaceholder is shown in the input box when it submits prompts.
+const defaultPlaceholder = "Type a prompt… (Enter to send, Up/Ctrl+R for history, Tab to select step output, Ctrl+O to inspect the plan, Ctrl+P to pause)"
+
+// liveTailLines caps how many output lines the live pane shows per step.
+const liveTailLines = 8
+
 func newModel(agent *runtimepkg.Runtime, outputs <-chan runtimepkg.RuntimeEvent, cancel context.CancelFunc) *model {
 	ta := textarea.New()
-	ta.Placeholder = "Type a prompt… (Enter to send)"
+	ta.Placeholder = defaultPlaceholder
 	ta.CharLimit = 0
 	ta.SetHeight(3)
 	ta.Focus()
@@ -117,27 +212,28 @@ func newModel(agent *runtimepkg.Runtime, outputs <-chan runtimepkg.RuntimeEvent,
 		cancel:  cancel,
 		vp:      vp,
 		ta:      ta,
-		border:  lipgloss.NewStyle().Border(lipgloss.NormalBorder()).BorderForeground(lipgloss.Color("240")),
+		border:  lipgloss.NewStyle().Border(lipgloss.NormalBorder()).BorderForeground(theme.Border),
 	}
 	sp := spinner.New()
-	sp.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("63"))
+	sp.Style = lipgloss.NewStyle().Foreground(theme.Accent)
 	m.spin = sp
 	_ = m.rebuildRenderer(80)
-	// Bright purple rounded border, transparent background, 1-char horizontal padding.
+	// Rounded panel border, transparent background, 1-char horizontal padding.
 	m.userStyle = lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("129")).
-		Foreground(lipgloss.Color("252")).
+		BorderForeground(theme.Panel).
+		Foreground(theme.Text).
 		PaddingLeft(1).
 		PaddingRight(1)
-	// Plan panel style (panel block similar to user input), purple rounded border
+	// Plan panel style (panel block similar to user input), rounded border
 	m.planStyle = lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("129")).
-		Foreground(lipgloss.Color("252")).
+		BorderForeground(theme.Panel).
+		Foreground(theme.Text).
 		PaddingLeft(1).
 		PaddingRight(1)
 	m.planSnapshotIndex = -1
+	m.focusedOutput = -1
 	return &m
 }
 
@@ -151,6 +247,37 @@ func waitForEvent(ch <-chan runtimepkg.RuntimeEvent) tea.Cmd {
 	}
 }
 
+// switchProfile handles "/profile [name]": without a name it lists the
+// profiles, otherwise it switches the agent to that profile.
+func (m *model) switchProfile(name string) {
+	label := lipgloss.NewStyle().Foreground(theme.Accent).Render("[profile] ")
+	if resolveProfile == nil || len(profiles) == 0 {
+		m.appendLine(label + "No profiles are configured.\n")
+		return
+	}
+	if name == "" {
+		m.appendLine(label + "Available profiles: " + strings.Join(profiles, ", ") + "\n")
+		return
+	}
+	settings, err := resolveProfile(name)
+	if err == nil {
+		err = m.agent.SwitchModel(settings)
+	}
+	if err != nil {
+		m.appendLine(lipgloss.NewStyle().Foreground(theme.Error).Render("[profile] ") + err.Error() + "\n")
+	}
+}
+
+// isExitCommand reports whether input matches a configured exit command.
+func (m *model) isExitCommand(input string) bool {
+	for _, candidate := range m.exitCommands {
+		if input != "" && strings.EqualFold(input, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
 // renderTranscript renders all transcript items according to current width.
 func (m *model) renderTranscript() string {
 	var out strings.Builder
@@ -162,8 +289,18 @@ func (m *model) renderTranscript() string {
 	if userWidth < 1 {
 		userWidth = 1
 	}
-	for _, it := range m.items {
+	m.itemLines = m.itemLines[:0]
+	lines := 0
+	for i, it := range m.items {
+		m.itemLines = append(m.itemLines, lines)
+		start := out.Len()
 		switch it.kind {
+		case itemOutput:
+			out.WriteString(renderStepOutput(it.output, i == m.focusedOutput))
+		case itemDiff:
+			out.WriteString(renderFileDiff(it.diff, i == m.focusedOutput))
+		case itemReasoning:
+			out.WriteString(renderReasoning(it.reasoning, i == m.focusedOutput))
 		case itemPlan:
 			// Render stored snapshot text (keeps historical integrity)
 			out.WriteString(it.text)
@@ -190,6 +327,7 @@ func (m *model) renderTranscript() string {
 		default:
 			out.WriteString(it.text)
 		}
+		lines += strings.Count(out.String()[start:], "\n")
 	}
 	return out.String()
 }
@@ -201,23 +339,33 @@ func (m *model) refresh() {
 	wasAtBottom := m.vp.AtBottom()
 
 	content := m.renderTranscript()
+	content += m.renderLiveReasoning()
 	if m.currentRendered != "" {
 		content += m.currentRendered
 	}
+	content += m.renderLiveOutput()
 	// Anchor content to the bottom of the viewport: if there are fewer
 	// visual lines than the viewport height, prepend newlines so that
 	// the content starts from the bottom edge.
+	m.contentPadding = 0
 	if m.vp.Height > 0 {
 		lines := countRenderedLines(content)
 		if lines < m.vp.Height {
-			padding := strings.Repeat("\n", m.vp.Height-lines)
-			content = padding + content
+			m.contentPadding = m.vp.Height - lines
+			content = strings.Repeat("\n", m.contentPadding) + content
+		}
+	}
+	if m.search.active {
+		content, m.search.matches = highlightSearch(content, m.search.query, m.search.current)
+		if m.search.current >= len(m.search.matches) {
+			m.search.current = len(m.search.matches) - 1
 		}
 	}
 	m.vp.SetContent(content)
 	// Only auto-scroll to the bottom if we were already at bottom (sticky)
-	// or when actively streaming new content.
-	if wasAtBottom || m.streaming {
+	// or when actively streaming new content. Searching keeps the viewport
+	// on the current match.
+	if (wasAtBottom || m.streaming) && !m.search.active {
 		m.vp.GotoBottom()
 	}
 }
@@ -246,6 +394,78 @@ func stripANSI(s string) string {
 	return ansiRegexp.ReplaceAllString(s, "")
 }
 
+// terminalEscapeRegexp matches CSI and OSC sequences; SGR (color) sequences
+// end in 'm' and are kept by terminalText.
+var terminalEscapeRegexp = regexp.MustCompile("\x1b\\[[0-9;?]*[A-Za-z]|\x1b\\][^\x07\x1b]*(\x07|\x1b\\\\)")
+
+// terminalText prepares raw terminal output for the live tail: colors pass
+// through, other control sequences are dropped, and carriage-return redraws
+// such as progress bars collapse to their latest state.
+func terminalText(s string) string {
+	s = terminalEscapeRegexp.ReplaceAllStringFunc(s, func(seq string) string {
+		if strings.HasPrefix(seq, "\x1b[") && strings.HasSuffix(seq, "m") {
+			return seq
+		}
+		return ""
+	})
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		if idx := strings.LastIndex(line, "\r"); idx >= 0 {
+			lines[i] = line[idx+1:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// appendLiveOutput adds a command output chunk to the step's live tail.
+func (m *model) appendLiveOutput(stepID, chunk string) {
+	if m.liveOutput == nil {
+		m.liveOutput = make(map[string]string)
+	}
+	if _, ok := m.liveOutput[stepID]; !ok {
+		m.liveOrder = append(m.liveOrder, stepID)
+	}
+	text := m.liveOutput[stepID] + chunk
+	lines := strings.Split(text, "\n")
+	// Keep one extra entry for the partial line still being written.
+	if len(lines) > liveTailLines+1 {
+		lines = lines[len(lines)-liveTailLines-1:]
+	}
+	m.liveOutput[stepID] = strings.Join(lines, "\n")
+}
+
+// clearLiveOutput drops the live tail once a step finishes.
+func (m *model) clearLiveOutput(stepID string) {
+	if _, ok := m.liveOutput[stepID]; !ok {
+		return
+	}
+	delete(m.liveOutput, stepID)
+	for i, id := range m.liveOrder {
+		if id == stepID {
+			m.liveOrder = append(m.liveOrder[:i], m.liveOrder[i+1:]...)
+			break
+		}
+	}
+}
+
+// renderLiveOutput shows the output tail of each executing step below the transcript.
+func (m *model) renderLiveOutput() string {
+	if len(m.liveOrder) == 0 {
+		return ""
+	}
+	header := lipgloss.NewStyle().Foreground(theme.Accent).Bold(true)
+	body := lipgloss.NewStyle().Foreground(theme.Muted)
+	var out strings.Builder
+	for _, id := range m.liveOrder {
+		out.WriteString(header.Render("[running "+id+"]") + "\n")
+		tail := strings.TrimRight(terminalText(m.liveOutput[id]), "\n")
+		if tail != "" {
+			out.WriteString(body.Render(tail) + "\n")
+		}
+	}
+	return out.String()
+}
+
 // recalcLayout recomputes viewport sizes based on current terminal size and
 // the number of lines needed to render the plan panel so it stays visible.
 func (m *model) recalcLayout() {
@@ -303,7 +523,7 @@ func (m *model) renderPlan() string {
 	// Header
 	// Removed the literal "Plan:" label per user request.
 	// Keep styling invocation (with empty content) to avoid altering surrounding layout.
-	inner.WriteString(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("63")).Render(""))
+	inner.WriteString(lipgloss.NewStyle().Bold(true).Foreground(theme.Accent).Render(""))
 	inner.WriteString("\n")
 	// Lines
 	for _, step := range m.planSteps {
@@ -319,29 +539,37 @@ func (m *model) renderPlan() string {
 		} else if status == "" {
 			status = "pending"
 		}
-		var box, color string
+		box := "⬤ "
+		var color lipgloss.Color
 		switch status {
 		case string(runtimepkg.PlanCompleted):
 			// Completed: green circle
-			box, color = "⬤ ", "70"
+			color = theme.Success
 		case string(runtimepkg.PlanFailed):
 			// Failed: red circle
-			box, color = "⬤ ", "196"
+			color = theme.Failure
 		case "executing":
 			// Running: yellow circle
-			box, color = "⬤ ", "214"
+			color = theme.Warning
 		default:
 			// Pending/Waiting/Ready: white circle
-			box, color = "⬤ ", "250"
+			color = theme.Pending
 			if len(step.WaitingForID) > 0 {
 				// Waiting on dependencies, render dimmer
-				color = "244"
+				color = theme.Muted
 			}
 		}
-		line := lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Render(box)
-		titleStyled := lipgloss.NewStyle().Foreground(lipgloss.Color("252")).Render(" " + title)
+		line := lipgloss.NewStyle().Foreground(color).Render(box)
+		titleStyled := lipgloss.NewStyle().Foreground(theme.Text).Render(" " + title)
+		if m.inspect.active && id == m.inspect.stepID {
+			// The step selected with Ctrl+O.
+			titleStyled = lipgloss.NewStyle().Foreground(theme.Accent).Bold(true).Render(" › " + title)
+		}
 		inner.WriteString(line)
 		inner.WriteString(titleStyled)
+		if elapsed, ok := m.stepClock(id); ok {
+			inner.WriteString(lipgloss.NewStyle().Foreground(theme.Muted).Render(" " + elapsed))
+		}
 		inner.WriteString("\n")
 	}
 	// Render as a bordered panel. Set the width so the final block (including
@@ -356,6 +584,7 @@ func (m *model) renderPlan() string {
 
 // setPlan loads the plan steps and builds a fast index.
 func (m *model) setPlan(steps []runtimepkg.PlanStep) {
+	m.releasePlanSnapshot()
 	m.planSteps = make([]runtimepkg.PlanStep, len(steps))
 	copy(m.planSteps, steps)
 	m.planIndex = make(map[string]int, len(steps))
@@ -369,6 +598,11 @@ func (m *model) setPlan(steps []runtimepkg.PlanStep) {
 			delete(m.executing, k)
 		}
 	}
+	m.stepStarted = make(map[string]time.Time)
+	m.stepElapsed = make(map[string]time.Duration)
+	if _, ok := m.planIndex[m.inspect.stepID]; m.inspect.active && !ok {
+		m.inspect.stepID = m.defaultInspectStep()
+	}
 	// Anchor a new inline plan snapshot in the transcript and track its index.
 	snapshot := m.renderPlan()
 	m.items = append(m.items, transcriptItem{kind: itemPlan, text: snapshot})
@@ -446,7 +680,7 @@ func (m *model) rebuildRenderer(wrap int) error {
 		wrap = 10
 	}
 	r, err := glam.NewTermRenderer(
-		glam.WithStylePath("dark"), // fixed style to avoid OSC queries
+		glam.WithStylePath(theme.Glamour), // fixed style to avoid OSC queries
 		glam.WithWordWrap(wrap),
 	)
 	if err != nil {
@@ -499,6 +733,16 @@ func (m model) Init() tea.Cmd {
 func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 	var cmd tea.Cmd
+	if key, ok := msg.(tea.KeyMsg); ok {
+		if m.handlePlanKey(key) || m.handlePauseKey(key) || m.handleHistoryKey(key) {
+			return m, nil
+		}
+		consumed, cmd := m.handlePickerKey(key)
+		if consumed {
+			return m, nil
+		}
+		cmds = append(cmds, cmd)
+	}
 	m.ta, cmd = m.ta.Update(msg)
 	cmds = append(cmds, cmd)
 	m.spin, cmd = m.spin.Update(msg)
@@ -508,12 +752,9 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.MouseMsg:
-		// With mouse reporting disabled, this case should rarely be reached.
-		// If mouse events do come through (e.g., from some terminals that send
-		// them anyway), we can optionally handle wheel events here.
-		// But normally, mouse events won't be captured when mouse reporting is off.
+		// Mouse reporting is off by default so text can be selected natively;
+		// F2 or /mouse turns it on for wheel scrolling.
 		if msg.Button == tea.MouseButtonWheelUp || msg.Button == tea.MouseButtonWheelDown {
-			// If wheel events somehow come through, handle them for scrolling
 			m.vp, cmd = m.vp.Update(msg)
 			if cmd != nil {
 				cmds = append(cmds, cmd)
@@ -527,35 +768,64 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.requesting || m.streaming || m.busy {
 			m.flashFrame++
 		}
+		m.tickPlanClock()
 	case tea.WindowSizeMsg:
 		m.vp, _ = m.vp.Update(msg)
 		m.width = msg.Width
 		m.height = msg.Height
 		m.recalcLayout()
+		if m.agent != nil {
+			// PTY steps format their output for the visible transcript width.
+			m.agent.SetTerminalSize(m.vp.Width-2, m.vp.Height)
+		}
 		m.ready = true
 		m.refresh()
 		return m, nil
 
 	case tea.KeyMsg:
+		if m.handleHistorySearchKey(msg) || m.handleSearchKey(msg) || m.handleOutputKey(msg) {
+			return m, tea.Batch(cmds...)
+		}
 		// Allow explicit scrolling keys to be handled by the viewport even
 		// while the textarea is focused. We still block the default 'u'/'d'
 		// half-page shortcuts by unbinding them in the viewport keymap.
 		switch msg.Type {
-		case tea.KeyPgUp, tea.KeyPgDown, tea.KeyUp, tea.KeyDown, tea.KeyHome, tea.KeyEnd:
+		case tea.KeyPgUp, tea.KeyPgDown, tea.KeyHome, tea.KeyEnd:
 			m.vp, cmd = m.vp.Update(msg)
 			if cmd != nil {
 				cmds = append(cmds, cmd)
 			}
 			return m, tea.Batch(cmds...)
+		case tea.KeyUp, tea.KeyDown:
+			// Up/Down move the cursor in the input box and recall prompts
+			// (see handleHistoryKey); they scroll only while searching.
+			if m.search.active {
+				m.vp, cmd = m.vp.Update(msg)
+				if cmd != nil {
+					cmds = append(cmds, cmd)
+				}
+			}
+			return m, tea.Batch(cmds...)
 		}
 		// Do NOT pass other raw key events to the viewport; this prevents the
 		// viewport from capturing common typing keys while the user is writing.
+		if msg.Type == tea.KeyEsc && m.busy && m.agent != nil {
+			// Esc stops the running plan; Ctrl+C still exits.
+			m.agent.Cancel("user pressed Esc")
+			return m, nil
+		}
 		if msg.Type == tea.KeyCtrlC || msg.Type == tea.KeyEsc {
 			if m.cancel != nil {
 				m.cancel()
 			}
 			return m, tea.Quit
 		}
+		if m.replay && (msg.Type == tea.KeyCtrlD || (msg.Type == tea.KeyEnter && !msg.Alt)) {
+			if msg.Type == tea.KeyCtrlD {
+				return m, tea.Batch(cmds...)
+			}
+			return m, tea.Batch(append(cmds, m.handleReplayEnter())...)
+		}
 		// Insert newline on Ctrl+J (LF) to emulate Shift+Enter behavior, which
 		// most terminals cannot reliably detect.
 		if msg.Type == tea.KeyCtrlJ {
@@ -567,11 +837,94 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.ta.InsertString("\n")
 			return m, tea.Batch(cmds...)
 		}
+		if msg.Type == tea.KeyCtrlY {
+			return m, tea.Batch(append(cmds, m.copySelection())...)
+		}
+		if msg.Type == tea.KeyF2 {
+			return m, tea.Batch(append(cmds, m.toggleMouse())...)
+		}
+		if msg.Type == tea.KeyCtrlD && m.stdinStep != "" {
+			if err := m.agent.SubmitCommandStdin(m.stdinStep, "", true); err != nil {
+				m.appendLine(lipgloss.NewStyle().Foreground(theme.Error).Render("[stdin] ") + err.Error() + "\n")
+			}
+			return m, tea.Batch(cmds...)
+		}
+		if msg.Type == tea.KeyEnter && m.stdinStep != "" && m.pendingApproval == "" {
+			// Forward the typed line to the interactive step instead of the agent.
+			line := m.ta.Value()
+			if err := m.agent.SubmitCommandStdin(m.stdinStep, line+"\n", false); err != nil {
+				m.appendLine(lipgloss.NewStyle().Foreground(theme.Error).Render("[stdin] ") + err.Error() + "\n")
+			} else {
+				m.appendLiveOutput(m.stdinStep, line+"\n")
+				m.refresh()
+			}
+			m.ta.Reset()
+			return m, tea.Batch(cmds...)
+		}
 		if msg.Type == tea.KeyEnter {
 			prompt := strings.TrimSpace(m.ta.Value())
+			if m.pendingApproval != "" {
+				// Answer the pending approval instead of submitting a prompt.
+				approved := strings.EqualFold(prompt, "y") || strings.EqualFold(prompt, "yes")
+				m.agent.SubmitApproval(m.pendingApproval, approved, prompt)
+				verdict := "rejected"
+				if approved {
+					verdict = "approved"
+				}
+				m.appendLine(lipgloss.NewStyle().Foreground(theme.Warning).Render("[approval] ") + fmt.Sprintf("Step %s %s.\n", m.pendingApproval, verdict))
+				m.pendingApproval = ""
+				m.ta.Reset()
+				return m, tea.Batch(cmds...)
+			}
+			if name, ok := strings.CutPrefix(prompt, "/profile"); ok && (name == "" || name[0] == ' ') {
+				m.switchProfile(strings.TrimSpace(name))
+				m.ta.Reset()
+				return m, tea.Batch(cmds...)
+			}
+			if path, ok := strings.CutPrefix(prompt, "/attach"); ok && (path == "" || path[0] == ' ') {
+				m.handleAttachCommand(strings.TrimSpace(path))
+				m.ta.Reset()
+				return m, tea.Batch(cmds...)
+			}
+			if path, ok := strings.CutPrefix(prompt, "/export"); ok && (path == "" || path[0] == ' ') {
+				m.handleExportCommand(strings.TrimSpace(path))
+				m.ta.Reset()
+				return m, tea.Batch(cmds...)
+			}
+			switch prompt {
+			case "/copy":
+				m.ta.Reset()
+				return m, tea.Batch(append(cmds, m.copySelection())...)
+			case "/mouse":
+				m.ta.Reset()
+				return m, tea.Batch(append(cmds, m.toggleMouse())...)
+			case "/notify":
+				m.toggleNotify()
+				m.ta.Reset()
+				return m, tea.Batch(cmds...)
+			case "/plan":
+				m.showPlan()
+				m.ta.Reset()
+				return m, tea.Batch(cmds...)
+			case "/resume", "/discard":
+				m.handleRecoveryCommand(prompt)
+				m.ta.Reset()
+				return m, tea.Batch(cmds...)
+			}
+			if m.isExitCommand(prompt) {
+				if m.cancel != nil {
+					m.cancel()
+				}
+				return m, tea.Quit
+			}
 			if prompt != "" {
-				m.agent.SubmitPrompt(prompt)
+				if m.history != nil {
+					if err := m.history.add(prompt); err != nil {
+						m.appendLine(lipgloss.NewStyle().Foreground(theme.Error).Render("[history] ") + err.Error() + "\n")
+					}
+				}
 				m.appendUserBlock(prompt)
+				m.agent.SubmitPromptWithAttachments(prompt, m.takeAttachments(prompt))
 				m.ta.Reset()
 				m.requesting = true
 				m.streaming = false
@@ -600,7 +953,20 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, tea.Batch(append(cmds, cmd, waitForEvent(m.outputs))...)
 			}
 			return m, tea.Batch(append(cmds, waitForEvent(m.outputs))...)
+		case runtimepkg.EventTypeReasoningDelta:
+			if !m.streaming {
+				m.streaming = true
+				m.requesting = false
+			}
+			m.busy = true
+			m.currentReasoning.WriteString(evt.Message)
+			m.lastType = evt.Type
+			if cmd := m.scheduleRender(); cmd != nil {
+				return m, tea.Batch(append(cmds, cmd, waitForEvent(m.outputs))...)
+			}
+			return m, tea.Batch(append(cmds, waitForEvent(m.outputs))...)
 		case runtimepkg.EventTypeAssistantMessage:
+			m.flushReasoning()
 			final := m.currentMD.String()
 			m.currentMD.Reset()
 			m.currentRendered = ""
@@ -615,6 +981,8 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.busy = true
 			m.recalcLayout()
 		case runtimepkg.EventTypeStatus:
+			// A response without a message ends its reasoning here.
+			m.flushReasoning()
 			// Update/seed plan step status inline when possible.
 			if evt.Metadata != nil {
 				// If a full plan is included in metadata, load it.
@@ -659,36 +1027,96 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					title, _ := evt.Metadata["title"].(string)
 					m.ensureStep(stepID, title)
 					if st, has := evt.Metadata["status"]; has {
+						m.clearLiveOutput(stepID)
+						m.appendStepOutput(stepID, title, evt.Metadata)
+						if m.stdinStep == stepID {
+							m.stdinStep = ""
+							m.ta.Placeholder = defaultPlaceholder
+						}
+						m.recordStepDuration(stepID, evt.Metadata)
 						m.updateStepStatus(stepID, st)
 					} else {
+						if interactive, _ := evt.Metadata["interactive"].(bool); interactive {
+							m.stdinStep = stepID
+							m.ta.Placeholder = fmt.Sprintf("Input for step %s… (Enter sends a line, Ctrl+D sends EOF)", stepID)
+						}
+						m.recordStepStart(stepID, evt.Metadata)
 						m.updateStepStatus(stepID, "executing")
 					}
 					m.refresh()
 					return m, tea.Batch(append(cmds, waitForEvent(m.outputs))...)
 				}
 			}
+			if paused, ok := evt.Metadata["paused"].(bool); ok {
+				m.paused = paused
+			}
+			if done, _ := evt.Metadata["hands_free_complete"].(bool); done {
+				m.notify("goagent finished", evt.Message)
+			}
 			// Fallback: append status line
-			line := lipgloss.NewStyle().Foreground(lipgloss.Color("244")).Render("[status] ") + evt.Message + "\n"
+			line := lipgloss.NewStyle().Foreground(theme.Muted).Render("[status] ") + evt.Message + "\n"
 			m.appendLine(line)
 		case runtimepkg.EventTypeError:
-			line := lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Bold(true).Render("[error] ") + evt.Message + "\n"
+			line := lipgloss.NewStyle().Foreground(theme.Error).Bold(true).Render("[error] ") + evt.Message + "\n"
+			m.appendLine(line)
+		case runtimepkg.EventTypeCommandOutput:
+			stepID, _ := evt.Metadata["step_id"].(string)
+			m.appendLiveOutput(stepID, evt.Message)
+			m.refresh()
+		case runtimepkg.EventTypeApprovalRequest:
+			stepID, _ := evt.Metadata["step_id"].(string)
+			command, _ := evt.Metadata["command"].(string)
+			m.pendingApproval = stepID
+			line := lipgloss.NewStyle().Foreground(theme.Warning).Bold(true).Render("[approval] ") + evt.Message + "\n"
+			if command = strings.TrimSpace(command); command != "" {
+				line += command + "\n"
+			}
+			line += "Type y to run it, anything else to reject.\n"
+			m.appendLine(line)
+			m.notify("goagent needs approval", evt.Message)
+		case runtimepkg.EventTypeSubagentRequest:
+			line := lipgloss.NewStyle().Foreground(theme.Agent).Bold(true).Render(fmt.Sprintf("[%s asks] ", evt.Agent)) + evt.Message + "\n"
 			m.appendLine(line)
 		case runtimepkg.EventTypeRequestInput:
-			line := lipgloss.NewStyle().Foreground(lipgloss.Color("33")).Render("[input] ") + evt.Message + "\n"
+			m.flushReasoning()
+			line := lipgloss.NewStyle().Foreground(theme.Input).Render("[input] ") + evt.Message + "\n"
 			m.appendLine(line)
+			if m.busy {
+				// Only after a turn; the prompt at startup needs no alert.
+				m.notify("goagent needs your input", evt.Message)
+			}
 			// Ready for user input: clear busy states and stop the bar.
 			m.busy = false
 			m.requesting = false
 			m.streaming = false
 			m.recalcLayout()
+		case runtimepkg.EventTypeWorkspaceChange:
+			m.appendLine(lipgloss.NewStyle().Foreground(theme.Muted).Render("[workspace] ") + evt.Message + "\n")
+		case runtimepkg.EventTypeEnvironment:
+			// The CLI prints the probe summary before the TUI starts.
+		case runtimepkg.EventTypeFileChange:
+			if m.appendFileDiff(evt.Metadata) {
+				m.refresh()
+			} else {
+				m.appendLine(evt.Message + "\n")
+			}
 		default:
 			m.appendLine(evt.Message + "\n")
 		}
 		return m, tea.Batch(append(cmds, waitForEvent(m.outputs))...)
 
+	case filesIndexedMsg:
+		if msg.err == nil {
+			m.workspaceFiles = msg.files
+			if m.picker.active {
+				m.filterPicker()
+			}
+		}
+		return m, nil
+
 	case errMsg:
 		m.vp, _ = m.vp.Update(msg)
-		m.appendLine(lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render("[closed] ") + msg.err.Error() + "\n")
+		m.appendLine(lipgloss.NewStyle().Foreground(theme.Border).Render("[closed] ") + msg.err.Error() + "\n")
 		return m, tea.Tick(2*time.Second, func(time.Time) tea.Msg { return tea.Quit })
 	case renderTick:
 		m.vp, cmd = m.vp.Update(msg)
@@ -704,7 +1132,14 @@ func (m model) View() string {
 	if !m.ready {
 		return "Initializing…"
 	}
-	top := m.border.Render(m.vp.View())
+	view := m.vp.View()
+	if m.picker.active {
+		view = m.overlayPicker(view)
+	}
+	if m.inspect.detail {
+		view = m.overlayStepDetail(view)
+	}
+	top := m.border.Render(view)
 	// Middle status bar: always render a dedicated row (as spaces when inactive)
 	barWidth := m.width
 	if barWidth < 1 {
@@ -719,7 +1154,13 @@ func (m model) View() string {
 		palette = "begin"
 	}
 	var middle string
-	if palette == "none" {
+	if m.historySearch.active {
+		middle = m.historySearchStatus(barWidth)
+	} else if m.search.active {
+		middle = m.searchStatus(barWidth)
+	} else if m.paused {
+		middle = m.pausedStatus(barWidth)
+	} else if palette == "none" {
 		middle = strings.Repeat(" ", barWidth)
 	} else {
 		middle = m.renderGradientBar(barWidth, palette)
@@ -739,34 +1180,15 @@ func (m *model) renderGradientBar(width int, palette string) string {
 	b.Grow(width * 10)
 	// Animate hue offset with frame; wave lightness to get a subtle fade.
 	baseHue := float64((m.flashFrame * 5) % 360)
-	sat := 0.85
-	amp := 0.15
-	char := "█"
-	switch palette {
-	case "begin":
-		// Cooler, softer band while waiting for first token
-		sat = 0.65
-		amp = 0.10
-		char = "▄"
-	case "stream":
-		// Vibrant during streaming
-		sat = 0.90
-		amp = 0.18
-		char = "█"
-	case "work":
-		// In-between turns while tools/plan execute; steady, warmer tones
-		sat = 0.75
-		amp = 0.08
-		char = "▓"
-	}
+	bar := theme.barPalette(palette)
 	for i := 0; i < width; i++ {
 		// Spread hues along the bar and offset over time.
 		hue := math.Mod(baseHue+float64(i*3), 360.0)
 		// Fade using a sine wave across the bar + time.
 		phase := (float64(i)/float64(width))*2*math.Pi + float64(m.flashFrame)/8.0
-		light := 0.50 + amp*math.Sin(phase)
-		hex := hslToHex(hue, sat, light)
-		seg := lipgloss.NewStyle().Foreground(lipgloss.Color(hex)).Render(char)
+		light := bar.Lightness + bar.Amplitude*math.Sin(phase)
+		hex := hslToHex(hue, bar.Saturation, light)
+		seg := lipgloss.NewStyle().Foreground(lipgloss.Color(hex)).Render(bar.Char)
 		b.WriteString(seg)
 	}
 	return b.String()
@@ -817,8 +1239,15 @@ func clamp01(v float64) float64 {
 // Run launches the Bubble Tea TUI with the provided runtime options.
 // Returns a POSIX-style exit code.
 func Run(ctx context.Context, options runtimepkg.RuntimeOptions) int {
+	return RunSession(ctx, options, "")
+}
+
+// RunSession behaves like Run but resumes the conversation stored at
+// sessionPath when the file exists and saves it back when the TUI exits.
+// An empty sessionPath disables persistence.
+func RunSession(ctx context.Context, options runtimepkg.RuntimeOptions, sessionPath string) int {
 	if strings.TrimSpace(options.APIKey) == "" {
-		fmt.Fprintln(os.Stderr, "OPENAI_API_KEY must be set")
+		fmt.Fprintln(os.Stderr, "an API key must be set (OPENAI_API_KEY or ANTHROPIC_API_KEY)")
 		return 1
 	}
 
@@ -829,9 +1258,15 @@ func Run(ctx context.Context, options runtimepkg.RuntimeOptions) int {
 	// Prevent OSC background color queries from contaminating stdin by
 	// explicitly setting color profile and background for lipgloss/termenv.
 	lipgloss.SetColorProfile(termenv.TrueColor)
-	lipgloss.SetHasDarkBackground(true)
+	lipgloss.SetHasDarkBackground(theme.Dark)
 
-	agent, err := runtimepkg.NewRuntime(options)
+	var agent *runtimepkg.Runtime
+	var err error
+	if _, statErr := os.Stat(sessionPath); sessionPath != "" && statErr == nil {
+		agent, err = runtimepkg.NewRuntimeFromSession(sessionPath, options)
+	} else {
+		agent, err = runtimepkg.NewRuntime(options)
+	}
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "failed to create runtime:", err)
 		return 1
@@ -840,11 +1275,39 @@ func Run(ctx context.Context, options runtimepkg.RuntimeOptions) int {
 
 	runCtx, cancel := context.WithCancel(ctx)
 	go func() { _ = agent.Run(runCtx) }()
+	if sessionPath != "" {
+		defer func() {
+			if err := agent.SaveSession(sessionPath); err != nil {
+				fmt.Fprintln(os.Stderr, "failed to save session:", err)
+			}
+		}()
+	}
 
-	// Disable mouse reporting entirely to allow terminal-native text selection.
-	// This means mouse wheel scrolling won't work, but users can still scroll with
-	// keyboard (Page Up/Down, arrow keys) and select text normally with the mouse.
-	p := tea.NewProgram(newModel(agent, outputs, cancel), tea.WithAltScreen())
+	// Start with mouse reporting disabled to allow terminal-native text
+	// selection; users scroll with the keyboard (Page Up/Down, arrow keys) or
+	// turn on mouse capture with F2 or /mouse for wheel scrolling.
+	m := newModel(agent, outputs, cancel)
+	m.exitCommands = options.ExitCommands
+	if notifyOnStart {
+		if m.notifier, err = notify.New(notifyMode, os.Stderr); err != nil {
+			m.appendLine(lipgloss.NewStyle().Foreground(theme.Error).Render("[notify] ") + err.Error() + "\n")
+		}
+	}
+	if plan, err := runtimepkg.LoadInterruptedPlan(options.JournalPath); err != nil {
+		m.appendLine(lipgloss.NewStyle().Foreground(theme.Error).Render("[recovery] ") + err.Error() + "\n")
+	} else if plan != nil {
+		m.offerRecovery(plan)
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		m.history = loadPromptHistory(filepath.Join(cwd, promptHistoryFile))
+		m.workspaceRoot = cwd
+	}
+	opts := []tea.ProgramOption{tea.WithAltScreen()}
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice == 0 {
+		// Stdin was piped into the prompt; read keys from the terminal.
+		opts = append(opts, tea.WithInputTTY())
+	}
+	p := tea.NewProgram(m, opts...)
 	if _, err := p.Run(); err != nil {
 		fmt.Fprintln(os.Stderr, "tui error:", err)
 		return 1
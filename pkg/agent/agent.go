@@ -0,0 +1,102 @@
+package agent
+
+import "github.com/asynkron/goagent/internal/core/runtime"
+
+// APIVersion is the semantic version of the contract described by this
+// package. Additive changes bump the minor version; breaking changes bump the
+// major version together with the module path.
+const APIVersion = "1.0.0"
+
+// Runtime runs the plan/execute loop. Feed it prompts with SubmitPrompt or
+// Inputs and read what happens from Outputs.
+type Runtime = runtime.Runtime
+
+// Options configures a Runtime. The zero value plus an APIKey (or a
+// ProviderClient) is a working configuration.
+type Options = runtime.RuntimeOptions
+
+// New validates options and builds a Runtime. Call Run to start it.
+func New(options Options) (*Runtime, error) {
+	return runtime.NewRuntime(options)
+}
+
+// NewFromSession builds a Runtime from options and restores the conversation
+// and plan saved by Runtime.SaveSession.
+func NewFromSession(path string, options Options) (*Runtime, error) {
+	return runtime.NewRuntimeFromSession(path, options)
+}
+
+// Attachment is a file sent to the model with a prompt.
+type Attachment = runtime.Attachment
+
+// LoadAttachment reads path into an Attachment, detecting images by content.
+func LoadAttachment(path string) (Attachment, error) {
+	return runtime.LoadAttachment(path)
+}
+
+// ChatMessage is one entry of the conversation history.
+type ChatMessage = runtime.ChatMessage
+
+// MessageRole identifies the author of a ChatMessage.
+type MessageRole = runtime.MessageRole
+
+// Message roles.
+const (
+	RoleSystem    = runtime.RoleSystem
+	RoleUser      = runtime.RoleUser
+	RoleAssistant = runtime.RoleAssistant
+	RoleTool      = runtime.RoleTool
+)
+
+// TranscriptFormat selects the output of Runtime.ExportTranscript.
+type TranscriptFormat = runtime.TranscriptFormat
+
+// Transcript formats.
+const (
+	TranscriptMarkdown = runtime.TranscriptMarkdown
+	TranscriptHTML     = runtime.TranscriptHTML
+)
+
+// HistoryStore persists the conversation history.
+type HistoryStore = runtime.HistoryStore
+
+// NewMemoryHistoryStore returns a HistoryStore that keeps the history in
+// memory only.
+func NewMemoryHistoryStore() *runtime.MemoryHistoryStore {
+	return runtime.NewMemoryHistoryStore()
+}
+
+// Budget caps model requests, tokens and spend per session.
+type Budget = runtime.Budget
+
+// BudgetUsage reports what a session has consumed so far.
+type BudgetUsage = runtime.BudgetUsage
+
+// ModelSettings selects the model for Runtime.SwitchModel.
+type ModelSettings = runtime.ModelSettings
+
+// ApprovalPolicy decides which plan steps need host confirmation.
+type ApprovalPolicy = runtime.ApprovalPolicy
+
+// Approval policies.
+const (
+	ApprovalPolicyNever   = runtime.ApprovalPolicyNever
+	ApprovalPolicyOnWrite = runtime.ApprovalPolicyOnWrite
+	ApprovalPolicyAlways  = runtime.ApprovalPolicyAlways
+)
+
+// EnvPolicy controls which environment variables shell steps inherit.
+type EnvPolicy = runtime.EnvPolicy
+
+// Environment policies.
+const (
+	EnvInheritAll       = runtime.EnvInheritAll
+	EnvInheritAllowlist = runtime.EnvInheritAllowlist
+	EnvClean            = runtime.EnvClean
+)
+
+// Supported values for Options.Provider.
+const (
+	ProviderOpenAI    = runtime.ProviderOpenAI
+	ProviderAnthropic = runtime.ProviderAnthropic
+)
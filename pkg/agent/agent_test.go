@@ -0,0 +1,44 @@
+package agent_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/asynkron/goagent/pkg/agent"
+	"github.com/asynkron/goagent/pkg/runtimetest"
+)
+
+func TestNewRunsWithCustomProviderAndInternalCommand(t *testing.T) {
+	t.Parallel()
+
+	var handled []string
+	options := agent.Options{
+		InternalCommands: map[string]agent.InternalCommandHandler{
+			"greet": func(_ context.Context, req agent.InternalCommandRequest) (agent.Observation, error) {
+				handled = append(handled, req.Raw)
+				return agent.Observation{Stdout: "hello"}, nil
+			},
+		},
+	}
+	step := runtimetest.Step("greet", "greet world")
+	step.Command.Shell = agent.InternalCommandShell
+
+	h := runtimetest.New(t, options,
+		runtimetest.PlanCall("call-1", agent.PlanResponse{Message: "Greeting", Plan: []agent.PlanStep{step}}),
+		runtimetest.MessageCall("call-2", "Greeted"),
+	)
+	events := h.Prompt("say hello")
+
+	if len(handled) != 1 || handled[0] != "greet world" {
+		t.Fatalf("expected the internal command to run once, got %v", handled)
+	}
+	var sawCompletion bool
+	for _, evt := range events {
+		if evt.Type == agent.EventTypeAssistantMessage && evt.Message == "Greeted" {
+			sawCompletion = true
+		}
+	}
+	if !sawCompletion {
+		t.Fatalf("expected the final assistant message, got %+v", events)
+	}
+}
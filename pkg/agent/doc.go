@@ -0,0 +1,35 @@
+// Package agent is the supported API for embedding the GoAgent runtime in other
+// Go programs.
+//
+// The runtime itself lives under internal/ and changes freely between releases.
+// This package re-exports the curated subset hosts need: constructing and
+// driving a Runtime, its options, the events it emits, the plan types and the
+// extension points for internal commands, tools, providers and execution
+// backends. The exported types are aliases, so values flow between this
+// package and the runtime without conversion.
+//
+// # Stability
+//
+// The identifiers in this package follow semantic versioning of the module
+// (APIVersion tracks the current contract): within a major version they are
+// not removed or renamed, fields are only added to structs, and the string
+// values of event, input and status constants do not change. Hosts should
+// therefore construct option and event structs with field names and ignore
+// event types they do not recognize. Anything reachable only through the
+// internal packages carries no such promise.
+//
+// A minimal host:
+//
+//	rt, err := agent.New(agent.Options{APIKey: key, DisableInputReader: true, DisableOutputForwarding: true})
+//	if err != nil {
+//		return err
+//	}
+//	go rt.Run(ctx)
+//	rt.SubmitPrompt("Summarize the README")
+//	for evt := range rt.Outputs() {
+//		if evt.Type == agent.EventTypeRequestInput {
+//			break
+//		}
+//		fmt.Println(evt.Type, evt.Message)
+//	}
+package agent
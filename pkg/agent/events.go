@@ -0,0 +1,81 @@
+package agent
+
+import "github.com/asynkron/goagent/internal/core/runtime"
+
+// Event is emitted on Runtime.Outputs.
+type Event = runtime.RuntimeEvent
+
+// EventType distinguishes Events.
+type EventType = runtime.EventType
+
+// Event types. New types may be added in minor releases.
+const (
+	EventTypeStatus           = runtime.EventTypeStatus
+	EventTypeAssistantMessage = runtime.EventTypeAssistantMessage
+	EventTypeAssistantDelta   = runtime.EventTypeAssistantDelta
+	EventTypeReasoningDelta   = runtime.EventTypeReasoningDelta
+	EventTypeError            = runtime.EventTypeError
+	EventTypeRequestInput     = runtime.EventTypeRequestInput
+	EventTypeFileChange       = runtime.EventTypeFileChange
+	EventTypeApprovalRequest  = runtime.EventTypeApprovalRequest
+	EventTypeCommandOutput    = runtime.EventTypeCommandOutput
+	EventTypeSubagentRequest  = runtime.EventTypeSubagentRequest
+	EventTypeWorkspaceChange  = runtime.EventTypeWorkspaceChange
+	EventTypeEnvironment      = runtime.EventTypeEnvironment
+)
+
+// EventFilter selects the events of a Runtime.Subscribe subscription and how
+// it buffers them.
+type EventFilter = runtime.EventFilter
+
+// OverflowPolicy decides what a subscription does when its buffer is full.
+type OverflowPolicy = runtime.OverflowPolicy
+
+// Overflow policies.
+const (
+	OverflowDropNewest = runtime.OverflowDropNewest
+	OverflowDropOldest = runtime.OverflowDropOldest
+	OverflowBlock      = runtime.OverflowBlock
+)
+
+// StatusLevel grades status and error events.
+type StatusLevel = runtime.StatusLevel
+
+// Status levels.
+const (
+	StatusLevelInfo  = runtime.StatusLevelInfo
+	StatusLevelWarn  = runtime.StatusLevelWarn
+	StatusLevelError = runtime.StatusLevelError
+)
+
+// Input is pushed into Runtime.Inputs.
+type Input = runtime.InputEvent
+
+// InputType distinguishes Inputs.
+type InputType = runtime.InputEventType
+
+// Input types.
+const (
+	InputTypePrompt           = runtime.InputTypePrompt
+	InputTypeCancel           = runtime.InputTypeCancel
+	InputTypeShutdown         = runtime.InputTypeShutdown
+	InputTypeApprovalDecision = runtime.InputTypeApprovalDecision
+	InputTypeCommandStdin     = runtime.InputTypeCommandStdin
+)
+
+// FileChange describes a file touched by a plan step, as carried by
+// EventTypeFileChange.
+type FileChange = runtime.FileChange
+
+// ApplyPatchResult is the "data" metadata of an apply_patch step: the lines
+// added and removed per file and in total.
+type ApplyPatchResult = runtime.ApplyPatchResult
+
+// ApplyPatchFile is one file of an ApplyPatchResult.
+type ApplyPatchFile = runtime.ApplyPatchFile
+
+// MarshalEventJSON encodes evt as the single JSON line written by the JSONL
+// output format.
+func MarshalEventJSON(evt Event) []byte {
+	return runtime.MarshalEventJSON(evt)
+}
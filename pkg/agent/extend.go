@@ -0,0 +1,144 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/asynkron/goagent/internal/core/runtime"
+)
+
+// InternalCommandHandler runs an agent scoped command in-process instead of
+// the host shell. Register handlers in Options.InternalCommands, keyed by
+// command name.
+type InternalCommandHandler = runtime.InternalCommandHandler
+
+// InternalCommandShell is the CommandDraft.Shell value of plan steps that run
+// internal commands.
+const InternalCommandShell = runtime.AgentShell
+
+// InternalCommandRequest is the parsed invocation passed to an
+// InternalCommandHandler.
+type InternalCommandRequest = runtime.InternalCommandRequest
+
+// ToolSpec registers a host function tool offered to the model next to the
+// plan tool (Options.Tools).
+type ToolSpec = runtime.ToolSpec
+
+// ToolHandler runs a ToolSpec with arguments validated against its schema.
+type ToolHandler = runtime.ToolHandler
+
+// Hooks observe and shape plan requests and step execution (Options.Hooks).
+type Hooks = runtime.Hooks
+
+// ChainHooks combines hooks so each callback runs those of all of them in
+// order.
+func ChainHooks(hooks ...Hooks) Hooks {
+	return runtime.ChainHooks(hooks...)
+}
+
+// Provider requests plans from a model backend (Options.ProviderClient).
+type Provider = runtime.Provider
+
+// ToolCall is the tool invocation returned by a Provider.
+type ToolCall = runtime.ToolCall
+
+// ReasoningHandler returns the function a Provider streams reasoning to, or
+// nil; the runtime emits what it receives as EventTypeReasoningDelta.
+func ReasoningHandler(ctx context.Context) func(string) {
+	return runtime.ReasoningHandler(ctx)
+}
+
+// ExecutionBackend builds the process for a shell step
+// (Options.ExecutionBackend).
+type ExecutionBackend = runtime.ExecutionBackend
+
+// ContainerBackend runs shell steps inside a container.
+type ContainerBackend = runtime.ContainerBackend
+
+// CommandRunner produces shell step observations without starting processes
+// (Options.CommandRunner).
+type CommandRunner = runtime.CommandRunner
+
+// CommandPolicy allows, denies or gates plan steps before they run.
+type CommandPolicy = runtime.CommandPolicy
+
+// CommandRule is one rule of a CommandPolicy.
+type CommandRule = runtime.CommandRule
+
+// DefaultCommandPolicy returns the policy used when Options.CommandPolicy is
+// nil.
+func DefaultCommandPolicy() *CommandPolicy {
+	return runtime.DefaultCommandPolicy()
+}
+
+// Logger receives structured logs (Options.Logger).
+type Logger = runtime.Logger
+
+// LogField is a structured logging attribute.
+type LogField = runtime.LogField
+
+// Metrics collects runtime metrics (Options.Metrics).
+type Metrics = runtime.Metrics
+
+// Tracer receives spans for passes, model requests and steps
+// (Options.Tracer).
+type Tracer = runtime.Tracer
+
+// Span is an in-flight Tracer span.
+type Span = runtime.Span
+
+// LanguageServer configures a language server whose diagnostics are appended
+// to apply_patch observations (Options.LanguageServers).
+type LanguageServer = runtime.LanguageServer
+
+// Diagnostic is a problem a language server reported for an edited file.
+type Diagnostic = runtime.Diagnostic
+
+// WebFetchOptions configures the fetch_url internal command
+// (Options.WebFetch).
+type WebFetchOptions = runtime.WebFetchOptions
+
+// FetchResult is the structured result of the fetch_url internal command.
+type FetchResult = runtime.FetchResult
+
+// WebSearchOptions configures the web_search internal command
+// (Options.WebSearch).
+type WebSearchOptions = runtime.WebSearchOptions
+
+// SearchProvider queries a web search API for web_search.
+type SearchProvider = runtime.SearchProvider
+
+// WebSearchResult is one hit returned by a SearchProvider.
+type WebSearchResult = runtime.WebSearchResult
+
+// SearchProviderConfig selects a built-in SearxNG, Brave or Bing provider.
+type SearchProviderConfig = runtime.SearchProviderConfig
+
+// NewSearchProvider returns the built-in provider named by cfg.Name.
+func NewSearchProvider(cfg SearchProviderConfig) (SearchProvider, error) {
+	return runtime.NewSearchProvider(cfg)
+}
+
+// Memory is a fact the agent remembered with the remember internal command.
+type Memory = runtime.Memory
+
+// MemoryStore reads and writes the memory file (Options.MemoryPath), e.g. for
+// hosts that let users edit memories.
+type MemoryStore = runtime.MemoryStore
+
+// NewMemoryStore returns a store backed by the JSON file at path.
+func NewMemoryStore(path string) *MemoryStore {
+	return runtime.NewMemoryStore(path)
+}
+
+// SemanticSearchOptions configures the semantic_search internal command
+// (Options.SemanticSearch).
+type SemanticSearchOptions = runtime.SemanticSearchOptions
+
+// Embedder turns texts into embedding vectors for semantic_search.
+type Embedder = runtime.Embedder
+
+// OpenAIEmbedder calls an OpenAI compatible /embeddings endpoint.
+type OpenAIEmbedder = runtime.OpenAIEmbedder
+
+// SemanticSearchResult is the structured result of semantic_search.
+type SemanticSearchResult = runtime.SemanticSearchResult
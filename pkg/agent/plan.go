@@ -0,0 +1,38 @@
+package agent
+
+import "github.com/asynkron/goagent/internal/core/runtime"
+
+// PlanResponse is the structured answer the model returns on every pass.
+type PlanResponse = runtime.PlanResponse
+
+// PlanStep is one command of a plan.
+type PlanStep = runtime.PlanStep
+
+// CommandDraft is the shell command of a PlanStep.
+type CommandDraft = runtime.CommandDraft
+
+// PlanStatus is the execution status of a PlanStep.
+type PlanStatus = runtime.PlanStatus
+
+// Plan step statuses.
+const (
+	PlanPending   = runtime.PlanPending
+	PlanCompleted = runtime.PlanCompleted
+	PlanFailed    = runtime.PlanFailed
+	PlanAbandoned = runtime.PlanAbandoned
+	PlanCancelled = runtime.PlanCancelled
+)
+
+// PlanObservation is recorded on a PlanStep once it ran.
+type PlanObservation = runtime.PlanObservation
+
+// Observation is the outcome of running a step, as returned by internal
+// commands and command runners.
+type Observation = runtime.PlanObservationPayload
+
+// PlanSnapshot is the progress of the executing plan, from
+// Runtime.PlanSnapshot.
+type PlanSnapshot = runtime.PlanSnapshot
+
+// PlanStepProgress is the progress of one step within a PlanSnapshot.
+type PlanStepProgress = runtime.PlanStepProgress
@@ -1,10 +1,12 @@
 package patch
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io/fs"
+	"slices"
 	"strings"
 	"unicode"
 )
@@ -12,6 +14,7 @@ import (
 type workspace interface {
 	Ensure(path string, create bool) (*state, error)
 	Delete(path string) error
+	MakeDir(path string) error
 	Commit() ([]Result, error)
 }
 
@@ -29,6 +32,64 @@ type state struct {
 	isNew                   bool
 	movePath                string
 	options                 Options
+	// reported accumulates hunk statuses across every operation touching
+	// the file and ends up in its Result.
+	reported []HunkStatus
+	// large marks files above normalizeLimit, which are matched exactly even
+	// when whitespace is ignored.
+	large bool
+	// mode is the permission set requested by the patch, zero if none.
+	mode fs.FileMode
+}
+
+// targetMode is the mode a written file ends up with: the mode requested by
+// the patch, else the file's original permissions, else 0644.
+func (s *state) targetMode() fs.FileMode {
+	if s.mode != 0 {
+		return s.mode
+	}
+	mode := s.originalMode & (fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky)
+	if mode&fs.ModePerm == 0 {
+		mode |= 0o644
+	}
+	return mode
+}
+
+const (
+	// normalizeLimit is the file size above which whitespace-insensitive
+	// matching is skipped; it keeps a second normalized copy of every line.
+	normalizeLimit = 4 << 20
+	// binarySniffLength is how much of a file is searched for a NUL byte,
+	// the same heuristic git uses to tell binary files from text.
+	binarySniffLength = 8000
+)
+
+// normalizes reports whether hunks are also matched with whitespace removed.
+func (s *state) normalizes() bool {
+	return s.options.IgnoreWhitespace && !s.large
+}
+
+// checkPatchable refuses files the text matcher cannot handle: binaries and
+// files above Options.MaxFileSize.
+func checkPatchable(rel string, size int64, head []byte, opts Options) error {
+	if opts.MaxFileSize > 0 && size > opts.MaxFileSize {
+		return &Error{
+			Message:      fmt.Sprintf("Refusing to patch %s: %d bytes exceeds the %d byte limit.", rel, size, opts.MaxFileSize),
+			Code:         "FILE_TOO_LARGE",
+			RelativePath: rel,
+		}
+	}
+	if len(head) > binarySniffLength {
+		head = head[:binarySniffLength]
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return &Error{
+			Message:      fmt.Sprintf("Refusing to patch binary file %s.", rel),
+			Code:         "BINARY_FILE",
+			RelativePath: rel,
+		}
+	}
+	return nil
 }
 
 func apply(ctx context.Context, operations []Operation, ws workspace) ([]Result, error) {
@@ -48,6 +109,14 @@ func apply(ctx context.Context, operations []Operation, ws workspace) ([]Result,
 				}
 				return nil, &Error{Message: err.Error()}
 			}
+		case OperationAddDirectory:
+			if err := ws.MakeDir(op.Path); err != nil {
+				var pe *Error
+				if errors.As(err, &pe) {
+					return nil, pe
+				}
+				return nil, &Error{Message: err.Error()}
+			}
 		case OperationUpdate, OperationAdd:
 			state, err := ws.Ensure(op.Path, op.Type == OperationAdd)
 			if err != nil {
@@ -59,15 +128,29 @@ func apply(ctx context.Context, operations []Operation, ws workspace) ([]Result,
 			}
 			state.cursor = 0
 			state.hunkStatuses = nil
+			if op.Type == OperationAdd {
+				// An add without hunks still creates the (empty) file.
+				state.touched = true
+			}
 			for index, hunk := range op.Hunks {
 				if ctx.Err() != nil {
 					return nil, &Error{Message: ctx.Err().Error()}
 				}
 				number := index + 1
+				status := HunkApplied
 				if err := applyHunk(state, hunk); err != nil {
-					return nil, enhanceHunkError(err, state, hunk, number)
+					recovered, rerr := recoverHunk(state, hunk, err)
+					if rerr != nil {
+						return nil, enhanceHunkError(rerr, state, hunk, number)
+					}
+					status = recovered
 				}
-				state.hunkStatuses = append(state.hunkStatuses, HunkStatus{Number: number, Status: "applied"})
+				state.hunkStatuses = append(state.hunkStatuses, HunkStatus{Number: number, Status: status})
+				state.reported = append(state.reported, HunkStatus{Number: len(state.reported) + 1, Status: status})
+				state.touched = true
+			}
+			if op.Mode != 0 {
+				state.mode = op.Mode
 				state.touched = true
 			}
 			trimmedMove := strings.TrimSpace(op.MovePath)
@@ -109,21 +192,9 @@ func applyHunk(state *state, hunk Hunk) error {
 		return nil
 	}
 
-	matchIndex := findSubsequence(state.lines, before, state.cursor, hunk.AtEOF)
-	if matchIndex == -1 {
-		matchIndex = findSubsequence(state.lines, before, 0, hunk.AtEOF)
-	}
-
-	if matchIndex == -1 && state.options.IgnoreWhitespace {
-		normalizedBefore := make([]string, len(before))
-		for i, line := range before {
-			normalizedBefore[i] = normalizeLine(line)
-		}
-		normalizedLines := ensureNormalizedLines(state)
-		matchIndex = findSubsequence(normalizedLines, normalizedBefore, state.cursor, hunk.AtEOF)
-		if matchIndex == -1 {
-			matchIndex = findSubsequence(normalizedLines, normalizedBefore, 0, hunk.AtEOF)
-		}
+	matchIndex, mismatch := locateHunk(state, hunk)
+	if matchIndex == -1 && mismatch != nil {
+		return mismatch
 	}
 
 	if matchIndex == -1 {
@@ -146,6 +217,244 @@ func applyHunk(state *state, hunk Hunk) error {
 	return nil
 }
 
+// locateHunk finds where the hunk's before lines sit, searching from the
+// cursor first and then from the top, exactly and then (when enabled) with
+// whitespace removed. Matches failing the context check are skipped; the first
+// such failure is returned when no match passes.
+func locateHunk(state *state, hunk Hunk) (int, *Error) {
+	var mismatch *Error
+	search := func(haystack, needle []string, start int) int {
+		for i := start; i <= len(haystack); i++ {
+			i = findSubsequence(haystack, needle, i, hunk.AtEOF)
+			if i == -1 {
+				return -1
+			}
+			err := verifyContext(state, hunk, i)
+			if err == nil {
+				return i
+			}
+			if mismatch == nil {
+				mismatch = err
+			}
+		}
+		return -1
+	}
+
+	before := hunk.Before
+	matchIndex := search(state.lines, before, state.cursor)
+	if matchIndex == -1 {
+		matchIndex = search(state.lines, before, 0)
+	}
+
+	if matchIndex == -1 && state.normalizes() {
+		normalizedBefore := make([]string, len(before))
+		for i, line := range before {
+			normalizedBefore[i] = normalizeLine(line)
+		}
+		normalizedLines := ensureNormalizedLines(state)
+		matchIndex = search(normalizedLines, normalizedBefore, state.cursor)
+		if matchIndex == -1 {
+			matchIndex = search(normalizedLines, normalizedBefore, 0)
+		}
+	}
+	return matchIndex, mismatch
+}
+
+// verifyContext enforces Options.RequireContext for a hunk matched at index:
+// up to N of the hunk's leading and trailing context lines must be present
+// and equal the file byte for byte, even when whitespace is ignored. Fewer
+// context lines are accepted only where the file itself starts or ends.
+func verifyContext(state *state, hunk Hunk, index int) *Error {
+	required := state.options.RequireContext
+	if required <= 0 {
+		return nil
+	}
+	before := hunk.Before
+	leading := 0
+	for leading < len(before) && leading < len(hunk.After) && before[leading] == hunk.After[leading] {
+		leading++
+	}
+	trailing := 0
+	for trailing < len(before)-leading && trailing < len(hunk.After)-leading &&
+		before[len(before)-1-trailing] == hunk.After[len(hunk.After)-1-trailing] {
+		trailing++
+	}
+
+	// A trailing empty element is the file's final newline, not a line.
+	available := len(state.lines)
+	if available > 0 && state.lines[available-1] == "" {
+		available--
+	}
+	end := index + len(before)
+	mismatch := func(format string, args ...any) *Error {
+		return &Error{
+			Message:      fmt.Sprintf("Context check failed in %s: ", state.relativePath) + fmt.Sprintf(format, args...),
+			Code:         "CONTEXT_MISMATCH",
+			RelativePath: state.relativePath,
+		}
+	}
+
+	if want := min(required, index+leading); leading < want {
+		return mismatch("hunk has %d leading context lines, %d required.", leading, want)
+	}
+	if want := min(required, max(0, available-end)+trailing); trailing < want {
+		return mismatch("hunk has %d trailing context lines, %d required.", trailing, want)
+	}
+	for j := 0; j < min(required, leading); j++ {
+		if state.lines[index+j] != before[j] {
+			return mismatch("leading context line %d diverged: expected %q, found %q.", j+1, before[j], state.lines[index+j])
+		}
+	}
+	for j := 0; j < min(required, trailing); j++ {
+		offset := len(before) - 1 - j
+		if state.lines[index+offset] != before[offset] {
+			return mismatch("trailing context line %d diverged: expected %q, found %q.", j+1, before[offset], state.lines[index+offset])
+		}
+	}
+	return nil
+}
+
+// recoverHunk handles a hunk applyHunk could not place: it is skipped when
+// already applied, merged with the drifted lines, or written as a conflict,
+// as the options allow, in that order. Otherwise the error is returned.
+func recoverHunk(state *state, hunk Hunk, err error) (string, error) {
+	if !isHunkNotFound(err) {
+		return "", err
+	}
+	if state.options.AllowAlreadyApplied && skipAppliedHunk(state, hunk) {
+		return HunkAlreadyApplied, nil
+	}
+	if state.options.Merge {
+		status, conflict := mergeHunk(state, hunk)
+		if conflict != nil {
+			return "", conflict
+		}
+		if status != "" {
+			return status, nil
+		}
+	}
+	if state.options.BestEffort {
+		writeConflict(state, hunk)
+		return HunkConflict, nil
+	}
+	return "", err
+}
+
+// skipAppliedHunk reports whether the hunk's after lines are already in the
+// file where the hunk belongs and, if so, moves the cursor past them. The
+// match must overlap the window nearestHunkLocation picks for the before
+// lines, so the same text elsewhere in the file does not count, and the hunk
+// needs context lines to anchor it. Hunks that only delete lines or change
+// nothing are never treated as applied.
+func skipAppliedHunk(state *state, hunk Hunk) bool {
+	if len(hunk.After) == 0 || slices.Equal(hunk.Before, hunk.After) || !hasContextLines(hunk) {
+		return false
+	}
+	near, length := nearestHunkLocation(state.lines, hunk.Before, state.cursor)
+	expected := func(index int) bool {
+		return index < near+max(length, 1) && near < index+len(hunk.After)
+	}
+	index := findSubsequenceWhere(state.lines, hunk.After, hunk.AtEOF, expected)
+	if index == -1 && state.normalizes() {
+		normalizedAfter := make([]string, len(hunk.After))
+		for i, line := range hunk.After {
+			normalizedAfter[i] = normalizeLine(line)
+		}
+		index = findSubsequenceWhere(ensureNormalizedLines(state), normalizedAfter, hunk.AtEOF, expected)
+	}
+	if index == -1 {
+		return false
+	}
+	state.cursor = index + len(hunk.After)
+	return true
+}
+
+// hasContextLines reports whether the hunk starts or ends with lines it
+// keeps.
+func hasContextLines(hunk Hunk) bool {
+	return len(hunk.Before) > 0 && (hunk.Before[0] == hunk.After[0] || hunk.Before[len(hunk.Before)-1] == hunk.After[len(hunk.After)-1])
+}
+
+// findSubsequenceWhere returns the first occurrence of needle whose index
+// accept allows, or -1.
+func findSubsequenceWhere(haystack, needle []string, requireEOF bool, accept func(int) bool) int {
+	for i := findSubsequence(haystack, needle, 0, requireEOF); i != -1; i = findSubsequence(haystack, needle, i+1, requireEOF) {
+		if accept(i) {
+			return i
+		}
+	}
+	return -1
+}
+
+// isHunkNotFound reports whether err is a hunk that could not be placed, as
+// opposed to a failure of the workspace itself.
+func isHunkNotFound(err error) bool {
+	var pe *Error
+	return errors.As(err, &pe) && (pe.Code == "HUNK_NOT_FOUND" || pe.Code == "CONTEXT_MISMATCH")
+}
+
+// Conflict markers written around hunks that could not be located or merged
+// in best-effort mode.
+const (
+	conflictOursMarker  = "<<<<<<< ours"
+	conflictSplitMarker = "======="
+	conflictPatchMarker = ">>>>>>> patch"
+)
+
+// writeConflict replaces the region that most resembles the hunk's context
+// with merge-style conflict markers holding the current lines ("ours") and
+// the hunk's replacement ("patch"), leaving the resolution to the user.
+func writeConflict(state *state, hunk Hunk) {
+	start, length := nearestHunkLocation(state.lines, hunk.Before, state.cursor)
+	block := make([]string, 0, length+len(hunk.After)+3)
+	block = append(block, conflictOursMarker)
+	block = append(block, state.lines[start:start+length]...)
+	block = append(block, conflictSplitMarker)
+	block = append(block, hunk.After...)
+	block = append(block, conflictPatchMarker)
+	state.lines = splice(state.lines, start, length, block)
+	updateNormalizedLines(state, start, length, block)
+	state.cursor = start + len(block)
+}
+
+// nearestHunkLocation returns the window of lines sharing the most lines with
+// before, compared without whitespace. Ties go to the window closest to the
+// cursor, which is also where the conflict lands when nothing matches.
+func nearestHunkLocation(lines, before []string, cursor int) (int, int) {
+	// A trailing empty element is the file's final newline, not a line.
+	available := len(lines)
+	if available > 0 && lines[available-1] == "" {
+		available--
+	}
+	length := min(len(before), available)
+	cursor = max(0, min(cursor, available-length))
+	normalizedBefore := make([]string, len(before))
+	for i, line := range before {
+		normalizedBefore[i] = normalizeLine(line)
+	}
+
+	best, bestScore := cursor, 0
+	for i := 0; i+length <= available && length > 0; i++ {
+		score := 0
+		for j := 0; j < length; j++ {
+			if normalizeLine(lines[i+j]) == normalizedBefore[j] {
+				score++
+			}
+		}
+		if score > bestScore || (score == bestScore && score > 0 && abs(i-cursor) < abs(best-cursor)) {
+			best, bestScore = i, score
+		}
+	}
+	return best, length
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
 func splice(target []string, index, deleteCount int, replacement []string) []string {
 	if deleteCount == 0 && len(replacement) == 0 {
 		return target
@@ -202,7 +511,7 @@ func ensureNormalizedLines(state *state) []string {
 	if state == nil {
 		return nil
 	}
-	if !state.options.IgnoreWhitespace {
+	if !state.normalizes() {
 		return state.lines
 	}
 	if state.normalizedLines != nil {
@@ -217,7 +526,7 @@ func ensureNormalizedLines(state *state) []string {
 }
 
 func updateNormalizedLines(state *state, index, deleteCount int, replacement []string) {
-	if state == nil || !state.options.IgnoreWhitespace {
+	if state == nil || !state.normalizes() {
 		return
 	}
 	normalized := ensureNormalizedLines(state)
@@ -253,7 +562,11 @@ func enhanceHunkError(err error, state *state, hunk Hunk, number int) *Error {
 	if pe != nil && len(pe.HunkStatuses) > 0 {
 		statuses = append(statuses, pe.HunkStatuses...)
 	}
-	statuses = append(statuses, HunkStatus{Number: number, Status: "no-match"})
+	failed := HunkNoMatch
+	if pe.Code == "MERGE_CONFLICT" {
+		failed = HunkConflict
+	}
+	statuses = append(statuses, HunkStatus{Number: number, Status: failed})
 	pe.HunkStatuses = statuses
 
 	if pe.Code == "" {
@@ -280,22 +593,39 @@ func describeHunkStatuses(statuses []HunkStatus) string {
 	if len(statuses) == 0 {
 		return ""
 	}
-	var applied []string
+	var applied, merged, skipped []string
 	var failed string
 	for _, status := range statuses {
-		if status.Status == "applied" {
+		switch status.Status {
+		case HunkApplied:
 			applied = append(applied, fmt.Sprintf("%d", status.Number))
 			continue
+		case HunkMerged:
+			merged = append(merged, fmt.Sprintf("%d", status.Number))
+			continue
+		case HunkAlreadyApplied:
+			skipped = append(skipped, fmt.Sprintf("%d", status.Number))
+			continue
 		}
 		if failed == "" {
-			failed = fmt.Sprintf("No match for hunk %d.", status.Number)
+			if status.Status == HunkConflict {
+				failed = fmt.Sprintf("Hunk %d conflicts with changes in the file.", status.Number)
+			} else {
+				failed = fmt.Sprintf("No match for hunk %d.", status.Number)
+			}
 		}
 	}
 
-	parts := make([]string, 0, 2)
+	parts := make([]string, 0, 4)
 	if len(applied) > 0 {
 		parts = append(parts, fmt.Sprintf("Hunks applied: %s.", strings.Join(applied, ", ")))
 	}
+	if len(merged) > 0 {
+		parts = append(parts, fmt.Sprintf("Hunks merged: %s.", strings.Join(merged, ", ")))
+	}
+	if len(skipped) > 0 {
+		parts = append(parts, fmt.Sprintf("Hunks already applied: %s.", strings.Join(skipped, ", ")))
+	}
 	if failed != "" {
 		parts = append(parts, failed)
 	}
@@ -313,7 +643,24 @@ func FormatError(err *Error) string {
 		message = "Unknown error occurred."
 	}
 	code := err.Code
-	if code == "HUNK_NOT_FOUND" || strings.Contains(strings.ToLower(message), "hunk not found") {
+	if code == "MERGE_CONFLICT" {
+		// Only the conflicting region is shown; the rest of the file did
+		// not stand in the way.
+		parts := []string{message}
+		if summary := describeHunkStatuses(err.HunkStatuses); summary != "" {
+			parts = append(parts, "", summary)
+		}
+		if err.FailedHunk != nil && len(err.FailedHunk.RawPatchLines) > 0 {
+			parts = append(parts, "", "Offending hunk:")
+			parts = append(parts, strings.Join(err.FailedHunk.RawPatchLines, "\n"))
+		}
+		if len(err.Conflict) > 0 {
+			parts = append(parts, "", "Merged lines (ours is the file, patch is the hunk):")
+			parts = append(parts, strings.Join(err.Conflict, "\n"))
+		}
+		return strings.Join(parts, "\n")
+	}
+	if code == "HUNK_NOT_FOUND" || code == "CONTEXT_MISMATCH" || strings.Contains(strings.ToLower(message), "hunk not found") {
 		relativePath := err.RelativePath
 		if relativePath == "" {
 			relativePath = "unknown file"
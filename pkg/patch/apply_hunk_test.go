@@ -1,6 +1,9 @@
 package patch
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestApplyHunkInsertsAtEnd(t *testing.T) {
 	t.Parallel()
@@ -70,3 +73,63 @@ func TestSplice(t *testing.T) {
 		t.Fatalf("unexpected splice result: %#v", got)
 	}
 }
+
+func TestApplyHunkRequireContextSkipsWrongCopy(t *testing.T) {
+	t.Parallel()
+
+	st := &state{
+		relativePath: "repeat.go",
+		lines:        []string{"func a() {", "\treturn 1", "}", "func b() {", "\treturn 1", "}", ""},
+		options:      Options{RequireContext: 1},
+	}
+	hunk := Hunk{
+		Before: []string{"func b() {", "\treturn 1", "}"},
+		After:  []string{"func b() {", "\treturn 2", "}"},
+	}
+	if err := applyHunk(st, hunk); err != nil {
+		t.Fatalf("applyHunk returned error: %v", err)
+	}
+	if st.lines[1] != "\treturn 1" || st.lines[4] != "\treturn 2" {
+		t.Fatalf("patched the wrong copy: %#v", st.lines)
+	}
+
+	short := Hunk{Before: []string{"\treturn 1"}, After: []string{"\treturn 3"}}
+	err := applyHunk(st, short)
+	perr, ok := err.(*Error)
+	if !ok || perr.Code != "CONTEXT_MISMATCH" {
+		t.Fatalf("expected CONTEXT_MISMATCH for a hunk without context, got %#v", err)
+	}
+}
+
+func TestApplyHunkRequireContextReportsDivergingLine(t *testing.T) {
+	t.Parallel()
+
+	st := &state{
+		relativePath: "spaces.txt",
+		lines:        []string{"a", "key =  1", "end"},
+		options:      Options{IgnoreWhitespace: true, RequireContext: 1},
+	}
+	hunk := Hunk{
+		Before: []string{"a", "key = 1", "end"},
+		After:  []string{"a", "key = 2", "end"},
+	}
+	if err := applyHunk(st, hunk); err != nil {
+		t.Fatalf("changed lines may still differ in whitespace: %v", err)
+	}
+
+	st.lines = []string{"x", "a  ", "key = 2", "end", "y"}
+	st.normalizedLines = nil
+	st.cursor = 0
+	hunk = Hunk{
+		Before: []string{"a", "key = 2", "end"},
+		After:  []string{"a", "key = 3", "end"},
+	}
+	err := applyHunk(st, hunk)
+	perr, ok := err.(*Error)
+	if !ok || perr.Code != "CONTEXT_MISMATCH" {
+		t.Fatalf("expected CONTEXT_MISMATCH, got %#v", err)
+	}
+	if !strings.Contains(perr.Message, `leading context line 1 diverged: expected "a", found "a  "`) {
+		t.Fatalf("unexpected message: %s", perr.Message)
+	}
+}
@@ -9,9 +9,10 @@ import (
 )
 
 type stubWorkspace struct {
-	ensureFunc func(path string, create bool) (*state, error)
-	deleteFunc func(path string) error
-	commitFunc func() ([]Result, error)
+	ensureFunc  func(path string, create bool) (*state, error)
+	deleteFunc  func(path string) error
+	makeDirFunc func(path string) error
+	commitFunc  func() ([]Result, error)
 }
 
 func (s *stubWorkspace) Ensure(path string, create bool) (*state, error) {
@@ -28,6 +29,13 @@ func (s *stubWorkspace) Delete(path string) error {
 	return errors.New("unexpected Delete call")
 }
 
+func (s *stubWorkspace) MakeDir(path string) error {
+	if s.makeDirFunc != nil {
+		return s.makeDirFunc(path)
+	}
+	return errors.New("unexpected MakeDir call")
+}
+
 func (s *stubWorkspace) Commit() ([]Result, error) {
 	if s.commitFunc != nil {
 		return s.commitFunc()
@@ -0,0 +1,246 @@
+package patch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContextLines is how many unchanged lines surround each generated hunk,
+// as in `diff -u`.
+const diffContextLines = 3
+
+// Diff returns an update operation that turns oldContent into newContent.
+// Hunks carry three lines of context and are located by content like any
+// other hunk, so the operation applies to the old content with Apply* and
+// renders with FormatOperations. Identical contents yield an operation
+// without hunks.
+//
+// Lines are compared without their terminators. Applying a patch keeps a
+// file's trailing newline as it was, so a change in only the final newline
+// produces no hunk.
+func Diff(oldContent, newContent, path string) Operation {
+	op := Operation{Type: OperationUpdate, Path: path}
+	edits := diffLines(splitDocument(oldContent), splitDocument(newContent))
+
+	var changes []int
+	for i, e := range edits {
+		if e.kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+	for len(changes) > 0 {
+		first := changes[0]
+		last := first
+		for len(changes) > 0 && changes[0]-last <= 2*diffContextLines+1 {
+			last = changes[0]
+			changes = changes[1:]
+		}
+		start := max(0, first-diffContextLines)
+		end := min(len(edits), last+1+diffContextLines)
+		lines := make([]string, 0, end-start+1)
+		for _, e := range edits[start:end] {
+			lines = append(lines, string(e.kind)+e.line)
+		}
+		if end == len(edits) {
+			// Anchoring the last hunk keeps it off earlier copies of the same
+			// lines.
+			lines = append(lines, "*** End of File")
+		}
+		// The lines are well formed, so parseHunk cannot fail.
+		hunk, _ := parseHunk(lines, path, "@@")
+		op.Hunks = append(op.Hunks, hunk)
+	}
+	return op
+}
+
+// FormatOperations renders operations in the "*** Begin Patch" envelope that
+// Parse reads. Hunks are written from their Lines when present (as for parsed
+// or generated hunks) and otherwise from Before and After.
+func FormatOperations(operations []Operation) string {
+	var b strings.Builder
+	b.WriteString("*** Begin Patch\n")
+	for _, op := range operations {
+		switch op.Type {
+		case OperationDelete:
+			fmt.Fprintf(&b, "*** Delete File: %s\n", op.Path)
+			continue
+		case OperationAddDirectory:
+			fmt.Fprintf(&b, "*** Add Directory: %s\n", op.Path)
+			continue
+		case OperationAdd:
+			fmt.Fprintf(&b, "*** Add File: %s\n", op.Path)
+		default:
+			fmt.Fprintf(&b, "*** Update File: %s\n", op.Path)
+			if target := strings.TrimSpace(op.MovePath); target != "" {
+				fmt.Fprintf(&b, "*** Move to: %s\n", target)
+			}
+		}
+		if op.Mode != 0 {
+			fmt.Fprintf(&b, "*** File Mode: %04o\n", uint32(op.Mode.Perm()))
+		}
+		for _, hunk := range op.Hunks {
+			if op.Type != OperationAdd {
+				header := hunk.Header
+				if !strings.HasPrefix(header, "@@") {
+					header = "@@"
+				}
+				b.WriteString(header)
+				b.WriteString("\n")
+			}
+			for _, line := range formatHunkLines(hunk) {
+				b.WriteString(line)
+				b.WriteString("\n")
+			}
+		}
+	}
+	b.WriteString("*** End Patch\n")
+	return b.String()
+}
+
+func formatHunkLines(hunk Hunk) []string {
+	if len(hunk.Lines) > 0 {
+		return hunk.Lines
+	}
+	prefix := 0
+	for prefix < len(hunk.Before) && prefix < len(hunk.After) && hunk.Before[prefix] == hunk.After[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(hunk.Before)-prefix && suffix < len(hunk.After)-prefix &&
+		hunk.Before[len(hunk.Before)-1-suffix] == hunk.After[len(hunk.After)-1-suffix] {
+		suffix++
+	}
+	var lines []string
+	for _, line := range hunk.Before[:prefix] {
+		lines = append(lines, " "+line)
+	}
+	for _, line := range hunk.Before[prefix : len(hunk.Before)-suffix] {
+		lines = append(lines, "-"+line)
+	}
+	for _, line := range hunk.After[prefix : len(hunk.After)-suffix] {
+		lines = append(lines, "+"+line)
+	}
+	for _, line := range hunk.Before[len(hunk.Before)-suffix:] {
+		lines = append(lines, " "+line)
+	}
+	if hunk.AtEOF {
+		lines = append(lines, "*** End of File")
+	}
+	return lines
+}
+
+// splitDocument splits content into lines the way the workspaces do, minus
+// the empty element a trailing newline leaves behind.
+func splitDocument(content string) []string {
+	if content == "" {
+		return nil
+	}
+	normalized := strings.ReplaceAll(content, "\r\n", "\n")
+	normalized = strings.ReplaceAll(normalized, "\r", "\n")
+	return strings.Split(strings.TrimSuffix(normalized, "\n"), "\n")
+}
+
+// lineEdit is one step of an edit script: ' ' keeps, '-' removes and '+'
+// inserts line.
+type lineEdit struct {
+	kind byte
+	line string
+}
+
+// diffLines computes a shortest edit script from a to b with Myers' algorithm
+// after trimming the common prefix and suffix.
+func diffLines(a, b []string) []lineEdit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]lineEdit, 0, len(a)+len(b)-prefix-suffix)
+	for _, line := range a[:prefix] {
+		edits = append(edits, lineEdit{' ', line})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, lineEdit{' ', line})
+	}
+	return edits
+}
+
+func myers(a, b []string) []lineEdit {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		edits := make([]lineEdit, 0, n+m)
+		for _, line := range a {
+			edits = append(edits, lineEdit{'-', line})
+		}
+		for _, line := range b {
+			edits = append(edits, lineEdit{'+', line})
+		}
+		return edits
+	}
+
+	offset := n + m
+	v := make([]int, 2*offset+2)
+	var trace [][]int
+search:
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk the trace back from the end, collecting edits in reverse.
+	var reversed []lineEdit
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			reversed = append(reversed, lineEdit{' ', a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, lineEdit{'+', b[y-1]})
+				y--
+			} else {
+				reversed = append(reversed, lineEdit{'-', a[x-1]})
+				x--
+			}
+		}
+	}
+
+	edits := make([]lineEdit, len(reversed))
+	for i, e := range reversed {
+		edits[len(reversed)-1-i] = e
+	}
+	return edits
+}
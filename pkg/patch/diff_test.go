@@ -0,0 +1,114 @@
+package patch
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDiffRoundTripsThroughFormatAndApply(t *testing.T) {
+	t.Parallel()
+
+	var long []string
+	for i := 0; i < 40; i++ {
+		long = append(long, fmt.Sprintf("line %d", i))
+	}
+	edited := append([]string(nil), long...)
+	edited[2] = "changed 2"
+	edited = append(edited[:20], append([]string{"inserted"}, edited[20:]...)...)
+	edited = append(edited[:30], edited[32:]...)
+	edited[len(edited)-1] = "last"
+
+	cases := []struct {
+		name     string
+		old, new string
+	}{
+		{"middle edits", strings.Join(long, "\n") + "\n", strings.Join(edited, "\n") + "\n"},
+		{"append to empty", "", "one\ntwo\n"},
+		{"repeated lines", "x\ny\nx\ny\n", "x\ny\nx\nz\n"},
+		{"remove lines", "a\nb\nc\n", "c\n"},
+		{"crlf", "a\r\nb\r\n", "a\r\nc\r\n"},
+	}
+	for _, tc := range cases {
+		op := Diff(tc.old, tc.new, "doc.txt")
+		formatted := FormatOperations([]Operation{op})
+		parsed, err := Parse(formatted)
+		if err != nil {
+			t.Fatalf("%s: Parse failed: %v\n%s", tc.name, err, formatted)
+		}
+		updated, _, err := ApplyToMemory(ctxBackground(), parsed, map[string]string{"doc.txt": tc.old}, Options{})
+		if err != nil {
+			t.Fatalf("%s: apply failed: %v\n%s", tc.name, err, formatted)
+		}
+		if splitDocumentString(updated["doc.txt"]) != splitDocumentString(tc.new) {
+			t.Fatalf("%s: got %q, want %q\n%s", tc.name, updated["doc.txt"], tc.new, formatted)
+		}
+
+		reversed, err := Reverse(parsed)
+		if err != nil {
+			t.Fatalf("%s: Reverse failed: %v", tc.name, err)
+		}
+		restored, _, err := ApplyMemoryPatch(ctxBackground(), FormatOperations(reversed), updated, Options{})
+		if err != nil {
+			t.Fatalf("%s: reverse apply failed: %v", tc.name, err)
+		}
+		if splitDocumentString(restored["doc.txt"]) != splitDocumentString(tc.old) {
+			t.Fatalf("%s: reverse got %q, want %q", tc.name, restored["doc.txt"], tc.old)
+		}
+	}
+}
+
+func TestDiffGroupsChangesIntoHunks(t *testing.T) {
+	t.Parallel()
+
+	if op := Diff("same\n", "same\n", "a.txt"); len(op.Hunks) != 0 {
+		t.Fatalf("expected no hunks for identical content, got %#v", op.Hunks)
+	}
+
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\n"
+	op := Diff(old, strings.Replace(strings.Replace(old, "b\n", "B\n", 1), "m\n", "M\n", 1), "a.txt")
+	if len(op.Hunks) != 2 {
+		t.Fatalf("expected two hunks, got %d", len(op.Hunks))
+	}
+	want := []string{" a", "-b", "+B", " c", " d", " e"}
+	if strings.Join(op.Hunks[0].Lines, "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected first hunk: %q", op.Hunks[0].Lines)
+	}
+	if !op.Hunks[1].AtEOF || op.Hunks[0].AtEOF {
+		t.Fatalf("expected only the last hunk to be anchored at EOF")
+	}
+}
+
+func TestFormatOperationsWritesEveryDirective(t *testing.T) {
+	t.Parallel()
+
+	operations := []Operation{
+		{Type: OperationAdd, Path: "run.sh", Mode: 0o755, Hunks: []Hunk{{After: []string{"#!/bin/sh"}}}},
+		{Type: OperationUpdate, Path: "a.txt", MovePath: "b.txt", Hunks: []Hunk{{Before: []string{"keep", "old"}, After: []string{"keep", "new"}}}},
+		{Type: OperationDelete, Path: "gone.txt"},
+		{Type: OperationAddDirectory, Path: "assets"},
+	}
+	want := strings.Join([]string{
+		"*** Begin Patch",
+		"*** Add File: run.sh",
+		"*** File Mode: 0755",
+		"+#!/bin/sh",
+		"*** Update File: a.txt",
+		"*** Move to: b.txt",
+		"@@",
+		" keep",
+		"-old",
+		"+new",
+		"*** Delete File: gone.txt",
+		"*** Add Directory: assets",
+		"*** End Patch",
+		"",
+	}, "\n")
+	if got := FormatOperations(operations); got != want {
+		t.Fatalf("unexpected output:\n%s", got)
+	}
+}
+
+func splitDocumentString(content string) string {
+	return strings.Join(splitDocument(content), "\n")
+}
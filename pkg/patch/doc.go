@@ -3,5 +3,7 @@
 // The package is extracted from GoAgent's internal command implementation so that it can be
 // reused by other tools. It exposes primitives to parse patch payloads, apply them to the
 // filesystem, or operate on in-memory documents which makes it straightforward to embed in
-// editors and testing utilities.
+// editors and testing utilities. ApplyFS runs patches against any fs.FS, such as
+// fstest.MapFS, without touching the disk. Diff and FormatOperations go the other way,
+// turning before/after document states into a patch in the same format.
 package patch
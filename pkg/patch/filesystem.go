@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -12,6 +13,13 @@ import (
 
 // ApplyFilesystem applies operations to the OS filesystem.
 func ApplyFilesystem(ctx context.Context, operations []Operation, opts FilesystemOptions) ([]Result, error) {
+	if opts.Reverse {
+		reversed, err := Reverse(operations)
+		if err != nil {
+			return nil, &Error{Message: err.Error()}
+		}
+		operations = reversed
+	}
 	ws, err := newFilesystemWorkspace(opts)
 	if err != nil {
 		return nil, err
@@ -28,11 +36,33 @@ func ApplyFilesystemPatch(ctx context.Context, patchBody string, opts Filesystem
 	return ApplyFilesystem(ctx, operations, opts)
 }
 
+// ApplyFilesystemPatchReader streams a raw patch payload from r and applies it
+// to the filesystem.
+func ApplyFilesystemPatchReader(ctx context.Context, r io.Reader, opts FilesystemOptions) ([]Result, error) {
+	operations, err := ParseReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyFilesystem(ctx, operations, opts)
+}
+
 type filesystemWorkspace struct {
 	options    Options
 	workingDir string
 	states     map[string]*state
 	deletions  []Result
+	// pendingDeletes holds absolute paths whose removal is deferred until
+	// Commit when running in atomic mode.
+	pendingDeletes []string
+	// realDir caches workingDir with symlinks resolved.
+	realDir string
+	// directories reports added directories; pendingDirs holds the ones
+	// whose creation is deferred until Commit in atomic mode.
+	directories []Result
+	pendingDirs []string
+	// createdDirs lists the directories an atomic commit created, parents
+	// first, so a rollback can remove them again.
+	createdDirs []string
 }
 
 func newFilesystemWorkspace(opts FilesystemOptions) (*filesystemWorkspace, error) {
@@ -61,7 +91,7 @@ func (ws *filesystemWorkspace) Ensure(path string, create bool) (*state, error)
 	}
 	if state, ok := ws.states[abs]; ok {
 		state.options = ws.options
-		if ws.options.IgnoreWhitespace {
+		if state.normalizes() {
 			state.normalizedLines = ensureNormalizedLines(state)
 		} else {
 			state.normalizedLines = nil
@@ -93,10 +123,16 @@ func (ws *filesystemWorkspace) Ensure(path string, create bool) (*state, error)
 		if info.IsDir() {
 			return nil, fmt.Errorf("cannot patch directory %s", rel)
 		}
+		if err := checkPatchable(rel, info.Size(), nil, ws.options); err != nil {
+			return nil, err
+		}
 		content, readErr := os.ReadFile(abs)
 		if readErr != nil {
 			return nil, fmt.Errorf("failed to read %s: %v", rel, readErr)
 		}
+		if err := checkPatchable(rel, int64(len(content)), content, ws.options); err != nil {
+			return nil, err
+		}
 		normalized := strings.ReplaceAll(string(content), "\r\n", "\n")
 		normalized = strings.ReplaceAll(normalized, "\r", "\n")
 		lines := strings.Split(normalized, "\n")
@@ -109,8 +145,9 @@ func (ws *filesystemWorkspace) Ensure(path string, create bool) (*state, error)
 			originalEndsWithNewline: &ends,
 			originalMode:            info.Mode(),
 			options:                 ws.options,
+			large:                   len(content) > normalizeLimit,
 		}
-		if ws.options.IgnoreWhitespace {
+		if state.normalizes() {
 			state.normalizedLines = ensureNormalizedLines(state)
 		}
 		ws.states[abs] = state
@@ -145,6 +182,11 @@ func (ws *filesystemWorkspace) Delete(path string) error {
 	if statErr != nil || info.IsDir() {
 		return &Error{Message: fmt.Sprintf("Failed to delete file %s", rel)}
 	}
+	if ws.options.Atomic {
+		ws.pendingDeletes = append(ws.pendingDeletes, abs)
+		ws.deletions = append(ws.deletions, Result{Status: "D", Path: rel})
+		return nil
+	}
 	if err := os.Remove(abs); err != nil {
 		return &Error{Message: fmt.Sprintf("Failed to delete file %s", rel)}
 	}
@@ -152,8 +194,36 @@ func (ws *filesystemWorkspace) Delete(path string) error {
 	return nil
 }
 
+// MakeDir creates a directory (and its parents). An existing directory is
+// left alone and not reported; an existing file is an error.
+func (ws *filesystemWorkspace) MakeDir(path string) error {
+	abs, rel, err := ws.resolvePath(path)
+	if err != nil {
+		return err
+	}
+	info, statErr := os.Stat(abs)
+	switch {
+	case statErr == nil && info.IsDir():
+		return nil
+	case statErr == nil:
+		return &Error{Message: fmt.Sprintf("Cannot add directory %s: a file exists at that path", rel)}
+	case !errors.Is(statErr, fs.ErrNotExist):
+		return fmt.Errorf("failed to stat %s: %v", rel, statErr)
+	}
+	if ws.options.Atomic {
+		ws.pendingDirs = append(ws.pendingDirs, abs)
+	} else if err := os.MkdirAll(abs, 0o755); err != nil {
+		return &Error{Message: fmt.Sprintf("Failed to create directory %s: %v", rel, err)}
+	}
+	ws.directories = append(ws.directories, Result{Status: "A", Path: rel})
+	return nil
+}
+
 func (ws *filesystemWorkspace) Commit() ([]Result, error) {
-	results := append([]Result{}, ws.deletions...)
+	if ws.options.Atomic {
+		return ws.commitAtomic()
+	}
+	results := append(append([]Result{}, ws.deletions...), ws.directories...)
 	for _, state := range ws.states {
 		if !state.touched {
 			continue
@@ -184,22 +254,13 @@ func (ws *filesystemWorkspace) Commit() ([]Result, error) {
 			return nil, &Error{Message: fmt.Sprintf("failed to create directory for %s: %v", displayPath, err)}
 		}
 
-		perm := state.originalMode & fs.ModePerm
-		if perm == 0 {
-			perm = 0o644
-		}
-
-		if err := os.WriteFile(writePath, []byte(newContent), perm); err != nil {
+		desired := state.targetMode()
+		if err := os.WriteFile(writePath, []byte(newContent), desired&fs.ModePerm); err != nil {
 			return nil, &Error{Message: fmt.Sprintf("failed to write %s: %v", displayPath, err)}
 		}
 
-		if state.originalMode != 0 {
-			desired := (state.originalMode & fs.ModePerm) | (state.originalMode & (fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky))
-			if desired == 0 {
-				desired = perm
-			}
-
-			specialBits := state.originalMode & (fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky)
+		if state.originalMode != 0 || state.mode != 0 {
+			specialBits := desired & (fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky)
 			needsChmod := specialBits != 0
 			if !needsChmod {
 				info, statErr := os.Stat(writePath)
@@ -229,11 +290,238 @@ func (ws *filesystemWorkspace) Commit() ([]Result, error) {
 		if state.isNew {
 			status = "A"
 		}
-		results = append(results, Result{Status: status, Path: displayPath})
+		results = append(results, Result{Status: status, Path: displayPath, Hunks: state.reported})
+	}
+	return results, nil
+}
+
+// stagedWrite is a fully written temporary file waiting to be renamed over its
+// destination.
+type stagedWrite struct {
+	tempPath    string
+	writePath   string
+	displayPath string
+	sourcePath  string
+	status      string
+	hunks       []HunkStatus
+}
+
+// fileBackup remembers what a path looked like before an atomic commit touched
+// it so the change can be undone.
+type fileBackup struct {
+	path    string
+	content []byte
+	mode    fs.FileMode
+	existed bool
+}
+
+func (ws *filesystemWorkspace) commitAtomic() ([]Result, error) {
+	var staged []stagedWrite
+	removeTemps := func() {
+		for _, entry := range staged {
+			_ = os.Remove(entry.tempPath)
+		}
+		ws.removeCreatedDirs()
+	}
+
+	for _, state := range ws.states {
+		if !state.touched {
+			continue
+		}
+		entry, err := ws.stageState(state)
+		if err != nil {
+			removeTemps()
+			return nil, err
+		}
+		staged = append(staged, entry)
+	}
+
+	var backups []fileBackup
+	rollback := func() {
+		for i := len(backups) - 1; i >= 0; i-- {
+			restoreBackup(backups[i])
+		}
+	}
+	backup := func(path string) error {
+		entry, err := captureBackup(path)
+		if err != nil {
+			return err
+		}
+		backups = append(backups, entry)
+		return nil
+	}
+	fail := func(index int, err error) ([]Result, error) {
+		for _, entry := range staged[index:] {
+			_ = os.Remove(entry.tempPath)
+		}
+		rollback()
+		ws.removeCreatedDirs()
+		return nil, &Error{Message: err.Error()}
+	}
+
+	results := append(append([]Result{}, ws.deletions...), ws.directories...)
+	for i, entry := range staged {
+		if err := backup(entry.writePath); err != nil {
+			return fail(i, fmt.Errorf("failed to back up %s: %v", entry.displayPath, err))
+		}
+		if err := os.Rename(entry.tempPath, entry.writePath); err != nil {
+			return fail(i, fmt.Errorf("failed to write %s: %v", entry.displayPath, err))
+		}
+		if entry.sourcePath != entry.writePath {
+			if err := backup(entry.sourcePath); err != nil {
+				return fail(i+1, fmt.Errorf("failed to back up %s: %v", entry.displayPath, err))
+			}
+			if err := os.Remove(entry.sourcePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fail(i+1, fmt.Errorf("failed to remove %s after move: %v", entry.sourcePath, err))
+			}
+		}
+		results = append(results, Result{Status: entry.status, Path: entry.displayPath, Hunks: entry.hunks})
+	}
+
+	written := make(map[string]bool, len(staged))
+	for _, entry := range staged {
+		written[entry.writePath] = true
+	}
+	for _, path := range ws.pendingDeletes {
+		// A file deleted and re-added by the same patch keeps its new content.
+		if written[path] {
+			continue
+		}
+		if err := backup(path); err != nil {
+			return fail(len(staged), fmt.Errorf("failed to back up %s: %v", path, err))
+		}
+		if err := os.Remove(path); err != nil {
+			return fail(len(staged), fmt.Errorf("failed to delete %s: %v", path, err))
+		}
+	}
+	// Directories come last; a failure removes the ones created so far.
+	for _, path := range ws.pendingDirs {
+		if err := ws.mkdirAll(path); err != nil {
+			return fail(len(staged), fmt.Errorf("failed to create directory %s: %v", path, err))
+		}
 	}
 	return results, nil
 }
 
+// stageState writes the patched content of state into a temporary file next to
+// its destination so the final rename stays on the same filesystem.
+func (ws *filesystemWorkspace) stageState(state *state) (stagedWrite, error) {
+	newContent := strings.Join(state.lines, "\n")
+	if state.originalEndsWithNewline != nil {
+		if *state.originalEndsWithNewline && !strings.HasSuffix(newContent, "\n") {
+			newContent += "\n"
+		}
+		if !*state.originalEndsWithNewline && strings.HasSuffix(newContent, "\n") {
+			newContent = strings.TrimSuffix(newContent, "\n")
+		}
+	}
+
+	entry := stagedWrite{
+		writePath:   state.path,
+		displayPath: state.relativePath,
+		sourcePath:  state.path,
+		status:      "M",
+		hunks:       state.reported,
+	}
+	if state.isNew {
+		entry.status = "A"
+	}
+	if moveTarget := strings.TrimSpace(state.movePath); moveTarget != "" {
+		abs, rel, err := ws.resolvePath(moveTarget)
+		if err != nil {
+			return stagedWrite{}, err
+		}
+		entry.writePath = abs
+		entry.displayPath = rel
+	}
+
+	dir := filepath.Dir(entry.writePath)
+	if err := ws.mkdirAll(dir); err != nil {
+		return stagedWrite{}, &Error{Message: fmt.Sprintf("failed to create directory for %s: %v", entry.displayPath, err)}
+	}
+	temp, err := os.CreateTemp(dir, "."+filepath.Base(entry.writePath)+".patch-*")
+	if err != nil {
+		return stagedWrite{}, &Error{Message: fmt.Sprintf("failed to stage %s: %v", entry.displayPath, err)}
+	}
+	entry.tempPath = temp.Name()
+	_, writeErr := temp.WriteString(newContent)
+	closeErr := temp.Close()
+	if writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr != nil {
+		_ = os.Remove(entry.tempPath)
+		return stagedWrite{}, &Error{Message: fmt.Sprintf("failed to stage %s: %v", entry.displayPath, writeErr)}
+	}
+
+	if err := os.Chmod(entry.tempPath, state.targetMode()); err != nil {
+		_ = os.Remove(entry.tempPath)
+		return stagedWrite{}, &Error{Message: fmt.Sprintf("failed to set permissions for %s: %v", entry.displayPath, err)}
+	}
+	return entry, nil
+}
+
+// mkdirAll is os.MkdirAll that records every directory it creates in
+// ws.createdDirs.
+func (ws *filesystemWorkspace) mkdirAll(dir string) error {
+	var missing []string
+	for path := dir; ; path = filepath.Dir(path) {
+		if _, err := os.Lstat(path); !errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		missing = append(missing, path)
+		if filepath.Dir(path) == path {
+			break
+		}
+	}
+	err := os.MkdirAll(dir, 0o755)
+	// Record what exists now even on error: MkdirAll may have created some
+	// parents before failing.
+	for i := len(missing) - 1; i >= 0; i-- {
+		if _, statErr := os.Lstat(missing[i]); statErr == nil {
+			ws.createdDirs = append(ws.createdDirs, missing[i])
+		}
+	}
+	return err
+}
+
+// removeCreatedDirs removes the directories an atomic commit created, deepest
+// first. Directories that are not empty are left alone.
+func (ws *filesystemWorkspace) removeCreatedDirs() {
+	for i := len(ws.createdDirs) - 1; i >= 0; i-- {
+		_ = os.Remove(ws.createdDirs[i])
+	}
+	ws.createdDirs = nil
+}
+
+func captureBackup(path string) (fileBackup, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fileBackup{path: path}, nil
+	}
+	if err != nil {
+		return fileBackup{}, err
+	}
+	if info.IsDir() {
+		return fileBackup{}, fmt.Errorf("%s is a directory", path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fileBackup{}, err
+	}
+	return fileBackup{path: path, content: content, mode: info.Mode(), existed: true}, nil
+}
+
+// restoreBackup is best effort: the original error is what gets reported.
+func restoreBackup(entry fileBackup) {
+	if !entry.existed {
+		_ = os.Remove(entry.path)
+		return
+	}
+	_ = os.WriteFile(entry.path, entry.content, entry.mode&fs.ModePerm)
+	_ = os.Chmod(entry.path, entry.mode&(fs.ModePerm|fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky))
+}
+
 func (ws *filesystemWorkspace) resolvePath(relative string) (string, string, error) {
 	rel := strings.TrimSpace(relative)
 	if rel == "" {
@@ -249,8 +537,79 @@ func (ws *filesystemWorkspace) resolvePath(relative string) (string, string, err
 	base := filepath.Clean(ws.workingDir)
 	abs := filepath.Clean(filepath.Join(base, cleaned))
 	// Ensure the resolved absolute path stays within the workspace directory.
-	if relToBase, err := filepath.Rel(base, abs); err != nil || strings.HasPrefix(relToBase, "..") {
-		return "", "", fmt.Errorf("invalid patch path outside workspace: %s", rel)
+	if !withinDir(base, abs) {
+		return "", "", outsideWorkspaceError(rel)
+	}
+	if ws.options.RestrictToWorkingDir {
+		// Writes follow a symlink in the final component, and a dangling
+		// one has no target to check, so refuse them outright.
+		if info, err := os.Lstat(abs); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			return "", "", &Error{
+				Message:      fmt.Sprintf("invalid patch path through symlink: %s", rel),
+				Code:         "SYMLINK",
+				RelativePath: rel,
+			}
+		}
+		real, err := resolveExisting(abs)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to resolve %s: %v", rel, err)
+		}
+		if !withinDir(ws.realWorkingDir(), real) {
+			return "", "", outsideWorkspaceError(rel)
+		}
 	}
 	return abs, cleaned, nil
 }
+
+// realWorkingDir is the working directory with symlinks resolved, so a
+// workspace that is itself reached through a symlink still contains its files.
+func (ws *filesystemWorkspace) realWorkingDir() string {
+	if ws.realDir == "" {
+		ws.realDir = filepath.Clean(ws.workingDir)
+		if real, err := filepath.EvalSymlinks(ws.realDir); err == nil {
+			ws.realDir = real
+		}
+	}
+	return ws.realDir
+}
+
+// resolveExisting resolves symlinks in the longest existing prefix of path
+// and appends the rest, so files that do not exist yet are judged by the
+// directory they would be created in.
+func resolveExisting(path string) (string, error) {
+	var missing []string
+	for {
+		real, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			for i := len(missing) - 1; i >= 0; i-- {
+				real = filepath.Join(real, missing[i])
+			}
+			return real, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		if info, lerr := os.Lstat(path); lerr == nil && info.Mode()&fs.ModeSymlink != 0 {
+			return "", fmt.Errorf("dangling symlink %s", path)
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", err
+		}
+		missing = append(missing, filepath.Base(path))
+		path = parent
+	}
+}
+
+func withinDir(base, path string) bool {
+	rel, err := filepath.Rel(base, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func outsideWorkspaceError(rel string) *Error {
+	return &Error{
+		Message:      fmt.Sprintf("invalid patch path outside workspace: %s", rel),
+		Code:         "OUTSIDE_WORKSPACE",
+		RelativePath: rel,
+	}
+}
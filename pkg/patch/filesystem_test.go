@@ -74,3 +74,237 @@ func TestApplyFilesystemAddsAndMovesFile(t *testing.T) {
 		t.Fatalf("unexpected moved content: %q", content)
 	}
 }
+
+func TestApplyFilesystemAtomicRollsBackOnFailure(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\n"), 0o644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), []byte("two\n"), 0o644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	// Moving onto a non-empty directory makes the rename fail during commit.
+	if err := os.MkdirAll(filepath.Join(dir, "blocked", "child"), 0o755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	ops := []Operation{
+		{Type: OperationUpdate, Path: "a.txt", Hunks: []Hunk{{Before: []string{"one"}, After: []string{"uno"}}}},
+		{Type: OperationDelete, Path: "c.txt"},
+	}
+	if err := os.WriteFile(filepath.Join(dir, "c.txt"), []byte("three\n"), 0o644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	// The added file's directories are created while staging and must go
+	// away with the rollback.
+	ops = append(ops, Operation{Type: OperationAdd, Path: "new/deep/file.txt", Hunks: []Hunk{{After: []string{"fresh"}}}})
+	ops = append(ops, Operation{Type: OperationUpdate, Path: "b.txt", MovePath: "blocked", Hunks: []Hunk{{Before: []string{"two"}, After: []string{"dos"}}}})
+
+	_, err := ApplyFilesystem(context.Background(), ops, FilesystemOptions{Options: Options{Atomic: true}, WorkingDir: dir})
+	if err == nil {
+		t.Fatalf("expected commit to fail")
+	}
+
+	for name, want := range map[string]string{"a.txt": "one\n", "b.txt": "two\n", "c.txt": "three\n"} {
+		content, readErr := os.ReadFile(filepath.Join(dir, name))
+		if readErr != nil {
+			t.Fatalf("failed to read %s: %v", name, readErr)
+		}
+		if string(content) != want {
+			t.Fatalf("expected %s to be restored to %q, got %q", name, want, content)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to list directory: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("expected staged files and created directories to be cleaned up, found %d entries", len(entries))
+	}
+}
+
+func TestApplyFilesystemRefusesBinaryAndOversizedFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "image.bin"), []byte("one\x00two\n"), 0o644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "big.txt"), []byte("one\ntwo\nthree\n"), 0o644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	cases := []struct {
+		path string
+		code string
+	}{
+		{path: "image.bin", code: "BINARY_FILE"},
+		{path: "big.txt", code: "FILE_TOO_LARGE"},
+	}
+	for _, tc := range cases {
+		ops := []Operation{{
+			Type:  OperationUpdate,
+			Path:  tc.path,
+			Hunks: []Hunk{{Before: []string{"one"}, After: []string{"uno"}}},
+		}}
+		_, err := ApplyFilesystem(context.Background(), ops, FilesystemOptions{Options: Options{MaxFileSize: 8}, WorkingDir: dir})
+		perr, ok := err.(*Error)
+		if !ok || perr.Code != tc.code || perr.RelativePath != tc.path {
+			t.Fatalf("%s: expected %s error, got %#v", tc.path, tc.code, err)
+		}
+	}
+}
+
+func TestApplyFilesystemRestrictToWorkingDirRejectsSymlinkEscape(t *testing.T) {
+	t.Parallel()
+
+	outside := t.TempDir()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("one\n"), 0o644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	update := []Operation{{
+		Type:  OperationUpdate,
+		Path:  "link/secret.txt",
+		Hunks: []Hunk{{Before: []string{"one"}, After: []string{"two"}}},
+	}}
+	add := []Operation{{
+		Type:  OperationAdd,
+		Path:  "link/nested/new.txt",
+		Hunks: []Hunk{{After: []string{"hello"}}},
+	}}
+	traversal := []Operation{{
+		Type:  OperationAdd,
+		Path:  "../escape.txt",
+		Hunks: []Hunk{{After: []string{"hello"}}},
+	}}
+	opts := FilesystemOptions{Options: Options{RestrictToWorkingDir: true}, WorkingDir: dir}
+	for _, ops := range [][]Operation{update, add, traversal} {
+		_, err := ApplyFilesystem(context.Background(), ops, opts)
+		perr, ok := err.(*Error)
+		if !ok || perr.Code != "OUTSIDE_WORKSPACE" {
+			t.Fatalf("%s: expected OUTSIDE_WORKSPACE, got %#v", ops[0].Path, err)
+		}
+	}
+	content, err := os.ReadFile(filepath.Join(outside, "secret.txt"))
+	if err != nil || string(content) != "one\n" {
+		t.Fatalf("file outside the workspace changed: %q, %v", content, err)
+	}
+
+	inside := []Operation{{
+		Type:  OperationAdd,
+		Path:  "nested/new.txt",
+		Hunks: []Hunk{{After: []string{"hello"}}},
+	}}
+	if _, err := ApplyFilesystem(context.Background(), inside, opts); err != nil {
+		t.Fatalf("ApplyFilesystem rejected a path inside the workspace: %v", err)
+	}
+}
+
+func TestApplyFilesystemRestrictToWorkingDirRejectsDanglingSymlink(t *testing.T) {
+	t.Parallel()
+
+	outside := filepath.Join(t.TempDir(), "created.txt")
+	dir := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	add := []Operation{{
+		Type:  OperationAdd,
+		Path:  "link",
+		Hunks: []Hunk{{After: []string{"hello"}}},
+	}}
+	opts := FilesystemOptions{Options: Options{RestrictToWorkingDir: true}, WorkingDir: dir}
+	_, err := ApplyFilesystem(context.Background(), add, opts)
+	if perr, ok := err.(*Error); !ok || perr.Code != "SYMLINK" {
+		t.Fatalf("expected SYMLINK, got %#v", err)
+	}
+	if _, err := os.Stat(outside); err == nil {
+		t.Fatal("the patch created the symlink's target outside the workspace")
+	}
+}
+
+func TestApplyFilesystemSetsFileMode(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "tool.sh"), []byte("echo hi\n"), 0o644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	ops, err := Parse("*** Begin Patch\n*** Add File: run.sh\n*** File Mode: 0755\n+#!/bin/sh\n*** Update File: tool.sh\n*** File Mode: 0700\n*** End Patch\n")
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	for _, atomic := range []bool{false, true} {
+		if _, err := ApplyFilesystem(context.Background(), ops, FilesystemOptions{Options: Options{Atomic: atomic}, WorkingDir: dir}); err != nil {
+			t.Fatalf("ApplyFilesystem (atomic=%v) returned error: %v", atomic, err)
+		}
+		for path, want := range map[string]os.FileMode{"run.sh": 0o755, "tool.sh": 0o700} {
+			info, err := os.Stat(filepath.Join(dir, path))
+			if err != nil {
+				t.Fatalf("stat %s: %v", path, err)
+			}
+			if info.Mode().Perm() != want {
+				t.Fatalf("atomic=%v: %s has mode %v, want %v", atomic, path, info.Mode().Perm(), want)
+			}
+		}
+		content, _ := os.ReadFile(filepath.Join(dir, "tool.sh"))
+		if string(content) != "echo hi\n" {
+			t.Fatalf("mode-only change rewrote content: %q", content)
+		}
+		if err := os.Remove(filepath.Join(dir, "run.sh")); err != nil {
+			t.Fatalf("failed to reset fixture: %v", err)
+		}
+		if err := os.Chmod(filepath.Join(dir, "tool.sh"), 0o644); err != nil {
+			t.Fatalf("failed to reset fixture: %v", err)
+		}
+	}
+}
+
+func TestApplyFilesystemAddsEmptyFilesAndDirectories(t *testing.T) {
+	t.Parallel()
+
+	ops, err := Parse("*** Begin Patch\n*** Add File: empty.txt\n*** Add Directory: assets/icons\n*** Add File: .keep\n*** End Patch\n")
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(ops) != 3 || ops[1].Type != OperationAddDirectory || ops[1].Path != "assets/icons" {
+		t.Fatalf("unexpected operations: %#v", ops)
+	}
+
+	for _, atomic := range []bool{false, true} {
+		dir := t.TempDir()
+		results, err := ApplyFilesystem(context.Background(), ops, FilesystemOptions{Options: Options{Atomic: atomic}, WorkingDir: dir})
+		if err != nil {
+			t.Fatalf("ApplyFilesystem (atomic=%v) returned error: %v", atomic, err)
+		}
+		if len(results) != 3 {
+			t.Fatalf("unexpected results: %#v", results)
+		}
+		for _, path := range []string{"empty.txt", ".keep"} {
+			content, err := os.ReadFile(filepath.Join(dir, path))
+			if err != nil || len(content) != 0 {
+				t.Fatalf("expected empty %s, got %q, %v", path, content, err)
+			}
+		}
+		if info, err := os.Stat(filepath.Join(dir, "assets", "icons")); err != nil || !info.IsDir() {
+			t.Fatalf("expected directory, got %v", err)
+		}
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "taken"), nil, 0o644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	if _, err := ApplyFilesystem(context.Background(), []Operation{{Type: OperationAddDirectory, Path: "taken"}}, FilesystemOptions{WorkingDir: dir}); err == nil {
+		t.Fatalf("expected an error when a file occupies the directory path")
+	}
+}
@@ -0,0 +1,115 @@
+package patch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ApplyGit applies operations to the filesystem like ApplyFilesystem and then
+// stages exactly the files the patch touched in the git index, so additions,
+// deletions, and renames show up in `git status` as staged changes and no
+// other path is staged. The working directory must be inside a git work tree,
+// which is checked before anything is written, and the git binary must be on
+// PATH.
+//
+// Staging happens after the files have been written; if it fails the returned
+// error reports that the edits were applied but not staged.
+func ApplyGit(ctx context.Context, operations []Operation, opts FilesystemOptions) ([]Result, error) {
+	if opts.Reverse {
+		reversed, err := Reverse(operations)
+		if err != nil {
+			return nil, &Error{Message: err.Error()}
+		}
+		operations = reversed
+	}
+	fsws, err := newFilesystemWorkspace(opts)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := runGit(ctx, fsws.workingDir, "rev-parse", "--is-inside-work-tree"); err != nil {
+		return nil, &Error{Message: fmt.Sprintf("cannot stage the patch: %s is not inside a git work tree (%s)", fsws.workingDir, err)}
+	}
+	return apply(ctx, operations, &gitWorkspace{filesystemWorkspace: fsws, ctx: ctx})
+}
+
+// ApplyGitPatch parses a raw patch payload, applies it to the filesystem, and
+// stages the result in the git index.
+func ApplyGitPatch(ctx context.Context, patchBody string, opts FilesystemOptions) ([]Result, error) {
+	operations, err := Parse(patchBody)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyGit(ctx, operations, opts)
+}
+
+// gitWorkspace decorates the filesystem workspace with a staging step.
+type gitWorkspace struct {
+	*filesystemWorkspace
+	ctx context.Context
+}
+
+func (ws *gitWorkspace) Commit() ([]Result, error) {
+	results, err := ws.filesystemWorkspace.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var paths []string
+	add := func(path string) {
+		if path != "" && !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	for _, result := range results {
+		// Added directories have no index entry; git tracks their files.
+		if info, err := os.Lstat(filepath.Join(ws.workingDir, result.Path)); err == nil && info.IsDir() {
+			continue
+		}
+		add(result.Path)
+	}
+	// Moves only report their destination; the source must be staged too so
+	// git records the rename rather than an untracked copy.
+	for _, state := range ws.states {
+		if state.touched && strings.TrimSpace(state.movePath) != "" {
+			add(state.relativePath)
+		}
+	}
+	if len(paths) == 0 {
+		return results, nil
+	}
+	sort.Strings(paths)
+
+	// update-index stages exactly these paths: unlike git add it does not
+	// expand directories or skip ignored files, and --remove drops deleted
+	// files and move sources from the index.
+	args := append([]string{"update-index", "--add", "--remove", "--"}, paths...)
+	if _, err := runGit(ws.ctx, ws.workingDir, args...); err != nil {
+		return nil, &Error{Message: fmt.Sprintf("patch applied but staging failed: %s", err)}
+	}
+	return results, nil
+}
+
+// runGit runs git in dir and returns stdout; errors carry git's stderr.
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return "", errors.New(detail)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
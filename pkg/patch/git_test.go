@@ -0,0 +1,120 @@
+package patch
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyGitStagesChanges(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	runGit := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+		return string(out)
+	}
+
+	runGit("init", "-q")
+	for name, content := range map[string]string{"keep.txt": "one\n", "old.txt": "move me\n", "gone.txt": "bye\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write fixture: %v", err)
+		}
+	}
+	runGit("add", ".")
+	runGit("commit", "-q", "-m", "seed")
+
+	patchBody := strings.Join([]string{
+		"*** Begin Patch",
+		"*** Update File: keep.txt",
+		"@@",
+		"-one",
+		"+two",
+		"*** Update File: old.txt",
+		"*** Move to: new.txt",
+		"*** Delete File: gone.txt",
+		"*** Add File: added.txt",
+		"+hello",
+		"*** End Patch",
+	}, "\n")
+
+	if _, err := ApplyGitPatch(context.Background(), patchBody, FilesystemOptions{WorkingDir: dir}); err != nil {
+		t.Fatalf("ApplyGitPatch returned error: %v", err)
+	}
+
+	staged := runGit("diff", "--cached", "--name-status", "-M")
+	for _, want := range []string{"M\tkeep.txt", "D\tgone.txt", "A\tadded.txt", "new.txt"} {
+		if !strings.Contains(staged, want) {
+			t.Fatalf("expected %q in staged changes, got:\n%s", want, staged)
+		}
+	}
+	if unstaged := runGit("status", "--porcelain", "--untracked-files=all"); strings.Contains(unstaged, "??") {
+		t.Fatalf("expected no untracked files, got:\n%s", unstaged)
+	}
+}
+
+func TestApplyGitStagesIgnoredPathsAndNothingElse(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	runGit := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+		return string(out)
+	}
+	runGit("init", "-q")
+	for name, content := range map[string]string{".gitignore": "*.log\n", "stray.txt": "untracked\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write fixture: %v", err)
+		}
+	}
+
+	patchBody := "*** Begin Patch\n*** Add File: build.log\n+output\n*** Add Directory: empty\n*** End Patch"
+	if _, err := ApplyGitPatch(context.Background(), patchBody, FilesystemOptions{WorkingDir: dir}); err != nil {
+		t.Fatalf("ApplyGitPatch returned error: %v", err)
+	}
+	if staged := runGit("diff", "--cached", "--name-only"); staged != "build.log\n" {
+		t.Fatalf("expected only build.log to be staged, got:\n%s", staged)
+	}
+}
+
+func TestApplyGitFailsOutsideARepositoryBeforeWriting(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	patchBody := "*** Begin Patch\n*** Add File: added.txt\n+hello\n*** End Patch"
+	_, err := ApplyGitPatch(context.Background(), patchBody, FilesystemOptions{WorkingDir: dir})
+	if err == nil || !strings.Contains(err.Error(), "not inside a git work tree") {
+		t.Fatalf("expected a not-a-repository error, got %v", err)
+	}
+	if _, statErr := os.Stat(filepath.Join(dir, "added.txt")); !os.IsNotExist(statErr) {
+		t.Fatalf("expected nothing to be written, got %v", statErr)
+	}
+}
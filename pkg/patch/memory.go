@@ -2,7 +2,9 @@ package patch
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"path/filepath"
 	"strings"
 )
@@ -14,6 +16,13 @@ func ApplyToMemory(ctx context.Context, operations []Operation, files map[string
 	for k, v := range files {
 		snapshot[k] = v
 	}
+	if opts.Reverse {
+		reversed, err := Reverse(operations)
+		if err != nil {
+			return nil, nil, &Error{Message: err.Error()}
+		}
+		operations = reversed
+	}
 	ws := newMemoryWorkspace(snapshot, opts)
 	results, err := apply(ctx, operations, ws)
 	if err != nil {
@@ -31,11 +40,53 @@ func ApplyMemoryPatch(ctx context.Context, patchBody string, files map[string]st
 	return ApplyToMemory(ctx, operations, files, opts)
 }
 
+// ApplyFS applies operations to the files of a read-only fs.FS such as an
+// embed.FS or fstest.MapFS. Nothing is written back: the returned map holds the
+// content of every file the patch added or modified, keyed by its path after
+// any move, and deletions only show up in the results.
+func ApplyFS(ctx context.Context, operations []Operation, fsys fs.FS, opts Options) (map[string]string, []Result, error) {
+	if fsys == nil {
+		return nil, nil, errors.New("nil file system")
+	}
+	files := make(map[string]string)
+	for _, op := range operations {
+		if op.Type == OperationAddDirectory {
+			continue
+		}
+		for _, target := range []string{op.Path, op.MovePath} {
+			name, ok := validatePath(target)
+			if !ok {
+				continue
+			}
+			content, err := fs.ReadFile(fsys, name)
+			switch {
+			case err == nil:
+				files[filepath.Clean(strings.TrimSpace(target))] = string(content)
+			case !errors.Is(err, fs.ErrNotExist):
+				return nil, nil, &Error{Message: fmt.Sprintf("failed to read %s: %v", name, err)}
+			}
+		}
+	}
+
+	updated, results, err := ApplyToMemory(ctx, operations, files, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	patched := make(map[string]string)
+	for _, result := range results {
+		if content, ok := updated[result.Path]; ok && result.Status != "D" {
+			patched[result.Path] = content
+		}
+	}
+	return patched, results, nil
+}
+
 type memoryWorkspace struct {
-	options   Options
-	files     map[string]string
-	states    map[string]*state
-	deletions []Result
+	options     Options
+	files       map[string]string
+	states      map[string]*state
+	deletions   []Result
+	directories []Result
 }
 
 func newMemoryWorkspace(files map[string]string, opts Options) *memoryWorkspace {
@@ -53,7 +104,7 @@ func (ws *memoryWorkspace) Ensure(path string, create bool) (*state, error) {
 	}
 	if state, ok := ws.states[rel]; ok {
 		state.options = ws.options
-		if ws.options.IgnoreWhitespace {
+		if state.normalizes() {
 			state.normalizedLines = ensureNormalizedLines(state)
 		} else {
 			state.normalizedLines = nil
@@ -80,6 +131,10 @@ func (ws *memoryWorkspace) Ensure(path string, create bool) (*state, error) {
 		return state, nil
 	}
 
+	if err := checkPatchable(rel, int64(len(content)), []byte(content[:min(len(content), binarySniffLength)]), ws.options); err != nil {
+		return nil, err
+	}
+
 	normalized := strings.ReplaceAll(content, "\r\n", "\n")
 	normalized = strings.ReplaceAll(normalized, "\r", "\n")
 	lines := strings.Split(normalized, "\n")
@@ -91,8 +146,9 @@ func (ws *memoryWorkspace) Ensure(path string, create bool) (*state, error) {
 		originalContent:         content,
 		originalEndsWithNewline: &ends,
 		options:                 ws.options,
+		large:                   len(content) > normalizeLimit,
 	}
-	if ws.options.IgnoreWhitespace {
+	if state.normalizes() {
 		state.normalizedLines = ensureNormalizedLines(state)
 	}
 	ws.states[rel] = state
@@ -113,8 +169,22 @@ func (ws *memoryWorkspace) Delete(path string) error {
 	return nil
 }
 
+// MakeDir reports the directory as added. The map only holds files, so
+// nothing is stored; a file at the same path is an error.
+func (ws *memoryWorkspace) MakeDir(path string) error {
+	rel := filepath.Clean(strings.TrimSpace(path))
+	if rel == "" || rel == "." {
+		return fmt.Errorf("invalid patch path")
+	}
+	if _, ok := ws.files[rel]; ok {
+		return &Error{Message: fmt.Sprintf("Cannot add directory %s: a file exists at that path", rel)}
+	}
+	ws.directories = append(ws.directories, Result{Status: "A", Path: rel})
+	return nil
+}
+
 func (ws *memoryWorkspace) Commit() ([]Result, error) {
-	results := append([]Result{}, ws.deletions...)
+	results := append(append([]Result{}, ws.deletions...), ws.directories...)
 	for key, state := range ws.states {
 		if !state.touched {
 			continue
@@ -150,7 +220,7 @@ func (ws *memoryWorkspace) Commit() ([]Result, error) {
 		if state.isNew {
 			status = "A"
 		}
-		results = append(results, Result{Status: status, Path: display})
+		results = append(results, Result{Status: status, Path: display, Hunks: state.reported})
 	}
 	return results, nil
 }
@@ -2,7 +2,10 @@ package patch
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func TestApplyToMemoryCopiesInput(t *testing.T) {
@@ -59,3 +62,208 @@ func TestMemoryWorkspaceCommitMoveValidatesPath(t *testing.T) {
 func ctxBackground() context.Context {
 	return context.Background()
 }
+
+func TestApplyToMemoryBestEffortWritesConflictMarkers(t *testing.T) {
+	t.Parallel()
+
+	initial := map[string]string{"file.txt": "one\ntwo changed\nthree\nfour\n"}
+	operations := []Operation{{
+		Type: OperationUpdate,
+		Path: "file.txt",
+		Hunks: []Hunk{
+			{Before: []string{"one", "two", "three"}, After: []string{"one", "TWO", "three"}},
+			{Before: []string{"four"}, After: []string{"FOUR"}},
+		},
+	}}
+
+	if _, _, err := ApplyToMemory(ctxBackground(), operations, initial, Options{}); err == nil {
+		t.Fatalf("expected hunk failure without best effort")
+	}
+
+	updated, results, err := ApplyToMemory(ctxBackground(), operations, initial, Options{BestEffort: true})
+	if err != nil {
+		t.Fatalf("ApplyToMemory returned error: %v", err)
+	}
+	want := "<<<<<<< ours\none\ntwo changed\nthree\n=======\none\nTWO\nthree\n>>>>>>> patch\nFOUR\n"
+	if updated["file.txt"] != want {
+		t.Fatalf("unexpected content:\n%s", updated["file.txt"])
+	}
+	if len(results) != 1 || results[0].Status != "M" || !results[0].Conflicted() {
+		t.Fatalf("unexpected results: %#v", results)
+	}
+	wantHunks := []HunkStatus{{Number: 1, Status: HunkConflict}, {Number: 2, Status: HunkApplied}}
+	if len(results[0].Hunks) != len(wantHunks) || results[0].Hunks[0] != wantHunks[0] || results[0].Hunks[1] != wantHunks[1] {
+		t.Fatalf("unexpected hunk statuses: %#v", results[0].Hunks)
+	}
+}
+
+func TestApplyToMemorySkipsAlreadyAppliedHunks(t *testing.T) {
+	t.Parallel()
+
+	// The first hunk was applied by an earlier attempt; the second was not.
+	initial := map[string]string{"file.txt": "one\nTWO\nthree\nfour\n"}
+	operations := []Operation{{
+		Type: OperationUpdate,
+		Path: "file.txt",
+		Hunks: []Hunk{
+			{Before: []string{"one", "two", "three"}, After: []string{"one", "TWO", "three"}},
+			{Before: []string{"four"}, After: []string{"FOUR"}},
+		},
+	}}
+
+	if _, _, err := ApplyToMemory(ctxBackground(), operations, initial, Options{}); err == nil {
+		t.Fatalf("expected hunk failure without AllowAlreadyApplied")
+	}
+
+	updated, results, err := ApplyToMemory(ctxBackground(), operations, initial, Options{AllowAlreadyApplied: true})
+	if err != nil {
+		t.Fatalf("ApplyToMemory returned error: %v", err)
+	}
+	if want := "one\nTWO\nthree\nFOUR\n"; updated["file.txt"] != want {
+		t.Fatalf("unexpected content:\n%s", updated["file.txt"])
+	}
+	wantHunks := []HunkStatus{{Number: 1, Status: HunkAlreadyApplied}, {Number: 2, Status: HunkApplied}}
+	if len(results) != 1 || len(results[0].Hunks) != 2 || results[0].Hunks[0] != wantHunks[0] || results[0].Hunks[1] != wantHunks[1] {
+		t.Fatalf("unexpected results: %#v", results)
+	}
+
+	// A hunk whose lines are neither before nor after still fails.
+	initial["file.txt"] = "one\nelse\nthree\nfour\n"
+	if _, _, err := ApplyToMemory(ctxBackground(), operations, initial, Options{AllowAlreadyApplied: true}); err == nil {
+		t.Fatalf("expected a hunk that matches nothing to fail")
+	}
+}
+
+func TestApplyToMemoryIgnoresAppliedTextElsewhere(t *testing.T) {
+	t.Parallel()
+
+	// first() was changed some other way; second() happens to hold the
+	// hunk's result, which must not count as the hunk being applied.
+	initial := map[string]string{"file.go": "func first() {\n\tx := 2\n\treturn x\n}\n\nfunc second() {\n\tx := 1\n\treturn x\n}\n"}
+	operations := []Operation{{
+		Type: OperationUpdate,
+		Path: "file.go",
+		Hunks: []Hunk{
+			{Before: []string{"\tx := 0", "\treturn x"}, After: []string{"\tx := 1", "\treturn x"}},
+		},
+	}}
+	if _, _, err := ApplyToMemory(ctxBackground(), operations, initial, Options{AllowAlreadyApplied: true}); err == nil {
+		t.Fatalf("expected the hunk to fail instead of matching second()")
+	}
+}
+
+func TestApplyToMemoryMergesDriftedHunks(t *testing.T) {
+	t.Parallel()
+
+	// The file gained a line and a rename since the patch was written.
+	initial := map[string]string{"file.go": "func run() {\n\tsetup()\n\tlog(\"start\")\n\tstep(1)\n\tstep(2)\n\tcleanup()\n}\n"}
+	operations := []Operation{{
+		Type: OperationUpdate,
+		Path: "file.go",
+		Hunks: []Hunk{{
+			Before: []string{"func run() {", "\tinit()", "\tstep(1)", "\tstep(2)", "\tcleanup()", "}"},
+			After:  []string{"func run() {", "\tinit()", "\tstep(1)", "\tstep(2)", "\tstep(3)", "\tcleanup()", "}"},
+		}},
+	}}
+
+	if _, _, err := ApplyToMemory(ctxBackground(), operations, initial, Options{}); err == nil {
+		t.Fatalf("expected hunk failure without Merge")
+	}
+
+	updated, results, err := ApplyToMemory(ctxBackground(), operations, initial, Options{Merge: true})
+	if err != nil {
+		t.Fatalf("ApplyToMemory returned error: %v", err)
+	}
+	want := "func run() {\n\tsetup()\n\tlog(\"start\")\n\tstep(1)\n\tstep(2)\n\tstep(3)\n\tcleanup()\n}\n"
+	if updated["file.go"] != want {
+		t.Fatalf("unexpected content:\n%s", updated["file.go"])
+	}
+	if len(results) != 1 || len(results[0].Hunks) != 1 || results[0].Hunks[0].Status != HunkMerged {
+		t.Fatalf("unexpected results: %#v", results)
+	}
+
+	// Both sides changing the same line is a conflict that leaves the file
+	// alone and reports just the conflicting region.
+	operations[0].Hunks[0].After = []string{"func run() {", "\tinit()", "\tstep(1)", "\tstep(2)", "\tfinish()", "}"}
+	initial["file.go"] = "func run() {\n\tinit()\n\tstep(1)\n\tstep(2)\n\tcleanup(true)\n}\n"
+	_, _, err = ApplyToMemory(ctxBackground(), operations, initial, Options{Merge: true})
+	var perr *Error
+	if !errors.As(err, &perr) || perr.Code != "MERGE_CONFLICT" {
+		t.Fatalf("expected MERGE_CONFLICT, got %v", err)
+	}
+	formatted := FormatError(perr)
+	if !strings.Contains(formatted, "<<<<<<< ours\n\tcleanup(true)\n=======\n\tfinish()\n>>>>>>> patch") || strings.Contains(formatted, "Full content of file") {
+		t.Fatalf("unexpected conflict report:\n%s", formatted)
+	}
+
+	updated, results, err = ApplyToMemory(ctxBackground(), operations, initial, Options{Merge: true, BestEffort: true})
+	if err != nil {
+		t.Fatalf("ApplyToMemory returned error: %v", err)
+	}
+	want = "func run() {\n\tinit()\n\tstep(1)\n\tstep(2)\n<<<<<<< ours\n\tcleanup(true)\n=======\n\tfinish()\n>>>>>>> patch\n}\n"
+	if updated["file.go"] != want || !results[0].Conflicted() {
+		t.Fatalf("unexpected content:\n%s", updated["file.go"])
+	}
+}
+
+func TestNearestHunkLocationPrefersBestOverlap(t *testing.T) {
+	t.Parallel()
+
+	lines := []string{"a", "b", "x", "c", "d", "e", ""}
+	start, length := nearestHunkLocation(lines, []string{"c", " d ", "z"}, 0)
+	if start != 3 || length != 3 {
+		t.Fatalf("expected window at 3 of length 3, got %d, %d", start, length)
+	}
+
+	start, length = nearestHunkLocation([]string{"only", ""}, []string{"p", "q"}, 5)
+	if start != 0 || length != 1 {
+		t.Fatalf("expected clamped window, got %d, %d", start, length)
+	}
+}
+
+func TestApplyFSReturnsPatchedDocuments(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"src/main.go": {Data: []byte("package main\n\nfunc main() {}\n")},
+		"old.txt":     {Data: []byte("bye\n")},
+		"keep.txt":    {Data: []byte("untouched\n")},
+	}
+	patchBody := "*** Begin Patch\n" +
+		"*** Update File: src/main.go\n@@\n-func main() {}\n+func main() { println(1) }\n" +
+		"*** Add File: notes.md\n+# notes\n" +
+		"*** Delete File: old.txt\n" +
+		"*** End Patch\n"
+	operations, err := Parse(patchBody)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	patched, results, err := ApplyFS(ctxBackground(), operations, fsys, Options{})
+	if err != nil {
+		t.Fatalf("ApplyFS returned error: %v", err)
+	}
+	want := map[string]string{
+		"src/main.go": "package main\n\nfunc main() { println(1) }\n",
+		"notes.md":    "# notes",
+	}
+	if len(patched) != len(want) {
+		t.Fatalf("unexpected documents: %#v", patched)
+	}
+	for path, content := range want {
+		if patched[path] != content {
+			t.Fatalf("%s: got %q, want %q", path, patched[path], content)
+		}
+	}
+	if len(results) != 3 {
+		t.Fatalf("unexpected results: %#v", results)
+	}
+	if string(fsys["old.txt"].Data) != "bye\n" {
+		t.Fatalf("source file system was modified")
+	}
+
+	missing := []Operation{{Type: OperationUpdate, Path: "nope.txt", Hunks: []Hunk{{Before: []string{"a"}, After: []string{"b"}}}}}
+	if _, _, err := ApplyFS(ctxBackground(), missing, fsys, Options{}); err == nil {
+		t.Fatalf("expected an error for a missing file")
+	}
+}
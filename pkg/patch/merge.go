@@ -0,0 +1,192 @@
+package patch
+
+import (
+	"fmt"
+	"slices"
+)
+
+// mergeWindowSlack bounds how far the merged region may grow, shrink or
+// shift from the window that most resembles the hunk's before lines.
+const mergeWindowSlack = 8
+
+// mergeHunk recovers a hunk whose before lines drifted under Options.Merge.
+// The lines of the file that best match the hunk ("ours") are merged with
+// its after lines ("theirs"), using its before lines as the common base.
+// A clean merge is applied and reported as merged. A conflicting one is
+// written with conflict markers in best-effort mode and otherwise returned
+// as a MERGE_CONFLICT Error without touching the file. An empty status and
+// nil error mean too little of the hunk is left in the file to merge.
+func mergeHunk(state *state, hunk Hunk) (string, *Error) {
+	if len(hunk.Before) == 0 {
+		return "", nil
+	}
+	start, end, matched := driftedRegion(state, hunk.Before)
+	if matched*2 < len(hunk.Before) {
+		return "", nil
+	}
+	merged, conflicts := merge3(hunk.Before, state.lines[start:end], hunk.After, state.normalizes())
+	if conflicts > 0 && !state.options.BestEffort {
+		return "", &Error{
+			Message:      fmt.Sprintf("Hunk conflicts with changes to lines %d-%d of %s.", start+1, end, state.relativePath),
+			Code:         "MERGE_CONFLICT",
+			RelativePath: state.relativePath,
+			Conflict:     merged,
+		}
+	}
+	state.lines = splice(state.lines, start, end-start, merged)
+	updateNormalizedLines(state, start, end-start, merged)
+	state.cursor = start + len(merged)
+	if conflicts > 0 {
+		return HunkConflict, nil
+	}
+	return HunkMerged, nil
+}
+
+// driftedRegion returns the lines [start, end) that share the most lines
+// with before, in order, and how many they share. It searches around the
+// window nearestHunkLocation picks so lines inserted into or removed from
+// the region are covered. Ties go to the window whose unmatched lines at
+// either edge pair up with unmatched before lines, as edited lines do, then
+// to the shorter window, then to the one closest to the anchor.
+func driftedRegion(state *state, before []string) (start, end, matched int) {
+	lines, base := state.lines, before
+	if state.normalizes() {
+		lines = ensureNormalizedLines(state)
+		base = make([]string, len(before))
+		for i, line := range before {
+			base[i] = normalizeLine(line)
+		}
+	}
+	anchor, length := nearestHunkLocation(state.lines, before, state.cursor)
+	available := len(lines)
+	if available > 0 && lines[available-1] == "" {
+		available--
+	}
+
+	start, end, matched = anchor, anchor+length, -1
+	bestSkew := 0
+	for s := max(0, anchor-mergeWindowSlack); s <= min(available, anchor+mergeWindowSlack); s++ {
+		for e := s + max(0, length-mergeWindowSlack); e <= min(available, s+length+mergeWindowSlack); e++ {
+			m, skew := alignWindow(base, lines[s:e])
+			better := m > matched
+			if m == matched {
+				switch {
+				case skew != bestSkew:
+					better = skew < bestSkew
+				case e-s != end-start:
+					better = e-s < end-start
+				default:
+					better = abs(s-anchor) < abs(start-anchor)
+				}
+			}
+			if better {
+				start, end, matched, bestSkew = s, e, m, skew
+			}
+		}
+	}
+	return start, end, matched
+}
+
+// alignWindow returns how many lines base and window share in order, and
+// how far the unmatched lines before the first and after the last shared
+// line differ in number between the two.
+func alignWindow(base, window []string) (matched, skew int) {
+	edits := diffLines(base, window)
+	first, last := len(edits), -1
+	for i, e := range edits {
+		if e.kind == ' ' {
+			matched++
+			first = min(first, i)
+			last = i
+		}
+	}
+	edge := func(edits []lineEdit) int {
+		n := 0
+		for _, e := range edits {
+			if e.kind == '-' {
+				n++
+			} else {
+				n--
+			}
+		}
+		return abs(n)
+	}
+	if matched == 0 {
+		return 0, edge(edits)
+	}
+	return matched, edge(edits[:first]) + edge(edits[last+1:])
+}
+
+// merge3 merges the changes from base to ours and from base to theirs, as
+// diff3 does. Lines neither side changed are taken from ours. A region both
+// sides changed differently is written between conflict markers and
+// counted in conflicts.
+func merge3(base, ours, theirs []string, normalize bool) (merged []string, conflicts int) {
+	key := func(lines []string) []string {
+		if !normalize {
+			return lines
+		}
+		keys := make([]string, len(lines))
+		for i, line := range lines {
+			keys[i] = normalizeLine(line)
+		}
+		return keys
+	}
+	equal := func(a, b []string) bool { return slices.Equal(key(a), key(b)) }
+	toOurs := matchIndices(key(base), key(ours))
+	toTheirs := matchIndices(key(base), key(theirs))
+
+	b, o, t := 0, 0, 0
+	for b < len(base) || o < len(ours) || t < len(theirs) {
+		if b < len(base) && toOurs[b] == o && toTheirs[b] == t {
+			merged = append(merged, ours[o])
+			b, o, t = b+1, o+1, t+1
+			continue
+		}
+		// The changed region ends at the next base line both sides kept.
+		nb, no, nt := len(base), len(ours), len(theirs)
+		for i := b; i < len(base); i++ {
+			if toOurs[i] >= 0 && toTheirs[i] >= 0 {
+				nb, no, nt = i, toOurs[i], toTheirs[i]
+				break
+			}
+		}
+		baseChunk, oursChunk, theirsChunk := base[b:nb], ours[o:no], theirs[t:nt]
+		switch {
+		case equal(oursChunk, baseChunk):
+			merged = append(merged, theirsChunk...)
+		case equal(theirsChunk, baseChunk), equal(oursChunk, theirsChunk):
+			merged = append(merged, oursChunk...)
+		default:
+			merged = append(merged, conflictOursMarker)
+			merged = append(merged, oursChunk...)
+			merged = append(merged, conflictSplitMarker)
+			merged = append(merged, theirsChunk...)
+			merged = append(merged, conflictPatchMarker)
+			conflicts++
+		}
+		b, o, t = nb, no, nt
+	}
+	return merged, conflicts
+}
+
+// matchIndices maps each line of a to the line of b it is kept as in a
+// shortest edit script, or -1 when it is removed.
+func matchIndices(a, b []string) []int {
+	matches := make([]int, len(a))
+	ai, bi := 0, 0
+	for _, e := range diffLines(a, b) {
+		switch e.kind {
+		case ' ':
+			matches[ai] = bi
+			ai++
+			bi++
+		case '-':
+			matches[ai] = -1
+			ai++
+		case '+':
+			bi++
+		}
+	}
+	return matches
+}
@@ -1,8 +1,12 @@
 package patch
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
 	"strings"
 )
 
@@ -16,6 +20,8 @@ const (
 	OperationUpdate OperationType = "update"
 	// OperationDelete represents an "*** Delete File" directive.
 	OperationDelete OperationType = "delete"
+	// OperationAddDirectory represents an "*** Add Directory" directive.
+	OperationAddDirectory OperationType = "add-directory"
 )
 
 // Operation describes a high-level instruction contained in a patch payload.
@@ -27,6 +33,10 @@ type Operation struct {
 	Path     string
 	MovePath string
 	Hunks    []Hunk
+	// Mode holds the permission bits requested with "*** File Mode:" or a
+	// git "new mode" line. Zero keeps the existing permissions (0644 for new
+	// files). Only the filesystem appliers honor it.
+	Mode fs.FileMode
 }
 
 // Hunk captures a unified-diff hunk belonging to an Operation.
@@ -45,6 +55,21 @@ type HunkStatus struct {
 	Status string `json:"status"`
 }
 
+// Hunk statuses reported in HunkStatus.
+const (
+	HunkApplied = "applied"
+	// HunkConflict marks a hunk written as conflict markers in best-effort
+	// mode.
+	HunkConflict = "conflict"
+	HunkNoMatch  = "no-match"
+	// HunkAlreadyApplied marks a hunk skipped under
+	// Options.AllowAlreadyApplied because its result is already in the file.
+	HunkAlreadyApplied = "already-applied"
+	// HunkMerged marks a hunk applied by a three-way merge under
+	// Options.Merge.
+	HunkMerged = "merged"
+)
+
 // FailedHunk stores the raw lines of the hunk that could not be applied.
 type FailedHunk struct {
 	Number        int      `json:"number"`
@@ -60,6 +85,9 @@ type Error struct {
 	OriginalContent string
 	HunkStatuses    []HunkStatus
 	FailedHunk      *FailedHunk
+	// Conflict holds the merged lines of a MERGE_CONFLICT hunk, with the
+	// regions both sides changed between conflict markers.
+	Conflict []string
 }
 
 // Error implements the error interface.
@@ -77,6 +105,48 @@ func (e *Error) Error() string {
 // in-memory operations.
 type Options struct {
 	IgnoreWhitespace bool
+	// Reverse applies the inverse of the supplied operations (see Reverse),
+	// rolling back a patch that was previously applied.
+	Reverse bool
+	// Atomic stages every filesystem write in a temporary file and only swaps
+	// them into place once all of them succeeded. If anything fails while
+	// committing, files that were already replaced or deleted are restored so
+	// the workspace is never left half-patched. In-memory application is
+	// always atomic and ignores this flag.
+	Atomic bool
+	// BestEffort keeps going when a hunk cannot be located: the hunk is
+	// written as conflict markers (<<<<<<< ours / ======= / >>>>>>> patch)
+	// around the lines that most resemble its context, and reported with
+	// status "conflict" in the file's Result instead of failing the patch.
+	BestEffort bool
+	// AllowAlreadyApplied skips a hunk whose before lines cannot be found
+	// but whose after lines are already in the file, reporting it with
+	// status "already-applied" instead of failing. Resending a patch that
+	// was partly applied then only applies the missing hunks.
+	AllowAlreadyApplied bool
+	// Merge recovers hunks whose before lines drifted: the lines that most
+	// resemble them are merged three-way with the after lines, using the
+	// before lines as the base. A clean merge is applied with status
+	// "merged". Overlapping changes fail with Error code "MERGE_CONFLICT",
+	// which shows just the conflicting region, unless BestEffort writes them
+	// as conflict markers.
+	Merge bool
+	// MaxFileSize refuses to patch existing files larger than this many
+	// bytes with Error code "FILE_TOO_LARGE". Zero means no limit.
+	MaxFileSize int64
+	// RequireContext makes every located hunk prove its position: up to this
+	// many of its leading and trailing context lines must match the file
+	// exactly, even when whitespace is ignored. Matches that fail are skipped
+	// and, if none passes, the Error (code "CONTEXT_MISMATCH") names the
+	// context line that diverged. It stops short hunks from landing on the
+	// wrong copy of repeated code.
+	RequireContext int
+	// RestrictToWorkingDir resolves symlinks before touching the filesystem
+	// and refuses, with Error code "OUTSIDE_WORKSPACE", any path that lands
+	// outside the working directory, including through a symlinked parent
+	// directory. A path that is itself a symlink is refused with code
+	// "SYMLINK". Paths that escape lexically ("../") are always refused.
+	RestrictToWorkingDir bool
 }
 
 // FilesystemOptions augments Options with a working directory used to resolve
@@ -90,152 +160,262 @@ type FilesystemOptions struct {
 type Result struct {
 	Status string
 	Path   string
+	// Hunks lists the outcome of every hunk applied to the file, in order.
+	Hunks []HunkStatus
+}
+
+// Conflicted reports whether any hunk was written as conflict markers.
+func (r Result) Conflicted() bool {
+	for _, hunk := range r.Hunks {
+		if hunk.Status == HunkConflict {
+			return true
+		}
+	}
+	return false
 }
 
 // Parse converts the textual representation of an apply_patch payload into a
-// slice of operations that can later be applied.
+// slice of operations that can later be applied. Both the "*** Begin Patch"
+// envelope and unified diffs as produced by `git diff` or `diff -u` are
+// accepted; the format is detected from the first header line.
 func Parse(input string) ([]Operation, error) {
-	lines := splitLines(input)
-	var (
-		operations  []Operation
-		currentOp   *Operation
-		currentHunk *Hunk
-		inside      bool
-	)
-
-	flushHunk := func() error {
-		if currentHunk == nil {
-			return nil
+	return ParseReader(strings.NewReader(input))
+}
+
+// ParseReader behaves like Parse but consumes the patch payload incrementally
+// from r, so callers handling very large patches do not need to buffer the
+// whole body into a string first.
+func ParseReader(r io.Reader) ([]Operation, error) {
+	return parseLines(r, &detectingParser{})
+}
+
+// lineParser is implemented by the envelope and unified diff parsers.
+type lineParser interface {
+	feed(line string) error
+	finish() ([]Operation, error)
+}
+
+func parseLines(r io.Reader, p lineParser) ([]Operation, error) {
+	if r == nil {
+		return nil, errors.New("nil patch reader")
+	}
+	reader := bufio.NewReader(r)
+	for {
+		chunk, readErr := reader.ReadString('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return nil, fmt.Errorf("failed to read patch: %w", readErr)
 		}
-		if currentOp == nil {
-			return errors.New("hunk encountered before file directive")
+		atEOF := readErr != nil
+		if !atEOF {
+			chunk = strings.TrimSuffix(chunk, "\n")
 		}
-		parsed, err := parseHunk(currentHunk.Lines, currentOp.Path, currentHunk.Header)
-		if err != nil {
-			return err
+		chunk = strings.TrimSuffix(chunk, "\r")
+		// Lone carriage returns are treated as line breaks, matching the
+		// normalisation applied to in-memory payloads.
+		for _, line := range strings.Split(chunk, "\r") {
+			if err := p.feed(line); err != nil {
+				return nil, err
+			}
+		}
+		if atEOF {
+			break
 		}
-		currentOp.Hunks = append(currentOp.Hunks, parsed)
-		currentHunk = nil
-		return nil
 	}
+	return p.finish()
+}
 
-	flushOp := func() error {
-		if currentOp == nil {
+// detectingParser forwards lines to the envelope or unified parser depending on
+// which header appears first. Lines before that header are ignored by both
+// formats, so nothing needs to be replayed.
+type detectingParser struct {
+	target lineParser
+}
+
+func (p *detectingParser) feed(line string) error {
+	if p.target == nil {
+		switch {
+		case line == "*** Begin Patch":
+			p.target = &parser{}
+		case looksLikeUnifiedDiff(line):
+			p.target = &unifiedParser{}
+		default:
 			return nil
 		}
-		if err := flushHunk(); err != nil {
-			return err
-		}
-		if len(currentOp.Hunks) == 0 && (currentOp.Type != OperationUpdate || strings.TrimSpace(currentOp.MovePath) == "") {
-			return fmt.Errorf("no hunks provided for %s", currentOp.Path)
-		}
-		operations = append(operations, *currentOp)
-		currentOp = nil
+	}
+	return p.target.feed(line)
+}
+
+func (p *detectingParser) finish() ([]Operation, error) {
+	if p.target == nil {
+		return nil, nil
+	}
+	return p.target.finish()
+}
+
+// parser accumulates operations one line at a time.
+type parser struct {
+	operations  []Operation
+	currentOp   *Operation
+	currentHunk *Hunk
+	inside      bool
+}
+
+func (p *parser) flushHunk() error {
+	if p.currentHunk == nil {
 		return nil
 	}
+	if p.currentOp == nil {
+		return errors.New("hunk encountered before file directive")
+	}
+	parsed, err := parseHunk(p.currentHunk.Lines, p.currentOp.Path, p.currentHunk.Header)
+	if err != nil {
+		return err
+	}
+	p.currentOp.Hunks = append(p.currentOp.Hunks, parsed)
+	p.currentHunk = nil
+	return nil
+}
+
+func (p *parser) flushOp() error {
+	if p.currentOp == nil {
+		return nil
+	}
+	if err := p.flushHunk(); err != nil {
+		return err
+	}
+	// Only an update needs hunks, unless it moves the file or changes its
+	// mode; adds without hunks create empty files and deletes take none.
+	if len(p.currentOp.Hunks) == 0 && p.currentOp.Type == OperationUpdate && strings.TrimSpace(p.currentOp.MovePath) == "" && p.currentOp.Mode == 0 {
+		return fmt.Errorf("no hunks provided for %s", p.currentOp.Path)
+	}
+	p.operations = append(p.operations, *p.currentOp)
+	p.currentOp = nil
+	return nil
+}
 
-	for _, rawLine := range lines {
-		line := rawLine
-		switch line {
-		case "*** Begin Patch":
-			inside = true
-			continue
-		case "*** End Patch":
-			if inside {
-				if err := flushOp(); err != nil {
-					return nil, err
-				}
+func (p *parser) feed(line string) error {
+	switch line {
+	case "*** Begin Patch":
+		p.inside = true
+		return nil
+	case "*** End Patch":
+		if p.inside {
+			if err := p.flushOp(); err != nil {
+				return err
 			}
-			inside = false
-			continue
 		}
+		p.inside = false
+		return nil
+	}
 
-		if !inside {
-			continue
-		}
+	if !p.inside {
+		return nil
+	}
 
-		trimmed := strings.TrimSpace(line)
+	trimmed := strings.TrimSpace(line)
 
-		if trimmed == "*** End of File" {
-			if currentOp == nil {
-				return nil, fmt.Errorf("end-of-file marker encountered before a file directive")
-			}
-			if currentHunk == nil {
-				currentHunk = &Hunk{}
-			}
-			currentHunk.Lines = append(currentHunk.Lines, line)
-			continue
+	if trimmed == "*** End of File" {
+		if p.currentOp == nil {
+			return fmt.Errorf("end-of-file marker encountered before a file directive")
 		}
+		if p.currentHunk == nil {
+			p.currentHunk = &Hunk{}
+		}
+		p.currentHunk.Lines = append(p.currentHunk.Lines, line)
+		return nil
+	}
 
-		if strings.HasPrefix(trimmed, "*** Move to: ") {
-			if currentOp == nil {
-				return nil, fmt.Errorf("move directive encountered before a file directive")
-			}
-			if currentOp.Type != OperationUpdate {
-				return nil, fmt.Errorf("move directive only allowed for update operations")
-			}
-			currentOp.MovePath = strings.TrimSpace(strings.TrimPrefix(trimmed, "*** Move to: "))
-			continue
+	if strings.HasPrefix(trimmed, "*** Move to: ") {
+		if p.currentOp == nil {
+			return fmt.Errorf("move directive encountered before a file directive")
+		}
+		if p.currentOp.Type != OperationUpdate {
+			return fmt.Errorf("move directive only allowed for update operations")
 		}
+		p.currentOp.MovePath = strings.TrimSpace(strings.TrimPrefix(trimmed, "*** Move to: "))
+		return nil
+	}
 
-		if strings.HasPrefix(trimmed, "*** Delete File: ") {
-			if err := flushOp(); err != nil {
-				return nil, err
-			}
-			path := strings.TrimSpace(strings.TrimPrefix(trimmed, "*** Delete File: "))
-			operations = append(operations, Operation{Type: OperationDelete, Path: path})
-			currentOp = nil
-			currentHunk = nil
-			continue
+	if value, ok := strings.CutPrefix(trimmed, "*** File Mode: "); ok {
+		if p.currentOp == nil {
+			return fmt.Errorf("file mode directive encountered before a file directive")
+		}
+		mode, err := parseFileMode(value)
+		if err != nil {
+			return fmt.Errorf("invalid file mode for %s: %w", p.currentOp.Path, err)
 		}
+		p.currentOp.Mode = mode
+		return nil
+	}
 
-		if strings.HasPrefix(trimmed, "*** ") {
-			if err := flushOp(); err != nil {
-				return nil, err
-			}
-			if updatePath, ok := strings.CutPrefix(trimmed, "*** Update File: "); ok {
-				path := strings.TrimSpace(updatePath)
-				currentOp = &Operation{Type: OperationUpdate, Path: path}
-				continue
-			}
-			if addPath, ok := strings.CutPrefix(trimmed, "*** Add File: "); ok {
-				path := strings.TrimSpace(addPath)
-				currentOp = &Operation{Type: OperationAdd, Path: path}
-				continue
-			}
-			return nil, fmt.Errorf("unsupported patch directive: %s", line)
+	if strings.HasPrefix(trimmed, "*** Delete File: ") {
+		if err := p.flushOp(); err != nil {
+			return err
 		}
+		path := strings.TrimSpace(strings.TrimPrefix(trimmed, "*** Delete File: "))
+		p.operations = append(p.operations, Operation{Type: OperationDelete, Path: path})
+		p.currentOp = nil
+		p.currentHunk = nil
+		return nil
+	}
 
-		if currentOp == nil {
-			if trimmed == "" {
-				continue
-			}
-			return nil, fmt.Errorf("diff content appeared before a file directive: %q", line)
+	if dirPath, ok := strings.CutPrefix(trimmed, "*** Add Directory: "); ok {
+		if err := p.flushOp(); err != nil {
+			return err
 		}
+		p.operations = append(p.operations, Operation{Type: OperationAddDirectory, Path: strings.TrimSpace(dirPath)})
+		return nil
+	}
 
-		if strings.HasPrefix(line, "@@") {
-			if err := flushHunk(); err != nil {
-				return nil, err
-			}
-			currentHunk = &Hunk{Header: line}
-			continue
+	if strings.HasPrefix(trimmed, "*** ") {
+		if err := p.flushOp(); err != nil {
+			return err
+		}
+		if updatePath, ok := strings.CutPrefix(trimmed, "*** Update File: "); ok {
+			path := strings.TrimSpace(updatePath)
+			p.currentOp = &Operation{Type: OperationUpdate, Path: path}
+			return nil
+		}
+		if addPath, ok := strings.CutPrefix(trimmed, "*** Add File: "); ok {
+			path := strings.TrimSpace(addPath)
+			p.currentOp = &Operation{Type: OperationAdd, Path: path}
+			return nil
+		}
+		return fmt.Errorf("unsupported patch directive: %s", line)
+	}
+
+	if p.currentOp == nil {
+		if trimmed == "" {
+			return nil
 		}
+		return fmt.Errorf("diff content appeared before a file directive: %q", line)
+	}
 
-		if currentHunk == nil {
-			currentHunk = &Hunk{}
+	if strings.HasPrefix(line, "@@") {
+		if err := p.flushHunk(); err != nil {
+			return err
 		}
-		currentHunk.Lines = append(currentHunk.Lines, line)
+		p.currentHunk = &Hunk{Header: line}
+		return nil
 	}
 
-	if inside {
+	if p.currentHunk == nil {
+		p.currentHunk = &Hunk{}
+	}
+	p.currentHunk.Lines = append(p.currentHunk.Lines, line)
+	return nil
+}
+
+func (p *parser) finish() ([]Operation, error) {
+	if p.inside {
 		return nil, errors.New("missing *** End Patch terminator")
 	}
 
-	if err := flushOp(); err != nil {
+	if err := p.flushOp(); err != nil {
 		return nil, err
 	}
 
-	return operations, nil
+	return p.operations, nil
 }
 
 func parseHunk(lines []string, filePath, header string) (Hunk, error) {
@@ -266,8 +446,20 @@ func parseHunk(lines []string, filePath, header string) (Hunk, error) {
 	return hunk, nil
 }
 
-func splitLines(input string) []string {
-	normalized := strings.ReplaceAll(input, "\r\n", "\n")
-	normalized = strings.ReplaceAll(normalized, "\r", "\n")
-	return strings.Split(normalized, "\n")
+// parseFileMode reads an octal permission set such as "755", "0755", or the
+// git form "100755". Only permission bits are accepted.
+func parseFileMode(value string) (fs.FileMode, error) {
+	value = strings.TrimSpace(value)
+	parsed, err := strconv.ParseUint(value, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not an octal mode", value)
+	}
+	// Git prefixes regular files with their object type.
+	if parsed&^0o777 == 0o100000 {
+		parsed &= 0o777
+	}
+	if parsed == 0 || parsed > 0o777 {
+		return 0, fmt.Errorf("%q is not a permission mode between 001 and 777", value)
+	}
+	return fs.FileMode(parsed), nil
 }
@@ -35,3 +35,29 @@ func TestParseErrorsOnMissingEnd(t *testing.T) {
 		t.Fatalf("expected error for missing terminator")
 	}
 }
+
+func TestParseReadsFileModes(t *testing.T) {
+	t.Parallel()
+
+	envelope := "*** Begin Patch\n*** Add File: run.sh\n*** File Mode: 0755\n+#!/bin/sh\n*** Update File: tool.sh\n*** File Mode: 644\n*** End Patch\n"
+	ops, err := Parse(envelope)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(ops) != 2 || ops[0].Mode != 0o755 || ops[1].Mode != 0o644 || len(ops[1].Hunks) != 0 {
+		t.Fatalf("unexpected operations: %#v", ops)
+	}
+
+	gitDiff := "diff --git a/tool.sh b/tool.sh\nold mode 100644\nnew mode 100755\n"
+	ops, err = Parse(gitDiff)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(ops) != 1 || ops[0].Type != OperationUpdate || ops[0].Path != "tool.sh" || ops[0].Mode != 0o755 {
+		t.Fatalf("unexpected mode-only operation: %#v", ops)
+	}
+
+	if _, err := Parse("*** Begin Patch\n*** Add File: x\n*** File Mode: 9999\n+x\n*** End Patch\n"); err == nil {
+		t.Fatalf("expected an error for a malformed mode")
+	}
+}
@@ -0,0 +1,65 @@
+package patch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestParseReaderMatchesParse(t *testing.T) {
+	t.Parallel()
+
+	patchBody := "*** Begin Patch\r\n*** Update File: a.txt\r\n@@\r\n-old\r\n+new\r\n*** Delete File: b.txt\r\n*** End Patch\r\n"
+	want, err := Parse(patchBody)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	// OneByteReader forces the parser to assemble lines across many reads.
+	got, err := ParseReader(iotest.OneByteReader(strings.NewReader(patchBody)))
+	if err != nil {
+		t.Fatalf("ParseReader returned error: %v", err)
+	}
+	if len(got) != len(want) || len(got) != 2 {
+		t.Fatalf("unexpected operations: %#v", got)
+	}
+	if got[0].Path != "a.txt" || len(got[0].Hunks) != 1 || got[0].Hunks[0].After[0] != "new" {
+		t.Fatalf("unexpected update operation: %#v", got[0])
+	}
+	if got[1].Type != OperationDelete || got[1].Path != "b.txt" {
+		t.Fatalf("unexpected delete operation: %#v", got[1])
+	}
+}
+
+func TestParseReaderPropagatesReadErrors(t *testing.T) {
+	t.Parallel()
+
+	boom := errors.New("boom")
+	if _, err := ParseReader(iotest.ErrReader(boom)); !errors.Is(err, boom) {
+		t.Fatalf("expected read error to be wrapped, got %v", err)
+	}
+}
+
+func TestApplyFilesystemPatchReaderAddsFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	patchBody := "*** Begin Patch\n*** Add File: nested/new.txt\n+hello\n*** End Patch\n"
+	results, err := ApplyFilesystemPatchReader(context.Background(), strings.NewReader(patchBody), FilesystemOptions{WorkingDir: dir})
+	if err != nil {
+		t.Fatalf("ApplyFilesystemPatchReader returned error: %v", err)
+	}
+	if len(results) != 1 || results[0].Status != "A" {
+		t.Fatalf("unexpected results: %#v", results)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "nested", "new.txt"))
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(content) != "hello" {
+		t.Fatalf("unexpected content: %q", content)
+	}
+}
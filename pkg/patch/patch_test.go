@@ -77,3 +77,32 @@ func TestApplyMemoryPatchReportsParseError(t *testing.T) {
 		t.Fatalf("expected parse error")
 	}
 }
+
+func TestApplyMemoryPatchReverseRollsBackChanges(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	patchBody := "*** Begin Patch\n*** Update File: a.txt\n@@\n-one\n+uno\n*** Move to: b.txt\n*** Add File: c.txt\n+new\n*** End Patch\n"
+	initial := map[string]string{"a.txt": "one\ntwo\n"}
+
+	applied, _, err := ApplyMemoryPatch(ctx, patchBody, initial, Options{})
+	if err != nil {
+		t.Fatalf("ApplyMemoryPatch returned error: %v", err)
+	}
+
+	restored, _, err := ApplyMemoryPatch(ctx, patchBody, applied, Options{Reverse: true})
+	if err != nil {
+		t.Fatalf("reverse apply returned error: %v", err)
+	}
+	if len(restored) != 1 || restored["a.txt"] != "one\ntwo\n" {
+		t.Fatalf("unexpected restored files: %#v", restored)
+	}
+}
+
+func TestReverseRejectsDeletions(t *testing.T) {
+	t.Parallel()
+
+	if _, err := Reverse([]Operation{{Type: OperationDelete, Path: "gone.txt"}}); err == nil {
+		t.Fatalf("expected error when reversing a deletion")
+	}
+}
@@ -0,0 +1,71 @@
+package patch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reverse builds the inverse of the supplied operations so that a previously
+// applied patch can be rolled back. Additions become deletions, updates have
+// their hunks inverted, and moves are pointed back at the original path. The
+// operations are returned in reverse order so dependent edits unwind cleanly.
+//
+// Deletions cannot be reversed because the patch format does not record the
+// removed content; Reverse reports an error when it encounters one. File modes
+// are dropped for the same reason.
+func Reverse(operations []Operation) ([]Operation, error) {
+	reversed := make([]Operation, 0, len(operations))
+	for i := len(operations) - 1; i >= 0; i-- {
+		op := operations[i]
+		switch op.Type {
+		case OperationAdd:
+			reversed = append(reversed, Operation{Type: OperationDelete, Path: op.Path})
+		case OperationDelete:
+			return nil, fmt.Errorf("cannot reverse deletion of %s: original content is unknown", op.Path)
+		case OperationAddDirectory:
+			return nil, fmt.Errorf("cannot reverse creation of directory %s", op.Path)
+		case OperationUpdate:
+			inverse := Operation{Type: OperationUpdate, Path: op.Path}
+			if target := strings.TrimSpace(op.MovePath); target != "" {
+				inverse.Path = target
+				inverse.MovePath = op.Path
+			}
+			for _, hunk := range op.Hunks {
+				inverse.Hunks = append(inverse.Hunks, reverseHunk(hunk))
+			}
+			reversed = append(reversed, inverse)
+		default:
+			return nil, fmt.Errorf("unsupported patch operation for %s: %s", op.Path, op.Type)
+		}
+	}
+	return reversed, nil
+}
+
+func reverseHunk(hunk Hunk) Hunk {
+	return Hunk{
+		Header:        hunk.Header,
+		Lines:         reverseDiffLines(hunk.Lines),
+		RawPatchLines: reverseDiffLines(hunk.RawPatchLines),
+		Before:        append([]string(nil), hunk.After...),
+		After:         append([]string(nil), hunk.Before...),
+		AtEOF:         hunk.AtEOF,
+	}
+}
+
+func reverseDiffLines(lines []string) []string {
+	if lines == nil {
+		return nil
+	}
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			out[i] = "-" + line[1:]
+		case strings.HasPrefix(line, "-"):
+			out[i] = "+" + line[1:]
+		default:
+			out[i] = line
+		}
+	}
+	return out
+}
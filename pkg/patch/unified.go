@@ -0,0 +1,299 @@
+package patch
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// ParseUnified converts the output of `git diff` or `diff -u` into operations.
+//
+// File headers ("diff --git", "---"/"+++"), "@@" hunk ranges, new/deleted file
+// markers, rename lines, and the new mode of regular files are understood. Line numbers in hunk headers are only used to delimit the
+// hunk bodies; hunks are located by content like their "*** Begin Patch"
+// counterparts.
+func ParseUnified(input string) ([]Operation, error) {
+	return parseLines(strings.NewReader(input), &unifiedParser{})
+}
+
+// unifiedParser accumulates operations from unified diff text one line at a
+// time.
+type unifiedParser struct {
+	operations []Operation
+	file       *unifiedFile
+	hunk       *unifiedHunk
+}
+
+type unifiedFile struct {
+	oldPath string
+	newPath string
+	added   bool
+	deleted bool
+	hunks   []Hunk
+	mode    fs.FileMode
+}
+
+type unifiedHunk struct {
+	header       string
+	lines        []string
+	oldRemaining int
+	newRemaining int
+}
+
+func (p *unifiedParser) feed(line string) error {
+	if p.hunk != nil {
+		if p.hunk.oldRemaining > 0 || p.hunk.newRemaining > 0 {
+			return p.feedHunkLine(line)
+		}
+		// A trailing "no newline" marker belongs to the hunk that just ended.
+		if line == "\\ No newline at end of file" {
+			p.hunk.lines = append(p.hunk.lines, line)
+			return nil
+		}
+		if err := p.flushHunk(); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(line, "diff --git "):
+		if err := p.flushFile(); err != nil {
+			return err
+		}
+		oldPath, newPath := parseGitHeaderPaths(strings.TrimPrefix(line, "diff --git "))
+		p.file = &unifiedFile{oldPath: oldPath, newPath: newPath}
+	case strings.HasPrefix(line, "--- "):
+		// Plain `diff -u` output has no "diff --git" line, so a "---" header
+		// after hunks starts a new file.
+		if p.file == nil || len(p.file.hunks) > 0 {
+			if err := p.flushFile(); err != nil {
+				return err
+			}
+			p.file = &unifiedFile{}
+		}
+		path := parseUnifiedPath(strings.TrimPrefix(line, "--- "))
+		if path == "" {
+			p.file.added = true
+		} else {
+			p.file.oldPath = path
+		}
+	case strings.HasPrefix(line, "+++ "):
+		if p.file == nil {
+			return fmt.Errorf("unexpected +++ header without preceding --- header: %q", line)
+		}
+		path := parseUnifiedPath(strings.TrimPrefix(line, "+++ "))
+		if path == "" {
+			p.file.deleted = true
+		} else {
+			p.file.newPath = path
+		}
+	case strings.HasPrefix(line, "@@"):
+		if p.file == nil {
+			return fmt.Errorf("hunk encountered before file header: %q", line)
+		}
+		oldCount, newCount, err := parseHunkRange(line)
+		if err != nil {
+			return err
+		}
+		p.hunk = &unifiedHunk{header: line, oldRemaining: oldCount, newRemaining: newCount}
+	case p.file != nil && strings.HasPrefix(line, "new file mode"):
+		p.file.added = true
+		return p.file.setMode(strings.TrimPrefix(line, "new file mode"))
+	case p.file != nil && strings.HasPrefix(line, "new mode "):
+		return p.file.setMode(strings.TrimPrefix(line, "new mode "))
+	case p.file != nil && strings.HasPrefix(line, "deleted file mode"):
+		p.file.deleted = true
+	case p.file != nil && strings.HasPrefix(line, "rename from "):
+		p.file.oldPath = strings.TrimSpace(strings.TrimPrefix(line, "rename from "))
+	case p.file != nil && strings.HasPrefix(line, "rename to "):
+		p.file.newPath = strings.TrimSpace(strings.TrimPrefix(line, "rename to "))
+	case p.file != nil && strings.HasPrefix(line, "Binary files "):
+		return fmt.Errorf("binary patches are not supported: %s", line)
+	default:
+		// index, old mode, similarity lines and any surrounding prose carry
+		// no information needed to apply the patch.
+	}
+	return nil
+}
+
+func (p *unifiedParser) feedHunkLine(line string) error {
+	h := p.hunk
+	switch {
+	case line == "\\ No newline at end of file":
+		h.lines = append(h.lines, line)
+		return nil
+	case strings.HasPrefix(line, "+"):
+		h.newRemaining--
+	case strings.HasPrefix(line, "-"):
+		h.oldRemaining--
+	case strings.HasPrefix(line, " "):
+		h.oldRemaining--
+		h.newRemaining--
+	case line == "":
+		// Some tools strip the leading space from blank context lines.
+		line = " "
+		h.oldRemaining--
+		h.newRemaining--
+	default:
+		return fmt.Errorf("unsupported hunk line in %s: %q", p.file.displayPath(), line)
+	}
+	if h.oldRemaining < 0 || h.newRemaining < 0 {
+		return fmt.Errorf("hunk %q in %s is longer than its header declares", h.header, p.file.displayPath())
+	}
+	h.lines = append(h.lines, line)
+	return nil
+}
+
+func (p *unifiedParser) flushHunk() error {
+	if p.hunk == nil {
+		return nil
+	}
+	if p.hunk.oldRemaining > 0 || p.hunk.newRemaining > 0 {
+		return fmt.Errorf("hunk %q in %s is truncated", p.hunk.header, p.file.displayPath())
+	}
+	parsed, err := parseHunk(p.hunk.lines, p.file.displayPath(), p.hunk.header)
+	if err != nil {
+		return err
+	}
+	p.file.hunks = append(p.file.hunks, parsed)
+	p.hunk = nil
+	return nil
+}
+
+func (p *unifiedParser) flushFile() error {
+	if err := p.flushHunk(); err != nil {
+		return err
+	}
+	file := p.file
+	p.file = nil
+	if file == nil {
+		return nil
+	}
+	switch {
+	case file.deleted:
+		if file.oldPath == "" {
+			return errors.New("deleted file is missing its path")
+		}
+		p.operations = append(p.operations, Operation{Type: OperationDelete, Path: file.oldPath})
+	case file.added:
+		if file.newPath == "" {
+			return errors.New("added file is missing its path")
+		}
+		p.operations = append(p.operations, Operation{Type: OperationAdd, Path: file.newPath, Hunks: file.hunks, Mode: file.mode})
+	default:
+		op := Operation{Type: OperationUpdate, Path: file.oldPath, Hunks: file.hunks, Mode: file.mode}
+		if op.Path == "" {
+			op.Path = file.newPath
+		}
+		if file.newPath != "" && file.newPath != op.Path {
+			op.MovePath = file.newPath
+		}
+		if op.Path == "" {
+			return errors.New("diff is missing file headers")
+		}
+		if len(op.Hunks) == 0 && op.MovePath == "" && op.Mode == 0 {
+			return nil
+		}
+		p.operations = append(p.operations, op)
+	}
+	return nil
+}
+
+func (p *unifiedParser) finish() ([]Operation, error) {
+	if err := p.flushFile(); err != nil {
+		return nil, err
+	}
+	return p.operations, nil
+}
+
+// setMode records a git mode such as "100755". Symlinks and submodules have
+// no permission bits to apply and are left alone.
+func (f *unifiedFile) setMode(value string) error {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "100") {
+		return nil
+	}
+	mode, err := parseFileMode(value)
+	if err != nil {
+		return fmt.Errorf("invalid file mode for %s: %w", f.displayPath(), err)
+	}
+	f.mode = mode
+	return nil
+}
+
+func (f *unifiedFile) displayPath() string {
+	if f.newPath != "" {
+		return f.newPath
+	}
+	return f.oldPath
+}
+
+// parseHunkRange extracts the old and new line counts from an "@@ -a,b +c,d @@"
+// header. Omitted counts default to one, as in the diff format.
+func parseHunkRange(header string) (int, int, error) {
+	fields := strings.Fields(strings.TrimPrefix(header, "@@"))
+	if len(fields) < 2 || !strings.HasPrefix(fields[0], "-") || !strings.HasPrefix(fields[1], "+") {
+		return 0, 0, fmt.Errorf("malformed hunk header: %q", header)
+	}
+	oldCount, err := parseRangeCount(fields[0][1:])
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed hunk header %q: %w", header, err)
+	}
+	newCount, err := parseRangeCount(fields[1][1:])
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed hunk header %q: %w", header, err)
+	}
+	return oldCount, newCount, nil
+}
+
+func parseRangeCount(value string) (int, error) {
+	start, count, found := strings.Cut(value, ",")
+	if _, err := strconv.Atoi(start); err != nil {
+		return 0, err
+	}
+	if !found {
+		return 1, nil
+	}
+	return strconv.Atoi(count)
+}
+
+// parseUnifiedPath cleans a ---/+++ header value, returning an empty string for
+// /dev/null.
+func parseUnifiedPath(value string) string {
+	// diff -u appends a tab-separated timestamp.
+	if tab := strings.IndexByte(value, '\t'); tab != -1 {
+		value = value[:tab]
+	}
+	value = strings.TrimSpace(value)
+	if value == "/dev/null" {
+		return ""
+	}
+	return stripDiffPrefix(value)
+}
+
+func parseGitHeaderPaths(value string) (string, string) {
+	// Paths without spaces are the common case; anything ambiguous is
+	// resolved later by the ---/+++ or rename lines.
+	if index := strings.Index(value, " b/"); index != -1 && strings.HasPrefix(value, "a/") {
+		return value[2:index], value[index+3:]
+	}
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return "", ""
+	}
+	return stripDiffPrefix(fields[0]), stripDiffPrefix(fields[1])
+}
+
+func stripDiffPrefix(path string) string {
+	if strings.HasPrefix(path, "a/") || strings.HasPrefix(path, "b/") {
+		return path[2:]
+	}
+	return path
+}
+
+// looksLikeUnifiedDiff reports whether line opens a unified or git diff.
+func looksLikeUnifiedDiff(line string) bool {
+	return strings.HasPrefix(line, "diff --git ") || strings.HasPrefix(line, "--- ")
+}
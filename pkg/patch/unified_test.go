@@ -0,0 +1,97 @@
+package patch
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseDetectsGitDiff(t *testing.T) {
+	t.Parallel()
+
+	diff := strings.Join([]string{
+		"diff --git a/notes.txt b/notes.txt",
+		"index 1234567..89abcde 100644",
+		"--- a/notes.txt",
+		"+++ b/notes.txt",
+		"@@ -1,3 +1,3 @@",
+		" alpha",
+		"--- dashes",
+		"+++ pluses",
+		" gamma",
+		"diff --git a/new.txt b/new.txt",
+		"new file mode 100644",
+		"--- /dev/null",
+		"+++ b/new.txt",
+		"@@ -0,0 +1 @@",
+		"+fresh",
+		"diff --git a/old.txt b/old.txt",
+		"deleted file mode 100644",
+		"--- a/old.txt",
+		"+++ /dev/null",
+		"@@ -1 +0,0 @@",
+		"-stale",
+		"diff --git a/from.txt b/to.txt",
+		"similarity index 100%",
+		"rename from from.txt",
+		"rename to to.txt",
+		"",
+	}, "\n")
+
+	ops, err := Parse(diff)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(ops) != 4 {
+		t.Fatalf("expected four operations, got %#v", ops)
+	}
+	if ops[0].Type != OperationUpdate || ops[0].Path != "notes.txt" {
+		t.Fatalf("unexpected update: %#v", ops[0])
+	}
+	if got := ops[0].Hunks[0].Before; len(got) != 3 || got[1] != "-- dashes" {
+		t.Fatalf("unexpected before lines: %#v", got)
+	}
+	if ops[1].Type != OperationAdd || ops[1].Path != "new.txt" {
+		t.Fatalf("unexpected add: %#v", ops[1])
+	}
+	if ops[2].Type != OperationDelete || ops[2].Path != "old.txt" {
+		t.Fatalf("unexpected delete: %#v", ops[2])
+	}
+	if ops[3].Type != OperationUpdate || ops[3].Path != "from.txt" || ops[3].MovePath != "to.txt" {
+		t.Fatalf("unexpected rename: %#v", ops[3])
+	}
+}
+
+func TestParseUnifiedAppliesDiffU(t *testing.T) {
+	t.Parallel()
+
+	diff := strings.Join([]string{
+		"--- notes.txt\t2024-01-01 00:00:00.000000000 +0000",
+		"+++ notes.txt\t2024-01-02 00:00:00.000000000 +0000",
+		"@@ -1,2 +1,2 @@",
+		"-alpha",
+		"+omega",
+		" beta",
+	}, "\n")
+
+	ops, err := ParseUnified(diff)
+	if err != nil {
+		t.Fatalf("ParseUnified returned error: %v", err)
+	}
+	updated, _, err := ApplyToMemory(context.Background(), ops, map[string]string{"notes.txt": "alpha\nbeta\n"}, Options{})
+	if err != nil {
+		t.Fatalf("ApplyToMemory returned error: %v", err)
+	}
+	if got := updated["notes.txt"]; got != "omega\nbeta\n" {
+		t.Fatalf("unexpected content: %q", got)
+	}
+}
+
+func TestParseUnifiedRejectsTruncatedHunk(t *testing.T) {
+	t.Parallel()
+
+	diff := "--- a/x.txt\n+++ b/x.txt\n@@ -1,3 +1,3 @@\n one\n"
+	if _, err := ParseUnified(diff); err == nil {
+		t.Fatalf("expected error for truncated hunk")
+	}
+}
@@ -0,0 +1,243 @@
+package patch
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Severity grades a Diagnostic.
+type Severity string
+
+const (
+	// SeverityError marks a problem that makes the patch fail to apply.
+	SeverityError Severity = "error"
+	// SeverityWarning marks a patch that applies but probably not as meant.
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a problem Validate found in a patch.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Path     string   `json:"path"`
+	// Hunk is the 1-based hunk number within the operation, zero when the
+	// diagnostic concerns the whole operation.
+	Hunk    int    `json:"hunk,omitempty"`
+	Message string `json:"message"`
+}
+
+// Validate checks operations against fsys without applying them. Operations
+// are replayed in order against a virtual copy of the files they touch, so an
+// update of a file added earlier in the same patch is fine. It reports:
+//
+//   - MISSING_TARGET: an update, move or delete of a file that does not exist
+//   - INVALID_PATH: a path that is empty or escapes the root
+//   - DUPLICATE_OPERATION: a file targeted by more than one add or update
+//   - ADD_OVER_EXISTING: an add or move that replaces an existing file
+//   - DELETE_MODIFIED: a delete of a file the patch modified first
+//   - HUNK_NOT_FOUND: a hunk whose lines are not in the file
+//   - OVERLAPPING_HUNKS: hunks of one operation that claim the same lines
+//   - BINARY_FILE: an update of a binary file
+//
+// Hunks are located like Apply with IgnoreWhitespace set, so a patch that only
+// differs in whitespace passes here but may fail a strict apply.
+func Validate(operations []Operation, fsys fs.FS) []Diagnostic {
+	v := &validator{fsys: fsys, files: make(map[string]*virtualFile)}
+	for i, op := range operations {
+		v.check(i+1, op)
+	}
+	return v.diagnostics
+}
+
+// HasErrors reports whether any diagnostic has error severity.
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatDiagnostics renders diagnostics one per line, errors first.
+func FormatDiagnostics(diagnostics []Diagnostic) string {
+	var lines []string
+	for _, severity := range []Severity{SeverityError, SeverityWarning} {
+		for _, d := range diagnostics {
+			if d.Severity != severity {
+				continue
+			}
+			location := d.Path
+			if d.Hunk > 0 {
+				location = fmt.Sprintf("%s (hunk %d)", d.Path, d.Hunk)
+			}
+			lines = append(lines, fmt.Sprintf("%s %s: %s: %s", d.Severity, d.Code, location, d.Message))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// virtualFile is a file as the patch sees it after the operations so far.
+type virtualFile struct {
+	exists bool
+	binary bool
+	lines  []string
+	// operation is the first add or update targeting the file, zero if none.
+	operation int
+	modified  bool
+}
+
+type validator struct {
+	fsys        fs.FS
+	files       map[string]*virtualFile
+	diagnostics []Diagnostic
+}
+
+func (v *validator) report(severity Severity, code, path string, hunk int, format string, args ...any) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{
+		Severity: severity,
+		Code:     code,
+		Path:     path,
+		Hunk:     hunk,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) check(number int, op Operation) {
+	name, ok := validatePath(op.Path)
+	if !ok {
+		v.report(SeverityError, "INVALID_PATH", op.Path, 0, "operation %d has an invalid path", number)
+		return
+	}
+	file := v.lookup(name)
+
+	switch op.Type {
+	case OperationDelete:
+		if !file.exists {
+			v.report(SeverityError, "MISSING_TARGET", name, 0, "cannot delete a file that does not exist")
+			return
+		}
+		if file.modified {
+			v.report(SeverityWarning, "DELETE_MODIFIED", name, 0, "operation %d deletes the file after operation %d changed it; those changes are lost", number, file.operation)
+		}
+		*file = virtualFile{}
+	case OperationAddDirectory:
+		if file.exists {
+			v.report(SeverityError, "ADD_OVER_EXISTING", name, 0, "cannot add a directory where a file exists")
+		}
+	case OperationAdd, OperationUpdate:
+		if file.operation > 0 {
+			v.report(SeverityWarning, "DUPLICATE_OPERATION", name, 0, "operation %d targets the file again after operation %d; merge them into one", number, file.operation)
+		} else {
+			file.operation = number
+		}
+		if op.Type == OperationAdd {
+			if file.exists {
+				v.report(SeverityWarning, "ADD_OVER_EXISTING", name, 0, "the file exists and its content will be replaced")
+			}
+			*file = virtualFile{exists: true, operation: file.operation, lines: []string{}}
+		} else if !file.exists {
+			v.report(SeverityError, "MISSING_TARGET", name, 0, "cannot update a file that does not exist")
+			return
+		} else if file.binary {
+			v.report(SeverityError, "BINARY_FILE", name, 0, "binary files cannot be patched")
+			return
+		}
+		v.checkHunks(name, file, op.Hunks)
+		file.modified = true
+		if target := strings.TrimSpace(op.MovePath); target != "" {
+			v.move(name, file, target)
+		}
+	default:
+		v.report(SeverityError, "UNSUPPORTED_OPERATION", name, 0, "unsupported operation %q", op.Type)
+	}
+}
+
+// checkHunks locates every hunk against the file as it was before the
+// operation, then applies them to the virtual copy for later operations.
+func (v *validator) checkHunks(name string, file *virtualFile, hunks []Hunk) {
+	type span struct{ number, start, end int }
+	var spans []span
+	cursor := 0
+	for i, hunk := range hunks {
+		if len(hunk.Before) == 0 {
+			continue
+		}
+		located := &state{lines: file.lines, cursor: cursor, options: Options{IgnoreWhitespace: true}}
+		start, _ := locateHunk(located, hunk)
+		if start == -1 {
+			v.report(SeverityError, "HUNK_NOT_FOUND", name, i+1, "the hunk's context and removed lines are not in the file")
+			continue
+		}
+		end := start + len(hunk.Before)
+		for _, other := range spans {
+			if start < other.end && other.start < end {
+				v.report(SeverityError, "OVERLAPPING_HUNKS", name, i+1, "the hunk changes lines %d-%d, which hunk %d also changes", start+1, end, other.number)
+			}
+		}
+		spans = append(spans, span{number: i + 1, start: start, end: end})
+		cursor = end
+	}
+
+	patched := &state{lines: file.lines, options: Options{IgnoreWhitespace: true}}
+	for _, hunk := range hunks {
+		_ = applyHunk(patched, hunk)
+	}
+	file.lines = patched.lines
+}
+
+func (v *validator) move(name string, file *virtualFile, target string) {
+	targetName, ok := validatePath(target)
+	if !ok {
+		v.report(SeverityError, "INVALID_PATH", target, 0, "invalid move destination for %s", name)
+		return
+	}
+	if targetName == name {
+		return
+	}
+	if destination := v.lookup(targetName); destination.exists {
+		v.report(SeverityWarning, "ADD_OVER_EXISTING", targetName, 0, "moving %s here replaces an existing file", name)
+	}
+	moved := *file
+	v.files[targetName] = &moved
+	*file = virtualFile{}
+}
+
+// lookup returns the virtual file for name, reading it from fsys the first
+// time.
+func (v *validator) lookup(name string) *virtualFile {
+	if file, ok := v.files[name]; ok {
+		return file
+	}
+	file := &virtualFile{}
+	v.files[name] = file
+	if v.fsys == nil {
+		return file
+	}
+	content, err := fs.ReadFile(v.fsys, name)
+	if err != nil {
+		// Directories and unreadable files count as present so they are not
+		// reported missing, but they carry no lines to match.
+		file.exists = !errors.Is(err, fs.ErrNotExist)
+		return file
+	}
+	file.exists = true
+	file.binary = checkPatchable(name, 0, content, Options{}) != nil
+	normalized := strings.ReplaceAll(string(content), "\r\n", "\n")
+	normalized = strings.ReplaceAll(normalized, "\r", "\n")
+	file.lines = strings.Split(normalized, "\n")
+	return file
+}
+
+// validatePath converts a patch path into an fs.FS name.
+func validatePath(p string) (string, bool) {
+	cleaned := path.Clean(strings.TrimLeft(filepath.ToSlash(strings.TrimSpace(p)), "/"))
+	if cleaned == "." || !fs.ValidPath(cleaned) {
+		return "", false
+	}
+	return cleaned, true
+}
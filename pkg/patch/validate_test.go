@@ -0,0 +1,79 @@
+package patch
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestValidateReportsProblemsWithoutApplying(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"main.go":   {Data: []byte("package main\n\nfunc main() {\n\tprintln(1)\n}\n")},
+		"readme.md": {Data: []byte("# title\n")},
+		"logo.png":  {Data: []byte("\x89PNG\x00\x00")},
+	}
+	operations := []Operation{
+		{Type: OperationUpdate, Path: "missing.go", Hunks: []Hunk{{Before: []string{"a"}, After: []string{"b"}}}},
+		{Type: OperationUpdate, Path: "main.go", Hunks: []Hunk{
+			{Before: []string{"func main() {", "\tprintln(1)"}, After: []string{"func main() {", "\tprintln(2)"}},
+			{Before: []string{"\tprintln(1)", "}"}, After: []string{"\tprintln(3)", "}"}},
+			{Before: []string{"not there"}, After: []string{"x"}},
+		}},
+		{Type: OperationDelete, Path: "main.go"},
+		{Type: OperationAdd, Path: "readme.md", Hunks: []Hunk{{After: []string{"new"}}}},
+		{Type: OperationUpdate, Path: "readme.md", Hunks: []Hunk{{Before: []string{"new"}, After: []string{"newer"}}}},
+		{Type: OperationUpdate, Path: "logo.png", Hunks: []Hunk{{Before: []string{"x"}, After: []string{"y"}}}},
+		{Type: OperationAdd, Path: "../outside.txt", Hunks: []Hunk{{After: []string{"x"}}}},
+	}
+
+	diagnostics := Validate(operations, fsys)
+	want := []struct {
+		severity Severity
+		code     string
+		path     string
+		hunk     int
+	}{
+		{SeverityError, "MISSING_TARGET", "missing.go", 0},
+		{SeverityError, "OVERLAPPING_HUNKS", "main.go", 2},
+		{SeverityError, "HUNK_NOT_FOUND", "main.go", 3},
+		{SeverityWarning, "DELETE_MODIFIED", "main.go", 0},
+		{SeverityWarning, "ADD_OVER_EXISTING", "readme.md", 0},
+		{SeverityWarning, "DUPLICATE_OPERATION", "readme.md", 0},
+		{SeverityError, "BINARY_FILE", "logo.png", 0},
+		{SeverityError, "INVALID_PATH", "../outside.txt", 0},
+	}
+	if len(diagnostics) != len(want) {
+		t.Fatalf("expected %d diagnostics, got %#v", len(want), diagnostics)
+	}
+	for i, w := range want {
+		d := diagnostics[i]
+		if d.Severity != w.severity || d.Code != w.code || d.Path != w.path || d.Hunk != w.hunk {
+			t.Fatalf("diagnostic %d: got %#v, want %+v", i, d, w)
+		}
+	}
+	if !HasErrors(diagnostics) {
+		t.Fatalf("expected HasErrors to be true")
+	}
+	formatted := FormatDiagnostics(diagnostics)
+	if !strings.HasPrefix(formatted, "error MISSING_TARGET: missing.go:") || !strings.Contains(formatted, "warning DELETE_MODIFIED: main.go:") {
+		t.Fatalf("unexpected formatting:\n%s", formatted)
+	}
+}
+
+func TestValidateFollowsEarlierOperations(t *testing.T) {
+	t.Parallel()
+
+	operations := []Operation{
+		{Type: OperationAdd, Path: "new.txt", Hunks: []Hunk{{After: []string{"one"}}}},
+		{Type: OperationUpdate, Path: "old.txt", MovePath: "moved.txt", Hunks: []Hunk{{Before: []string{"a"}, After: []string{"b"}}}},
+		{Type: OperationUpdate, Path: "moved.txt", Hunks: []Hunk{{Before: []string{"b"}, After: []string{"c"}}}},
+	}
+	diagnostics := Validate(operations, fstest.MapFS{"old.txt": {Data: []byte("a\n")}})
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			t.Fatalf("unexpected error: %#v", d)
+		}
+	}
+}
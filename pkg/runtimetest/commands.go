@@ -0,0 +1,94 @@
+package runtimetest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/asynkron/goagent/internal/core/runtime"
+)
+
+// CommandResult is the canned outcome of a shell step.
+type CommandResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	// Err is returned as the execution error, e.g. to simulate a timeout.
+	Err error
+}
+
+// FakeCommands is a runtime.CommandRunner that answers shell steps with
+// canned results keyed by their run string instead of starting processes.
+// Steps without a registered result succeed with empty output unless Strict
+// is set. It is safe for concurrent use.
+type FakeCommands struct {
+	// Strict fails steps whose run string has no registered result.
+	Strict bool
+
+	mu      sync.Mutex
+	results map[string]CommandResult
+	steps   []runtime.PlanStep
+}
+
+// NewFakeCommands returns a runner without registered results.
+func NewFakeCommands() *FakeCommands {
+	return &FakeCommands{results: make(map[string]CommandResult)}
+}
+
+// On registers the result returned for steps whose trimmed run string equals
+// run, and returns the runner for chaining.
+func (f *FakeCommands) On(run string, result CommandResult) *FakeCommands {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.results[strings.TrimSpace(run)] = result
+	return f
+}
+
+// RunCommand records step and returns its canned observation.
+func (f *FakeCommands) RunCommand(ctx context.Context, step runtime.PlanStep) (runtime.PlanObservationPayload, error) {
+	if err := ctx.Err(); err != nil {
+		return runtime.PlanObservationPayload{OperationCanceled: true}, err
+	}
+
+	f.mu.Lock()
+	f.steps = append(f.steps, step)
+	result, ok := f.results[strings.TrimSpace(step.Command.Run)]
+	f.mu.Unlock()
+
+	if !ok && f.Strict {
+		result = CommandResult{ExitCode: 127, Err: fmt.Errorf("runtimetest: unexpected command %q", step.Command.Run)}
+	}
+	exitCode := result.ExitCode
+	payload := runtime.PlanObservationPayload{
+		Stdout:   result.Stdout,
+		Stderr:   result.Stderr,
+		ExitCode: &exitCode,
+	}
+	if result.Err != nil {
+		payload.Details = result.Err.Error()
+		return payload, result.Err
+	}
+	if exitCode != 0 {
+		return payload, fmt.Errorf("command[%s]: exited with code %d", step.ID, exitCode)
+	}
+	return payload, nil
+}
+
+// Steps returns the steps run so far, in execution order.
+func (f *FakeCommands) Steps() []runtime.PlanStep {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]runtime.PlanStep(nil), f.steps...)
+}
+
+// Commands returns the run strings of the steps run so far.
+func (f *FakeCommands) Commands() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	runs := make([]string, 0, len(f.steps))
+	for _, step := range f.steps {
+		runs = append(runs, step.Command.Run)
+	}
+	return runs
+}
@@ -0,0 +1,9 @@
+// Package runtimetest provides deterministic building blocks for testing hosts
+// that embed the GoAgent runtime.
+//
+// ScriptedProvider replays canned plan tool calls instead of contacting a model,
+// FakeCommands answers shell steps with canned observations instead of starting
+// processes, and Recorder captures the runtime events a host would render. New
+// wires all three into a running runtime so an integration test only needs to
+// submit prompts and inspect what happened.
+package runtimetest
@@ -0,0 +1,121 @@
+package runtimetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/asynkron/goagent/internal/core/runtime"
+)
+
+// DefaultTimeout bounds how long Harness helpers wait for the runtime.
+const DefaultTimeout = 10 * time.Second
+
+// Harness is a running runtime wired to a ScriptedProvider, FakeCommands and
+// a Recorder.
+type Harness struct {
+	Runtime  *runtime.Runtime
+	Provider *ScriptedProvider
+	Commands *FakeCommands
+	Events   *Recorder
+
+	t      testing.TB
+	cancel context.CancelFunc
+	done   chan error
+	cursor int
+}
+
+// New starts a runtime built from options with the scripted calls as its
+// model. Options that would reach the network, the terminal or the disk are
+// overridden: history, snapshots and failure logs are disabled, stdin and
+// stdout are left alone, and shell steps go to Commands. Unset
+// ProviderClient and CommandRunner fields are filled with a
+// ScriptedProvider and FakeCommands; a custom CommandRunner leaves Commands
+// nil. The runtime is shut down when the test ends.
+func New(t testing.TB, options runtime.RuntimeOptions, calls ...runtime.ToolCall) *Harness {
+	t.Helper()
+
+	h := &Harness{t: t, Events: NewRecorder(), done: make(chan error, 1)}
+	if provider, ok := options.ProviderClient.(*ScriptedProvider); ok {
+		h.Provider = provider
+		provider.Enqueue(calls...)
+	} else if options.ProviderClient == nil {
+		h.Provider = NewScriptedProvider(calls...)
+		options.ProviderClient = h.Provider
+	}
+	if options.CommandRunner == nil {
+		h.Commands = NewFakeCommands()
+		options.CommandRunner = h.Commands
+	} else if commands, ok := options.CommandRunner.(*FakeCommands); ok {
+		h.Commands = commands
+	}
+
+	historyPath := ""
+	options.HistoryLogPath = &historyPath
+	options.EventLogPath = &historyPath
+	options.DisableSnapshots = true
+	options.DisableFailureLogs = true
+	options.DisableInputReader = true
+	options.DisableOutputForwarding = true
+
+	rt, err := runtime.NewRuntime(options)
+	if err != nil {
+		t.Fatalf("runtimetest: create runtime: %v", err)
+	}
+	h.Runtime = rt
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	go h.Events.Drain(rt.Outputs())
+	go func() { h.done <- rt.Run(ctx) }()
+	t.Cleanup(h.Close)
+
+	return h
+}
+
+// Prompt submits prompt and waits until the runtime asks for input again,
+// returning the events emitted in between. It fails the test on timeout.
+func (h *Harness) Prompt(prompt string) []runtime.RuntimeEvent {
+	h.t.Helper()
+
+	// The runtime asks for the first prompt right after starting.
+	if h.cursor == 0 {
+		h.WaitFor(runtime.EventTypeRequestInput)
+	}
+	start := h.cursor
+	h.Runtime.SubmitPrompt(prompt)
+	h.WaitFor(runtime.EventTypeRequestInput)
+	return h.Events.Events()[start:h.cursor]
+}
+
+// WaitFor blocks until an event of type typ is recorded after the previously
+// awaited event and returns it. It fails the test on timeout.
+func (h *Harness) WaitFor(typ runtime.EventType) runtime.RuntimeEvent {
+	h.t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+	evt, index, err := h.Events.WaitForType(ctx, h.cursor, typ)
+	if err != nil {
+		h.t.Fatalf("runtimetest: waiting for %s event: %v", typ, err)
+	}
+	h.cursor = index + 1
+	return evt
+}
+
+// Close shuts the runtime down and waits for Run to return. It is safe to
+// call more than once.
+func (h *Harness) Close() {
+	if h.cancel == nil {
+		return
+	}
+	h.Runtime.Shutdown("test finished")
+	select {
+	case <-h.done:
+	case <-time.After(DefaultTimeout):
+		h.cancel()
+		<-h.done
+	}
+	h.cancel()
+	h.cancel = nil
+}
@@ -0,0 +1,77 @@
+package runtimetest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/asynkron/goagent/internal/core/runtime"
+)
+
+func TestHarnessRunsScriptedPlan(t *testing.T) {
+	t.Parallel()
+
+	h := New(t, runtime.RuntimeOptions{},
+		PlanCall("call-1", runtime.PlanResponse{
+			Message: "Listing files",
+			Plan:    []runtime.PlanStep{Step("list", "ls")},
+		}),
+		MessageCall("call-2", "All done"),
+	)
+	h.Commands.On("ls", CommandResult{Stdout: "main.go\n"})
+
+	events := h.Prompt("what is here?")
+
+	if got := h.Commands.Commands(); len(got) != 1 || got[0] != "ls" {
+		t.Fatalf("expected the ls step to run once, got %v", got)
+	}
+	if h.Provider.Remaining() != 0 {
+		t.Fatalf("expected the whole script to be consumed, %d calls left", h.Provider.Remaining())
+	}
+
+	var messages []string
+	for _, evt := range events {
+		// Plan messages carry the tool call; streamed ones are consolidated deltas.
+		if evt.Type == runtime.EventTypeAssistantMessage && evt.Metadata["tool_call_id"] != nil {
+			messages = append(messages, evt.Message)
+		}
+	}
+	if len(messages) != 2 || messages[1] != "All done" {
+		t.Fatalf("unexpected assistant messages: %v", messages)
+	}
+
+	requests := h.Provider.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("expected two plan requests, got %d", len(requests))
+	}
+	last := requests[1][len(requests[1])-1]
+	if !strings.Contains(last.Content, "main.go") {
+		t.Fatalf("expected the canned output in the follow-up request, got %q", last.Content)
+	}
+}
+
+func TestFakeCommandsStrictRejectsUnknownCommands(t *testing.T) {
+	t.Parallel()
+
+	commands := NewFakeCommands()
+	commands.Strict = true
+
+	payload, err := commands.RunCommand(t.Context(), Step("s1", "rm -rf /"))
+	if err == nil {
+		t.Fatal("expected an error for an unregistered command")
+	}
+	if payload.ExitCode == nil || *payload.ExitCode != 127 {
+		t.Fatalf("expected exit code 127, got %+v", payload.ExitCode)
+	}
+}
+
+func TestScriptedProviderReportsExhaustion(t *testing.T) {
+	t.Parallel()
+
+	provider := NewScriptedProvider(MessageCall("call-1", "hi"))
+	if _, err := provider.RequestPlan(t.Context(), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := provider.RequestPlan(t.Context(), nil); err != ErrScriptExhausted {
+		t.Fatalf("expected ErrScriptExhausted, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package runtimetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/asynkron/goagent/internal/core/runtime"
+	"github.com/asynkron/goagent/internal/core/schema"
+)
+
+// ErrScriptExhausted is returned by ScriptedProvider once every queued tool
+// call has been handed out.
+var ErrScriptExhausted = errors.New("runtimetest: no scripted tool call left")
+
+// ScriptedProvider is a runtime.Provider that returns queued tool calls in
+// order. It records the history sent with each request so tests can assert on
+// what the model would have seen. It is safe for concurrent use.
+type ScriptedProvider struct {
+	mu       sync.Mutex
+	calls    []runtime.ToolCall
+	next     int
+	requests [][]runtime.ChatMessage
+}
+
+// NewScriptedProvider queues calls to be returned one per plan request.
+func NewScriptedProvider(calls ...runtime.ToolCall) *ScriptedProvider {
+	return &ScriptedProvider{calls: append([]runtime.ToolCall(nil), calls...)}
+}
+
+// Enqueue appends calls to the script.
+func (p *ScriptedProvider) Enqueue(calls ...runtime.ToolCall) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, calls...)
+}
+
+// RequestPlan returns the next scripted tool call or ErrScriptExhausted.
+func (p *ScriptedProvider) RequestPlan(ctx context.Context, history []runtime.ChatMessage) (runtime.ToolCall, error) {
+	if err := ctx.Err(); err != nil {
+		return runtime.ToolCall{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, append([]runtime.ChatMessage(nil), history...))
+	if p.next >= len(p.calls) {
+		return runtime.ToolCall{}, ErrScriptExhausted
+	}
+	call := p.calls[p.next]
+	p.next++
+	return call, nil
+}
+
+// RequestPlanStreaming behaves like RequestPlan. The plan message, when
+// present, is forwarded to onDelta as a single delta.
+func (p *ScriptedProvider) RequestPlanStreaming(ctx context.Context, history []runtime.ChatMessage, onDelta func(string)) (runtime.ToolCall, error) {
+	call, err := p.RequestPlan(ctx, history)
+	if err != nil || onDelta == nil || call.Name != schema.ToolName {
+		return call, err
+	}
+	var plan runtime.PlanResponse
+	if json.Unmarshal([]byte(call.Arguments), &plan) == nil && plan.Message != "" {
+		onDelta(plan.Message)
+	}
+	return call, nil
+}
+
+// Requests returns the histories received so far, one per plan request.
+func (p *ScriptedProvider) Requests() [][]runtime.ChatMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([][]runtime.ChatMessage(nil), p.requests...)
+}
+
+// Remaining reports how many scripted calls have not been consumed yet.
+func (p *ScriptedProvider) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.calls) - p.next
+}
+
+// PlanCall builds the plan tool call the runtime expects from a model. Steps
+// without a status default to pending.
+func PlanCall(id string, plan runtime.PlanResponse) runtime.ToolCall {
+	if plan.Plan == nil {
+		plan.Plan = []runtime.PlanStep{}
+	}
+	for i := range plan.Plan {
+		if plan.Plan[i].Status == "" {
+			plan.Plan[i].Status = runtime.PlanPending
+		}
+		if plan.Plan[i].WaitingForID == nil {
+			plan.Plan[i].WaitingForID = []string{}
+		}
+	}
+	// PlanResponse omits empty reasoning, but the plan schema requires it.
+	reasoning := plan.Reasoning
+	if reasoning == nil {
+		reasoning = []string{}
+	}
+	args, err := json.Marshal(struct {
+		runtime.PlanResponse
+		Reasoning []string `json:"reasoning"`
+	}{plan, reasoning})
+	if err != nil {
+		panic(fmt.Sprintf("runtimetest: marshal plan: %v", err))
+	}
+	return runtime.ToolCall{ID: id, Name: schema.ToolName, Arguments: string(args)}
+}
+
+// MessageCall builds a plan tool call that only replies with message and
+// finishes the turn.
+func MessageCall(id, message string) runtime.ToolCall {
+	return PlanCall(id, runtime.PlanResponse{Message: message})
+}
+
+// Step builds a pending shell plan step running run.
+func Step(id, run string) runtime.PlanStep {
+	return runtime.PlanStep{
+		ID:    id,
+		Title: run,
+		Command: runtime.CommandDraft{
+			Reason:     "scripted step",
+			Shell:      "/bin/bash",
+			Run:        run,
+			TimeoutSec: 60,
+			TailLines:  200,
+			MaxBytes:   16384,
+		},
+	}
+}
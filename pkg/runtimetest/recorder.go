@@ -0,0 +1,106 @@
+package runtimetest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/asynkron/goagent/internal/core/runtime"
+)
+
+// Recorder collects runtime events in the order they were emitted.
+type Recorder struct {
+	mu      sync.Mutex
+	events  []runtime.RuntimeEvent
+	changed chan struct{}
+	done    chan struct{}
+}
+
+// NewRecorder returns an empty recorder. Use Record to feed it events or
+// Drain to consume a runtime's output channel.
+func NewRecorder() *Recorder {
+	return &Recorder{changed: make(chan struct{}), done: make(chan struct{})}
+}
+
+// Record appends evt and wakes up pending waiters.
+func (r *Recorder) Record(evt runtime.RuntimeEvent) {
+	r.mu.Lock()
+	r.events = append(r.events, evt)
+	close(r.changed)
+	r.changed = make(chan struct{})
+	r.mu.Unlock()
+}
+
+// Drain records every event from outputs until the channel is closed. It
+// blocks, so callers usually run it in a goroutine.
+func (r *Recorder) Drain(outputs <-chan runtime.RuntimeEvent) {
+	defer close(r.done)
+	for evt := range outputs {
+		r.Record(evt)
+	}
+}
+
+// Done is closed once Drain has consumed the whole output channel.
+func (r *Recorder) Done() <-chan struct{} {
+	return r.done
+}
+
+// Events returns a copy of the recorded events.
+func (r *Recorder) Events() []runtime.RuntimeEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]runtime.RuntimeEvent(nil), r.events...)
+}
+
+// OfType returns the recorded events of the given types.
+func (r *Recorder) OfType(types ...runtime.EventType) []runtime.RuntimeEvent {
+	var matched []runtime.RuntimeEvent
+	for _, evt := range r.Events() {
+		for _, typ := range types {
+			if evt.Type == typ {
+				matched = append(matched, evt)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// Messages returns the messages of the recorded events of type typ.
+func (r *Recorder) Messages(typ runtime.EventType) []string {
+	events := r.OfType(typ)
+	messages := make([]string, 0, len(events))
+	for _, evt := range events {
+		messages = append(messages, evt.Message)
+	}
+	return messages
+}
+
+// WaitFor blocks until match accepts an event recorded at or after index
+// from, returning that event and its index. It fails with ctx.Err() when
+// ctx ends first.
+func (r *Recorder) WaitFor(ctx context.Context, from int, match func(runtime.RuntimeEvent) bool) (runtime.RuntimeEvent, int, error) {
+	for {
+		r.mu.Lock()
+		for i := from; i < len(r.events); i++ {
+			if match(r.events[i]) {
+				evt := r.events[i]
+				r.mu.Unlock()
+				return evt, i, nil
+			}
+		}
+		from = max(from, len(r.events))
+		changed := r.changed
+		r.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return runtime.RuntimeEvent{}, -1, ctx.Err()
+		}
+	}
+}
+
+// WaitForType is WaitFor matching the first event of type typ.
+func (r *Recorder) WaitForType(ctx context.Context, from int, typ runtime.EventType) (runtime.RuntimeEvent, int, error) {
+	return r.WaitFor(ctx, from, func(evt runtime.RuntimeEvent) bool { return evt.Type == typ })
+}